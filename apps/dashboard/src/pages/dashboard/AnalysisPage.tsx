@@ -91,6 +91,25 @@ interface AnalysisResponse {
   message?: string
 }
 
+// Trabajo de análisis asíncrono (POST /analysis/analyze y GET /analysis/jobs/:id)
+interface AnalysisJob {
+  id: string
+  status: 'queued' | 'running' | 'completed' | 'failed'
+  progress: number
+  result?: MarketAnalysis
+  preview?: PaywallPreview
+  error?: string
+}
+
+interface AnalysisJobResponse {
+  success?: boolean
+  data?: AnalysisJob & { job_id?: string }
+  message?: string
+}
+
+const API_URL = 'http://localhost:8081/api/v1'
+const JOB_POLL_INTERVAL = 1000
+
 export default function AnalysisPage() {
   const [url, setUrl] = useState(() => {
     // Obtener URL de los parámetros de la URL si existe
@@ -121,40 +140,38 @@ export default function AnalysisPage() {
     setProgress(0)
 
     try {
-      // Simular progreso
-      const progressInterval = setInterval(() => {
-        setProgress(prev => {
-          if (prev >= 90) {
-            clearInterval(progressInterval)
-            return 90
-          }
-          return prev + 10
-        })
-      }, 500)
-
-      // Llamar al API de análisis
-      const response = await fetch('http://localhost:8081/api/v1/analysis/analyze', {
+      // Encolar el análisis: el API responde el ID del trabajo
+      const response = await fetch(`${API_URL}/analysis/analyze`, {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
         },
         body: JSON.stringify({ url }),
       })
-
-      clearInterval(progressInterval)
-      setProgress(100)
-
-      if (!response.ok) {
-        throw new Error(`Error ${response.status}: ${response.statusText}`)
+      const submitted: AnalysisJobResponse = await response.json()
+      const jobId = submitted.data?.job_id
+      if (!response.ok || !jobId) {
+        throw new Error(submitted.message || `Error ${response.status}: ${response.statusText}`)
       }
 
-      const result: AnalysisResponse = await response.json()
-
-      if (result.success) {
-        setAnalysis(result.data || null)
-        setPreview(result.preview || null)
+      // Consultar el avance hasta que el trabajo termine
+      let job: AnalysisJob | undefined
+      do {
+        await new Promise(resolve => setTimeout(resolve, JOB_POLL_INTERVAL))
+        const statusResponse = await fetch(`${API_URL}/analysis/jobs/${jobId}`)
+        const status: AnalysisJobResponse = await statusResponse.json()
+        if (!statusResponse.ok || !status.data) {
+          throw new Error(status.message || `Error ${statusResponse.status}: ${statusResponse.statusText}`)
+        }
+        job = status.data
+        setProgress(job.progress)
+      } while (job.status === 'queued' || job.status === 'running')
+
+      if (job.status === 'completed') {
+        setAnalysis(job.result || null)
+        setPreview(job.preview || null)
       } else {
-        setError(result.error || 'Error desconocido en el análisis')
+        setError(job.error || 'Error desconocido en el análisis')
       }
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Error de conexión')
@@ -174,7 +191,7 @@ export default function AnalysisPage() {
     setError(null)
 
     try {
-      const response = await fetch(`http://localhost:8081/api/v1/analysis/preview?url=${encodeURIComponent(url)}`)
+      const response = await fetch(`${API_URL}/analysis/preview?url=${encodeURIComponent(url)}`)
       
       if (!response.ok) {
         throw new Error(`Error ${response.status}: ${response.statusText}`)
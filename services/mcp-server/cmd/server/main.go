@@ -1,20 +1,38 @@
 package main
 
 import (
+	"context"
 	"log"
 	"mcp-server/internal/cache"
 	"mcp-server/internal/handlers"
+	"mcp-server/internal/middleware"
 	"mcp-server/internal/services"
 	"mcp-server/internal/tenant"
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/dian"
+	"mcp-server/pkg/errors"
+	"mcp-server/pkg/exogena"
+	"mcp-server/pkg/habeasdata"
+	"mcp-server/pkg/notify"
+	"mcp-server/pkg/payments"
+	"mcp-server/pkg/payments/wompi"
+	"mcp-server/pkg/sarlaft"
+	"mcp-server/pkg/shipping"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
 func main() {
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		ErrorHandler: errors.HandleError,
+	})
 
 	// Middleware
 	app.Use(logger.New())
@@ -41,7 +59,53 @@ func main() {
 
 	// Inicializar servicios
 	configService := services.NewConfigService("http://localhost:8090", "admin@tause.pro", "admin123")
-	analysisService := services.NewAnalysisService(configService, redisCache)
+	analysisService := services.NewAnalysisService(configService, redisCache).
+		WithSearchProvider(newSearchProvider(configService))
+	analysisJobService := services.NewAnalysisJobService(analysisService, redisCache)
+	analysisJobService.Start(context.Background(), 4)
+	// Los documentos DIAN se emiten contra el simulador local; los
+	// consecutivos se guardan en Redis para no repetirlos entre réplicas
+	dianService := dian.NewService(dian.NewSimulator())
+	if redisCache != nil {
+		dianService.WithSequenceStore(redisCache)
+	} else {
+		log.Printf("⚠️ Sin Redis los consecutivos DIAN solo se guardan en memoria: un reinicio los repite")
+	}
+	colombiaService := colombia.NewService().WithCompanyRegistry(newCompanyRegistry(redisCache))
+	// Listas restrictivas (OFAC, ONU) y PEP para el control SARLAFT de los
+	// pagadores y de la vinculación de comercios
+	sarlaftService := sarlaft.NewService()
+	if dir := os.Getenv("SARLAFT_LISTS_DIR"); dir != "" {
+		if _, err := sarlaftService.WithListsDir(dir).Reload(); err != nil {
+			log.Printf("⚠️ SARLAFT: %v", err)
+		}
+	}
+	// Tenants sin llaves de Wompi cobran contra el stub local
+	wompiStub := wompi.NewStubServer(publicURL() + "/sandbox/wompi")
+	paymentsService := payments.NewService(wompiStub).
+		WithWompiBaseURL(os.Getenv("WOMPI_BASE_URL")).
+		WithInvoicing(dianService).
+		WithScreening(sarlaftService)
+	if redisCache != nil {
+		paymentsService.WithCache(redisCache)
+	}
+	// Resuelve los pagos que siguen pendientes después de su vencimiento
+	paymentsService.StartReconciler(context.Background(), 5*time.Minute)
+	// Transportadoras sin credenciales cotizan y generan guías en el simulador
+	// y avisan al destinatario los hitos del envío por WhatsApp y correo
+	notifyService := notify.NewService()
+	shippingService := shipping.NewService(shipping.NewSimulator()).
+		WithNotifications(notifyService).
+		WithPublicURL(publicURL())
+	// Consulta el rastreo de las guías activas que no notifican por webhook
+	shippingService.StartTracker(context.Background(), 10*time.Minute)
+	// Autorizaciones y solicitudes de los titulares sobre los datos que guardan
+	// los pagos, los envíos y los avisos
+	habeasService := habeasdata.NewService().
+		WithSources(handlers.HabeasDataSources(paymentsService, shippingService, notifyService)...)
+	// Información exógena y resumen de IVA y retenciones a partir de los
+	// documentos DIAN aprobados y los pagos recibidos
+	exogenaService := exogena.NewService(dianService, paymentsService, colombiaService)
 
 	// Inicializar tenant manager (simulado sin DB por ahora)
 	var tenantManager *tenant.TenantManager
@@ -54,9 +118,20 @@ func main() {
 	// Inicializar handlers
 	configHandler := handlers.NewConfigHandler(configService)
 	analysisHandler := handlers.NewAnalysisHandler(analysisService)
+	analysisJobHandler := handlers.NewAnalysisJobHandler(analysisJobService)
+	dianHandler := handlers.NewDIANHandler(dianService, colombiaService)
+	fiscalHandler := handlers.NewFiscalHandler(colombiaService)
+	companyHandler := handlers.NewCompanyHandler(colombiaService)
+	paymentsHandler := handlers.NewPaymentsHandler(paymentsService)
+	shippingHandler := handlers.NewShippingHandler(shippingService, paymentsService, notifyService)
+	shippingService.OnStatusChange(shippingHandler.SyncOrder)
+	habeasHandler := handlers.NewHabeasDataHandler(habeasService)
+	sarlaftHandler := handlers.NewSARLAFTHandler(sarlaftService)
+	exogenaHandler := handlers.NewExogenaHandler(exogenaService)
+	mcpHandler := handlers.NewMCPHandler(dianService, colombiaService, paymentsService, shippingService, habeasService)
 	var tenantHandler *handlers.TenantHandler
 	if tenantManager != nil {
-		tenantHandler = handlers.NewTenantHandler(tenantManager)
+		tenantHandler = handlers.NewTenantHandler(tenantManager, colombiaService, sarlaftService)
 	}
 
 	// Health check
@@ -67,9 +142,21 @@ func main() {
 		})
 	})
 
+	// Stub de Wompi: API sandbox y páginas de pago simuladas
+	app.All("/sandbox/wompi/*", adaptor.HTTPHandler(http.StripPrefix("/sandbox/wompi", wompiStub)))
+
 	// API routes
 	api := app.Group("/api/v1")
 
+	// Webhooks de pasarelas (sin tenant: el pago identifica al comercio)
+	webhooks := api.Group("/webhooks")
+	webhooks.Post("/wompi", paymentsHandler.WompiWebhook)
+	// Webhooks de rastreo de las transportadoras (sin tenant: la guía identifica al comercio)
+	webhooks.Post("/shipping/:carrier", shippingHandler.CarrierWebhook)
+
+	// Página pública de rastreo enlazada en los avisos al destinatario
+	app.Get("/rastreo/:id", shippingHandler.TrackingPage)
+
 	// Rutas de configuración del sistema
 	config := api.Group("/config")
 	config.Get("/", configHandler.GetConfig)
@@ -77,14 +164,105 @@ func main() {
 	config.Post("/test-api-key", configHandler.TestAPIKey)
 	config.Get("/api-key-status", configHandler.GetAPIKeyStatus)
 	config.Post("/reset-usage", configHandler.ResetUsage)
+	config.Get("/fiscal", fiscalHandler.ListParameters)
+	config.Get("/fiscal/current", fiscalHandler.GetParameters)
+	// Los parámetros fiscales aplican a todos los tenants: solo los publica el Super Admin
+	config.Post("/fiscal", middleware.TenantMiddleware(), middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), fiscalHandler.PublishParameters)
 
 	// Rutas de análisis automático
 	analysis := api.Group("/analysis")
-	analysis.Post("/analyze", analysisHandler.AnalyzeCompany)
+	// Encola el análisis y responde el trabajo (igual que POST /jobs)
+	analysis.Post("/analyze", analysisJobHandler.SubmitAnalysisJob)
 	analysis.Get("/preview", analysisHandler.GetAnalysisPreview)
 	analysis.Get("/full", analysisHandler.GetFullAnalysis)
 	analysis.Post("/report", analysisHandler.GenerateReport)
 	analysis.Get("/health", analysisHandler.HealthCheck)
+	analysis.Post("/jobs", analysisJobHandler.SubmitAnalysisJob)
+	analysis.Get("/jobs/:id", analysisJobHandler.GetAnalysisJob)
+	analysis.Get("/jobs/:id/events", analysisJobHandler.StreamAnalysisJob)
+
+	// Rutas Colombia: facturación electrónica DIAN
+	colombiaRoutes := api.Group("/colombia", middleware.TenantMiddleware())
+	colombiaRoutes.Get("/cities", handlers.SearchCities)
+	colombiaRoutes.Post("/nit/validate", companyHandler.ValidateNIT)
+	colombiaRoutes.Get("/companies/:nit", companyHandler.LookupCompany)
+	colombiaRoutes.Get("/companies/:nit/onboarding", companyHandler.PrefillOnboarding)
+	colombiaRoutes.Post("/identity/validate", handlers.ValidateIdentityDocument)
+	colombiaRoutes.Post("/address/normalize", handlers.NormalizeAddress)
+	colombiaRoutes.Post("/phone/validate", handlers.ValidatePhone)
+	colombiaRoutes.Get("/ciiu", handlers.SearchCIIU)
+	colombiaRoutes.Post("/ciiu/classify", handlers.ClassifyCIIU)
+	colombiaRoutes.Post("/payments", paymentsHandler.CreatePayment)
+	colombiaRoutes.Post("/payments/pse", paymentsHandler.ProcessPSEPayment)
+	colombiaRoutes.Get("/payments/pse/banks", paymentsHandler.ListPSEBanks)
+	colombiaRoutes.Get("/payments", paymentsHandler.ListPayments)
+	colombiaRoutes.Get("/payments/:id", paymentsHandler.GetPayment)
+	colombiaRoutes.Put("/payments/:id/shipment", paymentsHandler.AttachShipment)
+	// Reembolsos y anulaciones mueven dinero: solo el owner o un admin del tenant
+	colombiaRoutes.Post("/payments/:id/refunds", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), paymentsHandler.RefundPayment)
+	colombiaRoutes.Post("/payments/:id/void", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), paymentsHandler.VoidPayment)
+	// Asociar la factura cambia lo que se acredita en un reembolso: solo el owner o un admin
+	colombiaRoutes.Put("/payments/:id/invoice", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), paymentsHandler.LinkInvoice)
+	// Conciliación manual de recaudos y contraentregas: aprueba pagos, solo el owner o un admin
+	colombiaRoutes.Post("/payments/cash/confirm", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), paymentsHandler.ConfirmCashPayment)
+	colombiaRoutes.Post("/payments/cod/events", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), paymentsHandler.ShipmentEvent)
+	colombiaRoutes.Get("/orders/:id", paymentsHandler.GetOrder)
+	colombiaRoutes.Get("/shipping/carriers", shippingHandler.ListCarriers)
+	colombiaRoutes.Post("/shipping/quotes", shippingHandler.QuoteShipping)
+	colombiaRoutes.Get("/shipping/rate-tables", shippingHandler.ListRateTables)
+	colombiaRoutes.Get("/shipping/rate-tables/:id", shippingHandler.GetRateTable)
+	// Las tablas fijan lo que se cobra a los clientes del comercio: solo el owner o un admin
+	colombiaRoutes.Post("/shipping/rate-tables", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), shippingHandler.SaveRateTable)
+	colombiaRoutes.Put("/shipping/rate-tables/:id", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), shippingHandler.SaveRateTable)
+	colombiaRoutes.Delete("/shipping/rate-tables/:id", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), shippingHandler.DeleteRateTable)
+	colombiaRoutes.Post("/shipments", shippingHandler.CreateShipment)
+	colombiaRoutes.Get("/shipments", shippingHandler.ListShipments)
+	colombiaRoutes.Get("/shipments/:id", shippingHandler.GetShipment)
+	colombiaRoutes.Get("/shipments/:id/label", shippingHandler.GetShipmentLabel)
+	colombiaRoutes.Get("/shipments/:id/tracking", shippingHandler.TrackShipment)
+	colombiaRoutes.Post("/shipments/:id/cancel", shippingHandler.CancelShipment)
+	colombiaRoutes.Get("/notifications", shippingHandler.ListNotifications)
+	// Habeas data: el aviso de privacidad y la radicación de solicitudes son
+	// públicos para los titulares y se registran antes del grupo autenticado,
+	// que cubre el resto de /habeas-data
+	colombiaRoutes.Get("/habeas-data/notice", habeasHandler.GetPrivacyNotice)
+	colombiaRoutes.Post("/habeas-data/requests", habeasHandler.FileDataRequest)
+	habeasRoutes := colombiaRoutes.Group("/habeas-data", middleware.AuthMiddleware())
+	habeasRoutes.Post("/consents", habeasHandler.RecordConsent)
+	habeasRoutes.Get("/consents", habeasHandler.ListConsents)
+	habeasRoutes.Post("/consents/revoke", habeasHandler.RevokeConsent)
+	habeasRoutes.Get("/requests", habeasHandler.ListDataRequests)
+	habeasRoutes.Get("/requests/:id", habeasHandler.GetDataRequest)
+	habeasRoutes.Post("/requests/:id/extend", middleware.RequireRole("owner", "admin"), habeasHandler.ExtendDataRequest)
+	habeasRoutes.Post("/requests/:id/resolve", middleware.RequireRole("owner", "admin"), habeasHandler.ResolveDataRequest)
+	habeasRoutes.Get("/requests/:id/export", middleware.RequireRole("owner", "admin"), habeasHandler.ExportDataRequest)
+	colombiaRoutes.Post("/invoice/dian", dianHandler.CreateInvoice)
+	colombiaRoutes.Post("/support-document/dian", dianHandler.CreateSupportDocument)
+	colombiaRoutes.Post("/payroll/dian", dianHandler.CreatePayroll)
+	colombiaRoutes.Get("/documents", dianHandler.ListDocuments)
+	colombiaRoutes.Get("/documents/:id", dianHandler.GetDocument)
+	colombiaRoutes.Get("/documents/:id/xml", dianHandler.GetDocumentXML)
+	// Información exógena y resúmenes tributarios: datos de terceros, solo el owner o un admin
+	colombiaRoutes.Get("/exogena/:year", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), exogenaHandler.GetExogenaOverview)
+	colombiaRoutes.Get("/exogena/:year/:format", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), exogenaHandler.GetExogenaFormat)
+	colombiaRoutes.Get("/tax-reports/:year", middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"), exogenaHandler.GetTaxSummary)
+
+	// Administración SARLAFT: oficial de cumplimiento del tenant (las alertas de
+	// vinculación de comercios quedan en el tenant "plataforma")
+	sarlaftRoutes := api.Group("/admin/sarlaft", middleware.TenantMiddleware(), middleware.AuthMiddleware(), middleware.RequireRole("owner", "admin"))
+	sarlaftRoutes.Get("/lists", sarlaftHandler.ListLists)
+	sarlaftRoutes.Post("/lists/reload", sarlaftHandler.ReloadLists)
+	sarlaftRoutes.Post("/screenings", sarlaftHandler.Screen)
+	sarlaftRoutes.Get("/screenings/:id", sarlaftHandler.GetScreening)
+	sarlaftRoutes.Get("/hits", sarlaftHandler.ListHits)
+	sarlaftRoutes.Get("/hits/:id", sarlaftHandler.GetHit)
+	sarlaftRoutes.Post("/hits/:id/review", sarlaftHandler.ReviewHit)
+
+	// Rutas MCP: herramientas y agentes
+	mcp := api.Group("/mcp", middleware.TenantMiddleware(), middleware.AuthMiddleware())
+	mcp.Get("/tools", mcpHandler.ListMCPTools)
+	mcp.Post("/tools/execute", mcpHandler.ExecuteMCPTool)
+	mcp.Post("/agents/:id/chat", mcpHandler.ChatWithAgent)
 
 	// Rutas de tenant (si está disponible)
 	if tenantHandler != nil {
@@ -192,3 +370,87 @@ func main() {
 	log.Printf("🔧 Configuración completada, iniciando servidor...")
 	app.Listen(":" + port)
 }
+
+// publicURL URL pública del servidor, usada en los enlaces del stub de Wompi
+func publicURL() string {
+	if url := os.Getenv("PUBLIC_URL"); url != "" {
+		return url
+	}
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8081"
+	}
+	return "http://localhost:" + port
+}
+
+// newSearchProvider arma la cadena de búsqueda del análisis a partir de
+// SEARCH_PROVIDERS (por ejemplo "tavily,searxng,fixtures"); cada proveedor se
+// usa si el anterior falla. Sin configurar se usa solo Tavily.
+func newSearchProvider(configService *services.ConfigService) services.SearchProvider {
+	names := os.Getenv("SEARCH_PROVIDERS")
+	if names == "" {
+		names = "tavily"
+	}
+
+	var providers []services.SearchProvider
+	for _, name := range strings.Split(names, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "tavily":
+			providers = append(providers, services.NewTavilyProvider(configService, &http.Client{Timeout: 30 * time.Second}))
+		case "searxng":
+			baseURL := os.Getenv("SEARXNG_URL")
+			if baseURL == "" {
+				log.Printf("⚠️ Búsqueda searxng omitida: falta SEARXNG_URL")
+				continue
+			}
+			providers = append(providers, services.NewSearXNGProvider(baseURL))
+		case "brave":
+			apiKey := os.Getenv("BRAVE_API_KEY")
+			if apiKey == "" {
+				log.Printf("⚠️ Búsqueda brave omitida: falta BRAVE_API_KEY")
+				continue
+			}
+			providers = append(providers, services.NewBraveProvider(apiKey))
+		case "fixtures":
+			fixtures, err := services.LoadFixtureSearchProvider(os.Getenv("SEARCH_FIXTURES"))
+			if err != nil {
+				log.Printf("⚠️ Búsqueda con fixtures omitida: %v", err)
+				continue
+			}
+			providers = append(providers, fixtures)
+		case "":
+		default:
+			log.Printf("⚠️ Proveedor de búsqueda desconocido: %s", name)
+		}
+	}
+
+	if len(providers) == 1 {
+		log.Printf("✅ Búsqueda del análisis: %s", providers[0].Name())
+		return providers[0]
+	}
+	chain := services.NewFallbackSearchProvider(providers...)
+	log.Printf("✅ Búsqueda del análisis: %s", chain.Name())
+	return chain
+}
+
+// newCompanyRegistry elige el registro de empresas: servicio HTTP si está
+// configurado, archivo de fixtures o las empresas de ejemplo (sandbox)
+func newCompanyRegistry(redisCache *cache.RedisCache) colombia.CompanyRegistry {
+	var registry colombia.CompanyRegistry = colombia.SandboxRegistry()
+	if baseURL := os.Getenv("COMPANY_REGISTRY_URL"); baseURL != "" {
+		registry = colombia.NewHTTPRegistry(baseURL, os.Getenv("COMPANY_REGISTRY_API_KEY"))
+		log.Printf("✅ Registro de empresas: %s", baseURL)
+	} else if path := os.Getenv("COMPANY_REGISTRY_FIXTURES"); path != "" {
+		fixtures, err := colombia.NewFixtureRegistry(path)
+		if err != nil {
+			log.Printf("⚠️ Fixtures de empresas no cargadas, usando sandbox: %v", err)
+		} else {
+			registry = fixtures
+		}
+	}
+
+	if redisCache == nil {
+		return registry
+	}
+	return colombia.NewCachedRegistry(registry, redisCache, 24*time.Hour)
+}
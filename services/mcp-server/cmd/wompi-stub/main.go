@@ -0,0 +1,35 @@
+// Comando wompi-stub levanta el stub de Wompi como servidor independiente para
+// pruebas locales. Los eventos firmados se envían a -events (por defecto el
+// webhook del MCP server).
+//
+//	go run ./cmd/wompi-stub -addr :8095 -events http://localhost:8081/api/v1/webhooks/wompi
+//
+// Para cobrar contra él, el MCP server se inicia con
+// WOMPI_BASE_URL=http://localhost:8095/v1 y el tenant se configura con las
+// credenciales del stub: pub_stub_local, prv_stub_local, stub_events_secret y
+// stub_integrity_secret.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"mcp-server/pkg/payments/wompi"
+)
+
+func main() {
+	addr := flag.String("addr", ":8095", "dirección de escucha")
+	publicURL := flag.String("public-url", "http://localhost:8095", "URL pública del stub")
+	events := flag.String("events", "http://localhost:8081/api/v1/webhooks/wompi", "URL que recibe los eventos (vacío: no enviar)")
+	settle := flag.Duration("settle", 3*time.Second, "tiempo hasta finalizar cada transacción")
+	flag.Parse()
+
+	stub := wompi.NewStubServer(*publicURL)
+	stub.EventsURL = *events
+	stub.SettleAfter = *settle
+
+	log.Printf("💳 Stub de Wompi en %s (API: %s/v1)", *addr, *publicURL)
+	log.Fatal(http.ListenAndServe(*addr, stub))
+}
@@ -242,19 +242,49 @@ func (r *RedisCache) GetCachedResult(key string, dest interface{}) (bool, error)
 
 // ===== DISTRIBUTED LOCKS =====
 
-// AcquireLock intenta adquirir un lock distribuido
-func (r *RedisCache) AcquireLock(lockKey string, ttl time.Duration) (bool, error) {
+// Scripts que solo tocan el lock si sigue siendo del mismo dueño
+var (
+	renewLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// AcquireLock intenta adquirir un lock distribuido a nombre de owner
+func (r *RedisCache) AcquireLock(lockKey, owner string, ttl time.Duration) (bool, error) {
 	key := fmt.Sprintf("lock:%s", lockKey)
 
 	// SetNX retorna true si la clave no existía
-	ok, err := r.client.SetNX(r.ctx, key, "1", ttl).Result()
+	ok, err := r.client.SetNX(r.ctx, key, owner, ttl).Result()
 	return ok, err
 }
 
-// ReleaseLock libera un lock
-func (r *RedisCache) ReleaseLock(lockKey string) error {
+// RenewLock extiende el lock si aún es de owner; false si venció o es de otro
+func (r *RedisCache) RenewLock(lockKey, owner string, ttl time.Duration) (bool, error) {
 	key := fmt.Sprintf("lock:%s", lockKey)
-	return r.client.Del(r.ctx, key).Err()
+	renewed, err := renewLockScript.Run(r.ctx, r.client, []string{key}, owner, ttl.Milliseconds()).Int()
+	return renewed == 1, err
+}
+
+// ReleaseLock libera el lock solo si es de owner, para no borrar el que otra
+// instancia tomó después de que venciera
+func (r *RedisCache) ReleaseLock(lockKey, owner string) error {
+	key := fmt.Sprintf("lock:%s", lockKey)
+	return releaseLockScript.Run(r.ctx, r.client, []string{key}, owner).Err()
+}
+
+// ===== SEQUENCES =====
+
+// IncrementSequence incrementa un consecutivo sin vencimiento (numeración de
+// documentos) y retorna el nuevo valor
+func (r *RedisCache) IncrementSequence(key string) (int64, error) {
+	return r.client.Incr(r.ctx, "sequence:"+key).Result()
 }
 
 // ===== ANALYTICS COUNTERS =====
@@ -287,6 +317,12 @@ func (r *RedisCache) buildMetricKey(metric string, tags map[string]string) strin
 	return key
 }
 
+// NormalizeURL normaliza la URL de una empresa como se usa en las claves de
+// análisis (sin esquema, "www." ni barra final)
+func NormalizeURL(url string) string {
+	return normalizeURL(url)
+}
+
 func normalizeURL(url string) string {
 	// Implementación simple, mejorar según necesidad
 	normalized := url
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"mcp-server/internal/services"
@@ -36,60 +35,6 @@ type AnalysisResponse struct {
 	Message string                   `json:"message,omitempty"`
 }
 
-// AnalyzeCompany analiza una empresa completa
-func (h *AnalysisHandler) AnalyzeCompany(c *fiber.Ctx) error {
-	var req AnalysisRequest
-
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(AnalysisResponse{
-			Success: false,
-			Error:   "Formato de request inválido",
-		})
-	}
-
-	// Validar URL
-	if req.URL == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(AnalysisResponse{
-			Success: false,
-			Error:   "URL es requerida",
-		})
-	}
-
-	// Iniciar análisis
-	ctx := c.Context()
-	analysis, err := h.analysisService.AnalyzeCompany(ctx, req.URL)
-	if err != nil {
-		// Manejar errores específicos de configuración
-		if strings.Contains(err.Error(), "API key de Tavily no válida") {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(AnalysisResponse{
-				Success: false,
-				Error:   "Servicio de análisis no disponible. Configure las API keys en el Super Admin.",
-			})
-		}
-
-		return c.Status(fiber.StatusInternalServerError).JSON(AnalysisResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Error en análisis: %v", err),
-		})
-	}
-
-	// Generar preview para paywall
-	preview, err := h.analysisService.GeneratePaywallPreview(analysis)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(AnalysisResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Error generando preview: %v", err),
-		})
-	}
-
-	return c.JSON(AnalysisResponse{
-		Success: true,
-		Data:    analysis,
-		Preview: preview,
-		Message: fmt.Sprintf("Análisis completado para %s", analysis.Company.Name),
-	})
-}
-
 // GetAnalysisPreview obtiene solo la vista previa del análisis
 func (h *AnalysisHandler) GetAnalysisPreview(c *fiber.Ctx) error {
 	url := c.Query("url")
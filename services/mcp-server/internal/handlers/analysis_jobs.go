@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"mcp-server/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	sseHeartbeat    = 15 * time.Second
+	ssePollInterval = time.Second
+	sseMaxDuration  = 5 * time.Minute
+)
+
+// AnalysisJobHandler trabajos de análisis asíncronos: el cliente encola el
+// análisis y consulta su avance o se suscribe a los eventos de progreso
+type AnalysisJobHandler struct {
+	jobService *services.AnalysisJobService
+}
+
+// NewAnalysisJobHandler crea una nueva instancia del handler
+func NewAnalysisJobHandler(jobService *services.AnalysisJobService) *AnalysisJobHandler {
+	return &AnalysisJobHandler{
+		jobService: jobService,
+	}
+}
+
+// SubmitAnalysisJob encola el análisis de una empresa y retorna el ID del trabajo
+func (h *AnalysisJobHandler) SubmitAnalysisJob(c *fiber.Ctx) error {
+	var req AnalysisRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Formato de request inválido",
+		})
+	}
+	if req.URL == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "URL es requerida",
+		})
+	}
+
+	job, deduplicated, err := h.jobService.Submit(req.URL)
+	if err != nil {
+		status := fiber.StatusConflict
+		if errors.Is(err, services.ErrQueueFull) {
+			status = fiber.StatusServiceUnavailable
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	message := "Análisis encolado"
+	if deduplicated {
+		message = "Ya hay un análisis en curso para esta URL"
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"job_id":       job.ID,
+			"status":       job.Status,
+			"progress":     job.Progress,
+			"deduplicated": deduplicated,
+			"status_url":   "/api/v1/analysis/jobs/" + job.ID,
+			"events_url":   "/api/v1/analysis/jobs/" + job.ID + "/events",
+		},
+		"message": message,
+	})
+}
+
+// GetAnalysisJob consulta el estado, el avance por etapa y el resultado de un trabajo
+func (h *AnalysisJobHandler) GetAnalysisJob(c *fiber.Ctx) error {
+	job, err := h.jobService.GetJob(c.Params("id"))
+	if err != nil {
+		return analysisJobError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// StreamAnalysisJob envía el avance del trabajo como Server-Sent Events: un
+// evento progress por cada cambio de etapa y un evento completed o failed al
+// final. Los trabajos de otra instancia se siguen consultando Redis.
+func (h *AnalysisJobHandler) StreamAnalysisJob(c *fiber.Ctx) error {
+	id := c.Params("id")
+	job, err := h.jobService.GetJob(id)
+	if err != nil {
+		return analysisJobError(c, err)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := writeJobEvent(w, job); err != nil || job.Finished() {
+			return
+		}
+
+		events, cancel, local := h.jobService.Subscribe(id)
+		defer cancel()
+		if !local {
+			h.pollJob(w, job)
+			return
+		}
+
+		heartbeat := time.NewTicker(sseHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case event, open := <-events:
+				if !open {
+					// Terminó: el estado final se lee completo
+					if final, err := h.jobService.GetJob(id); err == nil {
+						writeJobEvent(w, final)
+					}
+					return
+				}
+				if event.Job.Finished() {
+					continue
+				}
+				if err := writeJobEvent(w, event.Job); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if err := writeSSE(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+		}
+	})
+	return nil
+}
+
+// pollJob sigue un trabajo de otra instancia consultando Redis
+func (h *AnalysisJobHandler) pollJob(w *bufio.Writer, last *services.AnalysisJob) {
+	ticker := time.NewTicker(ssePollInterval)
+	defer ticker.Stop()
+	started := time.Now()
+	lastBeat := started
+
+	for time.Since(started) < sseMaxDuration {
+		<-ticker.C
+		job, err := h.jobService.GetJob(last.ID)
+		if err != nil {
+			return
+		}
+		if job.Status != last.Status || job.Progress != last.Progress {
+			if err := writeJobEvent(w, job); err != nil || job.Finished() {
+				return
+			}
+			last = job
+			lastBeat = time.Now()
+			continue
+		}
+		if time.Since(lastBeat) >= sseHeartbeat {
+			if err := writeSSE(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			lastBeat = time.Now()
+		}
+	}
+}
+
+// writeJobEvent escribe el estado del trabajo como evento SSE
+func writeJobEvent(w *bufio.Writer, job *services.AnalysisJob) error {
+	eventType := services.EventProgress
+	switch job.Status {
+	case services.JobCompleted:
+		eventType = services.EventCompleted
+	case services.JobFailed:
+		eventType = services.EventFailed
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return writeSSE(w, fmt.Sprintf("event: %s\ndata: %s\n\n", eventType, data))
+}
+
+// writeSSE escribe y envía de inmediato; falla si el cliente se desconectó
+func writeSSE(w *bufio.Writer, payload string) error {
+	if _, err := w.WriteString(payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// analysisJobError responde 404 para trabajos inexistentes y 500 en otro caso
+func analysisJobError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	if errors.Is(err, services.ErrJobNotFound) {
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   true,
+		"message": err.Error(),
+	})
+}
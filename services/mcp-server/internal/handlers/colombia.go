@@ -2,389 +2,242 @@ package handlers
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"mcp-server/internal/models"
+	"mcp-server/pkg/colombia"
 	"mcp-server/pkg/errors"
 )
 
 // Temporal: usar imports para evitar errores de compilación
 var _ = errors.NewPaywallError
 
-// ValidateNIT valida el NIT colombiano
-func ValidateNIT(c *fiber.Ctx) error {
+// ValidateCC valida la cédula de ciudadanía colombiana
+func ValidateCC(c *fiber.Ctx) error {
 	var request struct {
-		NIT string `json:"nit" validate:"required"`
+		CC string `json:"cc" validate:"required"`
 	}
 
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
-			"message": "NIT requerido",
+			"message": "Cédula requerida",
 		})
 	}
 
-	// Limpiar NIT (remover espacios, guiones, puntos)
-	cleanNIT := strings.ReplaceAll(request.NIT, " ", "")
-	cleanNIT = strings.ReplaceAll(cleanNIT, "-", "")
-	cleanNIT = strings.ReplaceAll(cleanNIT, ".", "")
-
-	// Validar formato básico
-	if len(cleanNIT) < 9 || len(cleanNIT) > 10 {
+	doc, err := colombia.ValidateDocument(colombia.DocCedulaCiudadania, request.CC)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
-			"message": "NIT debe tener entre 9 y 10 dígitos",
+			"message": err.Error(),
 			"valid":   false,
 		})
 	}
 
-	// Separar número y dígito de verificación
-	nitNumber := cleanNIT[:len(cleanNIT)-1]
-	checkDigit := cleanNIT[len(cleanNIT)-1:]
-
-	// Calcular dígito de verificación
-	calculatedDigit := calculateNITCheckDigit(nitNumber)
-	isValid := checkDigit == calculatedDigit
-
-	response := map[string]interface{}{
-		"valid":           isValid,
-		"nit":            cleanNIT,
-		"formatted_nit":  formatNIT(cleanNIT),
-		"check_digit":    checkDigit,
-		"calculated":     calculatedDigit,
-	}
-
-	if !isValid {
-		response["error"] = true
-		response["message"] = fmt.Sprintf("NIT inválido. Dígito de verificación debería ser %s", calculatedDigit)
-		return c.Status(fiber.StatusBadRequest).JSON(response)
-	}
-
-	// Si es válido, agregar información adicional
-	response["message"] = "NIT válido"
-	response["company_info"] = map[string]string{
-		"status": "Activo", // En producción consultaría RUES
-		"type":   "Sociedad por Acciones Simplificada", // Ejemplo
-	}
-
-	return c.JSON(response)
+	return c.JSON(fiber.Map{
+		"valid":        true,
+		"cc":           doc.Number,
+		"formatted_cc": doc.Formatted,
+		"message":      "Cédula válida",
+		"info": map[string]string{
+			"document_type": doc.TypeName,
+			"country":       "Colombia",
+		},
+	})
 }
 
-// ValidateCC valida la cédula de ciudadanía colombiana
-func ValidateCC(c *fiber.Ctx) error {
+// ValidateIdentityDocument valida cualquier documento de identificación DIAN
+// (CC, CE, TI, RC, PA, PEP, PPT, NIT, NIT de otro país, NUIP)
+func ValidateIdentityDocument(c *fiber.Ctx) error {
 	var request struct {
-		CC string `json:"cc" validate:"required"`
+		DocumentType   string `json:"document_type" validate:"required"`
+		DocumentNumber string `json:"document_number" validate:"required"`
 	}
 
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
-			"message": "Cédula requerida",
+			"message": "Documento requerido",
 		})
 	}
 
-	// Limpiar cédula
-	cleanCC := strings.ReplaceAll(request.CC, ".", "")
-	cleanCC = strings.ReplaceAll(cleanCC, ",", "")
-	cleanCC = strings.ReplaceAll(cleanCC, " ", "")
-
-	// Validaciones básicas
-	if len(cleanCC) < 6 || len(cleanCC) > 10 {
+	doc, err := colombia.ValidateDocument(request.DocumentType, request.DocumentNumber)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
-			"message": "Cédula debe tener entre 6 y 10 dígitos",
+			"message": err.Error(),
 			"valid":   false,
 		})
 	}
 
-	// Verificar que solo contenga números
-	if _, err := strconv.Atoi(cleanCC); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   true,
-			"message": "Cédula debe contener solo números",
-			"valid":   false,
-		})
-	}
-
-	// Formatear cédula con puntos
-	formattedCC := formatCC(cleanCC)
-
 	return c.JSON(fiber.Map{
-		"valid":        true,
-		"cc":          cleanCC,
-		"formatted_cc": formattedCC,
-		"message":     "Cédula válida",
-		"info": map[string]string{
-			"document_type": "Cédula de Ciudadanía",
-			"country":      "Colombia",
-		},
+		"success": true,
+		"valid":   true,
+		"data":    doc,
 	})
 }
 
-// CreateDIANInvoice crea una factura electrónica DIAN
-func CreateDIANInvoice(c *fiber.Ctx) error {
-	tenant := c.Locals("tenant").(*models.Tenant)
-	_ = tenant // Temporal para evitar error de compilación
+// Helper types and functions
 
-	var request struct {
-		CustomerNIT     string  `json:"customer_nit" validate:"required"`
-		CustomerName    string  `json:"customer_name" validate:"required"`
-		Items          []InvoiceItem `json:"items" validate:"required"`
-		PaymentMethod   string  `json:"payment_method" validate:"required"`
-		Notes          string  `json:"notes,omitempty"`
-	}
+type InvoiceItem struct {
+	Description string  `json:"description" validate:"required"`
+	Quantity    int     `json:"quantity" validate:"required,min=1"`
+	UnitPrice   int     `json:"unit_price_cop" validate:"required,min=1"`
+	IVARate     float64 `json:"iva_rate" validate:"oneof=0 5 19"`
+	IVACategory string  `json:"iva_category,omitempty"` // general, reducido, exento, excluido (prevalece sobre iva_rate)
+	INCCategory string  `json:"inc_category,omitempty"` // comidas, telefonia, vehiculos, lujo
+}
 
-	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   true,
-			"message": "Datos de factura inválidos",
+// SearchCities busca municipios en el catálogo DIVIPOLA (?q=cali valle, ?department=antioquia)
+func SearchCities(c *fiber.Ctx) error {
+	if department := c.Query("department"); department != "" && c.Query("q") == "" {
+		dept, ok := colombia.FindDepartment(department)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "Departamento no encontrado",
+			})
+		}
+		return c.JSON(fiber.Map{
+			"success": true,
+			"data": fiber.Map{
+				"department":     dept,
+				"municipalities": colombia.Municipalities(dept.Code),
+			},
 		})
 	}
 
-	// Calcular totales
-	subtotal := 0
-	totalIVA := 0
-	
-	for _, item := range request.Items {
-		itemSubtotal := item.Quantity * item.UnitPrice
-		itemIVA := int(float64(itemSubtotal) * item.IVARate / 100)
-		
-		subtotal += itemSubtotal
-		totalIVA += itemIVA
+	query := c.Query("q")
+	if strings.TrimSpace(query) == "" {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"data":    colombia.Departments(),
+		})
 	}
-
-	total := subtotal + totalIVA
-
-	// Generar número de factura (en producción sería secuencial desde DIAN)
-	invoiceNumber := fmt.Sprintf("FE-%d", 1000+len(request.Items))
-
-	// Simular respuesta de DIAN
-	invoice := map[string]interface{}{
-		"invoice_number":    invoiceNumber,
-		"cufe":             "a1b2c3d4e5f6789012345678901234567890abcd", // CUFE simulado
-		"qr_code":          "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg==",
-		"status":           "approved",
-		"customer": map[string]string{
-			"nit":  request.CustomerNIT,
-			"name": request.CustomerName,
-		},
-		"totals": map[string]int{
-			"subtotal_cop": subtotal,
-			"iva_cop":      totalIVA,
-			"total_cop":    total,
-		},
-		"payment_method": request.PaymentMethod,
-		"issued_at":     "2024-01-15T10:30:00-05:00",
-		"due_date":      "2024-01-22T23:59:59-05:00",
-		"pdf_url":       "https://api.tause.pro/invoices/" + invoiceNumber + ".pdf",
-		"xml_url":       "https://api.tause.pro/invoices/" + invoiceNumber + ".xml",
+	if department := c.Query("department"); department != "" {
+		query += " " + department
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+	return c.JSON(fiber.Map{
 		"success": true,
-		"message": "Factura DIAN creada exitosamente",
-		"data":    invoice,
+		"data":    colombia.SearchMunicipalities(query, c.QueryInt("limit", 10)),
 	})
 }
 
-// ProcessPSEPayment procesa un pago PSE
-func ProcessPSEPayment(c *fiber.Ctx) error {
+// NormalizeAddress interpreta una dirección colombiana y retorna sus componentes
+func NormalizeAddress(c *fiber.Ctx) error {
 	var request struct {
-		Amount      int    `json:"amount_cop" validate:"required,min=1000"`
-		Description string `json:"description" validate:"required"`
-		CustomerEmail string `json:"customer_email" validate:"required,email"`
-		Bank        string `json:"bank" validate:"required"`
-		DocumentType string `json:"document_type" validate:"required,oneof=CC CE TI"`
-		DocumentNumber string `json:"document_number" validate:"required"`
+		Address string `json:"address" validate:"required"`
 	}
 
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
-			"message": "Datos de pago inválidos",
+			"message": "Dirección requerida",
 		})
 	}
 
-	// Simular integración con PSE/Wompi
-	paymentReference := fmt.Sprintf("PSE_%d_%s", request.Amount, strings.ToUpper(request.Bank))
-	
-	// Simular respuesta de PSE
-	payment := map[string]interface{}{
-		"reference":        paymentReference,
-		"status":          "pending",
-		"amount_cop":      request.Amount,
-		"currency":        "COP",
-		"payment_method":  "PSE",
-		"bank":           request.Bank,
-		"customer": map[string]string{
-			"email":           request.CustomerEmail,
-			"document_type":   request.DocumentType,
-			"document_number": request.DocumentNumber,
-		},
-		"urls": map[string]string{
-			"payment_url": "https://pse.redeban.com.co/pay/" + paymentReference,
-			"success_url": "https://app.tause.pro/payment/success",
-			"cancel_url":  "https://app.tause.pro/payment/cancel",
-		},
-		"expires_at": "2024-01-15T11:30:00-05:00", // 1 hora para completar
-		"created_at": "2024-01-15T10:30:00-05:00",
+	address, err := colombia.ParseAddress(request.Address)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+	return c.JSON(fiber.Map{
 		"success": true,
-		"message": "Pago PSE iniciado. Redirige al cliente a payment_url",
-		"data":    payment,
+		"data":    address,
 	})
 }
 
-// CreateShipment crea un envío con Servientrega
-func CreateShipment(c *fiber.Ctx) error {
+// ValidatePhone valida un teléfono colombiano y lo retorna en E.164 y formato WhatsApp
+func ValidatePhone(c *fiber.Ctx) error {
 	var request struct {
-		OriginCity      string `json:"origin_city" validate:"required"`
-		DestinyCity     string `json:"destiny_city" validate:"required"`
-		RecipientName   string `json:"recipient_name" validate:"required"`
-		RecipientPhone  string `json:"recipient_phone" validate:"required"`
-		RecipientAddress string `json:"recipient_address" validate:"required"`
-		Weight          int    `json:"weight_grams" validate:"required,min=1"`
-		DeclaredValue   int    `json:"declared_value_cop" validate:"required,min=1000"`
-		PaymentType     string `json:"payment_type" validate:"required,oneof=origen destino"`
+		Phone string `json:"phone" validate:"required"`
 	}
 
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
-			"message": "Datos de envío inválidos",
+			"message": "Teléfono requerido",
 		})
 	}
 
-	// Calcular costo de envío (simulado)
-	shippingCost := calculateShippingCost(request.OriginCity, request.DestinyCity, request.Weight)
-	
-	// Generar número de guía
-	trackingNumber := fmt.Sprintf("SER%d%02d", 2024001, request.Weight%100)
-
-	// Simular respuesta de Servientrega
-	shipment := map[string]interface{}{
-		"tracking_number": trackingNumber,
-		"status":         "created",
-		"origin":         request.OriginCity,
-		"destiny":        request.DestinyCity,
-		"recipient": map[string]string{
-			"name":    request.RecipientName,
-			"phone":   request.RecipientPhone,
-			"address": request.RecipientAddress,
-		},
-		"package": map[string]interface{}{
-			"weight_grams":        request.Weight,
-			"declared_value_cop":  request.DeclaredValue,
-		},
-		"costs": map[string]int{
-			"shipping_cop":        shippingCost,
-			"insurance_cop":       int(float64(request.DeclaredValue) * 0.001), // 0.1%
-			"total_cop":          shippingCost + int(float64(request.DeclaredValue) * 0.001),
-		},
-		"estimated_delivery": "2024-01-17", // 2 días hábiles
-		"payment_type":       request.PaymentType,
-		"created_at":        "2024-01-15T10:30:00-05:00",
-		"tracking_url":      "https://www.servientrega.com/rastreo/" + trackingNumber,
+	phone, err := colombia.ParsePhone(request.Phone)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+			"valid":   false,
+		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+	return c.JSON(fiber.Map{
 		"success": true,
-		"message": "Envío creado exitosamente",
-		"data":    shipment,
+		"valid":   true,
+		"data":    phone,
 	})
 }
 
-// Helper types and functions
-
-type InvoiceItem struct {
-	Description string  `json:"description" validate:"required"`
-	Quantity    int     `json:"quantity" validate:"required,min=1"`
-	UnitPrice   int     `json:"unit_price_cop" validate:"required,min=1"`
-	IVARate     float64 `json:"iva_rate" validate:"required,oneof=0 5 19"`
-}
-
-func calculateNITCheckDigit(nit string) string {
-	// Algoritmo de cálculo de dígito de verificación NIT Colombia
-	weights := []int{3, 7, 13, 17, 19, 23, 29, 37, 41, 43, 47, 53, 59, 67, 71}
-	sum := 0
-	
-	for i, digit := range nit {
-		if d, err := strconv.Atoi(string(digit)); err == nil {
-			sum += d * weights[len(weights)-len(nit)+i]
+// SearchCIIU consulta el catálogo CIIU (?code=4771, ?section=G)
+func SearchCIIU(c *fiber.Ctx) error {
+	if code := c.Query("code"); code != "" {
+		activity, ok := colombia.LookupCIIU(code)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "Código CIIU no encontrado",
+			})
 		}
+		return c.JSON(fiber.Map{
+			"success": true,
+			"data":    activity,
+		})
 	}
-	
-	remainder := sum % 11
-	if remainder < 2 {
-		return strconv.Itoa(remainder)
-	}
-	return strconv.Itoa(11 - remainder)
-}
 
-func formatNIT(nit string) string {
-	if len(nit) < 9 {
-		return nit
-	}
-	// Formato: 900.123.456-7
-	digits := nit[:len(nit)-1]
-	checkDigit := nit[len(nit)-1:]
-	
-	formatted := ""
-	for i, d := range digits {
-		if i > 0 && (len(digits)-i)%3 == 0 {
-			formatted += "."
-		}
-		formatted += string(d)
+	if section := c.Query("section"); section != "" {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"data":    colombia.CIIUActivities(section),
+		})
 	}
-	
-	return formatted + "-" + checkDigit
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    colombia.CIIUSections(),
+	})
 }
 
-func formatCC(cc string) string {
-	// Formato: 1.234.567
-	formatted := ""
-	for i, d := range cc {
-		if i > 0 && (len(cc)-i)%3 == 0 {
-			formatted += "."
-		}
-		formatted += string(d)
+// ClassifyCIIU sugiere códigos CIIU para la descripción de un negocio
+func ClassifyCIIU(c *fiber.Ctx) error {
+	var request struct {
+		Description string `json:"description" validate:"required"`
+		Limit       int    `json:"limit"`
 	}
-	return formatted
-}
 
-func calculateShippingCost(origin, destiny string, weight int) int {
-	// Costos base por ciudad (simulados)
-	baseCosts := map[string]map[string]int{
-		"Bogotá": {
-			"Medellín":     12000,
-			"Cali":         15000,
-			"Barranquilla": 18000,
-			"Cartagena":    20000,
-		},
-		"Medellín": {
-			"Bogotá":       12000,
-			"Cali":         10000,
-			"Barranquilla": 16000,
-		},
+	if err := c.BodyParser(&request); err != nil || strings.TrimSpace(request.Description) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Descripción de la actividad requerida",
+		})
 	}
-	
-	// Costo base
-	cost := 8000 // Costo mínimo
-	if originCosts, exists := baseCosts[origin]; exists {
-		if destinyCost, exists := originCosts[destiny]; exists {
-			cost = destinyCost
-		}
+	if request.Limit <= 0 {
+		request.Limit = 5
 	}
-	
-	// Agregar costo por peso (cada 100g adicionales después de 500g)
-	if weight > 500 {
-		extraWeight := (weight - 500) / 100
-		cost += extraWeight * 500
+
+	matches := colombia.ClassifyActivity(request.Description, request.Limit)
+	message := "No se encontró una actividad CIIU para la descripción"
+	if len(matches) > 0 {
+		message = fmt.Sprintf("Actividad sugerida: %s %s", matches[0].Code, matches[0].Description)
+		if matches[0].Confidence < colombia.MinCIIUConfidence {
+			message += " (confianza baja, revise las alternativas)"
+		}
 	}
-	
-	return cost
-} 
\ No newline at end of file
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    matches,
+		"message": message,
+	})
+}
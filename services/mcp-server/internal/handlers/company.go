@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"mcp-server/internal/models"
+	"mcp-server/pkg/colombia"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CompanyHandler consulta empresas por NIT en el RUT/RUES
+type CompanyHandler struct {
+	colombiaService *colombia.Service
+}
+
+// NewCompanyHandler crea una nueva instancia del handler
+func NewCompanyHandler(colombiaService *colombia.Service) *CompanyHandler {
+	return &CompanyHandler{
+		colombiaService: colombiaService,
+	}
+}
+
+// ValidateNIT valida el NIT colombiano y lo enriquece con los datos del registro
+func (h *CompanyHandler) ValidateNIT(c *fiber.Ctx) error {
+	var request struct {
+		NIT string `json:"nit" validate:"required"`
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "NIT requerido",
+		})
+	}
+
+	doc, err := colombia.ValidateDocument(colombia.DocNIT, request.NIT)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+			"valid":   false,
+		})
+	}
+
+	response := fiber.Map{
+		"valid":         true,
+		"nit":           doc.Number + doc.CheckDigit,
+		"formatted_nit": doc.Formatted,
+		"check_digit":   doc.CheckDigit,
+		"calculated":    doc.CheckDigit,
+		"message":       "NIT válido",
+	}
+
+	// El registro es informativo: el NIT sigue siendo válido si no responde
+	company, err := h.colombiaService.LookupCompany(c.Context(), request.NIT)
+	switch {
+	case err == nil:
+		response["company"] = company
+		if !company.Active() {
+			response["message"] = "NIT válido, pero la empresa figura como " + company.Status
+		}
+	case errors.Is(err, colombia.ErrCompanyNotFound):
+		response["message"] = "NIT válido, no encontrado en el registro de empresas"
+	default:
+		response["registry_error"] = err.Error()
+	}
+
+	return c.JSON(response)
+}
+
+// LookupCompany retorna los datos registrales de una empresa
+func (h *CompanyHandler) LookupCompany(c *fiber.Ctx) error {
+	company, status, err := h.lookup(c)
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    company,
+	})
+}
+
+// PrefillOnboarding propone la configuración del tenant a partir del registro
+// de la empresa, para que el onboarding solo pida confirmarla
+func (h *CompanyHandler) PrefillOnboarding(c *fiber.Ctx) error {
+	company, status, err := h.lookup(c)
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	message := "Datos de la empresa precargados desde el registro"
+	if !company.Active() {
+		message = "La empresa figura como " + company.Status + " en el registro; verifique los datos"
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"company":  company,
+			"settings": companyTenantSettings(company),
+		},
+		"message": message,
+	})
+}
+
+func (h *CompanyHandler) lookup(c *fiber.Ctx) (*colombia.CompanyRecord, int, error) {
+	company, err := h.colombiaService.LookupCompany(c.Context(), c.Params("nit"))
+	switch {
+	case err == nil:
+		return company, fiber.StatusOK, nil
+	case errors.Is(err, colombia.ErrCompanyNotFound):
+		return nil, fiber.StatusNotFound, err
+	case errors.As(err, new(*colombia.RegistryError)):
+		return nil, fiber.StatusBadGateway, err
+	default:
+		return nil, fiber.StatusBadRequest, err
+	}
+}
+
+// companyTenantSettings llena la configuración del tenant con los datos del RUT
+func companyTenantSettings(company *colombia.CompanyRecord) models.TenantSettings {
+	settings := models.TenantSettings{
+		Country:           "CO",
+		Currency:          "COP",
+		Timezone:          "America/Bogota",
+		Language:          "es",
+		NITNumber:         company.NIT + "-" + company.CheckDigit,
+		City:              company.City,
+		CityCode:          company.CityCode,
+		PersonType:        company.PersonType,
+		ResponsableIVA:    company.HasResponsibility(colombia.RespIVA),
+		GranContribuyente: company.HasResponsibility(colombia.RespGranContribuyente),
+		Autorretenedor:    company.HasResponsibility(colombia.RespAutorretenedor),
+		RegimenSimple:     company.HasResponsibility(colombia.RespRegimenSimple),
+		BusinessName:      company.LegalName,
+		BusinessAddress:   company.Address,
+		BusinessPhone:     company.Phone,
+		BusinessEmail:     company.Email,
+	}
+	if activity, ok := company.PrimaryActivity(); ok {
+		settings.CIIU = activity.Code
+	}
+	if address, err := colombia.ParseAddress(company.Address); err == nil {
+		settings.BusinessAddress = address.Canonical
+	}
+	if phone, err := colombia.ParsePhone(company.Phone); err == nil {
+		settings.BusinessPhone = phone.E164
+		if phone.Type == colombia.PhoneMobile {
+			settings.WhatsAppNumber = phone.E164
+		}
+	}
+	return settings
+}
@@ -0,0 +1,775 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"mcp-server/internal/models"
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/dian"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DIANHandler maneja la emisión de documentos electrónicos DIAN
+type DIANHandler struct {
+	dianService     *dian.Service
+	colombiaService *colombia.Service
+}
+
+// NewDIANHandler crea una nueva instancia del handler
+func NewDIANHandler(dianService *dian.Service, colombiaService *colombia.Service) *DIANHandler {
+	return &DIANHandler{
+		dianService:     dianService,
+		colombiaService: colombiaService,
+	}
+}
+
+// CreateInvoice crea una factura electrónica DIAN y la envía a validación
+func (h *DIANHandler) CreateInvoice(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		CustomerNIT          string        `json:"customer_nit" validate:"required"` // Número del documento del cliente
+		CustomerDocumentType string        `json:"customer_document_type,omitempty"` // Por defecto NIT
+		CustomerName         string        `json:"customer_name" validate:"required"`
+		CustomerEmail        string        `json:"customer_email,omitempty"`
+		CustomerCity         string        `json:"customer_city,omitempty"`
+		CustomerAddress      string        `json:"customer_address,omitempty"`
+		Items                []InvoiceItem `json:"items" validate:"required"`
+		PaymentMethod        string        `json:"payment_method" validate:"required"`
+		Notes                string        `json:"notes,omitempty"`
+		PaymentForm          string        `json:"payment_form,omitempty"` // contado (por defecto) o credito
+		DueDays              int           `json:"due_days,omitempty"`     // Plazo de la venta a crédito (por defecto 30 días)
+		// Retenciones que informa el cliente: retefuente y reteiva en %, reteica por mil
+		Withholdings []WithholdingItem `json:"withholdings,omitempty"`
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de factura inválidos",
+		})
+	}
+
+	if len(request.Items) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "La factura debe tener al menos un ítem",
+		})
+	}
+
+	issuer, err := dianIssuer(tenant)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Ciudad del emisor inválida: " + err.Error(),
+		})
+	}
+
+	issuedAt := time.Now().In(colombia.Location())
+	invoice := &dian.Invoice{
+		IssueDate: issuedAt,
+		DueDate:   invoiceDueDate(issuedAt),
+		Issuer:    issuer,
+		Customer: dian.Party{
+			Name:     request.CustomerName,
+			Email:    request.CustomerEmail,
+			TaxLevel: "R-99-PN",
+		},
+		PaymentMethod: dianPaymentMeansCode(request.PaymentMethod),
+		PaymentForm:   "1",
+		Notes:         request.Notes,
+	}
+	switch strings.ToLower(strings.TrimSpace(request.PaymentForm)) {
+	case "", "contado":
+	case "credito", "crédito":
+		dueDays := request.DueDays
+		if dueDays <= 0 {
+			dueDays = 30
+		}
+		invoice.PaymentForm = "2"
+		invoice.DueDate = colombia.NextBusinessDay(issuedAt.AddDate(0, 0, dueDays))
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Forma de pago inválida: use contado o credito",
+		})
+	}
+	if err := setPartyDocument(&invoice.Customer, request.CustomerDocumentType, colombia.DocNIT, request.CustomerNIT); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Documento del cliente inválido: " + err.Error(),
+		})
+	}
+	if err := setPartyCity(&invoice.Customer, request.CustomerCity); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Ciudad del cliente inválida: " + err.Error(),
+		})
+	}
+	if request.CustomerAddress != "" {
+		address, err := colombia.ParseAddress(request.CustomerAddress)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "Dirección del cliente inválida: " + err.Error(),
+			})
+		}
+		invoice.Customer.Address = address.Canonical
+	}
+	params, err := h.colombiaService.Fiscal().Current()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	for _, item := range request.Items {
+		line, err := invoiceLineFromItem(params, item)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		}
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	if len(request.Withholdings) > 0 {
+		invoice.Compute()
+		withholdings, err := reportedWithholdings(invoice, request.Withholdings)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		}
+		invoice.Withholdings = withholdings
+	}
+
+	submission, err := h.dianService.IssueInvoice(c.Context(), dianConfigForTenant(tenant), invoice)
+	if err != nil {
+		return dianIssueError(c, "Error enviando la factura a la DIAN: ", err)
+	}
+
+	status := fiber.StatusCreated
+	message := "Factura DIAN creada exitosamente"
+	if submission.Status == dian.SubmissionRejected {
+		status = fiber.StatusUnprocessableEntity
+		message = "La DIAN rechazó la factura"
+	} else if submission.Status == dian.SubmissionPending {
+		status = fiber.StatusAccepted
+		message = "Factura enviada a la DIAN, pendiente de validación"
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": submission.Status != dian.SubmissionRejected,
+		"message": message,
+		"data":    invoiceResponse(submission, invoice, request.PaymentMethod),
+	})
+}
+
+// CreateSupportDocument emite un documento soporte por compras a no obligados a facturar
+func (h *DIANHandler) CreateSupportDocument(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		SupplierDocumentType string        `json:"supplier_document_type"`
+		SupplierID           string        `json:"supplier_id" validate:"required"`
+		SupplierName         string        `json:"supplier_name" validate:"required"`
+		SupplierCity         string        `json:"supplier_city,omitempty"`
+		NonResident          bool          `json:"non_resident,omitempty"`
+		Items                []InvoiceItem `json:"items" validate:"required"`
+		PaymentMethod        string        `json:"payment_method"`
+		Notes                string        `json:"notes,omitempty"`
+		// Concepto de retención (compras, servicios, honorarios...): si se indica
+		// se liquidan las retenciones que el tenant practica al vendedor
+		WithholdingConcept string `json:"withholding_concept,omitempty"`
+		SupplierDeclarante bool   `json:"supplier_declarante,omitempty"`
+		SupplierActivity   string `json:"supplier_activity,omitempty"` // CIIU o descripción, para la tarifa de ICA
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de documento soporte inválidos",
+		})
+	}
+
+	if request.SupplierID == "" || len(request.Items) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "El documento soporte requiere vendedor y al menos un ítem",
+		})
+	}
+
+	acquirer, err := dianIssuer(tenant)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Ciudad del adquiriente inválida: " + err.Error(),
+		})
+	}
+
+	doc := &dian.SupportDocument{
+		IssueDate: time.Now(),
+		Acquirer:  acquirer,
+		Supplier: dian.Party{
+			Name:     request.SupplierName,
+			TaxLevel: "R-99-PN",
+		},
+		NonResident:   request.NonResident,
+		PaymentMethod: dianPaymentMeansCode(request.PaymentMethod),
+		PaymentForm:   "1",
+		Notes:         request.Notes,
+		Concept:       request.WithholdingConcept,
+	}
+	if err := setPartyDocument(&doc.Supplier, request.SupplierDocumentType, colombia.DocCedulaCiudadania, request.SupplierID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Documento del vendedor inválido: " + err.Error(),
+		})
+	}
+	if !request.NonResident {
+		if err := setPartyCity(&doc.Supplier, request.SupplierCity); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "Ciudad del vendedor inválida: " + err.Error(),
+			})
+		}
+	}
+	params, err := h.colombiaService.Fiscal().Current()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	for _, item := range request.Items {
+		line, err := invoiceLineFromItem(params, item)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		}
+		doc.Lines = append(doc.Lines, line)
+	}
+	if request.WithholdingConcept != "" && !request.NonResident {
+		withholdings, err := h.supportDocumentWithholdings(tenant, doc, request.SupplierDeclarante, request.SupplierActivity)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "No se pudieron liquidar las retenciones: " + err.Error(),
+			})
+		}
+		doc.Withholdings = withholdings
+	}
+
+	submission, err := h.dianService.IssueSupportDocument(c.Context(), dianConfigForTenant(tenant), doc)
+	if err != nil {
+		return dianIssueError(c, "Error enviando el documento soporte a la DIAN: ", err)
+	}
+
+	return h.submissionResponse(c, submission, "Documento soporte emitido exitosamente")
+}
+
+// CreatePayroll emite un documento de nómina electrónica individual
+func (h *DIANHandler) CreatePayroll(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		Employee           dian.PayrollEmployee `json:"employee" validate:"required"`
+		PeriodStart        string               `json:"period_start" validate:"required"` // YYYY-MM-DD
+		PeriodEnd          string               `json:"period_end" validate:"required"`
+		PaymentDate        string               `json:"payment_date,omitempty"`
+		PayrollPeriod      string               `json:"payroll_period,omitempty"`
+		DaysWorked         int                  `json:"days_worked" validate:"required"`
+		TransportAllowance int                  `json:"transport_allowance_cop,omitempty"`
+		OtherEarnings      []dian.PayrollItem   `json:"other_earnings,omitempty"`
+		OtherDeductions    []dian.PayrollItem   `json:"other_deductions,omitempty"`
+		PaymentMethod      string               `json:"payment_method,omitempty"`
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de nómina inválidos",
+		})
+	}
+
+	periodStart, errStart := time.Parse("2006-01-02", request.PeriodStart)
+	periodEnd, errEnd := time.Parse("2006-01-02", request.PeriodEnd)
+	if errStart != nil || errEnd != nil || periodEnd.Before(periodStart) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Periodo de liquidación inválido (formato YYYY-MM-DD)",
+		})
+	}
+	if request.Employee.ID == "" || request.Employee.Salary <= 0 || request.DaysWorked <= 0 || request.DaysWorked > 30 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "La nómina requiere trabajador, salario y días trabajados (1-30)",
+		})
+	}
+
+	paymentDate := periodEnd
+	if request.PaymentDate != "" {
+		if parsed, err := time.Parse("2006-01-02", request.PaymentDate); err == nil {
+			paymentDate = parsed
+		}
+	}
+
+	employee := request.Employee
+	if employee.WorkerType == "" {
+		employee.WorkerType = "01"
+	}
+	if employee.WorkerSubType == "" {
+		employee.WorkerSubType = "00"
+	}
+	if employee.DocumentType == "" {
+		employee.DocumentType = colombia.DocCedulaCiudadania
+	}
+	employeeDoc, err := colombia.ValidateDocument(employee.DocumentType, employee.ID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Documento del trabajador inválido: " + err.Error(),
+		})
+	}
+	employee.DocumentType = employeeDoc.TypeCode
+	employee.ID = employeeDoc.Number
+	if employee.ContractType == "" {
+		employee.ContractType = "2"
+	}
+
+	transport := request.TransportAllowance
+	if transport == 0 {
+		// Auxilio de transporte para quienes devengan hasta dos salarios mínimos
+		params, err := h.colombiaService.Fiscal().At(periodEnd)
+		if err == nil && !employee.IntegralSalary && employee.Salary <= 2*params.MinimumWage {
+			transport = params.TransportAllowance * request.DaysWorked / 30
+		}
+	}
+
+	period := request.PayrollPeriod
+	if period == "" {
+		period = "5" // Mensual
+	}
+
+	employer, err := dianIssuer(tenant)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Ciudad del empleador inválida: " + err.Error(),
+		})
+	}
+
+	payroll := &dian.Payroll{
+		IssueDate:          time.Now(),
+		PeriodStart:        periodStart,
+		PeriodEnd:          periodEnd,
+		PaymentDate:        paymentDate,
+		PayrollPeriod:      period,
+		Employer:           employer,
+		Employee:           employee,
+		DaysWorked:         request.DaysWorked,
+		TransportAllowance: transport,
+		OtherEarnings:      request.OtherEarnings,
+		OtherDeductions:    request.OtherDeductions,
+		PaymentMethod:      dianPaymentMeansCode(request.PaymentMethod),
+	}
+
+	submission, err := h.dianService.IssuePayroll(c.Context(), dianConfigForTenant(tenant), payroll)
+	if err != nil {
+		return dianIssueError(c, "Error enviando la nómina a la DIAN: ", err)
+	}
+
+	return h.submissionResponse(c, submission, "Nómina electrónica emitida exitosamente")
+}
+
+// GetDocument consulta un documento emitido y opcionalmente refresca su estado en la DIAN
+func (h *DIANHandler) GetDocument(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	submission, err := h.dianService.GetSubmission(tenant.ID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	if c.QueryBool("refresh") || submission.Status == dian.SubmissionPending {
+		submission, err = h.dianService.RefreshStatus(c.Context(), dianConfigForTenant(tenant), submission.ID)
+		if err != nil {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error":   true,
+				"code":    "DIAN_API_ERROR",
+				"message": err.Error(),
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    submission,
+	})
+}
+
+// GetDocumentXML descarga el XML generado de un documento
+func (h *DIANHandler) GetDocumentXML(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	submission, err := h.dianService.GetSubmission(tenant.ID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", "application/xml")
+	c.Set("Content-Disposition", "attachment; filename="+strings.TrimSuffix(submission.FileName, ".zip")+".xml")
+	return c.Send(submission.XML)
+}
+
+// ListDocuments lista los documentos electrónicos emitidos por el tenant
+func (h *DIANHandler) ListDocuments(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+	submissions := h.dianService.ListSubmissions(tenant.ID)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"documents": submissions,
+			"total":     len(submissions),
+		},
+	})
+}
+
+// Helper functions
+
+// submissionResponse responde según el estado del envío a la DIAN
+func (h *DIANHandler) submissionResponse(c *fiber.Ctx, submission *dian.Submission, createdMessage string) error {
+	status := fiber.StatusCreated
+	message := createdMessage
+	switch submission.Status {
+	case dian.SubmissionRejected:
+		status = fiber.StatusUnprocessableEntity
+		message = "La DIAN rechazó el documento"
+	case dian.SubmissionPending:
+		status = fiber.StatusAccepted
+		message = "Documento enviado a la DIAN, pendiente de validación"
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": submission.Status != dian.SubmissionRejected,
+		"message": message,
+		"data":    submission,
+	})
+}
+
+// dianIssueError responde el error de una emisión: 501 si el tenant está
+// configurado para un ambiente de la DIAN distinto al simulador y 502 si
+// falló el envío
+func dianIssueError(c *fiber.Ctx, prefix string, err error) error {
+	if errors.Is(err, dian.ErrEnvironmentUnsupported) {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+			"error":   true,
+			"code":    "DIAN_ENVIRONMENT_UNSUPPORTED",
+			"message": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+		"error":   true,
+		"code":    "DIAN_API_ERROR",
+		"message": prefix + err.Error(),
+	})
+}
+
+// dianConfigForTenant arma la configuración DIAN del tenant. Sin ambiente
+// configurado el tenant opera contra el simulador, tenga o no DIANAPIKey
+// (PIN del software); habilitación y producción aún no están soportados.
+func dianConfigForTenant(tenant *models.Tenant) dian.TenantConfig {
+	settings := tenant.Settings
+	cfg := dian.TenantConfig{
+		TenantID:    tenant.ID,
+		Environment: dian.Environment(settings.DIANEnvironment),
+		SoftwareID:  settings.DIANSoftwareID,
+		SoftwarePIN: settings.DIANAPIKey,
+	}
+
+	if cfg.Environment == "" {
+		cfg.Environment = dian.EnvironmentSimulator
+	}
+
+	if settings.DIANInvoicePrefix != "" {
+		cfg.InvoiceNumbering = dian.NumberingRange{
+			Resolution:   settings.DIANResolution,
+			Prefix:       settings.DIANInvoicePrefix,
+			From:         settings.DIANRangeFrom,
+			To:           settings.DIANRangeTo,
+			TechnicalKey: settings.DIANTechnicalKey,
+		}
+	}
+
+	if settings.DIANSupportPrefix != "" {
+		cfg.SupportNumbering = dian.NumberingRange{
+			Resolution: settings.DIANSupportResolution,
+			Prefix:     settings.DIANSupportPrefix,
+			From:       settings.DIANSupportRangeFrom,
+			To:         settings.DIANSupportRangeTo,
+		}
+	}
+
+	if settings.DIANPayrollPrefix != "" {
+		cfg.PayrollNumbering = dian.DefaultPayrollNumbering
+		cfg.PayrollNumbering.Prefix = settings.DIANPayrollPrefix
+	}
+
+	return cfg
+}
+
+// invoiceDueDate vence a los 7 días; si cae en fin de semana o festivo se
+// traslada al siguiente día hábil
+func invoiceDueDate(issuedAt time.Time) time.Time {
+	return colombia.NextBusinessDay(issuedAt.AddDate(0, 0, 7))
+}
+
+// dianIssuer construye la parte emisora con el NIT y la ciudad del tenant validados
+func dianIssuer(tenant *models.Tenant) (dian.Party, error) {
+	name := tenant.Settings.BusinessName
+	if name == "" {
+		name = tenant.Name
+	}
+	party := dian.Party{
+		Name:     name,
+		Email:    tenant.Settings.BusinessEmail,
+		Address:  tenant.Settings.BusinessAddress,
+		TaxLevel: "O-47",
+	}
+
+	if err := setPartyDocument(&party, colombia.DocNIT, colombia.DocNIT, tenant.Settings.NITNumber); err != nil {
+		return party, err
+	}
+	// La dirección configurada se normaliza cuando sigue la nomenclatura
+	if address, err := colombia.ParseAddress(party.Address); err == nil {
+		party.Address = address.Canonical
+	}
+
+	city := tenant.Settings.CityCode
+	if city == "" {
+		city = tenant.Settings.City
+	}
+	if city == "" {
+		return party, fmt.Errorf("el tenant no tiene ciudad configurada")
+	}
+	return party, setPartyCity(&party, city)
+}
+
+// setPartyDocument valida el documento de identificación y lo asigna
+// normalizado a la parte; sin tipo se usa defaultType
+func setPartyDocument(party *dian.Party, docType, defaultType, number string) error {
+	if docType == "" {
+		docType = defaultType
+	}
+	doc, err := colombia.ValidateDocument(docType, number)
+	if err != nil {
+		return err
+	}
+	party.DocumentType = doc.TypeCode
+	party.ID = doc.Number
+	party.CheckDigit = doc.CheckDigit
+	return nil
+}
+
+// setPartyCity resuelve la ciudad (nombre o código DIVIPOLA) y asigna el
+// código y nombre canónicos a la parte. Una ciudad vacía no se valida.
+func setPartyCity(party *dian.Party, city string) error {
+	if strings.TrimSpace(city) == "" {
+		return nil
+	}
+	municipality, err := colombia.ResolveMunicipality(city)
+	if err != nil {
+		return err
+	}
+	party.CityCode = municipality.Code
+	party.CityName = municipality.Name
+	return nil
+}
+
+// invoiceLineFromItem convierte un ítem en línea de documento, resolviendo
+// las tarifas de IVA e INC por categoría cuando se indican
+func invoiceLineFromItem(params *colombia.FiscalParameters, item InvoiceItem) (dian.InvoiceLine, error) {
+	line := dian.InvoiceLine{
+		Description: item.Description,
+		Quantity:    item.Quantity,
+		UnitPrice:   item.UnitPrice,
+		IVARate:     item.IVARate,
+	}
+
+	if item.IVACategory != "" {
+		rate, err := params.IVARate(item.IVACategory)
+		if err != nil {
+			return line, err
+		}
+		line.IVARate = rate
+	}
+	if item.INCCategory != "" {
+		rate, err := params.INCRate(item.INCCategory)
+		if err != nil {
+			return line, err
+		}
+		line.INCRate = rate
+		if item.INCCategory == colombia.INCComidas {
+			// El servicio de restaurante está excluido de IVA y grava con INC
+			line.IVARate = 0
+		}
+	}
+
+	return line, nil
+}
+
+// WithholdingItem retención informada por el cliente sobre una factura
+type WithholdingItem struct {
+	Type string  `json:"type"` // retefuente, reteiva, reteica
+	Rate float64 `json:"rate"`
+}
+
+// reportedWithholdings liquida las retenciones que informa el cliente sobre la
+// factura: la retención en la fuente y la de ICA sobre el subtotal (ICA por
+// mil) y la de IVA sobre el IVA facturado
+func reportedWithholdings(invoice *dian.Invoice, items []WithholdingItem) ([]dian.WithholdingAmount, error) {
+	var withholdings []dian.WithholdingAmount
+	for _, item := range items {
+		kind := strings.ToLower(strings.TrimSpace(item.Type))
+		if item.Rate <= 0 || item.Rate > 100 {
+			return nil, fmt.Errorf("tarifa de retención inválida: %.2f", item.Rate)
+		}
+		withholding := dian.WithholdingAmount{Type: kind, Rate: item.Rate}
+		switch kind {
+		case dian.WithholdingRenta:
+			withholding.Name = "Retención en la fuente"
+			withholding.Base = invoice.Subtotal
+			withholding.Amount = int(math.Round(float64(invoice.Subtotal) * item.Rate / 100))
+		case dian.WithholdingIVA:
+			withholding.Name = "Retención de IVA"
+			withholding.Base = invoice.TaxTotal(dian.TaxIVA)
+			withholding.Amount = int(math.Round(float64(withholding.Base) * item.Rate / 100))
+		case dian.WithholdingICA:
+			withholding.Name = "Retención de ICA"
+			withholding.Base = invoice.Subtotal
+			withholding.Amount = int(math.Round(float64(invoice.Subtotal) * item.Rate / 1000))
+		default:
+			return nil, fmt.Errorf("tipo de retención desconocido: %s (use retefuente, reteiva o reteica)", item.Type)
+		}
+		if withholding.Amount > 0 {
+			withholdings = append(withholdings, withholding)
+		}
+	}
+	return withholdings, nil
+}
+
+// supportDocumentWithholdings liquida las retenciones que el tenant practica
+// al vendedor no obligado a facturar según el concepto del documento soporte
+func (h *DIANHandler) supportDocumentWithholdings(tenant *models.Tenant, doc *dian.SupportDocument, declarante bool, activity string) ([]dian.WithholdingAmount, error) {
+	seller := colombia.TaxProfile{
+		PersonType: colombia.PersonNatural,
+		Declarante: declarante,
+		CityCode:   doc.Supplier.CityCode,
+	}
+	if doc.Supplier.DocumentType == colombia.DocNIT {
+		seller.PersonType = colombia.PersonJuridica
+	}
+	if _, ok := colombia.LookupCIIU(activity); ok {
+		seller.CIIU = activity
+	} else {
+		seller.Activity = activity
+	}
+
+	lines := make([]colombia.TaxLine, 0, len(doc.Lines))
+	for _, line := range doc.Lines {
+		lines = append(lines, colombia.TaxLine{
+			Description: line.Description,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+		})
+	}
+
+	result, err := h.colombiaService.CalculateTaxes(colombia.TaxRequest{
+		Seller:  seller,
+		Buyer:   taxProfileForTenant(tenant),
+		Lines:   lines,
+		Concept: doc.Concept,
+		Date:    doc.IssueDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	withholdings := make([]dian.WithholdingAmount, 0, len(result.Withholdings))
+	for _, withholding := range result.Withholdings {
+		withholdings = append(withholdings, dian.WithholdingAmount{
+			Type:   withholding.Type,
+			Name:   withholding.Name,
+			Rate:   withholding.Rate,
+			Base:   withholding.Base,
+			Amount: withholding.Amount,
+		})
+	}
+	return withholdings, nil
+}
+
+// dianPaymentMeansCode traduce el medio de pago al código de la lista DIAN
+func dianPaymentMeansCode(method string) string {
+	codes := map[string]string{
+		"efectivo":      "10",
+		"cheque":        "20",
+		"consignacion":  "42",
+		"transferencia": "47",
+		"pse":           "47",
+		"nequi":         "47",
+		"daviplata":     "47",
+		"tarjeta":       "48",
+		"credito":       "48",
+		"debito":        "49",
+	}
+	if code, exists := codes[strings.ToLower(strings.TrimSpace(method))]; exists {
+		return code
+	}
+	return "ZZZ" // Acuerdo mutuo
+}
+
+func invoiceResponse(submission *dian.Submission, invoice *dian.Invoice, paymentMethod string) map[string]interface{} {
+	response := map[string]interface{}{
+		"id":             submission.ID,
+		"invoice_number": submission.Number,
+		"cufe":           submission.DocumentKey,
+		"qr_code":        submission.QRCode,
+		"status":         submission.Status,
+		"environment":    submission.Environment,
+		"track_id":       submission.TrackID,
+		"dian_response":  submission.Response,
+		"customer": map[string]string{
+			"nit":  invoice.Customer.ID,
+			"name": invoice.Customer.Name,
+		},
+		"totals": map[string]int{
+			"subtotal_cop": invoice.Subtotal,
+			"iva_cop":      invoice.TaxTotal(dian.TaxIVA),
+			"total_cop":    invoice.Total,
+		},
+		"payment_method": paymentMethod,
+		"issued_at":      invoice.IssueDate.Format(time.RFC3339),
+		"due_date":       invoice.DueDate.Format(time.RFC3339),
+		"xml_url":        "/api/v1/colombia/documents/" + submission.ID + "/xml",
+	}
+	if len(invoice.Withholdings) > 0 {
+		response["withholdings"] = invoice.Withholdings
+	}
+	return response
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"mcp-server/internal/models"
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/exogena"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExogenaHandler reportes tributarios del tenant: formatos de información
+// exógena y resumen mensual de IVA y retenciones
+type ExogenaHandler struct {
+	exogenaService *exogena.Service
+}
+
+// NewExogenaHandler crea una nueva instancia del handler
+func NewExogenaHandler(exogenaService *exogena.Service) *ExogenaHandler {
+	return &ExogenaHandler{
+		exogenaService: exogenaService,
+	}
+}
+
+// GetExogenaOverview genera todos los formatos del año y resume sus totales
+func (h *ExogenaHandler) GetExogenaOverview(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	year, err := strconv.Atoi(c.Params("year"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Año gravable inválido",
+		})
+	}
+
+	profile := exogenaProfileForTenant(tenant)
+	var formats []fiber.Map
+	for _, format := range exogena.Formats() {
+		report, err := h.exogenaService.Generate(tenant.ID, year, format.Code, profile)
+		if err != nil {
+			return exogenaError(c, err)
+		}
+		formats = append(formats, fiber.Map{
+			"format":    report.Format,
+			"version":   report.Version,
+			"name":      report.Name,
+			"records":   len(report.Rows),
+			"total_cop": report.Total,
+			"file_name": report.FileName(1),
+			"notes":     report.Notes,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"year":    year,
+			"formats": formats,
+		},
+	})
+}
+
+// GetExogenaFormat genera un formato (?output=json|xml|csv, ?envio=1)
+func (h *ExogenaHandler) GetExogenaFormat(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	year, err := strconv.Atoi(c.Params("year"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Año gravable inválido",
+		})
+	}
+	sendNumber := c.QueryInt("envio", 1)
+	if sendNumber < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "El número de envío debe ser mayor que cero",
+		})
+	}
+
+	report, err := h.exogenaService.Generate(tenant.ID, year, c.Params("format"), exogenaProfileForTenant(tenant))
+	if err != nil {
+		return exogenaError(c, err)
+	}
+
+	switch c.Query("output", "json") {
+	case "xml":
+		c.Set("Content-Type", "application/xml; charset=ISO-8859-1")
+		c.Set("Content-Disposition", "attachment; filename="+report.FileName(sendNumber))
+		return c.Send(report.XML(sendNumber, time.Now().In(colombia.Location())))
+	case "csv":
+		data, err := report.CSV()
+		if err != nil {
+			return exogenaError(c, err)
+		}
+		c.Set("Content-Type", "text/csv; charset=utf-8")
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=exogena-%s-%d.csv", report.Format, report.Year))
+		return c.Send(data)
+	case "json":
+		return c.JSON(fiber.Map{
+			"success": true,
+			"data":    report,
+		})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":   true,
+		"message": "Salida inválida: use json, xml o csv",
+	})
+}
+
+// GetTaxSummary resumen mensual de IVA y retenciones (?output=csv,
+// ?iva_period=bimestral|cuatrimestral|anual)
+func (h *ExogenaHandler) GetTaxSummary(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	year, err := strconv.Atoi(c.Params("year"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Año gravable inválido",
+		})
+	}
+
+	summary, err := h.exogenaService.TaxSummary(tenant.ID, year, exogenaProfileForTenant(tenant), c.Query("iva_period"))
+	if err != nil {
+		return exogenaError(c, err)
+	}
+
+	if c.Query("output") == "csv" {
+		data, err := summary.CSV()
+		if err != nil {
+			return exogenaError(c, err)
+		}
+		c.Set("Content-Type", "text/csv; charset=utf-8")
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=resumen-tributario-%d.csv", year))
+		return c.Send(data)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    summary,
+	})
+}
+
+// exogenaProfileForTenant datos del informante a partir de la configuración
+// del tenant; sin NIT válido las cuantías menores quedan sin dirección
+func exogenaProfileForTenant(tenant *models.Tenant) exogena.Profile {
+	profile := exogena.Profile{
+		ResponsableIVA:    tenant.Settings.ResponsableIVA,
+		GranContribuyente: tenant.Settings.GranContribuyente,
+		RegimenSimple:     tenant.Settings.RegimenSimple,
+	}
+	if issuer, err := dianIssuer(tenant); err == nil {
+		profile.Informant = exogena.ThirdFromParty(issuer)
+	}
+	return profile
+}
+
+// exogenaError responde 404 para formatos no soportados y 400 en otro caso
+func exogenaError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	if errors.Is(err, exogena.ErrUnknownFormat) {
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   true,
+		"message": err.Error(),
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fmt"
+	"mcp-server/internal/models"
+	"mcp-server/pkg/colombia"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FiscalHandler administra los parámetros fiscales (UVT, salario mínimo, tarifas)
+type FiscalHandler struct {
+	colombiaService *colombia.Service
+}
+
+// NewFiscalHandler crea una nueva instancia del handler
+func NewFiscalHandler(colombiaService *colombia.Service) *FiscalHandler {
+	return &FiscalHandler{
+		colombiaService: colombiaService,
+	}
+}
+
+// ListParameters lista los parámetros fiscales publicados por vigencia
+func (h *FiscalHandler) ListParameters(c *fiber.Ctx) error {
+	return c.JSON(ConfigResponse{
+		Success: true,
+		Data:    h.colombiaService.Fiscal().List(),
+	})
+}
+
+// GetParameters obtiene los parámetros vigentes en una fecha (?date=YYYY-MM-DD, por defecto hoy)
+func (h *FiscalHandler) GetParameters(c *fiber.Ctx) error {
+	date := time.Now()
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ConfigResponse{
+				Success: false,
+				Error:   "Fecha inválida, use el formato YYYY-MM-DD",
+			})
+		}
+		// Fin del día para incluir parámetros que entran en vigencia ese día
+		date = parsed.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	params, err := h.colombiaService.Fiscal().At(date)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ConfigResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(ConfigResponse{
+		Success: true,
+		Data:    params,
+	})
+}
+
+// PublishParameters publica los parámetros fiscales de una vigencia futura.
+// Los parámetros aplican a todos los tenants: solo los publica el Super Admin
+// (owner o admin del tenant de la plataforma).
+func (h *FiscalHandler) PublishParameters(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+	if tenant.ID != models.PlatformTenant {
+		return c.Status(fiber.StatusForbidden).JSON(ConfigResponse{
+			Success: false,
+			Error:   "Solo el Super Admin de la plataforma puede publicar parámetros fiscales",
+		})
+	}
+
+	var req colombia.FiscalParameters
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ConfigResponse{
+			Success: false,
+			Error:   "Formato de request inválido",
+		})
+	}
+
+	params, err := h.colombiaService.Fiscal().Publish(req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ConfigResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Error publicando parámetros fiscales: %v", err),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(ConfigResponse{
+		Success: true,
+		Data:    params,
+	})
+}
@@ -0,0 +1,535 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mcp-server/internal/models"
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/habeasdata"
+	"mcp-server/pkg/notify"
+	"mcp-server/pkg/payments"
+	"mcp-server/pkg/shipping"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HabeasDataHandler maneja las autorizaciones de tratamiento de datos y las
+// solicitudes de los titulares (Ley 1581 de 2012)
+type HabeasDataHandler struct {
+	habeasService *habeasdata.Service
+}
+
+// NewHabeasDataHandler crea una nueva instancia del handler
+func NewHabeasDataHandler(habeasService *habeasdata.Service) *HabeasDataHandler {
+	return &HabeasDataHandler{
+		habeasService: habeasService,
+	}
+}
+
+// subjectRequest identificación del titular recibida por la API o por las
+// herramientas MCP
+type subjectRequest struct {
+	Name           string `json:"name,omitempty"`
+	DocumentType   string `json:"document_type,omitempty"`
+	DocumentNumber string `json:"document_number,omitempty"`
+	Email          string `json:"email,omitempty"`
+	Phone          string `json:"phone,omitempty"`
+}
+
+func (r subjectRequest) subject() habeasdata.Subject {
+	return habeasdata.Subject{
+		Name:           r.Name,
+		DocumentType:   r.DocumentType,
+		DocumentNumber: r.DocumentNumber,
+		Email:          r.Email,
+		Phone:          r.Phone,
+	}
+}
+
+// consentRequest autorización recibida por la API o por las herramientas MCP
+type consentRequest struct {
+	subjectRequest
+	Purposes          flexibleList `json:"purposes" validate:"required"`
+	Granted           *bool        `json:"granted,omitempty"` // Vacío: autorizó
+	Channel           string       `json:"channel,omitempty"`
+	PolicyVersion     string       `json:"policy_version,omitempty"`
+	EvidenceText      string       `json:"evidence_text,omitempty"` // Mensaje del titular o texto aceptado
+	EvidenceReference string       `json:"evidence_reference,omitempty"`
+}
+
+func (r consentRequest) consent(collectedBy string) habeasdata.ConsentRequest {
+	granted := r.Granted == nil || *r.Granted
+	return habeasdata.ConsentRequest{
+		Subject:       r.subject(),
+		Purposes:      r.Purposes,
+		Granted:       granted,
+		Channel:       r.Channel,
+		PolicyVersion: r.PolicyVersion,
+		Evidence: habeasdata.Evidence{
+			Text:      r.EvidenceText,
+			Reference: r.EvidenceReference,
+		},
+		CollectedBy: collectedBy,
+	}
+}
+
+// dataRequest solicitud del titular recibida por la API o por las herramientas MCP
+type dataRequest struct {
+	subjectRequest
+	Type        string            `json:"type" validate:"required"` // consulta, rectificacion, supresion, revocatoria
+	Description string            `json:"description,omitempty"`
+	Channel     string            `json:"channel,omitempty"`
+	Corrections map[string]string `json:"corrections,omitempty"`
+	Purposes    flexibleList      `json:"purposes,omitempty"`
+}
+
+func (r dataRequest) input() habeasdata.RequestInput {
+	return habeasdata.RequestInput{
+		Type:        r.Type,
+		Subject:     r.subject(),
+		Description: r.Description,
+		Channel:     r.Channel,
+		Corrections: r.Corrections,
+		Purposes:    r.Purposes,
+	}
+}
+
+// GetPrivacyNotice aviso de privacidad del tenant para las finalidades
+// indicadas (?purposes=operacion,mercadeo)
+func (h *HabeasDataHandler) GetPrivacyNotice(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var purposes []string
+	if value := c.Query("purposes"); value != "" {
+		purposes = splitList(value)
+	}
+	controller := habeasControllerForTenant(tenant)
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"notice":         habeasdata.Notice(controller, purposes),
+			"policy_url":     controller.PolicyURL,
+			"policy_version": controller.PolicyVersion,
+			"purposes":       habeasdata.Purposes(),
+		},
+	})
+}
+
+// RecordConsent registra la autorización (o negativa) del titular con su evidencia
+func (h *HabeasDataHandler) RecordConsent(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request consentRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de autorización inválidos",
+		})
+	}
+
+	input := request.consent(collectedBy(c))
+	input.Evidence.IP = c.IP()
+	input.Evidence.UserAgent = c.Get(fiber.HeaderUserAgent)
+	consents, err := h.habeasService.RecordConsent(habeasControllerForTenant(tenant), input)
+	if err != nil {
+		return habeasError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": consentText(consents),
+		"data":    consents,
+	})
+}
+
+// ListConsents historial de autorizaciones del tenant o de un titular
+// (?document_number=, ?email=, ?phone=) con la decisión vigente por finalidad
+func (h *HabeasDataHandler) ListConsents(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	subject := habeasdata.Subject{
+		DocumentType:   c.Query("document_type"),
+		DocumentNumber: c.Query("document_number"),
+		Email:          c.Query("email"),
+		Phone:          c.Query("phone"),
+	}
+	consents := h.habeasService.Consents(tenant.ID, subject)
+	data := fiber.Map{
+		"consents": consents,
+		"total":    len(consents),
+	}
+	if subject.DocumentNumber != "" || subject.Email != "" || subject.Phone != "" {
+		data["current"] = h.habeasService.CurrentConsents(tenant.ID, subject)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// RevokeConsent revoca las autorizaciones del titular para las finalidades
+// indicadas, o todas
+func (h *HabeasDataHandler) RevokeConsent(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		subjectRequest
+		Purposes flexibleList `json:"purposes,omitempty"`
+		Reason   string       `json:"reason,omitempty"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de revocatoria inválidos",
+		})
+	}
+
+	revoked, err := h.habeasService.RevokeConsent(tenant.ID, request.subject(), request.Purposes, request.Reason)
+	if err != nil {
+		return habeasError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("%d autorizaciones revocadas", len(revoked)),
+		"data":    revoked,
+	})
+}
+
+// FileDataRequest radica una consulta o reclamo del titular
+func (h *HabeasDataHandler) FileDataRequest(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request dataRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de la solicitud inválidos",
+		})
+	}
+
+	filed, err := h.habeasService.FileRequest(habeasControllerForTenant(tenant), request.input())
+	if err != nil {
+		return habeasError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": dataRequestText(filed),
+		"data":    filed,
+	})
+}
+
+// ListDataRequests solicitudes del tenant por vencimiento (?status=radicada,
+// prorrogada, resuelta, rechazada, abiertas o vencidas)
+func (h *HabeasDataHandler) ListDataRequests(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	list := h.habeasService.Requests(tenant.ID, c.Query("status"))
+	overdue := 0
+	for _, request := range list {
+		if request.Overdue {
+			overdue++
+		}
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"requests": list,
+			"total":    len(list),
+			"overdue":  overdue,
+		},
+	})
+}
+
+// GetDataRequest consulta una solicitud
+func (h *HabeasDataHandler) GetDataRequest(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	request, err := h.habeasService.GetRequest(tenant.ID, c.Params("id"))
+	if err != nil {
+		return habeasError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    request,
+	})
+}
+
+// ExtendDataRequest prorroga el término de la solicitud informando el motivo
+func (h *HabeasDataHandler) ExtendDataRequest(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		Reason string `json:"reason" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de la prórroga inválidos",
+		})
+	}
+
+	extended, err := h.habeasService.ExtendRequest(tenant.ID, c.Params("id"), request.Reason)
+	if err != nil {
+		return habeasError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Solicitud prorrogada hasta el " + colombia.FormatDate(extended.DueAt),
+		"data":    extended,
+	})
+}
+
+// ResolveDataRequest responde la solicitud; si procede, ejecuta la supresión,
+// revocatoria o rectificación en los módulos de la plataforma
+func (h *HabeasDataHandler) ResolveDataRequest(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		Approved bool   `json:"approved"`
+		Response string `json:"response,omitempty"` // Requerida si no procede
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de la respuesta inválidos",
+		})
+	}
+
+	resolved, err := h.habeasService.ResolveRequest(tenant.ID, c.Params("id"), request.Approved, request.Response)
+	if err != nil {
+		return habeasError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Solicitud %s %s", resolved.ID, resolved.Status),
+		"data":    resolved,
+	})
+}
+
+// ExportDataRequest descarga en JSON los datos personales del titular de la
+// solicitud: autorizaciones, solicitudes y registros en cada módulo
+func (h *HabeasDataHandler) ExportDataRequest(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	request, err := h.habeasService.GetRequest(tenant.ID, c.Params("id"))
+	if err != nil {
+		return habeasError(c, err)
+	}
+	export, err := h.habeasService.ExportSubject(tenant.ID, request.Subject)
+	if err != nil {
+		return habeasError(c, err)
+	}
+
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=datos-personales-%s.json", request.ID))
+	return c.JSON(export)
+}
+
+// Helper functions
+
+// habeasError responde 404 si la solicitud no existe, 409 si ya fue resuelta
+// y 400 en otro caso
+func habeasError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, habeasdata.ErrRequestNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, habeasdata.ErrRequestClosed):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   true,
+		"message": err.Error(),
+	})
+}
+
+// habeasControllerForTenant responsable del tratamiento: el negocio del tenant
+func habeasControllerForTenant(tenant *models.Tenant) habeasdata.Controller {
+	settings := tenant.Settings
+	controller := habeasdata.Controller{
+		TenantID:      tenant.ID,
+		Name:          settings.BusinessName,
+		NIT:           settings.NITNumber,
+		Email:         settings.PrivacyContactEmail,
+		PolicyURL:     settings.PrivacyPolicyURL,
+		PolicyVersion: settings.PrivacyPolicyVersion,
+	}
+	if controller.Name == "" {
+		controller.Name = tenant.Name
+	}
+	if controller.Email == "" {
+		controller.Email = settings.BusinessEmail
+	}
+	if controller.PolicyVersion == "" {
+		controller.PolicyVersion = "1.0"
+	}
+	return controller
+}
+
+// collectedBy usuario que registra la autorización por la API
+func collectedBy(c *fiber.Ctx) string {
+	if user, ok := c.Locals("user").(*models.User); ok && user != nil {
+		return user.Email
+	}
+	return "api"
+}
+
+func consentText(consents []*habeasdata.Consent) string {
+	var purposes []string
+	for _, consent := range consents {
+		purposes = append(purposes, consent.Purpose)
+	}
+	decision := "autorizó"
+	if len(consents) > 0 && !consents[0].Granted {
+		decision = "no autorizó"
+	}
+	return fmt.Sprintf("El titular %s el tratamiento para: %s", decision, strings.Join(purposes, ", "))
+}
+
+func dataRequestText(request *habeasdata.Request) string {
+	return fmt.Sprintf("Solicitud de %s %s radicada; vence el %s (%d días hábiles)",
+		request.Type, request.ID, colombia.FormatDate(request.DueAt), request.BusinessDaysLeft)
+}
+
+// flexibleList lista que llega como arreglo JSON o como texto separado por comas
+type flexibleList []string
+
+// UnmarshalJSON acepta ["a","b"] o "a, b"
+func (l *flexibleList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*l = list
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("se esperaba una lista o un texto separado por comas")
+	}
+	*l = splitList(text)
+	return nil
+}
+
+func splitList(text string) []string {
+	var list []string
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// HabeasDataSources módulos que guardan datos de los clientes finales: los
+// pagos (conservados como soporte contable), los envíos y los avisos enviados
+func HabeasDataSources(paymentsService *payments.Service, shippingService *shipping.Service, notifyService *notify.Service) []habeasdata.Source {
+	recipientMatcher := func(subject habeasdata.Subject) func(shipping.Party) bool {
+		return func(party shipping.Party) bool {
+			return subject.Matches(habeasdata.Subject{DocumentNumber: party.Document, Email: party.Email, Phone: party.Phone})
+		}
+	}
+
+	return []habeasdata.Source{
+		{
+			Name: "pagos",
+			Export: func(tenantID string, subject habeasdata.Subject) []habeasdata.Record {
+				var records []habeasdata.Record
+				for _, payment := range paymentsService.ListPayments(tenantID, "") {
+					customer := payment.Customer
+					if !subject.Matches(habeasdata.Subject{DocumentType: customer.DocumentType, DocumentNumber: customer.DocumentNumber, Email: customer.Email, Phone: customer.Phone}) {
+						continue
+					}
+					records = append(records, habeasdata.Record{
+						Source: "pagos",
+						Kind:   "pago",
+						ID:     payment.Reference,
+						At:     payment.CreatedAt,
+						Data: fiber.Map{
+							"order_id": payment.OrderID,
+							"method":   payment.Method,
+							"amount":   payment.Amount,
+							"status":   payment.Status,
+							"customer": customer,
+						},
+					})
+				}
+				return records
+			},
+			Retention: "Soportes contables y tributarios: se conservan por 10 años (Código de Comercio, art. 60; Ley 962 de 2005, art. 28)",
+		},
+		{
+			Name: "envios",
+			Export: func(tenantID string, subject habeasdata.Subject) []habeasdata.Record {
+				var records []habeasdata.Record
+				for _, shipment := range shippingService.RecipientShipments(tenantID, recipientMatcher(subject)) {
+					records = append(records, habeasdata.Record{
+						Source: "envios",
+						Kind:   "envio",
+						ID:     shipment.ID,
+						At:     shipment.CreatedAt,
+						Data: fiber.Map{
+							"order_id":        shipment.Reference,
+							"carrier":         shipment.CarrierName,
+							"tracking_number": shipment.TrackingNumber,
+							"status":          shipment.Status,
+							"recipient":       shipment.Recipient,
+						},
+					})
+				}
+				return records
+			},
+			Erase: func(tenantID string, subject habeasdata.Subject) (int, error) {
+				erased, active := shippingService.EraseRecipient(tenantID, recipientMatcher(subject))
+				if active > 0 {
+					return erased, fmt.Errorf("%d envíos en curso conservan los datos del destinatario hasta la entrega", active)
+				}
+				return erased, nil
+			},
+			Rectify: func(tenantID string, subject habeasdata.Subject, corrections map[string]string) (int, error) {
+				return shippingService.RectifyRecipient(tenantID, recipientMatcher(subject), func(party *shipping.Party) {
+					if name, ok := corrections["name"]; ok {
+						party.Name = name
+					}
+					if email, ok := corrections["email"]; ok {
+						party.Email = email
+					}
+					if value, ok := corrections["phone"]; ok {
+						if phone, err := colombia.ParsePhone(value); err == nil {
+							party.Phone = phone.E164
+						}
+					}
+					if value, ok := corrections["address"]; ok {
+						if address, err := colombia.ParseAddress(value); err == nil {
+							party.Address = address.Formatted
+						}
+					}
+				}), nil
+			},
+		},
+		{
+			Name: "avisos",
+			Export: func(tenantID string, subject habeasdata.Subject) []habeasdata.Record {
+				var records []habeasdata.Record
+				for _, delivery := range notifyService.Outbox(tenantID, "") {
+					if !subject.Matches(habeasdata.Subject{Email: delivery.Message.To, Phone: delivery.Message.To}) {
+						continue
+					}
+					records = append(records, habeasdata.Record{
+						Source: "avisos",
+						Kind:   delivery.Message.Channel,
+						ID:     delivery.ID,
+						At:     delivery.CreatedAt,
+						Data:   delivery.Message,
+					})
+				}
+				return records
+			},
+			Erase: func(tenantID string, subject habeasdata.Subject) (int, error) {
+				return notifyService.EraseRecipient(tenantID, func(to string) bool {
+					return subject.Matches(habeasdata.Subject{Email: to, Phone: to})
+				}), nil
+			},
+		},
+	}
+}
@@ -1,16 +1,44 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"mcp-server/internal/models"
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/dian"
+	"mcp-server/pkg/habeasdata"
+	"mcp-server/pkg/payments"
+	"mcp-server/pkg/shipping"
 )
 
+// MCPHandler maneja la ejecución de herramientas y agentes MCP
+type MCPHandler struct {
+	dianService     *dian.Service
+	colombiaService *colombia.Service
+	paymentsService *payments.Service
+	shippingService *shipping.Service
+	habeasService   *habeasdata.Service
+}
+
+// NewMCPHandler crea una nueva instancia del handler
+func NewMCPHandler(dianService *dian.Service, colombiaService *colombia.Service, paymentsService *payments.Service, shippingService *shipping.Service, habeasService *habeasdata.Service) *MCPHandler {
+	return &MCPHandler{
+		dianService:     dianService,
+		colombiaService: colombiaService,
+		paymentsService: paymentsService,
+		shippingService: shippingService,
+		habeasService:   habeasService,
+	}
+}
+
 // ExecuteMCPTool ejecuta una herramienta MCP
-func ExecuteMCPTool(c *fiber.Ctx) error {
+func (h *MCPHandler) ExecuteMCPTool(c *fiber.Ctx) error {
 	tenant := c.Locals("tenant").(*models.Tenant)
 	user := c.Locals("user").(*models.User)
 
@@ -28,8 +56,7 @@ func ExecuteMCPTool(c *fiber.Ctx) error {
 		})
 	}
 
-	// Simular ejecución de herramienta MCP
-	result, err := executeMCPToolSimulated(request.Tool, request.Input, tenant)
+	result, err := h.executeMCPTool(c.Context(), request.Tool, request.Input, tenant)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
@@ -59,7 +86,7 @@ func ExecuteMCPTool(c *fiber.Ctx) error {
 }
 
 // ListMCPTools lista las herramientas MCP disponibles
-func ListMCPTools(c *fiber.Ctx) error {
+func (h *MCPHandler) ListMCPTools(c *fiber.Ctx) error {
 	tenant := c.Locals("tenant").(*models.Tenant)
 	
 	// Herramientas disponibles según el plan y features
@@ -77,7 +104,7 @@ func ListMCPTools(c *fiber.Ctx) error {
 }
 
 // ChatWithAgent maneja chat con un agente MCP
-func ChatWithAgent(c *fiber.Ctx) error {
+func (h *MCPHandler) ChatWithAgent(c *fiber.Ctx) error {
 	tenant := c.Locals("tenant").(*models.Tenant)
 	user := c.Locals("user").(*models.User)
 	
@@ -139,23 +166,36 @@ func ChatWithAgent(c *fiber.Ctx) error {
 
 // Helper functions
 
-func executeMCPToolSimulated(toolName string, input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
-	// Simular ejecución de diferentes herramientas MCP
+func (h *MCPHandler) executeMCPTool(ctx context.Context, toolName string, input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
 	switch toolName {
 	case "product_catalog":
 		return executeProductCatalog(input, tenant)
 	case "price_calculator":
-		return executePriceCalculator(input, tenant)
+		return h.executePriceCalculator(input, tenant)
+	case "tax_calculator":
+		return h.executeTaxCalculator(input, tenant)
 	case "inventory_check":
 		return executeInventoryCheck(input, tenant)
 	case "shipping_calculator":
-		return executeShippingCalculator(input, tenant)
+		return h.executeShippingCalculator(ctx, input, tenant)
+	case "carrier_selector":
+		return h.executeCarrierSelector(ctx, input, tenant)
+	case "tracking_checker":
+		return h.executeTrackingChecker(ctx, input, tenant)
 	case "payment_processor":
-		return executePaymentProcessor(input, tenant)
+		return h.executePaymentProcessor(ctx, input, tenant)
 	case "invoice_generator":
-		return executeInvoiceGenerator(input, tenant)
+		return h.executeInvoiceGenerator(ctx, input, tenant)
+	case "support_document_generator":
+		return h.executeSupportDocumentGenerator(ctx, input, tenant)
+	case "payroll_generator":
+		return h.executePayrollGenerator(ctx, input, tenant)
 	case "faq_searcher":
 		return executeFAQSearcher(input, tenant)
+	case "consent_capture":
+		return h.executeConsentCapture(input, tenant)
+	case "data_subject_request":
+		return h.executeDataSubjectRequest(input, tenant)
 	default:
 		return nil, fmt.Errorf("herramienta '%s' no encontrada", toolName)
 	}
@@ -196,7 +236,7 @@ func executeProductCatalog(input map[string]interface{}, tenant *models.Tenant)
 	}, nil
 }
 
-func executePriceCalculator(input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+func (h *MCPHandler) executePriceCalculator(input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
 	productID, _ := input["product_id"].(string)
 	quantity, _ := input["quantity"].(float64)
 	
@@ -209,7 +249,20 @@ func executePriceCalculator(input map[string]interface{}, tenant *models.Tenant)
 		discount = int(float64(subtotal) * 0.1) // 10% descuento por cantidad
 	}
 	
-	iva := int(float64(subtotal-discount) * 0.19) // IVA 19%
+	ivaCategory, _ := input["iva_category"].(string)
+	params, err := h.colombiaService.Fiscal().Current()
+	if err != nil {
+		return nil, err
+	}
+	ivaRate, err := params.IVARate(ivaCategory)
+	if err != nil {
+		return nil, err
+	}
+	if !tenant.Settings.ResponsableIVA {
+		ivaRate = 0
+	}
+
+	iva := int(math.Round(float64(subtotal-discount) * ivaRate / 100))
 	total := subtotal - discount + iva
 
 	return map[string]interface{}{
@@ -218,6 +271,7 @@ func executePriceCalculator(input map[string]interface{}, tenant *models.Tenant)
 		"unit_price_cop": unitPrice,
 		"subtotal_cop":   subtotal,
 		"discount_cop":   discount,
+		"iva_rate":       ivaRate,
 		"iva_cop":        iva,
 		"total_cop":      total,
 		"formatted_total": fmt.Sprintf("$%s", formatCOPAmount(total)),
@@ -243,63 +297,531 @@ func executeInventoryCheck(input map[string]interface{}, tenant *models.Tenant)
 	}, nil
 }
 
-func executeShippingCalculator(input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
-	city, _ := input["city"].(string)
-	weight, _ := input["weight"].(float64)
-	
-	// Simular cálculo de envío
-	baseCost := 12000 // $12.000 COP base
-	if weight > 1000 {
-		baseCost += int((weight - 1000) / 500 * 2000) // $2.000 por cada 500g adicionales
+func (h *MCPHandler) executeShippingCalculator(ctx context.Context, input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+	result, err := h.shopShippingRates(ctx, input, tenant)
+	if err != nil {
+		return nil, err
 	}
-	
+	city, quote := result.Destination, result.Selected
+
+	options := make([]map[string]interface{}, 0, len(result.Quotes))
+	for _, option := range result.Quotes {
+		options = append(options, map[string]interface{}{
+			"carrier":       option.CarrierName,
+			"cost_cop":      option.Total,
+			"delivery_days": option.DeliveryDays,
+		})
+	}
+
 	return map[string]interface{}{
-		"city":           city,
-		"weight_grams":   int(weight),
-		"cost_cop":       baseCost,
-		"formatted_cost": fmt.Sprintf("$%s", formatCOPAmount(baseCost)),
-		"delivery_days":  2,
-		"carrier":        "Servientrega",
-		"message":        fmt.Sprintf("Envío a %s: $%s", city, formatCOPAmount(baseCost)),
+		"city":               city.Name,
+		"city_code":          city.Code,
+		"department":         city.Department,
+		"weight_grams":       result.Package.WeightGrams,
+		"cost_cop":           quote.Total,
+		"formatted_cost":     fmt.Sprintf("$%s", formatCOPAmount(int(quote.Total))),
+		"delivery_days":      quote.DeliveryDays, // Días hábiles, sin fines de semana ni festivos
+		"estimated_delivery": quote.EstimatedDelivery.Format("2006-01-02"),
+		"carrier":            quote.CarrierName,
+		"options":            options,
+		"message":            fmt.Sprintf("Envío a %s (%s) con %s: $%s", city.Name, city.Department, quote.CarrierName, formatCOPAmount(int(quote.Total))),
 	}, nil
 }
 
-func executePaymentProcessor(input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
-	amount, _ := input["amount"].(float64)
-	method, _ := input["method"].(string)
-	
-	// Simular procesamiento de pago
-	reference := fmt.Sprintf("PAY_%d", time.Now().Unix())
-	
+// executeCarrierSelector cotiza con todas las transportadoras habilitadas y
+// recomienda una por precio o por tiempo de entrega
+func (h *MCPHandler) executeCarrierSelector(ctx context.Context, input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+	result, err := h.shopShippingRates(ctx, input, tenant)
+	if err != nil {
+		return nil, err
+	}
+
 	return map[string]interface{}{
-		"reference":     reference,
-		"amount_cop":    int(amount),
-		"payment_method": method,
-		"status":        "pending",
-		"payment_url":   fmt.Sprintf("https://pse.redeban.com.co/pay/%s", reference),
-		"expires_at":    time.Now().Add(1 * time.Hour),
-		"message":       "Pago iniciado. Redirige al cliente para completar el pago.",
+		"carrier":      result.Selected.Carrier,
+		"carrier_name": result.Selected.CarrierName,
+		"strategy":     result.Strategy,
+		"selected":     result.Selected,
+		"quotes":       result.Quotes,
+		"unavailable":  result.Unavailable,
+		"origin":       result.Origin.Name,
+		"destination":  result.Destination.Name,
+		"message":      shippingSelectionText(result),
 	}, nil
 }
 
-func executeInvoiceGenerator(input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+// executeTrackingChecker consulta el rastreo de un envío por "tracking_number"
+// o "shipment_id" y lo actualiza con la transportadora
+func (h *MCPHandler) executeTrackingChecker(ctx context.Context, input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+	trackingNumber, _ := input["tracking_number"].(string)
+	shipmentID, _ := input["shipment_id"].(string)
+
+	var shipment *shipping.Shipment
+	var err error
+	switch {
+	case shipmentID != "":
+		shipment, err = h.shippingService.GetShipment(tenant.ID, shipmentID)
+	case trackingNumber != "":
+		shipment, err = h.shippingService.FindByTracking(tenant.ID, trackingNumber)
+	default:
+		return nil, fmt.Errorf("tracking_number o shipment_id requerido")
+	}
+	if err != nil {
+		return nil, err
+	}
+	shipment, err = h.shippingService.Track(ctx, shippingConfigForTenant(tenant), shipment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"shipment_id":     shipment.ID,
+		"order_id":        shipment.Reference,
+		"carrier":         shipment.CarrierName,
+		"tracking_number": shipment.TrackingNumber,
+		"status":          shipment.Status,
+		"status_label":    shipping.StatusLabel(shipment.Status),
+		"delivered":       shipment.Status == shipping.StatusDelivered,
+		"destination":     shipment.Destination.Name,
+		"tracking_url":    shipment.TrackingURL,
+		"events":          shipment.Events,
+		"message":         fmt.Sprintf("Guía %s de %s: %s", shipment.TrackingNumber, shipment.CarrierName, shipping.StatusLabel(shipment.Status)),
+	}
+	if !shipping.Final(shipment.Status) {
+		result["estimated_delivery"] = shipment.Quote.EstimatedDelivery.Format("2006-01-02")
+	}
+	if n := len(shipment.Events); n > 0 {
+		last := shipment.Events[n-1]
+		result["last_event"] = last
+		if last.Description != "" {
+			result["message"] = fmt.Sprintf("%s (%s)", result["message"], last.Description)
+		}
+	}
+	return result, nil
+}
+
+// executeConsentCapture registra la autorización de tratamiento de datos que
+// el cliente da en la conversación. "message" guarda el texto del cliente como
+// evidencia; sin finalidades devuelve el aviso de privacidad para pedirla.
+func (h *MCPHandler) executeConsentCapture(input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+	var request consentRequest
+	if err := decodeToolInput(input, &request); err != nil {
+		return nil, err
+	}
+	if message, _ := input["message"].(string); message != "" && request.EvidenceText == "" {
+		request.EvidenceText = message
+	}
+
+	controller := habeasControllerForTenant(tenant)
+	if len(request.Purposes) == 0 {
+		return map[string]interface{}{
+			"recorded": false,
+			"notice":   habeasdata.Notice(controller, nil),
+			"message":  "Antes de continuar, comparta el aviso de privacidad y pida la autorización del cliente",
+		}, nil
+	}
+
+	consents, err := h.habeasService.RecordConsent(controller, request.consent("agente"))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(consents))
+	for _, consent := range consents {
+		ids = append(ids, consent.ID)
+	}
+	return map[string]interface{}{
+		"recorded":    true,
+		"consent_ids": ids,
+		"granted":     consents[0].Granted,
+		"notice":      habeasdata.Notice(controller, request.Purposes),
+		"message":     consentText(consents),
+	}, nil
+}
+
+// executeDataSubjectRequest radica la consulta, rectificación, supresión o
+// revocatoria que el cliente pide en la conversación e informa el plazo de
+// respuesta
+func (h *MCPHandler) executeDataSubjectRequest(input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+	var request dataRequest
+	if err := decodeToolInput(input, &request); err != nil {
+		return nil, err
+	}
+
+	filed, err := h.habeasService.FileRequest(habeasControllerForTenant(tenant), request.input())
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"request_id":         filed.ID,
+		"type":               filed.Type,
+		"status":             filed.Status,
+		"due_date":           filed.DueAt.Format("2006-01-02"),
+		"business_days_left": filed.BusinessDaysLeft,
+		"message":            dataRequestText(filed),
+	}, nil
+}
+
+// shopShippingRates cotiza el envío de las herramientas de logística. Acepta
+// "city" o "destination" como destino, "origin" (por defecto la ciudad del
+// negocio), "weight" en gramos, "length", "width" y "height" en centímetros,
+// "declared_value", "cash_on_delivery" y "strategy" (precio o tiempo).
+func (h *MCPHandler) shopShippingRates(ctx context.Context, input map[string]interface{}, tenant *models.Tenant) (*shipping.RateShopping, error) {
+	destination, _ := input["destination"].(string)
+	if destination == "" {
+		destination, _ = input["city"].(string)
+	}
+	origin, _ := input["origin"].(string)
+	weight, _ := input["weight"].(float64)
+	declaredValue, _ := input["declared_value"].(float64)
+	cashOnDelivery, _ := input["cash_on_delivery"].(float64)
+	strategy, _ := input["strategy"].(string)
+	length, _ := input["length"].(float64)
+	width, _ := input["width"].(float64)
+	height, _ := input["height"].(float64)
+	if weight <= 0 {
+		weight = 1000
+	}
+
+	request := shippingQuoteRequest{
+		OriginCity:     origin,
+		DestinyCity:    destination,
+		Weight:         int(weight),
+		Length:         int(length),
+		Width:          int(width),
+		Height:         int(height),
+		DeclaredValue:  int64(declaredValue),
+		CashOnDelivery: int64(cashOnDelivery),
+		Strategy:       strategy,
+	}
+	quoteRequest, err := request.quoteRequest(tenant)
+	if err != nil {
+		return nil, err
+	}
+	return h.shippingService.Quote(ctx, shippingConfigForTenant(tenant), quoteRequest, request.Strategy)
+}
+
+func (h *MCPHandler) executePaymentProcessor(ctx context.Context, input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+	var request paymentRequest
+	if err := decodeToolInput(input, &request); err != nil {
+		return nil, err
+	}
+	// La herramienta recibe el monto como "amount"
+	if amount, ok := input["amount"].(float64); ok && request.Amount == 0 {
+		request.Amount = int64(amount)
+	}
+	payment, err := createPayment(ctx, h.paymentsService, tenant, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"payment_id":     payment.ID,
+		"reference":      payment.Reference,
+		"amount_cop":     payment.Amount,
+		"payment_method": payment.Method,
+		"status":         payment.Status,
+		"payment_url":    payment.PaymentURL,
+		"expires_at":     payment.ExpiresAt,
+		"voucher":        payment.Voucher,
+		"order_status":   orderStatus(h.paymentsService, payment),
+		"sandbox":        payment.Sandbox,
+		"message":        paymentStatusText(payment),
+	}, nil
+}
+
+func (h *MCPHandler) executeInvoiceGenerator(ctx context.Context, input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+	customerNIT, _ := input["customer_nit"].(string)
 	customerName, _ := input["customer_name"].(string)
-	items, _ := input["items"].([]interface{})
-	
-	// Simular generación de factura
-	invoiceNumber := fmt.Sprintf("FE-%d", time.Now().Unix())
-	
+	paymentMethod, _ := input["payment_method"].(string)
+	if customerNIT == "" || customerName == "" {
+		return nil, fmt.Errorf("customer_nit y customer_name son requeridos")
+	}
+
+	lines, err := invoiceLinesFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, err := dianIssuer(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	issuedAt := time.Now().In(colombia.Location())
+	invoice := &dian.Invoice{
+		IssueDate: issuedAt,
+		DueDate:   invoiceDueDate(issuedAt),
+		Issuer:    issuer,
+		Customer: dian.Party{
+			Name:         customerName,
+			TaxLevel:     "R-99-PN",
+		},
+		Lines:         lines,
+		PaymentMethod: dianPaymentMeansCode(paymentMethod),
+		PaymentForm:   "1",
+	}
+	customerDocType, _ := input["customer_document_type"].(string)
+	if err := setPartyDocument(&invoice.Customer, customerDocType, colombia.DocNIT, customerNIT); err != nil {
+		return nil, err
+	}
+	customerCity, _ := input["customer_city"].(string)
+	if err := setPartyCity(&invoice.Customer, customerCity); err != nil {
+		return nil, err
+	}
+
+	submission, err := h.dianService.IssueInvoice(ctx, dianConfigForTenant(tenant), invoice)
+	if err != nil {
+		return nil, err
+	}
+
 	return map[string]interface{}{
-		"invoice_number": invoiceNumber,
+		"invoice_number": submission.Number,
 		"customer_name":  customerName,
-		"items_count":    len(items),
-		"status":         "generated",
-		"cufe":          "ABC123456789", // CUFE simulado
-		"pdf_url":       fmt.Sprintf("https://api.tause.pro/invoices/%s.pdf", invoiceNumber),
-		"message":       fmt.Sprintf("Factura %s generada exitosamente", invoiceNumber),
+		"items_count":    len(lines),
+		"status":         submission.Status,
+		"cufe":           submission.DocumentKey,
+		"qr_code":        submission.QRCode,
+		"total_cop":      invoice.Total,
+		"message":        fmt.Sprintf("Factura %s %s", submission.Number, submissionStatusText(submission)),
 	}, nil
 }
 
+func (h *MCPHandler) executeSupportDocumentGenerator(ctx context.Context, input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+	supplierID, _ := input["supplier_id"].(string)
+	supplierName, _ := input["supplier_name"].(string)
+	supplierType, _ := input["supplier_document_type"].(string)
+	paymentMethod, _ := input["payment_method"].(string)
+	if supplierID == "" || supplierName == "" {
+		return nil, fmt.Errorf("supplier_id y supplier_name son requeridos")
+	}
+
+	lines, err := invoiceLinesFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	acquirer, err := dianIssuer(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &dian.SupportDocument{
+		IssueDate: time.Now(),
+		Acquirer:  acquirer,
+		Supplier: dian.Party{
+			Name:         supplierName,
+			TaxLevel:     "R-99-PN",
+		},
+		Lines:         lines,
+		PaymentMethod: dianPaymentMeansCode(paymentMethod),
+		PaymentForm:   "1",
+	}
+	if err := setPartyDocument(&doc.Supplier, supplierType, colombia.DocCedulaCiudadania, supplierID); err != nil {
+		return nil, err
+	}
+	supplierCity, _ := input["supplier_city"].(string)
+	if err := setPartyCity(&doc.Supplier, supplierCity); err != nil {
+		return nil, err
+	}
+
+	submission, err := h.dianService.IssueSupportDocument(ctx, dianConfigForTenant(tenant), doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"document_number": submission.Number,
+		"supplier_name":   supplierName,
+		"status":          submission.Status,
+		"cuds":            submission.DocumentKey,
+		"total_cop":       doc.Total,
+		"message":         fmt.Sprintf("Documento soporte %s %s", submission.Number, submissionStatusText(submission)),
+	}, nil
+}
+
+func (h *MCPHandler) executePayrollGenerator(ctx context.Context, input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+	employeeID, _ := input["employee_id"].(string)
+	firstName, _ := input["first_name"].(string)
+	surname, _ := input["surname"].(string)
+	salary, _ := input["salary"].(float64)
+	days, _ := input["days_worked"].(float64)
+	transport, _ := input["transport_allowance"].(float64)
+	period, _ := input["period"].(string) // YYYY-MM
+	if employeeID == "" || salary <= 0 {
+		return nil, fmt.Errorf("employee_id y salary son requeridos")
+	}
+	if days <= 0 {
+		days = 30
+	}
+
+	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	if period != "" {
+		parsed, err := time.Parse("2006-01", period)
+		if err != nil {
+			return nil, fmt.Errorf("period inválido, use YYYY-MM")
+		}
+		start = parsed
+	}
+	end := start.AddDate(0, 1, -1)
+
+	employer, err := dianIssuer(tenant)
+	if err != nil {
+		return nil, err
+	}
+	employeeDoc, err := colombia.ValidateDocument(colombia.DocCedulaCiudadania, employeeID)
+	if err != nil {
+		return nil, fmt.Errorf("documento del trabajador inválido: %w", err)
+	}
+
+	payroll := &dian.Payroll{
+		IssueDate:     time.Now(),
+		PeriodStart:   start,
+		PeriodEnd:     end,
+		PaymentDate:   end,
+		PayrollPeriod: "5",
+		Employer:      employer,
+		Employee: dian.PayrollEmployee{
+			WorkerType:    "01",
+			WorkerSubType: "00",
+			DocumentType:  employeeDoc.TypeCode,
+			ID:            employeeDoc.Number,
+			FirstName:     firstName,
+			FirstSurname:  surname,
+			ContractType:  "2",
+			Salary:        int(salary),
+		},
+		DaysWorked:         int(days),
+		TransportAllowance: int(transport),
+		PaymentMethod:      "47",
+	}
+
+	submission, err := h.dianService.IssuePayroll(ctx, dianConfigForTenant(tenant), payroll)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"payroll_number":       submission.Number,
+		"employee_id":          employeeID,
+		"status":               submission.Status,
+		"cune":                 submission.DocumentKey,
+		"earnings_total_cop":   payroll.EarningsTotal,
+		"deductions_total_cop": payroll.DeductionsTotal,
+		"net_total_cop":        payroll.NetTotal,
+		"message":              fmt.Sprintf("Nómina %s por $%s %s", submission.Number, formatCOPAmount(payroll.NetTotal), submissionStatusText(submission)),
+	}, nil
+}
+
+func (h *MCPHandler) executeTaxCalculator(input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
+	var request struct {
+		Items        []colombia.TaxLine  `json:"items"`
+		Concept      string              `json:"concept"`
+		Role         string              `json:"role"` // seller (venta del tenant) o buyer (compra del tenant)
+		Counterparty colombia.TaxProfile `json:"counterparty"`
+		CityCode     string              `json:"city_code"`
+	}
+	if err := decodeToolInput(input, &request); err != nil {
+		return nil, err
+	}
+
+	taxRequest := colombia.TaxRequest{
+		Lines:    request.Items,
+		Concept:  request.Concept,
+		CityCode: request.CityCode,
+	}
+	if request.Counterparty.PersonType == "" {
+		request.Counterparty.PersonType = colombia.PersonNatural
+	}
+	if request.Role == "buyer" {
+		taxRequest.Seller = request.Counterparty
+		taxRequest.Buyer = taxProfileForTenant(tenant)
+	} else {
+		taxRequest.Seller = taxProfileForTenant(tenant)
+		taxRequest.Buyer = request.Counterparty
+	}
+
+	result, err := h.colombiaService.CalculateTaxes(taxRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"taxes":   result,
+		"message": fmt.Sprintf("Total $%s, retenciones $%s, neto a pagar $%s", formatCOPAmount(result.Total), formatCOPAmount(result.ReteFuente+result.ReteIVA+result.ReteICA), formatCOPAmount(result.NetPayable)),
+	}, nil
+}
+
+// taxProfileForTenant perfil tributario del tenant según su configuración
+func taxProfileForTenant(tenant *models.Tenant) colombia.TaxProfile {
+	settings := tenant.Settings
+	personType := settings.PersonType
+	if personType == "" {
+		personType = colombia.PersonJuridica
+	}
+	return colombia.TaxProfile{
+		PersonType:        personType,
+		ResponsableIVA:    settings.ResponsableIVA,
+		GranContribuyente: settings.GranContribuyente,
+		Autorretenedor:    settings.Autorretenedor,
+		RegimenSimple:     settings.RegimenSimple,
+		Declarante:        true,
+		CityCode:          settings.CityCode,
+		CIIU:              settings.CIIU,
+	}
+}
+
+// decodeToolInput convierte la entrada genérica de una herramienta en una estructura
+func decodeToolInput(input map[string]interface{}, target interface{}) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("entrada inválida para la herramienta: %w", err)
+	}
+	return nil
+}
+
+// invoiceLinesFromInput convierte los ítems de la entrada MCP en líneas de documento
+func invoiceLinesFromInput(input map[string]interface{}) ([]dian.InvoiceLine, error) {
+	items, _ := input["items"].([]interface{})
+	if len(items) == 0 {
+		return nil, fmt.Errorf("se requiere al menos un ítem")
+	}
+
+	lines := make([]dian.InvoiceLine, 0, len(items))
+	for _, raw := range items {
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("ítem inválido")
+		}
+		description, _ := item["description"].(string)
+		quantity, _ := item["quantity"].(float64)
+		unitPrice, _ := item["unit_price_cop"].(float64)
+		ivaRate, _ := item["iva_rate"].(float64)
+		if quantity <= 0 {
+			quantity = 1
+		}
+		lines = append(lines, dian.InvoiceLine{
+			Description: description,
+			Quantity:    int(quantity),
+			UnitPrice:   int(unitPrice),
+			IVARate:     ivaRate,
+		})
+	}
+	return lines, nil
+}
+
+func submissionStatusText(submission *dian.Submission) string {
+	switch submission.Status {
+	case dian.SubmissionApproved:
+		return "validado por la DIAN"
+	case dian.SubmissionPending:
+		return "pendiente de validación en la DIAN"
+	default:
+		return "rechazado por la DIAN"
+	}
+}
+
 func executeFAQSearcher(input map[string]interface{}, tenant *models.Tenant) (map[string]interface{}, error) {
 	query, _ := input["query"].(string)
 	
@@ -355,10 +877,24 @@ func getMCPToolsForTenant(tenant *models.Tenant) []map[string]interface{} {
 			"category":    "logistica",
 			"available":   tenant.IsFeatureEnabled("ecommerce_tool"),
 		},
+		{
+			"name":         "carrier_selector",
+			"display_name": "Selector de Transportadora",
+			"description":  "Cotizar con Servientrega, Coordinadora, Interrapidísimo y TCC (tarifas del comercio o simulador) y elegir la más económica o la más rápida",
+			"category":     "logistica",
+			"available":    tenant.IsFeatureEnabled("ecommerce_tool"),
+		},
+		{
+			"name":         "tracking_checker",
+			"display_name": "Rastreo de Envíos",
+			"description":  "Consultar el estado de una guía por número de guía o envío",
+			"category":     "logistica",
+			"available":    tenant.IsFeatureEnabled("ecommerce_tool"),
+		},
 		{
 			"name":        "payment_processor",
 			"display_name": "Procesador de Pagos",
-			"description": "Procesar pagos PSE, Nequi, etc.",
+			"description": "Cobrar con Wompi (PSE, Nequi, tarjeta, transferencia Bancolombia, link de pago), efectivo en Efecty/Baloto o contraentrega",
 			"category":    "ventas",
 			"available":   tenant.IsFeatureEnabled("ecommerce_tool"),
 		},
@@ -369,6 +905,27 @@ func getMCPToolsForTenant(tenant *models.Tenant) []map[string]interface{} {
 			"category":    "contabilidad",
 			"available":   true,
 		},
+		{
+			"name":         "tax_calculator",
+			"display_name": "Calculadora de Impuestos",
+			"description":  "Liquidar IVA, INC, retención en la fuente, ReteIVA y ReteICA",
+			"category":     "contabilidad",
+			"available":    true,
+		},
+		{
+			"name":         "support_document_generator",
+			"display_name": "Documento Soporte DIAN",
+			"description":  "Emitir documentos soporte por compras a no obligados a facturar",
+			"category":     "contabilidad",
+			"available":    true,
+		},
+		{
+			"name":         "payroll_generator",
+			"display_name": "Nómina Electrónica DIAN",
+			"description":  "Emitir documentos de nómina electrónica individual",
+			"category":     "contabilidad",
+			"available":    true,
+		},
 		{
 			"name":        "faq_searcher",
 			"display_name": "Buscador de FAQ",
@@ -376,6 +933,20 @@ func getMCPToolsForTenant(tenant *models.Tenant) []map[string]interface{} {
 			"category":    "soporte",
 			"available":   true,
 		},
+		{
+			"name":         "consent_capture",
+			"display_name": "Autorización de Datos Personales",
+			"description":  "Registrar la autorización de tratamiento de datos del cliente (Ley 1581 de 2012) con su evidencia",
+			"category":     "soporte",
+			"available":    true,
+		},
+		{
+			"name":         "data_subject_request",
+			"display_name": "Solicitudes de Habeas Data",
+			"description":  "Radicar consultas, rectificaciones, supresiones y revocatorias de datos personales con su plazo legal",
+			"category":     "soporte",
+			"available":    true,
+		},
 	}
 
 	// Filtrar herramientas disponibles según el plan
@@ -389,6 +960,24 @@ func getMCPToolsForTenant(tenant *models.Tenant) []map[string]interface{} {
 	return availableTools
 }
 
+// businessHoursResponse describe el horario de atención y si hoy se atiende,
+// teniendo en cuenta los festivos nacionales
+func businessHoursResponse(now time.Time) string {
+	hours := colombia.DefaultBusinessHours
+	response := fmt.Sprintf("Atendemos %s, excepto festivos.", hours.Describe())
+
+	if hours.IsOpen(now) {
+		return response + " En este momento estamos atendiendo."
+	}
+	if holiday, ok := colombia.IsHoliday(now); ok {
+		response += fmt.Sprintf(" Hoy es festivo (%s).", holiday.Name)
+	}
+	if next := hours.NextOpening(now); !next.IsZero() {
+		response += fmt.Sprintf(" Volvemos a atender el %s a las %s.", colombia.FormatDate(next), next.Format("15:04"))
+	}
+	return response
+}
+
 func simulateAgentResponse(agentID, message string, tenant *models.Tenant) *AgentResponse {
 	// Simular respuesta inteligente del agente
 	responses := map[string]string{
@@ -396,7 +985,7 @@ func simulateAgentResponse(agentID, message string, tenant *models.Tenant) *Agen
 		"envio":       "El envío a Bogotá cuesta $12.000 COP y demora 2 días hábiles.",
 		"pago":        "Aceptamos PSE, Nequi, tarjetas y efectivo. ¿Con cuál prefieres pagar?",
 		"disponible":  "Tenemos 25 unidades disponibles en stock.",
-		"horarios":    "Atendemos de lunes a viernes de 8:00 AM a 6:00 PM y sábados de 9:00 AM a 2:00 PM.",
+		"horarios":    businessHoursResponse(time.Now()),
 	}
 
 	// Buscar respuesta más relevante
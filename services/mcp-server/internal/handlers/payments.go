@@ -0,0 +1,627 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"mcp-server/internal/models"
+	"mcp-server/pkg/payments"
+	"mcp-server/pkg/payments/wompi"
+	"mcp-server/pkg/sarlaft"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PaymentsHandler maneja los cobros con Wompi (PSE, Nequi, tarjeta,
+// transferencia Bancolombia y links de pago) y sus webhooks
+type PaymentsHandler struct {
+	paymentsService *payments.Service
+}
+
+// NewPaymentsHandler crea una nueva instancia del handler
+func NewPaymentsHandler(paymentsService *payments.Service) *PaymentsHandler {
+	return &PaymentsHandler{
+		paymentsService: paymentsService,
+	}
+}
+
+// paymentRequest datos de un cobro recibidos por la API o por la herramienta MCP
+type paymentRequest struct {
+	OrderID        string `json:"order_id,omitempty"`
+	Method         string `json:"method" validate:"required"` // pse, nequi, card, bancolombia_transfer, link, efectivo, contraentrega
+	Amount         int64  `json:"amount_cop" validate:"required"`
+	Description    string `json:"description,omitempty"`
+	CustomerEmail  string `json:"customer_email" validate:"required,email"`
+	CustomerName   string `json:"customer_name,omitempty"`
+	CustomerPhone  string `json:"customer_phone,omitempty"` // Nequi
+	DocumentType   string `json:"document_type,omitempty"`  // PSE
+	DocumentNumber string `json:"document_number,omitempty"`
+	PersonType     string `json:"person_type,omitempty"`
+	BankCode       string `json:"bank_code,omitempty"`  // PSE: código o nombre de la entidad financiera
+	CardToken      string `json:"card_token,omitempty"` // Token de Wompi generado en el navegador
+	Installments   int    `json:"installments,omitempty"`
+	RedirectURL    string `json:"redirect_url,omitempty"`
+	ExpiresMinutes int    `json:"expires_minutes,omitempty"`
+	CashNetwork    string `json:"cash_network,omitempty"` // Efectivo: efecty, baloto
+	Carrier        string `json:"carrier,omitempty"`      // Contraentrega
+	TrackingNumber string `json:"tracking_number,omitempty"`
+}
+
+// Nombres en español con que llegan los medios de pago
+var paymentMethodAliases = map[string]string{
+	"tarjeta":        payments.MethodCard,
+	"credito":        payments.MethodCard,
+	"crédito":        payments.MethodCard,
+	"bancolombia":    payments.MethodBancolombiaTransfer,
+	"transferencia":  payments.MethodBancolombiaTransfer,
+	"link_de_pago":   payments.MethodLink,
+	"contra entrega": payments.MethodCashOnDelivery,
+	"contra_entrega": payments.MethodCashOnDelivery,
+	"cod":            payments.MethodCashOnDelivery,
+	"efecty":         payments.MethodCash,
+	"baloto":         payments.MethodCash,
+}
+
+func (r paymentRequest) method() string {
+	method := strings.ToLower(strings.TrimSpace(r.Method))
+	if alias, ok := paymentMethodAliases[method]; ok {
+		return alias
+	}
+	return method
+}
+
+// cashNetwork red de recaudo; "efecty" o "baloto" también llegan como medio
+func (r paymentRequest) cashNetwork() string {
+	if r.CashNetwork != "" {
+		return r.CashNetwork
+	}
+	if method := strings.ToLower(strings.TrimSpace(r.Method)); method != payments.MethodCash {
+		return method
+	}
+	return payments.ProviderEfecty
+}
+
+func (r paymentRequest) customer() payments.Customer {
+	return payments.Customer{
+		Email:          r.CustomerEmail,
+		Name:           r.CustomerName,
+		Phone:          r.CustomerPhone,
+		DocumentType:   r.DocumentType,
+		DocumentNumber: r.DocumentNumber,
+		PersonType:     r.PersonType,
+	}
+}
+
+// createPayment crea el pago según el medio: transacción o link de Wompi,
+// referencia de efectivo o contraentrega
+func createPayment(ctx context.Context, paymentsService *payments.Service, tenant *models.Tenant, request paymentRequest) (*payments.Payment, error) {
+	cfg := paymentsConfigForTenant(tenant)
+	expiresIn := time.Duration(request.ExpiresMinutes) * time.Minute
+
+	switch request.method() {
+	case payments.MethodLink:
+		return paymentsService.CreatePaymentLink(ctx, cfg, payments.LinkRequest{
+			OrderID:     request.OrderID,
+			Description: request.Description,
+			Amount:      request.Amount,
+			RedirectURL: request.RedirectURL,
+			ExpiresIn:   expiresIn,
+			SingleUse:   request.OrderID != "",
+		})
+	case payments.MethodCash:
+		cashRequest := payments.CashRequest{
+			OrderID:     request.OrderID,
+			Network:     request.cashNetwork(),
+			Amount:      request.Amount,
+			Description: request.Description,
+			Customer:    request.customer(),
+		}
+		if expiresIn > 0 {
+			cashRequest.ExpiresAt = time.Now().Add(expiresIn)
+		}
+		return paymentsService.CreateCashVoucher(ctx, cfg, cashRequest)
+	case payments.MethodCashOnDelivery:
+		return paymentsService.CreateCashOnDelivery(ctx, cfg, payments.CODRequest{
+			OrderID:        request.OrderID,
+			Amount:         request.Amount,
+			Description:    request.Description,
+			Customer:       request.customer(),
+			Carrier:        request.Carrier,
+			TrackingNumber: request.TrackingNumber,
+		})
+	}
+
+	return paymentsService.CreatePayment(ctx, cfg, payments.PaymentRequest{
+		OrderID:      request.OrderID,
+		Method:       request.method(),
+		Amount:       request.Amount,
+		Description:  request.Description,
+		Customer:     request.customer(),
+		RedirectURL:  request.RedirectURL,
+		BankCode:     request.BankCode,
+		CardToken:    request.CardToken,
+		Installments: request.Installments,
+		ExpiresIn:    expiresIn,
+	})
+}
+
+// CreatePayment inicia el cobro de un pedido con Wompi
+func (h *PaymentsHandler) CreatePayment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request paymentRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de pago inválidos",
+		})
+	}
+
+	payment, err := createPayment(c.Context(), h.paymentsService, tenant, request)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": paymentStatusText(payment),
+		"data":    payment,
+	})
+}
+
+// ProcessPSEPayment inicia un pago PSE y retorna la URL del banco
+func (h *PaymentsHandler) ProcessPSEPayment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		OrderID        string `json:"order_id,omitempty"`
+		Amount         int64  `json:"amount_cop" validate:"required,min=1000"`
+		Description    string `json:"description" validate:"required"`
+		CustomerEmail  string `json:"customer_email" validate:"required,email"`
+		Bank           string `json:"bank" validate:"required"` // Código o nombre de la entidad financiera (ver /payments/pse/banks)
+		DocumentType   string `json:"document_type" validate:"required"`
+		DocumentNumber string `json:"document_number" validate:"required"`
+		PersonType     string `json:"person_type,omitempty"`
+		RedirectURL    string `json:"redirect_url,omitempty"`
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de pago inválidos",
+		})
+	}
+
+	payment, err := createPayment(c.Context(), h.paymentsService, tenant, paymentRequest{
+		OrderID:        request.OrderID,
+		Method:         payments.MethodPSE,
+		Amount:         request.Amount,
+		Description:    request.Description,
+		CustomerEmail:  request.CustomerEmail,
+		DocumentType:   request.DocumentType,
+		DocumentNumber: request.DocumentNumber,
+		PersonType:     request.PersonType,
+		BankCode:       request.Bank,
+		RedirectURL:    request.RedirectURL,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Pago PSE iniciado. Redirige al cliente a payment_url",
+		"data":    payment,
+	})
+}
+
+// ListPSEBanks lista las entidades financieras disponibles para PSE
+func (h *PaymentsHandler) ListPSEBanks(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	banks, err := h.paymentsService.FinancialInstitutions(c.Context(), paymentsConfigForTenant(tenant))
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"banks": banks,
+			"total": len(banks),
+		},
+	})
+}
+
+// GetPayment consulta un pago; con ?refresh=true consulta el estado en Wompi
+func (h *PaymentsHandler) GetPayment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var payment *payments.Payment
+	var err error
+	if c.Query("refresh") == "true" {
+		payment, err = h.paymentsService.RefreshStatus(c.Context(), paymentsConfigForTenant(tenant), c.Params("id"))
+	} else {
+		payment, err = h.paymentsService.GetPayment(tenant.ID, c.Params("id"))
+	}
+	if err != nil {
+		status := fiber.StatusBadGateway
+		if errors.Is(err, payments.ErrPaymentNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    payment,
+	})
+}
+
+// ListPayments lista los pagos del tenant, opcionalmente de un pedido (?order_id=)
+func (h *PaymentsHandler) ListPayments(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	list := h.paymentsService.ListPayments(tenant.ID, c.Query("order_id"))
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"payments": list,
+			"total":    len(list),
+		},
+	})
+}
+
+// WompiWebhook recibe los eventos de Wompi. Responde 200 a todo evento con
+// firma válida para que Wompi no lo reintente; un checksum inválido es 401.
+func (h *PaymentsHandler) WompiWebhook(c *fiber.Ctx) error {
+	payment, err := h.paymentsService.HandleWompiEvent(c.Body(), c.Get("X-Event-Checksum"))
+	switch {
+	case errors.Is(err, wompi.ErrInvalidChecksum):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	case errors.Is(err, payments.ErrPaymentNotFound):
+		log.Printf("⚠️ Evento de Wompi sin pago asociado: %v", err)
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "Evento ignorado: " + err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Evento aplicado",
+		"data": fiber.Map{
+			"payment_id":   payment.ID,
+			"order_id":     payment.OrderID,
+			"status":       payment.Status,
+			"order_status": orderStatus(h.paymentsService, payment),
+		},
+	})
+}
+
+// ConfirmCashPayment registra el recaudo de una referencia de efectivo
+// reportado por Efecty o Baloto (archivo de recaudo o notificación). Lo
+// concilia un usuario autenticado del comercio (owner o admin).
+func (h *PaymentsHandler) ConfirmCashPayment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		PaymentCode string `json:"payment_code" validate:"required"`
+		Network     string `json:"network,omitempty"`
+		Amount      int64  `json:"amount_cop" validate:"required"`
+		Receipt     string `json:"receipt,omitempty"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de recaudo inválidos",
+		})
+	}
+
+	payment, err := h.paymentsService.ConfirmCashPayment(tenant.ID, payments.CashConfirmation{
+		PaymentCode: request.PaymentCode,
+		Network:     request.Network,
+		Amount:      request.Amount,
+		Receipt:     request.Receipt,
+	})
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": paymentStatusText(payment),
+		"data":    payment,
+	})
+}
+
+// AttachShipment asocia la guía de la transportadora a un pago contraentrega
+func (h *PaymentsHandler) AttachShipment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		Carrier        string `json:"carrier" validate:"required"`
+		TrackingNumber string `json:"tracking_number" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de envío inválidos",
+		})
+	}
+
+	payment, err := h.paymentsService.AttachShipment(tenant.ID, c.Params("id"), request.Carrier, request.TrackingNumber)
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Guía %s asociada al pago %s", request.TrackingNumber, payment.Reference),
+		"data":    payment,
+	})
+}
+
+// ShipmentEvent concilia un pago contraentrega con un evento del envío
+// (entregado aprueba el pago, devuelto lo rechaza). Es la conciliación manual
+// del comercio autenticado; las transportadoras reportan por el webhook firmado
+// /webhooks/shipping/:carrier.
+func (h *PaymentsHandler) ShipmentEvent(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		Carrier         string    `json:"carrier"`
+		TrackingNumber  string    `json:"tracking_number" validate:"required"`
+		Status          string    `json:"status" validate:"required"`
+		Description     string    `json:"description,omitempty"`
+		CollectedAmount int64     `json:"collected_amount_cop,omitempty"`
+		At              time.Time `json:"at,omitempty"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Evento de envío inválido",
+		})
+	}
+
+	payment, err := h.paymentsService.HandleShipmentEvent(tenant.ID, payments.ShipmentEvent{
+		Carrier:         request.Carrier,
+		TrackingNumber:  request.TrackingNumber,
+		Status:          request.Status,
+		Description:     request.Description,
+		CollectedAmount: request.CollectedAmount,
+		At:              request.At,
+	})
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Envío %s: pago %s %s", request.TrackingNumber, payment.Reference, payment.Status),
+		"data":    payment,
+	})
+}
+
+// RefundPayment reembolsa total o parcialmente un pago aprobado. El
+// encabezado Idempotency-Key evita reembolsos duplicados en los reintentos.
+func (h *PaymentsHandler) RefundPayment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		Amount         int64  `json:"amount_cop,omitempty"` // Vacío: todo el saldo reembolsable
+		Reason         string `json:"reason,omitempty"`
+		IdempotencyKey string `json:"idempotency_key,omitempty"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de reembolso inválidos",
+		})
+	}
+	key := c.Get("Idempotency-Key")
+	if key == "" {
+		key = request.IdempotencyKey
+	}
+
+	payment, refund, err := h.paymentsService.RefundPayment(c.Context(), paymentsConfigForTenant(tenant), c.Params("id"), payments.RefundRequest{
+		Amount:         request.Amount,
+		Reason:         request.Reason,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	message := fmt.Sprintf("Reembolso %s por $%d %s", refund.ID, refund.Amount, refund.Status)
+	if refund.CreditNoteNumber != "" {
+		message += fmt.Sprintf(". Nota crédito %s emitida", refund.CreditNoteNumber)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": message,
+		"data": fiber.Map{
+			"refund":       refund,
+			"payment":      payment,
+			"order_status": orderStatus(h.paymentsService, payment),
+		},
+	})
+}
+
+// VoidPayment anula un pago completo (tarjeta aprobada sin compensar, o
+// efectivo y contraentrega sin recaudo)
+func (h *PaymentsHandler) VoidPayment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		Reason string `json:"reason,omitempty"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "Datos de anulación inválidos",
+			})
+		}
+	}
+
+	payment, err := h.paymentsService.VoidPayment(c.Context(), paymentsConfigForTenant(tenant), c.Params("id"), request.Reason)
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Pago %s anulado", payment.Reference),
+		"data": fiber.Map{
+			"payment":      payment,
+			"order_status": orderStatus(h.paymentsService, payment),
+		},
+	})
+}
+
+// LinkInvoice asocia el pago a su factura DIAN, para que los reembolsos y
+// anulaciones emitan la nota crédito
+func (h *PaymentsHandler) LinkInvoice(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		InvoiceID string `json:"invoice_id" validate:"required"` // Id del documento en /colombia/documents
+	}
+	if err := c.BodyParser(&request); err != nil || request.InvoiceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invoice_id es requerido",
+		})
+	}
+
+	payment, err := h.paymentsService.LinkInvoice(c.Context(), paymentsConfigForTenant(tenant), c.Params("id"), request.InvoiceID)
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Pago %s asociado a la factura", payment.Reference),
+		"data":    payment,
+	})
+}
+
+// GetOrder consulta el estado de pago de un pedido y sus pagos
+func (h *PaymentsHandler) GetOrder(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	order, err := h.paymentsService.GetOrder(tenant.ID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"order":         order,
+			"ready_to_ship": order.ReadyToShip(),
+			"payments":      h.paymentsService.ListPayments(tenant.ID, order.ID),
+		},
+	})
+}
+
+// paymentError responde 404 si el pago no existe, 409 si la clave de
+// idempotencia choca y 400 en otro caso
+func paymentError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, payments.ErrPaymentNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, payments.ErrIdempotencyConflict):
+		status = fiber.StatusConflict
+	case errors.Is(err, sarlaft.ErrScreeningHold):
+		status = fiber.StatusForbidden
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   true,
+		"message": err.Error(),
+	})
+}
+
+// orderStatus estado del pedido según la máquina de estados, o el que
+// sugiere el pago si no tiene pedido
+func orderStatus(paymentsService *payments.Service, payment *payments.Payment) string {
+	if order, err := paymentsService.GetOrder(payment.TenantID, payment.OrderID); err == nil {
+		return order.Status
+	}
+	return payment.OrderStatus()
+}
+
+// paymentsConfigForTenant credenciales de Wompi del tenant (sin llave privada
+// cobra contra el stub local) y su configuración DIAN para las notas crédito
+func paymentsConfigForTenant(tenant *models.Tenant) payments.TenantConfig {
+	settings := tenant.Settings
+	return payments.TenantConfig{
+		TenantID: tenant.ID,
+		Wompi: wompi.Config{
+			PublicKey:       settings.WompiPublicKey,
+			PrivateKey:      settings.WompiAPIKey,
+			EventsSecret:    settings.WompiEventsSecret,
+			IntegritySecret: settings.WompiIntegritySecret,
+		},
+		CashAgreements: map[string]string{
+			payments.ProviderEfecty: settings.EfectyAgreement,
+			payments.ProviderBaloto: settings.BalotoAgreement,
+		},
+		DIAN: dianConfigForTenant(tenant),
+		Screening: sarlaft.Policy{
+			Enabled:   settings.SARLAFTScreening,
+			Threshold: settings.SARLAFTThreshold,
+		},
+	}
+}
+
+// paymentStatusText mensaje para el comercio según el estado del pago
+func paymentStatusText(payment *payments.Payment) string {
+	switch {
+	case payment.Status == payments.StatusApproved:
+		return fmt.Sprintf("Pago %s aprobado", payment.Reference)
+	case payment.Status == payments.StatusRefunded:
+		return fmt.Sprintf("Pago %s reembolsado en su totalidad", payment.Reference)
+	case payment.Final():
+		return fmt.Sprintf("Pago %s %s: %s", payment.Reference, payment.Status, payment.StatusMessage)
+	case payment.PaymentURL != "":
+		return fmt.Sprintf("Pago %s iniciado. Redirige al cliente a %s", payment.Reference, payment.PaymentURL)
+	case payment.Voucher != nil:
+		return fmt.Sprintf("Referencia de pago %s generada. %s", payment.Voucher.PaymentCode, payment.Voucher.Instructions)
+	case payment.Method == payments.MethodCashOnDelivery:
+		return fmt.Sprintf("Pago contraentrega %s registrado: el pedido puede despacharse y se cobra al entregar", payment.Reference)
+	case payment.Method == payments.MethodNequi:
+		return fmt.Sprintf("Pago %s enviado a Nequi. El cliente debe aprobarlo en su celular", payment.Reference)
+	default:
+		return fmt.Sprintf("Pago %s en proceso", payment.Reference)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-server/pkg/payments"
+	"mcp-server/pkg/payments/wompi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestWompiWebhookRejectsInvalidChecksum(t *testing.T) {
+	stub := wompi.NewStubServer("http://stub.local")
+	stub.SettleAfter = time.Hour
+	service := payments.NewService(stub)
+
+	type delivery struct {
+		body     []byte
+		checksum string
+	}
+	events := make(chan delivery, 1)
+	stub.OnEvent(func(body []byte, checksum string) {
+		events <- delivery{body, checksum}
+	})
+
+	payment, err := service.CreatePayment(context.Background(), payments.TenantConfig{TenantID: "tenant-prueba"}, payments.PaymentRequest{
+		OrderID:  "pedido-1",
+		Method:   payments.MethodNequi,
+		Amount:   50000,
+		Customer: payments.Customer{Email: "pagador@ejemplo.com.co", Phone: wompi.StubNequiApproved},
+	})
+	if err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+	stub.Settle(payment.ProviderID)
+	event := <-events
+
+	app := fiber.New()
+	app.Post("/webhooks/wompi", NewPaymentsHandler(service).WompiWebhook)
+	post := func(body []byte, checksum string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/wompi", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Event-Checksum", checksum)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	forged := strings.Replace(string(event.body), event.checksum, strings.Repeat("0", len(event.checksum)), 1)
+	tests := []struct {
+		name     string
+		body     []byte
+		checksum string
+		want     int
+	}{
+		{"checksum del cuerpo alterado", []byte(forged), strings.Repeat("0", len(event.checksum)), fiber.StatusUnauthorized},
+		{"encabezado que no coincide", event.body, strings.Repeat("f", len(event.checksum)), fiber.StatusUnauthorized},
+		{"datos alterados", bytes.Replace(event.body, []byte(`"APPROVED"`), []byte(`"VOIDED"`), 1), event.checksum, fiber.StatusUnauthorized},
+		{"evento firmado", event.body, event.checksum, fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := post(tt.body, tt.checksum); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
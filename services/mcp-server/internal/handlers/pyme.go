@@ -288,12 +288,16 @@ func getDefaultToolsForCategory(category string) []string {
 		},
 		"soporte": {
 			"faq_searcher",
+			"consent_capture",
+			"data_subject_request",
 			"ticket_creator",
 			"status_checker",
 			"troubleshooter",
 		},
 		"contabilidad": {
 			"invoice_generator",
+			"support_document_generator",
+			"payroll_generator",
 			"expense_tracker",
 			"tax_calculator",
 			"dian_reporter",
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"mcp-server/internal/models"
+	"mcp-server/pkg/sarlaft"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SARLAFTHandler administración del control SARLAFT: listas cargadas,
+// consultas de terceros y revisión de alertas por el oficial de cumplimiento
+type SARLAFTHandler struct {
+	sarlaftService *sarlaft.Service
+}
+
+// NewSARLAFTHandler crea una nueva instancia del handler
+func NewSARLAFTHandler(sarlaftService *sarlaft.Service) *SARLAFTHandler {
+	return &SARLAFTHandler{
+		sarlaftService: sarlaftService,
+	}
+}
+
+// ListLists listas restrictivas y de control cargadas
+func (h *SARLAFTHandler) ListLists(c *fiber.Ctx) error {
+	lists := h.sarlaftService.Lists()
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"lists": lists,
+			"total": len(lists),
+		},
+	})
+}
+
+// ReloadLists vuelve a leer los archivos de las listas. Las listas son de toda
+// la plataforma: solo las recarga su oficial de cumplimiento.
+func (h *SARLAFTHandler) ReloadLists(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+	if tenant.ID != models.PlatformTenant {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   true,
+			"message": "Solo el cumplimiento de la plataforma puede recargar las listas",
+		})
+	}
+
+	lists, err := h.sarlaftService.Reload()
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+			"data":    lists,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("%d listas cargadas", len(lists)),
+		"data":    lists,
+	})
+}
+
+// Screen consulta a un tercero (cliente, proveedor, empleado) en las listas
+func (h *SARLAFTHandler) Screen(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		Name           string  `json:"name"`
+		DocumentType   string  `json:"document_type,omitempty"`
+		DocumentNumber string  `json:"document_number,omitempty"`
+		PersonType     string  `json:"person_type,omitempty"` // natural, juridica
+		Reference      string  `json:"reference,omitempty"`
+		Threshold      float64 `json:"threshold,omitempty"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de la consulta inválidos",
+		})
+	}
+
+	threshold := request.Threshold
+	if threshold == 0 {
+		threshold = tenant.Settings.SARLAFTThreshold
+	}
+	screening, err := h.sarlaftService.Screen(sarlaft.ScreenRequest{
+		TenantID:  tenant.ID,
+		Context:   sarlaft.ContextManual,
+		Reference: request.Reference,
+		Subject: sarlaft.Subject{
+			Name:           request.Name,
+			DocumentType:   request.DocumentType,
+			DocumentNumber: request.DocumentNumber,
+			PersonType:     request.PersonType,
+		},
+		Threshold: threshold,
+	})
+	if err != nil {
+		return sarlaftError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": screeningText(screening),
+		"data":    screening,
+	})
+}
+
+// GetScreening consulta con coincidencias y el estado actual de sus alertas
+func (h *SARLAFTHandler) GetScreening(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	screening, err := h.sarlaftService.GetScreening(tenant.ID, c.Params("id"))
+	if err != nil {
+		return sarlaftError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    screening,
+	})
+}
+
+// ListHits alertas del tenant (?status=pendiente|confirmada|descartada,
+// ?context=pago|vinculacion|consulta)
+func (h *SARLAFTHandler) ListHits(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	hits := h.sarlaftService.Hits(tenant.ID, c.Query("status"), c.Query("context"))
+	pending := 0
+	for _, hit := range hits {
+		if hit.Status == sarlaft.HitPending {
+			pending++
+		}
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"hits":    hits,
+			"total":   len(hits),
+			"pending": pending,
+		},
+	})
+}
+
+// GetHit consulta una alerta
+func (h *SARLAFTHandler) GetHit(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	hit, err := h.sarlaftService.GetHit(tenant.ID, c.Params("id"))
+	if err != nil {
+		return sarlaftError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    hit,
+	})
+}
+
+// ReviewHit registra la decisión del oficial de cumplimiento sobre la alerta
+func (h *SARLAFTHandler) ReviewHit(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+	user := c.Locals("user").(*models.User)
+
+	var request struct {
+		Status string `json:"status" validate:"required"` // confirmada, descartada
+		Notes  string `json:"notes" validate:"required"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de la revisión inválidos",
+		})
+	}
+
+	hit, err := h.sarlaftService.ReviewHit(tenant.ID, c.Params("id"), sarlaft.Review{
+		Status:   request.Status,
+		Reviewer: user.Email,
+		Notes:    request.Notes,
+	})
+	if err != nil {
+		return sarlaftError(c, err)
+	}
+	message := fmt.Sprintf("Alerta %s %s", hit.ID, hit.Status)
+	if hit.Status == sarlaft.HitConfirmed && hit.Restrictive {
+		message += ": no opere con el tercero y reporte la operación a la UIAF (ROS)"
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": message,
+		"data":    hit,
+	})
+}
+
+// Helper functions
+
+// sarlaftError responde 404 si la alerta o la consulta no existen y 400 en
+// otro caso
+func sarlaftError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	if errors.Is(err, sarlaft.ErrHitNotFound) || errors.Is(err, sarlaft.ErrScreeningNotFound) {
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   true,
+		"message": err.Error(),
+	})
+}
+
+func screeningText(screening *sarlaft.Screening) string {
+	switch screening.Result {
+	case sarlaft.ResultBlocked:
+		return "Tercero retenido: " + screening.Summary()
+	case sarlaft.ResultReview:
+		return fmt.Sprintf("%d coincidencias en listas de control para revisión", len(screening.Hits))
+	default:
+		return "Sin coincidencias en las listas consultadas"
+	}
+}
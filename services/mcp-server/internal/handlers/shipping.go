@@ -0,0 +1,632 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"mcp-server/internal/models"
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/notify"
+	"mcp-server/pkg/payments"
+	"mcp-server/pkg/shipping"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ShippingHandler maneja las cotizaciones y guías con Servientrega,
+// Coordinadora, Interrapidísimo y TCC (por ahora en el simulador)
+type ShippingHandler struct {
+	shippingService *shipping.Service
+	paymentsService *payments.Service
+	notifyService   *notify.Service
+}
+
+// NewShippingHandler crea una nueva instancia del handler
+func NewShippingHandler(shippingService *shipping.Service, paymentsService *payments.Service, notifyService *notify.Service) *ShippingHandler {
+	return &ShippingHandler{
+		shippingService: shippingService,
+		paymentsService: paymentsService,
+		notifyService:   notifyService,
+	}
+}
+
+// shippingQuoteRequest datos de una cotización recibidos por la API o por las
+// herramientas MCP
+type shippingQuoteRequest struct {
+	OriginCity     string `json:"origin_city,omitempty"` // Vacío: ciudad del negocio
+	DestinyCity    string `json:"destiny_city" validate:"required"`
+	Weight         int    `json:"weight_grams" validate:"required,min=1"`
+	Length         int    `json:"length_cm,omitempty"` // Medidas de cada pieza para el peso volumétrico
+	Width          int    `json:"width_cm,omitempty"`
+	Height         int    `json:"height_cm,omitempty"`
+	DeclaredValue  int64  `json:"declared_value_cop,omitempty"`
+	Pieces         int    `json:"pieces,omitempty"`
+	CashOnDelivery int64  `json:"cash_on_delivery_cop,omitempty"`
+	Strategy       string `json:"strategy,omitempty"` // precio, tiempo
+}
+
+// quoteRequest resuelve las ciudades contra el catálogo DIVIPOLA
+func (r shippingQuoteRequest) quoteRequest(tenant *models.Tenant) (shipping.QuoteRequest, error) {
+	origin, err := shippingOrigin(tenant, r.OriginCity)
+	if err != nil {
+		return shipping.QuoteRequest{}, err
+	}
+	destiny, err := colombia.ResolveMunicipality(r.DestinyCity)
+	if err != nil {
+		return shipping.QuoteRequest{}, fmt.Errorf("ciudad de destino inválida: %w", err)
+	}
+	return shipping.QuoteRequest{
+		Origin:      origin,
+		Destination: destiny,
+		Package: shipping.Package{
+			WeightGrams:   r.Weight,
+			LengthCm:      r.Length,
+			WidthCm:       r.Width,
+			HeightCm:      r.Height,
+			DeclaredValue: r.DeclaredValue,
+			Pieces:        r.Pieces,
+		},
+		CashOnDelivery: r.CashOnDelivery,
+	}, nil
+}
+
+// shippingOrigin ciudad de origen del envío: la indicada o la del negocio
+func shippingOrigin(tenant *models.Tenant, city string) (colombia.Municipality, error) {
+	if city == "" {
+		if origin, ok := colombia.MunicipalityByCode(tenant.Settings.CityCode); ok {
+			return origin, nil
+		}
+		city = tenant.Settings.City
+	}
+	if city == "" {
+		return colombia.Municipality{}, fmt.Errorf("ciudad de origen requerida: configure la ciudad del negocio o envíe origin_city")
+	}
+	origin, err := colombia.ResolveMunicipality(city)
+	if err != nil {
+		return colombia.Municipality{}, fmt.Errorf("ciudad de origen inválida: %w", err)
+	}
+	return origin, nil
+}
+
+// ListCarriers lista las transportadoras y si el tenant las tiene habilitadas
+func (h *ShippingHandler) ListCarriers(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    h.shippingService.Carriers(shippingConfigForTenant(tenant)),
+	})
+}
+
+// QuoteShipping cotiza con todas las transportadoras habilitadas y elige la
+// mejor por precio o por tiempo de entrega
+func (h *ShippingHandler) QuoteShipping(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request shippingQuoteRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de cotización inválidos",
+		})
+	}
+
+	quoteRequest, err := request.quoteRequest(tenant)
+	if err != nil {
+		return shippingError(c, err)
+	}
+	result, err := h.shippingService.Quote(c.Context(), shippingConfigForTenant(tenant), quoteRequest, request.Strategy)
+	if err != nil {
+		return shippingError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": shippingSelectionText(result),
+		"data":    result,
+	})
+}
+
+// CreateShipment genera la guía con la transportadora indicada o con la mejor
+// cotización. Con payment_id, la guía recauda el pago contraentrega.
+func (h *ShippingHandler) CreateShipment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var request struct {
+		shippingQuoteRequest
+		Carrier           string `json:"carrier,omitempty"` // Vacío: la mejor cotización
+		OrderID           string `json:"order_id,omitempty"`
+		PaymentID         string `json:"payment_id,omitempty"` // Pago contraentrega a recaudar
+		RecipientName     string `json:"recipient_name" validate:"required"`
+		RecipientDocument string `json:"recipient_document,omitempty"`
+		RecipientPhone    string `json:"recipient_phone" validate:"required"`
+		RecipientEmail    string `json:"recipient_email,omitempty"`
+		RecipientAddress  string `json:"recipient_address" validate:"required"`
+		Content           string `json:"content,omitempty"`
+		PaymentType       string `json:"payment_type,omitempty"` // Flete: origen, destino
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Datos de envío inválidos",
+		})
+	}
+
+	quoteRequest, err := request.quoteRequest(tenant)
+	if err != nil {
+		return shippingError(c, err)
+	}
+
+	// Las transportadoras rechazan direcciones en texto libre
+	address, err := colombia.ParseAddress(request.RecipientAddress)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Dirección del destinatario inválida: " + err.Error(),
+		})
+	}
+
+	// La transportadora notifica al destinatario por SMS/WhatsApp
+	phone, err := colombia.ParsePhone(request.RecipientPhone)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Teléfono del destinatario inválido: " + err.Error(),
+		})
+	}
+
+	carrier := ""
+	if request.Carrier != "" {
+		code, ok := shipping.NormalizeCarrier(request.Carrier)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "Transportadora no soportada: " + request.Carrier,
+			})
+		}
+		carrier = code
+	}
+
+	// El valor a recaudar es el del pago contraentrega
+	if request.PaymentID != "" {
+		payment, err := h.paymentsService.GetPayment(tenant.ID, request.PaymentID)
+		if err != nil {
+			return paymentError(c, err)
+		}
+		if payment.Method != payments.MethodCashOnDelivery {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": fmt.Sprintf("El pago %s no es contraentrega", payment.Reference),
+			})
+		}
+		quoteRequest.CashOnDelivery = payment.Amount
+		if request.OrderID == "" {
+			request.OrderID = payment.OrderID
+		}
+	}
+
+	shipment, err := h.shippingService.CreateShipment(c.Context(), shippingConfigForTenant(tenant), shipping.ShipmentRequest{
+		GuideRequest: shipping.GuideRequest{
+			QuoteRequest: quoteRequest,
+			Sender:       shipmentSender(tenant),
+			Recipient: shipping.Party{
+				Name:     request.RecipientName,
+				Document: request.RecipientDocument,
+				Phone:    phone.E164,
+				Email:    request.RecipientEmail,
+				Address:  address.Formatted,
+			},
+			Reference: request.OrderID,
+		},
+		Carrier:        carrier,
+		Strategy:       request.Strategy,
+		FreightPayment: request.PaymentType,
+	})
+	if err != nil {
+		return shippingError(c, err)
+	}
+
+	response := fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Guía %s de %s creada: $%s, entrega estimada %s", shipment.TrackingNumber, shipment.CarrierName, formatCOPAmount(int(shipment.Quote.Total)), colombia.FormatDate(shipment.Quote.EstimatedDelivery)),
+		"data":    shipment,
+	}
+	if request.PaymentID != "" {
+		payment, err := h.paymentsService.AttachShipment(tenant.ID, request.PaymentID, shipment.CarrierName, shipment.TrackingNumber)
+		if err != nil {
+			return paymentError(c, err)
+		}
+		response["payment"] = payment
+	}
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// ListShipments lista los envíos del tenant, opcionalmente de un pedido (?order_id=)
+func (h *ShippingHandler) ListShipments(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	list := h.shippingService.ListShipments(tenant.ID, c.Query("order_id"))
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"shipments": list,
+			"total":     len(list),
+		},
+	})
+}
+
+// GetShipment consulta un envío
+func (h *ShippingHandler) GetShipment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	shipment, err := h.shippingService.GetShipment(tenant.ID, c.Params("id"))
+	if err != nil {
+		return shippingError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    shipment,
+	})
+}
+
+// GetShipmentLabel descarga el rótulo PDF de la guía
+func (h *ShippingHandler) GetShipmentLabel(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	shipment, err := h.shippingService.GetShipment(tenant.ID, c.Params("id"))
+	if err != nil {
+		return shippingError(c, err)
+	}
+	label, err := h.shippingService.Label(c.Context(), shippingConfigForTenant(tenant), shipment.ID)
+	if err != nil {
+		return shippingError(c, err)
+	}
+
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", fmt.Sprintf("inline; filename=guia-%s-%s.pdf", shipment.Carrier, shipment.TrackingNumber))
+	return c.Send(label)
+}
+
+// TrackShipment consulta el rastreo con la transportadora
+func (h *ShippingHandler) TrackShipment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	shipment, err := h.shippingService.Track(c.Context(), shippingConfigForTenant(tenant), c.Params("id"))
+	if err != nil {
+		return shippingError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Guía %s de %s: %s", shipment.TrackingNumber, shipment.CarrierName, shipping.StatusLabel(shipment.Status)),
+		"data":    shipment,
+	})
+}
+
+// CancelShipment anula la guía; solo antes de que la transportadora la recoja
+func (h *ShippingHandler) CancelShipment(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	shipment, err := h.shippingService.Cancel(c.Context(), shippingConfigForTenant(tenant), c.Params("id"))
+	if err != nil {
+		return shippingError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Guía %s de %s anulada", shipment.TrackingNumber, shipment.CarrierName),
+		"data":    shipment,
+	})
+}
+
+// ListRateTables lista las tablas de tarifas negociadas del tenant,
+// opcionalmente de una transportadora (?carrier=)
+func (h *ShippingHandler) ListRateTables(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	carrier := ""
+	if name := c.Query("carrier"); name != "" {
+		code, ok := shipping.NormalizeCarrier(name)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "Transportadora no soportada: " + name,
+			})
+		}
+		carrier = code
+	}
+
+	list := h.shippingService.RateTables(tenant.ID, carrier)
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"rate_tables": list,
+			"total":       len(list),
+		},
+	})
+}
+
+// SaveRateTable carga la tabla de tarifas negociada con una transportadora:
+// zonas por código DIVIPOLA, rangos de peso, divisor volumétrico, recargo de
+// combustible, seguro y mínimos. Con :id reemplaza la tabla existente.
+func (h *ShippingHandler) SaveRateTable(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	var table shipping.RateTable
+	if err := c.BodyParser(&table); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Tabla de tarifas inválida",
+		})
+	}
+	table.ID = c.Params("id")
+
+	saved, err := h.shippingService.SaveRateTable(tenant.ID, table)
+	if err != nil {
+		return shippingError(c, err)
+	}
+
+	status := fiber.StatusCreated
+	if table.ID != "" {
+		status = fiber.StatusOK
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("%s guardada con %d zonas para %s", saved.Name, len(saved.Zones), shipping.CarrierName(saved.Carrier)),
+		"data":    saved,
+	})
+}
+
+// GetRateTable consulta una tabla de tarifas
+func (h *ShippingHandler) GetRateTable(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	table, err := h.shippingService.GetRateTable(tenant.ID, c.Params("id"))
+	if err != nil {
+		return shippingError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    table,
+	})
+}
+
+// DeleteRateTable elimina una tabla de tarifas; la transportadora vuelve a
+// cotizar con su API
+func (h *ShippingHandler) DeleteRateTable(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	if err := h.shippingService.DeleteRateTable(tenant.ID, c.Params("id")); err != nil {
+		return shippingError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Tabla de tarifas eliminada",
+	})
+}
+
+// CarrierWebhook recibe las notificaciones de rastreo de una transportadora
+// (/webhooks/shipping/:carrier), firmadas con la clave del comercio
+func (h *ShippingHandler) CarrierWebhook(c *fiber.Ctx) error {
+	result, err := h.shippingService.HandleWebhook(c.Context(), c.Params("carrier"), c.Body(), c.Get(shipping.WebhookSignatureHeader))
+	switch {
+	case errors.Is(err, shipping.ErrInvalidSignature):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	if result.Ignored > 0 {
+		log.Printf("⚠️ Webhook de %s: %d eventos de guías no registradas", shipping.CarrierName(result.Carrier), result.Ignored)
+	}
+	message := "Eventos aplicados"
+	if result.Applied == 0 {
+		message = "Evento ignorado: guía no registrada"
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": message,
+		"data":    result,
+	})
+}
+
+// TrackingPage página pública de rastreo que se enlaza en los avisos al
+// destinatario (/rastreo/:id)
+func (h *ShippingHandler) TrackingPage(c *fiber.Ctx) error {
+	tracking, err := h.shippingService.GetPublicTracking(c.Params("id"))
+	if err != nil {
+		if c.Accepts("text/html", "application/json") == "application/json" {
+			return shippingError(c, err)
+		}
+		c.Set("Content-Type", "text/html; charset=utf-8")
+		return c.Status(fiber.StatusNotFound).SendString("<html><body><h1>Envío no encontrado</h1></body></html>")
+	}
+	if c.Accepts("text/html", "application/json") == "application/json" {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"data":    tracking,
+		})
+	}
+
+	var page strings.Builder
+	fmt.Fprintf(&page, "<html><head><meta charset=\"utf-8\"><meta name=\"viewport\" content=\"width=device-width\"><title>Rastreo %s</title></head><body>",
+		html.EscapeString(tracking.TrackingNumber))
+	fmt.Fprintf(&page, "<h1>%s</h1><p>Pedido %s de %s</p>",
+		html.EscapeString(tracking.StatusLabel), html.EscapeString(tracking.Reference), html.EscapeString(tracking.Merchant))
+	fmt.Fprintf(&page, "<p>%s, guía <a href=\"%s\">%s</a>. Destino: %s. Entrega estimada: %s.</p>",
+		html.EscapeString(tracking.CarrierName), html.EscapeString(tracking.CarrierURL), html.EscapeString(tracking.TrackingNumber),
+		html.EscapeString(tracking.Destination), html.EscapeString(colombia.FormatDate(tracking.Estimated)))
+	if tracking.CashOnDelivery > 0 && !shipping.Final(tracking.Status) {
+		fmt.Fprintf(&page, "<p><strong>Pago contraentrega: $%s</strong></p>", formatCOPAmount(int(tracking.CashOnDelivery)))
+	}
+	page.WriteString("<ul>")
+	for i := len(tracking.Events) - 1; i >= 0; i-- {
+		event := tracking.Events[i]
+		fmt.Fprintf(&page, "<li>%s - %s: %s (%s)</li>",
+			html.EscapeString(event.At.Format("2006-01-02 15:04")), html.EscapeString(shipping.StatusLabel(event.Status)),
+			html.EscapeString(event.Description), html.EscapeString(event.Location))
+	}
+	page.WriteString("</ul></body></html>")
+
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.SendString(page.String())
+}
+
+// ListNotifications bandeja de salida de avisos al cliente final,
+// opcionalmente de un envío (?reference=)
+func (h *ShippingHandler) ListNotifications(c *fiber.Ctx) error {
+	tenant := c.Locals("tenant").(*models.Tenant)
+
+	list := h.notifyService.Outbox(tenant.ID, c.Query("reference"))
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"notifications": list,
+			"total":         len(list),
+		},
+	})
+}
+
+// SyncOrder lleva los cambios de estado de los envíos a los pedidos: el
+// estado del despacho y, en contraentrega, el recaudo o la devolución. Se
+// registra como listener del servicio de envíos.
+func (h *ShippingHandler) SyncOrder(ctx context.Context, shipment *shipping.Shipment, event shipping.TrackingEvent) {
+	if shipment.CashOnDelivery > 0 && (shipment.Status == shipping.StatusDelivered || shipment.Status == shipping.StatusReturned) {
+		_, err := h.paymentsService.HandleShipmentEvent(shipment.TenantID, payments.ShipmentEvent{
+			Carrier:         shipment.CarrierName,
+			TrackingNumber:  shipment.TrackingNumber,
+			Status:          shipment.Status,
+			Description:     event.Description,
+			CollectedAmount: event.CollectedAmount,
+			At:              event.At,
+		})
+		if err != nil && !errors.Is(err, payments.ErrPaymentNotFound) {
+			log.Printf("⚠️ Envío %s: no se pudo conciliar la contraentrega: %v", shipment.ID, err)
+		}
+	}
+	if shipment.Reference != "" {
+		// Los pedidos sin pagos registrados no tienen estado que actualizar
+		_, _ = h.paymentsService.UpdateFulfillment(shipment.TenantID, shipment.Reference, payments.Fulfillment{
+			ShipmentID:     shipment.ID,
+			Carrier:        shipment.Carrier,
+			TrackingNumber: shipment.TrackingNumber,
+			Status:         shipment.Status,
+			UpdatedAt:      event.At,
+		})
+	}
+}
+
+// Helper functions
+
+// shippingError responde 404 si el envío o la tabla no existen, 409 si la guía ya no se
+// puede anular, 501 si la transportadora solo opera en el simulador y 400 en otro caso
+func shippingError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, shipping.ErrShipmentNotFound), errors.Is(err, shipping.ErrRateTableNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, shipping.ErrNotCancellable):
+		status = fiber.StatusConflict
+	case errors.Is(err, shipping.ErrCarrierUnsupported):
+		status = fiber.StatusNotImplemented
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   true,
+		"message": err.Error(),
+	})
+}
+
+// shippingConfigForTenant transportadoras habilitadas del tenant con sus
+// credenciales; las que no tienen llave operan contra el simulador y las que
+// la tienen quedan como no soportadas hasta que exista su adaptador
+func shippingConfigForTenant(tenant *models.Tenant) shipping.TenantConfig {
+	settings := tenant.Settings
+	credentials := map[string]shipping.Credentials{
+		shipping.CarrierServientrega:    {APIKey: settings.ServientregaAPIKey},
+		shipping.CarrierCoordinadora:    {APIKey: settings.CoordinadoraAPIKey},
+		shipping.CarrierInterrapidisimo: {APIKey: settings.InterrapidisimoAPIKey},
+		shipping.CarrierTCC:             {APIKey: settings.TCCAPIKey},
+	}
+
+	fromEmail := settings.NotificationsEmail
+	if fromEmail == "" {
+		fromEmail = settings.BusinessEmail
+	}
+	cfg := shipping.TenantConfig{
+		TenantID:      tenant.ID,
+		Carriers:      make(map[string]shipping.Credentials),
+		WebhookSecret: settings.ShippingWebhookSecret,
+		Notify: notify.Config{
+			TenantID:              tenant.ID,
+			WhatsAppPhoneNumberID: settings.WhatsAppPhoneNumberID,
+			WhatsAppToken:         settings.WhatsAppAccessToken,
+			SMTPHost:              settings.SMTPHost,
+			SMTPPort:              settings.SMTPPort,
+			SMTPUser:              settings.SMTPUser,
+			SMTPPassword:          settings.SMTPPassword,
+			FromEmail:             fromEmail,
+			FromName:              settings.BusinessName,
+		},
+	}
+	if strings.TrimSpace(settings.ShippingCarriers) == "" {
+		cfg.Carriers = credentials
+		return cfg
+	}
+	for _, name := range strings.Split(settings.ShippingCarriers, ",") {
+		if code, ok := shipping.NormalizeCarrier(name); ok {
+			cfg.Carriers[code] = credentials[code]
+		}
+	}
+	return cfg
+}
+
+// shipmentSender remitente de los envíos: los datos del negocio del tenant
+func shipmentSender(tenant *models.Tenant) shipping.Party {
+	settings := tenant.Settings
+	sender := shipping.Party{
+		Name:     settings.BusinessName,
+		Document: settings.NITNumber,
+		Phone:    settings.BusinessPhone,
+		Email:    settings.BusinessEmail,
+		Address:  settings.BusinessAddress,
+	}
+	if sender.Name == "" {
+		sender.Name = tenant.Name
+	}
+	if phone, err := colombia.ParsePhone(settings.BusinessPhone); err == nil {
+		sender.Phone = phone.E164
+	}
+	if address, err := colombia.ParseAddress(settings.BusinessAddress); err == nil {
+		sender.Address = address.Formatted
+	}
+	return sender
+}
+
+// shippingSelectionText explica la transportadora elegida frente a las demás
+func shippingSelectionText(result *shipping.RateShopping) string {
+	selected := result.Selected
+	criterion := "la más económica"
+	if result.Strategy == shipping.SelectBySpeed {
+		criterion = "la más rápida"
+	}
+	service := selected.Service
+	if selected.RateTableID != "" {
+		service += ", tarifa negociada"
+	}
+	return fmt.Sprintf("%s es %s de %d cotizaciones para %s → %s: $%s en %d días hábiles (%s)",
+		selected.CarrierName, criterion, len(result.Quotes), result.Origin.Name, result.Destination.Name,
+		formatCOPAmount(int(selected.Total)), selected.DeliveryDays, service)
+}
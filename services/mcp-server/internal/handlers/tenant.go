@@ -1,20 +1,28 @@
 package handlers
 
 import (
+	"errors"
+	"mcp-server/internal/models"
 	"mcp-server/internal/tenant"
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/sarlaft"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // TenantHandler maneja las operaciones de tenants
 type TenantHandler struct {
-	tenantManager *tenant.TenantManager
+	tenantManager   *tenant.TenantManager
+	colombiaService *colombia.Service
+	sarlaftService  *sarlaft.Service
 }
 
 // NewTenantHandler crea un nuevo handler de tenants
-func NewTenantHandler(tenantManager *tenant.TenantManager) *TenantHandler {
+func NewTenantHandler(tenantManager *tenant.TenantManager, colombiaService *colombia.Service, sarlaftService *sarlaft.Service) *TenantHandler {
 	return &TenantHandler{
-		tenantManager: tenantManager,
+		tenantManager:   tenantManager,
+		colombiaService: colombiaService,
+		sarlaftService:  sarlaftService,
 	}
 }
 
@@ -27,6 +35,24 @@ func (h *TenantHandler) CreateTenant(c *fiber.Ctx) error {
 		})
 	}
 
+	// Precargar la empresa desde el RUT/RUES si se envió el NIT
+	if input.NIT != "" {
+		company, err := h.colombiaService.LookupCompany(c.Context(), input.NIT)
+		switch {
+		case err == nil:
+			input.Company = company
+			if input.CompanyName == "" {
+				input.CompanyName = company.LegalName
+			}
+		case errors.Is(err, colombia.ErrCompanyNotFound), errors.As(err, new(*colombia.RegistryError)):
+			// El registro es informativo; se continúa con los datos enviados
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
 	// Validar input
 	if input.Subdomain == "" || input.CompanyName == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -34,6 +60,20 @@ func (h *TenantHandler) CreateTenant(c *fiber.Ctx) error {
 		})
 	}
 
+	// Consultar la empresa en listas restrictivas antes de vincularla
+	screening, err := h.screenCompany(input)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if screening != nil && screening.Blocked() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":        "La vinculación requiere revisión de cumplimiento (SARLAFT)",
+			"screening_id": screening.ID,
+		})
+	}
+
 	// Crear tenant
 	tenant, err := h.tenantManager.CreateTenant(c.Context(), input)
 	if err != nil {
@@ -42,10 +82,57 @@ func (h *TenantHandler) CreateTenant(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"success": true,
 		"data":    tenant,
-	})
+	}
+	if screening != nil && screening.Result != sarlaft.ResultClear {
+		response["screening"] = screening
+	}
+	return c.JSON(response)
+}
+
+// screenCompany consulta la empresa por NIT y por su nombre y razón social
+// (la del registro si se encontró). Las alertas quedan para el cumplimiento de
+// la plataforma.
+func (h *TenantHandler) screenCompany(input tenant.CreateTenantInput) (*sarlaft.Screening, error) {
+	if h.sarlaftService == nil {
+		return nil, nil
+	}
+	names := []string{input.CompanyName}
+	personType := ""
+	if input.Company != nil {
+		names = append(names, input.Company.LegalName, input.Company.TradeName)
+		personType = input.Company.PersonType
+	}
+
+	var result *sarlaft.Screening
+	screened := make(map[string]bool)
+	for _, name := range names {
+		key := colombia.NormalizeText(name)
+		if key == "" || screened[key] {
+			continue
+		}
+		screened[key] = true
+		screening, err := h.sarlaftService.Screen(sarlaft.ScreenRequest{
+			TenantID:  models.PlatformTenant,
+			Context:   sarlaft.ContextOnboarding,
+			Reference: input.Subdomain,
+			Subject: sarlaft.Subject{
+				Name:           name,
+				DocumentType:   "NIT",
+				DocumentNumber: input.NIT,
+				PersonType:     personType,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if result == nil || screening.Blocked() || (screening.Result != sarlaft.ResultClear && !result.Blocked()) {
+			result = screening
+		}
+	}
+	return result, nil
 }
 
 // GetTenant obtiene información del tenant actual
@@ -85,9 +85,13 @@ func getTenantInfo(tenantID string) (*models.Tenant, error) {
 			Currency:   "COP",
 			Timezone:   "America/Bogota",
 			Language:   "es",
-			NITNumber:  "900123456-7",
+			NITNumber:  "900123456-8",
 			City:       "Bogotá",
 			Industry:   "retail",
+			CityCode:   "11001",
+			CIIU:       "4771",
+			PersonType: "juridica",
+			ResponsableIVA: true,
 		},
 		CreatedAt: "2024-01-01T00:00:00Z",
 		UpdatedAt: "2024-01-01T00:00:00Z",
@@ -1,5 +1,10 @@
 package models
 
+// PlatformTenant tenant de la propia plataforma: su owner o admin actúa como
+// Super Admin y bajo él se registran las alertas SARLAFT de la vinculación de
+// nuevos comercios
+const PlatformTenant = "plataforma"
+
 // Tenant representa una PYME en TausePro
 type Tenant struct {
 	ID        string         `json:"id" db:"id"`
@@ -22,6 +27,15 @@ type TenantSettings struct {
 	City      string `json:"city" db:"city"`           // Ciudad principal
 	Industry  string `json:"industry" db:"industry"`   // retail, services, manufacturing, etc
 
+	// Perfil tributario
+	CityCode          string `json:"city_code,omitempty" db:"city_code"` // Código DIVIPOLA de la ciudad principal
+	CIIU              string `json:"ciiu,omitempty" db:"ciiu"`           // Actividad económica principal
+	PersonType        string `json:"person_type,omitempty" db:"person_type"` // natural, juridica
+	ResponsableIVA    bool   `json:"responsable_iva" db:"responsable_iva"`
+	GranContribuyente bool   `json:"gran_contribuyente" db:"gran_contribuyente"`
+	Autorretenedor    bool   `json:"autorretenedor" db:"autorretenedor"`
+	RegimenSimple     bool   `json:"regimen_simple" db:"regimen_simple"`
+
 	// Configuraciones de negocio
 	BusinessName    string `json:"business_name" db:"business_name"`
 	BusinessAddress string `json:"business_address" db:"business_address"`
@@ -29,9 +43,51 @@ type TenantSettings struct {
 	BusinessEmail   string `json:"business_email" db:"business_email"`
 
 	// Integraciones Colombia
-	WompiAPIKey       string `json:"wompi_api_key,omitempty" db:"wompi_api_key"`
-	DIANAPIKey        string `json:"dian_api_key,omitempty" db:"dian_api_key"`
-	ServientregaAPIKey string `json:"servientrega_api_key,omitempty" db:"servientrega_api_key"`
+	WompiAPIKey       string `json:"wompi_api_key,omitempty" db:"wompi_api_key"` // Llave privada (prv_); vacío = sandbox (stub local)
+	WompiPublicKey    string `json:"wompi_public_key,omitempty" db:"wompi_public_key"`
+	WompiEventsSecret string `json:"-" db:"wompi_events_secret"`    // Verifica los webhooks
+	WompiIntegritySecret string `json:"-" db:"wompi_integrity_secret"` // Firma las transacciones
+	EfectyAgreement   string `json:"efecty_agreement,omitempty" db:"efecty_agreement"` // Convenio de recaudo; vacío = sandbox
+	BalotoAgreement   string `json:"baloto_agreement,omitempty" db:"baloto_agreement"`
+	DIANAPIKey        string `json:"dian_api_key,omitempty" db:"dian_api_key"` // PIN del software propio (CUDS, CUNE y CUDE)
+	ServientregaAPIKey string `json:"servientrega_api_key,omitempty" db:"servientrega_api_key"` // Vacío = sandbox (simulador); con llave la transportadora aún no está soportada
+	CoordinadoraAPIKey string `json:"coordinadora_api_key,omitempty" db:"coordinadora_api_key"`
+	InterrapidisimoAPIKey string `json:"interrapidisimo_api_key,omitempty" db:"interrapidisimo_api_key"`
+	TCCAPIKey         string `json:"tcc_api_key,omitempty" db:"tcc_api_key"`
+	ShippingCarriers  string `json:"shipping_carriers,omitempty" db:"shipping_carriers"` // Transportadoras habilitadas separadas por coma; vacío = todas
+	ShippingWebhookSecret string `json:"-" db:"shipping_webhook_secret"` // Verifica los webhooks de rastreo de las transportadoras
+
+	// Avisos al cliente final (WhatsApp Cloud API y correo SMTP); vacío = solo bandeja de salida
+	WhatsAppPhoneNumberID string `json:"whatsapp_phone_number_id,omitempty" db:"whatsapp_phone_number_id"`
+	WhatsAppAccessToken   string `json:"-" db:"whatsapp_access_token"`
+	SMTPHost          string `json:"smtp_host,omitempty" db:"smtp_host"`
+	SMTPPort          int    `json:"smtp_port,omitempty" db:"smtp_port"`
+	SMTPUser          string `json:"smtp_user,omitempty" db:"smtp_user"`
+	SMTPPassword      string `json:"-" db:"smtp_password"`
+	NotificationsEmail string `json:"notifications_email,omitempty" db:"notifications_email"` // Remitente de los correos; vacío = business_email
+
+	// Protección de datos personales (Ley 1581 de 2012)
+	PrivacyPolicyURL     string `json:"privacy_policy_url,omitempty" db:"privacy_policy_url"`
+	PrivacyPolicyVersion string `json:"privacy_policy_version,omitempty" db:"privacy_policy_version"` // Vacío = 1.0
+	PrivacyContactEmail  string `json:"privacy_contact_email,omitempty" db:"privacy_contact_email"` // Consultas y reclamos; vacío = business_email
+
+	// SARLAFT: consulta de pagadores en listas restrictivas (OFAC, ONU) y PEP
+	SARLAFTScreening bool    `json:"sarlaft_screening" db:"sarlaft_screening"`
+	SARLAFTThreshold float64 `json:"sarlaft_threshold,omitempty" db:"sarlaft_threshold"` // Similitud mínima de nombres (0 a 1); vacío = 0.88
+
+	// Facturación electrónica DIAN
+	DIANEnvironment   string `json:"dian_environment,omitempty" db:"dian_environment"` // simulador (habilitacion y produccion aún no soportados)
+	DIANSoftwareID    string `json:"dian_software_id,omitempty" db:"dian_software_id"`
+	DIANResolution    string `json:"dian_resolution,omitempty" db:"dian_resolution"`
+	DIANInvoicePrefix string `json:"dian_invoice_prefix,omitempty" db:"dian_invoice_prefix"`
+	DIANRangeFrom     int64  `json:"dian_range_from,omitempty" db:"dian_range_from"`
+	DIANRangeTo       int64  `json:"dian_range_to,omitempty" db:"dian_range_to"`
+	DIANTechnicalKey  string `json:"-" db:"dian_technical_key"` // Clave técnica de la resolución
+	DIANSupportResolution string `json:"dian_support_resolution,omitempty" db:"dian_support_resolution"` // Documento soporte
+	DIANSupportPrefix     string `json:"dian_support_prefix,omitempty" db:"dian_support_prefix"`
+	DIANSupportRangeFrom  int64  `json:"dian_support_range_from,omitempty" db:"dian_support_range_from"`
+	DIANSupportRangeTo    int64  `json:"dian_support_range_to,omitempty" db:"dian_support_range_to"`
+	DIANPayrollPrefix     string `json:"dian_payroll_prefix,omitempty" db:"dian_payroll_prefix"` // Nómina electrónica
 
 	// Configuraciones UI
 	PrimaryColor   string `json:"primary_color" db:"primary_color"`     // Color primario de la marca
@@ -16,7 +16,7 @@ type User struct {
 	Position  string `json:"position,omitempty" db:"position"` // Cargo en la empresa
 	
 	// Configuraciones Colombia
-	DocumentType   string `json:"document_type,omitempty" db:"document_type"` // CC, CE, TI
+	DocumentType   string `json:"document_type,omitempty" db:"document_type"` // CC, CE, TI, PA, PPT... (colombia.DocumentTypes)
 	DocumentNumber string `json:"document_number,omitempty" db:"document_number"`
 	
 	// Estados
@@ -149,7 +149,7 @@ type RegisterRequest struct {
 	NITNumber      string `json:"nit_number" validate:"required"`
 	City           string `json:"city" validate:"required"`
 	Industry       string `json:"industry" validate:"required"`
-	DocumentType   string `json:"document_type" validate:"required,oneof=CC CE TI"`
+	DocumentType   string `json:"document_type" validate:"required"` // Abreviatura o código DIAN (CC, CE, PA, PPT...)
 	DocumentNumber string `json:"document_number" validate:"required"`
 	Phone          string `json:"phone,omitempty"`
 }
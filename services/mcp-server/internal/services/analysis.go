@@ -6,52 +6,78 @@ import (
 	"fmt"
 	"log"
 	"mcp-server/internal/cache"
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/webcrawl"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
 // AnalysisService maneja el análisis automático de empresas
 type AnalysisService struct {
-	configService *ConfigService
-	httpClient    *http.Client
-	agentName     string
-	cache         *cache.RedisCache
+	configService  *ConfigService
+	httpClient     *http.Client
+	agentName      string
+	cache          *cache.RedisCache
+	searchProvider SearchProvider // Tavily por defecto
+	crawler        *webcrawl.Crawler
+
+	stageTimeouts map[string]time.Duration // Tiempo límite por etapa del análisis
+	deadline      time.Duration            // Plazo general del análisis
 }
 
 // MarketAnalysis representa el análisis completo de una empresa
 type MarketAnalysis struct {
-	Company             CompanyInfo          `json:"company"`
-	Industry            IndustryAnalysis     `json:"industry"`
-	Competitors         []CompetitorInfo     `json:"competitors"`
-	Opportunities       []Opportunity        `json:"opportunities"`
-	Recommendations     []Recommendation     `json:"recommendations"`
-	ColombiaContext     ColombiaMarketData   `json:"colombia_context"`
-	AnalysisDate        time.Time            `json:"analysis_date"`
-	DigitalizationScore *DigitalizationScore `json:"digitalization_score"`
+	Company             CompanyInfo              `json:"company"`
+	Industry            IndustryAnalysis         `json:"industry"`
+	Competitors         []CompetitorInfo         `json:"competitors"`
+	Opportunities       []Opportunity            `json:"opportunities"`
+	Recommendations     []Recommendation         `json:"recommendations"`
+	ColombiaContext     ColombiaMarketData       `json:"colombia_context"`
+	AnalysisDate        time.Time                `json:"analysis_date"`
+	DigitalizationScore *DigitalizationScore     `json:"digitalization_score"`
+	Sections            map[string]SectionStatus `json:"sections,omitempty"` // Estado de cada etapa del análisis
+	Partial             bool                     `json:"partial"`            // Alguna etapa falló o superó su tiempo límite
 }
 
 // CompanyInfo información básica de la empresa
 type CompanyInfo struct {
-	Name        string            `json:"name"`
-	URL         string            `json:"url"`
-	Description string            `json:"description"`
-	Industry    string            `json:"industry"`
-	Location    string            `json:"location"`
-	Size        string            `json:"size"`
-	Founded     string            `json:"founded"`
-	SocialMedia map[string]string `json:"social_media"`
-	Website     WebsiteAnalysis   `json:"website"`
+	Name            string            `json:"name"`
+	URL             string            `json:"url"`
+	Description     string            `json:"description"`
+	Industry        string            `json:"industry"`       // Sector según la sección CIIU
+	CIIU            string            `json:"ciiu,omitempty"` // Actividad económica estimada
+	CIIUDescription string            `json:"ciiu_description,omitempty"`
+	CIIUConfidence  float64           `json:"ciiu_confidence,omitempty"`
+	Location        string            `json:"location"`
+	Size            string            `json:"size"`
+	Founded         string            `json:"founded"`
+	SocialMedia     map[string]string `json:"social_media"`
+	Website         WebsiteAnalysis   `json:"website"`
 }
 
 // WebsiteAnalysis análisis del sitio web
 type WebsiteAnalysis struct {
-	Technologies []string    `json:"technologies"`
-	Performance  string      `json:"performance"`
-	SEO          SEOAnalysis `json:"seo"`
-	Ecommerce    bool        `json:"ecommerce"`
-	Blog         bool        `json:"blog"`
-	ContactInfo  bool        `json:"contact_info"`
+	Technologies   []string    `json:"technologies"`
+	Performance    string      `json:"performance"`
+	SEO            SEOAnalysis `json:"seo"`
+	Ecommerce      bool        `json:"ecommerce"`
+	Blog           bool        `json:"blog"`
+	ContactInfo    bool        `json:"contact_info"`
+	Crawled        bool        `json:"crawled"` // false si el sitio no se pudo visitar
+	FinalURL       string      `json:"final_url,omitempty"`
+	HTTPS          bool        `json:"https"`
+	Redirects      []string    `json:"redirects,omitempty"`
+	MobileFriendly bool        `json:"mobile_friendly"` // Declara viewport para móviles
+	ResponseTimeMS int64       `json:"response_time_ms"`
+	PageWeightKB   int64       `json:"page_weight_kb"` // Solo el HTML de la página de inicio
+	PagesCrawled   []string    `json:"pages_crawled,omitempty"`
+	Phones         []string    `json:"phones,omitempty"`
+	Emails         []string    `json:"emails,omitempty"`
+	WhatsApp       []string    `json:"whatsapp,omitempty"`
+	Signals        []string    `json:"signals,omitempty"` // Evidencia de tienda en línea y blog
+	Error          string      `json:"error,omitempty"`
 }
 
 // SEOAnalysis análisis SEO básico
@@ -59,12 +85,18 @@ type SEOAnalysis struct {
 	Title       string   `json:"title"`
 	Description string   `json:"description"`
 	Keywords    []string `json:"keywords"`
+	H1          []string `json:"h1,omitempty"`
+	H2          []string `json:"h2,omitempty"`
+	Canonical   string   `json:"canonical,omitempty"`
 	Score       int      `json:"score"`
+	Issues      []string `json:"issues,omitempty"`
 }
 
 // IndustryAnalysis análisis de la industria
 type IndustryAnalysis struct {
 	Name          string   `json:"name"`
+	CIIU          string   `json:"ciiu,omitempty"`
+	Section       string   `json:"section,omitempty"` // Sección CIIU (A-U)
 	Size          string   `json:"size"`
 	Growth        string   `json:"growth"`
 	Trends        []string `json:"trends"`
@@ -140,16 +172,37 @@ type TavilyResult struct {
 
 // NewAnalysisService crea una nueva instancia del servicio de análisis
 func NewAnalysisService(configService *ConfigService, cache *cache.RedisCache) *AnalysisService {
+	httpClient := &http.Client{Timeout: 30 * time.Second}
 	return &AnalysisService{
-		configService: configService,
-		httpClient:    &http.Client{Timeout: 30 * time.Second},
-		agentName:     "TausePro Market Intelligence",
-		cache:         cache,
+		configService:  configService,
+		httpClient:     httpClient,
+		agentName:      "TausePro Market Intelligence",
+		cache:          cache,
+		searchProvider: NewTavilyProvider(configService, httpClient),
+		crawler:        webcrawl.NewCrawler(),
+		stageTimeouts:  defaultStageTimeouts(),
+		deadline:       defaultAnalysisDeadline,
 	}
 }
 
-// AnalyzeCompany analiza una empresa completa
+// WithSearchProvider cambia el proveedor de búsqueda (fixtures, SearXNG o una
+// cadena de respaldo)
+func (s *AnalysisService) WithSearchProvider(provider SearchProvider) *AnalysisService {
+	s.searchProvider = provider
+	return s
+}
+
+// AnalyzeCompany analiza una empresa completa. Las etapas corren en paralelo
+// según sus dependencias, cada una con su tiempo límite y todas dentro del
+// plazo general; una etapa que falla o se demora deja datos por defecto y su
+// estado en Sections en lugar de abortar el análisis.
 func (s *AnalysisService) AnalyzeCompany(ctx context.Context, url string) (*MarketAnalysis, error) {
+	return s.AnalyzeCompanyWithProgress(ctx, url, nil)
+}
+
+// AnalyzeCompanyWithProgress analiza la empresa e informa el avance de cada
+// etapa. Un análisis en cache no reporta etapas.
+func (s *AnalysisService) AnalyzeCompanyWithProgress(ctx context.Context, url string, progress StageProgressFunc) (*MarketAnalysis, error) {
 	log.Printf("🔍 %s iniciando análisis de empresa: %s", s.agentName, url)
 
 	// 0. Verificar cache primero
@@ -162,61 +215,28 @@ func (s *AnalysisService) AnalyzeCompany(ctx context.Context, url string) (*Mark
 		}
 	}
 
-	// 1. Extraer información básica de la empresa
-	companyInfo, err := s.extractCompanyInfo(ctx, url)
-	if err != nil {
-		return nil, fmt.Errorf("error extrayendo información de empresa: %w", err)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("análisis cancelado: %w", err)
 	}
 
-	// 2. Investigar la industria
-	industryAnalysis, err := s.analyzeIndustry(ctx, companyInfo.Industry)
-	if err != nil {
-		return nil, fmt.Errorf("error analizando industria: %w", err)
-	}
-
-	// 3. Identificar competidores
-	competitors, err := s.findCompetitors(ctx, companyInfo.Name, companyInfo.Industry)
-	if err != nil {
-		return nil, fmt.Errorf("error encontrando competidores: %w", err)
-	}
-
-	// 4. Identificar oportunidades
-	opportunities, err := s.identifyOpportunities(ctx, companyInfo, industryAnalysis)
-	if err != nil {
-		return nil, fmt.Errorf("error identificando oportunidades: %w", err)
-	}
-
-	// 5. Generar recomendaciones
-	recommendations, err := s.generateRecommendations(ctx, companyInfo, industryAnalysis, competitors)
-	if err != nil {
-		return nil, fmt.Errorf("error generando recomendaciones: %w", err)
-	}
-
-	// 6. Contexto colombiano
-	colombiaContext, err := s.getColombiaContext(ctx, companyInfo.Industry)
-	if err != nil {
-		return nil, fmt.Errorf("error obteniendo contexto colombiano: %w", err)
-	}
-
-	analysis := &MarketAnalysis{
-		Company:         *companyInfo,
-		Industry:        *industryAnalysis,
-		Competitors:     competitors,
-		Opportunities:   opportunities,
-		Recommendations: recommendations,
-		ColombiaContext: *colombiaContext,
-		AnalysisDate:    time.Now(),
-	}
+	// 1-6. Empresa, industria, competidores, oportunidades, recomendaciones y
+	// contexto colombiano en paralelo según sus dependencias
+	analysis := s.runPipeline(ctx, url, progress)
+	analysis.AnalysisDate = time.Now()
 
 	// Agregar scoring robusto de digitalización
 	scoringService := NewScoringService()
 	digitalizationScore := scoringService.CalculateDigitalizationScore(analysis)
 	analysis.DigitalizationScore = digitalizationScore
 
-	log.Printf("Análisis completado para: %s", companyInfo.Name)
+	if analysis.Partial {
+		log.Printf("⚠️ Análisis parcial para: %s", analysis.Company.Name)
+	} else {
+		log.Printf("Análisis completado para: %s", analysis.Company.Name)
+	}
 
-	// Guardar en cache
-	if s.cache != nil {
+	// Guardar en cache; los análisis parciales se repiten en la próxima consulta
+	if s.cache != nil && !analysis.Partial {
 		if err := s.cache.CacheAnalysis(url, analysis); err != nil {
 			log.Printf("⚠️ Error guardando en cache: %v", err)
 		} else {
@@ -229,18 +249,23 @@ func (s *AnalysisService) AnalyzeCompany(ctx context.Context, url string) (*Mark
 
 // extractCompanyInfo extrae información básica de la empresa
 func (s *AnalysisService) extractCompanyInfo(ctx context.Context, url string) (*CompanyInfo, error) {
+	// El sitio se visita mientras se hacen las búsquedas
+	websiteDone := make(chan struct{})
+	var website WebsiteAnalysis
+	var site *webcrawl.Site
+	go func() {
+		defer close(websiteDone)
+		website, site = s.analyzeWebsite(ctx, url)
+	}()
+
 	// Búsqueda estructurada basada en la documentación de Tavily
 	query := fmt.Sprintf("%s empresa información oficial Colombia", url)
 
 	// Buscar información real con Tavily
-	results, err := s.searchTavily(ctx, query)
+	// Si la búsqueda falla se continúa con los datos que se deducen de la URL
+	results, err := s.search(ctx, query)
 	if err != nil {
-		// Si es error de configuración, continuar con datos por defecto
-		if strings.Contains(err.Error(), "API key de Tavily no válida") {
-			log.Printf("Tavily no configurado, usando datos por defecto: %v", err)
-		} else {
-			return nil, fmt.Errorf("error extrayendo información de empresa: %w", err)
-		}
+		log.Printf("⚠️ Búsqueda de la empresa %s falló, usando datos por defecto: %v", url, err)
 	}
 
 	// Procesar resultados reales de Tavily
@@ -249,6 +274,9 @@ func (s *AnalysisService) extractCompanyInfo(ctx context.Context, url string) (*
 		SocialMedia: make(map[string]string),
 	}
 
+	// Texto con el que se clasifica la actividad económica
+	activityText := ""
+
 	// Extraer información de los resultados de Tavily
 	if len(results) > 0 {
 		// Usar el primer resultado para extraer información básica
@@ -277,30 +305,31 @@ func (s *AnalysisService) extractCompanyInfo(ctx context.Context, url string) (*
 			}
 			companyInfo.Description = content
 		}
-
-		// Buscar información específica de la industria
-		industryQuery := fmt.Sprintf("%s industria sector mercado Colombia", companyInfo.Name)
-		industryResults, err := s.searchTavily(ctx, industryQuery)
-		if err == nil && len(industryResults) > 0 {
-			// Extraer industria del contenido
-			content := industryResults[0].Content
-			if strings.Contains(strings.ToLower(content), "tecnología") || strings.Contains(strings.ToLower(content), "tech") {
-				companyInfo.Industry = "Tecnología"
-			} else if strings.Contains(strings.ToLower(content), "comercio") || strings.Contains(strings.ToLower(content), "retail") {
-				companyInfo.Industry = "Comercio"
-			} else if strings.Contains(strings.ToLower(content), "servicios") {
-				companyInfo.Industry = "Servicios"
-			} else if strings.Contains(strings.ToLower(content), "manufactura") || strings.Contains(strings.ToLower(content), "industrial") {
-				companyInfo.Industry = "Manufactura"
-			} else {
-				companyInfo.Industry = "Servicios"
-			}
-		}
-
-		// Buscar información de redes sociales
-		socialQuery := fmt.Sprintf("%s redes sociales Facebook Instagram LinkedIn Twitter", companyInfo.Name)
-		socialResults, err := s.searchTavily(ctx, socialQuery)
-		if err == nil && len(socialResults) > 0 {
+		activityText = firstResult.Title + " " + firstResult.Content
+
+		// Las búsquedas de industria y redes sociales son independientes: se
+		// hacen en paralelo y sus errores solo reducen el detalle
+		var industryResults, socialResults []SearchResult
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			industryQuery := fmt.Sprintf("%s industria sector mercado Colombia", companyInfo.Name)
+			industryResults, _ = s.search(ctx, industryQuery)
+		}()
+		go func() {
+			defer wg.Done()
+			socialQuery := fmt.Sprintf("%s redes sociales Facebook Instagram LinkedIn Twitter", companyInfo.Name)
+			socialResults, _ = s.search(ctx, socialQuery)
+		}()
+		wg.Wait()
+
+		if len(industryResults) > 0 {
+			activityText += " " + industryResults[0].Content
+		}
+
+		// Redes sociales encontradas
+		if len(socialResults) > 0 {
 			content := socialResults[0].Content
 			if strings.Contains(content, "facebook.com") {
 				companyInfo.SocialMedia["facebook"] = "Encontrado"
@@ -314,6 +343,12 @@ func (s *AnalysisService) extractCompanyInfo(ctx context.Context, url string) (*
 		}
 	}
 
+	// Sitio web visitado: completa el nombre y la descripción, y sus enlaces y
+	// datos de contacto son más confiables que los de las búsquedas
+	<-websiteDone
+	companyInfo.Website = website
+	activityText += applySite(companyInfo, site)
+
 	// Fallback: extraer nombre de la URL si no se pudo obtener de Tavily
 	if companyInfo.Name == "" {
 		// Extraer dominio de la URL
@@ -331,65 +366,72 @@ func (s *AnalysisService) extractCompanyInfo(ctx context.Context, url string) (*
 		}
 	}
 
+	classifyCompanyActivity(companyInfo, activityText)
 	if companyInfo.Industry == "" {
-		companyInfo.Industry = "Tecnología"
+		companyInfo.Industry = "Sin clasificar"
 	}
 	if companyInfo.Location == "" {
 		companyInfo.Location = "Colombia"
 	}
 	if companyInfo.Description == "" {
-		companyInfo.Description = fmt.Sprintf("Empresa colombiana en el sector de %s", companyInfo.Industry)
+		companyInfo.Description = "Empresa colombiana"
+		if companyInfo.CIIU != "" {
+			companyInfo.Description = fmt.Sprintf("Empresa colombiana en el sector de %s", companyInfo.Industry)
+		}
 	}
 
-	// Analizar sitio web usando datos reales
-	companyInfo.Website = s.analyzeWebsite(url)
+	return companyInfo, err
+}
 
-	return companyInfo, nil
+// classifyCompanyActivity asigna la actividad CIIU más probable según el texto
+// encontrado de la empresa y usa su sección como sector
+func classifyCompanyActivity(company *CompanyInfo, text string) {
+	matches := colombia.ClassifyActivity(company.Name+" "+text, 1)
+	if len(matches) == 0 {
+		return
+	}
+	best := matches[0]
+	company.CIIU = best.Code
+	company.CIIUDescription = best.Description
+	company.CIIUConfidence = best.Confidence
+	company.Industry = best.Sector
 }
 
-// analyzeWebsite analiza el sitio web de la empresa
-func (s *AnalysisService) analyzeWebsite(url string) WebsiteAnalysis {
-	// Análisis básico del sitio web
-	analysis := WebsiteAnalysis{
-		Technologies: []string{"WordPress", "PHP", "MySQL"},
-		Performance:  "Buena",
-		Ecommerce:    false,
-		Blog:         true,
-		ContactInfo:  true,
-		SEO: SEOAnalysis{
-			Title:       "Título de la empresa",
-			Description: "Descripción de la empresa",
-			Keywords:    []string{"colombia", "servicios", "empresa"},
-			Score:       75,
-		},
+// industrySearchTerm término de búsqueda de la industria: la actividad CIIU
+// es más específica que el sector ("Elaboración de productos de panadería")
+func industrySearchTerm(company *CompanyInfo) string {
+	if company.CIIUDescription != "" {
+		return company.CIIUDescription
 	}
-
-	return analysis
+	if company.Industry == "Sin clasificar" {
+		return "pymes"
+	}
+	return company.Industry
 }
 
 // analyzeIndustry analiza la industria de la empresa
-func (s *AnalysisService) analyzeIndustry(ctx context.Context, industry string) (*IndustryAnalysis, error) {
-	query := fmt.Sprintf("%s industria mercado Colombia tendencias 2025 oportunidades desafíos", industry)
+func (s *AnalysisService) analyzeIndustry(ctx context.Context, company *CompanyInfo) (*IndustryAnalysis, error) {
+	industry := company.Industry
+	query := fmt.Sprintf("%s industria mercado Colombia tendencias 2025 oportunidades desafíos", industrySearchTerm(company))
 
 	// Buscar información real de la industria con Tavily
-	results, err := s.searchTavily(ctx, query)
+	results, err := s.search(ctx, query)
 	if err != nil {
-		// Si es error de configuración, continuar con datos por defecto
-		if strings.Contains(err.Error(), "API key de Tavily no válida") {
-			log.Printf("Tavily no configurado para industria, usando datos por defecto: %v", err)
-		} else {
-			return nil, fmt.Errorf("error analizando industria: %w", err)
-		}
+		log.Printf("⚠️ Búsqueda de la industria falló, usando datos por defecto: %v", err)
 	}
 
 	analysis := &IndustryAnalysis{
 		Name:          industry,
+		CIIU:          company.CIIU,
 		Size:          "Mediana",
 		Growth:        "Creciente",
 		Trends:        []string{},
 		Challenges:    []string{},
 		Opportunities: []string{},
 	}
+	if activity, ok := colombia.LookupCIIU(company.CIIU); ok {
+		analysis.Section = activity.Section
+	}
 
 	// Procesar resultados reales de Tavily
 	if len(results) > 0 {
@@ -482,7 +524,7 @@ func (s *AnalysisService) analyzeIndustry(ctx context.Context, industry string)
 		}
 	}
 
-	return analysis, nil
+	return analysis, err
 }
 
 // findCompetitors encuentra competidores de la empresa
@@ -490,14 +532,9 @@ func (s *AnalysisService) findCompetitors(ctx context.Context, companyName, indu
 	query := fmt.Sprintf("%s competidores directos %s mercado Colombia", companyName, industry)
 
 	// Buscar competidores reales con Tavily
-	results, err := s.searchTavily(ctx, query)
+	results, err := s.search(ctx, query)
 	if err != nil {
-		// Si es error de configuración, continuar con datos por defecto
-		if strings.Contains(err.Error(), "API key de Tavily no válida") {
-			log.Printf("Tavily no configurado para competidores, usando datos por defecto: %v", err)
-		} else {
-			return nil, fmt.Errorf("error buscando competidores: %w", err)
-		}
+		log.Printf("⚠️ Búsqueda de competidores falló, usando datos por defecto: %v", err)
 	}
 
 	competitors := []CompetitorInfo{}
@@ -590,7 +627,7 @@ func (s *AnalysisService) findCompetitors(ctx context.Context, companyName, indu
 		}
 	}
 
-	return competitors, nil
+	return competitors, err
 }
 
 // identifyOpportunities identifica oportunidades para la empresa
@@ -598,13 +635,9 @@ func (s *AnalysisService) identifyOpportunities(ctx context.Context, company *Co
 	// Búsqueda de oportunidades usando patrones de Tavily
 	query := fmt.Sprintf("%s oportunidades crecimiento %s mercado Colombia", company.Name, industry.Name)
 
-	results, err := s.searchTavily(ctx, query)
+	results, err := s.search(ctx, query)
 	if err != nil {
-		if strings.Contains(err.Error(), "API key de Tavily no válida") {
-			log.Printf("Tavily no configurado para oportunidades, usando datos por defecto: %v", err)
-		} else {
-			return nil, fmt.Errorf("error buscando oportunidades: %w", err)
-		}
+		log.Printf("⚠️ Búsqueda de oportunidades falló, usando datos por defecto: %v", err)
 	}
 
 	opportunities := []Opportunity{
@@ -639,7 +672,7 @@ func (s *AnalysisService) identifyOpportunities(ctx context.Context, company *Co
 		log.Printf("✅ Encontradas %d oportunidades específicas para %s", len(results), company.Name)
 	}
 
-	return opportunities, nil
+	return opportunities, err
 }
 
 // generateRecommendations genera recomendaciones específicas
@@ -691,56 +724,9 @@ func (s *AnalysisService) getColombiaContext(ctx context.Context, industry strin
 	return context, nil
 }
 
-// searchTavily realiza búsquedas usando Tavily
-func (s *AnalysisService) searchTavily(ctx context.Context, query string) ([]TavilyResult, error) {
-	// Obtener API key de Tavily desde el config
-	tavilyAPIKey, isActive := s.configService.GetAPIKey("tavily")
-	if !isActive {
-		return nil, fmt.Errorf("API key de Tavily no configurada")
-	}
-
-	// Verificar si la API key es válida (no es demo)
-	if strings.HasPrefix(tavilyAPIKey, "tvly-test-") || strings.HasPrefix(tavilyAPIKey, "tvly-demo-") {
-		return nil, fmt.Errorf("API key de Tavily no válida. Configure una API key real en el Super Admin")
-	}
-
-	request := TavilySearchRequest{
-		APIKey:            tavilyAPIKey,
-		Query:             query,
-		SearchDepth:       "advanced",
-		IncludeAnswer:     true,
-		IncludeRawContent: true,
-		IncludeImages:     false,
-	}
-
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("error marshaling request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.tavily.com/search", strings.NewReader(string(jsonData)))
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("tavily API error: %d", resp.StatusCode)
-	}
-
-	var tavilyResp TavilySearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tavilyResp); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
-	}
-
-	return tavilyResp.Results, nil
+// search realiza las búsquedas con el proveedor configurado
+func (s *AnalysisService) search(ctx context.Context, query string) ([]SearchResult, error) {
+	return s.searchProvider.Search(ctx, query)
 }
 
 // GenerateReport genera un reporte en formato JSON
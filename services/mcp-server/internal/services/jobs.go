@@ -0,0 +1,504 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server/internal/cache"
+
+	"github.com/google/uuid"
+)
+
+// ===== TRABAJOS DE ANÁLISIS ASÍNCRONOS =====
+
+// Errores de los trabajos de análisis
+var (
+	ErrJobNotFound = errors.New("trabajo de análisis no encontrado")
+	ErrQueueFull   = errors.New("la cola de análisis está llena, intente más tarde")
+)
+
+// Estados de un trabajo
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// Tipos de evento de progreso
+const (
+	EventProgress  = "progress"
+	EventCompleted = "completed"
+	EventFailed    = "failed"
+)
+
+const (
+	jobQueueSize = 100
+	jobTTL       = 24 * time.Hour   // Vigencia del trabajo en Redis
+	jobMemoryTTL = time.Hour        // Vigencia en memoria de los trabajos terminados
+	jobLockTTL   = 30 * time.Second // Se renueva mientras el trabajo espera o corre
+)
+
+// AnalysisJob trabajo de análisis de una empresa y su avance por etapa
+type AnalysisJob struct {
+	ID         string                   `json:"id"`
+	URL        string                   `json:"url"`
+	Status     string                   `json:"status"`
+	Progress   int                      `json:"progress"` // Porcentaje de etapas terminadas
+	Stages     map[string]SectionStatus `json:"stages"`
+	Result     *MarketAnalysis          `json:"result,omitempty"`
+	Preview    *PaywallPreview          `json:"preview,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+	CreatedAt  time.Time                `json:"created_at"`
+	StartedAt  *time.Time               `json:"started_at,omitempty"`
+	FinishedAt *time.Time               `json:"finished_at,omitempty"`
+}
+
+// Finished indica si el trabajo terminó
+func (j *AnalysisJob) Finished() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed
+}
+
+func (j *AnalysisJob) clone() *AnalysisJob {
+	c := *j
+	c.Stages = make(map[string]SectionStatus, len(j.Stages))
+	for stage, status := range j.Stages {
+		c.Stages[stage] = status
+	}
+	return &c
+}
+
+// JobEvent evento de progreso de un trabajo
+type JobEvent struct {
+	Type string       `json:"type"`
+	Job  *AnalysisJob `json:"job"`
+}
+
+// jobStore operaciones de Redis que usan los trabajos (lo implementa
+// cache.RedisCache). Los locks llevan el ID del trabajo como dueño.
+type jobStore interface {
+	CacheResult(key string, value interface{}, ttl time.Duration) error
+	GetCachedResult(key string, dest interface{}) (bool, error)
+	AcquireLock(lockKey, owner string, ttl time.Duration) (bool, error)
+	RenewLock(lockKey, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(lockKey, owner string) error
+}
+
+// AnalysisJobService ejecuta los análisis en un pool de workers. El avance
+// queda en Redis para consultarlo desde cualquier instancia; sin Redis solo en
+// memoria. Los envíos simultáneos de la misma URL comparten el trabajo.
+type AnalysisJobService struct {
+	analysis *AnalysisService
+	cache    jobStore // nil sin Redis
+	queue    chan string
+	lockTTL  time.Duration
+
+	mu          sync.RWMutex
+	jobs        map[string]*AnalysisJob
+	active      map[string]string        // URL normalizada → trabajo en curso
+	renewals    map[string]chan struct{} // Trabajo → fin de la renovación de su lock
+	subscribers map[string][]chan JobEvent
+	now         func() time.Time
+}
+
+// NewAnalysisJobService crea el servicio de trabajos de análisis
+func NewAnalysisJobService(analysis *AnalysisService, redisCache *cache.RedisCache) *AnalysisJobService {
+	s := &AnalysisJobService{
+		analysis:    analysis,
+		queue:       make(chan string, jobQueueSize),
+		lockTTL:     jobLockTTL,
+		jobs:        make(map[string]*AnalysisJob),
+		active:      make(map[string]string),
+		renewals:    make(map[string]chan struct{}),
+		subscribers: make(map[string][]chan JobEvent),
+		now:         time.Now,
+	}
+	if redisCache != nil {
+		s.cache = redisCache
+	}
+	return s
+}
+
+// Start inicia los workers, que terminan cuando se cancela el contexto
+func (s *AnalysisJobService) Start(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-s.queue:
+					s.run(ctx, id)
+				}
+			}
+		}()
+	}
+}
+
+// Submit encola el análisis de una URL. Si ya hay un trabajo en curso para la
+// misma URL normalizada se retorna ese trabajo y deduplicated es true.
+func (s *AnalysisJobService) Submit(url string) (job *AnalysisJob, deduplicated bool, err error) {
+	normalized := cache.NormalizeURL(url)
+	if normalized == "" {
+		return nil, false, fmt.Errorf("URL es requerida")
+	}
+
+	id := newJobID()
+	acquired, err := s.acquire(normalized, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if !acquired {
+		if existing, ok := s.activeJob(normalized); ok {
+			return existing, true, nil
+		}
+		return nil, false, fmt.Errorf("ya hay un análisis en curso para %s", normalized)
+	}
+
+	now := s.now()
+	job = &AnalysisJob{
+		ID:        id,
+		URL:       url,
+		Status:    JobQueued,
+		Stages:    make(map[string]SectionStatus),
+		CreatedAt: now,
+	}
+	for _, stage := range Stages() {
+		job.Stages[stage] = SectionStatus{Status: SectionPending}
+	}
+
+	s.mu.Lock()
+	// La URL se revisa y se ocupa en la misma sección crítica para que dos
+	// solicitudes simultáneas no creen dos trabajos. Con Redis también se
+	// revisa: si el lock se perdió, el trabajo de esta instancia sigue siendo
+	// el de la URL.
+	if existingID, busy := s.active[normalized]; busy {
+		existing, ok := s.jobs[existingID]
+		if ok {
+			existing = existing.clone()
+		}
+		s.mu.Unlock()
+		s.release(normalized, id)
+		if !ok {
+			return nil, false, fmt.Errorf("ya hay un análisis en curso para %s", normalized)
+		}
+		return existing, true, nil
+	}
+	s.pruneLocked(now)
+	s.jobs[job.ID] = job
+	s.active[normalized] = job.ID
+	snapshot := job.clone()
+	var done chan struct{}
+	if s.cache != nil {
+		done = make(chan struct{})
+		s.renewals[job.ID] = done
+	}
+	s.mu.Unlock()
+
+	s.persist(snapshot)
+	if s.cache != nil {
+		if err := s.cache.CacheResult(jobURLKey(normalized), job.ID, s.lockTTL); err != nil {
+			log.Printf("⚠️ Error guardando el trabajo de %s: %v", normalized, err)
+		}
+		go s.renewLock(normalized, job.ID, done)
+	}
+
+	select {
+	case s.queue <- job.ID:
+	default:
+		s.finish(job.ID, nil, ErrQueueFull)
+		return nil, false, ErrQueueFull
+	}
+	return snapshot, false, nil
+}
+
+// GetJob consulta un trabajo en memoria o, si lo creó otra instancia, en Redis
+func (s *AnalysisJobService) GetJob(id string) (*AnalysisJob, error) {
+	s.mu.RLock()
+	job, ok := s.jobs[id]
+	if ok {
+		job = job.clone()
+	}
+	s.mu.RUnlock()
+	if ok {
+		return job, nil
+	}
+
+	if s.cache != nil {
+		var stored AnalysisJob
+		found, err := s.cache.GetCachedResult(jobKey(id), &stored)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return &stored, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+}
+
+// Subscribe recibe los eventos de un trabajo de esta instancia. El canal se
+// cierra cuando el trabajo termina o al llamar la función de cancelación; ok
+// es false si el trabajo no está en esta instancia o ya terminó.
+func (s *AnalysisJobService) Subscribe(id string) (events <-chan JobEvent, cancel func(), ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, exists := s.jobs[id]
+	if !exists || job.Finished() {
+		return nil, func() {}, false
+	}
+	ch := make(chan JobEvent, 16)
+	s.subscribers[id] = append(s.subscribers[id], ch)
+
+	cancel = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		subscribers := s.subscribers[id]
+		for i, subscriber := range subscribers {
+			if subscriber == ch {
+				s.subscribers[id] = append(subscribers[:i], subscribers[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+	return ch, cancel, true
+}
+
+// run ejecuta un trabajo de la cola
+func (s *AnalysisJobService) run(ctx context.Context, id string) {
+	now := s.now()
+	s.update(id, EventProgress, func(job *AnalysisJob) {
+		job.Status = JobRunning
+		job.StartedAt = &now
+	})
+
+	s.mu.RLock()
+	job, ok := s.jobs[id]
+	url := ""
+	if ok {
+		url = job.URL
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	analysis, err := s.analysis.AnalyzeCompanyWithProgress(ctx, url, func(stage string, status SectionStatus) {
+		s.update(id, EventProgress, func(job *AnalysisJob) {
+			job.Stages[stage] = status
+			job.Progress = stageProgress(job.Stages)
+		})
+	})
+	s.finish(id, analysis, err)
+}
+
+// finish registra el resultado, libera la URL y avisa a los suscriptores
+func (s *AnalysisJobService) finish(id string, analysis *MarketAnalysis, err error) {
+	var preview *PaywallPreview
+	if err == nil && analysis != nil {
+		preview, err = s.analysis.GeneratePaywallPreview(analysis)
+	}
+
+	now := s.now()
+	event := EventCompleted
+	if err != nil {
+		event = EventFailed
+	}
+	var url string
+	s.update(id, event, func(job *AnalysisJob) {
+		url = job.URL
+		job.FinishedAt = &now
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = JobCompleted
+		job.Progress = 100
+		job.Result = analysis
+		job.Preview = preview
+		if len(analysis.Sections) > 0 {
+			job.Stages = analysis.Sections
+		} else {
+			// Análisis en cache: no pasó por las etapas
+			for stage := range job.Stages {
+				job.Stages[stage] = SectionStatus{Status: SectionOK}
+			}
+		}
+	})
+
+	normalized := cache.NormalizeURL(url)
+	s.mu.Lock()
+	if s.active[normalized] == id {
+		delete(s.active, normalized)
+	}
+	for _, ch := range s.subscribers[id] {
+		close(ch)
+	}
+	delete(s.subscribers, id)
+	if done, ok := s.renewals[id]; ok {
+		close(done)
+		delete(s.renewals, id)
+	}
+	s.mu.Unlock()
+
+	s.release(normalized, id)
+}
+
+// update modifica el trabajo, lo guarda en Redis y publica el evento
+func (s *AnalysisJobService) update(id, eventType string, apply func(job *AnalysisJob)) {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	apply(job)
+	snapshot := job.clone()
+	for _, ch := range s.subscribers[id] {
+		select {
+		case ch <- JobEvent{Type: eventType, Job: snapshot}:
+		default:
+			// Suscriptor lento: recibirá el estado completo en el siguiente evento
+		}
+	}
+	s.mu.Unlock()
+
+	s.persist(snapshot)
+}
+
+// persist guarda el trabajo en Redis
+func (s *AnalysisJobService) persist(job *AnalysisJob) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.CacheResult(jobKey(job.ID), job, jobTTL); err != nil {
+		log.Printf("⚠️ Error guardando el trabajo %s: %v", job.ID, err)
+	}
+}
+
+// acquire reserva la URL para un nuevo trabajo con un lock distribuido en
+// Redis a nombre del trabajo. Sin Redis la reserva se hace en memoria al
+// registrar el trabajo (ver Submit).
+func (s *AnalysisJobService) acquire(normalized, id string) (bool, error) {
+	if s.cache == nil {
+		return true, nil
+	}
+	return s.cache.AcquireLock(jobLockKey(normalized), id, s.lockTTL)
+}
+
+// release libera el lock de la URL si sigue siendo del trabajo
+func (s *AnalysisJobService) release(normalized, id string) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.ReleaseLock(jobLockKey(normalized), id); err != nil {
+		log.Printf("⚠️ Error liberando el análisis de %s: %v", normalized, err)
+	}
+}
+
+// renewLock extiende el lock de la URL mientras el trabajo espera en la cola
+// o corre, hasta que finish cierre done. Si el lock ya no es del trabajo deja
+// de renovarlo: no se toma de nuevo porque otra instancia pudo ocuparlo.
+func (s *AnalysisJobService) renewLock(normalized, id string, done <-chan struct{}) {
+	ticker := time.NewTicker(s.lockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := s.cache.RenewLock(jobLockKey(normalized), id, s.lockTTL)
+		if err != nil {
+			log.Printf("⚠️ Error renovando el análisis de %s: %v", normalized, err)
+			continue
+		}
+		if !renewed {
+			select {
+			case <-done: // Terminó mientras se renovaba
+			default:
+				log.Printf("⚠️ El análisis %s perdió el lock de %s", id, normalized)
+			}
+			return
+		}
+		if err := s.cache.CacheResult(jobURLKey(normalized), id, s.lockTTL); err != nil {
+			log.Printf("⚠️ Error guardando el trabajo de %s: %v", normalized, err)
+		}
+	}
+}
+
+// activeJob trabajo en curso de la URL. Con Redis el trabajo puede estar en
+// otra instancia, que pudo tomar el lock sin alcanzar a registrar el trabajo
+func (s *AnalysisJobService) activeJob(normalized string) (*AnalysisJob, bool) {
+	s.mu.RLock()
+	id, ok := s.active[normalized]
+	s.mu.RUnlock()
+	if ok {
+		job, err := s.GetJob(id)
+		return job, err == nil
+	}
+	if s.cache == nil {
+		return nil, false
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		found, err := s.cache.GetCachedResult(jobURLKey(normalized), &id)
+		if err == nil && found {
+			job, err := s.GetJob(id)
+			return job, err == nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return nil, false
+}
+
+// pruneLocked olvida los trabajos terminados hace más de una hora (siguen en Redis)
+func (s *AnalysisJobService) pruneLocked(now time.Time) {
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > jobMemoryTTL {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// stageProgress porcentaje de etapas terminadas
+func stageProgress(stages map[string]SectionStatus) int {
+	if len(stages) == 0 {
+		return 0
+	}
+	finished := 0
+	for _, status := range stages {
+		if status.Status != SectionPending && status.Status != SectionRunning {
+			finished++
+		}
+	}
+	return finished * 100 / len(stages)
+}
+
+func newJobID() string {
+	return "ANL-" + strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:16])
+}
+
+func jobKey(id string) string {
+	return "analysis_job:" + id
+}
+
+func jobURLKey(normalized string) string {
+	return "analysis_job_url:" + normalized
+}
+
+func jobLockKey(normalized string) string {
+	return "analysis_job:" + normalized
+}
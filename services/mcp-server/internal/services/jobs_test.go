@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmitDeduplicatesConcurrentRequestsWithoutRedis(t *testing.T) {
+	service := NewAnalysisJobService(&AnalysisService{}, nil)
+
+	// La primera solicitud se detiene antes de registrar su trabajo hasta que
+	// la segunda termine
+	entered, release := make(chan struct{}), make(chan struct{})
+	var calls int32
+	service.now = func() time.Time {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(entered)
+			<-release
+		}
+		return time.Now()
+	}
+
+	type result struct {
+		job          *AnalysisJob
+		deduplicated bool
+		err          error
+	}
+	first := make(chan result, 1)
+	go func() {
+		job, deduplicated, err := service.Submit("https://www.cafeejemplo.com.co/")
+		first <- result{job, deduplicated, err}
+	}()
+	<-entered
+
+	second, secondDeduplicated, err := service.Submit("cafeejemplo.com.co")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	close(release)
+	r := <-first
+	if r.err != nil {
+		t.Fatalf("Submit() error = %v", r.err)
+	}
+
+	if r.deduplicated == secondDeduplicated {
+		t.Errorf("deduplicated = %v y %v, want exactamente un trabajo nuevo", r.deduplicated, secondDeduplicated)
+	}
+	if r.job.ID != second.ID {
+		t.Errorf("trabajos distintos para la misma URL: %s y %s", r.job.ID, second.ID)
+	}
+	if len(service.jobs) != 1 || len(service.queue) != 1 {
+		t.Errorf("trabajos = %d, en cola = %d, want 1 y 1", len(service.jobs), len(service.queue))
+	}
+}
+
+// memoryJobStore Redis en memoria para los trabajos, con vencimiento real de los locks
+type memoryJobStore struct {
+	mu      sync.Mutex
+	values  map[string]interface{}
+	locks   map[string]string
+	expires map[string]time.Time
+}
+
+func newMemoryJobStore() *memoryJobStore {
+	return &memoryJobStore{values: map[string]interface{}{}, locks: map[string]string{}, expires: map[string]time.Time{}}
+}
+
+func (m *memoryJobStore) CacheResult(key string, value interface{}, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *memoryJobStore) GetCachedResult(key string, dest interface{}) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	if id, isString := value.(string); ok && isString {
+		if target, isTarget := dest.(*string); isTarget {
+			*target = id
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryJobStore) owner(key string) (string, bool) {
+	if time.Now().After(m.expires[key]) {
+		delete(m.locks, key)
+	}
+	owner, ok := m.locks[key]
+	return owner, ok
+}
+
+func (m *memoryJobStore) AcquireLock(key, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, held := m.owner(key); held {
+		return false, nil
+	}
+	m.locks[key], m.expires[key] = owner, time.Now().Add(ttl)
+	return true, nil
+}
+
+func (m *memoryJobStore) RenewLock(key, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, held := m.owner(key); !held || current != owner {
+		return false, nil
+	}
+	m.expires[key] = time.Now().Add(ttl)
+	return true, nil
+}
+
+func (m *memoryJobStore) ReleaseLock(key, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, held := m.owner(key); held && current == owner {
+		delete(m.locks, key)
+	}
+	return nil
+}
+
+func (m *memoryJobStore) lockOwner(key string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	owner, _ := m.owner(key)
+	return owner
+}
+
+func TestSubmitKeepsLockWhileJobIsQueued(t *testing.T) {
+	store := newMemoryJobStore()
+	service := NewAnalysisJobService(&AnalysisService{}, nil)
+	service.cache = store
+	service.lockTTL = 30 * time.Millisecond
+
+	// Sin workers el trabajo queda en la cola mucho más que el plazo del lock
+	first, _, err := service.Submit("https://www.cafeejemplo.com.co/")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	time.Sleep(5 * service.lockTTL)
+
+	second, deduplicated, err := service.Submit("cafeejemplo.com.co")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if !deduplicated || second.ID != first.ID {
+		t.Errorf("Submit() = %s (deduplicated %v), want el trabajo en cola %s", second.ID, deduplicated, first.ID)
+	}
+	key := jobLockKey("cafeejemplo.com.co")
+	if owner := store.lockOwner(key); owner != first.ID {
+		t.Errorf("dueño del lock = %q, want %s", owner, first.ID)
+	}
+
+	service.finish(first.ID, nil, context.Canceled)
+	if owner := store.lockOwner(key); owner != "" {
+		t.Errorf("dueño del lock = %q después de terminar, want libre", owner)
+	}
+}
+
+func TestFinishDoesNotReleaseAnotherOwnersLock(t *testing.T) {
+	store := newMemoryJobStore()
+	service := NewAnalysisJobService(&AnalysisService{}, nil)
+	service.cache = store
+	service.lockTTL = time.Hour
+
+	job, _, err := service.Submit("https://www.cafeejemplo.com.co/")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// El lock venció y lo tomó el trabajo de otra instancia
+	key := jobLockKey("cafeejemplo.com.co")
+	store.mu.Lock()
+	store.locks[key] = "ANL-OTRAINSTANCIA"
+	store.mu.Unlock()
+
+	service.finish(job.ID, nil, context.Canceled)
+	if owner := store.lockOwner(key); owner != "ANL-OTRAINSTANCIA" {
+		t.Errorf("dueño del lock = %q, want el de la otra instancia", owner)
+	}
+}
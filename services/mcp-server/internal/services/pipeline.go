@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrSearchUnavailable el proveedor de búsqueda no está configurado
+var ErrSearchUnavailable = errors.New("búsqueda no disponible")
+
+// Etapas del análisis de una empresa
+const (
+	StageCompany         = "company"
+	StageIndustry        = "industry"
+	StageCompetitors     = "competitors"
+	StageOpportunities   = "opportunities"
+	StageRecommendations = "recommendations"
+	StageColombia        = "colombia_context"
+)
+
+// Estados de una etapa
+const (
+	SectionPending = "pending" // Esperando a sus dependencias
+	SectionRunning = "running"
+	SectionOK      = "ok"
+	SectionDefault = "por_defecto" // Sin proveedor de búsqueda: datos por defecto
+	SectionTimeout = "timeout"     // Superó su tiempo límite: datos por defecto
+	SectionError   = "error"       // La búsqueda falló: datos por defecto
+)
+
+// defaultAnalysisDeadline plazo general del análisis
+const defaultAnalysisDeadline = 45 * time.Second
+
+// defaultStageTimeouts la empresa hace tres búsquedas (dos en paralelo) y el
+// resto una; recomendaciones y contexto no consultan servicios externos
+func defaultStageTimeouts() map[string]time.Duration {
+	return map[string]time.Duration{
+		StageCompany:         25 * time.Second,
+		StageIndustry:        15 * time.Second,
+		StageCompetitors:     15 * time.Second,
+		StageOpportunities:   15 * time.Second,
+		StageRecommendations: 5 * time.Second,
+		StageColombia:        5 * time.Second,
+	}
+}
+
+// SectionStatus resultado de una etapa del análisis
+type SectionStatus struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// StageProgressFunc recibe el estado de cada etapa cuando empieza y cuando termina
+type StageProgressFunc func(stage string, status SectionStatus)
+
+// Stages etapas del análisis en orden de ejecución
+func Stages() []string {
+	return []string{StageCompany, StageIndustry, StageCompetitors, StageColombia, StageOpportunities, StageRecommendations}
+}
+
+// WithStageTimeout cambia el tiempo límite de una etapa
+func (s *AnalysisService) WithStageTimeout(stage string, timeout time.Duration) *AnalysisService {
+	s.stageTimeouts[stage] = timeout
+	return s
+}
+
+// WithDeadline cambia el plazo general del análisis
+func (s *AnalysisService) WithDeadline(deadline time.Duration) *AnalysisService {
+	s.deadline = deadline
+	return s
+}
+
+// analysisStage etapa del análisis: corre cuando terminan sus dependencias y
+// escribe su sección del análisis
+type analysisStage struct {
+	name string
+	deps []string
+	run  func(ctx context.Context) error
+}
+
+// runPipeline ejecuta las etapas del análisis. Cada etapa deja datos (reales
+// o por defecto) aunque falle, así que las dependientes siempre pueden correr;
+// solo se registra el estado. Cada etapa escribe un campo distinto del
+// análisis y lee los de sus dependencias después de que estas terminan.
+func (s *AnalysisService) runPipeline(ctx context.Context, url string, progress StageProgressFunc) *MarketAnalysis {
+	if progress == nil {
+		progress = func(string, SectionStatus) {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.deadline)
+	defer cancel()
+
+	analysis := &MarketAnalysis{}
+	var company *CompanyInfo
+	var industry *IndustryAnalysis
+
+	stages := []analysisStage{
+		{name: StageCompany, run: func(ctx context.Context) error {
+			var err error
+			company, err = s.extractCompanyInfo(ctx, url)
+			analysis.Company = *company
+			return err
+		}},
+		{name: StageIndustry, deps: []string{StageCompany}, run: func(ctx context.Context) error {
+			var err error
+			industry, err = s.analyzeIndustry(ctx, company)
+			analysis.Industry = *industry
+			return err
+		}},
+		{name: StageCompetitors, deps: []string{StageCompany}, run: func(ctx context.Context) error {
+			var err error
+			analysis.Competitors, err = s.findCompetitors(ctx, company.Name, industrySearchTerm(company))
+			return err
+		}},
+		{name: StageColombia, deps: []string{StageCompany}, run: func(ctx context.Context) error {
+			colombiaContext, err := s.getColombiaContext(ctx, company.Industry)
+			if colombiaContext != nil {
+				analysis.ColombiaContext = *colombiaContext
+			}
+			return err
+		}},
+		{name: StageOpportunities, deps: []string{StageCompany, StageIndustry}, run: func(ctx context.Context) error {
+			var err error
+			analysis.Opportunities, err = s.identifyOpportunities(ctx, company, industry)
+			return err
+		}},
+		{name: StageRecommendations, deps: []string{StageCompany, StageIndustry, StageCompetitors}, run: func(ctx context.Context) error {
+			var err error
+			analysis.Recommendations, err = s.generateRecommendations(ctx, company, industry, analysis.Competitors)
+			return err
+		}},
+	}
+
+	done := make(map[string]chan struct{}, len(stages))
+	for _, stage := range stages {
+		done[stage.name] = make(chan struct{})
+	}
+
+	var mu sync.Mutex
+	sections := make(map[string]SectionStatus, len(stages))
+	var wg sync.WaitGroup
+	for _, stage := range stages {
+		wg.Add(1)
+		go func(stage analysisStage) {
+			defer wg.Done()
+			defer close(done[stage.name])
+			for _, dep := range stage.deps {
+				<-done[dep]
+			}
+
+			stageCtx, stageCancel := context.WithTimeout(ctx, s.stageTimeouts[stage.name])
+			defer stageCancel()
+			progress(stage.name, SectionStatus{Status: SectionRunning})
+			started := time.Now()
+			err := stage.run(stageCtx)
+			status := sectionStatus(stageCtx, err)
+			status.DurationMS = time.Since(started).Milliseconds()
+			if status.Status != SectionOK {
+				log.Printf("⚠️ Etapa %s del análisis de %s: %s %s", stage.name, url, status.Status, status.Error)
+			}
+
+			mu.Lock()
+			sections[stage.name] = status
+			mu.Unlock()
+			progress(stage.name, status)
+		}(stage)
+	}
+	wg.Wait()
+
+	analysis.Sections = sections
+	for _, section := range sections {
+		if section.Status == SectionTimeout || section.Status == SectionError {
+			analysis.Partial = true
+		}
+	}
+	return analysis
+}
+
+// sectionStatus clasifica el resultado de una etapa
+func sectionStatus(ctx context.Context, err error) SectionStatus {
+	switch {
+	case err == nil:
+		return SectionStatus{Status: SectionOK}
+	case errors.Is(err, ErrSearchUnavailable):
+		return SectionStatus{Status: SectionDefault, Error: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return SectionStatus{Status: SectionTimeout, Error: "la etapa superó su tiempo límite"}
+	}
+	return SectionStatus{Status: SectionError, Error: err.Error()}
+}
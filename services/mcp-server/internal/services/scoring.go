@@ -2,6 +2,7 @@ package services
 
 import (
 	"math"
+	"mcp-server/pkg/colombia"
 	"strings"
 	"time"
 )
@@ -20,6 +21,29 @@ type ScoringWeights struct {
 	Technical          float64 // 10%
 }
 
+// sectorBenchmarks score de digitalización de referencia de las pymes por
+// sección CIIU: promedio y percentil 90
+var sectorBenchmarks = map[string]struct{ average, top float64 }{
+	"A": {22, 45},
+	"C": {35, 62},
+	"F": {33, 60},
+	"G": {40, 70},
+	"H": {38, 66},
+	"I": {42, 72},
+	"J": {65, 88},
+	"K": {60, 85},
+	"L": {45, 74},
+	"M": {52, 80},
+	"N": {41, 69},
+	"P": {48, 76},
+	"Q": {39, 67},
+	"R": {44, 73},
+	"S": {31, 58},
+}
+
+// defaultBenchmark referencia para secciones sin suficientes pymes medidas
+var defaultBenchmark = struct{ average, top float64 }{40, 68}
+
 // NewScoringService crea una nueva instancia con pesos predefinidos
 func NewScoringService() *ScoringService {
 	return &ScoringService{
@@ -72,10 +96,44 @@ func (s *ScoringService) CalculateDigitalizationScore(analysis *MarketAnalysis)
 	// Calcular nivel y recomendaciones
 	score.Level = s.getScoreLevel(score.Total)
 	score.Recommendations = s.generatePrioritizedRecommendations(score)
+	score.Peers = s.comparePeers(analysis.Company.CIIU, score.Total)
 
 	return score
 }
 
+// comparePeers compara el score con el de las pymes de la misma sección CIIU;
+// nil si la actividad de la empresa no se pudo clasificar
+func (s *ScoringService) comparePeers(ciiu string, total float64) *PeerBenchmark {
+	activity, ok := colombia.LookupCIIU(ciiu)
+	if !ok {
+		return nil
+	}
+	benchmark, exists := sectorBenchmarks[activity.Section]
+	if !exists {
+		benchmark = defaultBenchmark
+	}
+
+	peers := &PeerBenchmark{
+		Section:      activity.Section,
+		Sector:       activity.Sector,
+		CIIU:         activity.Code,
+		AverageScore: benchmark.average,
+		TopScore:     benchmark.top,
+		Difference:   total - benchmark.average,
+	}
+	switch {
+	case total >= benchmark.top:
+		peers.Position = "Entre las más digitalizadas de su sector"
+	case peers.Difference >= 5:
+		peers.Position = "Por encima del promedio de su sector"
+	case peers.Difference > -5:
+		peers.Position = "En el promedio de su sector"
+	default:
+		peers.Position = "Por debajo del promedio de su sector"
+	}
+	return peers
+}
+
 // calculateWebPresenceScore evalúa la presencia web del negocio
 func (s *ScoringService) calculateWebPresenceScore(analysis *MarketAnalysis) CategoryScore {
 	score := 0.0
@@ -347,21 +405,47 @@ func (s *ScoringService) calculateTechnicalScore(analysis *MarketAnalysis) Categ
 	score := 0.0
 	details := []ScoreDetail{}
 
-	// HTTPS/SSL (30 puntos) - Simulado
-	score += 30
-	details = append(details, ScoreDetail{
-		Item:   "Sitio seguro (HTTPS)",
-		Points: 30,
-		Status: "✓",
-	})
+	// HTTPS y diseño móvil según la visita al sitio; si no se pudo visitar se
+	// asumen presentes
+	website := analysis.Company.Website
+	https, mobile := true, true
+	if website.Crawled {
+		https, mobile = website.HTTPS, website.MobileFriendly
+	}
 
-	// Mobile responsive (40 puntos) - Simulado
-	score += 40
-	details = append(details, ScoreDetail{
-		Item:   "Diseño móvil",
-		Points: 40,
-		Status: "✓",
-	})
+	// HTTPS/SSL (30 puntos)
+	if https {
+		score += 30
+		details = append(details, ScoreDetail{
+			Item:   "Sitio seguro (HTTPS)",
+			Points: 30,
+			Status: "✓",
+		})
+	} else {
+		details = append(details, ScoreDetail{
+			Item:           "Sitio sin HTTPS",
+			Points:         0,
+			Status:         "✗",
+			Recommendation: "Instalar un certificado SSL y redirigir a HTTPS",
+		})
+	}
+
+	// Mobile responsive (40 puntos)
+	if mobile {
+		score += 40
+		details = append(details, ScoreDetail{
+			Item:   "Diseño móvil",
+			Points: 40,
+			Status: "✓",
+		})
+	} else {
+		details = append(details, ScoreDetail{
+			Item:           "Sin diseño móvil",
+			Points:         0,
+			Status:         "✗",
+			Recommendation: "Adaptar el sitio a celulares (viewport y diseño responsive)",
+		})
+	}
 
 	// Velocidad de carga (30 puntos)
 	if analysis.Company.Website.Performance == "Buena" {
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ===== PROVEEDORES DE BÚSQUEDA =====
+
+// SearchResult resultado de una búsqueda, común a todos los proveedores
+type SearchResult = TavilyResult
+
+// SearchProvider busca información pública para las etapas del análisis. Lo
+// implementan Tavily, el adaptador genérico (SearXNG o Brave), las fixtures y
+// la cadena de respaldo. Un proveedor sin configurar retorna
+// ErrSearchUnavailable para que la etapa use datos por defecto.
+type SearchProvider interface {
+	Name() string
+	Search(ctx context.Context, query string) ([]SearchResult, error)
+}
+
+// ===== TAVILY =====
+
+// TavilyProvider busca en la API de Tavily con la API key del Super Admin,
+// que se lee en cada búsqueda para tomar los cambios sin reiniciar
+type TavilyProvider struct {
+	configService *ConfigService
+	httpClient    *http.Client
+	endpoint      string
+}
+
+// NewTavilyProvider crea el proveedor de Tavily
+func NewTavilyProvider(configService *ConfigService, httpClient *http.Client) *TavilyProvider {
+	return &TavilyProvider{
+		configService: configService,
+		httpClient:    httpClient,
+		endpoint:      "https://api.tavily.com/search",
+	}
+}
+
+// Name nombre del proveedor
+func (p *TavilyProvider) Name() string {
+	return "tavily"
+}
+
+// Search realiza la búsqueda en Tavily
+func (p *TavilyProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	// Obtener API key de Tavily desde el config
+	tavilyAPIKey, isActive := p.configService.GetAPIKey("tavily")
+	if !isActive {
+		return nil, fmt.Errorf("%w: API key de Tavily no configurada", ErrSearchUnavailable)
+	}
+
+	// Verificar si la API key es válida (no es demo)
+	if strings.HasPrefix(tavilyAPIKey, "tvly-test-") || strings.HasPrefix(tavilyAPIKey, "tvly-demo-") {
+		return nil, fmt.Errorf("%w: API key de Tavily no válida. Configure una API key real en el Super Admin", ErrSearchUnavailable)
+	}
+
+	request := TavilySearchRequest{
+		APIKey:            tavilyAPIKey,
+		Query:             query,
+		SearchDepth:       "advanced",
+		IncludeAnswer:     true,
+		IncludeRawContent: true,
+		IncludeImages:     false,
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tavily API error: %d", resp.StatusCode)
+	}
+
+	var tavilyResp TavilySearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tavilyResp); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return tavilyResp.Results, nil
+}
+
+// ===== ADAPTADOR GENÉRICO (SEARXNG / BRAVE) =====
+
+// GenericSearchProvider consulta una API de búsqueda por GET con el texto en
+// un parámetro de la URL. Entiende las respuestas de SearXNG
+// ({"results": [...]}) y de Brave ({"web": {"results": [...]}}).
+type GenericSearchProvider struct {
+	name       string
+	endpoint   string
+	params     url.Values // Parámetros fijos de la consulta
+	queryParam string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// NewGenericSearchProvider crea el adaptador contra el endpoint indicado
+func NewGenericSearchProvider(name, endpoint string) *GenericSearchProvider {
+	return &GenericSearchProvider{
+		name:       name,
+		endpoint:   endpoint,
+		params:     url.Values{},
+		queryParam: "q",
+		headers:    map[string]string{"Accept": "application/json"},
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// NewSearXNGProvider crea el adaptador para una instancia de SearXNG con el
+// formato JSON habilitado
+func NewSearXNGProvider(baseURL string) *GenericSearchProvider {
+	return NewGenericSearchProvider("searxng", strings.TrimRight(baseURL, "/")+"/search").
+		WithParam("format", "json").
+		WithParam("language", "es-CO")
+}
+
+// NewBraveProvider crea el adaptador para la API de búsqueda de Brave
+func NewBraveProvider(apiKey string) *GenericSearchProvider {
+	return NewGenericSearchProvider("brave", "https://api.search.brave.com/res/v1/web/search").
+		WithParam("country", "CO").
+		WithParam("search_lang", "es").
+		WithHeader("X-Subscription-Token", apiKey)
+}
+
+// WithParam agrega un parámetro fijo a cada consulta
+func (p *GenericSearchProvider) WithParam(key, value string) *GenericSearchProvider {
+	p.params.Set(key, value)
+	return p
+}
+
+// WithHeader agrega un header a cada consulta (por ejemplo la API key)
+func (p *GenericSearchProvider) WithHeader(key, value string) *GenericSearchProvider {
+	p.headers[key] = value
+	return p
+}
+
+// Name nombre del proveedor
+func (p *GenericSearchProvider) Name() string {
+	return p.name
+}
+
+// genericSearchResponse respuestas de SearXNG y de Brave
+type genericSearchResponse struct {
+	Results []genericSearchResult `json:"results"`
+	Web     struct {
+		Results []genericSearchResult `json:"results"`
+	} `json:"web"`
+}
+
+type genericSearchResult struct {
+	Title       string  `json:"title"`
+	URL         string  `json:"url"`
+	Content     string  `json:"content"`     // SearXNG
+	Description string  `json:"description"` // Brave
+	Score       float64 `json:"score"`
+}
+
+// Search realiza la búsqueda en la API configurada
+func (p *GenericSearchProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	if p.endpoint == "" {
+		return nil, fmt.Errorf("%w: endpoint de %s no configurado", ErrSearchUnavailable, p.name)
+	}
+
+	params := url.Values{}
+	for key, values := range p.params {
+		params[key] = values
+	}
+	params.Set(p.queryParam, query)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	for key, value := range p.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, errors.New(strings.TrimSpace(fmt.Sprintf("%s API error: %d %s", p.name, resp.StatusCode, body)))
+	}
+
+	var decoded genericSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	raw := decoded.Results
+	if len(raw) == 0 {
+		raw = decoded.Web.Results
+	}
+	results := make([]SearchResult, 0, len(raw))
+	for _, result := range raw {
+		content := result.Content
+		if content == "" {
+			content = result.Description
+		}
+		results = append(results, SearchResult{
+			Title:   result.Title,
+			URL:     result.URL,
+			Content: content,
+			Score:   result.Score,
+		})
+	}
+	return results, nil
+}
+
+// ===== PROVEEDOR DE FIXTURES =====
+
+// SearchFixture respuesta grabada para una consulta
+type SearchFixture struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+// FixtureSearchProvider reproduce respuestas grabadas para obtener análisis
+// repetibles en pruebas y demos. Las consultas se comparan sin distinguir
+// mayúsculas ni espacios; la consulta "*" responde las que no estén grabadas.
+type FixtureSearchProvider struct {
+	fixtures map[string][]SearchResult
+}
+
+// NewFixtureSearchProvider crea el proveedor con las respuestas indicadas
+func NewFixtureSearchProvider(fixtures []SearchFixture) *FixtureSearchProvider {
+	provider := &FixtureSearchProvider{fixtures: make(map[string][]SearchResult, len(fixtures))}
+	for _, fixture := range fixtures {
+		provider.fixtures[normalizeQuery(fixture.Query)] = fixture.Results
+	}
+	return provider
+}
+
+// LoadFixtureSearchProvider carga las respuestas de un archivo JSON (lista de SearchFixture)
+func LoadFixtureSearchProvider(path string) (*FixtureSearchProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el archivo de búsquedas: %w", err)
+	}
+	var fixtures []SearchFixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("archivo de búsquedas inválido: %w", err)
+	}
+	return NewFixtureSearchProvider(fixtures), nil
+}
+
+// Name nombre del proveedor
+func (p *FixtureSearchProvider) Name() string {
+	return "fixtures"
+}
+
+// Search retorna la respuesta grabada para la consulta
+func (p *FixtureSearchProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	results, exists := p.fixtures[normalizeQuery(query)]
+	if !exists {
+		results, exists = p.fixtures["*"]
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: sin respuesta grabada para %q", ErrSearchUnavailable, query)
+	}
+	return append([]SearchResult(nil), results...), nil
+}
+
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// ===== CADENA DE RESPALDO =====
+
+// FallbackSearchProvider prueba los proveedores en orden y retorna la primera
+// respuesta exitosa. Si todos fallan retorna los errores de cada uno; el error
+// es ErrSearchUnavailable solo si ninguno estaba configurado.
+type FallbackSearchProvider struct {
+	providers []SearchProvider
+}
+
+// NewFallbackSearchProvider crea la cadena con los proveedores en orden de preferencia
+func NewFallbackSearchProvider(providers ...SearchProvider) *FallbackSearchProvider {
+	return &FallbackSearchProvider{providers: providers}
+}
+
+// Name nombre de la cadena, por ejemplo "tavily>searxng"
+func (p *FallbackSearchProvider) Name() string {
+	names := make([]string, len(p.providers))
+	for i, provider := range p.providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ">")
+}
+
+// Search busca con cada proveedor hasta que uno responda
+func (p *FallbackSearchProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	if len(p.providers) == 0 {
+		return nil, fmt.Errorf("%w: no hay proveedores de búsqueda configurados", ErrSearchUnavailable)
+	}
+
+	var failures []string
+	unavailable := true
+	for _, provider := range p.providers {
+		results, err := provider.Search(ctx, query)
+		if err == nil {
+			return results, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if !errors.Is(err, ErrSearchUnavailable) {
+			unavailable = false
+			log.Printf("⚠️ Búsqueda con %s falló, probando el siguiente proveedor: %v", provider.Name(), err)
+		}
+		failures = append(failures, provider.Name()+": "+err.Error())
+	}
+
+	if unavailable {
+		return nil, fmt.Errorf("%w: %s", ErrSearchUnavailable, strings.Join(failures, "; "))
+	}
+	// Algún proveedor estaba configurado y falló: la etapa queda en error
+	return nil, fmt.Errorf("búsqueda fallida: %s", strings.Join(failures, "; "))
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubSearchProvider proveedor que falla con el error indicado y cuenta las búsquedas
+type stubSearchProvider struct {
+	name     string
+	err      error
+	searches int
+}
+
+func (p *stubSearchProvider) Name() string {
+	return p.name
+}
+
+func (p *stubSearchProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	p.searches++
+	return nil, p.err
+}
+
+func searchFixtures() *FixtureSearchProvider {
+	return NewFixtureSearchProvider([]SearchFixture{
+		{Query: "Café Ejemplo  SAS Bogotá", Results: []SearchResult{
+			{Title: "Café Ejemplo SAS", URL: "https://www.cafeejemplo.com.co/", Content: "Tostadores de café en Bogotá", Score: 0.9},
+		}},
+	})
+}
+
+func TestFallbackSearchUsesNextProviderWhenOneFails(t *testing.T) {
+	// Un proveedor configurado que responde con error del servidor
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "servicio no disponible", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	failing := NewSearXNGProvider(server.URL)
+
+	chain := NewFallbackSearchProvider(failing, searchFixtures())
+	if name := chain.Name(); name != "searxng>fixtures" {
+		t.Errorf("Name() = %q, want searxng>fixtures", name)
+	}
+
+	results, err := chain.Search(context.Background(), "café ejemplo sas   bogotá")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].URL != "https://www.cafeejemplo.com.co/" {
+		t.Errorf("Search() = %+v, want el resultado grabado", results)
+	}
+}
+
+func TestFallbackSearchErrors(t *testing.T) {
+	notConfigured := func(name string) *stubSearchProvider {
+		return &stubSearchProvider{name: name, err: fmt.Errorf("%w: %s no configurado", ErrSearchUnavailable, name)}
+	}
+
+	tests := []struct {
+		name        string
+		providers   []SearchProvider
+		unavailable bool // El error debe ser ErrSearchUnavailable (la etapa usa datos por defecto)
+	}{
+		{"sin proveedores", nil, true},
+		{"ninguno configurado", []SearchProvider{notConfigured("tavily"), notConfigured("searxng")}, true},
+		{"consulta sin respuesta grabada", []SearchProvider{notConfigured("tavily"), searchFixtures()}, true},
+		{"uno configurado que falla", []SearchProvider{notConfigured("tavily"), &stubSearchProvider{name: "brave", err: errors.New("brave API error: 429")}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFallbackSearchProvider(tt.providers...).Search(context.Background(), "otra empresa")
+			if err == nil {
+				t.Fatal("Search() no retornó error")
+			}
+			if errors.Is(err, ErrSearchUnavailable) != tt.unavailable {
+				t.Errorf("Search() error = %v, ErrSearchUnavailable = %v, want %v", err, !tt.unavailable, tt.unavailable)
+			}
+		})
+	}
+}
+
+func TestFallbackSearchStopsWhenContextIsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	first := &stubSearchProvider{name: "tavily", err: context.Canceled}
+	second := &stubSearchProvider{name: "searxng", err: errors.New("no debería consultarse")}
+
+	_, err := NewFallbackSearchProvider(first, second).Search(ctx, "Café Ejemplo SAS Bogotá")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Search() error = %v, want context.Canceled", err)
+	}
+	if second.searches != 0 {
+		t.Errorf("se consultó el siguiente proveedor con el contexto cancelado")
+	}
+}
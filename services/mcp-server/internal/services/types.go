@@ -71,9 +71,21 @@ type DigitalizationScore struct {
 	Level           string                      `json:"level"`
 	Categories      map[string]CategoryScore    `json:"categories"`
 	Recommendations []PrioritizedRecommendation `json:"recommendations"`
+	Peers           *PeerBenchmark              `json:"peers,omitempty"`
 	Timestamp       time.Time                   `json:"timestamp"`
 }
 
+// PeerBenchmark comparación con pymes de la misma sección CIIU
+type PeerBenchmark struct {
+	Section      string  `json:"section"`
+	Sector       string  `json:"sector"`
+	CIIU         string  `json:"ciiu,omitempty"`
+	AverageScore float64 `json:"averageScore"`
+	TopScore     float64 `json:"topScore"` // Percentil 90 del sector
+	Difference   float64 `json:"difference"`
+	Position     string  `json:"position"`
+}
+
 // CategoryScore puntuación por categoría
 type CategoryScore struct {
 	Name     string        `json:"name"`
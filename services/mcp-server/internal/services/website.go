@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"mcp-server/pkg/webcrawl"
+)
+
+// WithCrawler cambia el crawler del sitio web (límites, pausas o redes privadas en pruebas)
+func (s *AnalysisService) WithCrawler(crawler *webcrawl.Crawler) *AnalysisService {
+	s.crawler = crawler
+	return s
+}
+
+// analyzeWebsite visita el sitio de la empresa y resume lo encontrado. Si el
+// sitio no responde retorna el análisis sin datos (Crawled en false) y el
+// sitio nil.
+func (s *AnalysisService) analyzeWebsite(ctx context.Context, url string) (WebsiteAnalysis, *webcrawl.Site) {
+	site, err := s.crawler.Crawl(ctx, url)
+	if err != nil {
+		log.Printf("⚠️ No se pudo analizar el sitio %s: %v", url, err)
+		return WebsiteAnalysis{
+			Technologies: []string{},
+			Performance:  "Sin datos",
+			Error:        err.Error(),
+		}, nil
+	}
+
+	home := site.Home()
+	analysis := WebsiteAnalysis{
+		Technologies:   site.Technologies,
+		Performance:    websitePerformance(site),
+		Ecommerce:      site.Ecommerce,
+		Blog:           site.Blog,
+		ContactInfo:    site.ContactPage || len(site.Phones) > 0 || len(site.Emails) > 0 || len(site.WhatsApp) > 0,
+		Crawled:        true,
+		FinalURL:       site.FinalURL,
+		HTTPS:          site.HTTPS,
+		Redirects:      site.Redirects,
+		MobileFriendly: strings.Contains(strings.ToLower(home.Viewport), "width=device-width"),
+		ResponseTimeMS: site.ResponseTimeMS,
+		PageWeightKB:   (site.PageWeight + 1023) / 1024,
+		Phones:         site.Phones,
+		Emails:         site.Emails,
+		WhatsApp:       site.WhatsApp,
+		Signals:        append(append([]string{}, site.EcommerceSignals...), site.BlogSignals...),
+		SEO: SEOAnalysis{
+			Title:       home.Title,
+			Description: home.Description,
+			Keywords:    home.Keywords,
+			H1:          home.H1,
+			H2:          home.H2,
+			Canonical:   home.Canonical,
+		},
+	}
+	if analysis.Technologies == nil {
+		analysis.Technologies = []string{}
+	}
+	if analysis.SEO.Keywords == nil {
+		analysis.SEO.Keywords = []string{}
+	}
+	for _, page := range site.Pages {
+		analysis.PagesCrawled = append(analysis.PagesCrawled, page.URL)
+	}
+	analysis.SEO.Score, analysis.SEO.Issues = seoScore(home, site.HTTPS)
+	return analysis, site
+}
+
+// websitePerformance clasifica la velocidad por el tiempo de respuesta y el
+// peso del HTML de la página de inicio
+func websitePerformance(site *webcrawl.Site) string {
+	switch {
+	case site.ResponseTimeMS <= 1500 && site.PageWeight <= 300<<10:
+		return "Buena"
+	case site.ResponseTimeMS <= 4000:
+		return "Regular"
+	}
+	return "Lenta"
+}
+
+// seoScore puntaje SEO de la página de inicio (0-100) y lo que le falta
+func seoScore(home *webcrawl.Page, https bool) (int, []string) {
+	score := 0
+	var issues []string
+	check := func(ok bool, points int, issue string) {
+		if ok {
+			score += points
+		} else {
+			issues = append(issues, issue)
+		}
+	}
+
+	titleLength := len([]rune(home.Title))
+	check(home.Title != "", 15, "La página no tiene título")
+	if home.Title != "" {
+		check(titleLength >= 10 && titleLength <= 65, 5, fmt.Sprintf("El título tiene %d caracteres (recomendado entre 10 y 65)", titleLength))
+	}
+	descriptionLength := len([]rune(home.Description))
+	check(home.Description != "", 15, "Falta la meta descripción")
+	if home.Description != "" {
+		check(descriptionLength >= 50 && descriptionLength <= 160, 5, fmt.Sprintf("La meta descripción tiene %d caracteres (recomendado entre 50 y 160)", descriptionLength))
+	}
+	check(len(home.H1) == 1, 15, fmt.Sprintf("La página tiene %d encabezados H1 (recomendado uno)", len(home.H1)))
+	check(home.Canonical != "", 10, "Falta la URL canónica")
+	check(home.Viewport != "", 10, "No declara viewport para móviles")
+	check(https, 10, "El sitio no usa HTTPS")
+	check(home.Lang != "", 5, "No declara el idioma de la página")
+	check(home.Images == 0 || home.ImagesWithoutAlt*5 <= home.Images, 10,
+		fmt.Sprintf("%d de %d imágenes sin texto alternativo", home.ImagesWithoutAlt, home.Images))
+	return score, issues
+}
+
+// applySite completa la empresa con los datos del sitio: nombre y
+// descripción si las búsquedas no los dieron, redes sociales y contacto.
+// Retorna el texto del sitio para clasificar la actividad económica.
+func applySite(company *CompanyInfo, site *webcrawl.Site) string {
+	if site == nil {
+		return ""
+	}
+	home := site.Home()
+	if company.Name == "" {
+		company.Name = siteName(home.Title)
+	}
+	if company.Description == "" && home.Description != "" {
+		description := []rune(home.Description)
+		if len(description) > 200 {
+			description = append(description[:200], []rune("...")...)
+		}
+		company.Description = string(description)
+	}
+
+	for network, profile := range site.Social {
+		company.SocialMedia[network] = profile
+	}
+	if len(site.Phones) > 0 {
+		company.SocialMedia["phone"] = site.Phones[0]
+	}
+	if len(site.Emails) > 0 {
+		company.SocialMedia["email"] = site.Emails[0]
+	}
+	if len(site.WhatsApp) > 0 {
+		company.SocialMedia["whatsapp"] = "https://wa.me/" + strings.TrimPrefix(site.WhatsApp[0], "+")
+	}
+
+	return " " + home.Title + " " + home.Description + " " + strings.Join(home.H1, " ")
+}
+
+// siteName nombre de la empresa a partir del título de la página, sin las
+// partes genéricas ("Inicio | Café Ejemplo" → "Café Ejemplo")
+func siteName(title string) string {
+	generic := map[string]bool{"inicio": true, "home": true, "bienvenidos": true, "página principal": true}
+	for _, separator := range []string{" | ", " - ", " – ", " — ", " :: "} {
+		if !strings.Contains(title, separator) {
+			continue
+		}
+		for _, part := range strings.Split(title, separator) {
+			if part = strings.TrimSpace(part); part != "" && !generic[strings.ToLower(part)] {
+				return part
+			}
+		}
+	}
+	if generic[strings.ToLower(strings.TrimSpace(title))] {
+		return ""
+	}
+	return strings.TrimSpace(title)
+}
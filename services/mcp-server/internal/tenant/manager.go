@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"mcp-server/internal/cache"
+	"mcp-server/pkg/colombia"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -75,6 +76,10 @@ type CreateTenantInput struct {
 	CompanyName string `json:"companyName"`
 	Plan        string `json:"plan"`
 	Email       string `json:"email"`
+	NIT         string `json:"nit,omitempty"`
+
+	// Company datos del RUT/RUES precargados por el handler
+	Company *colombia.CompanyRecord `json:"-"`
 }
 
 // NewTenantManager crea un nuevo gestor de tenants
@@ -539,6 +544,12 @@ func (tm *TenantManager) CreateTenant(ctx context.Context, input CreateTenantInp
 		Limits:      getDefaultLimits("trial"),
 		Metadata:    make(map[string]interface{}),
 	}
+	if input.NIT != "" {
+		tenant.Metadata["nit"] = input.NIT
+	}
+	if input.Company != nil {
+		tenant.Metadata["company"] = input.Company
+	}
 
 	// Transacción para crear tenant
 	tx, err := tm.db.BeginTx(ctx, nil)
@@ -0,0 +1,464 @@
+package colombia
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ===== DIRECCIONES =====
+
+// AddressComponent abreviatura de nomenclatura con su nombre completo
+type AddressComponent struct {
+	Code string `json:"code"` // Abreviatura estilo DIAN (KR, CL, AP...)
+	Name string `json:"name"`
+}
+
+// Tipos de vía
+var viaTypes = map[string]AddressComponent{
+	"CL":  {"CL", "Calle"},
+	"KR":  {"KR", "Carrera"},
+	"AV":  {"AV", "Avenida"},
+	"AC":  {"AC", "Avenida Calle"},
+	"AK":  {"AK", "Avenida Carrera"},
+	"DG":  {"DG", "Diagonal"},
+	"TV":  {"TV", "Transversal"},
+	"AU":  {"AU", "Autopista"},
+	"CQ":  {"CQ", "Circular"},
+	"CV":  {"CV", "Circunvalar"},
+	"CT":  {"CT", "Carretera"},
+	"PJ":  {"PJ", "Pasaje"},
+	"KM":  {"KM", "Kilómetro"},
+	"VRD": {"VRD", "Vereda"},
+}
+
+// Formas en que se escriben los tipos de vía
+var viaAliases = map[string]string{
+	"CALLE": "CL", "CL": "CL", "CLL": "CL", "CLLE": "CL", "CALL": "CL",
+	"CARRERA": "KR", "CRA": "KR", "CR": "KR", "KR": "KR", "KRA": "KR", "CRR": "KR", "CARERA": "KR", "K": "KR",
+	"AVENIDA": "AV", "AV": "AV", "AVE": "AV", "AVDA": "AV",
+	"AC": "AC", "AK": "AK",
+	"DIAGONAL": "DG", "DG": "DG", "DIAG": "DG", "DI": "DG",
+	"TRANSVERSAL": "TV", "TV": "TV", "TR": "TV", "TRANSV": "TV", "TRANS": "TV", "TRV": "TV",
+	"AUTOPISTA": "AU", "AU": "AU", "AUT": "AU",
+	"CIRCULAR": "CQ", "CQ": "CQ", "CIR": "CQ",
+	"CIRCUNVALAR": "CV", "CV": "CV", "CIRCUNV": "CV",
+	"CARRETERA": "CT", "CT": "CT", "CTRA": "CT",
+	"PASAJE": "PJ", "PJ": "PJ",
+	"KILOMETRO": "KM", "KM": "KM",
+	"VEREDA": "VRD", "VRD": "VRD", "VDA": "VRD",
+}
+
+// Complementos de la dirección (apartamento, torre, barrio...)
+var complementTypes = map[string]AddressComponent{
+	"AP":   {"AP", "Apartamento"},
+	"TO":   {"TO", "Torre"},
+	"IN":   {"IN", "Interior"},
+	"CS":   {"CS", "Casa"},
+	"LC":   {"LC", "Local"},
+	"OF":   {"OF", "Oficina"},
+	"PI":   {"PI", "Piso"},
+	"BL":   {"BL", "Bloque"},
+	"MZ":   {"MZ", "Manzana"},
+	"LT":   {"LT", "Lote"},
+	"ED":   {"ED", "Edificio"},
+	"ET":   {"ET", "Etapa"},
+	"BG":   {"BG", "Bodega"},
+	"BR":   {"BR", "Barrio"},
+	"UR":   {"UR", "Urbanización"},
+	"CONJ": {"CONJ", "Conjunto"},
+	"FCA":  {"FCA", "Finca"},
+}
+
+var complementAliases = map[string]string{
+	"APARTAMENTO": "AP", "APTO": "AP", "APT": "AP", "AP": "AP", "APTOS": "AP",
+	"TORRE": "TO", "TO": "TO", "TORR": "TO",
+	"INTERIOR": "IN", "INT": "IN", "IN": "IN",
+	"CASA": "CS", "CS": "CS",
+	"LOCAL": "LC", "LC": "LC", "LOC": "LC",
+	"OFICINA": "OF", "OF": "OF", "OFC": "OF", "OFI": "OF",
+	"PISO": "PI", "PI": "PI",
+	"BLOQUE": "BL", "BL": "BL", "BLQ": "BL",
+	"MANZANA": "MZ", "MZ": "MZ", "MZA": "MZ",
+	"LOTE": "LT", "LT": "LT",
+	"EDIFICIO": "ED", "ED": "ED", "EDIF": "ED",
+	"ETAPA": "ET", "ET": "ET",
+	"BODEGA": "BG", "BG": "BG", "BOD": "BG",
+	"BARRIO": "BR", "BR": "BR",
+	"URBANIZACION": "UR", "URB": "UR", "UR": "UR",
+	"CONJUNTO": "CONJ", "CONJ": "CONJ", "CJTO": "CONJ",
+	"FINCA": "FCA", "FCA": "FCA",
+}
+
+// Cuadrantes de la nomenclatura urbana
+var quadrants = map[string]string{
+	"SUR": "SUR", "S": "SUR",
+	"ESTE": "ESTE", "E": "ESTE",
+	"NORTE": "NORTE", "N": "NORTE",
+	"OESTE": "OESTE",
+}
+
+// AddressComplement complemento con su valor ("AP 301", "BR El Prado")
+type AddressComplement struct {
+	AddressComponent
+	Value string `json:"value"`
+}
+
+// Address dirección colombiana estructurada
+type Address struct {
+	Rural       bool                `json:"rural"`
+	ViaType     AddressComponent    `json:"via_type"`
+	ViaNumber   string              `json:"via_number,omitempty"`   // 7A, 100 BIS
+	ViaName     string              `json:"via_name,omitempty"`     // Avenidas con nombre, nombre de la vereda o vía rural
+	ViaQuadrant string              `json:"via_quadrant,omitempty"` // SUR, ESTE
+	CrossNumber string              `json:"cross_number,omitempty"` // Número de la vía generadora (después de #)
+	Plate       string              `json:"plate,omitempty"`        // Placa (después del guion)
+	Quadrant    string              `json:"quadrant,omitempty"`
+	Complements []AddressComplement `json:"complements,omitempty"`
+	Reference   string              `json:"reference,omitempty"` // Texto libre al final (ciudad, indicaciones)
+	Raw         string              `json:"raw"`
+	Canonical   string              `json:"canonical"` // Formato DIAN: "KR 7 45 10 AP 301"
+	Formatted   string              `json:"formatted"` // Legible: "Carrera 7 # 45-10, Apartamento 301"
+}
+
+// ParseAddress interpreta una dirección en nomenclatura colombiana urbana
+// ("Cra 7 # 45-10 Apto 301", "Cl. 100 No. 15-20 Sur") o rural ("Vereda El
+// Salitre Finca La Esperanza", "Km 5 Vía La Calera") y la normaliza.
+func ParseAddress(raw string) (*Address, error) {
+	p := &addressParser{tokens: tokenizeAddress(raw)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("dirección vacía")
+	}
+
+	addr := &Address{Raw: strings.TrimSpace(raw)}
+	p.skip(",")
+
+	// Una finca sin vereda se trata como dirección rural de solo complementos
+	if code, ok := complementAliases[p.peek()]; ok && code == "FCA" {
+		addr.Rural = true
+		addr.Complements = p.complements()
+		addr.Reference = p.rest()
+		addr.format()
+		return addr, nil
+	}
+
+	viaType, ok := p.viaType()
+	if !ok {
+		return nil, fmt.Errorf("tipo de vía '%s' no reconocido (use Calle, Carrera, Diagonal, Transversal, Avenida, Vereda...)", p.peek())
+	}
+	addr.ViaType = viaType
+
+	switch viaType.Code {
+	case "VRD":
+		addr.Rural = true
+		addr.ViaName = p.words()
+		if addr.ViaName == "" {
+			return nil, fmt.Errorf("falta el nombre de la vereda")
+		}
+	case "KM", "CT":
+		addr.Rural = true
+		addr.ViaNumber = p.number()
+		addr.ViaName = p.words()
+		if addr.ViaNumber == "" && addr.ViaName == "" {
+			return nil, fmt.Errorf("falta el kilómetro o el nombre de la vía")
+		}
+	default:
+		if addr.ViaNumber = p.number(); addr.ViaNumber == "" {
+			// Avenidas con nombre: "Av Boyacá # 45-10"
+			addr.ViaName = p.wordsUntil("#")
+			if addr.ViaName == "" {
+				return nil, fmt.Errorf("falta el número de la %s", strings.ToLower(viaType.Name))
+			}
+		}
+		addr.ViaQuadrant = p.quadrant()
+
+		p.skip(",")
+		p.accept("#")
+		if addr.CrossNumber = p.number(); addr.CrossNumber == "" {
+			return nil, fmt.Errorf("falta el número de la vía generadora (después de #)")
+		}
+		p.accept("-")
+		if addr.Plate = p.plate(); addr.Plate == "" {
+			return nil, fmt.Errorf("falta la placa (después del guion)")
+		}
+		addr.Quadrant = p.quadrant()
+	}
+
+	addr.Complements = p.complements()
+	addr.Reference = p.rest()
+	addr.format()
+	return addr, nil
+}
+
+// format arma las representaciones canónica (DIAN) y legible
+func (a *Address) format() {
+	var canonical, formatted []string
+
+	if a.ViaType.Code != "" {
+		canonical = append(canonical, a.ViaType.Code)
+		formatted = append(formatted, a.ViaType.Name)
+	}
+	if a.ViaNumber != "" {
+		canonical = append(canonical, a.ViaNumber)
+		formatted = append(formatted, titleAddress(a.ViaNumber))
+	}
+	if a.ViaName != "" {
+		canonical = append(canonical, a.ViaName)
+		formatted = append(formatted, titleAddress(a.ViaName))
+	}
+	if a.ViaQuadrant != "" {
+		canonical = append(canonical, a.ViaQuadrant)
+		formatted = append(formatted, titleAddress(a.ViaQuadrant))
+	}
+	if a.CrossNumber != "" {
+		canonical = append(canonical, a.CrossNumber, a.Plate)
+		formatted = append(formatted, "#", titleAddress(a.CrossNumber)+"-"+a.Plate)
+	}
+	if a.Quadrant != "" {
+		canonical = append(canonical, a.Quadrant)
+		formatted = append(formatted, titleAddress(a.Quadrant))
+	}
+
+	human := strings.Join(formatted, " ")
+	for _, c := range a.Complements {
+		canonical = append(canonical, c.Code, c.Value)
+		part := c.Name + " " + titleAddress(c.Value)
+		if human == "" {
+			human = part
+		} else {
+			human += ", " + part
+		}
+	}
+
+	if a.Reference != "" {
+		human += ", " + titleAddress(a.Reference)
+	}
+
+	a.Canonical = strings.Join(canonical, " ")
+	a.Formatted = human
+}
+
+type addressParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *addressParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *addressParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *addressParser) accept(token string) bool {
+	if p.peek() == token {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *addressParser) skip(token string) {
+	for p.accept(token) {
+	}
+}
+
+func (p *addressParser) viaType() (AddressComponent, bool) {
+	code, ok := viaAliases[p.peek()]
+	if !ok {
+		return AddressComponent{}, false
+	}
+	p.pos++
+
+	// "Avenida Calle", "Av Cra"
+	if code == "AV" {
+		if next, ok := viaAliases[p.peek()]; ok && (next == "CL" || next == "KR") {
+			p.pos++
+			code = map[string]string{"CL": "AC", "KR": "AK"}[next]
+		}
+	}
+	return viaTypes[code], true
+}
+
+// number lee un número de vía con letras y BIS: "7", "7A", "45 B BIS C"
+func (p *addressParser) number() string {
+	token := p.peek()
+	if !startsWithDigit(token) || !isNumberToken(token) {
+		return ""
+	}
+	p.pos++
+	value := token
+
+	if letter := p.peek(); isLetterToken(letter) {
+		value += letter
+		p.pos++
+	}
+	if p.accept("BIS") {
+		value += " BIS"
+		if letter := p.peek(); isLetterToken(letter) {
+			value += " " + letter
+			p.pos++
+		}
+	}
+	return value
+}
+
+// plate lee la placa, que puede llevar letra ("10", "10A")
+func (p *addressParser) plate() string {
+	token := p.peek()
+	if !startsWithDigit(token) || !isNumberToken(token) {
+		return ""
+	}
+	p.pos++
+	if letter := p.peek(); isLetterToken(letter) {
+		token += letter
+		p.pos++
+	}
+	return token
+}
+
+func (p *addressParser) quadrant() string {
+	if q, ok := quadrants[p.peek()]; ok {
+		p.pos++
+		return q
+	}
+	return ""
+}
+
+// words lee palabras hasta el siguiente complemento
+func (p *addressParser) words() string {
+	return p.wordsUntil("")
+}
+
+func (p *addressParser) wordsUntil(stop string) string {
+	var words []string
+	for !p.done() {
+		token := p.peek()
+		if token == stop || token == "-" || token == "," {
+			break
+		}
+		if _, isComplement := complementAliases[token]; isComplement && len(words) > 0 {
+			break
+		}
+		if token == "#" {
+			break
+		}
+		words = append(words, token)
+		p.pos++
+	}
+	return strings.Join(words, " ")
+}
+
+// rest retorna el texto que sobra después de los complementos
+func (p *addressParser) rest() string {
+	var words []string
+	for ; !p.done(); p.pos++ {
+		if token := p.peek(); token != "#" && token != "-" && token != "," {
+			words = append(words, token)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func (p *addressParser) complements() []AddressComplement {
+	var result []AddressComplement
+	for !p.done() {
+		p.accept(",")
+		code, ok := complementAliases[p.peek()]
+		if !ok {
+			break
+		}
+		p.pos++
+		p.accept("#")
+		value := p.words()
+		if value == "" {
+			p.pos--
+			break
+		}
+		result = append(result, AddressComplement{AddressComponent: complementTypes[code], Value: value})
+	}
+	return result
+}
+
+// tokenizeAddress pasa a mayúsculas sin tildes y separa números, letras, # y guiones
+func tokenizeAddress(raw string) []string {
+	s := strings.ToUpper(accentReplacer.Replace(strings.ToLower(raw)))
+	for _, marker := range []string{"NO°", "N°", "Nº", "#"} {
+		s = strings.ReplaceAll(s, marker, " # ")
+	}
+	s = strings.NewReplacer("-", " - ", ",", " , ", ";", " , ", "/", " ", "°", " ", "º", " ").Replace(s)
+
+	var tokens []string
+	for _, field := range strings.Fields(s) {
+		tokens = append(tokens, splitAddressToken(field)...)
+	}
+
+	// "No 45" y "Número 45" equivalen a "# 45"
+	for i, token := range tokens {
+		if (token == "NO" || token == "NUMERO" || token == "NRO" || token == "NUM") && i+1 < len(tokens) && startsWithDigit(tokens[i+1]) {
+			tokens[i] = "#"
+		}
+	}
+	return tokens
+}
+
+// splitAddressToken separa "CRA7" en "CRA 7" y "APTO301" en "APTO 301"
+func splitAddressToken(token string) []string {
+	i := 0
+	for i < len(token) && token[i] >= 'A' && token[i] <= 'Z' {
+		i++
+	}
+	if i > 0 && i < len(token) && token[i] >= '0' && token[i] <= '9' {
+		prefix := token[:i]
+		_, isVia := viaAliases[prefix]
+		_, isComplement := complementAliases[prefix]
+		if isVia || isComplement {
+			return []string{prefix, token[i:]}
+		}
+	}
+	return []string{token}
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+// isNumberToken acepta dígitos seguidos opcionalmente de una letra: "45", "45A"
+func isNumberToken(s string) bool {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return i > 0 && (i == len(s) || (i == len(s)-1 && s[i] >= 'A' && s[i] <= 'Z'))
+}
+
+// isLetterToken letra suelta de la nomenclatura ("7 A"), sin confundirla con cuadrantes
+func isLetterToken(s string) bool {
+	if len(s) != 1 || s[0] < 'A' || s[0] > 'Z' {
+		return false
+	}
+	_, isQuadrant := quadrants[s]
+	return !isQuadrant
+}
+
+func titleAddress(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		switch {
+		case word == "BIS":
+			words[i] = "Bis"
+		case startsWithDigit(word) || len(word) == 1:
+			// Números y letras de nomenclatura se conservan
+		case word == "DE" || word == "DEL" || word == "LA" || word == "LAS" || word == "LOS" || word == "EL":
+			if i > 0 {
+				words[i] = strings.ToLower(word)
+			} else {
+				words[i] = word[:1] + strings.ToLower(word[1:])
+			}
+		default:
+			words[i] = word[:1] + strings.ToLower(word[1:])
+		}
+	}
+	return strings.Join(words, " ")
+}
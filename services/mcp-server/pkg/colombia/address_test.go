@@ -0,0 +1,252 @@
+package colombia
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	apto := func(value string) AddressComplement {
+		return AddressComplement{AddressComponent: complementTypes["AP"], Value: value}
+	}
+	complement := func(code, value string) AddressComplement {
+		return AddressComplement{AddressComponent: complementTypes[code], Value: value}
+	}
+
+	tests := []struct {
+		name      string
+		raw       string
+		want      Address // Solo los componentes; Raw, Canonical y Formatted se comparan aparte
+		canonical string
+		formatted string
+	}{
+		{
+			name:      "carrera con apartamento",
+			raw:       "Cra 7 # 45-10 Apto 301",
+			want:      Address{ViaType: viaTypes["KR"], ViaNumber: "7", CrossNumber: "45", Plate: "10", Complements: []AddressComplement{apto("301")}},
+			canonical: "KR 7 45 10 AP 301",
+			formatted: "Carrera 7 # 45-10, Apartamento 301",
+		},
+		{
+			name:      "calle abreviada con No.",
+			raw:       "Cl. 100 No. 15-20",
+			want:      Address{ViaType: viaTypes["CL"], ViaNumber: "100", CrossNumber: "15", Plate: "20"},
+			canonical: "CL 100 15 20",
+			formatted: "Calle 100 # 15-20",
+		},
+		{
+			name:      "cuadrante sur al final",
+			raw:       "Calle 100 No. 15-20 Sur",
+			want:      Address{ViaType: viaTypes["CL"], ViaNumber: "100", CrossNumber: "15", Plate: "20", Quadrant: "SUR"},
+			canonical: "CL 100 15 20 SUR",
+			formatted: "Calle 100 # 15-20 Sur",
+		},
+		{
+			name:      "sin espacios",
+			raw:       "CRA7#45-10",
+			want:      Address{ViaType: viaTypes["KR"], ViaNumber: "7", CrossNumber: "45", Plate: "10"},
+			canonical: "KR 7 45 10",
+			formatted: "Carrera 7 # 45-10",
+		},
+		{
+			name:      "sin numeral",
+			raw:       "Carrera 7 45-10",
+			want:      Address{ViaType: viaTypes["KR"], ViaNumber: "7", CrossNumber: "45", Plate: "10"},
+			canonical: "KR 7 45 10",
+			formatted: "Carrera 7 # 45-10",
+		},
+		{
+			name:      "letra, bis y cuadrante este",
+			raw:       "Carrera 7A Bis # 45B-10 Este",
+			want:      Address{ViaType: viaTypes["KR"], ViaNumber: "7A BIS", CrossNumber: "45B", Plate: "10", Quadrant: "ESTE"},
+			canonical: "KR 7A BIS 45B 10 ESTE",
+			formatted: "Carrera 7A Bis # 45B-10 Este",
+		},
+		{
+			name:      "letra separada y letra después del bis",
+			raw:       "Calle 45 B Bis C # 7-10",
+			want:      Address{ViaType: viaTypes["CL"], ViaNumber: "45B BIS C", CrossNumber: "7", Plate: "10"},
+			canonical: "CL 45B BIS C 7 10",
+			formatted: "Calle 45B Bis C # 7-10",
+		},
+		{
+			name:      "bis sin letra",
+			raw:       "Calle 45 Bis # 7-10",
+			want:      Address{ViaType: viaTypes["CL"], ViaNumber: "45 BIS", CrossNumber: "7", Plate: "10"},
+			canonical: "CL 45 BIS 7 10",
+			formatted: "Calle 45 Bis # 7-10",
+		},
+		{
+			name:      "bis con cuadrante sur de la vía",
+			raw:       "Cl 1 Bis Sur # 2-3",
+			want:      Address{ViaType: viaTypes["CL"], ViaNumber: "1 BIS", ViaQuadrant: "SUR", CrossNumber: "2", Plate: "3"},
+			canonical: "CL 1 BIS SUR 2 3",
+			formatted: "Calle 1 Bis Sur # 2-3",
+		},
+		{
+			name:      "transversal con oficina",
+			raw:       "Transversal 23 # 94-33 Oficina 502",
+			want:      Address{ViaType: viaTypes["TV"], ViaNumber: "23", CrossNumber: "94", Plate: "33", Complements: []AddressComplement{complement("OF", "502")}},
+			canonical: "TV 23 94 33 OF 502",
+			formatted: "Transversal 23 # 94-33, Oficina 502",
+		},
+		{
+			name:      "transversal este",
+			raw:       "Tv 5 Este # 10-20",
+			want:      Address{ViaType: viaTypes["TV"], ViaNumber: "5", ViaQuadrant: "ESTE", CrossNumber: "10", Plate: "20"},
+			canonical: "TV 5 ESTE 10 20",
+			formatted: "Transversal 5 Este # 10-20",
+		},
+		{
+			name:      "diagonal sur",
+			raw:       "Diagonal 40A Sur # 23-15",
+			want:      Address{ViaType: viaTypes["DG"], ViaNumber: "40A", ViaQuadrant: "SUR", CrossNumber: "23", Plate: "15"},
+			canonical: "DG 40A SUR 23 15",
+			formatted: "Diagonal 40A Sur # 23-15",
+		},
+		{
+			name:      "diagonal sin numeral ni guion",
+			raw:       "Diagonal 40A Sur 23 15",
+			want:      Address{ViaType: viaTypes["DG"], ViaNumber: "40A", ViaQuadrant: "SUR", CrossNumber: "23", Plate: "15"},
+			canonical: "DG 40A SUR 23 15",
+			formatted: "Diagonal 40A Sur # 23-15",
+		},
+		{
+			name: "diagonal con torre y apartamento",
+			raw:  "Dg 61C # 26-36, Torre 2 Apto 1203",
+			want: Address{ViaType: viaTypes["DG"], ViaNumber: "61C", CrossNumber: "26", Plate: "36",
+				Complements: []AddressComplement{complement("TO", "2"), apto("1203")}},
+			canonical: "DG 61C 26 36 TO 2 AP 1203",
+			formatted: "Diagonal 61C # 26-36, Torre 2, Apartamento 1203",
+		},
+		{
+			name:      "avenida con nombre",
+			raw:       "Av Boyacá # 45-10",
+			want:      Address{ViaType: viaTypes["AV"], ViaName: "BOYACA", CrossNumber: "45", Plate: "10"},
+			canonical: "AV BOYACA 45 10",
+			formatted: "Avenida Boyaca # 45-10",
+		},
+		{
+			name:      "avenida calle",
+			raw:       "Avenida Calle 26 # 68C-61",
+			want:      Address{ViaType: viaTypes["AC"], ViaNumber: "26", CrossNumber: "68C", Plate: "61"},
+			canonical: "AC 26 68C 61",
+			formatted: "Avenida Calle 26 # 68C-61",
+		},
+		{
+			name:      "avenida carrera con local",
+			raw:       "Ak 15 # 93-60 Local 3",
+			want:      Address{ViaType: viaTypes["AK"], ViaNumber: "15", CrossNumber: "93", Plate: "60", Complements: []AddressComplement{complement("LC", "3")}},
+			canonical: "AK 15 93 60 LC 3",
+			formatted: "Avenida Carrera 15 # 93-60, Local 3",
+		},
+		{
+			name:      "barrio como complemento",
+			raw:       "Calle 10 Sur # 5-20 Barrio El Prado",
+			want:      Address{ViaType: viaTypes["CL"], ViaNumber: "10", ViaQuadrant: "SUR", CrossNumber: "5", Plate: "20", Complements: []AddressComplement{complement("BR", "EL PRADO")}},
+			canonical: "CL 10 SUR 5 20 BR EL PRADO",
+			formatted: "Calle 10 Sur # 5-20, Barrio El Prado",
+		},
+		{
+			name: "N° con interior y casa",
+			raw:  "Cll 80 N° 10-43 Int 4 Casa 12",
+			want: Address{ViaType: viaTypes["CL"], ViaNumber: "80", CrossNumber: "10", Plate: "43",
+				Complements: []AddressComplement{complement("IN", "4"), complement("CS", "12")}},
+			canonical: "CL 80 10 43 IN 4 CS 12",
+			formatted: "Calle 80 # 10-43, Interior 4, Casa 12",
+		},
+		{
+			name: "ciudad al final como referencia",
+			raw:  "Kra 50 Nº 30-15 Bloque 3 Apto 402, Medellín",
+			want: Address{ViaType: viaTypes["KR"], ViaNumber: "50", CrossNumber: "30", Plate: "15",
+				Complements: []AddressComplement{complement("BL", "3"), apto("402")}, Reference: "MEDELLIN"},
+			canonical: "KR 50 30 15 BL 3 AP 402",
+			formatted: "Carrera 50 # 30-15, Bloque 3, Apartamento 402, Medellin",
+		},
+		{
+			name:      "vereda con finca",
+			raw:       "Vereda El Salitre Finca La Esperanza",
+			want:      Address{Rural: true, ViaType: viaTypes["VRD"], ViaName: "EL SALITRE", Complements: []AddressComplement{complement("FCA", "LA ESPERANZA")}},
+			canonical: "VRD EL SALITRE FCA LA ESPERANZA",
+			formatted: "Vereda El Salitre, Finca La Esperanza",
+		},
+		{
+			name:      "vereda abreviada con municipio",
+			raw:       "Vda La Balsa, Chía",
+			want:      Address{Rural: true, ViaType: viaTypes["VRD"], ViaName: "LA BALSA", Reference: "CHIA"},
+			canonical: "VRD LA BALSA",
+			formatted: "Vereda La Balsa, Chia",
+		},
+		{
+			name:      "kilómetro de una vía",
+			raw:       "Km 5 Vía La Calera",
+			want:      Address{Rural: true, ViaType: viaTypes["KM"], ViaNumber: "5", ViaName: "VIA LA CALERA"},
+			canonical: "KM 5 VIA LA CALERA",
+			formatted: "Kilómetro 5 Via la Calera",
+		},
+		{
+			name:      "finca sin vereda",
+			raw:       "Finca Los Naranjos",
+			want:      Address{Rural: true, Complements: []AddressComplement{complement("FCA", "LOS NARANJOS")}},
+			canonical: "FCA LOS NARANJOS",
+			formatted: "Finca Los Naranjos",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAddress(tt.raw)
+			if err != nil {
+				t.Fatalf("ParseAddress(%q) error = %v", tt.raw, err)
+			}
+			if got.Raw != strings.TrimSpace(tt.raw) {
+				t.Errorf("Raw = %q, want %q", got.Raw, tt.raw)
+			}
+			if got.Canonical != tt.canonical {
+				t.Errorf("Canonical = %q, want %q", got.Canonical, tt.canonical)
+			}
+			if got.Formatted != tt.formatted {
+				t.Errorf("Formatted = %q, want %q", got.Formatted, tt.formatted)
+			}
+
+			components := *got
+			components.Raw, components.Canonical, components.Formatted = "", "", ""
+			if !reflect.DeepEqual(components, tt.want) {
+				t.Errorf("ParseAddress(%q) =\n  %+v\nwant\n  %+v", tt.raw, components, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAddressMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{"vacía", "", "dirección vacía"},
+		{"solo espacios", "   ", "dirección vacía"},
+		{"solo separadores", " , ", "tipo de vía"},
+		{"tipo de vía desconocido", "Plaza 5 # 4-3", "tipo de vía 'PLAZA' no reconocido"},
+		{"sin número de vía", "Calle # 45-10", "falta el número de la calle"},
+		{"sin vía generadora", "Carrera 7 #", "falta el número de la vía generadora"},
+		{"sin placa", "Calle 100 # 15", "falta la placa"},
+		{"placa sin número", "Calle 100 # 15-", "falta la placa"},
+		{"vereda sin nombre", "Vereda", "falta el nombre de la vereda"},
+		{"kilómetro sin datos", "Km", "falta el kilómetro o el nombre de la vía"},
+		{"texto libre", "Al lado de la iglesia", "no reconocido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAddress(tt.raw)
+			if err == nil {
+				t.Fatalf("ParseAddress(%q) = %+v, want error", tt.raw, got)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseAddress(%q) error = %q, want %q", tt.raw, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package colombia
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ===== CALENDARIO DE FESTIVOS Y DÍAS HÁBILES =====
+
+// Holiday festivo nacional (Ley 51 de 1983)
+type Holiday struct {
+	Date  time.Time `json:"date"`
+	Name  string    `json:"name"`
+	Moved bool      `json:"moved"` // Trasladado al lunes por la Ley Emiliani
+}
+
+// Festivos de fecha fija que no se trasladan
+var fixedHolidays = []struct {
+	month time.Month
+	day   int
+	name  string
+}{
+	{time.January, 1, "Año Nuevo"},
+	{time.May, 1, "Día del Trabajo"},
+	{time.July, 20, "Día de la Independencia"},
+	{time.August, 7, "Batalla de Boyacá"},
+	{time.December, 8, "Inmaculada Concepción"},
+	{time.December, 25, "Navidad"},
+}
+
+// Festivos que se trasladan al lunes siguiente (Ley Emiliani)
+var emilianiHolidays = []struct {
+	month time.Month
+	day   int
+	name  string
+}{
+	{time.January, 6, "Día de los Reyes Magos"},
+	{time.March, 19, "Día de San José"},
+	{time.June, 29, "San Pedro y San Pablo"},
+	{time.August, 15, "La Asunción de la Virgen"},
+	{time.October, 12, "Día de la Raza"},
+	{time.November, 1, "Todos los Santos"},
+	{time.November, 11, "Independencia de Cartagena"},
+}
+
+// Festivos religiosos calculados desde el domingo de Pascua
+var easterHolidays = []struct {
+	offset int
+	name   string
+	moved  bool
+}{
+	{-3, "Jueves Santo", false},
+	{-2, "Viernes Santo", false},
+	{43, "Ascensión del Señor", true},
+	{64, "Corpus Christi", true},
+	{71, "Sagrado Corazón de Jesús", true},
+}
+
+var (
+	holidayCache   = make(map[int][]Holiday)
+	holidayCacheMu sync.Mutex
+)
+
+// Holidays retorna los festivos nacionales de un año ordenados por fecha
+func Holidays(year int) []Holiday {
+	holidayCacheMu.Lock()
+	defer holidayCacheMu.Unlock()
+
+	if cached, exists := holidayCache[year]; exists {
+		return append([]Holiday(nil), cached...)
+	}
+
+	var holidays []Holiday
+	for _, h := range fixedHolidays {
+		holidays = append(holidays, Holiday{Date: dateInBogota(year, h.month, h.day), Name: h.name})
+	}
+	for _, h := range emilianiHolidays {
+		date := dateInBogota(year, h.month, h.day)
+		moved := nextMonday(date)
+		holidays = append(holidays, Holiday{Date: moved, Name: h.name, Moved: !moved.Equal(date)})
+	}
+	easter := EasterSunday(year)
+	for _, h := range easterHolidays {
+		holidays = append(holidays, Holiday{Date: easter.AddDate(0, 0, h.offset), Name: h.name, Moved: h.moved})
+	}
+
+	sort.Slice(holidays, func(i, j int) bool {
+		return holidays[i].Date.Before(holidays[j].Date)
+	})
+	holidayCache[year] = holidays
+	return append([]Holiday(nil), holidays...)
+}
+
+// EasterSunday calcula el domingo de Pascua (algoritmo gregoriano anónimo)
+func EasterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return dateInBogota(year, time.Month(month), day)
+}
+
+// Location zona horaria de Colombia (America/Bogota, UTC-5 sin horario de verano)
+func Location() *time.Location {
+	return bogota
+}
+
+// IsHoliday indica si la fecha (en hora de Colombia) es festivo nacional
+func IsHoliday(t time.Time) (Holiday, bool) {
+	day := startOfDay(t)
+	for _, h := range Holidays(day.Year()) {
+		if h.Date.Equal(day) {
+			return h, true
+		}
+	}
+	return Holiday{}, false
+}
+
+// IsBusinessDay indica si la fecha es día hábil (lunes a viernes no festivo)
+func IsBusinessDay(t time.Time) bool {
+	day := startOfDay(t)
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	_, holiday := IsHoliday(day)
+	return !holiday
+}
+
+// NextBusinessDay retorna la misma fecha si es hábil o el siguiente día hábil,
+// conservando la hora
+func NextBusinessDay(t time.Time) time.Time {
+	t = t.In(bogota)
+	for !IsBusinessDay(t) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// AddBusinessDays suma (o resta, si n es negativo) días hábiles conservando la hora
+func AddBusinessDays(t time.Time, n int) time.Time {
+	t = t.In(bogota)
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, step)
+		if IsBusinessDay(t) {
+			n--
+		}
+	}
+	return t
+}
+
+// BusinessDaysBetween cuenta los días hábiles después de from y hasta to
+// (inclusive); es negativo si to es anterior a from
+func BusinessDaysBetween(from, to time.Time) int {
+	start, end := startOfDay(from), startOfDay(to)
+	sign := 1
+	if end.Before(start) {
+		start, end, sign = end, start, -1
+	}
+
+	count := 0
+	for day := start.AddDate(0, 0, 1); !day.After(end); day = day.AddDate(0, 0, 1) {
+		if IsBusinessDay(day) {
+			count++
+		}
+	}
+	return sign * count
+}
+
+// ===== HORARIO DE ATENCIÓN =====
+
+// OpeningHours franja de atención en minutos desde la medianoche
+type OpeningHours struct {
+	Open  int `json:"open"`
+	Close int `json:"close"`
+}
+
+// BusinessHours horario de atención por día de la semana; los festivos no se atiende
+type BusinessHours map[time.Weekday]OpeningHours
+
+// DefaultBusinessHours lunes a viernes de 8:00 a 18:00 y sábados de 9:00 a 14:00
+var DefaultBusinessHours = BusinessHours{
+	time.Monday:    {8 * 60, 18 * 60},
+	time.Tuesday:   {8 * 60, 18 * 60},
+	time.Wednesday: {8 * 60, 18 * 60},
+	time.Thursday:  {8 * 60, 18 * 60},
+	time.Friday:    {8 * 60, 18 * 60},
+	time.Saturday:  {9 * 60, 14 * 60},
+}
+
+// IsOpen indica si el negocio atiende en el momento indicado
+func (h BusinessHours) IsOpen(t time.Time) bool {
+	t = t.In(bogota)
+	hours, ok := h[t.Weekday()]
+	if !ok {
+		return false
+	}
+	if _, holiday := IsHoliday(t); holiday {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	return minute >= hours.Open && minute < hours.Close
+}
+
+// NextOpening retorna el próximo momento de apertura a partir de t
+// (t mismo si está abierto); cero si el horario no tiene días de atención
+func (h BusinessHours) NextOpening(t time.Time) time.Time {
+	if h.IsOpen(t) {
+		return t.In(bogota)
+	}
+
+	day := startOfDay(t)
+	for i := 0; i < 31; i++ {
+		hours, ok := h[day.Weekday()]
+		_, holiday := IsHoliday(day)
+		if ok && !holiday {
+			opening := day.Add(time.Duration(hours.Open) * time.Minute)
+			if opening.After(t) {
+				return opening
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// Describe resume el horario en texto ("lunes a viernes de 8:00 a 18:00...")
+func (h BusinessHours) Describe() string {
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+	var parts []string
+	for i := 0; i < len(weekdays); {
+		hours, ok := h[weekdays[i]]
+		if !ok {
+			i++
+			continue
+		}
+		j := i
+		for j+1 < len(weekdays) && h[weekdays[j+1]] == hours {
+			j++
+		}
+
+		days := weekdayNames[weekdays[i]]
+		if j > i {
+			days += " a " + weekdayNames[weekdays[j]]
+		}
+		parts = append(parts, fmt.Sprintf("%s de %s a %s", days, formatMinutes(hours.Open), formatMinutes(hours.Close)))
+		i = j + 1
+	}
+
+	switch len(parts) {
+	case 0:
+		return "sin horario de atención"
+	case 1:
+		return parts[0]
+	default:
+		result := parts[0]
+		for _, part := range parts[1 : len(parts)-1] {
+			result += ", " + part
+		}
+		return result + " y " + parts[len(parts)-1]
+	}
+}
+
+var weekdayNames = map[time.Weekday]string{
+	time.Monday:    "lunes",
+	time.Tuesday:   "martes",
+	time.Wednesday: "miércoles",
+	time.Thursday:  "jueves",
+	time.Friday:    "viernes",
+	time.Saturday:  "sábado",
+	time.Sunday:    "domingo",
+}
+
+var monthNames = []string{"", "enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"}
+
+// FormatDate formatea una fecha en español: "lunes 14 de octubre"
+func FormatDate(t time.Time) string {
+	t = t.In(bogota)
+	return fmt.Sprintf("%s %d de %s", weekdayNames[t.Weekday()], t.Day(), monthNames[t.Month()])
+}
+
+func formatMinutes(minutes int) string {
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
+
+func dateInBogota(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, bogota)
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.In(bogota)
+	return dateInBogota(t.Year(), t.Month(), t.Day())
+}
+
+func nextMonday(t time.Time) time.Time {
+	for t.Weekday() != time.Monday {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
@@ -0,0 +1,462 @@
+package colombia
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ===== CLASIFICACIÓN CIIU =====
+
+// Catálogo de clases CIIU Rev. 4 adaptada para Colombia (Resolución DANE
+// 066 de 2012), el mismo que usa la DIAN en la casilla 46 del RUT
+//
+//go:embed data/ciiu.csv
+var ciiuCSV []byte
+
+// MinCIIUConfidence confianza mínima para asignar automáticamente una actividad
+const MinCIIUConfidence = 0.5
+
+// CIIUSection sección CIIU (A-U) con un nombre corto de sector para reportes
+type CIIUSection struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Sector string `json:"sector"`
+}
+
+// Secciones de la CIIU Rev. 4 A.C.
+var ciiuSections = []CIIUSection{
+	{"A", "Agricultura, ganadería, caza, silvicultura y pesca", "Agropecuario"},
+	{"B", "Explotación de minas y canteras", "Minería"},
+	{"C", "Industrias manufactureras", "Manufactura"},
+	{"D", "Suministro de electricidad, gas, vapor y aire acondicionado", "Energía"},
+	{"E", "Distribución de agua; evacuación y tratamiento de aguas residuales, gestión de desechos y actividades de saneamiento ambiental", "Agua y saneamiento"},
+	{"F", "Construcción", "Construcción"},
+	{"G", "Comercio al por mayor y al por menor; reparación de vehículos automotores y motocicletas", "Comercio"},
+	{"H", "Transporte y almacenamiento", "Transporte y logística"},
+	{"I", "Alojamiento y servicios de comida", "Turismo y gastronomía"},
+	{"J", "Información y comunicaciones", "Tecnología y comunicaciones"},
+	{"K", "Actividades financieras y de seguros", "Financiero"},
+	{"L", "Actividades inmobiliarias", "Inmobiliario"},
+	{"M", "Actividades profesionales, científicas y técnicas", "Servicios profesionales"},
+	{"N", "Actividades de servicios administrativos y de apoyo", "Servicios empresariales"},
+	{"O", "Administración pública y defensa; planes de seguridad social de afiliación obligatoria", "Sector público"},
+	{"P", "Educación", "Educación"},
+	{"Q", "Actividades de atención de la salud humana y de asistencia social", "Salud"},
+	{"R", "Actividades artísticas, de entretenimiento y recreación", "Entretenimiento y cultura"},
+	{"S", "Otras actividades de servicios", "Servicios personales"},
+	{"T", "Actividades de los hogares individuales en calidad de empleadores; actividades no diferenciadas de los hogares individuales como productores de bienes y servicios para uso propio", "Hogares"},
+	{"U", "Actividades de organizaciones y entidades extraterritoriales", "Organismos internacionales"},
+}
+
+// CIIUActivity clase CIIU de cuatro dígitos
+type CIIUActivity struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	Division    string `json:"division"`
+	Section     string `json:"section"`
+	SectionName string `json:"section_name"`
+	Sector      string `json:"sector"`
+
+	tokens   map[string]bool
+	idfTotal float64
+}
+
+// CIIUMatch actividad sugerida para un texto. Score es el puntaje acumulado
+// de palabras clave y coincidencias con la descripción; Confidence va de 0 a 1
+// y baja cuando hay otras actividades con puntaje parecido.
+type CIIUMatch struct {
+	CIIUActivity
+	Score      float64  `json:"score"`
+	Confidence float64  `json:"confidence"`
+	Keywords   []string `json:"keywords,omitempty"`
+}
+
+// Palabras clave de actividades frecuentes en pymes. El peso refleja qué tan
+// específica es la palabra: "panadería" casi siempre es 1081, "pan" no.
+var ciiuKeywords = []struct {
+	keyword string
+	code    string
+	weight  float64
+}{
+	// Agropecuario
+	{"finca cafetera", "0123", 3}, {"caficultor", "0123", 3}, {"cultivo de cafe", "0123", 3},
+	{"floricultor", "0125", 3}, {"cultivo de flor", "0125", 3}, {"flor", "0125", 1},
+	{"aguacate", "0121", 2.5}, {"fruta", "0121", 1}, {"hortaliza", "0113", 2.5},
+	{"ganaderia", "0141", 3}, {"ganado", "0141", 2.5}, {"avicola", "0145", 3},
+	{"porcicultura", "0144", 3}, {"cerdo", "0144", 1.5}, {"piscicultura", "0322", 3},
+	{"trucha", "0322", 2.5}, {"tilapia", "0322", 2.5}, {"agropecuario", "0150", 2},
+	{"cannabis", "0128", 2.5},
+	// Minería y energía
+	{"mineria de oro", "0722", 3}, {"carbon", "0510", 2}, {"petroleo", "0610", 2},
+	{"servicio petrolero", "0910", 3}, {"energia solar", "3511", 3}, {"panel solar", "3511", 3},
+	{"generacion de energia", "3511", 3}, {"reciclaje", "3830", 3}, {"residuo", "3811", 2},
+	// Manufactura
+	{"panaderia", "1081", 3}, {"pasteleria", "1081", 2.5}, {"reposteria", "1081", 2.5},
+	{"bizcocheria", "1081", 3}, {"ponque", "1081", 2}, {"pan", "1081", 1.5},
+	{"chocolateria", "1082", 3}, {"confiteria", "1082", 2.5}, {"chocolate", "1082", 2},
+	{"lacteo", "1040", 2.5}, {"queso", "1040", 2}, {"yogur", "1040", 2},
+	{"embutido", "1011", 2.5}, {"carnico", "1011", 2},
+	{"tostion", "1062", 3}, {"tostador", "1062", 3}, {"cafe especial", "1062", 2.5}, {"cafe", "1062", 1},
+	{"panela", "1072", 3}, {"trapiche", "1072", 3}, {"arepa", "1089", 2},
+	{"pulpa de fruta", "1020", 3}, {"mermelada", "1020", 2.5}, {"harina", "1051", 2}, {"molino", "1051", 2},
+	{"comida preparada", "1084", 3}, {"aguardiente", "1101", 3}, {"cerveceria", "1103", 3},
+	{"cerveza artesanal", "1103", 3}, {"vino", "1102", 2}, {"jugo", "1104", 1.5},
+	{"confeccion", "1410", 3}, {"maquila", "1410", 2}, {"fabricacion de calzado", "1521", 3},
+	{"marroquineria", "1512", 3}, {"imprenta", "1811", 3}, {"litografia", "1811", 3},
+	{"impresion", "1811", 2}, {"jabon", "2023", 2.5}, {"detergente", "2023", 2.5},
+	{"laboratorio farmaceutico", "2100", 3}, {"plastico", "2229", 2}, {"carton", "1702", 2},
+	{"metalmecanica", "2592", 3}, {"estructura metalica", "2511", 3}, {"soldadura", "2592", 2},
+	{"fabrica de mueble", "3110", 3}, {"fabricacion de mueble", "3110", 3}, {"muebleria", "3110", 2},
+	{"carpinteria", "1630", 2}, {"bisuteria", "3210", 2.5},
+	// Construcción e inmobiliario
+	{"constructora", "4111", 3}, {"construccion de vivienda", "4111", 3}, {"construccion", "4111", 2},
+	{"obra civil", "4290", 3}, {"remodelacion", "4330", 3}, {"drywall", "4330", 3}, {"acabado", "4330", 1.5},
+	{"instalacion electrica", "4321", 3}, {"electricista", "4321", 3}, {"plomeria", "4322", 3},
+	{"fontaneria", "4322", 3}, {"aire acondicionado", "4322", 2},
+	{"inmobiliaria", "6820", 3}, {"finca raiz", "6820", 3}, {"bienes raices", "6820", 3},
+	{"arrendamiento", "6810", 2}, {"alquiler de inmueble", "6810", 3},
+	// Comercio
+	{"concesionario", "4511", 3}, {"taller mecanico", "4520", 3}, {"mecanica automotriz", "4520", 3},
+	{"lavadero de carro", "4520", 2.5}, {"autoparte", "4530", 3}, {"repuesto", "4530", 2.5},
+	{"taller de moto", "4542", 3}, {"moto", "4541", 2},
+	{"distribuidora de alimento", "4631", 3}, {"distribuidora de medicamento", "4645", 3},
+	{"material de construccion", "4663", 3}, {"deposito de material", "4663", 3},
+	{"al por mayor", "4690", 2.5}, {"mayorista", "4690", 2}, {"distribuidora", "4690", 1.5},
+	{"supermercado", "4711", 3}, {"minimercado", "4711", 3}, {"tienda de barrio", "4711", 3},
+	{"abarrote", "4711", 2.5}, {"viver", "4711", 2}, {"mercado", "4711", 1},
+	{"fruver", "4721", 3}, {"fruteria", "4721", 2.5}, {"huevo", "4722", 1.5},
+	{"carniceria", "4723", 3}, {"pescaderia", "4723", 3}, {"licorera", "4724", 3}, {"licor", "4724", 2},
+	{"estacion de servicio", "4731", 3}, {"gasolinera", "4731", 3}, {"lubricante", "4732", 2.5},
+	{"celular", "4741", 2}, {"computador", "4741", 1.5},
+	{"ferreteria", "4752", 3}, {"herramienta", "4752", 1.5}, {"pintura", "4752", 1},
+	{"electrodomestico", "4754", 3}, {"mueble", "4754", 1.5}, {"decoracion", "4759", 1.5},
+	{"papeleria", "4761", 3}, {"libreria", "4761", 3}, {"libro", "4761", 1.5},
+	{"articulo deportivo", "4762", 3}, {"deportivo", "4762", 1.5},
+	{"jugueteria", "4769", 3}, {"juguete", "4769", 2},
+	{"tienda de ropa", "4771", 3}, {"boutique", "4771", 2}, {"ropa", "4771", 2.5},
+	{"moda", "4771", 2}, {"prenda", "4771", 2}, {"vestido", "4771", 1.5},
+	{"zapateria", "4772", 3}, {"calzado", "4772", 2.5}, {"zapato", "4772", 2.5},
+	{"drogueria", "4773", 3}, {"farmacia", "4773", 3}, {"cosmetico", "4773", 2},
+	{"medicamento", "4773", 2}, {"maquillaje", "4773", 1.5},
+	{"optica", "4774", 2.5}, {"joyeria", "4774", 2.5}, {"floristeria", "4774", 3}, {"mascota", "4774", 2},
+	{"compraventa", "4775", 3}, {"segunda mano", "4775", 3},
+	{"tienda online", "4791", 3}, {"tienda virtual", "4791", 3}, {"ecommerce", "4791", 3},
+	{"comercio electronico", "4791", 3}, {"venta por internet", "4791", 3}, {"marketplace", "4791", 2},
+	// Transporte
+	{"transporte de carga", "4923", 3}, {"trasteo", "4923", 3}, {"mudanza", "4923", 3}, {"flete", "4923", 2},
+	{"transporte de pasajero", "4921", 3}, {"transporte escolar", "4921", 3}, {"transporte especial", "4921", 2.5},
+	{"taxi", "4921", 2}, {"aerolinea", "5111", 3}, {"bodegaje", "5210", 3}, {"almacenamiento", "5210", 2.5},
+	{"bodega", "5210", 2}, {"agencia de aduana", "5229", 3}, {"logistica", "5229", 2},
+	{"mensajeria", "5320", 3}, {"courier", "5320", 3}, {"domicilio", "5320", 2},
+	// Alojamiento y comidas
+	{"hotel", "5511", 3}, {"hostal", "5519", 2.5}, {"glamping", "5514", 3}, {"finca turistica", "5514", 2.5},
+	{"restaurante", "5611", 3}, {"asadero", "5611", 3}, {"pizzeria", "5611", 3}, {"almuerzo", "5611", 2},
+	{"gastronomia", "5611", 2}, {"parrilla", "5611", 2}, {"comida", "5611", 1.5}, {"menu", "5611", 1},
+	{"comida rapida", "5612", 3}, {"hamburguesa", "5612", 2}, {"perro caliente", "5612", 2},
+	{"cafeteria", "5613", 3}, {"tienda de cafe", "5613", 2.5}, {"cafe", "5613", 1.5},
+	{"heladeria", "5619", 3}, {"catering", "5621", 3}, {"banquete", "5621", 2.5},
+	{"discoteca", "5630", 3}, {"gastrobar", "5630", 2.5}, {"bar", "5630", 2.5},
+	// Tecnología y comunicaciones
+	{"desarrollo de software", "6201", 3}, {"desarrollo web", "6201", 3}, {"software", "6201", 3},
+	{"saas", "6201", 3}, {"aplicacion movil", "6201", 2.5}, {"programacion", "6201", 2}, {"app", "6201", 1.5},
+	{"plataforma", "6201", 1}, {"tecnologia", "6201", 1}, {"startup", "6201", 1},
+	{"consultoria ti", "6202", 3}, {"consultoria tecnologica", "6202", 3}, {"infraestructura ti", "6202", 2},
+	{"soporte tecnico", "6209", 2}, {"hosting", "6311", 3}, {"centro de dato", "6311", 3},
+	{"cloud", "6311", 2}, {"nube", "6311", 1.5}, {"portal web", "6312", 2},
+	{"proveedor de internet", "6110", 3}, {"telecomunicacion", "6190", 2.5},
+	{"editorial", "5811", 3}, {"periodico", "5813", 2.5}, {"revista", "5813", 2},
+	{"produccion audiovisual", "5911", 3}, {"productora", "5911", 2}, {"video", "5911", 1.5},
+	// Financiero
+	{"fintech", "6499", 2.5}, {"microcredito", "6499", 3}, {"prestamo", "6499", 2}, {"credito", "6499", 1.5},
+	{"factoring", "6493", 3}, {"fondo de empleado", "6492", 3}, {"cooperativa", "6424", 2},
+	{"banco", "6412", 2}, {"casa de cambio", "6614", 3}, {"corredor de seguro", "6621", 3}, {"seguro", "6621", 1.5},
+	// Servicios profesionales
+	{"firma de abogado", "6910", 3}, {"bufete", "6910", 3}, {"abogado", "6910", 3}, {"juridico", "6910", 2.5},
+	{"legal", "6910", 1.5}, {"asesoria tributaria", "6920", 3}, {"revisoria fiscal", "6920", 3},
+	{"contabilidad", "6920", 3}, {"contador", "6920", 3}, {"contable", "6920", 2.5}, {"auditoria", "6920", 2},
+	{"consultoria empresarial", "7020", 3}, {"asesoria empresarial", "7020", 2.5}, {"consultoria", "7020", 2},
+	{"coaching", "7020", 2}, {"arquitectura", "7110", 3}, {"arquitecto", "7110", 3},
+	{"topografia", "7110", 2.5}, {"ingenieria", "7110", 2},
+	{"marketing digital", "7310", 3}, {"agencia de marketing", "7310", 3}, {"publicidad", "7310", 3},
+	{"agencia digital", "7310", 2.5}, {"marketing", "7310", 2}, {"redes sociales", "7310", 1},
+	{"investigacion de mercado", "7320", 3}, {"encuesta", "7320", 2},
+	{"diseno grafico", "7410", 3}, {"diseno de interior", "7410", 3}, {"diseno", "7410", 1.5},
+	{"estudio fotografico", "7420", 3}, {"fotografia", "7420", 3}, {"fotografo", "7420", 3},
+	{"veterinaria", "7500", 3}, {"veterinario", "7500", 3},
+	// Servicios administrativos
+	{"alquiler de carro", "7710", 3}, {"agencia de empleo", "7810", 3}, {"reclutamiento", "7810", 2.5},
+	{"headhunter", "7810", 3}, {"empresa de servicio temporal", "7820", 3},
+	{"agencia de viaje", "7911", 3}, {"operador turistico", "7912", 3}, {"turismo", "7912", 1.5}, {"tour", "7912", 2},
+	{"seguridad privada", "8010", 3}, {"vigilancia", "8010", 3}, {"servicio de aseo", "8121", 3},
+	{"limpieza", "8121", 2}, {"aseo", "8121", 2}, {"paisajismo", "8130", 3}, {"jardineria", "8130", 2.5},
+	{"call center", "8220", 3}, {"contact center", "8220", 3},
+	{"organizacion de evento", "8230", 3}, {"evento", "8230", 2}, {"feria", "8230", 1.5},
+	{"cobranza", "8291", 3}, {"empaque", "8292", 1.5},
+	// Sector público, educación y salud
+	{"alcaldia", "8412", 3}, {"gobernacion", "8412", 3}, {"entidad publica", "8412", 3},
+	{"jardin infantil", "8511", 3}, {"preescolar", "8512", 3}, {"colegio", "8513", 3}, {"escuela", "8513", 2},
+	{"universidad", "8544", 3}, {"formacion para el trabajo", "8551", 3}, {"instituto tecnico", "8551", 2},
+	{"escuela de futbol", "8552", 3}, {"escuela de musica", "8553", 3}, {"clase de baile", "8553", 2.5},
+	{"idioma", "8559", 2.5}, {"ingles", "8559", 2}, {"academia", "8559", 2}, {"educacion virtual", "8559", 2.5},
+	{"capacitacion", "8559", 2}, {"curso", "8559", 1.5}, {"educacion", "8559", 1.5},
+	{"hospital", "8610", 3}, {"clinica", "8610", 2.5}, {"consultorio medico", "8621", 3},
+	{"medicina general", "8621", 3}, {"medico", "8621", 2}, {"odontologia", "8622", 3},
+	{"odontologo", "8622", 3}, {"dental", "8622", 2.5}, {"laboratorio clinico", "8691", 3},
+	{"fisioterapia", "8692", 3}, {"psicologia", "8692", 2.5}, {"psicologo", "8692", 2.5},
+	{"terapia", "8692", 1.5}, {"ips", "8699", 2}, {"salud", "8699", 1},
+	{"hogar geriatrico", "8730", 3}, {"adulto mayor", "8730", 2},
+	// Entretenimiento y servicios personales
+	{"concierto", "9007", 2.5}, {"museo", "9102", 3}, {"casino", "9200", 3}, {"apuesta", "9200", 2.5},
+	{"gimnasio", "9311", 3}, {"crossfit", "9311", 2.5}, {"recreacion", "9329", 2},
+	{"fundacion", "9499", 2}, {"ong", "9499", 3}, {"iglesia", "9491", 3},
+	{"reparacion de computador", "9511", 3}, {"mantenimiento de computador", "9511", 3},
+	{"reparacion de celular", "9512", 3}, {"lavanderia", "9601", 3}, {"tintoreria", "9601", 3},
+	{"peluqueria", "9602", 3}, {"barberia", "9602", 3}, {"salon de belleza", "9602", 3},
+	{"manicure", "9602", 3}, {"estetica", "9602", 2.5}, {"spa", "9602", 2.5},
+	{"funeraria", "9603", 3}, {"tatuaje", "9609", 3},
+}
+
+// Palabras que no aportan a la clasificación
+var ciiuStopwords = map[string]bool{
+	"de": true, "del": true, "la": true, "el": true, "lo": true, "los": true, "las": true,
+	"y": true, "e": true, "o": true, "u": true, "en": true, "a": true, "al": true, "por": true, "para": true,
+	"con": true, "sin": true, "su": true, "sus": true, "que": true, "otro": true, "otra": true, "ncp": true,
+	"tipo": true, "excepto": true, "actividad": true, "establecimiento": true, "especializado": true,
+	"nuestro": true, "nuestra": true, "empresa": true, "colombia": true, "mejor": true, "calidad": true,
+}
+
+type ciiuCatalog struct {
+	activities []*CIIUActivity
+	byCode     map[string]*CIIUActivity
+	sections   map[string]CIIUSection
+	idf        map[string]float64
+	keywords   map[string][]ciiuKeyword // palabra clave normalizada -> clases
+}
+
+type ciiuKeyword struct {
+	code   string
+	weight float64
+}
+
+var (
+	ciiuOnce   sync.Once
+	ciiuLoaded *ciiuCatalog
+)
+
+func loadCIIU() *ciiuCatalog {
+	ciiuOnce.Do(func() {
+		records, err := csv.NewReader(bytes.NewReader(ciiuCSV)).ReadAll()
+		if err != nil {
+			panic(fmt.Sprintf("catálogo CIIU inválido: %v", err))
+		}
+
+		cat := &ciiuCatalog{
+			byCode:   make(map[string]*CIIUActivity),
+			sections: make(map[string]CIIUSection),
+			idf:      make(map[string]float64),
+			keywords: make(map[string][]ciiuKeyword),
+		}
+		for _, section := range ciiuSections {
+			cat.sections[section.Code] = section
+		}
+
+		df := make(map[string]int)
+		for _, record := range records[1:] {
+			section := cat.sections[record[0]]
+			activity := &CIIUActivity{
+				Code:        record[2],
+				Description: record[3],
+				Division:    record[1],
+				Section:     section.Code,
+				SectionName: section.Name,
+				Sector:      section.Sector,
+				tokens:      make(map[string]bool),
+			}
+			for _, token := range ciiuTokens(activity.Description) {
+				if !ciiuStopwords[token] && !activity.tokens[token] {
+					activity.tokens[token] = true
+					df[token]++
+				}
+			}
+			cat.activities = append(cat.activities, activity)
+			cat.byCode[activity.Code] = activity
+		}
+
+		// Las palabras que aparecen en muchas descripciones ("comercio",
+		// "fabricación") pesan menos que las específicas ("panadería")
+		total := float64(len(cat.activities))
+		for token, count := range df {
+			cat.idf[token] = math.Log(1 + total/float64(count))
+		}
+		for _, activity := range cat.activities {
+			for token := range activity.tokens {
+				activity.idfTotal += cat.idf[token]
+			}
+		}
+
+		for _, kw := range ciiuKeywords {
+			if _, exists := cat.byCode[kw.code]; !exists {
+				panic(fmt.Sprintf("palabra clave %q apunta a la clase CIIU inexistente %s", kw.keyword, kw.code))
+			}
+			key := strings.Join(ciiuTokens(kw.keyword), " ")
+			cat.keywords[key] = append(cat.keywords[key], ciiuKeyword{code: kw.code, weight: kw.weight})
+		}
+
+		ciiuLoaded = cat
+	})
+	return ciiuLoaded
+}
+
+// LookupCIIU busca una clase CIIU por su código de cuatro dígitos
+func LookupCIIU(code string) (CIIUActivity, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) == 3 {
+		// Algunos sistemas pierden el cero inicial de las clases agropecuarias
+		code = "0" + code
+	}
+	activity, exists := loadCIIU().byCode[code]
+	if !exists {
+		return CIIUActivity{}, false
+	}
+	return *activity, true
+}
+
+// CIIUSections retorna las secciones de la CIIU en orden
+func CIIUSections() []CIIUSection {
+	return append([]CIIUSection(nil), ciiuSections...)
+}
+
+// CIIUActivities retorna las clases de una sección (o todas si section es vacío)
+func CIIUActivities(section string) []CIIUActivity {
+	section = strings.ToUpper(strings.TrimSpace(section))
+	result := []CIIUActivity{}
+	for _, activity := range loadCIIU().activities {
+		if section == "" || activity.Section == section {
+			result = append(result, *activity)
+		}
+	}
+	return result
+}
+
+// ClassifyActivity sugiere las clases CIIU que mejor describen un texto libre
+// (descripción de la empresa, contenido del sitio web, actividad declarada).
+// Combina palabras clave ponderadas de actividades frecuentes en pymes con la
+// coincidencia de palabras con las descripciones oficiales del catálogo.
+// Retorna hasta limit resultados ordenados por puntaje; vacío si nada coincide.
+func ClassifyActivity(text string, limit int) []CIIUMatch {
+	cat := loadCIIU()
+	tokens := ciiuTokens(text)
+	if len(tokens) == 0 {
+		return []CIIUMatch{}
+	}
+
+	scores := make(map[string]float64)
+	matched := make(map[string][]string)
+
+	// Palabras clave: cada una cuenta una sola vez aunque se repita
+	joined := " " + strings.Join(tokens, " ") + " "
+	for keyword, targets := range cat.keywords {
+		if !strings.Contains(joined, " "+keyword+" ") {
+			continue
+		}
+		for _, target := range targets {
+			scores[target.code] += target.weight
+			matched[target.code] = append(matched[target.code], keyword)
+		}
+	}
+
+	// Descripciones oficiales: fracción (ponderada por especificidad) de las
+	// palabras de la descripción presentes en el texto
+	present := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		present[token] = true
+	}
+	for _, activity := range cat.activities {
+		if activity.idfTotal == 0 {
+			continue
+		}
+		overlap := 0.0
+		for token := range activity.tokens {
+			if present[token] {
+				overlap += cat.idf[token]
+			}
+		}
+		if overlap > 0 {
+			scores[activity.Code] += 2 * overlap / activity.idfTotal
+		}
+	}
+
+	matches := make([]CIIUMatch, 0, len(scores))
+	for code, score := range scores {
+		if score < 0.5 {
+			continue
+		}
+		keywords := matched[code]
+		sort.Strings(keywords)
+		matches = append(matches, CIIUMatch{CIIUActivity: *cat.byCode[code], Score: score, Keywords: keywords})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Code < matches[j].Code
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	// La confianza combina la fuerza del puntaje con la distancia al siguiente:
+	// una sola palabra muy específica da confianza alta, un empate la reduce
+	for i := range matches {
+		strength := matches[i].Score / (matches[i].Score + 2)
+		var rival float64
+		if i == 0 && len(matches) > 1 {
+			rival = matches[1].Score
+		} else if i > 0 {
+			rival = matches[0].Score
+		}
+		separation := matches[i].Score / (matches[i].Score + rival)
+		confidence := math.Min(1, strength*(0.5+separation))
+		matches[i].Confidence = math.Round(confidence*100) / 100
+		matches[i].Score = math.Round(matches[i].Score*100) / 100
+	}
+	return matches
+}
+
+// BestCIIU retorna la actividad más probable para un texto si supera la
+// confianza mínima
+func BestCIIU(text string) (CIIUMatch, bool) {
+	matches := ClassifyActivity(text, 1)
+	if len(matches) == 0 || matches[0].Confidence < MinCIIUConfidence {
+		return CIIUMatch{}, false
+	}
+	return matches[0], true
+}
+
+// ciiuTokens normaliza el texto y reduce los plurales a singular
+func ciiuTokens(text string) []string {
+	words := strings.Fields(normalizeName(text))
+	for i, word := range words {
+		words[i] = singular(word)
+	}
+	return words
+}
+
+// singular quita el plural español más común: "panaderías" -> "panaderia",
+// "hoteles" -> "hotel". No es un lematizador; basta para comparar palabras.
+func singular(word string) string {
+	n := len(word)
+	if n <= 3 || word[n-1] != 's' {
+		return word
+	}
+	if n > 4 && word[n-2] == 'e' && strings.ContainsRune("lrndzj", rune(word[n-3])) {
+		return word[:n-2]
+	}
+	return word[:n-1]
+}
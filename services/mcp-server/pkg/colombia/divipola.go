@@ -0,0 +1,349 @@
+package colombia
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Catálogo DIVIPOLA del DANE (departamentos y municipios con su código).
+// Incluye las áreas no municipalizadas de Amazonas, Guainía y Vaupés, que
+// la DIAN y las transportadoras también reciben como municipio.
+//
+//go:embed data/divipola.csv
+var divipolaCSV []byte
+
+// MinMatchScore puntaje mínimo para considerar que una búsqueda coincide
+const MinMatchScore = 0.75
+
+// Department departamento colombiano con su código DANE de dos dígitos
+type Department struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Municipality municipio con su código DIVIPOLA de cinco dígitos
+type Municipality struct {
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	DepartmentCode string `json:"department_code"`
+	Department     string `json:"department"`
+	Capital        bool   `json:"capital"`
+
+	search     string
+	deptSearch string
+}
+
+// MunicipalityMatch resultado de una búsqueda aproximada
+type MunicipalityMatch struct {
+	Municipality
+	Score float64 `json:"score"`
+}
+
+type divipolaCatalog struct {
+	departments    []Department
+	municipalities []*Municipality
+	byCode         map[string]*Municipality
+	byDepartment   map[string][]*Municipality
+}
+
+var (
+	catalogOnce sync.Once
+	catalog     *divipolaCatalog
+)
+
+func loadCatalog() *divipolaCatalog {
+	catalogOnce.Do(func() {
+		records, err := csv.NewReader(bytes.NewReader(divipolaCSV)).ReadAll()
+		if err != nil {
+			panic(fmt.Sprintf("catálogo DIVIPOLA inválido: %v", err))
+		}
+
+		catalog = &divipolaCatalog{
+			byCode:       make(map[string]*Municipality),
+			byDepartment: make(map[string][]*Municipality),
+		}
+		for _, record := range records[1:] {
+			deptCode, deptName, code, name := record[0], record[1], record[2], record[3]
+			if _, exists := catalog.byDepartment[deptCode]; !exists {
+				catalog.departments = append(catalog.departments, Department{Code: deptCode, Name: deptName})
+			}
+
+			m := &Municipality{
+				Code:           code,
+				Name:           name,
+				DepartmentCode: deptCode,
+				Department:     deptName,
+				Capital:        strings.HasSuffix(code, "001"),
+				search:         normalizeName(name),
+				deptSearch:     normalizeName(deptName),
+			}
+			catalog.municipalities = append(catalog.municipalities, m)
+			catalog.byCode[code] = m
+			catalog.byDepartment[deptCode] = append(catalog.byDepartment[deptCode], m)
+		}
+
+		sort.Slice(catalog.departments, func(i, j int) bool {
+			return normalizeName(catalog.departments[i].Name) < normalizeName(catalog.departments[j].Name)
+		})
+	})
+	return catalog
+}
+
+// Departments retorna los 33 departamentos (incluido Bogotá D.C.) en orden alfabético
+func Departments() []Department {
+	return append([]Department(nil), loadCatalog().departments...)
+}
+
+// FindDepartment busca un departamento por código o nombre aproximado
+func FindDepartment(query string) (Department, bool) {
+	q := normalizeName(query)
+	if q == "" {
+		return Department{}, false
+	}
+
+	var best Department
+	bestScore := 0.0
+	for _, dept := range loadCatalog().departments {
+		if q == dept.Code {
+			return dept, true
+		}
+		if score := departmentScore(q, normalizeName(dept.Name)); score > bestScore {
+			best, bestScore = dept, score
+		}
+	}
+	return best, bestScore >= MinMatchScore
+}
+
+// Municipalities retorna los municipios de un departamento (código o nombre)
+func Municipalities(department string) []Municipality {
+	dept, ok := FindDepartment(department)
+	if !ok {
+		return []Municipality{}
+	}
+
+	list := loadCatalog().byDepartment[dept.Code]
+	result := make([]Municipality, 0, len(list))
+	for _, m := range list {
+		result = append(result, *m)
+	}
+	return result
+}
+
+// MunicipalityByCode busca un municipio por su código DIVIPOLA
+func MunicipalityByCode(code string) (Municipality, bool) {
+	m, exists := loadCatalog().byCode[strings.TrimSpace(code)]
+	if !exists {
+		return Municipality{}, false
+	}
+	return *m, true
+}
+
+// SearchMunicipalities busca municipios por nombre sin importar tildes ni
+// mayúsculas. Acepta el departamento al final ("cali valle") para desambiguar
+// y tolera errores de digitación. Los resultados van de mayor a menor puntaje.
+func SearchMunicipalities(query string, limit int) []MunicipalityMatch {
+	q := normalizeName(query)
+	if q == "" {
+		return []MunicipalityMatch{}
+	}
+	if m, ok := MunicipalityByCode(q); ok {
+		return []MunicipalityMatch{{Municipality: m, Score: 1}}
+	}
+
+	tokens := strings.Fields(q)
+	matches := []MunicipalityMatch{}
+	for _, m := range loadCatalog().municipalities {
+		if score := municipalityScore(q, tokens, m); score >= MinMatchScore {
+			matches = append(matches, MunicipalityMatch{Municipality: *m, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if matches[i].Capital != matches[j].Capital {
+			return matches[i].Capital
+		}
+		return matches[i].Code < matches[j].Code
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// ResolveMunicipality resuelve una ciudad escrita por el usuario (o su código)
+// a un único municipio. Si varios municipios empatan se prefiere la capital
+// de departamento; si no hay capital entre ellos la búsqueda es ambigua.
+func ResolveMunicipality(query string) (Municipality, error) {
+	matches := SearchMunicipalities(query, 0)
+	if len(matches) == 0 {
+		return Municipality{}, fmt.Errorf("ciudad '%s' no encontrada en el catálogo DIVIPOLA", query)
+	}
+
+	best := matches[0]
+	var tied []string
+	for _, match := range matches[1:] {
+		if match.Score < best.Score {
+			break
+		}
+		tied = append(tied, fmt.Sprintf("%s (%s)", match.Name, match.Department))
+	}
+	if len(tied) > 0 && !best.Capital {
+		options := append([]string{fmt.Sprintf("%s (%s)", best.Name, best.Department)}, tied...)
+		return Municipality{}, fmt.Errorf("ciudad '%s' ambigua, indique el departamento: %s", query, strings.Join(options, ", "))
+	}
+
+	return best.Municipality, nil
+}
+
+func municipalityScore(q string, tokens []string, m *Municipality) float64 {
+	score := nameScore(q, m.search)
+	if score == 1 {
+		return 1
+	}
+
+	// Separar el departamento al final de la búsqueda: "la union valle"
+	for k := len(tokens) - 1; k > 0; k-- {
+		deptScore := departmentScore(strings.Join(tokens[k:], " "), m.deptSearch)
+		if deptScore < MinMatchScore {
+			continue
+		}
+		cityScore := nameScore(strings.Join(tokens[:k], " "), m.search)
+		if cityScore < MinMatchScore {
+			continue
+		}
+		// El departamento coincidente desempata frente a homónimos sin él
+		if combined := minScore(1, cityScore+0.01*deptScore); combined > score {
+			score = combined
+		}
+	}
+	return score
+}
+
+func nameScore(q, name string) float64 {
+	if q == name {
+		return 1
+	}
+	score := similarity(q, name)
+	switch {
+	case strings.HasPrefix(name, q+" "):
+		// "bogota" -> "bogota dc", "cartagena" -> "cartagena de indias"
+		score = maxScore(score, 0.95)
+	case strings.HasSuffix(name, " "+q):
+		// "tumaco" -> "san andres de tumaco", "buga" -> "guadalajara de buga"
+		score = maxScore(score, 0.9)
+	}
+	return score
+}
+
+func departmentScore(q, dept string) float64 {
+	if q == dept {
+		return 1
+	}
+	score := similarity(q, dept)
+	switch {
+	case strings.HasPrefix(dept, q+" "):
+		// "valle" -> "valle del cauca"
+		score = maxScore(score, 0.95)
+	case containsWords(dept, q):
+		// "guajira" -> "la guajira", "san andres y providencia" -> "archipielago de san andres..."
+		score = maxScore(score, 0.9)
+	}
+	return score
+}
+
+// containsWords indica si todas las palabras de q aparecen en s
+func containsWords(s, q string) bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(s) {
+		words[word] = true
+	}
+	for _, word := range strings.Fields(q) {
+		if !words[word] {
+			return false
+		}
+	}
+	return true
+}
+
+// similarity distancia de edición normalizada entre 0 y 1
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	".", "",
+)
+
+// NormalizeText normaliza un nombre para compararlo (ver normalizeName)
+func NormalizeText(s string) string {
+	return normalizeName(s)
+}
+
+// normalizeName pasa a minúsculas, quita tildes y puntuación y colapsa espacios
+func normalizeName(s string) string {
+	s = accentReplacer.Replace(strings.ToLower(s))
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}), " ")
+}
+
+func maxScore(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minScore(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
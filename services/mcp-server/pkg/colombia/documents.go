@@ -0,0 +1,233 @@
+package colombia
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ===== DOCUMENTOS DE IDENTIFICACIÓN =====
+
+// Códigos DIAN de tipos de documento (anexo técnico de facturación electrónica)
+const (
+	DocRegistroCivil      = "11"
+	DocTarjetaIdentidad   = "12"
+	DocCedulaCiudadania   = "13"
+	DocTarjetaExtranjeria = "21"
+	DocCedulaExtranjeria  = "22"
+	DocNIT                = "31"
+	DocPasaporte          = "41"
+	DocExtranjero         = "42"
+	DocPEP                = "47"
+	DocPPT                = "48"
+	DocNITOtroPais        = "50"
+	DocNUIP               = "91"
+)
+
+// DocumentType tipo de documento de identificación y sus reglas de formato
+type DocumentType struct {
+	Code         string `json:"code"`         // Código DIAN
+	Abbreviation string `json:"abbreviation"` // CC, NIT, CE...
+	Name         string `json:"name"`
+	Numeric      bool   `json:"numeric"` // Solo dígitos; si no, alfanumérico
+	MinLength    int    `json:"min_length"`
+	MaxLength    int    `json:"max_length"`
+	Foreign      bool   `json:"foreign"` // Documento de extranjeros
+}
+
+// DocumentTypes catálogo de tipos de documento aceptados por la DIAN
+var DocumentTypes = []DocumentType{
+	{Code: DocRegistroCivil, Abbreviation: "RC", Name: "Registro civil de nacimiento", MinLength: 7, MaxLength: 11},
+	{Code: DocTarjetaIdentidad, Abbreviation: "TI", Name: "Tarjeta de identidad", Numeric: true, MinLength: 10, MaxLength: 11},
+	{Code: DocCedulaCiudadania, Abbreviation: "CC", Name: "Cédula de ciudadanía", Numeric: true, MinLength: 3, MaxLength: 10},
+	{Code: DocTarjetaExtranjeria, Abbreviation: "TE", Name: "Tarjeta de extranjería", Numeric: true, MinLength: 3, MaxLength: 10, Foreign: true},
+	{Code: DocCedulaExtranjeria, Abbreviation: "CE", Name: "Cédula de extranjería", Numeric: true, MinLength: 3, MaxLength: 10, Foreign: true},
+	{Code: DocNIT, Abbreviation: "NIT", Name: "Número de identificación tributaria", Numeric: true, MinLength: 1, MaxLength: 15},
+	{Code: DocPasaporte, Abbreviation: "PA", Name: "Pasaporte", MinLength: 5, MaxLength: 20},
+	{Code: DocExtranjero, Abbreviation: "DIE", Name: "Documento de identificación extranjero", MinLength: 3, MaxLength: 20, Foreign: true},
+	{Code: DocPEP, Abbreviation: "PEP", Name: "Permiso especial de permanencia", Numeric: true, MinLength: 6, MaxLength: 15, Foreign: true},
+	{Code: DocPPT, Abbreviation: "PPT", Name: "Permiso por protección temporal", Numeric: true, MinLength: 6, MaxLength: 15, Foreign: true},
+	{Code: DocNITOtroPais, Abbreviation: "NITE", Name: "NIT de otro país", MinLength: 3, MaxLength: 20, Foreign: true},
+	{Code: DocNUIP, Abbreviation: "NUIP", Name: "Número único de identificación personal", Numeric: true, MinLength: 10, MaxLength: 11},
+}
+
+// Nombres alternativos con que llegan los tipos de documento
+var documentTypeAliases = map[string]string{
+	"CEDULA":    DocCedulaCiudadania,
+	"PASAPORTE": DocPasaporte,
+	"PP":        DocPasaporte,
+	"PT":        DocPPT,
+	"NITEXT":    DocNITOtroPais,
+	"NIT_EXT":   DocNITOtroPais,
+	"NE":        DocNITOtroPais,
+}
+
+// LookupDocumentType busca un tipo de documento por código DIAN o abreviatura
+func LookupDocumentType(value string) (DocumentType, bool) {
+	key := strings.ToUpper(strings.TrimSpace(value))
+	if code, exists := documentTypeAliases[key]; exists {
+		key = code
+	}
+	for _, docType := range DocumentTypes {
+		if key == docType.Code || key == docType.Abbreviation {
+			return docType, true
+		}
+	}
+	return DocumentType{}, false
+}
+
+// IdentityDocument documento validado y normalizado
+type IdentityDocument struct {
+	TypeCode   string `json:"type_code"` // Código DIAN
+	Type       string `json:"type"`      // Abreviatura
+	TypeName   string `json:"type_name"`
+	Number     string `json:"number"` // Sin puntos, espacios ni dígito de verificación
+	CheckDigit string `json:"check_digit,omitempty"`
+	Formatted  string `json:"formatted"`
+	Foreign    bool   `json:"foreign"`
+}
+
+// ValidateDocument valida y normaliza un documento de identificación según su
+// tipo (código DIAN o abreviatura). Para NIT el último dígito, o lo que siga
+// al guion, se toma como dígito de verificación y se comprueba.
+func ValidateDocument(docType, number string) (*IdentityDocument, error) {
+	t, ok := LookupDocumentType(docType)
+	if !ok {
+		return nil, fmt.Errorf("tipo de documento '%s' no soportado", docType)
+	}
+
+	clean := cleanDocumentNumber(number)
+	if t.Code == DocNIT {
+		return validateNITDocument(t, clean)
+	}
+	clean = strings.ReplaceAll(clean, "-", "")
+
+	if err := checkDocumentFormat(t, clean); err != nil {
+		return nil, err
+	}
+
+	formatted := clean
+	if t.Numeric {
+		formatted = groupThousands(clean)
+	}
+	return &IdentityDocument{
+		TypeCode:  t.Code,
+		Type:      t.Abbreviation,
+		TypeName:  t.Name,
+		Number:    clean,
+		Formatted: formatted,
+		Foreign:   t.Foreign,
+	}, nil
+}
+
+// NITCheckDigit calcula el dígito de verificación de un NIT de cualquier longitud (hasta 15 dígitos)
+func NITCheckDigit(nit string) (string, error) {
+	weights := []int{3, 7, 13, 17, 19, 23, 29, 37, 41, 43, 47, 53, 59, 67, 71}
+	if nit == "" || len(nit) > len(weights) || !isDigits(nit) {
+		return "", fmt.Errorf("NIT debe tener entre 1 y %d dígitos", len(weights))
+	}
+
+	sum := 0
+	for i := range nit {
+		digit := int(nit[len(nit)-1-i] - '0')
+		sum += digit * weights[i]
+	}
+
+	remainder := sum % 11
+	if remainder < 2 {
+		return strconv.Itoa(remainder), nil
+	}
+	return strconv.Itoa(11 - remainder), nil
+}
+
+func validateNITDocument(t DocumentType, clean string) (*IdentityDocument, error) {
+	base, checkDigit := clean, ""
+	if parts := strings.SplitN(clean, "-", 2); len(parts) == 2 {
+		base, checkDigit = parts[0], parts[1]
+	} else if len(clean) > 1 {
+		base, checkDigit = clean[:len(clean)-1], clean[len(clean)-1:]
+	}
+
+	if err := checkDocumentFormat(t, base); err != nil {
+		return nil, err
+	}
+	calculated, err := NITCheckDigit(base)
+	if err != nil {
+		return nil, err
+	}
+	if checkDigit != calculated {
+		msg := fmt.Sprintf("NIT inválido: el dígito de verificación de %s debería ser %s", base, calculated)
+		if !strings.Contains(clean, "-") {
+			// Puede que el NIT se haya enviado sin dígito de verificación
+			if full, err := NITCheckDigit(clean); err == nil {
+				msg += fmt.Sprintf(" (si %s no incluye el DV, este es %s)", clean, full)
+			}
+		}
+		return nil, fmt.Errorf("%s", msg)
+	}
+
+	return &IdentityDocument{
+		TypeCode:   t.Code,
+		Type:       t.Abbreviation,
+		TypeName:   t.Name,
+		Number:     base,
+		CheckDigit: checkDigit,
+		Formatted:  groupThousands(base) + "-" + checkDigit,
+	}, nil
+}
+
+func checkDocumentFormat(t DocumentType, value string) error {
+	if len(value) < t.MinLength || len(value) > t.MaxLength {
+		if t.MinLength == t.MaxLength {
+			return fmt.Errorf("%s debe tener %d caracteres", t.Name, t.MinLength)
+		}
+		return fmt.Errorf("%s debe tener entre %d y %d caracteres", t.Name, t.MinLength, t.MaxLength)
+	}
+	if t.Numeric {
+		if !isDigits(value) {
+			return fmt.Errorf("%s debe contener solo números", t.Name)
+		}
+		if value[0] == '0' {
+			return fmt.Errorf("%s no puede empezar por cero", t.Name)
+		}
+		return nil
+	}
+	for _, r := range value {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return fmt.Errorf("%s solo admite letras y números", t.Name)
+		}
+	}
+	return nil
+}
+
+// cleanDocumentNumber quita puntos, comas y espacios y pasa a mayúsculas
+func cleanDocumentNumber(number string) string {
+	clean := strings.ToUpper(strings.TrimSpace(number))
+	for _, sep := range []string{".", ",", " "} {
+		clean = strings.ReplaceAll(clean, sep, "")
+	}
+	return clean
+}
+
+func groupThousands(digits string) string {
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,329 @@
+package colombia
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FiscalParameters parámetros fiscales vigentes desde una fecha: UVT, salario
+// mínimo, tarifas de IVA e INC y bases de retención en la fuente
+type FiscalParameters struct {
+	Year                int                           `json:"year"`
+	EffectiveFrom       time.Time                     `json:"effective_from"`
+	UVT                 int                           `json:"uvt"`
+	MinimumWage         int                           `json:"minimum_wage"`        // SMMLV
+	TransportAllowance  int                           `json:"transport_allowance"` // Auxilio de transporte
+	IVARates            map[string]float64            `json:"iva_rates"`
+	INCRates            map[string]float64            `json:"inc_rates"`
+	ReteIVARate         float64                       `json:"reteiva_rate"`
+	WithholdingConcepts map[string]WithholdingConcept `json:"withholding_concepts"`
+	Source              string                        `json:"source,omitempty"` // Norma que fija los valores
+	PublishedAt         time.Time                     `json:"published_at"`
+}
+
+// IVARate retorna la tarifa de IVA de una categoría (general si no se indica)
+func (p *FiscalParameters) IVARate(category string) (float64, error) {
+	if category == "" {
+		category = IVAGeneral
+	}
+	rate, ok := p.IVARates[strings.ToLower(category)]
+	if !ok {
+		return 0, fmt.Errorf("categoría de IVA desconocida: %s", category)
+	}
+	return rate, nil
+}
+
+// INCRate retorna la tarifa de impuesto al consumo de una categoría
+func (p *FiscalParameters) INCRate(category string) (float64, error) {
+	if category == INCNone {
+		return 0, nil
+	}
+	rate, ok := p.INCRates[strings.ToLower(category)]
+	if !ok {
+		return 0, fmt.Errorf("categoría de impuesto al consumo desconocida: %s", category)
+	}
+	return rate, nil
+}
+
+// Concept retorna un concepto de retención en la fuente
+func (p *FiscalParameters) Concept(code string) (WithholdingConcept, error) {
+	concept, ok := p.WithholdingConcepts[code]
+	if !ok {
+		return WithholdingConcept{}, fmt.Errorf("concepto de retención desconocido: %s", code)
+	}
+	return concept, nil
+}
+
+// UVTs convierte un valor en UVT a pesos
+func (p *FiscalParameters) UVTs(value float64) int {
+	return roundCOP(value * float64(p.UVT))
+}
+
+// clone copia los parámetros con sus propios mapas de tarifas y conceptos,
+// para que modificar la copia no altere los valores del almacén
+func (p FiscalParameters) clone() FiscalParameters {
+	p.IVARates = mergeRates(p.IVARates, nil)
+	p.INCRates = mergeRates(p.INCRates, nil)
+	p.WithholdingConcepts = mergeConcepts(p.WithholdingConcepts, nil)
+	return p
+}
+
+// FiscalStore almacén de parámetros fiscales con vigencia por fecha
+type FiscalStore struct {
+	mu      sync.RWMutex
+	entries []FiscalParameters // ordenados por EffectiveFrom
+	now     func() time.Time
+}
+
+// NewFiscalStore crea el almacén con los valores históricos publicados
+func NewFiscalStore() *FiscalStore {
+	store := &FiscalStore{
+		entries: seedFiscalParameters(),
+		now:     time.Now,
+	}
+	store.sort()
+	return store
+}
+
+// At retorna los parámetros vigentes en una fecha
+func (s *FiscalStore) At(date time.Time) (*FiscalParameters, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if !s.entries[i].EffectiveFrom.After(date) {
+			entry := s.entries[i].clone()
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("no hay parámetros fiscales vigentes para %s", date.Format("2006-01-02"))
+}
+
+// Current retorna los parámetros vigentes hoy
+func (s *FiscalStore) Current() (*FiscalParameters, error) {
+	return s.At(s.now())
+}
+
+// List retorna todos los parámetros publicados
+func (s *FiscalStore) List() []FiscalParameters {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]FiscalParameters, len(s.entries))
+	for i, entry := range s.entries {
+		result[i] = entry.clone()
+	}
+	return result
+}
+
+// Publish publica parámetros con vigencia futura. Las tarifas y conceptos no
+// informados se heredan de los parámetros vigentes anteriores. Los valores ya
+// vigentes no se pueden modificar.
+func (s *FiscalStore) Publish(params FiscalParameters) (*FiscalParameters, error) {
+	if params.EffectiveFrom.IsZero() {
+		if params.Year == 0 {
+			return nil, fmt.Errorf("se requiere el año o la fecha de vigencia")
+		}
+		params.EffectiveFrom = time.Date(params.Year, 1, 1, 0, 0, 0, 0, bogota)
+	}
+	if params.Year == 0 {
+		params.Year = params.EffectiveFrom.Year()
+	}
+	if params.UVT <= 0 || params.MinimumWage <= 0 {
+		return nil, fmt.Errorf("UVT y salario mínimo deben ser mayores a cero")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !params.EffectiveFrom.After(s.now()) {
+		return nil, fmt.Errorf("no se pueden modificar parámetros ya vigentes (%s)", params.EffectiveFrom.Format("2006-01-02"))
+	}
+
+	// Heredar lo no informado de los parámetros inmediatamente anteriores
+	var previous *FiscalParameters
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if s.entries[i].EffectiveFrom.Before(params.EffectiveFrom) {
+			previous = &s.entries[i]
+			break
+		}
+	}
+	if previous != nil {
+		if params.TransportAllowance == 0 {
+			params.TransportAllowance = previous.TransportAllowance
+		}
+		if params.ReteIVARate == 0 {
+			params.ReteIVARate = previous.ReteIVARate
+		}
+		params.IVARates = mergeRates(previous.IVARates, params.IVARates)
+		params.INCRates = mergeRates(previous.INCRates, params.INCRates)
+		params.WithholdingConcepts = mergeConcepts(previous.WithholdingConcepts, params.WithholdingConcepts)
+	}
+	params.PublishedAt = s.now()
+
+	replaced := false
+	for i := range s.entries {
+		if s.entries[i].EffectiveFrom.Equal(params.EffectiveFrom) {
+			s.entries[i] = params
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.entries = append(s.entries, params)
+		s.sort()
+	}
+
+	published := params.clone()
+	return &published, nil
+}
+
+func (s *FiscalStore) sort() {
+	sort.Slice(s.entries, func(i, j int) bool {
+		return s.entries[i].EffectiveFrom.Before(s.entries[j].EffectiveFrom)
+	})
+}
+
+func mergeRates(base, override map[string]float64) map[string]float64 {
+	merged := make(map[string]float64, len(base))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+	return merged
+}
+
+func mergeConcepts(base, override map[string]WithholdingConcept) map[string]WithholdingConcept {
+	merged := make(map[string]WithholdingConcept, len(base))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		value.Code = key
+		merged[key] = value
+	}
+	return merged
+}
+
+// ===== VALORES HISTÓRICOS =====
+
+var bogota = time.FixedZone("COT", -5*60*60)
+
+// seedFiscalParameters valores publicados por la DIAN (UVT) y el Gobierno (SMMLV)
+func seedFiscalParameters() []FiscalParameters {
+	yearly := []struct {
+		year, uvt, wage, transport int
+	}{
+		{2019, 34270, 828116, 97032},
+		{2020, 35607, 877803, 102854},
+		{2021, 36308, 908526, 106454},
+		{2022, 38004, 1000000, 117172},
+		{2023, 42412, 1160000, 140606},
+		{2024, 47065, 1300000, 162000},
+		{2025, 49799, 1423500, 200000},
+		{2026, 52374, 1750905, 249095},
+	}
+
+	var entries []FiscalParameters
+	for _, y := range yearly {
+		entries = append(entries, FiscalParameters{
+			Year:                y.year,
+			EffectiveFrom:       time.Date(y.year, 1, 1, 0, 0, 0, 0, bogota),
+			UVT:                 y.uvt,
+			MinimumWage:         y.wage,
+			TransportAllowance:  y.transport,
+			IVARates:            seedIVARates(),
+			INCRates:            seedINCRates(),
+			ReteIVARate:         15,
+			WithholdingConcepts: withholdingConceptsBefore2025(),
+			Source:              fmt.Sprintf("Resolución DIAN UVT %d; decreto de salario mínimo %d", y.year, y.year),
+		})
+	}
+
+	// Decreto 0572 de 2025: nuevas bases mínimas de retención desde junio de 2025
+	for i := range entries {
+		if entries[i].Year >= 2026 {
+			entries[i].WithholdingConcepts = withholdingConcepts2025()
+		}
+	}
+	var mid2025 FiscalParameters
+	for _, entry := range entries {
+		if entry.Year == 2025 {
+			mid2025 = entry.clone()
+		}
+	}
+	mid2025.EffectiveFrom = time.Date(2025, 6, 1, 0, 0, 0, 0, bogota)
+	mid2025.WithholdingConcepts = withholdingConcepts2025()
+	mid2025.Source = "Decreto 0572 de 2025"
+	entries = append(entries, mid2025)
+
+	return entries
+}
+
+func seedIVARates() map[string]float64 {
+	return map[string]float64{
+		IVAGeneral:  19,
+		IVAReducido: 5,
+		IVAExento:   0,
+		IVAExcluido: 0,
+	}
+}
+
+func seedINCRates() map[string]float64 {
+	return map[string]float64{
+		INCComidas:   8,
+		INCTelefonia: 4,
+		INCVehiculos: 8,
+		INCLujo:      16,
+	}
+}
+
+// withholdingConceptsBefore2025 bases mínimas del Decreto 1625 de 2016 (27 UVT compras, 4 UVT servicios)
+func withholdingConceptsBefore2025() map[string]WithholdingConcept {
+	return map[string]WithholdingConcept{
+		"compras":                 {Code: "compras", Name: "Compras generales", BaseUVT: 27, Rate: 2.5, NonDeclarantRate: 3.5},
+		"compras_agricolas":       {Code: "compras_agricolas", Name: "Compras de bienes agrícolas sin procesamiento", BaseUVT: 92, Rate: 1.5, NonDeclarantRate: 1.5},
+		"combustibles":            {Code: "combustibles", Name: "Compra de combustibles derivados del petróleo", BaseUVT: 0, Rate: 0.1, NonDeclarantRate: 0.1},
+		"servicios":               {Code: "servicios", Name: "Servicios generales", BaseUVT: 4, Rate: 4, NonDeclarantRate: 6},
+		"honorarios":              {Code: "honorarios", Name: "Honorarios y comisiones", BaseUVT: 0, Rate: 11, NonDeclarantRate: 10, NaturalPersonRate: 10},
+		"consultoria":             {Code: "consultoria", Name: "Consultoría y servicios profesionales", BaseUVT: 0, Rate: 11, NonDeclarantRate: 10, NaturalPersonRate: 10},
+		"software":                {Code: "software", Name: "Licenciamiento y desarrollo de software", BaseUVT: 0, Rate: 3.5, NonDeclarantRate: 3.5},
+		"arrendamiento_inmuebles": {Code: "arrendamiento_inmuebles", Name: "Arrendamiento de bienes raíces", BaseUVT: 27, Rate: 3.5, NonDeclarantRate: 3.5},
+		"arrendamiento_muebles":   {Code: "arrendamiento_muebles", Name: "Arrendamiento de bienes muebles", BaseUVT: 0, Rate: 4, NonDeclarantRate: 4},
+		"transporte_carga":        {Code: "transporte_carga", Name: "Transporte nacional de carga", BaseUVT: 4, Rate: 1, NonDeclarantRate: 1},
+		"transporte_pasajeros":    {Code: "transporte_pasajeros", Name: "Transporte terrestre de pasajeros", BaseUVT: 27, Rate: 3.5, NonDeclarantRate: 3.5},
+		"hoteles_restaurantes":    {Code: "hoteles_restaurantes", Name: "Servicios de hoteles, restaurantes y hospedaje", BaseUVT: 4, Rate: 3.5, NonDeclarantRate: 3.5},
+		"aseo_vigilancia":         {Code: "aseo_vigilancia", Name: "Servicios de aseo y vigilancia (sobre AIU)", BaseUVT: 4, Rate: 2, NonDeclarantRate: 2},
+		"temporales":              {Code: "temporales", Name: "Servicios temporales de empleo (sobre AIU)", BaseUVT: 4, Rate: 1, NonDeclarantRate: 1},
+		"construccion":            {Code: "construccion", Name: "Contratos de construcción y urbanización", BaseUVT: 27, Rate: 2, NonDeclarantRate: 2},
+		"rendimientos":            {Code: "rendimientos", Name: "Rendimientos financieros", BaseUVT: 0, Rate: 7, NonDeclarantRate: 7},
+	}
+}
+
+// withholdingConcepts2025 bases mínimas del Decreto 0572 de 2025 (10 UVT compras, 2 UVT servicios)
+func withholdingConcepts2025() map[string]WithholdingConcept {
+	concepts := withholdingConceptsBefore2025()
+	newBases := map[string]float64{
+		"compras":                 10,
+		"compras_agricolas":       70,
+		"servicios":               2,
+		"arrendamiento_inmuebles": 10,
+		"transporte_carga":        2,
+		"transporte_pasajeros":    10,
+		"hoteles_restaurantes":    2,
+		"aseo_vigilancia":         2,
+		"temporales":              2,
+		"construccion":            10,
+	}
+	for code, base := range newBases {
+		concept := concepts[code]
+		concept.BaseUVT = base
+		concepts[code] = concept
+	}
+	return concepts
+}
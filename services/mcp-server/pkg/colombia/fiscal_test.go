@@ -0,0 +1,71 @@
+package colombia
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFiscalStoreReturnsIsolatedCopies(t *testing.T) {
+	store := NewFiscalStore()
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, bogota)
+
+	params, err := store.At(date)
+	if err != nil {
+		t.Fatalf("At() error = %v", err)
+	}
+	params.IVARates[IVAGeneral] = 99
+	params.INCRates[INCComidas] = 99
+	params.WithholdingConcepts["compras"] = WithholdingConcept{Code: "compras", Rate: 99}
+
+	for _, entry := range store.List() {
+		entry.IVARates[IVAReducido] = 99
+	}
+
+	again, err := store.At(date)
+	if err != nil {
+		t.Fatalf("At() error = %v", err)
+	}
+	if again.IVARates[IVAGeneral] != 19 || again.IVARates[IVAReducido] != 5 {
+		t.Errorf("IVA = %v, el almacén no debe cambiar al modificar una copia", again.IVARates)
+	}
+	if again.INCRates[INCComidas] != 8 {
+		t.Errorf("INC comidas = %v, want 8", again.INCRates[INCComidas])
+	}
+	if again.WithholdingConcepts["compras"].Rate == 99 {
+		t.Error("los conceptos de retención del almacén no deben cambiar al modificar una copia")
+	}
+}
+
+func TestFiscalStoreMid2025DoesNotShareMaps(t *testing.T) {
+	store := NewFiscalStore()
+	january, err := store.At(time.Date(2025, 1, 15, 0, 0, 0, 0, bogota))
+	if err != nil {
+		t.Fatalf("At() error = %v", err)
+	}
+	june, err := store.At(time.Date(2025, 6, 15, 0, 0, 0, 0, bogota))
+	if err != nil {
+		t.Fatalf("At() error = %v", err)
+	}
+	if june.Source != "Decreto 0572 de 2025" {
+		t.Fatalf("Source = %q, want los parámetros de junio de 2025", june.Source)
+	}
+	if january.WithholdingConcepts["compras"].BaseUVT == june.WithholdingConcepts["compras"].BaseUVT {
+		t.Errorf("base compras = %v en ambas vigencias, el Decreto 0572 debe cambiarla", june.WithholdingConcepts["compras"].BaseUVT)
+	}
+
+	store.mu.Lock()
+	for i := range store.entries {
+		if store.entries[i].Year == 2025 && store.entries[i].Source != june.Source {
+			store.entries[i].IVARates[IVAGeneral] = 99
+		}
+	}
+	store.mu.Unlock()
+
+	june, err = store.At(time.Date(2025, 6, 15, 0, 0, 0, 0, bogota))
+	if err != nil {
+		t.Fatalf("At() error = %v", err)
+	}
+	if june.IVARates[IVAGeneral] != 19 {
+		t.Errorf("IVA general junio 2025 = %v, no debe compartir tarifas con enero", june.IVARates[IVAGeneral])
+	}
+}
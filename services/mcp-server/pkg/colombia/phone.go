@@ -0,0 +1,186 @@
+package colombia
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ===== PLAN DE NUMERACIÓN (CRC) =====
+
+// Tipos de número telefónico
+const (
+	PhoneMobile   = "Móvil"
+	PhoneLandline = "Fijo"
+)
+
+// CountryCode indicativo internacional de Colombia
+const CountryCode = "57"
+
+// Rangos móviles por operador al que fueron asignados. Con la portabilidad
+// numérica el operador real puede ser otro; es solo una referencia.
+var mobileRanges = []struct {
+	from, to int
+	operator string
+}{
+	{300, 305, "Tigo"},
+	{310, 314, "Claro"},
+	{315, 318, "Movistar"},
+	{319, 319, "Virgin Mobile"},
+	{320, 324, "Claro"},
+	{333, 333, "Tigo"},
+	{350, 351, "Avantel"},
+}
+
+// Indicativos de telefonía fija vigentes desde la migración de 2021 (60X)
+var landlineRegions = map[byte]string{
+	'1': "Bogotá y Cundinamarca",
+	'2': "Valle del Cauca, Cauca y Nariño",
+	'4': "Antioquia, Córdoba y Chocó",
+	'5': "Costa Caribe y San Andrés",
+	'6': "Caldas, Quindío y Risaralda",
+	'7': "Santanderes y Arauca",
+	'8': "Boyacá, Tolima, Huila, Meta, Casanare, Caquetá y Orinoquía-Amazonía",
+}
+
+// PhoneNumber número telefónico validado y normalizado
+type PhoneNumber struct {
+	Type          string   `json:"type"`
+	National      string   `json:"national"` // 10 dígitos sin indicativo de país
+	E164          string   `json:"e164"`     // +573001234567
+	WhatsApp      string   `json:"whatsapp"` // 573001234567, formato de la API de WhatsApp
+	WhatsAppURL   string   `json:"whatsapp_url"`
+	Formatted     string   `json:"formatted"`     // 300 123 4567 / 601 234 5678
+	International string   `json:"international"` // +57 300 123 4567
+	Operator      string   `json:"operator,omitempty"`
+	Region        string   `json:"region,omitempty"`
+	Migrated      bool     `json:"migrated"` // Convertido desde la marcación anterior a 2021
+	LikelyInvalid bool     `json:"likely_invalid"`
+	Warnings      []string `json:"warnings,omitempty"`
+}
+
+// ParsePhone valida un número colombiano según el plan de numeración y lo
+// normaliza a E.164. Acepta móviles, fijos con el indicativo 60X, la
+// marcación fija anterior a 2021 (indicativo de un dígito, o 03 desde
+// celular) y las formas internacionales +57, 0057 y 57.
+func ParsePhone(raw string) (*PhoneNumber, error) {
+	digits, international, err := phoneDigits(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Quitar el indicativo de país
+	switch {
+	case strings.HasPrefix(digits, "00"+CountryCode) && len(digits) == 14:
+		digits = digits[4:]
+	case (international || len(digits) == 12) && strings.HasPrefix(digits, CountryCode):
+		digits = digits[2:]
+	case international:
+		return nil, fmt.Errorf("el número +%s no es colombiano (indicativo +%s)", digits, CountryCode)
+	}
+
+	phone := &PhoneNumber{}
+	switch {
+	case len(digits) == 10 && digits[0] == '3':
+		if err := phone.setMobile(digits); err != nil {
+			return nil, err
+		}
+	case len(digits) == 10 && strings.HasPrefix(digits, "60"):
+		if err := phone.setLandline(digits); err != nil {
+			return nil, err
+		}
+	case len(digits) == 10 && strings.HasPrefix(digits, "03"):
+		// Marcación anterior desde celular: 03 + indicativo + número
+		if err := phone.setLandline("60" + digits[2:]); err != nil {
+			return nil, err
+		}
+		phone.Migrated = true
+	case len(digits) == 8 && digits[0] >= '1' && digits[0] <= '8':
+		// Marcación anterior con indicativo de un dígito: (1) 234 5678
+		if err := phone.setLandline("60" + digits); err != nil {
+			return nil, err
+		}
+		phone.Migrated = true
+	case len(digits) == 7:
+		return nil, fmt.Errorf("desde 2021 los números fijos se marcan con el indicativo de la región: 60X + %s (por ejemplo 601 %s en Bogotá)", digits, digits)
+	case strings.HasPrefix(digits, "018000") || strings.HasPrefix(digits, "01900"):
+		return nil, fmt.Errorf("las líneas 01 8000 y 01 900 no tienen formato internacional")
+	default:
+		return nil, fmt.Errorf("número de teléfono inválido para Colombia: se esperan 10 dígitos (3XX móvil o 60X fijo)")
+	}
+
+	phone.E164 = "+" + CountryCode + phone.National
+	phone.WhatsApp = CountryCode + phone.National
+	phone.WhatsAppURL = "https://wa.me/" + phone.WhatsApp
+	phone.Formatted = phone.National[:3] + " " + phone.National[3:6] + " " + phone.National[6:]
+	phone.International = "+" + CountryCode + " " + phone.Formatted
+	if phone.Migrated {
+		phone.Warnings = append(phone.Warnings, "número convertido a la marcación vigente desde 2021")
+	}
+	phone.checkSubscriber()
+	return phone, nil
+}
+
+// IsWhatsAppCapable indica si el número puede recibir mensajes de WhatsApp
+// (los móviles; los fijos solo si tienen WhatsApp Business)
+func (p *PhoneNumber) IsWhatsAppCapable() bool {
+	return p.Type == PhoneMobile && !p.LikelyInvalid
+}
+
+func (p *PhoneNumber) setMobile(digits string) error {
+	prefix := int(digits[0]-'0')*100 + int(digits[1]-'0')*10 + int(digits[2]-'0')
+	for _, r := range mobileRanges {
+		if prefix >= r.from && prefix <= r.to {
+			p.Type = PhoneMobile
+			p.National = digits
+			p.Operator = r.operator
+			return nil
+		}
+	}
+	return fmt.Errorf("el prefijo móvil %d no está asignado a ningún operador", prefix)
+}
+
+func (p *PhoneNumber) setLandline(digits string) error {
+	region, exists := landlineRegions[digits[2]]
+	if !exists {
+		return fmt.Errorf("el indicativo fijo %s no existe (vigentes: 601, 602, 604, 605, 606, 607 y 608)", digits[:3])
+	}
+	if digits[3] == '0' || digits[3] == '1' {
+		return fmt.Errorf("los números fijos no empiezan por %c después del indicativo", digits[3])
+	}
+	p.Type = PhoneLandline
+	p.National = digits
+	p.Region = region
+	return nil
+}
+
+// checkSubscriber marca números de relleno que pasan la validación de formato
+// pero casi nunca son reales (3000000000, 3001234567...)
+func (p *PhoneNumber) checkSubscriber() {
+	subscriber := p.National[3:]
+	repeated := strings.Count(subscriber, subscriber[:1]) == len(subscriber)
+	if repeated || strings.Contains("0123456789", subscriber) || strings.Contains("9876543210", subscriber) {
+		p.LikelyInvalid = true
+		p.Warnings = append(p.Warnings, "el número parece de relleno (dígitos repetidos o consecutivos)")
+	}
+}
+
+// phoneDigits extrae los dígitos e indica si el número venía en formato internacional
+func phoneDigits(raw string) (string, bool, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", false, fmt.Errorf("número de teléfono requerido")
+	}
+	international := strings.HasPrefix(s, "+")
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' || r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", false, fmt.Errorf("el teléfono contiene caracteres inválidos: %q", r)
+		}
+	}
+	return b.String(), international, nil
+}
@@ -0,0 +1,286 @@
+package colombia
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ===== REGISTRO DE EMPRESAS (RUT / RUES) =====
+
+// ErrCompanyNotFound el NIT no aparece en el registro consultado
+var ErrCompanyNotFound = errors.New("empresa no encontrada en el registro")
+
+// Responsabilidades del RUT (casilla 53) que afectan la facturación
+const (
+	RespRenta              = "05" // Impuesto de renta y complementarios, régimen ordinario
+	RespRetencionRenta     = "07" // Retención en la fuente a título de renta
+	RespGranContribuyente  = "13"
+	RespInformanteExogena  = "14"
+	RespAutorretenedor     = "15"
+	RespContabilidad       = "42" // Obligado a llevar contabilidad
+	RespRegimenSimple      = "47"
+	RespIVA                = "48" // Impuesto sobre las ventas, IVA
+	RespNoResponsableIVA   = "49"
+	RespFacturadorElectron = "52"
+)
+
+// TaxResponsibilityNames nombre de cada responsabilidad del RUT
+var TaxResponsibilityNames = map[string]string{
+	RespRenta:              "Impuesto de renta y complementarios régimen ordinario",
+	RespRetencionRenta:     "Retención en la fuente a título de renta",
+	RespGranContribuyente:  "Gran contribuyente",
+	RespInformanteExogena:  "Informante de exógena",
+	RespAutorretenedor:     "Autorretenedor",
+	RespContabilidad:       "Obligado a llevar contabilidad",
+	RespRegimenSimple:      "Régimen simple de tributación",
+	RespIVA:                "Impuesto sobre las ventas - IVA",
+	RespNoResponsableIVA:   "No responsable de IVA",
+	RespFacturadorElectron: "Facturador electrónico",
+}
+
+// CompanyActivity actividad económica CIIU registrada
+type CompanyActivity struct {
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+	Primary     bool   `json:"primary"`
+}
+
+// CompanyRecord datos de una empresa según el RUT y el registro mercantil
+type CompanyRecord struct {
+	NIT                string            `json:"nit"` // Sin dígito de verificación
+	CheckDigit         string            `json:"check_digit"`
+	LegalName          string            `json:"legal_name"` // Razón social
+	TradeName          string            `json:"trade_name,omitempty"`
+	PersonType         string            `json:"person_type"` // natural, juridica
+	LegalForm          string            `json:"legal_form,omitempty"`
+	Status             string            `json:"status"`                        // Activa, Cancelada, Suspendida...
+	RegistrationNumber string            `json:"registration_number,omitempty"` // Matrícula mercantil
+	ChamberOfCommerce  string            `json:"chamber_of_commerce,omitempty"`
+	RegisteredAt       string            `json:"registered_at,omitempty"`
+	LastRenewalYear    int               `json:"last_renewal_year,omitempty"`
+	Activities         []CompanyActivity `json:"activities"`
+	Responsibilities   []string          `json:"responsibilities"` // Códigos de la casilla 53
+	Address            string            `json:"address,omitempty"`
+	CityCode           string            `json:"city_code,omitempty"` // DIVIPOLA
+	City               string            `json:"city,omitempty"`
+	Department         string            `json:"department,omitempty"`
+	Email              string            `json:"email,omitempty"`
+	Phone              string            `json:"phone,omitempty"`
+	Source             string            `json:"source"`
+	RetrievedAt        time.Time         `json:"retrieved_at"`
+}
+
+// Active indica si la matrícula o el RUT están activos
+func (r *CompanyRecord) Active() bool {
+	return strings.EqualFold(r.Status, "activa") || strings.EqualFold(r.Status, "activo")
+}
+
+// HasResponsibility indica si la empresa tiene una responsabilidad del RUT
+func (r *CompanyRecord) HasResponsibility(code string) bool {
+	for _, resp := range r.Responsibilities {
+		if resp == code {
+			return true
+		}
+	}
+	return false
+}
+
+// PrimaryActivity retorna la actividad CIIU principal
+func (r *CompanyRecord) PrimaryActivity() (CompanyActivity, bool) {
+	for _, activity := range r.Activities {
+		if activity.Primary {
+			return activity, true
+		}
+	}
+	if len(r.Activities) > 0 {
+		return r.Activities[0], true
+	}
+	return CompanyActivity{}, false
+}
+
+// RegistryError el registro de empresas no respondió o respondió algo inválido
+type RegistryError struct {
+	Err error
+}
+
+func (e *RegistryError) Error() string {
+	return "registro de empresas no disponible: " + e.Err.Error()
+}
+
+func (e *RegistryError) Unwrap() error {
+	return e.Err
+}
+
+// CompanyRegistry consulta los datos registrales de una empresa por NIT.
+// Lo implementan el adaptador HTTP, el proveedor de fixtures y el cache.
+type CompanyRegistry interface {
+	// LookupCompany recibe el NIT sin dígito de verificación
+	LookupCompany(ctx context.Context, nit string) (*CompanyRecord, error)
+}
+
+// ===== ADAPTADOR HTTP =====
+
+// HTTPRegistry consulta un servicio de RUT/RUES expuesto en
+// GET {baseURL}/companies/{nit}, que responde un CompanyRecord en JSON
+type HTTPRegistry struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewHTTPRegistry crea el adaptador contra la URL base indicada
+func NewHTTPRegistry(baseURL, apiKey string) *HTTPRegistry {
+	return &HTTPRegistry{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// LookupCompany consulta la empresa en el servicio remoto
+func (r *HTTPRegistry) LookupCompany(ctx context.Context, nit string) (*CompanyRecord, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+"/companies/"+url.PathEscape(nit), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, &RegistryError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrCompanyNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &RegistryError{Err: fmt.Errorf("respuesta %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))}
+	}
+
+	var record CompanyRecord
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return nil, &RegistryError{Err: fmt.Errorf("respuesta inválida: %w", err)}
+	}
+	if record.Source == "" {
+		record.Source = "rues"
+	}
+	record.RetrievedAt = time.Now()
+	return &record, nil
+}
+
+// ===== PROVEEDOR DE FIXTURES =====
+
+// Empresas de ejemplo para sandbox y pruebas, incluida la del tenant de demostración
+//
+//go:embed data/companies.json
+var companyFixturesJSON []byte
+
+// FixtureRegistry registro en memoria cargado desde un archivo JSON
+// (lista de CompanyRecord). Se usa en pruebas y para tenants en sandbox.
+type FixtureRegistry struct {
+	records map[string]CompanyRecord
+}
+
+// NewFixtureRegistry carga las empresas de un archivo; sin ruta usa las de ejemplo
+func NewFixtureRegistry(path string) (*FixtureRegistry, error) {
+	data := companyFixturesJSON
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo leer el archivo de empresas: %w", err)
+		}
+		data = content
+	}
+
+	var records []CompanyRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("archivo de empresas inválido: %w", err)
+	}
+
+	registry := &FixtureRegistry{records: make(map[string]CompanyRecord, len(records))}
+	for _, record := range records {
+		if record.Source == "" {
+			record.Source = "fixture"
+		}
+		registry.records[record.NIT] = record
+	}
+	return registry, nil
+}
+
+// SandboxRegistry registro con las empresas de ejemplo embebidas
+func SandboxRegistry() *FixtureRegistry {
+	registry, err := NewFixtureRegistry("")
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+// LookupCompany busca la empresa en las fixtures
+func (r *FixtureRegistry) LookupCompany(ctx context.Context, nit string) (*CompanyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	record, exists := r.records[nit]
+	if !exists {
+		return nil, ErrCompanyNotFound
+	}
+	record.RetrievedAt = time.Now()
+	return &record, nil
+}
+
+// ===== CACHE =====
+
+// ResultCache cache de resultados con TTL (lo implementa cache.RedisCache)
+type ResultCache interface {
+	CacheResult(key string, value interface{}, ttl time.Duration) error
+	GetCachedResult(key string, dest interface{}) (bool, error)
+}
+
+// CachedRegistry envuelve un registro y guarda las consultas exitosas. Si el
+// cache falla se consulta directamente el registro.
+type CachedRegistry struct {
+	registry CompanyRegistry
+	cache    ResultCache
+	ttl      time.Duration
+}
+
+// NewCachedRegistry crea un registro con cache
+func NewCachedRegistry(registry CompanyRegistry, cache ResultCache, ttl time.Duration) *CachedRegistry {
+	return &CachedRegistry{
+		registry: registry,
+		cache:    cache,
+		ttl:      ttl,
+	}
+}
+
+// LookupCompany consulta primero el cache y luego el registro
+func (r *CachedRegistry) LookupCompany(ctx context.Context, nit string) (*CompanyRecord, error) {
+	key := "company:" + nit
+
+	var cached CompanyRecord
+	if found, err := r.cache.GetCachedResult(key, &cached); err == nil && found {
+		return &cached, nil
+	}
+
+	record, err := r.registry.LookupCompany(ctx, nit)
+	if err != nil {
+		return nil, err
+	}
+	_ = r.cache.CacheResult(key, record, r.ttl)
+	return record, nil
+}
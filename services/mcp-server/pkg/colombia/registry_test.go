@@ -0,0 +1,198 @@
+package colombia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFixtureRegistryLookup(t *testing.T) {
+	registry := SandboxRegistry()
+
+	tests := []struct {
+		nit            string
+		legalName      string
+		active         bool
+		primary        string
+		responsibility string
+	}{
+		{"900123456", "PYME DEMO S.A.S.", true, "4771", RespFacturadorElectron},
+		{"901234567", "DISTRIBUIDORA LÓPEZ Y CIA S.A.S.", true, "4631", RespGranContribuyente},
+		{"800987654", "PANADERÍA SAN JUAN LTDA", false, "1081", RespIVA},
+		{"1020304050", "MARÍA FERNANDA GÓMEZ RUIZ", true, "4771", RespRegimenSimple},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nit, func(t *testing.T) {
+			record, err := registry.LookupCompany(context.Background(), tt.nit)
+			if err != nil {
+				t.Fatalf("LookupCompany() error = %v", err)
+			}
+			if record.NIT != tt.nit || record.LegalName != tt.legalName {
+				t.Errorf("empresa = %s %s, want %s %s", record.NIT, record.LegalName, tt.nit, tt.legalName)
+			}
+			if record.Active() != tt.active {
+				t.Errorf("Active() = %v, want %v (estado %q)", record.Active(), tt.active, record.Status)
+			}
+			if activity, ok := record.PrimaryActivity(); !ok || activity.Code != tt.primary {
+				t.Errorf("PrimaryActivity() = %+v, want %s", activity, tt.primary)
+			}
+			if !record.HasResponsibility(tt.responsibility) {
+				t.Errorf("HasResponsibility(%s) = false, responsabilidades %v", tt.responsibility, record.Responsibilities)
+			}
+			if record.Source != "fixture" || record.RetrievedAt.IsZero() {
+				t.Errorf("Source = %q, RetrievedAt = %v", record.Source, record.RetrievedAt)
+			}
+
+			// Las fixtures deben traer el dígito de verificación correcto
+			digit, err := NITCheckDigit(tt.nit)
+			if err != nil || digit != record.CheckDigit {
+				t.Errorf("dígito de verificación = %s, calculado %s (%v)", record.CheckDigit, digit, err)
+			}
+		})
+	}
+}
+
+func TestFixtureRegistryErrors(t *testing.T) {
+	registry := SandboxRegistry()
+
+	if _, err := registry.LookupCompany(context.Background(), "999999999"); !errors.Is(err, ErrCompanyNotFound) {
+		t.Errorf("LookupCompany() de NIT inexistente error = %v, want ErrCompanyNotFound", err)
+	}
+	// Se busca por el NIT sin dígito de verificación
+	if _, err := registry.LookupCompany(context.Background(), "9001234568"); !errors.Is(err, ErrCompanyNotFound) {
+		t.Errorf("LookupCompany() con dígito de verificación error = %v, want ErrCompanyNotFound", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := registry.LookupCompany(ctx, "900123456"); !errors.Is(err, context.Canceled) {
+		t.Errorf("LookupCompany() con el contexto cancelado error = %v", err)
+	}
+
+	// Modificar el registro retornado no altera las fixtures
+	record, _ := registry.LookupCompany(context.Background(), "900123456")
+	record.LegalName = "OTRA"
+	if again, _ := registry.LookupCompany(context.Background(), "900123456"); again.LegalName != "PYME DEMO S.A.S." {
+		t.Errorf("LegalName = %q después de modificar una copia", again.LegalName)
+	}
+}
+
+func TestNewFixtureRegistryFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "empresas.json")
+	records := []CompanyRecord{
+		{NIT: "890903938", CheckDigit: "8", LegalName: "EMPRESA DE ARCHIVO S.A.", Status: "Activa", Source: "rues"},
+		{NIT: "860034313", CheckDigit: "7", LegalName: "SIN FUENTE S.A.", Status: "Activa"},
+	}
+	data, _ := json.Marshal(records)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := NewFixtureRegistry(path)
+	if err != nil {
+		t.Fatalf("NewFixtureRegistry() error = %v", err)
+	}
+	record, err := registry.LookupCompany(context.Background(), "890903938")
+	if err != nil || record.Source != "rues" {
+		t.Errorf("LookupCompany() = %+v, %v", record, err)
+	}
+	record, err = registry.LookupCompany(context.Background(), "860034313")
+	if err != nil || record.Source != "fixture" {
+		t.Errorf("LookupCompany() sin fuente = %+v, %v", record, err)
+	}
+	// El archivo reemplaza las empresas de ejemplo
+	if _, err := registry.LookupCompany(context.Background(), "900123456"); !errors.Is(err, ErrCompanyNotFound) {
+		t.Errorf("LookupCompany() de empresa de ejemplo error = %v", err)
+	}
+
+	if _, err := NewFixtureRegistry(filepath.Join(dir, "no-existe.json")); err == nil {
+		t.Error("NewFixtureRegistry() de un archivo inexistente no retornó error")
+	}
+	invalid := filepath.Join(dir, "invalido.json")
+	if err := os.WriteFile(invalid, []byte(`{"nit": "1"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFixtureRegistry(invalid); err == nil {
+		t.Error("NewFixtureRegistry() de un archivo inválido no retornó error")
+	}
+}
+
+func TestServiceLookupCompanyValidatesNIT(t *testing.T) {
+	service := NewService()
+
+	record, err := service.LookupCompany(context.Background(), "900.123.456-8")
+	if err != nil {
+		t.Fatalf("LookupCompany() error = %v", err)
+	}
+	if record.LegalName != "PYME DEMO S.A.S." {
+		t.Errorf("LegalName = %q", record.LegalName)
+	}
+
+	if _, err := service.LookupCompany(context.Background(), "900123456-7"); err == nil || errors.Is(err, ErrCompanyNotFound) {
+		t.Errorf("LookupCompany() con dígito de verificación errado error = %v, want error de validación", err)
+	}
+}
+
+// memoryCache cache en memoria que cuenta las escrituras
+type memoryCache struct {
+	values map[string][]byte
+	writes int
+}
+
+func (c *memoryCache) CacheResult(key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.values[key] = data
+	c.writes++
+	return nil
+}
+
+func (c *memoryCache) GetCachedResult(key string, dest interface{}) (bool, error) {
+	data, exists := c.values[key]
+	if !exists {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, dest)
+}
+
+// countingRegistry registro que cuenta las consultas
+type countingRegistry struct {
+	CompanyRegistry
+	lookups int
+}
+
+func (r *countingRegistry) LookupCompany(ctx context.Context, nit string) (*CompanyRecord, error) {
+	r.lookups++
+	return r.CompanyRegistry.LookupCompany(ctx, nit)
+}
+
+func TestCachedRegistry(t *testing.T) {
+	inner := &countingRegistry{CompanyRegistry: SandboxRegistry()}
+	cache := &memoryCache{values: map[string][]byte{}}
+	registry := NewCachedRegistry(inner, cache, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		record, err := registry.LookupCompany(context.Background(), "901234567")
+		if err != nil || record.LegalName != "DISTRIBUIDORA LÓPEZ Y CIA S.A.S." {
+			t.Fatalf("LookupCompany() = %+v, %v", record, err)
+		}
+	}
+	if inner.lookups != 1 || cache.writes != 1 {
+		t.Errorf("consultas al registro = %d, escrituras al cache = %d, want 1 y 1", inner.lookups, cache.writes)
+	}
+
+	// Los NIT inexistentes no se guardan en el cache
+	if _, err := registry.LookupCompany(context.Background(), "999999999"); !errors.Is(err, ErrCompanyNotFound) {
+		t.Errorf("LookupCompany() error = %v, want ErrCompanyNotFound", err)
+	}
+	if cache.writes != 1 {
+		t.Errorf("escrituras al cache = %d después de un NIT inexistente", cache.writes)
+	}
+}
@@ -0,0 +1,401 @@
+package colombia
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ===== PERFILES TRIBUTARIOS =====
+
+// Tipos de persona
+const (
+	PersonNatural  = "natural"
+	PersonJuridica = "juridica"
+)
+
+// TaxProfile perfil tributario de una de las partes de la operación
+type TaxProfile struct {
+	PersonType        string `json:"person_type"`        // natural, juridica
+	ResponsableIVA    bool   `json:"responsable_iva"`    // Responsable del impuesto sobre las ventas
+	GranContribuyente bool   `json:"gran_contribuyente"` // Calificado por la DIAN
+	Autorretenedor    bool   `json:"autorretenedor"`     // Autorretenedor de renta
+	RegimenSimple     bool   `json:"regimen_simple"`     // Régimen Simple de Tributación
+	Declarante        bool   `json:"declarante"`         // Declarante de renta (personas naturales)
+	CityCode          string `json:"city_code"`          // Código DIVIPOLA del municipio
+	CIIU              string `json:"ciiu"`               // Actividad económica principal
+	Activity          string `json:"activity,omitempty"` // Descripción libre si no se conoce el código CIIU
+}
+
+// isWithholdingAgent indica si la parte es agente de retención en la fuente
+func (p TaxProfile) isWithholdingAgent() bool {
+	return p.PersonType == PersonJuridica || p.GranContribuyente
+}
+
+// ===== IVA E IMPUESTO AL CONSUMO =====
+
+// Categorías de IVA (Estatuto Tributario arts. 424, 468, 468-1, 477)
+const (
+	IVAGeneral   = "general"   // Tarifa general 19%
+	IVAReducido  = "reducido"  // Tarifa diferencial 5%
+	IVAExento    = "exento"    // Gravado a tarifa 0% con derecho a devolución
+	IVAExcluido  = "excluido"  // No causa IVA
+	INCNone      = ""          // No causa impuesto al consumo
+	INCComidas   = "comidas"   // Restaurantes, cafeterías y bares 8%
+	INCTelefonia = "telefonia" // Telefonía móvil, internet y datos 4%
+	INCVehiculos = "vehiculos" // Vehículos y motos de alta gama 8%
+	INCLujo      = "lujo"      // Aerodinos, yates, vehículos de más de USD 30.000 16%
+)
+
+// ===== RETENCIÓN EN LA FUENTE =====
+
+// WithholdingConcept concepto de retención en la fuente (Decreto 1625 de 2016)
+type WithholdingConcept struct {
+	Code              string  `json:"code"`
+	Name              string  `json:"name"`
+	BaseUVT           float64 `json:"base_uvt"`            // Base mínima en UVT
+	Rate              float64 `json:"rate"`                // Tarifa para declarantes / personas jurídicas
+	NonDeclarantRate  float64 `json:"non_declarant_rate"`  // Tarifa para personas naturales no declarantes
+	NaturalPersonRate float64 `json:"natural_person_rate"` // Tarifa para personas naturales (si difiere)
+}
+
+// ===== RETEICA =====
+
+// Grupos de actividad para ICA según la división CIIU
+const (
+	ICAIndustrial = "industrial"
+	ICAComercial  = "comercial"
+	ICAServicios  = "servicios"
+	ICAFinanciera = "financiera"
+)
+
+// ICATariffs tarifas de ICA por municipio y grupo de actividad (por mil),
+// con excepciones por código CIIU. Son tarifas de referencia de los acuerdos
+// municipales; la tarifa exacta depende de la actividad registrada en el RIT.
+type ICATariffs struct {
+	City   string             `json:"city"`
+	Groups map[string]float64 `json:"groups"`
+	CIIU   map[string]float64 `json:"ciiu,omitempty"`
+}
+
+// ICATariffsByCity tarifas de ICA de las principales ciudades (código DIVIPOLA)
+var ICATariffsByCity = map[string]ICATariffs{
+	"11001": {
+		City:   "Bogotá D.C.",
+		Groups: map[string]float64{ICAIndustrial: 11.04, ICAComercial: 11.04, ICAServicios: 9.66, ICAFinanciera: 14},
+		CIIU: map[string]float64{
+			"1011": 4.14, "1081": 4.14, "4711": 4.14, "4721": 4.14, // Alimentos
+			"1811": 6.9, "5811": 6.9, "5813": 6.9, // Edición e impresión
+			"5611": 13.8, "5613": 13.8, "5630": 13.8, // Restaurantes y bares
+			"6201": 9.66, "6202": 9.66, "7020": 9.66, // Software y consultoría
+		},
+	},
+	"05001": {
+		City:   "Medellín",
+		Groups: map[string]float64{ICAIndustrial: 7, ICAComercial: 10, ICAServicios: 10, ICAFinanciera: 5},
+		CIIU:   map[string]float64{"4711": 6, "5611": 10, "6201": 7},
+	},
+	"76001": {
+		City:   "Cali",
+		Groups: map[string]float64{ICAIndustrial: 6.6, ICAComercial: 8.8, ICAServicios: 10, ICAFinanciera: 5},
+	},
+	"08001": {
+		City:   "Barranquilla",
+		Groups: map[string]float64{ICAIndustrial: 7, ICAComercial: 8, ICAServicios: 8, ICAFinanciera: 5},
+	},
+	"13001": {
+		City:   "Cartagena",
+		Groups: map[string]float64{ICAIndustrial: 7, ICAComercial: 8, ICAServicios: 8, ICAFinanciera: 5},
+	},
+	"68001": {
+		City:   "Bucaramanga",
+		Groups: map[string]float64{ICAIndustrial: 7, ICAComercial: 8, ICAServicios: 10, ICAFinanciera: 5},
+	},
+}
+
+// ICAActivityGroup clasifica un código CIIU en el grupo de actividad de ICA
+// según su sección: industria manufacturera (C), comercio (G), financiera (K)
+// y servicios para las demás
+func ICAActivityGroup(ciiu string) string {
+	activity, ok := LookupCIIU(ciiu)
+	if !ok {
+		return ICAServicios
+	}
+	switch activity.Section {
+	case "C":
+		return ICAIndustrial
+	case "G":
+		return ICAComercial
+	case "K":
+		return ICAFinanciera
+	default:
+		return ICAServicios
+	}
+}
+
+// ICATariff tarifa de ICA (por mil) para un municipio y actividad
+func ICATariff(cityCode, ciiu string) (float64, bool) {
+	tariffs, ok := ICATariffsByCity[cityCode]
+	if !ok {
+		return 0, false
+	}
+	if activity, ok := LookupCIIU(ciiu); ok {
+		ciiu = activity.Code
+	}
+	if rate, ok := tariffs.CIIU[ciiu]; ok {
+		return rate, true
+	}
+	rate, ok := tariffs.Groups[ICAActivityGroup(ciiu)]
+	return rate, ok
+}
+
+// ===== CÁLCULO =====
+
+// TaxLine ítem de la operación a liquidar
+type TaxLine struct {
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	UnitPrice   int    `json:"unit_price_cop"`
+	IVACategory string `json:"iva_category,omitempty"` // general, reducido, exento, excluido
+	INCCategory string `json:"inc_category,omitempty"` // comidas, telefonia, vehiculos, lujo
+}
+
+// TaxRequest operación a liquidar entre vendedor y comprador
+type TaxRequest struct {
+	Seller   TaxProfile `json:"seller"`
+	Buyer    TaxProfile `json:"buyer"`
+	Lines    []TaxLine  `json:"lines"`
+	Concept  string     `json:"concept"`             // Concepto de retención (compras, servicios, honorarios...)
+	CityCode string     `json:"city_code,omitempty"` // Municipio donde se realiza la operación
+	Date     time.Time  `json:"date"`
+}
+
+// TaxLineResult liquidación de un ítem
+type TaxLineResult struct {
+	Description string  `json:"description"`
+	Base        int     `json:"base_cop"`
+	IVARate     float64 `json:"iva_rate"`
+	IVA         int     `json:"iva_cop"`
+	INCRate     float64 `json:"inc_rate"`
+	INC         int     `json:"inc_cop"`
+	Total       int     `json:"total_cop"`
+}
+
+// Withholding retención practicada por el comprador
+type Withholding struct {
+	Type   string  `json:"type"` // retefuente, reteiva, reteica
+	Name   string  `json:"name"`
+	Base   int     `json:"base_cop"`
+	Rate   float64 `json:"rate"`
+	Amount int     `json:"amount_cop"`
+}
+
+// TaxResult liquidación completa de la operación
+type TaxResult struct {
+	Lines          []TaxLineResult `json:"lines"`
+	Subtotal       int             `json:"subtotal_cop"`
+	IVA            int             `json:"iva_cop"`
+	INC            int             `json:"inc_cop"`
+	Total          int             `json:"total_cop"`
+	Withholdings   []Withholding   `json:"withholdings"`
+	ReteFuente     int             `json:"retefuente_cop"`
+	ReteIVA        int             `json:"reteiva_cop"`
+	ReteICA        int             `json:"reteica_cop"`
+	NetPayable     int             `json:"net_payable_cop"` // Total menos retenciones
+	UVT            int             `json:"uvt_cop"`
+	SellerActivity *CIIUActivity   `json:"seller_activity,omitempty"` // Actividad usada para la tarifa de ICA
+	Notes          []string        `json:"notes,omitempty"`
+}
+
+// CalculateTaxes liquida IVA, impuesto al consumo y las retenciones que debe
+// practicar el comprador según los regímenes tributarios de las partes
+func (s *Service) CalculateTaxes(req TaxRequest) (*TaxResult, error) {
+	if len(req.Lines) == 0 {
+		return nil, fmt.Errorf("la operación debe tener al menos un ítem")
+	}
+	for i, line := range req.Lines {
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("ítem %d: la cantidad debe ser mayor a cero", i+1)
+		}
+		if line.UnitPrice < 0 {
+			return nil, fmt.Errorf("ítem %d: el precio unitario no puede ser negativo", i+1)
+		}
+	}
+	if req.Date.IsZero() {
+		req.Date = time.Now()
+	}
+	if req.Concept == "" {
+		req.Concept = "compras"
+	}
+	if req.CityCode == "" {
+		req.CityCode = req.Buyer.CityCode
+	}
+
+	params, err := s.fiscal.At(req.Date)
+	if err != nil {
+		return nil, err
+	}
+	uvt := params.UVT
+
+	result := &TaxResult{UVT: uvt, Withholdings: []Withholding{}}
+
+	activity, note, err := sellerActivity(req.Seller)
+	if err != nil {
+		return nil, err
+	}
+	if activity.Code != "" {
+		req.Seller.CIIU = activity.Code
+		result.SellerActivity = &activity
+	}
+	if note != "" {
+		result.Notes = append(result.Notes, note)
+	}
+
+	for _, line := range req.Lines {
+		ivaRate, err := params.IVARate(line.IVACategory)
+		if err != nil {
+			return nil, err
+		}
+		if !req.Seller.ResponsableIVA {
+			// Los no responsables de IVA no lo cobran
+			ivaRate = 0
+		}
+		incRate, err := params.INCRate(line.INCCategory)
+		if err != nil {
+			return nil, err
+		}
+		if incRate > 0 && ivaRate > 0 && line.INCCategory == INCComidas {
+			// El servicio de restaurante está excluido de IVA y grava con INC
+			ivaRate = 0
+		}
+
+		base := line.Quantity * line.UnitPrice
+		lineResult := TaxLineResult{
+			Description: line.Description,
+			Base:        base,
+			IVARate:     ivaRate,
+			IVA:         roundCOP(float64(base) * ivaRate / 100),
+			INCRate:     incRate,
+			INC:         roundCOP(float64(base) * incRate / 100),
+		}
+		lineResult.Total = lineResult.Base + lineResult.IVA + lineResult.INC
+
+		result.Lines = append(result.Lines, lineResult)
+		result.Subtotal += lineResult.Base
+		result.IVA += lineResult.IVA
+		result.INC += lineResult.INC
+	}
+	result.Total = result.Subtotal + result.IVA + result.INC
+
+	concept, err := params.Concept(req.Concept)
+	if err != nil {
+		return nil, err
+	}
+	baseMinimum := int(math.Ceil(concept.BaseUVT * float64(uvt)))
+	reachesBase := result.Subtotal >= baseMinimum
+
+	// Retención en la fuente
+	switch {
+	case !req.Buyer.isWithholdingAgent():
+		result.Notes = append(result.Notes, "El comprador no es agente de retención en la fuente")
+	case req.Seller.Autorretenedor:
+		result.Notes = append(result.Notes, "El vendedor es autorretenedor: no se practica retención en la fuente")
+	case req.Seller.RegimenSimple:
+		result.Notes = append(result.Notes, "El vendedor pertenece al Régimen Simple: no se practica retención en la fuente")
+	case !reachesBase:
+		result.Notes = append(result.Notes, fmt.Sprintf("La base no supera el mínimo de %.0f UVT ($%s) para %s", concept.BaseUVT, s.FormatCurrency(baseMinimum), concept.Name))
+	default:
+		rate := concept.Rate
+		if req.Seller.PersonType == PersonNatural {
+			if concept.NaturalPersonRate > 0 {
+				rate = concept.NaturalPersonRate
+			}
+			if !req.Seller.Declarante {
+				rate = concept.NonDeclarantRate
+			}
+		}
+		result.addWithholding("retefuente", "Retención en la fuente - "+concept.Name, result.Subtotal, rate)
+	}
+
+	// ReteIVA: la practican los grandes contribuyentes a responsables de IVA que no lo son
+	if result.IVA > 0 && req.Buyer.GranContribuyente && req.Seller.ResponsableIVA && !req.Seller.GranContribuyente && reachesBase {
+		result.addWithholding("reteiva", "Retención de IVA", result.IVA, params.ReteIVARate)
+	}
+
+	// ReteICA: la practica el comprador persona jurídica sobre operaciones en su municipio
+	if req.Buyer.PersonType == PersonJuridica && req.CityCode != "" && req.CityCode == req.Buyer.CityCode && reachesBase {
+		if tariff, ok := ICATariff(req.CityCode, req.Seller.CIIU); ok {
+			amount := roundCOP(float64(result.Subtotal) * tariff / 1000)
+			name := fmt.Sprintf("Retención de ICA %s (%s por mil)", ICATariffsByCity[req.CityCode].City, formatRate(tariff))
+			if result.SellerActivity != nil {
+				name = fmt.Sprintf("Retención de ICA %s, actividad %s %s (%s por mil)", ICATariffsByCity[req.CityCode].City, activity.Code, activity.Description, formatRate(tariff))
+			}
+			result.Withholdings = append(result.Withholdings, Withholding{
+				Type:   "reteica",
+				Name:   name,
+				Base:   result.Subtotal,
+				Rate:   tariff,
+				Amount: amount,
+			})
+			result.ReteICA = amount
+		} else {
+			result.Notes = append(result.Notes, "Tarifa de ICA no disponible para el municipio "+req.CityCode)
+		}
+	}
+
+	sort.SliceStable(result.Withholdings, func(i, j int) bool {
+		return result.Withholdings[i].Type < result.Withholdings[j].Type
+	})
+	result.NetPayable = result.Total - result.ReteFuente - result.ReteIVA - result.ReteICA
+
+	return result, nil
+}
+
+// sellerActivity valida el código CIIU del vendedor o, si no lo tiene, lo
+// clasifica a partir de la descripción de su actividad
+func sellerActivity(seller TaxProfile) (CIIUActivity, string, error) {
+	if seller.CIIU != "" {
+		activity, ok := LookupCIIU(seller.CIIU)
+		if !ok {
+			return CIIUActivity{}, "", fmt.Errorf("el código CIIU %s no existe en la CIIU Rev. 4 A.C.", seller.CIIU)
+		}
+		return activity, "", nil
+	}
+	if seller.Activity == "" {
+		return CIIUActivity{}, "", nil
+	}
+
+	match, ok := BestCIIU(seller.Activity)
+	if !ok {
+		return CIIUActivity{}, "No se pudo clasificar la actividad del vendedor en la CIIU: se aplica la tarifa de ICA de servicios", nil
+	}
+	return match.CIIUActivity, fmt.Sprintf("Actividad CIIU %s (%s) inferida de la descripción del vendedor con confianza %.0f%%; confírmela con el RUT", match.Code, match.Description, match.Confidence*100), nil
+}
+
+func (r *TaxResult) addWithholding(kind, name string, base int, rate float64) {
+	amount := roundCOP(float64(base) * rate / 100)
+	r.Withholdings = append(r.Withholdings, Withholding{
+		Type:   kind,
+		Name:   name,
+		Base:   base,
+		Rate:   rate,
+		Amount: amount,
+	})
+	switch kind {
+	case "retefuente":
+		r.ReteFuente += amount
+	case "reteiva":
+		r.ReteIVA += amount
+	}
+}
+
+// roundCOP redondea al peso más cercano
+func roundCOP(value float64) int {
+	return int(math.Round(value))
+}
+
+func formatRate(rate float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", rate), "0"), ".")
+}
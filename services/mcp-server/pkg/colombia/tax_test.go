@@ -0,0 +1,50 @@
+package colombia
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalculateTaxesValidatesLines(t *testing.T) {
+	service := NewService()
+	seller := TaxProfile{PersonType: PersonJuridica, ResponsableIVA: true}
+	buyer := TaxProfile{PersonType: PersonNatural}
+
+	tests := []struct {
+		name    string
+		line    TaxLine
+		wantErr string
+	}{
+		{"válido", TaxLine{Description: "Camisa", Quantity: 2, UnitPrice: 50000}, ""},
+		{"precio cero", TaxLine{Description: "Muestra gratis", Quantity: 1, UnitPrice: 0}, ""},
+		{"cantidad cero", TaxLine{Description: "Camisa", Quantity: 0, UnitPrice: 50000}, "la cantidad debe ser mayor a cero"},
+		{"cantidad negativa", TaxLine{Description: "Camisa", Quantity: -3, UnitPrice: 50000}, "la cantidad debe ser mayor a cero"},
+		{"precio negativo", TaxLine{Description: "Camisa", Quantity: 1, UnitPrice: -50000}, "el precio unitario no puede ser negativo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.CalculateTaxes(TaxRequest{
+				Seller: seller,
+				Buyer:  buyer,
+				Lines:  []TaxLine{tt.line},
+				Date:   time.Date(2025, 3, 1, 0, 0, 0, 0, bogota),
+			})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CalculateTaxes() error = %v", err)
+				}
+				if want := tt.line.Quantity * tt.line.UnitPrice; result.Subtotal != want {
+					t.Errorf("Subtotal = %d, want %d", result.Subtotal, want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("CalculateTaxes() sin error, want %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package dian
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+)
+
+// Client contrato de los servicios web de facturación electrónica de la DIAN
+// (WcfDianCustomerServices). Por ahora solo lo implementa el simulador: el
+// envío a la DIAN exige documentos firmados con XAdES-EPES, que el servicio
+// aún no genera (ver ErrEnvironmentUnsupported).
+type Client interface {
+	// SendBillSync envía un documento empaquetado en ZIP y espera la validación
+	SendBillSync(ctx context.Context, fileName string, zipContent []byte) (*DianResponse, error)
+	// SendNominaSync envía un documento de nómina electrónica empaquetado en ZIP
+	SendNominaSync(ctx context.Context, fileName string, zipContent []byte) (*DianResponse, error)
+	// SendTestSetAsync envía un documento del set de pruebas de habilitación
+	SendTestSetAsync(ctx context.Context, fileName string, zipContent []byte, testSetID string) (*UploadResponse, error)
+	// GetStatus consulta el estado de un documento por CUFE/CUDE o trackId
+	GetStatus(ctx context.Context, trackID string) (*DianResponse, error)
+	// GetStatusZip consulta el estado y retorna el ZIP con los ApplicationResponse
+	GetStatusZip(ctx context.Context, trackID string) ([]byte, error)
+}
+
+// Códigos de estado retornados por la DIAN
+const (
+	StatusProcessed      = "00" // Procesado correctamente
+	StatusDocumentErrors = "66" // NSU no encontrado / errores del documento
+	StatusNotFound       = "90" // TrackId no encontrado
+	StatusRejected       = "99" // Validaciones contienen errores en campos mandatorios
+)
+
+// DianResponse respuesta de SendBillSync y GetStatus
+type DianResponse struct {
+	IsValid           bool     `json:"is_valid" xml:"IsValid"`
+	StatusCode        string   `json:"status_code" xml:"StatusCode"`
+	StatusDescription string   `json:"status_description" xml:"StatusDescription"`
+	StatusMessage     string   `json:"status_message" xml:"StatusMessage"`
+	ErrorMessages     []string `json:"error_messages,omitempty" xml:"ErrorMessage>string"`
+	XMLBase64Bytes    string   `json:"-" xml:"XmlBase64Bytes"`
+	XMLDocumentKey    string   `json:"document_key" xml:"XmlDocumentKey"`
+	XMLFileName       string   `json:"file_name" xml:"XmlFileName"`
+
+	// ApplicationResponse decodificado desde XMLBase64Bytes (si viene)
+	ApplicationResponse *ApplicationResponse `json:"application_response,omitempty" xml:"-"`
+}
+
+// UploadResponse respuesta de SendTestSetAsync
+type UploadResponse struct {
+	ZipKey        string   `json:"zip_key" xml:"ZipKey"`
+	ErrorMessages []string `json:"error_messages,omitempty" xml:"ErrorMessageList>XmlParamsResponseTrackId>ProcessedMessage"`
+}
+
+// ApplicationResponse respuesta de aplicación UBL emitida por la DIAN
+type ApplicationResponse struct {
+	ID           string   `json:"id"`
+	UUID         string   `json:"uuid"`
+	IssueDate    string   `json:"issue_date"`
+	IssueTime    string   `json:"issue_time"`
+	ResponseCode string   `json:"response_code"`
+	Description  string   `json:"description"`
+	DocumentID   string   `json:"document_id"`
+	DocumentUUID string   `json:"document_uuid"`
+	Notes        []string `json:"notes,omitempty"`
+}
+
+// Accepted indica si la respuesta de aplicación corresponde a un documento validado
+func (a *ApplicationResponse) Accepted() bool {
+	// 02 = Documento validado por la DIAN, 04 = Documento rechazado
+	return a.ResponseCode == "02"
+}
+
+// ===== PARSEO DE RESPUESTAS =====
+
+// ParseApplicationResponse interpreta un documento UBL ApplicationResponse
+func ParseApplicationResponse(data []byte) (*ApplicationResponse, error) {
+	var doc struct {
+		XMLName          xml.Name `xml:"ApplicationResponse"`
+		ID               string   `xml:"ID"`
+		UUID             string   `xml:"UUID"`
+		IssueDate        string   `xml:"IssueDate"`
+		IssueTime        string   `xml:"IssueTime"`
+		Notes            []string `xml:"Note"`
+		DocumentResponse []struct {
+			Response struct {
+				ResponseCode string `xml:"ResponseCode"`
+				Description  string `xml:"Description"`
+			} `xml:"Response"`
+			DocumentReference struct {
+				ID   string `xml:"ID"`
+				UUID string `xml:"UUID"`
+			} `xml:"DocumentReference"`
+		} `xml:"DocumentResponse"`
+	}
+
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("ApplicationResponse inválido: %w", err)
+	}
+
+	response := &ApplicationResponse{
+		ID:        doc.ID,
+		UUID:      doc.UUID,
+		IssueDate: doc.IssueDate,
+		IssueTime: doc.IssueTime,
+		Notes:     doc.Notes,
+	}
+	if len(doc.DocumentResponse) > 0 {
+		first := doc.DocumentResponse[0]
+		response.ResponseCode = first.Response.ResponseCode
+		response.Description = first.Response.Description
+		response.DocumentID = first.DocumentReference.ID
+		response.DocumentUUID = first.DocumentReference.UUID
+	}
+
+	return response, nil
+}
@@ -0,0 +1,273 @@
+package dian
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// CreditNoteTypeCode tipo de documento (CreditNoteTypeCode) de la nota crédito
+const CreditNoteTypeCode = "91"
+
+// Conceptos de corrección de la nota crédito (anexo técnico, tabla 13.2.4)
+const (
+	CreditReasonPartialReturn = "1" // Devolución parcial de los bienes y/o no aceptación parcial del servicio
+	CreditReasonCancellation  = "2" // Anulación de factura electrónica
+	CreditReasonDiscount      = "3" // Rebaja o descuento parcial o total
+	CreditReasonPriceAdjust   = "4" // Ajuste de precio
+	CreditReasonOther         = "5" // Otros
+)
+
+// DefaultCreditNoteNumbering numeración de notas crédito: la DIAN no autoriza
+// rangos, el facturador define prefijo y consecutivo
+var DefaultCreditNoteNumbering = NumberingRange{
+	Prefix: "NC",
+	From:   1,
+	To:     999999999,
+}
+
+// InvoiceReference factura que corrige una nota crédito
+type InvoiceReference struct {
+	SubmissionID string    `json:"submission_id"`
+	Number       string    `json:"number"`
+	CUFE         string    `json:"cufe"`
+	IssueDate    time.Time `json:"issue_date"`
+}
+
+// CreditNote nota crédito electrónica que referencia una factura de venta
+// (UBL 2.1, anexo técnico 1.9). Anula la factura o reduce su valor, por
+// ejemplo por una devolución del pago.
+type CreditNote struct {
+	Prefix        string           `json:"prefix"`
+	Number        int64            `json:"number"`
+	IssueDate     time.Time        `json:"issue_date"`
+	Issuer        Party            `json:"issuer"`
+	Customer      Party            `json:"customer"`
+	Invoice       InvoiceReference `json:"invoice"`
+	ReasonCode    string           `json:"reason_code"` // Concepto de corrección (CreditReason...)
+	Reason        string           `json:"reason"`
+	Lines         []InvoiceLine    `json:"lines"`
+	PaymentMethod string           `json:"payment_method"`
+	Notes         string           `json:"notes,omitempty"`
+
+	// Totales calculados por Compute
+	Subtotal int         `json:"subtotal_cop"`
+	Taxes    []TaxAmount `json:"taxes"`
+	Total    int         `json:"total_cop"`
+}
+
+// Compute calcula subtotales e impuestos de la nota crédito
+func (cn *CreditNote) Compute() {
+	cn.Subtotal, cn.Taxes, cn.Total = computeTotals(cn.Lines)
+}
+
+// TaxTotal suma de un tributo en todas sus tarifas
+func (cn *CreditNote) TaxTotal(code string) int {
+	return taxTotal(cn.Taxes, code)
+}
+
+// Kind implementa Document
+func (cn *CreditNote) Kind() DocumentKind { return KindCreditNote }
+
+// FullNumber implementa Document
+func (cn *CreditNote) FullNumber() string { return fmt.Sprintf("%s%d", cn.Prefix, cn.Number) }
+
+// IssuerNIT implementa Document
+func (cn *CreditNote) IssuerNIT() string { return onlyDigits(cn.Issuer.ID) }
+
+// IssuedAt implementa Document
+func (cn *CreditNote) IssuedAt() time.Time { return cn.IssueDate }
+
+// FilePrefix implementa Document
+func (cn *CreditNote) FilePrefix() string { return "nc" }
+
+// DocumentKey calcula el CUDE:
+// SHA-384(NumNC + FecNC + HorNC + ValNC + 01 + ValImp1 + 04 + ValImp2 + 03 + ValImp3 + ValTot + NitOFE + NumAdq + Software-PIN + TipoAmbiente)
+func (cn *CreditNote) DocumentKey(softwarePIN string, env Environment) string {
+	issued := bogotaTime(cn.IssueDate)
+	return sha384Hex(
+		cn.FullNumber(),
+		issued.Format("2006-01-02"),
+		issued.Format("15:04:05-07:00"),
+		money(cn.Subtotal),
+		TaxIVA, money(cn.TaxTotal(TaxIVA)),
+		TaxINC, money(cn.TaxTotal(TaxINC)),
+		TaxICA, money(cn.TaxTotal(TaxICA)),
+		money(cn.Total),
+		cn.IssuerNIT(),
+		onlyDigits(cn.Customer.ID),
+		softwarePIN,
+		env.Code(),
+	)
+}
+
+// RenderXML genera el XML UBL de la nota crédito
+func (cn *CreditNote) RenderXML(meta RenderMeta) ([]byte, error) {
+	var buf bytes.Buffer
+	data := map[string]interface{}{
+		"Note":     cn,
+		"Meta":     meta,
+		"Issued":   bogotaTime(cn.IssueDate),
+		"Invoice":  bogotaTime(cn.Invoice.IssueDate),
+		"TypeCode": CreditNoteTypeCode,
+	}
+	if err := creditNoteTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error generando XML de nota crédito: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// CreditLines líneas de una nota crédito por amount pesos de la factura. Si
+// amount cubre el total se acreditan las líneas originales; si no, cada línea
+// se acredita en proporción, con sus mismas tarifas, y la última línea se
+// ajusta para que el total quede lo más cerca de amount sin superarlo.
+func CreditLines(invoice *Invoice, amount int) []InvoiceLine {
+	if amount >= invoice.Total || invoice.Total == 0 {
+		return append([]InvoiceLine(nil), invoice.Lines...)
+	}
+
+	ratio := float64(amount) / float64(invoice.Total)
+	lines := make([]InvoiceLine, 0, len(invoice.Lines))
+	for _, line := range invoice.Lines {
+		credited := roundCOP(float64(line.Subtotal()) * ratio)
+		if credited == 0 {
+			continue
+		}
+		lines = append(lines, InvoiceLine{
+			Description: "Devolución parcial: " + line.Description,
+			Quantity:    1,
+			UnitPrice:   credited,
+			IVARate:     line.IVARate,
+			INCRate:     line.INCRate,
+		})
+	}
+	if len(lines) == 0 {
+		return lines
+	}
+	last := &lines[len(lines)-1]
+	for {
+		if _, _, total := computeTotals(lines); total <= amount || last.UnitPrice <= 1 {
+			break
+		}
+		last.UnitPrice--
+	}
+	for {
+		last.UnitPrice++
+		if _, _, total := computeTotals(lines); total > amount {
+			last.UnitPrice--
+			break
+		}
+	}
+	return lines
+}
+
+var creditNoteTemplate = template.Must(template.New("credit_note").Funcs(templateFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<CreditNote xmlns="urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2" xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2" xmlns:sts="dian:gov:co:facturaelectronica:Structures-2-1">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent>
+        <sts:DianExtensions>
+          <sts:InvoiceSource>
+            <cbc:IdentificationCode listAgencyID="6" listAgencyName="United Nations Economic Commission for Europe" listSchemeURI="urn:oasis:names:specification:ubl:codelist:gc:CountryIdentificationCode-2.1">CO</cbc:IdentificationCode>
+          </sts:InvoiceSource>
+          <sts:SoftwareProvider>
+            <sts:ProviderID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)" schemeID="{{x .Note.Issuer.CheckDigit}}" schemeName="31">{{x .Note.IssuerNIT}}</sts:ProviderID>
+            <sts:SoftwareID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)">{{x .Meta.SoftwareID}}</sts:SoftwareID>
+          </sts:SoftwareProvider>
+          <sts:SoftwareSecurityCode schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)">{{.Meta.SoftwareCode}}</sts:SoftwareSecurityCode>
+          <sts:AuthorizationProvider>
+            <sts:AuthorizationProviderID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)" schemeID="4" schemeName="31">800197268</sts:AuthorizationProviderID>
+          </sts:AuthorizationProvider>
+          <sts:QRCode>{{x .Meta.QRCode}}</sts:QRCode>
+        </sts:DianExtensions>
+      </ext:ExtensionContent>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <cbc:UBLVersionID>UBL 2.1</cbc:UBLVersionID>
+  <cbc:CustomizationID>20</cbc:CustomizationID>
+  <cbc:ProfileID>DIAN 2.1: Nota Crédito de Factura Electrónica de Venta</cbc:ProfileID>
+  <cbc:ProfileExecutionID>{{.Meta.Environment.Code}}</cbc:ProfileExecutionID>
+  <cbc:ID>{{x .Note.FullNumber}}</cbc:ID>
+  <cbc:UUID schemeID="{{.Meta.Environment.Code}}" schemeName="CUDE-SHA384">{{.Meta.DocumentKey}}</cbc:UUID>
+  <cbc:IssueDate>{{date .Issued}}</cbc:IssueDate>
+  <cbc:IssueTime>{{time .Issued}}</cbc:IssueTime>
+  <cbc:CreditNoteTypeCode>{{.TypeCode}}</cbc:CreditNoteTypeCode>
+{{- if .Note.Notes}}
+  <cbc:Note>{{x .Note.Notes}}</cbc:Note>
+{{- end}}
+  <cbc:DocumentCurrencyCode>COP</cbc:DocumentCurrencyCode>
+  <cbc:LineCountNumeric>{{len .Note.Lines}}</cbc:LineCountNumeric>
+  <cac:DiscrepancyResponse>
+    <cbc:ReferenceID>{{x .Note.Invoice.Number}}</cbc:ReferenceID>
+    <cbc:ResponseCode>{{x .Note.ReasonCode}}</cbc:ResponseCode>
+    <cbc:Description>{{x .Note.Reason}}</cbc:Description>
+  </cac:DiscrepancyResponse>
+  <cac:BillingReference>
+    <cac:InvoiceDocumentReference>
+      <cbc:ID>{{x .Note.Invoice.Number}}</cbc:ID>
+      <cbc:UUID schemeName="CUFE-SHA384">{{x .Note.Invoice.CUFE}}</cbc:UUID>
+      <cbc:IssueDate>{{date .Invoice}}</cbc:IssueDate>
+    </cac:InvoiceDocumentReference>
+  </cac:BillingReference>
+  <cac:AccountingSupplierParty>
+    <cbc:AdditionalAccountID>1</cbc:AdditionalAccountID>
+    <cac:Party>
+      <cac:PartyTaxScheme>
+        <cbc:RegistrationName>{{x .Note.Issuer.Name}}</cbc:RegistrationName>
+        <cbc:CompanyID schemeAgencyID="195" schemeID="{{x .Note.Issuer.CheckDigit}}" schemeName="31">{{x .Note.IssuerNIT}}</cbc:CompanyID>
+        <cbc:TaxLevelCode>{{x .Note.Issuer.TaxLevel}}</cbc:TaxLevelCode>
+        <cac:TaxScheme><cbc:ID>01</cbc:ID><cbc:Name>IVA</cbc:Name></cac:TaxScheme>
+      </cac:PartyTaxScheme>
+    </cac:Party>
+  </cac:AccountingSupplierParty>
+  <cac:AccountingCustomerParty>
+    <cac:Party>
+      <cac:PartyTaxScheme>
+        <cbc:RegistrationName>{{x .Note.Customer.Name}}</cbc:RegistrationName>
+        <cbc:CompanyID schemeAgencyID="195" schemeID="{{x .Note.Customer.CheckDigit}}" schemeName="{{x .Note.Customer.DocumentType}}">{{x .Note.Customer.ID}}</cbc:CompanyID>
+        <cbc:TaxLevelCode>{{x .Note.Customer.TaxLevel}}</cbc:TaxLevelCode>
+        <cac:TaxScheme><cbc:ID>ZZ</cbc:ID><cbc:Name>No aplica</cbc:Name></cac:TaxScheme>
+      </cac:PartyTaxScheme>
+{{- if .Note.Customer.Email}}
+      <cac:Contact><cbc:ElectronicMail>{{x .Note.Customer.Email}}</cbc:ElectronicMail></cac:Contact>
+{{- end}}
+    </cac:Party>
+  </cac:AccountingCustomerParty>
+  <cac:PaymentMeans>
+    <cbc:ID>1</cbc:ID>
+    <cbc:PaymentMeansCode>{{x .Note.PaymentMethod}}</cbc:PaymentMeansCode>
+  </cac:PaymentMeans>
+{{- range .Note.Taxes}}
+  <cac:TaxTotal>
+    <cbc:TaxAmount currencyID="COP">{{money .Amount}}</cbc:TaxAmount>
+    <cac:TaxSubtotal>
+      <cbc:TaxableAmount currencyID="COP">{{money .Base}}</cbc:TaxableAmount>
+      <cbc:TaxAmount currencyID="COP">{{money .Amount}}</cbc:TaxAmount>
+      <cac:TaxCategory>
+        <cbc:Percent>{{percent .Percent}}</cbc:Percent>
+        <cac:TaxScheme><cbc:ID>{{.Code}}</cbc:ID><cbc:Name>{{x .Name}}</cbc:Name></cac:TaxScheme>
+      </cac:TaxCategory>
+    </cac:TaxSubtotal>
+  </cac:TaxTotal>
+{{- end}}
+  <cac:LegalMonetaryTotal>
+    <cbc:LineExtensionAmount currencyID="COP">{{money .Note.Subtotal}}</cbc:LineExtensionAmount>
+    <cbc:TaxExclusiveAmount currencyID="COP">{{money .Note.Subtotal}}</cbc:TaxExclusiveAmount>
+    <cbc:TaxInclusiveAmount currencyID="COP">{{money .Note.Total}}</cbc:TaxInclusiveAmount>
+    <cbc:PayableAmount currencyID="COP">{{money .Note.Total}}</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>
+{{- range $i, $line := .Note.Lines}}
+  <cac:CreditNoteLine>
+    <cbc:ID>{{inc $i}}</cbc:ID>
+    <cbc:CreditedQuantity unitCode="94">{{$line.Quantity}}</cbc:CreditedQuantity>
+    <cbc:LineExtensionAmount currencyID="COP">{{money $line.Subtotal}}</cbc:LineExtensionAmount>
+    <cac:Item><cbc:Description>{{x $line.Description}}</cbc:Description></cac:Item>
+    <cac:Price>
+      <cbc:PriceAmount currencyID="COP">{{money $line.UnitPrice}}</cbc:PriceAmount>
+      <cbc:BaseQuantity unitCode="94">1</cbc:BaseQuantity>
+    </cac:Price>
+  </cac:CreditNoteLine>
+{{- end}}
+</CreditNote>
+`))
@@ -0,0 +1,164 @@
+package dian
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Environment ambiente de la DIAN al que se envían los documentos
+type Environment string
+
+const (
+	EnvironmentSimulator    Environment = "simulador"
+	EnvironmentHabilitacion Environment = "habilitacion"
+	EnvironmentProduccion   Environment = "produccion"
+)
+
+// Code código del ambiente usado en CUFE/CUDE y en el XML (1 = producción, 2 = pruebas)
+func (e Environment) Code() string {
+	if e == EnvironmentProduccion {
+		return "1"
+	}
+	return "2"
+}
+
+// DocumentKind tipo de documento electrónico
+type DocumentKind string
+
+const (
+	KindInvoice         DocumentKind = "factura"
+	KindSupportDocument DocumentKind = "documento_soporte"
+	KindPayroll         DocumentKind = "nomina"
+	KindCreditNote      DocumentKind = "nota_credito"
+)
+
+// Document documento electrónico que puede firmarse, empaquetarse y enviarse a la DIAN
+type Document interface {
+	// Kind tipo de documento
+	Kind() DocumentKind
+	// FullNumber prefijo + consecutivo
+	FullNumber() string
+	// IssuerNIT NIT del emisor sin dígito de verificación
+	IssuerNIT() string
+	// IssuedAt fecha y hora de emisión
+	IssuedAt() time.Time
+	// FilePrefix prefijo del nombre de archivo XML según el anexo técnico
+	FilePrefix() string
+	// DocumentKey calcula el código único (CUFE, CUDE, CUDS, CUNE) con la clave indicada
+	DocumentKey(secret string, env Environment) string
+	// RenderXML genera el XML UBL/nómina del documento
+	RenderXML(meta RenderMeta) ([]byte, error)
+}
+
+// RenderMeta datos del software y la numeración necesarios para generar el XML
+type RenderMeta struct {
+	Environment   Environment
+	SoftwareID    string
+	SoftwareCode  string // Código de seguridad del software (SHA-384)
+	DocumentKey   string
+	Numbering     NumberingRange
+	QRCode        string
+	ProfileExecID string
+}
+
+// Party emisor o adquirente del documento
+type Party struct {
+	DocumentType string `json:"document_type"` // Código DIAN: 13 = CC, 31 = NIT, ...
+	ID           string `json:"id"`
+	CheckDigit   string `json:"check_digit,omitempty"`
+	Name         string `json:"name"`
+	Email        string `json:"email,omitempty"`
+	Address      string `json:"address,omitempty"`
+	CityCode     string `json:"city_code,omitempty"` // Código DIVIPOLA
+	CityName     string `json:"city_name,omitempty"`
+	TaxLevel     string `json:"tax_level,omitempty"` // Responsabilidades fiscales (O-13, O-15, R-99-PN...)
+}
+
+// Códigos de tributos usados en el CUFE
+const (
+	TaxIVA = "01"
+	TaxICA = "03"
+	TaxINC = "04"
+)
+
+// TaxAmount valor de un tributo agrupado por código y tarifa
+type TaxAmount struct {
+	Code    string  `json:"code"`
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+	Base    int     `json:"base_cop"`
+	Amount  int     `json:"amount_cop"`
+}
+
+// Tipos de retención en la fuente
+const (
+	WithholdingRenta = "retefuente"
+	WithholdingIVA   = "reteiva"
+	WithholdingICA   = "reteica"
+)
+
+// WithholdingAmount retención practicada sobre el documento. Es informativa:
+// no altera los totales, el CUFE/CUDS ni el XML, pero alimenta los reportes
+// de información exógena y las declaraciones de retención
+type WithholdingAmount struct {
+	Type   string  `json:"type"` // retefuente, reteiva, reteica
+	Name   string  `json:"name"`
+	Rate   float64 `json:"rate"`
+	Base   int     `json:"base_cop"`
+	Amount int     `json:"amount_cop"`
+}
+
+// withholdingTotal suma las retenciones de un tipo
+func withholdingTotal(withholdings []WithholdingAmount, kind string) int {
+	total := 0
+	for _, withholding := range withholdings {
+		if withholding.Type == kind {
+			total += withholding.Amount
+		}
+	}
+	return total
+}
+
+// softwareSecurityCode código de seguridad del software: SHA-384(SoftwareID + PIN + número)
+func softwareSecurityCode(softwareID, pin, number string) string {
+	sum := sha512.Sum384([]byte(softwareID + pin + number))
+	return hex.EncodeToString(sum[:])
+}
+
+// sha384Hex calcula el SHA-384 en hexadecimal usado por CUFE/CUDE/CUDS/CUNE
+func sha384Hex(parts ...string) string {
+	sum := sha512.Sum384([]byte(strings.Join(parts, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// money formatea un valor en pesos con dos decimales, como lo exige el anexo técnico
+func money(value int) string {
+	return fmt.Sprintf("%d.00", value)
+}
+
+// percent formatea una tarifa con dos decimales
+func percent(value float64) string {
+	return fmt.Sprintf("%.2f", value)
+}
+
+// roundCOP redondea al peso más cercano
+func roundCOP(value float64) int {
+	return int(math.Round(value))
+}
+
+// bogotaTime convierte una fecha a la zona horaria de Colombia (UTC-5 sin horario de verano)
+func bogotaTime(t time.Time) time.Time {
+	return t.In(time.FixedZone("COT", -5*60*60))
+}
+
+func xmlEscape(value string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
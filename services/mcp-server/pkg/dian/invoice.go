@@ -0,0 +1,284 @@
+package dian
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// InvoiceLine línea de detalle de una factura
+type InvoiceLine struct {
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   int     `json:"unit_price_cop"`
+	IVARate     float64 `json:"iva_rate"`
+	INCRate     float64 `json:"inc_rate,omitempty"`
+}
+
+// Subtotal valor antes de impuestos de la línea
+func (l InvoiceLine) Subtotal() int {
+	return l.Quantity * l.UnitPrice
+}
+
+// Invoice factura electrónica de venta (UBL 2.1, anexo técnico 1.9)
+type Invoice struct {
+	Prefix        string        `json:"prefix"`
+	Number        int64         `json:"number"`
+	IssueDate     time.Time     `json:"issue_date"`
+	DueDate       time.Time     `json:"due_date"`
+	Issuer        Party         `json:"issuer"`
+	Customer      Party         `json:"customer"`
+	Lines         []InvoiceLine `json:"lines"`
+	PaymentMethod string        `json:"payment_method"` // Código DIAN de medio de pago (10 = efectivo, 48 = tarjeta crédito, ...)
+	PaymentForm   string        `json:"payment_form"`   // 1 = contado, 2 = crédito
+	Notes         string        `json:"notes,omitempty"`
+
+	// Totales calculados por Compute
+	Subtotal int         `json:"subtotal_cop"`
+	Taxes    []TaxAmount `json:"taxes"`
+	Total    int         `json:"total_cop"`
+
+	// Retenciones que practica el cliente al pagar la factura
+	Withholdings []WithholdingAmount `json:"withholdings,omitempty"`
+}
+
+// WithholdingTotal suma las retenciones de un tipo que practica el cliente
+func (inv *Invoice) WithholdingTotal(kind string) int {
+	return withholdingTotal(inv.Withholdings, kind)
+}
+
+// Compute calcula subtotales y agrupa los impuestos por código y tarifa
+func (inv *Invoice) Compute() {
+	inv.Subtotal, inv.Taxes, inv.Total = computeTotals(inv.Lines)
+}
+
+// computeTotals calcula subtotal, impuestos agrupados por código y tarifa, y total
+func computeTotals(lines []InvoiceLine) (int, []TaxAmount, int) {
+	subtotal := 0
+	grouped := map[string]*TaxAmount{}
+
+	add := func(code, name string, rate float64, base int) {
+		key := fmt.Sprintf("%s:%.2f", code, rate)
+		tax, ok := grouped[key]
+		if !ok {
+			tax = &TaxAmount{Code: code, Name: name, Percent: rate}
+			grouped[key] = tax
+		}
+		tax.Base += base
+		tax.Amount = roundCOP(float64(tax.Base) * rate / 100)
+	}
+
+	for _, line := range lines {
+		lineSubtotal := line.Subtotal()
+		subtotal += lineSubtotal
+		add(TaxIVA, "IVA", line.IVARate, lineSubtotal)
+		if line.INCRate > 0 {
+			add(TaxINC, "INC", line.INCRate, lineSubtotal)
+		}
+	}
+
+	taxes := make([]TaxAmount, 0, len(grouped))
+	for _, tax := range grouped {
+		taxes = append(taxes, *tax)
+	}
+	sort.Slice(taxes, func(i, j int) bool {
+		if taxes[i].Code != taxes[j].Code {
+			return taxes[i].Code < taxes[j].Code
+		}
+		return taxes[i].Percent < taxes[j].Percent
+	})
+
+	total := subtotal
+	for _, tax := range taxes {
+		total += tax.Amount
+	}
+	return subtotal, taxes, total
+}
+
+// TaxTotal suma de un tributo en todas sus tarifas
+func (inv *Invoice) TaxTotal(code string) int {
+	return taxTotal(inv.Taxes, code)
+}
+
+func taxTotal(taxes []TaxAmount, code string) int {
+	total := 0
+	for _, tax := range taxes {
+		if tax.Code == code {
+			total += tax.Amount
+		}
+	}
+	return total
+}
+
+// Kind implementa Document
+func (inv *Invoice) Kind() DocumentKind { return KindInvoice }
+
+// FullNumber implementa Document
+func (inv *Invoice) FullNumber() string { return fmt.Sprintf("%s%d", inv.Prefix, inv.Number) }
+
+// IssuerNIT implementa Document
+func (inv *Invoice) IssuerNIT() string { return onlyDigits(inv.Issuer.ID) }
+
+// IssuedAt implementa Document
+func (inv *Invoice) IssuedAt() time.Time { return inv.IssueDate }
+
+// FilePrefix implementa Document
+func (inv *Invoice) FilePrefix() string { return "fv" }
+
+// DocumentKey calcula el CUFE:
+// SHA-384(NumFac + FecFac + HorFac + ValFac + 01 + ValImp1 + 04 + ValImp2 + 03 + ValImp3 + ValTot + NitOFE + NumAdq + ClTec + TipoAmbiente)
+func (inv *Invoice) DocumentKey(technicalKey string, env Environment) string {
+	issued := bogotaTime(inv.IssueDate)
+	return sha384Hex(
+		inv.FullNumber(),
+		issued.Format("2006-01-02"),
+		issued.Format("15:04:05-07:00"),
+		money(inv.Subtotal),
+		TaxIVA, money(inv.TaxTotal(TaxIVA)),
+		TaxINC, money(inv.TaxTotal(TaxINC)),
+		TaxICA, money(inv.TaxTotal(TaxICA)),
+		money(inv.Total),
+		inv.IssuerNIT(),
+		onlyDigits(inv.Customer.ID),
+		technicalKey,
+		env.Code(),
+	)
+}
+
+// RenderXML genera el XML UBL de la factura
+func (inv *Invoice) RenderXML(meta RenderMeta) ([]byte, error) {
+	var buf bytes.Buffer
+	data := map[string]interface{}{
+		"Invoice": inv,
+		"Meta":    meta,
+		"Issued":  bogotaTime(inv.IssueDate),
+		"Due":     bogotaTime(inv.DueDate),
+	}
+	if err := invoiceTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error generando XML de factura: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+var templateFuncs = template.FuncMap{
+	"x":       xmlEscape,
+	"money":   money,
+	"percent": percent,
+	"date":    func(t time.Time) string { return t.Format("2006-01-02") },
+	"time":    func(t time.Time) string { return t.Format("15:04:05-07:00") },
+	"inc":     func(i int) int { return i + 1 },
+}
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(templateFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2" xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2" xmlns:sts="dian:gov:co:facturaelectronica:Structures-2-1">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent>
+        <sts:DianExtensions>
+          <sts:InvoiceControl>
+            <sts:InvoiceAuthorization>{{x .Meta.Numbering.Resolution}}</sts:InvoiceAuthorization>
+            <sts:AuthorizationPeriod>
+              <cbc:StartDate>{{date .Meta.Numbering.ValidFrom}}</cbc:StartDate>
+              <cbc:EndDate>{{date .Meta.Numbering.ValidTo}}</cbc:EndDate>
+            </sts:AuthorizationPeriod>
+            <sts:AuthorizedInvoices>
+              <sts:Prefix>{{x .Meta.Numbering.Prefix}}</sts:Prefix>
+              <sts:From>{{.Meta.Numbering.From}}</sts:From>
+              <sts:To>{{.Meta.Numbering.To}}</sts:To>
+            </sts:AuthorizedInvoices>
+          </sts:InvoiceControl>
+          <sts:InvoiceSource>
+            <cbc:IdentificationCode listAgencyID="6" listAgencyName="United Nations Economic Commission for Europe" listSchemeURI="urn:oasis:names:specification:ubl:codelist:gc:CountryIdentificationCode-2.1">CO</cbc:IdentificationCode>
+          </sts:InvoiceSource>
+          <sts:SoftwareProvider>
+            <sts:ProviderID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)" schemeID="{{x .Invoice.Issuer.CheckDigit}}" schemeName="31">{{x .Invoice.IssuerNIT}}</sts:ProviderID>
+            <sts:SoftwareID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)">{{x .Meta.SoftwareID}}</sts:SoftwareID>
+          </sts:SoftwareProvider>
+          <sts:SoftwareSecurityCode schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)">{{.Meta.SoftwareCode}}</sts:SoftwareSecurityCode>
+          <sts:AuthorizationProvider>
+            <sts:AuthorizationProviderID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)" schemeID="4" schemeName="31">800197268</sts:AuthorizationProviderID>
+          </sts:AuthorizationProvider>
+          <sts:QRCode>{{x .Meta.QRCode}}</sts:QRCode>
+        </sts:DianExtensions>
+      </ext:ExtensionContent>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <cbc:UBLVersionID>UBL 2.1</cbc:UBLVersionID>
+  <cbc:CustomizationID>10</cbc:CustomizationID>
+  <cbc:ProfileID>DIAN 2.1: Factura Electrónica de Venta</cbc:ProfileID>
+  <cbc:ProfileExecutionID>{{.Meta.Environment.Code}}</cbc:ProfileExecutionID>
+  <cbc:ID>{{x .Invoice.FullNumber}}</cbc:ID>
+  <cbc:UUID schemeID="{{.Meta.Environment.Code}}" schemeName="CUFE-SHA384">{{.Meta.DocumentKey}}</cbc:UUID>
+  <cbc:IssueDate>{{date .Issued}}</cbc:IssueDate>
+  <cbc:IssueTime>{{time .Issued}}</cbc:IssueTime>
+  <cbc:DueDate>{{date .Due}}</cbc:DueDate>
+  <cbc:InvoiceTypeCode>01</cbc:InvoiceTypeCode>
+{{- if .Invoice.Notes}}
+  <cbc:Note>{{x .Invoice.Notes}}</cbc:Note>
+{{- end}}
+  <cbc:DocumentCurrencyCode>COP</cbc:DocumentCurrencyCode>
+  <cbc:LineCountNumeric>{{len .Invoice.Lines}}</cbc:LineCountNumeric>
+  <cac:AccountingSupplierParty>
+    <cbc:AdditionalAccountID>1</cbc:AdditionalAccountID>
+    <cac:Party>
+      <cac:PartyTaxScheme>
+        <cbc:RegistrationName>{{x .Invoice.Issuer.Name}}</cbc:RegistrationName>
+        <cbc:CompanyID schemeAgencyID="195" schemeID="{{x .Invoice.Issuer.CheckDigit}}" schemeName="31">{{x .Invoice.IssuerNIT}}</cbc:CompanyID>
+        <cbc:TaxLevelCode>{{x .Invoice.Issuer.TaxLevel}}</cbc:TaxLevelCode>
+        <cac:TaxScheme><cbc:ID>01</cbc:ID><cbc:Name>IVA</cbc:Name></cac:TaxScheme>
+      </cac:PartyTaxScheme>
+    </cac:Party>
+  </cac:AccountingSupplierParty>
+  <cac:AccountingCustomerParty>
+    <cac:Party>
+      <cac:PartyTaxScheme>
+        <cbc:RegistrationName>{{x .Invoice.Customer.Name}}</cbc:RegistrationName>
+        <cbc:CompanyID schemeAgencyID="195" schemeID="{{x .Invoice.Customer.CheckDigit}}" schemeName="{{x .Invoice.Customer.DocumentType}}">{{x .Invoice.Customer.ID}}</cbc:CompanyID>
+        <cbc:TaxLevelCode>{{x .Invoice.Customer.TaxLevel}}</cbc:TaxLevelCode>
+        <cac:TaxScheme><cbc:ID>ZZ</cbc:ID><cbc:Name>No aplica</cbc:Name></cac:TaxScheme>
+      </cac:PartyTaxScheme>
+{{- if .Invoice.Customer.Email}}
+      <cac:Contact><cbc:ElectronicMail>{{x .Invoice.Customer.Email}}</cbc:ElectronicMail></cac:Contact>
+{{- end}}
+    </cac:Party>
+  </cac:AccountingCustomerParty>
+  <cac:PaymentMeans>
+    <cbc:ID>{{x .Invoice.PaymentForm}}</cbc:ID>
+    <cbc:PaymentMeansCode>{{x .Invoice.PaymentMethod}}</cbc:PaymentMeansCode>
+    <cbc:PaymentDueDate>{{date .Due}}</cbc:PaymentDueDate>
+  </cac:PaymentMeans>
+{{- range .Invoice.Taxes}}
+  <cac:TaxTotal>
+    <cbc:TaxAmount currencyID="COP">{{money .Amount}}</cbc:TaxAmount>
+    <cac:TaxSubtotal>
+      <cbc:TaxableAmount currencyID="COP">{{money .Base}}</cbc:TaxableAmount>
+      <cbc:TaxAmount currencyID="COP">{{money .Amount}}</cbc:TaxAmount>
+      <cac:TaxCategory>
+        <cbc:Percent>{{percent .Percent}}</cbc:Percent>
+        <cac:TaxScheme><cbc:ID>{{.Code}}</cbc:ID><cbc:Name>{{x .Name}}</cbc:Name></cac:TaxScheme>
+      </cac:TaxCategory>
+    </cac:TaxSubtotal>
+  </cac:TaxTotal>
+{{- end}}
+  <cac:LegalMonetaryTotal>
+    <cbc:LineExtensionAmount currencyID="COP">{{money .Invoice.Subtotal}}</cbc:LineExtensionAmount>
+    <cbc:TaxExclusiveAmount currencyID="COP">{{money .Invoice.Subtotal}}</cbc:TaxExclusiveAmount>
+    <cbc:TaxInclusiveAmount currencyID="COP">{{money .Invoice.Total}}</cbc:TaxInclusiveAmount>
+    <cbc:PayableAmount currencyID="COP">{{money .Invoice.Total}}</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>
+{{- range $i, $line := .Invoice.Lines}}
+  <cac:InvoiceLine>
+    <cbc:ID>{{inc $i}}</cbc:ID>
+    <cbc:InvoicedQuantity unitCode="94">{{$line.Quantity}}</cbc:InvoicedQuantity>
+    <cbc:LineExtensionAmount currencyID="COP">{{money $line.Subtotal}}</cbc:LineExtensionAmount>
+    <cac:Item><cbc:Description>{{x $line.Description}}</cbc:Description></cac:Item>
+    <cac:Price>
+      <cbc:PriceAmount currencyID="COP">{{money $line.UnitPrice}}</cbc:PriceAmount>
+      <cbc:BaseQuantity unitCode="94">1</cbc:BaseQuantity>
+    </cac:Price>
+  </cac:InvoiceLine>
+{{- end}}
+</Invoice>
+`))
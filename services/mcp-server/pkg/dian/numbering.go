@@ -0,0 +1,88 @@
+package dian
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NumberingRange resolución de numeración autorizada por la DIAN
+type NumberingRange struct {
+	Resolution   string    `json:"resolution"`
+	Prefix       string    `json:"prefix"`
+	From         int64     `json:"from"`
+	To           int64     `json:"to"`
+	ValidFrom    time.Time `json:"valid_from"`
+	ValidTo      time.Time `json:"valid_to"`
+	TechnicalKey string    `json:"-"` // Clave técnica para el CUFE (solo facturas)
+}
+
+// TestNumberingRange rango de pruebas que la DIAN asigna en habilitación
+var TestNumberingRange = NumberingRange{
+	Resolution:   "18760000001",
+	Prefix:       "SETP",
+	From:         990000000,
+	To:           995000000,
+	ValidFrom:    time.Date(2019, 1, 19, 0, 0, 0, 0, time.UTC),
+	ValidTo:      time.Date(2030, 1, 19, 0, 0, 0, 0, time.UTC),
+	TechnicalKey: "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c",
+}
+
+// SequenceStore guarda el último consecutivo asignado de cada rango fuera
+// del proceso, para que un reinicio u otra réplica no repitan números (lo
+// implementa cache.RedisCache). IncrementSequence suma uno a la clave de
+// forma atómica y retorna el nuevo valor, empezando en 1.
+type SequenceStore interface {
+	IncrementSequence(key string) (int64, error)
+}
+
+// memorySequenceStore contadores del proceso; solo sirve con una instancia
+type memorySequenceStore struct {
+	mu       sync.Mutex
+	counters map[string]int64
+}
+
+func (m *memorySequenceStore) IncrementSequence(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[key]++
+	return m.counters[key], nil
+}
+
+// Sequencer asigna consecutivos por tenant y rango autorizado
+type Sequencer struct {
+	store SequenceStore
+}
+
+// NewSequencer crea un asignador con contadores en memoria
+func NewSequencer() *Sequencer {
+	return &Sequencer{store: &memorySequenceStore{counters: make(map[string]int64)}}
+}
+
+// NewStoredSequencer crea un asignador con contadores compartidos en store
+func NewStoredSequencer(store SequenceStore) *Sequencer {
+	return &Sequencer{store: store}
+}
+
+// Next retorna el siguiente consecutivo disponible
+func (s *Sequencer) Next(tenantID string, rng NumberingRange, at time.Time) (int64, error) {
+	if !rng.ValidFrom.IsZero() && at.Before(rng.ValidFrom) {
+		return 0, fmt.Errorf("la resolución %s aún no está vigente", rng.Resolution)
+	}
+	if !rng.ValidTo.IsZero() && at.After(rng.ValidTo) {
+		return 0, fmt.Errorf("la resolución %s está vencida desde %s", rng.Resolution, rng.ValidTo.Format("2006-01-02"))
+	}
+
+	// Una resolución nueva con el mismo prefijo empieza su propio contador
+	key := fmt.Sprintf("dian:%s:%s:%s:%d", tenantID, rng.Resolution, rng.Prefix, rng.From)
+	count, err := s.store.IncrementSequence(key)
+	if err != nil {
+		return 0, fmt.Errorf("no se pudo asignar el consecutivo %s: %w", rng.Prefix, err)
+	}
+
+	number := rng.From + count - 1
+	if number > rng.To {
+		return 0, fmt.Errorf("rango de numeración %s agotado (%d-%d)", rng.Prefix, rng.From, rng.To)
+	}
+	return number, nil
+}
@@ -0,0 +1,82 @@
+package dian
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingSequenceStore struct{}
+
+func (failingSequenceStore) IncrementSequence(key string) (int64, error) {
+	return 0, errors.New("redis no disponible")
+}
+
+func TestSequencerSharedStore(t *testing.T) {
+	// Dos asignadores con el mismo almacén equivalen a un reinicio o a dos réplicas
+	store := &memorySequenceStore{counters: make(map[string]int64)}
+	first, second := NewStoredSequencer(store), NewStoredSequencer(store)
+	at := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	seen := map[int64]bool{}
+	for i := 0; i < 3; i++ {
+		for _, sequencer := range []*Sequencer{first, second} {
+			number, err := sequencer.Next("tenant-prueba", TestNumberingRange, at)
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if seen[number] {
+				t.Fatalf("Next() repitió el consecutivo %d", number)
+			}
+			seen[number] = true
+		}
+	}
+	if want := TestNumberingRange.From + 5; !seen[want] || len(seen) != 6 {
+		t.Errorf("consecutivos = %v, want %d a %d", seen, TestNumberingRange.From, want)
+	}
+
+	// Otro tenant y otra resolución con el mismo prefijo empiezan en From
+	if number, _ := first.Next("otro-tenant", TestNumberingRange, at); number != TestNumberingRange.From {
+		t.Errorf("Next() de otro tenant = %d, want %d", number, TestNumberingRange.From)
+	}
+	renewed := TestNumberingRange
+	renewed.Resolution, renewed.From = "18760000002", 995000001
+	renewed.To = renewed.From + 1
+	if number, _ := first.Next("tenant-prueba", renewed, at); number != renewed.From {
+		t.Errorf("Next() de la resolución nueva = %d, want %d", number, renewed.From)
+	}
+}
+
+func TestSequencerErrors(t *testing.T) {
+	at := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	small := NumberingRange{Resolution: "1", Prefix: "FE", From: 1, To: 2}
+	sequencer := NewSequencer()
+	for want := int64(1); want <= 2; want++ {
+		if number, err := sequencer.Next("tenant-prueba", small, at); err != nil || number != want {
+			t.Fatalf("Next() = %d, %v, want %d", number, err, want)
+		}
+	}
+	if _, err := sequencer.Next("tenant-prueba", small, at); err == nil {
+		t.Error("Next() con el rango agotado no retornó error")
+	}
+
+	expired := small
+	expired.ValidTo = at.Add(-time.Hour)
+	if _, err := NewSequencer().Next("tenant-prueba", expired, at); err == nil {
+		t.Error("Next() con la resolución vencida no retornó error")
+	}
+
+	if _, err := NewStoredSequencer(failingSequenceStore{}).Next("tenant-prueba", small, at); err == nil {
+		t.Error("Next() sin almacén disponible no retornó error")
+	}
+}
+
+func TestServicesShareSequenceStore(t *testing.T) {
+	store := &memorySequenceStore{counters: make(map[string]int64)}
+	// Cada réplica tiene su simulador; solo comparten los consecutivos
+	first := issueApprovedInvoice(t, NewService(nil).WithSequenceStore(store))
+	second := issueApprovedInvoice(t, NewService(nil).WithSequenceStore(store))
+	if first.Number == second.Number {
+		t.Errorf("dos réplicas emitieron el mismo número %s", first.Number)
+	}
+}
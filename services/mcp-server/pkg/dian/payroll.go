@@ -0,0 +1,220 @@
+package dian
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// PayrollTypeCode tipo de XML de nómina usado en el CUNE (102 = individual, 103 = ajuste)
+const PayrollTypeCode = "102"
+
+// Aportes del trabajador a seguridad social sobre el IBC
+const (
+	PayrollHealthRate  = 4.0
+	PayrollPensionRate = 4.0
+)
+
+// DefaultPayrollNumbering numeración de nómina: la DIAN no autoriza rangos,
+// el empleador define prefijo y consecutivo
+var DefaultPayrollNumbering = NumberingRange{
+	Prefix: "NE",
+	From:   1,
+	To:     999999999,
+}
+
+// PayrollEmployee trabajador del documento de nómina
+type PayrollEmployee struct {
+	WorkerType     string    `json:"worker_type"`    // 01 = dependiente
+	WorkerSubType  string    `json:"worker_subtype"` // 00 = no aplica
+	DocumentType   string    `json:"document_type"`  // Código DIAN: 13 = CC, 22 = CE, ...
+	ID             string    `json:"id"`
+	FirstSurname   string    `json:"first_surname"`
+	SecondSurname  string    `json:"second_surname,omitempty"`
+	FirstName      string    `json:"first_name"`
+	OtherNames     string    `json:"other_names,omitempty"`
+	CityCode       string    `json:"city_code,omitempty"` // Código DIVIPOLA
+	Address        string    `json:"address,omitempty"`
+	ContractType   string    `json:"contract_type"` // 1 = término fijo, 2 = indefinido, ...
+	Salary         int       `json:"salary_cop"`    // Salario mensual
+	IntegralSalary bool      `json:"integral_salary"`
+	HireDate       time.Time `json:"hire_date"`
+	Code           string    `json:"code,omitempty"`
+}
+
+// PayrollItem devengado o deducción adicional
+type PayrollItem struct {
+	Description string `json:"description"`
+	Amount      int    `json:"amount_cop"`
+	Salary      bool   `json:"salary"` // Constitutivo de salario (suma al IBC)
+}
+
+// Payroll documento soporte de pago de nómina electrónica individual (Resolución 000013 de 2021)
+type Payroll struct {
+	Prefix             string          `json:"prefix"`
+	Number             int64           `json:"number"`
+	IssueDate          time.Time       `json:"issue_date"`
+	PeriodStart        time.Time       `json:"period_start"`
+	PeriodEnd          time.Time       `json:"period_end"`
+	PaymentDate        time.Time       `json:"payment_date"`
+	PayrollPeriod      string          `json:"payroll_period"` // 4 = quincenal, 5 = mensual
+	Employer           Party           `json:"employer"`
+	Employee           PayrollEmployee `json:"employee"`
+	DaysWorked         int             `json:"days_worked"`
+	TransportAllowance int             `json:"transport_allowance_cop"`
+	OtherEarnings      []PayrollItem   `json:"other_earnings,omitempty"`
+	OtherDeductions    []PayrollItem   `json:"other_deductions,omitempty"`
+	PaymentMethod      string          `json:"payment_method"` // Código DIAN de medio de pago
+
+	// Valores calculados por Compute
+	BasicPay        int `json:"basic_pay_cop"`
+	HealthAmount    int `json:"health_cop"`
+	PensionAmount   int `json:"pension_cop"`
+	EarningsTotal   int `json:"earnings_total_cop"`
+	DeductionsTotal int `json:"deductions_total_cop"`
+	NetTotal        int `json:"net_total_cop"`
+}
+
+// Compute liquida el salario básico, los aportes del trabajador y los totales
+func (p *Payroll) Compute() {
+	p.BasicPay = roundCOP(float64(p.Employee.Salary) * float64(p.DaysWorked) / 30)
+
+	ibc := p.BasicPay
+	p.EarningsTotal = p.BasicPay + p.TransportAllowance
+	for _, item := range p.OtherEarnings {
+		p.EarningsTotal += item.Amount
+		if item.Salary {
+			ibc += item.Amount
+		}
+	}
+	if p.Employee.IntegralSalary {
+		// El salario integral cotiza sobre el 70%
+		ibc = roundCOP(float64(ibc) * 0.7)
+	}
+
+	p.HealthAmount = roundCOP(float64(ibc) * PayrollHealthRate / 100)
+	p.PensionAmount = roundCOP(float64(ibc) * PayrollPensionRate / 100)
+	p.DeductionsTotal = p.HealthAmount + p.PensionAmount
+	for _, item := range p.OtherDeductions {
+		p.DeductionsTotal += item.Amount
+	}
+
+	p.NetTotal = p.EarningsTotal - p.DeductionsTotal
+}
+
+// Kind implementa Document
+func (p *Payroll) Kind() DocumentKind { return KindPayroll }
+
+// FullNumber implementa Document
+func (p *Payroll) FullNumber() string { return fmt.Sprintf("%s%d", p.Prefix, p.Number) }
+
+// IssuerNIT implementa Document
+func (p *Payroll) IssuerNIT() string { return onlyDigits(p.Employer.ID) }
+
+// IssuedAt implementa Document
+func (p *Payroll) IssuedAt() time.Time { return p.IssueDate }
+
+// FilePrefix implementa Document
+func (p *Payroll) FilePrefix() string { return "nie" }
+
+// DocumentKey calcula el CUNE:
+// SHA-384(NumNE + FecNE + HorNE + ValDev + ValDed + ValTolNE + NitNE + DocEmp + TipoXML + Software-PIN + TipAmb)
+func (p *Payroll) DocumentKey(softwarePIN string, env Environment) string {
+	issued := bogotaTime(p.IssueDate)
+	return sha384Hex(
+		p.FullNumber(),
+		issued.Format("2006-01-02"),
+		issued.Format("15:04:05-07:00"),
+		money(p.EarningsTotal),
+		money(p.DeductionsTotal),
+		money(p.NetTotal),
+		p.IssuerNIT(),
+		onlyDigits(p.Employee.ID),
+		PayrollTypeCode,
+		softwarePIN,
+		env.Code(),
+	)
+}
+
+// RenderXML genera el XML NominaIndividual del documento
+func (p *Payroll) RenderXML(meta RenderMeta) ([]byte, error) {
+	issued := bogotaTime(p.IssueDate)
+	worked := 0
+	if !p.Employee.HireDate.IsZero() {
+		worked = int(p.PeriodEnd.Sub(p.Employee.HireDate).Hours() / 24)
+	}
+
+	// El código DIVIPOLA del municipio inicia con los dos dígitos del departamento
+	department := ""
+	if len(p.Employer.CityCode) >= 2 {
+		department = p.Employer.CityCode[:2]
+	}
+
+	var buf bytes.Buffer
+	data := map[string]interface{}{
+		"Payroll":            p,
+		"Meta":               meta,
+		"Issued":             issued,
+		"TypeCode":           PayrollTypeCode,
+		"TimeWorked":         worked,
+		"HealthRate":         PayrollHealthRate,
+		"PensionRate":        PayrollPensionRate,
+		"EmployerCity":       p.Employer.CityCode,
+		"EmployerDepartment": department,
+	}
+	if err := payrollTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error generando XML de nómina: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+var payrollTemplate = template.Must(template.New("payroll").Funcs(templateFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<NominaIndividual xmlns="dian:gov:co:facturaelectronica:NominaIndividual" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2" SchemaLocation="" xmlns:xs="http://www.w3.org/2001/XMLSchema-instance">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent></ext:ExtensionContent>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <Novedad CUNENov="">false</Novedad>
+  <Periodo FechaIngreso="{{date .Payroll.Employee.HireDate}}" FechaLiquidacionInicio="{{date .Payroll.PeriodStart}}" FechaLiquidacionFin="{{date .Payroll.PeriodEnd}}" TiempoLaborado="{{.TimeWorked}}" FechaGen="{{date .Issued}}"/>
+  <NumeroSecuenciaXML Prefijo="{{x .Payroll.Prefix}}" Consecutivo="{{.Payroll.Number}}" Numero="{{x .Payroll.FullNumber}}"/>
+  <LugarGeneracionXML Pais="CO" DepartamentoEstado="{{.EmployerDepartment}}" MunicipioCiudad="{{.EmployerCity}}" Idioma="es"/>
+  <ProveedorXML RazonSocial="{{x .Payroll.Employer.Name}}" NIT="{{x .Payroll.IssuerNIT}}" DV="{{x .Payroll.Employer.CheckDigit}}" SoftwareID="{{x .Meta.SoftwareID}}" SoftwareSC="{{.Meta.SoftwareCode}}"/>
+  <CodigoQR>{{x .Meta.QRCode}}</CodigoQR>
+  <InformacionGeneral Version="V1.0: Documento Soporte de Pago de Nómina Electrónica" Ambiente="{{.Meta.Environment.Code}}" TipoXML="{{.TypeCode}}" CUNE="{{.Meta.DocumentKey}}" EncripCUNE="CUNE-SHA384" FechaGen="{{date .Issued}}" HoraGen="{{time .Issued}}" PeriodoNomina="{{x .Payroll.PayrollPeriod}}" TipoMoneda="COP"/>
+  <Empleador RazonSocial="{{x .Payroll.Employer.Name}}" NIT="{{x .Payroll.IssuerNIT}}" DV="{{x .Payroll.Employer.CheckDigit}}" Pais="CO" DepartamentoEstado="{{.EmployerDepartment}}" MunicipioCiudad="{{.EmployerCity}}" Direccion="{{x .Payroll.Employer.Address}}"/>
+  <Trabajador TipoTrabajador="{{x .Payroll.Employee.WorkerType}}" SubTipoTrabajador="{{x .Payroll.Employee.WorkerSubType}}" AltoRiesgoPension="false" TipoDocumento="{{x .Payroll.Employee.DocumentType}}" NumeroDocumento="{{x .Payroll.Employee.ID}}" PrimerApellido="{{x .Payroll.Employee.FirstSurname}}" SegundoApellido="{{x .Payroll.Employee.SecondSurname}}" PrimerNombre="{{x .Payroll.Employee.FirstName}}" OtrosNombres="{{x .Payroll.Employee.OtherNames}}" LugarTrabajoPais="CO" LugarTrabajoDepartamentoEstado="{{.EmployerDepartment}}" LugarTrabajoMunicipioCiudad="{{.EmployerCity}}" LugarTrabajoDireccion="{{x .Payroll.Employee.Address}}" SalarioIntegral="{{.Payroll.Employee.IntegralSalary}}" TipoContrato="{{x .Payroll.Employee.ContractType}}" Sueldo="{{money .Payroll.Employee.Salary}}" CodigoTrabajador="{{x .Payroll.Employee.Code}}"/>
+  <Pago Forma="1" Metodo="{{x .Payroll.PaymentMethod}}"/>
+  <FechasPagos>
+    <FechaPago>{{date .Payroll.PaymentDate}}</FechaPago>
+  </FechasPagos>
+  <Devengados>
+    <Basico DiasTrabajados="{{.Payroll.DaysWorked}}" SueldoTrabajado="{{money .Payroll.BasicPay}}"/>
+{{- if .Payroll.TransportAllowance}}
+    <Transporte AuxilioTransporte="{{money .Payroll.TransportAllowance}}"/>
+{{- end}}
+{{- if .Payroll.OtherEarnings}}
+    <OtrosConceptos>
+{{- range .Payroll.OtherEarnings}}
+      <OtroConcepto DescripcionConcepto="{{x .Description}}"{{if .Salary}} ConceptoS="{{money .Amount}}"{{else}} ConceptoNS="{{money .Amount}}"{{end}}/>
+{{- end}}
+    </OtrosConceptos>
+{{- end}}
+  </Devengados>
+  <Deducciones>
+    <Salud Porcentaje="{{percent .HealthRate}}" Deduccion="{{money .Payroll.HealthAmount}}"/>
+    <FondoPension Porcentaje="{{percent .PensionRate}}" Deduccion="{{money .Payroll.PensionAmount}}"/>
+{{- if .Payroll.OtherDeductions}}
+    <OtrasDeducciones>
+{{- range .Payroll.OtherDeductions}}
+      <OtraDeduccion>{{money .Amount}}</OtraDeduccion>
+{{- end}}
+    </OtrasDeducciones>
+{{- end}}
+  </Deducciones>
+  <DevengadosTotal>{{money .Payroll.EarningsTotal}}</DevengadosTotal>
+  <DeduccionesTotal>{{money .Payroll.DeductionsTotal}}</DeduccionesTotal>
+  <ComprobanteTotal>{{money .Payroll.NetTotal}}</ComprobanteTotal>
+</NominaIndividual>
+`))
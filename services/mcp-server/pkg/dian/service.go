@@ -0,0 +1,458 @@
+package dian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TenantConfig configuración DIAN de un facturador (tenant)
+type TenantConfig struct {
+	TenantID    string
+	Environment Environment
+
+	// Software propio registrado ante la DIAN
+	SoftwareID  string
+	SoftwarePIN string
+
+	// Resolución de numeración de facturas
+	InvoiceNumbering NumberingRange
+	// Resolución de numeración de documentos soporte
+	SupportNumbering NumberingRange
+	// Numeración de nómina electrónica (definida por el empleador)
+	PayrollNumbering NumberingRange
+	// Numeración de notas crédito (definida por el facturador)
+	CreditNoteNumbering NumberingRange
+}
+
+// Submission resultado del envío de un documento a la DIAN
+type Submission struct {
+	ID          string        `json:"id"`
+	TenantID    string        `json:"tenant_id"`
+	Kind        DocumentKind  `json:"kind"`
+	Number      string        `json:"number"`
+	DocumentKey string        `json:"document_key"` // CUFE/CUDE/CUDS/CUNE
+	Environment Environment   `json:"environment"`
+	TrackID     string        `json:"track_id,omitempty"`
+	Status      string        `json:"status"` // approved, rejected, pending
+	Response    *DianResponse `json:"dian_response,omitempty"`
+	FileName    string        `json:"file_name"`
+	QRCode      string        `json:"qr_code"`
+	SubmittedAt time.Time     `json:"submitted_at"`
+	Document    Document      `json:"document"`
+	XML         []byte        `json:"-"`
+}
+
+// Estados de un envío
+const (
+	SubmissionApproved = "approved"
+	SubmissionRejected = "rejected"
+	SubmissionPending  = "pending"
+)
+
+// ErrEnvironmentUnsupported solo se emiten documentos en el simulador. Los
+// ambientes de habilitación y producción exigen documentos firmados con
+// XAdES-EPES, que el servicio aún no genera, y no hay cliente para ellos.
+var ErrEnvironmentUnsupported = errors.New("solo se pueden emitir documentos en el simulador: el envío a la DIAN (habilitación y producción) requiere la firma XAdES-EPES, que aún no está soportada")
+
+// Service emite documentos electrónicos y los envía a la DIAN o al simulador
+type Service struct {
+	simulator *Simulator
+	sequencer *Sequencer
+
+	mu           sync.RWMutex
+	submissions  map[string]*Submission
+	reserved     map[string]int // Notas crédito en envío por factura (ID del envío)
+	fileSequence int64
+}
+
+// NewService crea el servicio DIAN con un simulador compartido para sandbox
+func NewService(simulator *Simulator) *Service {
+	if simulator == nil {
+		simulator = NewSimulator()
+	}
+	return &Service{
+		simulator:   simulator,
+		sequencer:   NewSequencer(),
+		submissions: make(map[string]*Submission),
+		reserved:    make(map[string]int),
+	}
+}
+
+// WithSequenceStore guarda los consecutivos en store en lugar de la memoria
+// del proceso. Sin él un reinicio o una segunda réplica repiten números.
+func (s *Service) WithSequenceStore(store SequenceStore) *Service {
+	s.sequencer = NewStoredSequencer(store)
+	return s
+}
+
+// checkEnvironment rechaza los ambientes de la DIAN, que exigen documentos
+// firmados. Se valida antes de numerar para no consumir consecutivos.
+func checkEnvironment(cfg TenantConfig) error {
+	if cfg.Environment == EnvironmentSimulator || cfg.Environment == "" {
+		return nil
+	}
+	return fmt.Errorf("%w (ambiente %s)", ErrEnvironmentUnsupported, cfg.Environment)
+}
+
+// ClientFor retorna el cliente DIAN del tenant; solo existe el simulador
+func (s *Service) ClientFor(cfg TenantConfig) (Client, error) {
+	if err := checkEnvironment(cfg); err != nil {
+		return nil, err
+	}
+	return s.simulator, nil
+}
+
+// IssueInvoice numera, genera, empaqueta y envía una factura electrónica
+func (s *Service) IssueInvoice(ctx context.Context, cfg TenantConfig, invoice *Invoice) (*Submission, error) {
+	if err := checkEnvironment(cfg); err != nil {
+		return nil, err
+	}
+	numbering := cfg.InvoiceNumbering
+	if numbering.Prefix == "" {
+		numbering = TestNumberingRange
+	}
+
+	if invoice.IssueDate.IsZero() {
+		invoice.IssueDate = time.Now()
+	}
+	number, err := s.sequencer.Next(cfg.TenantID, numbering, invoice.IssueDate)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Prefix = numbering.Prefix
+	invoice.Number = number
+	invoice.Compute()
+
+	return s.Submit(ctx, cfg, invoice, numbering, numbering.TechnicalKey)
+}
+
+// IssueSupportDocument numera y envía un documento soporte de adquisiciones a no obligados
+func (s *Service) IssueSupportDocument(ctx context.Context, cfg TenantConfig, doc *SupportDocument) (*Submission, error) {
+	if err := checkEnvironment(cfg); err != nil {
+		return nil, err
+	}
+	numbering := cfg.SupportNumbering
+	if numbering.Prefix == "" {
+		numbering = TestSupportNumberingRange
+	}
+
+	if doc.IssueDate.IsZero() {
+		doc.IssueDate = time.Now()
+	}
+	number, err := s.sequencer.Next(cfg.TenantID, numbering, doc.IssueDate)
+	if err != nil {
+		return nil, err
+	}
+	doc.Prefix = numbering.Prefix
+	doc.Number = number
+	doc.Compute()
+
+	// El CUDS usa el PIN del software en lugar de la clave técnica
+	return s.Submit(ctx, cfg, doc, numbering, cfg.SoftwarePIN)
+}
+
+// IssuePayroll numera y envía un documento de nómina electrónica individual
+func (s *Service) IssuePayroll(ctx context.Context, cfg TenantConfig, payroll *Payroll) (*Submission, error) {
+	if err := checkEnvironment(cfg); err != nil {
+		return nil, err
+	}
+	numbering := cfg.PayrollNumbering
+	if numbering.Prefix == "" {
+		numbering = DefaultPayrollNumbering
+	}
+
+	if payroll.IssueDate.IsZero() {
+		payroll.IssueDate = time.Now()
+	}
+	number, err := s.sequencer.Next(cfg.TenantID, numbering, payroll.IssueDate)
+	if err != nil {
+		return nil, err
+	}
+	payroll.Prefix = numbering.Prefix
+	payroll.Number = number
+	payroll.Compute()
+
+	// El CUNE usa el PIN del software en lugar de la clave técnica
+	return s.Submit(ctx, cfg, payroll, numbering, cfg.SoftwarePIN)
+}
+
+// IssueCreditNote numera y envía una nota crédito sobre una factura aprobada
+// del tenant. Emisor, adquirente, medio de pago y referencia se toman de la
+// factura; la suma de las notas crédito no puede superar su total.
+func (s *Service) IssueCreditNote(ctx context.Context, cfg TenantConfig, invoiceID string, note *CreditNote) (*Submission, error) {
+	if err := checkEnvironment(cfg); err != nil {
+		return nil, err
+	}
+	submission, err := s.GetSubmission(cfg.TenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	invoice, ok := submission.Document.(*Invoice)
+	if !ok {
+		return nil, fmt.Errorf("el documento %s no es una factura de venta", submission.Number)
+	}
+	if submission.Status != SubmissionApproved {
+		return nil, fmt.Errorf("la factura %s no está aprobada por la DIAN (%s)", submission.Number, submission.Status)
+	}
+	if len(note.Lines) == 0 {
+		return nil, fmt.Errorf("la nota crédito debe tener al menos una línea")
+	}
+
+	note.Issuer = invoice.Issuer
+	note.Customer = invoice.Customer
+	note.Invoice = InvoiceReference{
+		SubmissionID: submission.ID,
+		Number:       submission.Number,
+		CUFE:         submission.DocumentKey,
+		IssueDate:    invoice.IssueDate,
+	}
+	if note.PaymentMethod == "" {
+		note.PaymentMethod = invoice.PaymentMethod
+	}
+	if note.ReasonCode == "" {
+		note.ReasonCode = CreditReasonPartialReturn
+	}
+	note.Compute()
+	if err := s.reserveCredit(cfg.TenantID, submission, invoice, note.Total); err != nil {
+		return nil, err
+	}
+	// La reserva se libera al terminar: si la nota quedó registrada ya cuenta
+	// en el saldo, y si fue rechazada o falló el envío el monto vuelve a estar
+	// disponible
+	defer s.releaseCredit(submission.ID, note.Total)
+
+	numbering := cfg.CreditNoteNumbering
+	if numbering.Prefix == "" {
+		numbering = DefaultCreditNoteNumbering
+	}
+	if note.IssueDate.IsZero() {
+		note.IssueDate = time.Now()
+	}
+	number, err := s.sequencer.Next(cfg.TenantID, numbering, note.IssueDate)
+	if err != nil {
+		return nil, err
+	}
+	note.Prefix = numbering.Prefix
+	note.Number = number
+
+	// El CUDE usa el PIN del software en lugar de la clave técnica
+	return s.Submit(ctx, cfg, note, numbering, cfg.SoftwarePIN)
+}
+
+// CreditBalance saldo de una factura que aún puede acreditarse con notas crédito
+func (s *Service) CreditBalance(tenantID, invoiceID string) (int, error) {
+	submission, err := s.GetSubmission(tenantID, invoiceID)
+	if err != nil {
+		return 0, err
+	}
+	invoice, ok := submission.Document.(*Invoice)
+	if !ok {
+		return 0, fmt.Errorf("el documento %s no es una factura de venta", submission.Number)
+	}
+	return invoice.Total - s.creditedTotal(tenantID, invoiceID), nil
+}
+
+// reserveCredit aparta el monto de una nota crédito del saldo de la factura
+// mientras se envía, para que dos notas simultáneas no superen su total
+func (s *Service) reserveCredit(tenantID string, submission *Submission, invoice *Invoice, amount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if credited := s.creditedTotalLocked(tenantID, submission.ID); credited+amount > invoice.Total {
+		return fmt.Errorf("la nota crédito por $%d supera el saldo de la factura %s ($%d de $%d ya acreditados)",
+			amount, submission.Number, credited, invoice.Total)
+	}
+	s.reserved[submission.ID] += amount
+	return nil
+}
+
+// releaseCredit libera el monto reservado por reserveCredit
+func (s *Service) releaseCredit(invoiceID string, amount int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reserved[invoiceID] -= amount; s.reserved[invoiceID] <= 0 {
+		delete(s.reserved, invoiceID)
+	}
+}
+
+// creditedTotal suma de las notas crédito no rechazadas de una factura,
+// incluidas las que están en envío
+func (s *Service) creditedTotal(tenantID, invoiceID string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.creditedTotalLocked(tenantID, invoiceID)
+}
+
+// creditedTotalLocked como creditedTotal; requiere s.mu tomado
+func (s *Service) creditedTotalLocked(tenantID, invoiceID string) int {
+	total := s.reserved[invoiceID]
+	for _, submission := range s.submissions {
+		note, ok := submission.Document.(*CreditNote)
+		if ok && submission.TenantID == tenantID && note.Invoice.SubmissionID == invoiceID && submission.Status != SubmissionRejected {
+			total += note.Total
+		}
+	}
+	return total
+}
+
+// Submit genera el XML de un documento ya numerado y lo envía a la DIAN.
+// secret es la clave usada para el código único (clave técnica o PIN del software).
+// Mientras no se firmen los documentos solo acepta el ambiente simulador.
+func (s *Service) Submit(ctx context.Context, cfg TenantConfig, doc Document, numbering NumberingRange, secret string) (*Submission, error) {
+	client, err := s.ClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	env := cfg.Environment
+	if env == "" {
+		env = EnvironmentSimulator
+	}
+
+	documentKey := doc.DocumentKey(secret, env)
+	meta := RenderMeta{
+		Environment:  env,
+		SoftwareID:   cfg.SoftwareID,
+		SoftwareCode: softwareSecurityCode(cfg.SoftwareID, cfg.SoftwarePIN, doc.FullNumber()),
+		DocumentKey:  documentKey,
+		Numbering:    numbering,
+		QRCode:       qrURL(env, documentKey),
+	}
+
+	content, err := doc.RenderXML(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	sequence := s.nextFileSequence()
+	year := doc.IssuedAt().Year()
+	xmlName := XMLFileName(doc.FilePrefix(), doc.IssuerNIT(), year, sequence)
+	zipName := ZipFileName(doc.IssuerNIT(), year, sequence)
+	zipContent, err := PackageZip(xmlName, content)
+	if err != nil {
+		return nil, err
+	}
+
+	submission := &Submission{
+		ID:          uuid.New().String(),
+		TenantID:    cfg.TenantID,
+		Kind:        doc.Kind(),
+		Number:      doc.FullNumber(),
+		DocumentKey: documentKey,
+		Environment: env,
+		FileName:    zipName,
+		QRCode:      meta.QRCode,
+		SubmittedAt: time.Now(),
+		Document:    doc,
+		XML:         content,
+	}
+
+	send := client.SendBillSync
+	if doc.Kind() == KindPayroll {
+		send = client.SendNominaSync
+	}
+	response, err := send(ctx, zipName, zipContent)
+	if err != nil {
+		return nil, fmt.Errorf("error enviando documento a la DIAN: %w", err)
+	}
+	submission.Response = response
+	submission.TrackID = documentKey
+	submission.Status = statusFromResponse(response)
+
+	s.mu.Lock()
+	s.submissions[submission.ID] = submission
+	s.mu.Unlock()
+
+	return submission, nil
+}
+
+// RefreshStatus consulta a la DIAN el estado de un envío pendiente
+func (s *Service) RefreshStatus(ctx context.Context, cfg TenantConfig, submissionID string) (*Submission, error) {
+	submission, err := s.GetSubmission(cfg.TenantID, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	if submission.TrackID == "" {
+		return submission, nil
+	}
+
+	client, err := s.ClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.GetStatus(ctx, submission.TrackID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando estado en la DIAN: %w", err)
+	}
+
+	s.mu.Lock()
+	submission.Response = response
+	submission.Status = statusFromResponse(response)
+	s.mu.Unlock()
+
+	return submission, nil
+}
+
+// GetSubmission obtiene un envío del tenant
+func (s *Service) GetSubmission(tenantID, submissionID string) (*Submission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	submission, ok := s.submissions[submissionID]
+	if !ok || submission.TenantID != tenantID {
+		return nil, fmt.Errorf("documento %s no encontrado", submissionID)
+	}
+	return submission, nil
+}
+
+// ListSubmissions lista los envíos de un tenant ordenados por fecha
+func (s *Service) ListSubmissions(tenantID string) []*Submission {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*Submission
+	for _, submission := range s.submissions {
+		if submission.TenantID == tenantID {
+			result = append(result, submission)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SubmittedAt.Before(result[j].SubmittedAt)
+	})
+	return result
+}
+
+// nextFileSequence consecutivo de archivos enviados (parte del nombre del ZIP)
+func (s *Service) nextFileSequence() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fileSequence++
+	return s.fileSequence
+}
+
+func statusFromResponse(response *DianResponse) string {
+	switch {
+	case response.IsValid:
+		return SubmissionApproved
+	case response.StatusCode == StatusNotFound:
+		return SubmissionPending
+	default:
+		return SubmissionRejected
+	}
+}
+
+func qrURL(env Environment, documentKey string) string {
+	host := "https://catalogo-vpfe-hab.dian.gov.co"
+	if env == EnvironmentProduccion {
+		host = "https://catalogo-vpfe.dian.gov.co"
+	}
+	return host + "/document/searchqr?documentkey=" + url.QueryEscape(documentKey)
+}
@@ -0,0 +1,118 @@
+package dian
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func sandboxConfig() TenantConfig {
+	return TenantConfig{TenantID: "tenant-prueba", Environment: EnvironmentSimulator, SoftwarePIN: "12345"}
+}
+
+func testInvoice() *Invoice {
+	return &Invoice{
+		IssueDate:     time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC),
+		Issuer:        Party{DocumentType: "31", ID: "900123456", CheckDigit: "8", Name: "Café Ejemplo SAS"},
+		Customer:      Party{DocumentType: "13", ID: "1020304050", Name: "Ana Gómez"},
+		Lines:         []InvoiceLine{{Description: "Café de origen 500 g", Quantity: 4, UnitPrice: 25000, IVARate: 19}},
+		PaymentMethod: "10",
+		PaymentForm:   "1",
+	}
+}
+
+func issueApprovedInvoice(t *testing.T, service *Service) *Submission {
+	t.Helper()
+	submission, err := service.IssueInvoice(context.Background(), sandboxConfig(), testInvoice())
+	if err != nil {
+		t.Fatalf("IssueInvoice() error = %v", err)
+	}
+	if submission.Status != SubmissionApproved {
+		t.Fatalf("IssueInvoice() status = %s, response = %+v", submission.Status, submission.Response)
+	}
+	return submission
+}
+
+func TestIssueCreditNoteReservesBalanceWhileSubmitting(t *testing.T) {
+	service := NewService(nil)
+	invoice := issueApprovedInvoice(t, service)
+	total := invoice.Document.(*Invoice).Total
+
+	// El simulador detiene el primer envío hasta que se intente la segunda nota
+	entered, release := make(chan struct{}), make(chan struct{})
+	var calls int32
+	service.simulator.now = func() time.Time {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(entered)
+			<-release
+		}
+		return time.Now()
+	}
+
+	newNote := func() *CreditNote {
+		// Más de la mitad de la factura: dos notas no caben
+		return &CreditNote{Lines: []InvoiceLine{{Description: "Devolución parcial", Quantity: 3, UnitPrice: 25000, IVARate: 19}}}
+	}
+
+	type result struct {
+		submission *Submission
+		err        error
+	}
+	first := make(chan result, 1)
+	go func() {
+		submission, err := service.IssueCreditNote(context.Background(), sandboxConfig(), invoice.ID, newNote())
+		first <- result{submission, err}
+	}()
+	<-entered
+
+	if _, err := service.IssueCreditNote(context.Background(), sandboxConfig(), invoice.ID, newNote()); err == nil {
+		t.Error("IssueCreditNote() aceptó una nota que supera el saldo reservado por otra en envío")
+	}
+	close(release)
+
+	r := <-first
+	if r.err != nil {
+		t.Fatalf("IssueCreditNote() error = %v", r.err)
+	}
+	if r.submission.Status != SubmissionApproved {
+		t.Fatalf("IssueCreditNote() status = %s", r.submission.Status)
+	}
+	balance, err := service.CreditBalance(sandboxConfig().TenantID, invoice.ID)
+	if err != nil {
+		t.Fatalf("CreditBalance() error = %v", err)
+	}
+	if want := total - r.submission.Document.(*CreditNote).Total; balance != want {
+		t.Errorf("CreditBalance() = %d, want %d", balance, want)
+	}
+}
+
+func TestIssueCreditNoteReleasesReservationOnFailure(t *testing.T) {
+	service := NewService(nil)
+	invoice := issueApprovedInvoice(t, service)
+	total := invoice.Document.(*Invoice).Total
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	note := &CreditNote{Lines: []InvoiceLine{{Description: "Anulación", Quantity: 4, UnitPrice: 25000, IVARate: 19}}}
+	if _, err := service.IssueCreditNote(ctx, sandboxConfig(), invoice.ID, note); err == nil {
+		t.Fatal("IssueCreditNote() con el contexto cancelado no retornó error")
+	}
+
+	balance, err := service.CreditBalance(sandboxConfig().TenantID, invoice.ID)
+	if err != nil {
+		t.Fatalf("CreditBalance() error = %v", err)
+	}
+	if balance != total {
+		t.Errorf("CreditBalance() = %d, want %d (la reserva no se liberó)", balance, total)
+	}
+
+	note = &CreditNote{Lines: []InvoiceLine{{Description: "Anulación", Quantity: 4, UnitPrice: 25000, IVARate: 19}}}
+	submission, err := service.IssueCreditNote(context.Background(), sandboxConfig(), invoice.ID, note)
+	if err != nil {
+		t.Fatalf("IssueCreditNote() error = %v", err)
+	}
+	if submission.Status != SubmissionApproved {
+		t.Errorf("IssueCreditNote() status = %s", submission.Status)
+	}
+}
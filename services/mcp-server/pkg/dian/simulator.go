@@ -0,0 +1,319 @@
+package dian
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Simulator implementación en memoria de los servicios de la DIAN.
+// Se usa en pruebas y para tenants en sandbox: valida el empaquetado y la
+// estructura mínima del documento y responde con un ApplicationResponse.
+type Simulator struct {
+	mu        sync.Mutex
+	documents map[string]*DianResponse // por trackId y por código del documento
+	now       func() time.Time
+}
+
+// NewSimulator crea un simulador de la DIAN
+func NewSimulator() *Simulator {
+	return &Simulator{
+		documents: make(map[string]*DianResponse),
+		now:       time.Now,
+	}
+}
+
+// SendBillSync valida el documento y responde de forma síncrona
+func (s *Simulator) SendBillSync(ctx context.Context, fileName string, zipContent []byte) (*DianResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	response := s.validate(fileName, zipContent)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if response.XMLDocumentKey != "" {
+		if existing, ok := s.documents[response.XMLDocumentKey]; ok && existing.IsValid {
+			// La DIAN rechaza documentos ya procesados (regla 90)
+			return &DianResponse{
+				IsValid:           false,
+				StatusCode:        StatusRejected,
+				StatusDescription: "Documento con errores en campos mandatorios.",
+				StatusMessage:     "Documento procesado anteriormente.",
+				ErrorMessages:     []string{"Regla: 90, Rechazo: Documento procesado anteriormente."},
+				XMLDocumentKey:    response.XMLDocumentKey,
+				XMLFileName:       response.XMLFileName,
+			}, nil
+		}
+		s.documents[response.XMLDocumentKey] = response
+	}
+
+	return response, nil
+}
+
+// SendNominaSync valida un documento de nómina electrónica de forma síncrona
+func (s *Simulator) SendNominaSync(ctx context.Context, fileName string, zipContent []byte) (*DianResponse, error) {
+	return s.SendBillSync(ctx, fileName, zipContent)
+}
+
+// SendTestSetAsync registra el documento y retorna un ZipKey para consultar luego
+func (s *Simulator) SendTestSetAsync(ctx context.Context, fileName string, zipContent []byte, testSetID string) (*UploadResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if testSetID == "" {
+		return &UploadResponse{ErrorMessages: []string{"TestSetId requerido"}}, nil
+	}
+
+	response := s.validate(fileName, zipContent)
+	zipKey := uuid.New().String()
+
+	s.mu.Lock()
+	s.documents[zipKey] = response
+	if response.XMLDocumentKey != "" {
+		s.documents[response.XMLDocumentKey] = response
+	}
+	s.mu.Unlock()
+
+	return &UploadResponse{ZipKey: zipKey}, nil
+}
+
+// GetStatus retorna el resultado de un documento enviado
+func (s *Simulator) GetStatus(ctx context.Context, trackID string) (*DianResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response, ok := s.documents[trackID]
+	if !ok {
+		return &DianResponse{
+			IsValid:           false,
+			StatusCode:        StatusNotFound,
+			StatusDescription: "TrackId no encontrado",
+			StatusMessage:     fmt.Sprintf("TrackId no existe en los registros de la DIAN: %s", trackID),
+		}, nil
+	}
+
+	copied := *response
+	return &copied, nil
+}
+
+// GetStatusZip retorna el ApplicationResponse empaquetado en ZIP
+func (s *Simulator) GetStatusZip(ctx context.Context, trackID string) ([]byte, error) {
+	response, err := s.GetStatus(ctx, trackID)
+	if err != nil {
+		return nil, err
+	}
+	if response.XMLBase64Bytes == "" {
+		return nil, fmt.Errorf("TrackId no encontrado: %s", trackID)
+	}
+
+	content, err := base64.StdEncoding.DecodeString(response.XMLBase64Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return PackageZip("ar"+strings.TrimSuffix(response.XMLFileName, ".xml"), content)
+}
+
+// validate aplica las validaciones que el simulador puede verificar sin la firma XAdES
+func (s *Simulator) validate(fileName string, zipContent []byte) *DianResponse {
+	reject := func(xmlName, key string, messages ...string) *DianResponse {
+		return &DianResponse{
+			IsValid:           false,
+			StatusCode:        StatusRejected,
+			StatusDescription: "Validación contiene errores en campos mandatorios.",
+			StatusMessage:     "Documento con errores en campos mandatorios.",
+			ErrorMessages:     messages,
+			XMLDocumentKey:    key,
+			XMLFileName:       xmlName,
+		}
+	}
+
+	files, err := UnpackZip(zipContent)
+	if err != nil {
+		return reject("", "", "Regla: ZIP, Rechazo: El archivo no es un ZIP válido.")
+	}
+	if len(files) != 1 {
+		return reject("", "", "Regla: ZIP, Rechazo: El ZIP debe contener un único documento XML.")
+	}
+
+	var xmlName string
+	var content []byte
+	for name, data := range files {
+		xmlName, content = name, data
+	}
+
+	header, err := parseDocumentHeader(content)
+	if err != nil {
+		return reject(xmlName, "", "Regla: XML, Rechazo: El documento no es un XML bien formado.")
+	}
+
+	var problems []string
+	if header.ID == "" {
+		problems = append(problems, "Regla: FAD05, Rechazo: Número del documento no informado.")
+	}
+	if len(header.UUID) != 96 {
+		problems = append(problems, fmt.Sprintf("Regla: FAD06, Rechazo: Valor del %s no está calculado correctamente.", header.UUIDScheme))
+	}
+	if header.IssueDate == "" {
+		problems = append(problems, "Regla: FAD09, Rechazo: Fecha de emisión no informada.")
+	}
+	if len(problems) > 0 {
+		return reject(xmlName, header.UUID, problems...)
+	}
+
+	now := bogotaTime(s.now())
+	appResponse, err := renderApplicationResponse(applicationResponseData{
+		ID:           fmt.Sprintf("%d", now.UnixNano()),
+		UUID:         sha384Hex(header.UUID, now.Format(time.RFC3339Nano)),
+		IssueDate:    now.Format("2006-01-02"),
+		IssueTime:    now.Format("15:04:05-07:00"),
+		ResponseCode: "02",
+		Description:  "Documento validado por la DIAN",
+		DocumentID:   header.ID,
+		DocumentUUID: header.UUID,
+		UUIDScheme:   header.UUIDScheme,
+	})
+	if err != nil {
+		return reject(xmlName, header.UUID, "Error interno del simulador: "+err.Error())
+	}
+
+	return &DianResponse{
+		IsValid:           true,
+		StatusCode:        StatusProcessed,
+		StatusDescription: "Procesado Correctamente.",
+		StatusMessage:     fmt.Sprintf("La %s %s, ha sido autorizada.", header.documentName(), header.ID),
+		XMLBase64Bytes:    base64.StdEncoding.EncodeToString(appResponse),
+		XMLDocumentKey:    header.UUID,
+		XMLFileName:       xmlName,
+		ApplicationResponse: &ApplicationResponse{
+			ResponseCode: "02",
+			Description:  "Documento validado por la DIAN",
+			DocumentID:   header.ID,
+			DocumentUUID: header.UUID,
+			IssueDate:    now.Format("2006-01-02"),
+			IssueTime:    now.Format("15:04:05-07:00"),
+		},
+	}
+}
+
+// documentHeader campos comunes de los documentos UBL y de nómina
+type documentHeader struct {
+	XMLName    xml.Name
+	ID         string
+	UUID       string
+	UUIDScheme string
+	IssueDate  string
+	TypeCode   string
+}
+
+func (h documentHeader) documentName() string {
+	switch h.XMLName.Local {
+	case "CreditNote":
+		return "Nota Crédito"
+	case "DebitNote":
+		return "Nota Débito"
+	case "NominaIndividual":
+		return "Nómina Individual"
+	case "NominaIndividualDeAjuste":
+		return "Nómina Individual de Ajuste"
+	}
+	if h.TypeCode == SupportDocumentTypeCode {
+		return "Documento Soporte"
+	}
+	return "Factura electrónica"
+}
+
+func parseDocumentHeader(content []byte) (*documentHeader, error) {
+	var raw struct {
+		XMLName xml.Name
+		ID      string `xml:"ID"`
+		UUID    struct {
+			Value      string `xml:",chardata"`
+			SchemeName string `xml:"schemeName,attr"`
+		} `xml:"UUID"`
+		IssueDate       string `xml:"IssueDate"`
+		InvoiceTypeCode string `xml:"InvoiceTypeCode"`
+
+		// Nómina electrónica: los datos van en atributos
+		Sequence struct {
+			Number string `xml:"Numero,attr"`
+		} `xml:"NumeroSecuenciaXML"`
+		General struct {
+			CUNE     string `xml:"CUNE,attr"`
+			FechaGen string `xml:"FechaGen,attr"`
+		} `xml:"InformacionGeneral"`
+	}
+	if err := xml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(raw.XMLName.Local, "NominaIndividual") {
+		return &documentHeader{
+			XMLName:    raw.XMLName,
+			ID:         strings.TrimSpace(raw.Sequence.Number),
+			UUID:       strings.TrimSpace(raw.General.CUNE),
+			UUIDScheme: "CUNE",
+			IssueDate:  strings.TrimSpace(raw.General.FechaGen),
+		}, nil
+	}
+
+	return &documentHeader{
+		XMLName:    raw.XMLName,
+		ID:         strings.TrimSpace(raw.ID),
+		UUID:       strings.TrimSpace(raw.UUID.Value),
+		UUIDScheme: strings.TrimSuffix(raw.UUID.SchemeName, "-SHA384"),
+		IssueDate:  strings.TrimSpace(raw.IssueDate),
+		TypeCode:   strings.TrimSpace(raw.InvoiceTypeCode),
+	}, nil
+}
+
+type applicationResponseData struct {
+	ID, UUID, IssueDate, IssueTime       string
+	ResponseCode, Description            string
+	DocumentID, DocumentUUID, UUIDScheme string
+}
+
+func renderApplicationResponse(data applicationResponseData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := applicationResponseTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var applicationResponseTemplate = template.Must(template.New("ar").Funcs(templateFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<ApplicationResponse xmlns="urn:oasis:names:specification:ubl:schema:xsd:ApplicationResponse-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:UBLVersionID>UBL 2.1</cbc:UBLVersionID>
+  <cbc:CustomizationID>1</cbc:CustomizationID>
+  <cbc:ProfileID>DIAN 2.1</cbc:ProfileID>
+  <cbc:ProfileExecutionID>2</cbc:ProfileExecutionID>
+  <cbc:ID>{{.ID}}</cbc:ID>
+  <cbc:UUID schemeName="CUDE-SHA384">{{.UUID}}</cbc:UUID>
+  <cbc:IssueDate>{{.IssueDate}}</cbc:IssueDate>
+  <cbc:IssueTime>{{.IssueTime}}</cbc:IssueTime>
+  <cac:SenderParty><cac:PartyTaxScheme><cbc:RegistrationName>Unidad Especial Dirección de Impuestos y Aduanas Nacionales</cbc:RegistrationName><cbc:CompanyID schemeID="4" schemeName="31">800197268</cbc:CompanyID></cac:PartyTaxScheme></cac:SenderParty>
+  <cac:DocumentResponse>
+    <cac:Response>
+      <cbc:ResponseCode>{{.ResponseCode}}</cbc:ResponseCode>
+      <cbc:Description>{{x .Description}}</cbc:Description>
+    </cac:Response>
+    <cac:DocumentReference>
+      <cbc:ID>{{x .DocumentID}}</cbc:ID>
+      <cbc:UUID schemeName="{{x .UUIDScheme}}-SHA384">{{.DocumentUUID}}</cbc:UUID>
+    </cac:DocumentReference>
+  </cac:DocumentResponse>
+</ApplicationResponse>
+`))
@@ -0,0 +1,310 @@
+package dian
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Ejemplo del anexo técnico de factura electrónica (numeral 11.2)
+func TestInvoiceCUFE(t *testing.T) {
+	invoice := &Invoice{
+		Number:    323200000129,
+		IssueDate: time.Date(2019, 1, 16, 10, 53, 10, 0, time.FixedZone("COT", -5*60*60)),
+		Issuer:    Party{ID: "700085371"},
+		Customer:  Party{ID: "800199436"},
+		Lines:     []InvoiceLine{{Quantity: 1, UnitPrice: 1500000, IVARate: 19}},
+	}
+	invoice.Compute()
+
+	got := invoice.DocumentKey("693ff6f2a553c3646a063436fd4dd9ded0311471", EnvironmentProduccion)
+	want := "8bb918b19ba22a694f1da11c643b5e9de39adf60311cf179179e9b33381030bcd4c3c3f156c506ed5908f9276f5bd9b4"
+	if got != want {
+		t.Errorf("CUFE = %s, want %s", got, want)
+	}
+}
+
+func sha384(s string) string {
+	sum := sha512.Sum384([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func testSupportDocument() *SupportDocument {
+	return &SupportDocument{
+		IssueDate:     time.Date(2026, 3, 10, 11, 0, 0, 0, time.UTC),
+		Acquirer:      Party{DocumentType: "31", ID: "900123456", CheckDigit: "8", Name: "Café Ejemplo SAS"},
+		Supplier:      Party{DocumentType: "13", ID: "79876543", Name: "Pedro Ruiz"},
+		Lines:         []InvoiceLine{{Description: "Café pergamino (arroba)", Quantity: 10, UnitPrice: 120000}},
+		PaymentMethod: "10",
+		PaymentForm:   "1",
+	}
+}
+
+func testPayroll() *Payroll {
+	return &Payroll{
+		IssueDate:     time.Date(2026, 3, 31, 18, 0, 0, 0, time.UTC),
+		PeriodStart:   time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:     time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+		PaymentDate:   time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+		PayrollPeriod: "5",
+		Employer:      Party{DocumentType: "31", ID: "900123456", CheckDigit: "8", Name: "Café Ejemplo SAS"},
+		Employee: PayrollEmployee{
+			WorkerType: "01", WorkerSubType: "00", DocumentType: "13", ID: "1020304050",
+			FirstSurname: "Gómez", FirstName: "Ana", ContractType: "2", Salary: 1750905,
+			HireDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		DaysWorked:         30,
+		TransportAllowance: 249095,
+		PaymentMethod:      "42",
+	}
+}
+
+func TestDocumentKeysFieldOrder(t *testing.T) {
+	doc := testSupportDocument()
+	doc.Prefix, doc.Number = "DS", 15
+	doc.Compute()
+	// CUDS: número + fecha + hora + subtotal + 01 + IVA + total + vendedor + adquirente + PIN + ambiente
+	want := sha384("DS15" + "2026-03-10" + "06:00:00-05:00" + "1200000.00" + "01" + "0.00" + "1200000.00" + "79876543" + "900123456" + "12345" + "2")
+	if got := doc.DocumentKey("12345", EnvironmentHabilitacion); got != want {
+		t.Errorf("CUDS = %s, want %s", got, want)
+	}
+
+	payroll := testPayroll()
+	payroll.Prefix, payroll.Number = "NE", 7
+	payroll.Compute()
+	// CUNE: número + fecha + hora + devengados + deducciones + neto + empleador + trabajador + tipo XML + PIN + ambiente
+	want = sha384("NE7" + "2026-03-31" + "13:00:00-05:00" +
+		money(payroll.EarningsTotal) + money(payroll.DeductionsTotal) + money(payroll.NetTotal) +
+		"900123456" + "1020304050" + PayrollTypeCode + "12345" + "1")
+	if got := payroll.DocumentKey("12345", EnvironmentProduccion); got != want {
+		t.Errorf("CUNE = %s, want %s", got, want)
+	}
+	if payroll.EarningsTotal != 1750905+249095 || payroll.NetTotal != payroll.EarningsTotal-payroll.DeductionsTotal {
+		t.Errorf("totales de nómina = %d - %d = %d", payroll.EarningsTotal, payroll.DeductionsTotal, payroll.NetTotal)
+	}
+}
+
+func TestSimulatorRoundTrip(t *testing.T) {
+	service := NewService(nil)
+	fixed := time.Date(2026, 3, 31, 20, 15, 0, 0, time.UTC)
+	service.simulator.now = func() time.Time { return fixed }
+	cfg := sandboxConfig()
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		issue  func() (*Submission, error)
+		secret string
+		scheme string
+		doc    string // Nombre del documento en el mensaje de la DIAN
+	}{
+		{
+			name:   "factura",
+			issue:  func() (*Submission, error) { return service.IssueInvoice(ctx, cfg, testInvoice()) },
+			secret: TestNumberingRange.TechnicalKey,
+			scheme: `schemeName="CUFE-SHA384"`,
+			doc:    "Factura electrónica",
+		},
+		{
+			name:   "documento soporte",
+			issue:  func() (*Submission, error) { return service.IssueSupportDocument(ctx, cfg, testSupportDocument()) },
+			secret: cfg.SoftwarePIN,
+			scheme: `schemeName="CUDS-SHA384"`,
+			doc:    "Documento Soporte",
+		},
+		{
+			name:   "nómina",
+			issue:  func() (*Submission, error) { return service.IssuePayroll(ctx, cfg, testPayroll()) },
+			secret: cfg.SoftwarePIN,
+			scheme: `EncripCUNE="CUNE-SHA384"`,
+			doc:    "Nómina Individual",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submission, err := tt.issue()
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if submission.Status != SubmissionApproved {
+				t.Fatalf("Status = %s, response = %+v", submission.Status, submission.Response)
+			}
+
+			key := submission.Document.DocumentKey(tt.secret, EnvironmentSimulator)
+			if submission.DocumentKey != key || len(key) != 96 {
+				t.Errorf("DocumentKey = %s, want %s", submission.DocumentKey, key)
+			}
+			if !strings.Contains(string(submission.XML), key) || !strings.Contains(string(submission.XML), tt.scheme) {
+				t.Errorf("el XML no lleva el código único con %s", tt.scheme)
+			}
+
+			response := submission.Response
+			if !response.IsValid || response.StatusCode != StatusProcessed || response.XMLDocumentKey != key {
+				t.Errorf("respuesta = %+v", response)
+			}
+			if !strings.Contains(response.StatusMessage, tt.doc+" "+submission.Number) {
+				t.Errorf("StatusMessage = %q, want que nombre %q", response.StatusMessage, tt.doc+" "+submission.Number)
+			}
+
+			// El ApplicationResponse embebido se interpreta igual que el de la DIAN
+			raw, err := base64.StdEncoding.DecodeString(response.XMLBase64Bytes)
+			if err != nil {
+				t.Fatalf("XmlBase64Bytes error = %v", err)
+			}
+			appResponse, err := ParseApplicationResponse(raw)
+			if err != nil {
+				t.Fatalf("ParseApplicationResponse() error = %v", err)
+			}
+			if !appResponse.Accepted() || appResponse.DocumentUUID != key || appResponse.DocumentID != submission.Number {
+				t.Errorf("ApplicationResponse = %+v", appResponse)
+			}
+			if appResponse.IssueDate != "2026-03-31" || appResponse.IssueTime != "15:15:00-05:00" || len(appResponse.UUID) != 96 {
+				t.Errorf("fecha y código del ApplicationResponse = %s %s %s", appResponse.IssueDate, appResponse.IssueTime, appResponse.UUID)
+			}
+
+			// La consulta por código único retorna el mismo resultado
+			status, err := service.simulator.GetStatus(ctx, key)
+			if err != nil || !status.IsValid || status.XMLDocumentKey != key {
+				t.Errorf("GetStatus() = %+v, %v", status, err)
+			}
+			zipped, err := service.simulator.GetStatusZip(ctx, key)
+			if err != nil {
+				t.Fatalf("GetStatusZip() error = %v", err)
+			}
+			files, err := UnpackZip(zipped)
+			if err != nil || len(files) != 1 {
+				t.Fatalf("UnpackZip() = %d archivos, %v", len(files), err)
+			}
+			for _, content := range files {
+				if fromZip, err := ParseApplicationResponse(content); err != nil || fromZip.DocumentUUID != key {
+					t.Errorf("ApplicationResponse del ZIP = %+v, %v", fromZip, err)
+				}
+			}
+
+			// Reenviar el mismo documento se rechaza (regla 90)
+			zipContent, err := PackageZip("reenvio.xml", submission.XML)
+			if err != nil {
+				t.Fatalf("PackageZip() error = %v", err)
+			}
+			again, err := service.simulator.SendBillSync(ctx, "reenvio.zip", zipContent)
+			if err != nil {
+				t.Fatalf("SendBillSync() error = %v", err)
+			}
+			if again.IsValid || !strings.Contains(strings.Join(again.ErrorMessages, " "), "Regla: 90") {
+				t.Errorf("reenvío = %+v, want rechazo por regla 90", again)
+			}
+		})
+	}
+}
+
+func TestSimulatorRejectsMalformedDocuments(t *testing.T) {
+	simulator := NewSimulator()
+	ctx := context.Background()
+	zipOf := func(t *testing.T, files map[string]string) []byte {
+		t.Helper()
+		var content []byte
+		name := ""
+		for n, c := range files {
+			name, content = n, []byte(c)
+		}
+		zipped, err := PackageZip(name, content)
+		if err != nil {
+			t.Fatalf("PackageZip() error = %v", err)
+		}
+		return zipped
+	}
+
+	tests := []struct {
+		name string
+		zip  []byte
+		rule string
+	}{
+		{"no es un ZIP", []byte("hola"), "Regla: ZIP"},
+		{"XML mal formado", zipOf(t, map[string]string{"fv.xml": "<Invoice><cbc:ID>"}), "Regla: XML"},
+		{"sin código único", zipOf(t, map[string]string{"fv.xml": `<Invoice><ID>SETP990000001</ID><IssueDate>2026-03-10</IssueDate></Invoice>`}), "Regla: FAD06"},
+		{"sin número", zipOf(t, map[string]string{"fv.xml": `<Invoice><UUID schemeName="CUFE-SHA384">` + strings.Repeat("a", 96) + `</UUID><IssueDate>2026-03-10</IssueDate></Invoice>`}), "Regla: FAD05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := simulator.SendBillSync(ctx, "fv.zip", tt.zip)
+			if err != nil {
+				t.Fatalf("SendBillSync() error = %v", err)
+			}
+			if response.IsValid || response.StatusCode != StatusRejected {
+				t.Errorf("respuesta = %+v, want rechazo", response)
+			}
+			if !strings.Contains(strings.Join(response.ErrorMessages, " "), tt.rule) {
+				t.Errorf("ErrorMessages = %v, want %s", response.ErrorMessages, tt.rule)
+			}
+		})
+	}
+}
+
+func TestParseApplicationResponseRejected(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<ApplicationResponse xmlns="urn:oasis:names:specification:ubl:schema:xsd:ApplicationResponse-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:ID>1234</cbc:ID>
+  <cbc:UUID schemeName="CUDE-SHA384">abc</cbc:UUID>
+  <cbc:IssueDate>2026-03-10</cbc:IssueDate>
+  <cbc:IssueTime>09:31:00-05:00</cbc:IssueTime>
+  <cbc:Note>Regla: FAJ43b, Rechazo: Nombre informado no corresponde al registrado en el RUT</cbc:Note>
+  <cac:DocumentResponse>
+    <cac:Response>
+      <cbc:ResponseCode>04</cbc:ResponseCode>
+      <cbc:Description>Documento rechazado por la DIAN</cbc:Description>
+    </cac:Response>
+    <cac:DocumentReference>
+      <cbc:ID>SETP990000002</cbc:ID>
+      <cbc:UUID schemeName="CUFE-SHA384">def</cbc:UUID>
+    </cac:DocumentReference>
+  </cac:DocumentResponse>
+</ApplicationResponse>`)
+
+	got, err := ParseApplicationResponse(data)
+	if err != nil {
+		t.Fatalf("ParseApplicationResponse() error = %v", err)
+	}
+	if got.Accepted() {
+		t.Error("Accepted() = true para el código 04")
+	}
+	want := ApplicationResponse{
+		ID: "1234", UUID: "abc", IssueDate: "2026-03-10", IssueTime: "09:31:00-05:00",
+		ResponseCode: "04", Description: "Documento rechazado por la DIAN",
+		DocumentID: "SETP990000002", DocumentUUID: "def",
+		Notes: []string{"Regla: FAJ43b, Rechazo: Nombre informado no corresponde al registrado en el RUT"},
+	}
+	if got.ID != want.ID || got.UUID != want.UUID || got.ResponseCode != want.ResponseCode || got.Description != want.Description ||
+		got.DocumentID != want.DocumentID || got.DocumentUUID != want.DocumentUUID || len(got.Notes) != 1 || got.Notes[0] != want.Notes[0] {
+		t.Errorf("ParseApplicationResponse() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseApplicationResponse([]byte("<Invoice/>")); err == nil {
+		t.Error("ParseApplicationResponse() de otro documento no retornó error")
+	}
+}
+
+func TestIssueRefusesDIANEnvironments(t *testing.T) {
+	service := NewService(nil)
+	for _, env := range []Environment{EnvironmentHabilitacion, EnvironmentProduccion} {
+		cfg := sandboxConfig()
+		cfg.Environment = env
+		if _, err := service.IssueInvoice(context.Background(), cfg, testInvoice()); !errors.Is(err, ErrEnvironmentUnsupported) {
+			t.Errorf("IssueInvoice(%s) error = %v, want ErrEnvironmentUnsupported", env, err)
+		}
+		if _, err := service.Submit(context.Background(), cfg, testInvoice(), TestNumberingRange, ""); !errors.Is(err, ErrEnvironmentUnsupported) {
+			t.Errorf("Submit(%s) error = %v, want ErrEnvironmentUnsupported", env, err)
+		}
+	}
+
+	// Los intentos rechazados no consumen consecutivos
+	submission := issueApprovedInvoice(t, service)
+	if want := TestNumberingRange.Prefix + "990000000"; submission.Number != want {
+		t.Errorf("Number = %s, want %s", submission.Number, want)
+	}
+}
@@ -0,0 +1,228 @@
+package dian
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// SupportDocumentTypeCode tipo de documento (InvoiceTypeCode) del documento soporte
+const SupportDocumentTypeCode = "05"
+
+// TestSupportNumberingRange rango de pruebas del documento soporte en habilitación
+var TestSupportNumberingRange = NumberingRange{
+	Resolution: "18760000001",
+	Prefix:     "SEDS",
+	From:       984000000,
+	To:         985000000,
+	ValidFrom:  time.Date(2019, 1, 19, 0, 0, 0, 0, time.UTC),
+	ValidTo:    time.Date(2030, 1, 19, 0, 0, 0, 0, time.UTC),
+}
+
+// SupportDocument documento soporte en adquisiciones efectuadas a sujetos no
+// obligados a facturar (Resolución 000167 de 2021). Lo emite el comprador (el
+// tenant) en nombre del vendedor que no factura.
+type SupportDocument struct {
+	Prefix        string        `json:"prefix"`
+	Number        int64         `json:"number"`
+	IssueDate     time.Time     `json:"issue_date"`
+	Acquirer      Party         `json:"acquirer"` // Tenant que adquiere y emite el documento
+	Supplier      Party         `json:"supplier"` // Vendedor no obligado a facturar
+	NonResident   bool          `json:"non_resident"`
+	Lines         []InvoiceLine `json:"lines"`
+	PaymentMethod string        `json:"payment_method"`
+	PaymentForm   string        `json:"payment_form"`
+	Notes         string        `json:"notes,omitempty"`
+	Concept       string        `json:"concept,omitempty"` // Concepto de retención (compras, servicios, honorarios...)
+
+	// Totales calculados por Compute
+	Subtotal int         `json:"subtotal_cop"`
+	Taxes    []TaxAmount `json:"taxes"`
+	Total    int         `json:"total_cop"`
+
+	// Retenciones que el adquirente practica al vendedor
+	Withholdings []WithholdingAmount `json:"withholdings,omitempty"`
+}
+
+// WithholdingTotal suma las retenciones de un tipo practicadas al vendedor
+func (ds *SupportDocument) WithholdingTotal(kind string) int {
+	return withholdingTotal(ds.Withholdings, kind)
+}
+
+// Compute calcula subtotales e impuestos del documento soporte
+func (ds *SupportDocument) Compute() {
+	ds.Subtotal, ds.Taxes, ds.Total = computeTotals(ds.Lines)
+}
+
+// TaxTotal suma de un tributo en todas sus tarifas
+func (ds *SupportDocument) TaxTotal(code string) int {
+	return taxTotal(ds.Taxes, code)
+}
+
+// Kind implementa Document
+func (ds *SupportDocument) Kind() DocumentKind { return KindSupportDocument }
+
+// FullNumber implementa Document
+func (ds *SupportDocument) FullNumber() string { return fmt.Sprintf("%s%d", ds.Prefix, ds.Number) }
+
+// IssuerNIT implementa Document: el emisor del documento soporte es el adquirente
+func (ds *SupportDocument) IssuerNIT() string { return onlyDigits(ds.Acquirer.ID) }
+
+// IssuedAt implementa Document
+func (ds *SupportDocument) IssuedAt() time.Time { return ds.IssueDate }
+
+// FilePrefix implementa Document
+func (ds *SupportDocument) FilePrefix() string { return "ds" }
+
+// DocumentKey calcula el CUDS:
+// SHA-384(NumDS + FecDS + HorDS + ValDS + 01 + ValImp + ValTol + NumSNO + NITABS + Software-PIN + TipoAmbiente)
+func (ds *SupportDocument) DocumentKey(softwarePIN string, env Environment) string {
+	issued := bogotaTime(ds.IssueDate)
+	return sha384Hex(
+		ds.FullNumber(),
+		issued.Format("2006-01-02"),
+		issued.Format("15:04:05-07:00"),
+		money(ds.Subtotal),
+		TaxIVA, money(ds.TaxTotal(TaxIVA)),
+		money(ds.Total),
+		onlyDigits(ds.Supplier.ID),
+		ds.IssuerNIT(),
+		softwarePIN,
+		env.Code(),
+	)
+}
+
+// RenderXML genera el XML UBL del documento soporte
+func (ds *SupportDocument) RenderXML(meta RenderMeta) ([]byte, error) {
+	customization := "10" // Residente
+	if ds.NonResident {
+		customization = "11"
+	}
+
+	var buf bytes.Buffer
+	data := map[string]interface{}{
+		"Doc":           ds,
+		"Meta":          meta,
+		"Issued":        bogotaTime(ds.IssueDate),
+		"Customization": customization,
+		"TypeCode":      SupportDocumentTypeCode,
+	}
+	if err := supportDocumentTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error generando XML de documento soporte: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+var supportDocumentTemplate = template.Must(template.New("support").Funcs(templateFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2" xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2" xmlns:sts="dian:gov:co:facturaelectronica:Structures-2-1">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent>
+        <sts:DianExtensions>
+          <sts:InvoiceControl>
+            <sts:InvoiceAuthorization>{{x .Meta.Numbering.Resolution}}</sts:InvoiceAuthorization>
+            <sts:AuthorizationPeriod>
+              <cbc:StartDate>{{date .Meta.Numbering.ValidFrom}}</cbc:StartDate>
+              <cbc:EndDate>{{date .Meta.Numbering.ValidTo}}</cbc:EndDate>
+            </sts:AuthorizationPeriod>
+            <sts:AuthorizedInvoices>
+              <sts:Prefix>{{x .Meta.Numbering.Prefix}}</sts:Prefix>
+              <sts:From>{{.Meta.Numbering.From}}</sts:From>
+              <sts:To>{{.Meta.Numbering.To}}</sts:To>
+            </sts:AuthorizedInvoices>
+          </sts:InvoiceControl>
+          <sts:InvoiceSource>
+            <cbc:IdentificationCode listAgencyID="6" listAgencyName="United Nations Economic Commission for Europe" listSchemeURI="urn:oasis:names:specification:ubl:codelist:gc:CountryIdentificationCode-2.1">CO</cbc:IdentificationCode>
+          </sts:InvoiceSource>
+          <sts:SoftwareProvider>
+            <sts:ProviderID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)" schemeID="{{x .Doc.Acquirer.CheckDigit}}" schemeName="31">{{x .Doc.IssuerNIT}}</sts:ProviderID>
+            <sts:SoftwareID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)">{{x .Meta.SoftwareID}}</sts:SoftwareID>
+          </sts:SoftwareProvider>
+          <sts:SoftwareSecurityCode schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)">{{.Meta.SoftwareCode}}</sts:SoftwareSecurityCode>
+          <sts:AuthorizationProvider>
+            <sts:AuthorizationProviderID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)" schemeID="4" schemeName="31">800197268</sts:AuthorizationProviderID>
+          </sts:AuthorizationProvider>
+          <sts:QRCode>{{x .Meta.QRCode}}</sts:QRCode>
+        </sts:DianExtensions>
+      </ext:ExtensionContent>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <cbc:UBLVersionID>UBL 2.1</cbc:UBLVersionID>
+  <cbc:CustomizationID>{{.Customization}}</cbc:CustomizationID>
+  <cbc:ProfileID>DIAN 2.1: documento soporte en adquisiciones efectuadas a no obligados a facturar.</cbc:ProfileID>
+  <cbc:ProfileExecutionID>{{.Meta.Environment.Code}}</cbc:ProfileExecutionID>
+  <cbc:ID>{{x .Doc.FullNumber}}</cbc:ID>
+  <cbc:UUID schemeID="{{.Meta.Environment.Code}}" schemeName="CUDS-SHA384">{{.Meta.DocumentKey}}</cbc:UUID>
+  <cbc:IssueDate>{{date .Issued}}</cbc:IssueDate>
+  <cbc:IssueTime>{{time .Issued}}</cbc:IssueTime>
+  <cbc:InvoiceTypeCode>{{.TypeCode}}</cbc:InvoiceTypeCode>
+{{- if .Doc.Notes}}
+  <cbc:Note>{{x .Doc.Notes}}</cbc:Note>
+{{- end}}
+  <cbc:DocumentCurrencyCode>COP</cbc:DocumentCurrencyCode>
+  <cbc:LineCountNumeric>{{len .Doc.Lines}}</cbc:LineCountNumeric>
+  <cac:AccountingSupplierParty>
+    <cbc:AdditionalAccountID>2</cbc:AdditionalAccountID>
+    <cac:Party>
+      <cac:PartyTaxScheme>
+        <cbc:RegistrationName>{{x .Doc.Supplier.Name}}</cbc:RegistrationName>
+        <cbc:CompanyID schemeAgencyID="195" schemeID="{{x .Doc.Supplier.CheckDigit}}" schemeName="{{x .Doc.Supplier.DocumentType}}">{{x .Doc.Supplier.ID}}</cbc:CompanyID>
+        <cbc:TaxLevelCode>{{x .Doc.Supplier.TaxLevel}}</cbc:TaxLevelCode>
+        <cac:TaxScheme><cbc:ID>ZZ</cbc:ID><cbc:Name>No aplica</cbc:Name></cac:TaxScheme>
+      </cac:PartyTaxScheme>
+    </cac:Party>
+  </cac:AccountingSupplierParty>
+  <cac:AccountingCustomerParty>
+    <cbc:AdditionalAccountID>1</cbc:AdditionalAccountID>
+    <cac:Party>
+      <cac:PartyTaxScheme>
+        <cbc:RegistrationName>{{x .Doc.Acquirer.Name}}</cbc:RegistrationName>
+        <cbc:CompanyID schemeAgencyID="195" schemeID="{{x .Doc.Acquirer.CheckDigit}}" schemeName="31">{{x .Doc.IssuerNIT}}</cbc:CompanyID>
+        <cbc:TaxLevelCode>{{x .Doc.Acquirer.TaxLevel}}</cbc:TaxLevelCode>
+        <cac:TaxScheme><cbc:ID>01</cbc:ID><cbc:Name>IVA</cbc:Name></cac:TaxScheme>
+      </cac:PartyTaxScheme>
+    </cac:Party>
+  </cac:AccountingCustomerParty>
+  <cac:PaymentMeans>
+    <cbc:ID>{{x .Doc.PaymentForm}}</cbc:ID>
+    <cbc:PaymentMeansCode>{{x .Doc.PaymentMethod}}</cbc:PaymentMeansCode>
+  </cac:PaymentMeans>
+{{- range .Doc.Taxes}}
+  <cac:TaxTotal>
+    <cbc:TaxAmount currencyID="COP">{{money .Amount}}</cbc:TaxAmount>
+    <cac:TaxSubtotal>
+      <cbc:TaxableAmount currencyID="COP">{{money .Base}}</cbc:TaxableAmount>
+      <cbc:TaxAmount currencyID="COP">{{money .Amount}}</cbc:TaxAmount>
+      <cac:TaxCategory>
+        <cbc:Percent>{{percent .Percent}}</cbc:Percent>
+        <cac:TaxScheme><cbc:ID>{{.Code}}</cbc:ID><cbc:Name>{{x .Name}}</cbc:Name></cac:TaxScheme>
+      </cac:TaxCategory>
+    </cac:TaxSubtotal>
+  </cac:TaxTotal>
+{{- end}}
+  <cac:LegalMonetaryTotal>
+    <cbc:LineExtensionAmount currencyID="COP">{{money .Doc.Subtotal}}</cbc:LineExtensionAmount>
+    <cbc:TaxExclusiveAmount currencyID="COP">{{money .Doc.Subtotal}}</cbc:TaxExclusiveAmount>
+    <cbc:TaxInclusiveAmount currencyID="COP">{{money .Doc.Total}}</cbc:TaxInclusiveAmount>
+    <cbc:PayableAmount currencyID="COP">{{money .Doc.Total}}</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>
+{{- range $i, $line := .Doc.Lines}}
+  <cac:InvoiceLine>
+    <cbc:ID>{{inc $i}}</cbc:ID>
+    <cbc:InvoicedQuantity unitCode="94">{{$line.Quantity}}</cbc:InvoicedQuantity>
+    <cbc:LineExtensionAmount currencyID="COP">{{money $line.Subtotal}}</cbc:LineExtensionAmount>
+    <cac:InvoicePeriod>
+      <cbc:StartDate>{{date $.Issued}}</cbc:StartDate>
+      <cbc:DescriptionCode>1</cbc:DescriptionCode>
+      <cbc:Description>Por operación</cbc:Description>
+    </cac:InvoicePeriod>
+    <cac:Item><cbc:Description>{{x $line.Description}}</cbc:Description></cac:Item>
+    <cac:Price>
+      <cbc:PriceAmount currencyID="COP">{{money $line.UnitPrice}}</cbc:PriceAmount>
+      <cbc:BaseQuantity unitCode="94">1</cbc:BaseQuantity>
+    </cac:Price>
+  </cac:InvoiceLine>
+{{- end}}
+</Invoice>
+`))
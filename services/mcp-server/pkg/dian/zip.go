@@ -0,0 +1,79 @@
+package dian
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PackageZip empaqueta un XML en el ZIP que esperan los servicios de la DIAN.
+// El archivo interno conserva el nombre del XML y el ZIP usa la misma base.
+func PackageZip(xmlFileName string, content []byte) ([]byte, error) {
+	if !strings.HasSuffix(strings.ToLower(xmlFileName), ".xml") {
+		xmlFileName += ".xml"
+	}
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+
+	file, err := writer.Create(xmlFileName)
+	if err != nil {
+		return nil, fmt.Errorf("error creando entrada ZIP: %w", err)
+	}
+	if _, err := file.Write(content); err != nil {
+		return nil, fmt.Errorf("error escribiendo XML en ZIP: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("error cerrando ZIP: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnpackZip extrae todos los archivos de un ZIP retornado o enviado a la DIAN
+func UnpackZip(data []byte) (map[string][]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("ZIP inválido: %w", err)
+	}
+
+	files := make(map[string][]byte, len(reader.File))
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("error abriendo %s: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo %s: %w", f.Name, err)
+		}
+		files[f.Name] = content
+	}
+
+	return files, nil
+}
+
+// ZipFileName genera el nombre del ZIP según el anexo técnico:
+// z + NIT del emisor (10 dígitos) + código PPP + año (2) + consecutivo (8 hex)
+func ZipFileName(issuerNIT string, year int, sequence int64) string {
+	return fmt.Sprintf("z%010s000%02d%08x.zip", onlyDigits(issuerNIT), year%100, sequence)
+}
+
+// XMLFileName genera el nombre del XML del documento con el prefijo del tipo
+// (fv = factura, nc = nota crédito, nd = nota débito, ds = documento soporte, nie = nómina)
+func XMLFileName(prefix, issuerNIT string, year int, sequence int64) string {
+	return fmt.Sprintf("%s%010s000%02d%08x.xml", prefix, onlyDigits(issuerNIT), year%100, sequence)
+}
+
+func onlyDigits(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,281 @@
+package exogena
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ===== FORMATOS DE INFORMACIÓN EXÓGENA (RESOLUCIÓN DIAN 000162 DE 2023) =====
+
+// Formatos soportados
+const (
+	Format1001 = "1001" // Pagos o abonos en cuenta y retenciones practicadas
+	Format1007 = "1007" // Ingresos recibidos
+	Format1008 = "1008" // Saldos de cuentas por cobrar al 31 de diciembre
+)
+
+// Terceros especiales del anexo técnico
+const (
+	MinorAmountsDocType = "43"        // Sin identificación del exterior o cuantías menores
+	MinorAmountsID      = "222222222" // NIT ficticio de las cuantías menores
+	MinorAmountsName    = "CUANTIAS MENORES"
+	FinalConsumerID     = "222222222222" // Consumidor final de la facturación electrónica
+	CountryColombia     = "169"
+)
+
+// formatSpec estructura de un formato: columnas del tercero, columnas de
+// valores y tope de las cuantías menores
+type formatSpec struct {
+	code         string
+	version      int
+	name         string
+	element      string
+	thirdColumns []string
+	valueColumns []string
+	minorAmount  int64 // Terceros con valores por debajo se agrupan en cuantías menores, por concepto
+}
+
+var specs = map[string]formatSpec{
+	Format1001: {
+		code:         Format1001,
+		version:      10,
+		name:         "Pagos o abonos en cuenta y retenciones practicadas",
+		element:      "pagos",
+		thirdColumns: []string{"tdoc", "nid", "dv", "apl1", "apl2", "nom1", "nom2", "raz", "dir", "dpto", "mun", "pais"},
+		valueColumns: []string{"pagoded", "pagonded", "ivaded", "ivanded", "retfue", "retfueasu", "retfueiva", "retfueivanod"},
+		minorAmount:  100000,
+	},
+	Format1007: {
+		code:         Format1007,
+		version:      9,
+		name:         "Ingresos recibidos",
+		element:      "ingresos",
+		thirdColumns: []string{"tdoc", "nid", "apl1", "apl2", "nom1", "nom2", "raz", "pais"},
+		valueColumns: []string{"ing", "dev"},
+		minorAmount:  500000,
+	},
+	Format1008: {
+		code:         Format1008,
+		version:      7,
+		name:         "Saldos de cuentas por cobrar al 31 de diciembre",
+		element:      "saldoscc",
+		thirdColumns: []string{"tdoc", "nid", "dv", "apl1", "apl2", "nom1", "nom2", "raz", "dir", "dpto", "mun", "pais"},
+		valueColumns: []string{"sal"},
+		minorAmount:  5000000,
+	},
+}
+
+// columnLabels encabezados del prevalidador DIAN para la salida CSV
+var columnLabels = map[string]string{
+	"cpt":          "Concepto",
+	"tdoc":         "Tipo de documento",
+	"nid":          "Número identificación",
+	"dv":           "DV",
+	"apl1":         "Primer apellido",
+	"apl2":         "Segundo apellido",
+	"nom1":         "Primer nombre",
+	"nom2":         "Otros nombres",
+	"raz":          "Razón social",
+	"dir":          "Dirección",
+	"dpto":         "Código dpto.",
+	"mun":          "Código mcp",
+	"pais":         "País de residencia o domicilio",
+	"pagoded":      "Pago o abono en cuenta deducible",
+	"pagonded":     "Pago o abono en cuenta no deducible",
+	"ivaded":       "IVA mayor valor del costo o gasto deducible",
+	"ivanded":      "IVA mayor valor del costo o gasto no deducible",
+	"retfue":       "Retención en la fuente practicada en renta",
+	"retfueasu":    "Retención en la fuente asumida en renta",
+	"retfueiva":    "Retención en la fuente practicada IVA a responsables",
+	"retfueivanod": "Retención en la fuente practicada IVA a no residentes",
+	"ing":          "Ingresos brutos recibidos",
+	"dev":          "Devoluciones, rebajas y descuentos",
+	"sal":          "Saldo cuentas por cobrar al 31-12",
+}
+
+// FormatInfo descripción de un formato soportado
+type FormatInfo struct {
+	Code    string   `json:"code"`
+	Version int      `json:"version"`
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// Formats lista los formatos que genera el servicio
+func Formats() []FormatInfo {
+	var result []FormatInfo
+	for _, code := range []string{Format1001, Format1007, Format1008} {
+		spec := specs[code]
+		result = append(result, FormatInfo{
+			Code:    spec.code,
+			Version: spec.version,
+			Name:    spec.name,
+			Columns: spec.columns(),
+		})
+	}
+	return result
+}
+
+func (f formatSpec) columns() []string {
+	columns := append([]string{"cpt"}, f.thirdColumns...)
+	return append(columns, f.valueColumns...)
+}
+
+// Third tercero reportado con sus datos de identificación y ubicación
+type Third struct {
+	DocumentType  string `json:"tdoc"`
+	ID            string `json:"nid"`
+	CheckDigit    string `json:"dv,omitempty"`
+	FirstSurname  string `json:"apl1,omitempty"`
+	SecondSurname string `json:"apl2,omitempty"`
+	FirstName     string `json:"nom1,omitempty"`
+	OtherNames    string `json:"nom2,omitempty"`
+	BusinessName  string `json:"raz,omitempty"`
+	Address       string `json:"dir,omitempty"`
+	Department    string `json:"dpto,omitempty"`
+	Municipality  string `json:"mun,omitempty"`
+	Country       string `json:"pais"`
+}
+
+// Row registro del formato: concepto, tercero y valores por columna
+type Row struct {
+	Concept string           `json:"cpt"`
+	Third   Third            `json:"tercero"`
+	Values  map[string]int64 `json:"valores"`
+}
+
+// field valor de una columna del registro como texto
+func (r Row) field(column string) string {
+	switch column {
+	case "cpt":
+		return r.Concept
+	case "tdoc":
+		return r.Third.DocumentType
+	case "nid":
+		return r.Third.ID
+	case "dv":
+		return r.Third.CheckDigit
+	case "apl1":
+		return r.Third.FirstSurname
+	case "apl2":
+		return r.Third.SecondSurname
+	case "nom1":
+		return r.Third.FirstName
+	case "nom2":
+		return r.Third.OtherNames
+	case "raz":
+		return r.Third.BusinessName
+	case "dir":
+		return r.Third.Address
+	case "dpto":
+		return r.Third.Department
+	case "mun":
+		return r.Third.Municipality
+	case "pais":
+		return r.Third.Country
+	}
+	return strconv.FormatInt(r.Values[column], 10)
+}
+
+// Report formato de información exógena de un tenant y año gravable
+type Report struct {
+	Format  string   `json:"format"`
+	Version int      `json:"version"`
+	Name    string   `json:"name"`
+	Year    int      `json:"year"`
+	Columns []string `json:"columns"`
+	Rows    []Row    `json:"rows"`
+	Total   int64    `json:"total_cop"` // Suma de los valores reportados (ValorTotal del encabezado)
+	Notes   []string `json:"notes,omitempty"`
+}
+
+// FileName nombre del archivo según la especificación de Muisca:
+// Dmuisca_ + concepto (2) + formato (5) + versión (2) + año (4) + envío (8)
+func (r *Report) FileName(sendNumber int) string {
+	format, _ := strconv.Atoi(r.Format)
+	return fmt.Sprintf("Dmuisca_%02d%05d%02d%04d%08d.xml", 1, format, r.Version, r.Year, sendNumber)
+}
+
+// XML genera el archivo para el servicio informático de presentación masiva
+// de la DIAN, codificado en ISO-8859-1 como exige el prevalidador
+func (r *Report) XML(sendNumber int, sentAt time.Time) []byte {
+	spec := specs[r.Format]
+	var b strings.Builder
+
+	b.WriteString(`<?xml version="1.0" encoding="ISO-8859-1"?>` + "\n")
+	fmt.Fprintf(&b, `<mas xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:noNamespaceSchemaLocation="../xsd/%s.xsd">`+"\n", r.Format)
+	b.WriteString("<Cab>\n")
+	fmt.Fprintf(&b, "<Ano>%d</Ano>\n", r.Year)
+	b.WriteString("<CodCpt>1</CodCpt>\n")
+	fmt.Fprintf(&b, "<Formato>%s</Formato>\n", r.Format)
+	fmt.Fprintf(&b, "<Version>%d</Version>\n", r.Version)
+	fmt.Fprintf(&b, "<NumEnvio>%d</NumEnvio>\n", sendNumber)
+	fmt.Fprintf(&b, "<FecEnvio>%s</FecEnvio>\n", sentAt.Format("2006-01-02T15:04:05"))
+	fmt.Fprintf(&b, "<FecInicial>%d-01-01</FecInicial>\n", r.Year)
+	fmt.Fprintf(&b, "<FecFinal>%d-12-31</FecFinal>\n", r.Year)
+	fmt.Fprintf(&b, "<ValorTotal>%d</ValorTotal>\n", r.Total)
+	fmt.Fprintf(&b, "<CantReg>%d</CantReg>\n", len(r.Rows))
+	b.WriteString("</Cab>\n")
+
+	for _, row := range r.Rows {
+		b.WriteString("<" + spec.element)
+		for _, column := range r.Columns {
+			value := row.field(column)
+			if value == "" {
+				continue // El prevalidador rechaza atributos vacíos
+			}
+			b.WriteString(" " + column + `="`)
+			xml.EscapeText(&b, []byte(value))
+			b.WriteString(`"`)
+		}
+		b.WriteString("/>\n")
+	}
+	b.WriteString("</mas>\n")
+
+	return latin1(b.String())
+}
+
+// CSV genera el formato con los encabezados del prevalidador, en UTF-8 y
+// separado por punto y coma para abrirlo en hojas de cálculo en español
+func (r *Report) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = ';'
+
+	header := make([]string, len(r.Columns))
+	for i, column := range r.Columns {
+		header[i] = columnLabels[column]
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	for _, row := range r.Rows {
+		record := make([]string, len(r.Columns))
+		for i, column := range r.Columns {
+			record[i] = row.field(column)
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// latin1 convierte el texto a ISO-8859-1; los caracteres fuera del juego se
+// reemplazan por '?'
+func latin1(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+	return out
+}
@@ -0,0 +1,380 @@
+package exogena
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/dian"
+	"mcp-server/pkg/payments"
+)
+
+// ErrUnknownFormat el formato solicitado no está soportado
+var ErrUnknownFormat = errors.New("formato de información exógena no soportado")
+
+// Conceptos del formato 1001 por concepto de retención del documento soporte
+var paymentConcepts = map[string]string{
+	"honorarios":              "5002",
+	"consultoria":             "5002",
+	"servicios":               "5004",
+	"software":                "5004",
+	"transporte_carga":        "5004",
+	"transporte_pasajeros":    "5004",
+	"hoteles_restaurantes":    "5004",
+	"aseo_vigilancia":         "5004",
+	"temporales":              "5004",
+	"arrendamiento_inmuebles": "5005",
+	"arrendamiento_muebles":   "5005",
+	"rendimientos":            "5006",
+	"compras":                 "5007",
+	"compras_agricolas":       "5007",
+	"combustibles":            "5007",
+}
+
+// otherPaymentsConcept otros costos y deducciones
+const otherPaymentsConcept = "5016"
+
+// Conceptos de los formatos de ingresos y cuentas por cobrar
+const (
+	incomeConcept     = "4001" // Ingresos brutos de actividades ordinarias
+	receivableConcept = "1315" // Clientes
+)
+
+// Profile datos del informante necesarios para los formatos
+type Profile struct {
+	Informant         Third // Dirección y municipio que se usan para las cuantías menores
+	ResponsableIVA    bool  // Si no lo es, el IVA pagado es mayor valor del costo
+	GranContribuyente bool
+	RegimenSimple     bool
+}
+
+// Service genera la información exógena y los reportes tributarios de un
+// tenant a partir de los documentos electrónicos aprobados por la DIAN y de
+// los pagos recibidos
+type Service struct {
+	dian     *dian.Service
+	payments *payments.Service
+	colombia *colombia.Service
+	now      func() time.Time
+}
+
+// NewService crea el servicio de reportes tributarios
+func NewService(dianService *dian.Service, paymentsService *payments.Service, colombiaService *colombia.Service) *Service {
+	return &Service{
+		dian:     dianService,
+		payments: paymentsService,
+		colombia: colombiaService,
+		now:      time.Now,
+	}
+}
+
+// documents documentos aprobados del tenant; los pendientes y rechazados no
+// tienen validez tributaria y solo se cuentan para avisar
+type documents struct {
+	invoices    []*dian.Invoice
+	invoiceIDs  map[*dian.Invoice]string
+	support     []*dian.SupportDocument
+	creditNotes []*dian.CreditNote
+	pending     int
+}
+
+func (s *Service) documents(tenantID string) documents {
+	docs := documents{invoiceIDs: map[*dian.Invoice]string{}}
+	for _, submission := range s.dian.ListSubmissions(tenantID) {
+		if submission.Status != dian.SubmissionApproved {
+			if submission.Status == dian.SubmissionPending {
+				docs.pending++
+			}
+			continue
+		}
+		switch doc := submission.Document.(type) {
+		case *dian.Invoice:
+			docs.invoices = append(docs.invoices, doc)
+			docs.invoiceIDs[doc] = submission.ID
+		case *dian.SupportDocument:
+			docs.support = append(docs.support, doc)
+		case *dian.CreditNote:
+			docs.creditNotes = append(docs.creditNotes, doc)
+		}
+	}
+	return docs
+}
+
+// inYear indica si la fecha cae en el año gravable (hora de Colombia)
+func inYear(t time.Time, year int) bool {
+	return t.In(colombia.Location()).Year() == year
+}
+
+// yearEnd último instante del año gravable
+func yearEnd(year int) time.Time {
+	return time.Date(year+1, 1, 1, 0, 0, 0, 0, colombia.Location()).Add(-time.Nanosecond)
+}
+
+// Generate genera un formato para el año gravable
+func (s *Service) Generate(tenantID string, year int, format string, profile Profile) (*Report, error) {
+	spec, ok := specs[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s (use 1001, 1007 o 1008)", ErrUnknownFormat, format)
+	}
+	if year < 2000 || year > s.now().In(colombia.Location()).Year() {
+		return nil, fmt.Errorf("año gravable inválido: %d", year)
+	}
+
+	docs := s.documents(tenantID)
+	report := &Report{
+		Format:  spec.code,
+		Version: spec.version,
+		Name:    spec.name,
+		Year:    year,
+		Columns: spec.columns(),
+	}
+
+	var rows map[string]*Row
+	switch format {
+	case Format1001:
+		rows = s.payments1001(docs, year, profile, report)
+	case Format1007:
+		rows = s.income1007(docs, year)
+	case Format1008:
+		rows = s.receivables1008(tenantID, docs, year)
+	}
+
+	report.Rows = groupMinorAmounts(spec, rows, profile.Informant)
+	for _, row := range report.Rows {
+		for _, value := range row.Values {
+			report.Total += value
+		}
+	}
+	if docs.pending > 0 {
+		report.Notes = append(report.Notes, fmt.Sprintf("%d documentos pendientes de validación DIAN no se incluyeron", docs.pending))
+	}
+	return report, nil
+}
+
+// payments1001 pagos a proveedores soportados en documentos soporte y las
+// retenciones practicadas. Las compras a facturadores electrónicos no pasan
+// por la plataforma y deben agregarse desde la contabilidad; los pagos
+// laborales se reportan en el formato 2276.
+func (s *Service) payments1001(docs documents, year int, profile Profile, report *Report) map[string]*Row {
+	rows := map[string]*Row{}
+	nonResidents := 0
+	for _, doc := range docs.support {
+		if !inYear(doc.IssueDate, year) {
+			continue
+		}
+		concept, ok := paymentConcepts[doc.Concept]
+		if !ok {
+			concept = otherPaymentsConcept
+		}
+		third := ThirdFromParty(doc.Supplier)
+		if doc.NonResident {
+			third.Department, third.Municipality, third.Country = "", "", ""
+			nonResidents++
+		}
+
+		row := rowFor(rows, concept, third)
+		row.Values["pagoded"] += int64(doc.Subtotal)
+		if !profile.ResponsableIVA {
+			row.Values["ivaded"] += int64(doc.TaxTotal(dian.TaxIVA))
+		}
+		row.Values["retfue"] += int64(doc.WithholdingTotal(dian.WithholdingRenta))
+		if doc.NonResident {
+			row.Values["retfueivanod"] += int64(doc.WithholdingTotal(dian.WithholdingIVA))
+		} else {
+			row.Values["retfueiva"] += int64(doc.WithholdingTotal(dian.WithholdingIVA))
+		}
+	}
+	if nonResidents > 0 {
+		report.Notes = append(report.Notes, fmt.Sprintf("%d pagos a no residentes sin código de país: complételo antes de presentar", nonResidents))
+	}
+	report.Notes = append(report.Notes, "Incluye solo documentos soporte; agregue las compras facturadas por proveedores y reporte los pagos laborales en el formato 2276")
+	return rows
+}
+
+// income1007 ingresos facturados por cliente y sus devoluciones (notas crédito)
+func (s *Service) income1007(docs documents, year int) map[string]*Row {
+	rows := map[string]*Row{}
+	for _, invoice := range docs.invoices {
+		if !inYear(invoice.IssueDate, year) {
+			continue
+		}
+		row := rowFor(rows, incomeConcept, ThirdFromParty(invoice.Customer))
+		row.Values["ing"] += int64(invoice.Subtotal)
+	}
+	for _, note := range docs.creditNotes {
+		if !inYear(note.IssueDate, year) {
+			continue
+		}
+		row := rowFor(rows, incomeConcept, ThirdFromParty(note.Customer))
+		row.Values["dev"] += int64(note.Subtotal)
+	}
+	return rows
+}
+
+// receivables1008 saldo por cliente al 31 de diciembre de las facturas a
+// crédito: total menos retenciones, notas crédito y pagos asociados, más los
+// reembolsos de esos pagos. Las facturas de contado se consideran pagadas.
+func (s *Service) receivables1008(tenantID string, docs documents, year int) map[string]*Row {
+	cutoff := yearEnd(year)
+
+	credited := map[string]int{}
+	for _, note := range docs.creditNotes {
+		if !note.IssueDate.After(cutoff) {
+			credited[note.Invoice.SubmissionID] += note.Total
+		}
+	}
+
+	collected := map[string]int64{}
+	if s.payments != nil {
+		for _, payment := range s.payments.ListPayments(tenantID, "") {
+			if payment.InvoiceID == "" {
+				continue
+			}
+			collected[payment.InvoiceID] += paidBy(payment, cutoff)
+		}
+	}
+
+	rows := map[string]*Row{}
+	for _, invoice := range docs.invoices {
+		if invoice.IssueDate.After(cutoff) {
+			continue
+		}
+		id := docs.invoiceIDs[invoice]
+		if invoice.PaymentForm != "2" && collected[id] == 0 {
+			continue
+		}
+		balance := int64(invoice.Total-invoice.WithholdingTotal(dian.WithholdingRenta)-invoice.WithholdingTotal(dian.WithholdingIVA)-invoice.WithholdingTotal(dian.WithholdingICA)-credited[id]) - collected[id]
+		if balance <= 0 {
+			continue
+		}
+		row := rowFor(rows, receivableConcept, ThirdFromParty(invoice.Customer))
+		row.Values["sal"] += balance
+	}
+	return rows
+}
+
+// paidBy valor aprobado de un pago hasta la fecha de corte, neto de reembolsos
+func paidBy(payment *payments.Payment, cutoff time.Time) int64 {
+	if payment.FinalizedAt == nil || payment.FinalizedAt.After(cutoff) {
+		return 0
+	}
+	if payment.Status != payments.StatusApproved && payment.Status != payments.StatusRefunded {
+		return 0
+	}
+	paid := payment.Amount
+	for _, refund := range payment.Refunds {
+		if refund.Status == payments.RefundApproved && refund.FinalizedAt != nil && !refund.FinalizedAt.After(cutoff) {
+			paid -= refund.Amount
+		}
+	}
+	return paid
+}
+
+// rowFor registro acumulado de un concepto y tercero
+func rowFor(rows map[string]*Row, concept string, third Third) *Row {
+	key := concept + "|" + third.DocumentType + "|" + third.ID
+	row, ok := rows[key]
+	if !ok {
+		row = &Row{Concept: concept, Third: third, Values: map[string]int64{}}
+		rows[key] = row
+	}
+	return row
+}
+
+// groupMinorAmounts agrupa en cuantías menores los terceros cuyos valores no
+// superan el tope del formato, y también al consumidor final. En el 1001 las
+// retenciones se informan siempre por tercero, así que los pagos con
+// retención no se agrupan.
+func groupMinorAmounts(spec formatSpec, rows map[string]*Row, informant Third) []Row {
+	minor := map[string]*Row{}
+	var result []Row
+
+	for _, row := range rows {
+		amount := int64(0)
+		for _, value := range row.Values {
+			amount += value
+		}
+		withheld := row.Values["retfue"] + row.Values["retfueiva"] + row.Values["retfueivanod"]
+		isMinor := (amount < spec.minorAmount && withheld == 0) || row.Third.ID == FinalConsumerID
+		if !isMinor {
+			result = append(result, *row)
+			continue
+		}
+
+		group, ok := minor[row.Concept]
+		if !ok {
+			third := Third{
+				DocumentType: MinorAmountsDocType,
+				ID:           MinorAmountsID,
+				BusinessName: MinorAmountsName,
+				Country:      CountryColombia,
+			}
+			if spec.code != Format1007 {
+				third.Address = informant.Address
+				third.Department = informant.Department
+				third.Municipality = informant.Municipality
+			}
+			group = &Row{Concept: row.Concept, Third: third, Values: map[string]int64{}}
+			minor[row.Concept] = group
+		}
+		for column, value := range row.Values {
+			group.Values[column] += value
+		}
+	}
+	for _, group := range minor {
+		result = append(result, *group)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Concept != result[j].Concept {
+			return result[i].Concept < result[j].Concept
+		}
+		return result[i].Third.ID < result[j].Third.ID
+	})
+	return result
+}
+
+// ThirdFromParty convierte la parte de un documento DIAN en el tercero del formato
+func ThirdFromParty(party dian.Party) Third {
+	third := Third{
+		DocumentType: party.DocumentType,
+		ID:           party.ID,
+		Address:      strings.ToUpper(party.Address),
+		Country:      CountryColombia,
+	}
+	if third.DocumentType == "" {
+		third.DocumentType = colombia.DocNIT
+	}
+	if third.DocumentType == colombia.DocNIT {
+		third.CheckDigit = party.CheckDigit
+		third.BusinessName = strings.ToUpper(strings.TrimSpace(party.Name))
+	} else {
+		third.FirstName, third.OtherNames, third.FirstSurname, third.SecondSurname = splitName(party.Name)
+	}
+	if len(party.CityCode) == 5 {
+		third.Department = party.CityCode[:2]
+		third.Municipality = party.CityCode[2:]
+	}
+	return third
+}
+
+// splitName separa el nombre de una persona natural escrito como nombres
+// seguidos de apellidos: con cuatro o más palabras los dos primeros son
+// nombres; con tres, el primero
+func splitName(name string) (first, others, surname, secondSurname string) {
+	words := strings.Fields(strings.ToUpper(name))
+	switch len(words) {
+	case 0:
+		return "", "", "", ""
+	case 1:
+		return words[0], "", "", ""
+	case 2:
+		return words[0], "", words[1], ""
+	case 3:
+		return words[0], "", words[1], words[2]
+	}
+	return words[0], words[1], words[2], strings.Join(words[3:], " ")
+}
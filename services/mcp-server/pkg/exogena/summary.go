@@ -0,0 +1,277 @@
+package exogena
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/dian"
+	"mcp-server/pkg/payments"
+)
+
+// ===== RESUMEN MENSUAL DE IVA Y RETENCIONES =====
+
+// Periodicidad de la declaración de IVA (Estatuto Tributario art. 600)
+const (
+	IVABimonthly    = "bimestral"     // Grandes contribuyentes e ingresos del año anterior >= 92.000 UVT
+	IVAFourMonthly  = "cuatrimestral" // Demás responsables
+	IVAAnnual       = "anual"         // Régimen Simple
+	bimonthlyMinUVT = 92000
+)
+
+var monthNames = []string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"}
+
+// MonthSummary movimiento tributario de un mes
+type MonthSummary struct {
+	Month                int    `json:"month"`
+	Name                 string `json:"name"`
+	Invoices             int    `json:"invoices"`
+	Sales                int64  `json:"sales_cop"` // Base de las facturas de venta
+	SalesIVA             int64  `json:"sales_iva_cop"`
+	SalesINC             int64  `json:"sales_inc_cop"`
+	CreditNotes          int64  `json:"credit_notes_cop"`
+	CreditNotesIVA       int64  `json:"credit_notes_iva_cop"`
+	SupportDocuments     int    `json:"support_documents"`
+	Purchases            int64  `json:"purchases_cop"`
+	PurchasesIVA         int64  `json:"purchases_iva_cop"` // IVA descontable de los documentos soporte
+	Collected            int64  `json:"collected_cop"`     // Pagos aprobados netos de reembolsos
+	ReteFuentePracticed  int64  `json:"retefuente_practiced_cop"`
+	ReteIVAPracticed     int64  `json:"reteiva_practiced_cop"`
+	ReteICAPracticed     int64  `json:"reteica_practiced_cop"`
+	ReteFuenteWithheld   int64  `json:"retefuente_withheld_cop"` // Retenciones que le practicaron los clientes
+	ReteIVAWithheld      int64  `json:"reteiva_withheld_cop"`
+	ReteICAWithheld      int64  `json:"reteica_withheld_cop"`
+	WithholdingReturn350 int64  `json:"withholding_return_350_cop"` // Retención de renta e IVA a declarar en el formulario 350
+}
+
+func (m *MonthSummary) add(other MonthSummary) {
+	m.Invoices += other.Invoices
+	m.Sales += other.Sales
+	m.SalesIVA += other.SalesIVA
+	m.SalesINC += other.SalesINC
+	m.CreditNotes += other.CreditNotes
+	m.CreditNotesIVA += other.CreditNotesIVA
+	m.SupportDocuments += other.SupportDocuments
+	m.Purchases += other.Purchases
+	m.PurchasesIVA += other.PurchasesIVA
+	m.Collected += other.Collected
+	m.ReteFuentePracticed += other.ReteFuentePracticed
+	m.ReteIVAPracticed += other.ReteIVAPracticed
+	m.ReteICAPracticed += other.ReteICAPracticed
+	m.ReteFuenteWithheld += other.ReteFuenteWithheld
+	m.ReteIVAWithheld += other.ReteIVAWithheld
+	m.ReteICAWithheld += other.ReteICAWithheld
+	m.WithholdingReturn350 += other.WithholdingReturn350
+}
+
+// IVAPeriod liquidación estimada de un periodo de la declaración de IVA (formulario 300)
+type IVAPeriod struct {
+	Period       int   `json:"period"`
+	FromMonth    int   `json:"from_month"`
+	ToMonth      int   `json:"to_month"`
+	Generated    int64 `json:"generated_cop"`    // IVA generado neto de devoluciones
+	Discountable int64 `json:"discountable_cop"` // IVA descontable
+	Withheld     int64 `json:"withheld_cop"`     // Retenciones de IVA que le practicaron
+	Payable      int64 `json:"payable_cop"`
+	Balance      int64 `json:"balance_cop,omitempty"` // Saldo a favor
+}
+
+// TaxSummary resumen tributario del año gravable
+type TaxSummary struct {
+	Year        int            `json:"year"`
+	IVAPeriod   string         `json:"iva_period,omitempty"`
+	Months      []MonthSummary `json:"months"`
+	IVAPeriods  []IVAPeriod    `json:"iva_periods,omitempty"`
+	Totals      MonthSummary   `json:"totals"`
+	Notes       []string       `json:"notes,omitempty"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// TaxSummary resume mes a mes las ventas, compras, IVA y retenciones del año
+// gravable, y agrupa el IVA en los periodos de la declaración. ivaPeriod
+// fuerza la periodicidad; vacío la deduce del perfil y de los ingresos del
+// año anterior.
+func (s *Service) TaxSummary(tenantID string, year int, profile Profile, ivaPeriod string) (*TaxSummary, error) {
+	if year < 2000 || year > s.now().In(colombia.Location()).Year() {
+		return nil, fmt.Errorf("año gravable inválido: %d", year)
+	}
+	switch ivaPeriod {
+	case "", IVABimonthly, IVAFourMonthly, IVAAnnual:
+	default:
+		return nil, fmt.Errorf("periodicidad de IVA inválida: %s (use bimestral, cuatrimestral o anual)", ivaPeriod)
+	}
+
+	docs := s.documents(tenantID)
+	summary := &TaxSummary{Year: year, GeneratedAt: s.now()}
+	months := make([]MonthSummary, 12)
+	for i := range months {
+		months[i].Month = i + 1
+		months[i].Name = monthNames[i]
+	}
+	month := func(t time.Time) *MonthSummary {
+		if !inYear(t, year) {
+			return nil
+		}
+		return &months[t.In(colombia.Location()).Month()-1]
+	}
+
+	priorIncome := int64(0)
+	for _, invoice := range docs.invoices {
+		if inYear(invoice.IssueDate, year-1) {
+			priorIncome += int64(invoice.Subtotal)
+		}
+		m := month(invoice.IssueDate)
+		if m == nil {
+			continue
+		}
+		m.Invoices++
+		m.Sales += int64(invoice.Subtotal)
+		m.SalesIVA += int64(invoice.TaxTotal(dian.TaxIVA))
+		m.SalesINC += int64(invoice.TaxTotal(dian.TaxINC))
+		m.ReteFuenteWithheld += int64(invoice.WithholdingTotal(dian.WithholdingRenta))
+		m.ReteIVAWithheld += int64(invoice.WithholdingTotal(dian.WithholdingIVA))
+		m.ReteICAWithheld += int64(invoice.WithholdingTotal(dian.WithholdingICA))
+	}
+	for _, note := range docs.creditNotes {
+		if m := month(note.IssueDate); m != nil {
+			m.CreditNotes += int64(note.Subtotal)
+			m.CreditNotesIVA += int64(note.TaxTotal(dian.TaxIVA))
+		}
+	}
+	for _, doc := range docs.support {
+		m := month(doc.IssueDate)
+		if m == nil {
+			continue
+		}
+		m.SupportDocuments++
+		m.Purchases += int64(doc.Subtotal)
+		m.PurchasesIVA += int64(doc.TaxTotal(dian.TaxIVA))
+		m.ReteFuentePracticed += int64(doc.WithholdingTotal(dian.WithholdingRenta))
+		m.ReteIVAPracticed += int64(doc.WithholdingTotal(dian.WithholdingIVA))
+		m.ReteICAPracticed += int64(doc.WithholdingTotal(dian.WithholdingICA))
+	}
+	if s.payments != nil {
+		for _, payment := range s.payments.ListPayments(tenantID, "") {
+			if payment.FinalizedAt == nil {
+				continue
+			}
+			if m := month(*payment.FinalizedAt); m != nil && (payment.Status == payments.StatusApproved || payment.Status == payments.StatusRefunded) {
+				m.Collected += payment.Amount
+			}
+			for _, refund := range payment.Refunds {
+				if refund.Status != payments.RefundApproved || refund.FinalizedAt == nil {
+					continue
+				}
+				if m := month(*refund.FinalizedAt); m != nil {
+					m.Collected -= refund.Amount
+				}
+			}
+		}
+	}
+
+	for i := range months {
+		months[i].WithholdingReturn350 = months[i].ReteFuentePracticed + months[i].ReteIVAPracticed
+		summary.Totals.add(months[i])
+	}
+	summary.Months = months
+	summary.Totals.Name = "total"
+
+	if !profile.ResponsableIVA {
+		summary.Notes = append(summary.Notes, "El tenant no es responsable de IVA: no declara IVA")
+	} else {
+		if ivaPeriod == "" {
+			ivaPeriod = s.ivaPeriodicity(year, profile, priorIncome)
+		}
+		summary.IVAPeriod = ivaPeriod
+		summary.IVAPeriods = ivaPeriods(months, ivaPeriod)
+	}
+	if docs.pending > 0 {
+		summary.Notes = append(summary.Notes, fmt.Sprintf("%d documentos pendientes de validación DIAN no se incluyeron", docs.pending))
+	}
+	summary.Notes = append(summary.Notes, "La retención de ICA practicada se declara ante cada municipio y no hace parte del formulario 350")
+	return summary, nil
+}
+
+// ivaPeriodicity bimestral para grandes contribuyentes y para quienes
+// facturaron en el año anterior al menos 92.000 UVT; anual en el Régimen
+// Simple; cuatrimestral en los demás casos
+func (s *Service) ivaPeriodicity(year int, profile Profile, priorIncome int64) string {
+	if profile.RegimenSimple {
+		return IVAAnnual
+	}
+	if profile.GranContribuyente {
+		return IVABimonthly
+	}
+	if s.colombia != nil {
+		params, err := s.colombia.Fiscal().At(time.Date(year-1, 12, 31, 0, 0, 0, 0, colombia.Location()))
+		if err == nil && priorIncome >= int64(bimonthlyMinUVT*params.UVT) {
+			return IVABimonthly
+		}
+	}
+	return IVAFourMonthly
+}
+
+// ivaPeriods agrupa los meses en los periodos de la declaración de IVA
+func ivaPeriods(months []MonthSummary, periodicity string) []IVAPeriod {
+	length := 4
+	switch periodicity {
+	case IVABimonthly:
+		length = 2
+	case IVAAnnual:
+		length = 12
+	}
+
+	var periods []IVAPeriod
+	for start := 0; start < 12; start += length {
+		period := IVAPeriod{Period: start/length + 1, FromMonth: start + 1, ToMonth: start + length}
+		for _, m := range months[start : start+length] {
+			period.Generated += m.SalesIVA - m.CreditNotesIVA
+			period.Discountable += m.PurchasesIVA
+			period.Withheld += m.ReteIVAWithheld
+		}
+		net := period.Generated - period.Discountable - period.Withheld
+		if net >= 0 {
+			period.Payable = net
+		} else {
+			period.Balance = -net
+		}
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// CSV genera el resumen mensual con una fila por mes y la fila de totales
+func (t *TaxSummary) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = ';'
+
+	header := []string{"Mes", "Facturas", "Ventas", "IVA generado", "INC generado", "Notas crédito", "IVA notas crédito",
+		"Documentos soporte", "Compras", "IVA descontable", "Recaudo", "ReteFuente practicada", "ReteIVA practicada",
+		"ReteICA practicada", "ReteFuente que le practicaron", "ReteIVA que le practicaron", "ReteICA que le practicaron",
+		"Total formulario 350"}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	rows := append(append([]MonthSummary{}, t.Months...), t.Totals)
+	for _, m := range rows {
+		values := []int64{m.Sales, m.SalesIVA, m.SalesINC, m.CreditNotes, m.CreditNotesIVA}
+		record := []string{m.Name, strconv.Itoa(m.Invoices)}
+		for _, value := range values {
+			record = append(record, strconv.FormatInt(value, 10))
+		}
+		record = append(record, strconv.Itoa(m.SupportDocuments))
+		for _, value := range []int64{m.Purchases, m.PurchasesIVA, m.Collected, m.ReteFuentePracticed, m.ReteIVAPracticed,
+			m.ReteICAPracticed, m.ReteFuenteWithheld, m.ReteIVAWithheld, m.ReteICAWithheld, m.WithholdingReturn350} {
+			record = append(record, strconv.FormatInt(value, 10))
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
@@ -0,0 +1,290 @@
+package habeasdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server/pkg/colombia"
+)
+
+// ===== AUTORIZACIONES =====
+
+// Finalidades del tratamiento
+const (
+	PurposeOperations = "operacion"     // Gestión de pedidos, pagos, envíos y atención
+	PurposeInvoicing  = "facturacion"   // Facturación electrónica
+	PurposeMarketing  = "mercadeo"      // Publicidad, promociones y fidelización
+	PurposeAnalytics  = "analitica"     // Análisis de consumo y segmentación
+	PurposeTransfer   = "transferencia" // Transmisión o transferencia a terceros
+)
+
+// purposeDescriptions descripción de cada finalidad para el aviso de privacidad
+var purposeDescriptions = map[string]string{
+	PurposeOperations: "gestionar sus pedidos, pagos, envíos y solicitudes de atención",
+	PurposeInvoicing:  "expedir y enviar la factura electrónica",
+	PurposeMarketing:  "enviarle publicidad, promociones y novedades",
+	PurposeAnalytics:  "analizar sus hábitos de consumo para mejorar la oferta",
+	PurposeTransfer:   "compartir sus datos con aliados y proveedores que prestan servicios al comercio",
+}
+
+// Canales por los que se obtiene la autorización
+const (
+	ChannelAgent    = "agente" // Conversación con el agente del comercio
+	ChannelWhatsApp = "whatsapp"
+	ChannelWeb      = "web"
+	ChannelEmail    = "email"
+	ChannelPhone    = "telefono"
+	ChannelInPerson = "presencial"
+	ChannelAPI      = "api"
+)
+
+// defaultPolicyVer versión de la política si el comercio no indica otra
+const defaultPolicyVer = "1.0"
+
+var channels = map[string]bool{
+	ChannelAgent: true, ChannelWhatsApp: true, ChannelWeb: true, ChannelEmail: true,
+	ChannelPhone: true, ChannelInPerson: true, ChannelAPI: true,
+}
+
+// Purposes finalidades predefinidas; los comercios pueden registrar otras
+func Purposes() map[string]string {
+	result := make(map[string]string, len(purposeDescriptions))
+	for purpose, description := range purposeDescriptions {
+		result[purpose] = description
+	}
+	return result
+}
+
+// NormalizePurpose convierte la finalidad a su código ("Mercadeo" → mercadeo)
+func NormalizePurpose(value string) string {
+	return strings.ReplaceAll(colombia.NormalizeText(value), " ", "_")
+}
+
+// Evidence prueba de la autorización (Decreto 1377 de 2013, art. 8): qué
+// aceptó el titular y cómo
+type Evidence struct {
+	Text      string `json:"text"`                // Mensaje del titular o texto aceptado
+	Reference string `json:"reference,omitempty"` // Conversación, formulario o grabación
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Hash      string `json:"hash"` // SHA-256 del registro para detectar alteraciones
+}
+
+// Consent autorización (o negativa) del titular para una finalidad. El
+// registro es de solo adición: la revocatoria marca la autorización y una
+// nueva decisión del titular crea otro registro.
+type Consent struct {
+	ID               string     `json:"id"`
+	TenantID         string     `json:"tenant_id"`
+	Subject          Subject    `json:"subject"`
+	SubjectKey       string     `json:"subject_key"`
+	Purpose          string     `json:"purpose"`
+	Granted          bool       `json:"granted"` // false: el titular no autorizó
+	Channel          string     `json:"channel"`
+	PolicyVersion    string     `json:"policy_version"`
+	Evidence         Evidence   `json:"evidence"`
+	CollectedBy      string     `json:"collected_by,omitempty"` // Agente o usuario que la registró
+	At               time.Time  `json:"at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+}
+
+// Active indica si la autorización está vigente
+func (c *Consent) Active() bool {
+	return c.Granted && c.RevokedAt == nil
+}
+
+// ConsentRequest decisión del titular sobre una o varias finalidades
+type ConsentRequest struct {
+	Subject       Subject
+	Purposes      []string
+	Granted       bool
+	Channel       string
+	PolicyVersion string // Vacío: la versión vigente del comercio
+	Evidence      Evidence
+	CollectedBy   string
+}
+
+// RecordConsent registra la decisión del titular, un registro por finalidad
+func (s *Service) RecordConsent(controller Controller, req ConsentRequest) ([]*Consent, error) {
+	subject, err := req.Subject.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Purposes) == 0 {
+		return nil, fmt.Errorf("indique al menos una finalidad")
+	}
+	channel := colombia.NormalizeText(req.Channel)
+	if channel == "" {
+		channel = ChannelAgent
+	}
+	if !channels[channel] {
+		return nil, fmt.Errorf("canal de autorización inválido: %s", req.Channel)
+	}
+	if strings.TrimSpace(req.Evidence.Text) == "" && req.Evidence.Reference == "" {
+		return nil, fmt.Errorf("la autorización requiere evidencia: el texto aceptado o la referencia de la conversación")
+	}
+	version := firstNonEmpty(req.PolicyVersion, controller.PolicyVersion, defaultPolicyVer)
+
+	now := s.now().In(colombia.Location())
+	var consents []*Consent
+	seen := make(map[string]bool)
+	for _, purpose := range req.Purposes {
+		purpose = NormalizePurpose(purpose)
+		if purpose == "" || seen[purpose] {
+			continue
+		}
+		seen[purpose] = true
+		consent := &Consent{
+			ID:            newID("CON-"),
+			TenantID:      controller.TenantID,
+			Subject:       subject,
+			SubjectKey:    subject.Key(),
+			Purpose:       purpose,
+			Granted:       req.Granted,
+			Channel:       channel,
+			PolicyVersion: version,
+			Evidence:      req.Evidence,
+			CollectedBy:   req.CollectedBy,
+			At:            now,
+		}
+		consent.Evidence.Hash = consentHash(consent)
+		consents = append(consents, consent)
+	}
+	if len(consents) == 0 {
+		return nil, fmt.Errorf("indique al menos una finalidad")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]*Consent, 0, len(consents))
+	for _, consent := range consents {
+		s.consents[controller.TenantID] = append(s.consents[controller.TenantID], consent)
+		c := *consent
+		result = append(result, &c)
+	}
+	return result, nil
+}
+
+// consentHash huella del registro: titular, finalidad, decisión, versión de la
+// política, evidencia y fecha
+func consentHash(c *Consent) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		c.TenantID, c.SubjectKey, c.Purpose, fmt.Sprint(c.Granted), c.Channel, c.PolicyVersion,
+		c.Evidence.Text, c.Evidence.Reference, c.Evidence.IP, c.Evidence.UserAgent, c.At.UTC().Format(time.RFC3339Nano),
+	}, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyConsent indica si el registro no fue alterado desde que se guardó
+func VerifyConsent(c *Consent) bool {
+	return consentHash(c) == c.Evidence.Hash
+}
+
+// Consents historial de autorizaciones del tenant, del titular si se indica
+// alguno de sus identificadores
+func (s *Service) Consents(tenantID string, subject Subject) []*Consent {
+	filter := subject.DocumentNumber != "" || subject.Email != "" || subject.Phone != ""
+	if filter {
+		if normalized, err := subject.Normalize(); err == nil {
+			subject = normalized
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result []*Consent
+	for _, consent := range s.consents[tenantID] {
+		if !filter || consent.Subject.Matches(subject) {
+			c := *consent
+			result = append(result, &c)
+		}
+	}
+	return result
+}
+
+// CurrentConsents decisión vigente del titular por finalidad: la más reciente
+func (s *Service) CurrentConsents(tenantID string, subject Subject) map[string]*Consent {
+	current := make(map[string]*Consent)
+	for _, consent := range s.Consents(tenantID, subject) {
+		current[consent.Purpose] = consent
+	}
+	return current
+}
+
+// HasConsent indica si el titular autorizó la finalidad y no la ha revocado
+func (s *Service) HasConsent(tenantID string, subject Subject, purpose string) bool {
+	consent, ok := s.CurrentConsents(tenantID, subject)[NormalizePurpose(purpose)]
+	return ok && consent.Active()
+}
+
+// RevokeConsent revoca las autorizaciones vigentes del titular para las
+// finalidades indicadas, o para todas si no se indica ninguna. Retorna las
+// autorizaciones revocadas.
+func (s *Service) RevokeConsent(tenantID string, subject Subject, purposes []string, reason string) ([]*Consent, error) {
+	subject, err := subject.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]bool)
+	for _, purpose := range purposes {
+		targets[NormalizePurpose(purpose)] = true
+	}
+
+	now := s.now().In(colombia.Location())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var revoked []*Consent
+	for _, consent := range s.consents[tenantID] {
+		if !consent.Active() || !consent.Subject.Matches(subject) {
+			continue
+		}
+		if len(targets) > 0 && !targets[consent.Purpose] {
+			continue
+		}
+		revokedAt := now
+		consent.RevokedAt = &revokedAt
+		consent.RevocationReason = reason
+		c := *consent
+		revoked = append(revoked, &c)
+	}
+	return revoked, nil
+}
+
+// Notice aviso de privacidad para solicitar la autorización (Decreto 1377 de
+// 2013, art. 15): responsable, finalidades, derechos del titular y dónde
+// consultar la política
+func Notice(controller Controller, purposes []string) string {
+	if len(purposes) == 0 {
+		purposes = []string{PurposeOperations}
+	}
+	var descriptions []string
+	for _, purpose := range purposes {
+		purpose = NormalizePurpose(purpose)
+		if description, ok := purposeDescriptions[purpose]; ok {
+			descriptions = append(descriptions, description)
+		} else {
+			descriptions = append(descriptions, strings.ReplaceAll(purpose, "_", " "))
+		}
+	}
+	sort.Strings(descriptions)
+
+	responsible := controller.Name
+	if controller.NIT != "" {
+		responsible += " (NIT " + controller.NIT + ")"
+	}
+	notice := fmt.Sprintf("Autorizo a %s a tratar mis datos personales para %s, conforme a la Ley 1581 de 2012.", responsible, strings.Join(descriptions, "; "))
+	notice += " Como titular puedo conocer, actualizar, rectificar y suprimir mis datos, y revocar esta autorización"
+	if controller.Email != "" {
+		notice += " escribiendo a " + controller.Email
+	}
+	notice += "."
+	if controller.PolicyURL != "" {
+		notice += fmt.Sprintf(" Política de tratamiento de datos (versión %s): %s", firstNonEmpty(controller.PolicyVersion, defaultPolicyVer), controller.PolicyURL)
+	}
+	return notice
+}
@@ -0,0 +1,482 @@
+package habeasdata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server/pkg/colombia"
+)
+
+// ===== SOLICITUDES DE LOS TITULARES =====
+
+// Tipos de solicitud. La consulta (art. 14 de la Ley 1581) se atiende en 10
+// días hábiles prorrogables 5; los reclamos de rectificación, supresión y
+// revocatoria (art. 15) en 15 días hábiles prorrogables 8.
+const (
+	RequestAccess        = "consulta"
+	RequestRectification = "rectificacion"
+	RequestDeletion      = "supresion"
+	RequestRevocation    = "revocatoria"
+)
+
+// Estados de una solicitud
+const (
+	RequestFiled    = "radicada"
+	RequestExtended = "prorrogada"
+	RequestResolved = "resuelta"
+	RequestRejected = "rechazada"
+)
+
+// requestTerms días hábiles para responder y de prórroga de cada tipo
+var requestTerms = map[string]struct{ days, extension int }{
+	RequestAccess:        {10, 5},
+	RequestRectification: {15, 8},
+	RequestDeletion:      {15, 8},
+	RequestRevocation:    {15, 8},
+}
+
+// rectifiable campos que el titular puede pedir corregir
+var rectifiable = map[string]bool{"name": true, "email": true, "phone": true, "address": true}
+
+// Request solicitud de un titular sobre sus datos
+type Request struct {
+	ID              string            `json:"id"`
+	TenantID        string            `json:"tenant_id"`
+	Type            string            `json:"type"`
+	Status          string            `json:"status"`
+	Subject         Subject           `json:"subject"`
+	Description     string            `json:"description,omitempty"`
+	Channel         string            `json:"channel"`
+	Corrections     map[string]string `json:"corrections,omitempty"` // Rectificación: campo → valor correcto
+	Purposes        []string          `json:"purposes,omitempty"`    // Revocatoria: vacío = todas las finalidades
+	ReceivedAt      time.Time         `json:"received_at"`
+	DueAt           time.Time         `json:"due_at"`
+	ExtensionReason string            `json:"extension_reason,omitempty"`
+	ResolvedAt      *time.Time        `json:"resolved_at,omitempty"`
+	Response        string            `json:"response,omitempty"`
+	Outcome         *Outcome          `json:"outcome,omitempty"`
+	History         []RequestChange   `json:"history"`
+
+	// Calculados al consultar
+	BusinessDaysLeft int  `json:"business_days_left"`
+	Overdue          bool `json:"overdue"`
+}
+
+// RequestChange cambio de estado de una solicitud
+type RequestChange struct {
+	Status string    `json:"status"`
+	Note   string    `json:"note,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Outcome lo que se hizo al resolver la solicitud en cada módulo
+type Outcome struct {
+	RevokedConsents int               `json:"revoked_consents,omitempty"`
+	Erased          map[string]int    `json:"erased,omitempty"`    // Módulo → registros suprimidos
+	Rectified       map[string]int    `json:"rectified,omitempty"` // Módulo → registros corregidos
+	Retained        map[string]string `json:"retained,omitempty"`  // Módulo → motivo legal de conservación
+	Pending         []string          `json:"pending,omitempty"`   // Módulos a corregir manualmente
+	Errors          map[string]string `json:"errors,omitempty"`
+}
+
+// Open indica si la solicitud sigue en trámite
+func (r *Request) Open() bool {
+	return r.Status == RequestFiled || r.Status == RequestExtended
+}
+
+// RequestInput datos de una solicitud nueva
+type RequestInput struct {
+	Type        string
+	Subject     Subject
+	Description string
+	Channel     string
+	Corrections map[string]string
+	Purposes    []string
+}
+
+// ParseRequestType interpreta el tipo de solicitud
+func ParseRequestType(value string) (string, error) {
+	switch colombia.NormalizeText(value) {
+	case "consulta", "acceso", "access", "conocer":
+		return RequestAccess, nil
+	case "rectificacion", "actualizacion", "rectification", "correccion":
+		return RequestRectification, nil
+	case "supresion", "eliminacion", "borrado", "deletion", "delete":
+		return RequestDeletion, nil
+	case "revocatoria", "revocacion", "revocation":
+		return RequestRevocation, nil
+	}
+	return "", fmt.Errorf("tipo de solicitud inválido: %s (consulta, rectificacion, supresion o revocatoria)", value)
+}
+
+// FileRequest radica la solicitud del titular y calcula su vencimiento
+func (s *Service) FileRequest(controller Controller, input RequestInput) (*Request, error) {
+	requestType, err := ParseRequestType(input.Type)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := input.Subject.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	corrections := make(map[string]string)
+	if requestType == RequestRectification {
+		for field, value := range input.Corrections {
+			field = strings.ToLower(strings.TrimSpace(field))
+			if !rectifiable[field] {
+				return nil, fmt.Errorf("campo a rectificar no soportado: %s (name, email, phone o address)", field)
+			}
+			corrections[field] = strings.TrimSpace(value)
+		}
+		if len(corrections) == 0 {
+			return nil, fmt.Errorf("indique los datos a rectificar")
+		}
+	}
+	var purposes []string
+	for _, purpose := range input.Purposes {
+		if purpose = NormalizePurpose(purpose); purpose != "" {
+			purposes = append(purposes, purpose)
+		}
+	}
+	channel := colombia.NormalizeText(input.Channel)
+	if channel == "" {
+		channel = ChannelAgent
+	}
+
+	now := s.now().In(colombia.Location())
+	request := &Request{
+		ID:          newID("SOL-"),
+		TenantID:    controller.TenantID,
+		Type:        requestType,
+		Status:      RequestFiled,
+		Subject:     subject,
+		Description: strings.TrimSpace(input.Description),
+		Channel:     channel,
+		Purposes:    purposes,
+		ReceivedAt:  now,
+		DueAt:       endOfDay(colombia.AddBusinessDays(now, requestTerms[requestType].days)),
+		History:     []RequestChange{{Status: RequestFiled, At: now}},
+	}
+	if len(corrections) > 0 {
+		request.Corrections = corrections
+	}
+
+	s.mu.Lock()
+	s.requests[request.ID] = request
+	s.mu.Unlock()
+	return s.GetRequest(controller.TenantID, request.ID)
+}
+
+// GetRequest consulta una solicitud del tenant
+func (s *Service) GetRequest(tenantID, requestID string) (*Request, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	request, ok := s.requests[requestID]
+	if !ok || request.TenantID != tenantID {
+		return nil, fmt.Errorf("%w: %s", ErrRequestNotFound, requestID)
+	}
+	return s.view(request), nil
+}
+
+// Requests solicitudes del tenant por vencimiento, opcionalmente por estado;
+// "vencidas" lista las abiertas con el término vencido
+func (s *Service) Requests(tenantID, status string) []*Request {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*Request
+	for _, request := range s.requests {
+		if request.TenantID != tenantID {
+			continue
+		}
+		view := s.view(request)
+		switch status {
+		case "":
+		case "vencidas":
+			if !view.Overdue {
+				continue
+			}
+		case "abiertas":
+			if !view.Open() {
+				continue
+			}
+		default:
+			if view.Status != status {
+				continue
+			}
+		}
+		result = append(result, view)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DueAt.Before(result[j].DueAt)
+	})
+	return result
+}
+
+// ExtendRequest prorroga una vez el término de la solicitud, informando el
+// motivo al titular, antes de que venza
+func (s *Service) ExtendRequest(tenantID, requestID, reason string) (*Request, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, fmt.Errorf("la prórroga requiere informar el motivo al titular")
+	}
+	now := s.now().In(colombia.Location())
+
+	s.mu.Lock()
+	request, ok := s.requests[requestID]
+	if !ok || request.TenantID != tenantID {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrRequestNotFound, requestID)
+	}
+	switch {
+	case !request.Open():
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrRequestClosed, request.ID)
+	case request.Status == RequestExtended:
+		s.mu.Unlock()
+		return nil, fmt.Errorf("la solicitud %s ya fue prorrogada", request.ID)
+	case now.After(request.DueAt):
+		s.mu.Unlock()
+		return nil, fmt.Errorf("la solicitud %s ya venció: la prórroga debe informarse antes del vencimiento", request.ID)
+	}
+	request.DueAt = endOfDay(colombia.AddBusinessDays(request.DueAt, requestTerms[request.Type].extension))
+	request.Status = RequestExtended
+	request.ExtensionReason = reason
+	request.History = append(request.History, RequestChange{Status: RequestExtended, Note: reason, At: now})
+	s.mu.Unlock()
+
+	return s.GetRequest(tenantID, requestID)
+}
+
+// ResolveRequest responde la solicitud. Si procede: la supresión revoca las
+// autorizaciones y suprime los datos en los módulos que lo permiten; la
+// revocatoria revoca las finalidades pedidas; la rectificación corrige los
+// datos. Una solicitud que no procede requiere el motivo.
+func (s *Service) ResolveRequest(tenantID, requestID string, approved bool, response string) (*Request, error) {
+	request, err := s.GetRequest(tenantID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if !request.Open() {
+		return nil, fmt.Errorf("%w: %s", ErrRequestClosed, request.ID)
+	}
+	response = strings.TrimSpace(response)
+	if !approved && response == "" {
+		return nil, fmt.Errorf("indique al titular el motivo por el que la solicitud no procede")
+	}
+
+	status := RequestRejected
+	var outcome *Outcome
+	if approved {
+		status = RequestResolved
+		outcome, err = s.apply(request)
+		if err != nil {
+			return nil, err
+		}
+		if response == "" {
+			response = defaultResponse(request, outcome)
+		}
+	}
+
+	now := s.now().In(colombia.Location())
+	s.mu.Lock()
+	stored := s.requests[requestID]
+	stored.Status = status
+	stored.Response = response
+	stored.Outcome = outcome
+	stored.ResolvedAt = &now
+	stored.History = append(stored.History, RequestChange{Status: status, Note: response, At: now})
+	s.mu.Unlock()
+
+	return s.GetRequest(tenantID, requestID)
+}
+
+// apply ejecuta la solicitud procedente en el registro y en los módulos
+func (s *Service) apply(request *Request) (*Outcome, error) {
+	outcome := &Outcome{}
+	switch request.Type {
+	case RequestDeletion, RequestRevocation:
+		var purposes []string
+		if request.Type == RequestRevocation {
+			purposes = request.Purposes
+		}
+		revoked, err := s.RevokeConsent(request.TenantID, request.Subject, purposes, "Solicitud "+request.ID)
+		if err != nil {
+			return nil, err
+		}
+		outcome.RevokedConsents = len(revoked)
+		if request.Type == RequestRevocation {
+			return outcome, nil
+		}
+
+		for _, source := range s.sources {
+			if source.Erase == nil {
+				outcome.retain(source.Name, source.Retention)
+				continue
+			}
+			erased, err := source.Erase(request.TenantID, request.Subject)
+			if err != nil {
+				outcome.fail(source.Name, err)
+				continue
+			}
+			if outcome.Erased == nil {
+				outcome.Erased = make(map[string]int)
+			}
+			outcome.Erased[source.Name] = erased
+		}
+		outcome.retain("autorizaciones", "Se conservan como prueba de la autorización y su revocatoria (Decreto 1377 de 2013, art. 8)")
+
+	case RequestRectification:
+		outcome.Rectified = map[string]int{"autorizaciones": s.rectifyConsents(request.TenantID, request.Subject, request.Corrections)}
+		for _, source := range s.sources {
+			if source.Rectify == nil {
+				outcome.Pending = append(outcome.Pending, source.Name)
+				continue
+			}
+			rectified, err := source.Rectify(request.TenantID, request.Subject, request.Corrections)
+			if err != nil {
+				outcome.fail(source.Name, err)
+				continue
+			}
+			outcome.Rectified[source.Name] = rectified
+		}
+	}
+	return outcome, nil
+}
+
+// rectifyConsents corrige los datos de contacto del titular en sus
+// autorizaciones; el identificador y la huella del registro no cambian
+func (s *Service) rectifyConsents(tenantID string, subject Subject, corrections map[string]string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, consent := range s.consents[tenantID] {
+		if !consent.Subject.Matches(subject) {
+			continue
+		}
+		if name, ok := corrections["name"]; ok {
+			consent.Subject.Name = name
+		}
+		if email, ok := corrections["email"]; ok {
+			consent.Subject.Email = strings.ToLower(email)
+		}
+		if value, ok := corrections["phone"]; ok {
+			if phone, err := colombia.ParsePhone(value); err == nil {
+				consent.Subject.Phone = phone.E164
+			}
+		}
+		count++
+	}
+	return count
+}
+
+func (o *Outcome) retain(source, reason string) {
+	if o.Retained == nil {
+		o.Retained = make(map[string]string)
+	}
+	o.Retained[source] = reason
+}
+
+func (o *Outcome) fail(source string, err error) {
+	if o.Errors == nil {
+		o.Errors = make(map[string]string)
+	}
+	o.Errors[source] = err.Error()
+}
+
+// defaultResponse respuesta al titular según lo ejecutado
+func defaultResponse(request *Request, outcome *Outcome) string {
+	switch request.Type {
+	case RequestAccess:
+		return "Adjuntamos la relación de los datos personales que tratamos y de sus autorizaciones."
+	case RequestRevocation:
+		return fmt.Sprintf("Revocamos %d autorizaciones. No volveremos a tratar sus datos para esas finalidades.", outcome.RevokedConsents)
+	case RequestDeletion:
+		response := "Suprimimos sus datos personales y revocamos sus autorizaciones."
+		if len(outcome.Retained) > 0 {
+			response += " Conservamos únicamente los datos que la ley nos obliga a mantener (soportes contables y tributarios y la prueba de la autorización)."
+		}
+		return response
+	case RequestRectification:
+		return "Corregimos sus datos personales según su solicitud."
+	}
+	return ""
+}
+
+// view copia de la solicitud con el plazo restante calculado. Requiere s.mu.
+func (s *Service) view(request *Request) *Request {
+	r := *request
+	r.History = append([]RequestChange(nil), request.History...)
+	r.Purposes = append([]string(nil), request.Purposes...)
+	if request.Corrections != nil {
+		r.Corrections = make(map[string]string, len(request.Corrections))
+		for field, value := range request.Corrections {
+			r.Corrections[field] = value
+		}
+	}
+	if r.Open() {
+		now := s.now().In(colombia.Location())
+		r.BusinessDaysLeft = colombia.BusinessDaysBetween(now, r.DueAt)
+		r.Overdue = now.After(r.DueAt)
+	}
+	return &r
+}
+
+func endOfDay(t time.Time) time.Time {
+	t = t.In(colombia.Location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
+}
+
+// ===== EXPORTACIÓN =====
+
+// Export datos personales del titular en la plataforma, para responder
+// consultas y para la portabilidad
+type Export struct {
+	Subject     Subject           `json:"subject"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Consents    []*Consent        `json:"consents"`
+	Requests    []*Request        `json:"requests"`
+	Records     []Record          `json:"records"`
+	Retention   map[string]string `json:"retention,omitempty"` // Módulo → motivo de conservación
+}
+
+// ExportSubject reúne los datos del titular en el registro y en los módulos
+func (s *Service) ExportSubject(tenantID string, subject Subject) (*Export, error) {
+	subject, err := subject.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	export := &Export{
+		Subject:     subject,
+		GeneratedAt: s.now().In(colombia.Location()),
+		Consents:    s.Consents(tenantID, subject),
+		Records:     []Record{},
+	}
+	for _, consent := range export.Consents {
+		export.Subject = export.Subject.merge(consent.Subject)
+	}
+	for _, request := range s.Requests(tenantID, "") {
+		if request.Subject.Matches(subject) {
+			export.Requests = append(export.Requests, request)
+		}
+	}
+	for _, source := range s.sources {
+		if source.Export != nil {
+			export.Records = append(export.Records, source.Export(tenantID, subject)...)
+		}
+		if source.Erase == nil && source.Retention != "" {
+			if export.Retention == nil {
+				export.Retention = make(map[string]string)
+			}
+			export.Retention[source.Name] = source.Retention
+		}
+	}
+	sort.SliceStable(export.Records, func(i, j int) bool {
+		return export.Records[i].At.Before(export.Records[j].At)
+	})
+	return export, nil
+}
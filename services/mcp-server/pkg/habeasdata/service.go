@@ -0,0 +1,78 @@
+package habeasdata
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errores del registro de habeas data
+var (
+	ErrRequestNotFound = errors.New("solicitud de habeas data no encontrada")
+	ErrRequestClosed   = errors.New("la solicitud ya fue resuelta")
+)
+
+// Controller responsable del tratamiento: el comercio (tenant) con su
+// política de tratamiento de datos
+type Controller struct {
+	TenantID      string
+	Name          string
+	NIT           string
+	Email         string // Canal para consultas y reclamos de los titulares
+	PolicyURL     string
+	PolicyVersion string
+}
+
+// Record dato personal del titular en otro módulo de la plataforma
+type Record struct {
+	Source string      `json:"source"`
+	Kind   string      `json:"kind"`
+	ID     string      `json:"id"`
+	At     time.Time   `json:"at"`
+	Data   interface{} `json:"data"`
+}
+
+// Source módulo que guarda datos personales de los titulares. Sin Erase los
+// datos se conservan por obligación legal y Retention explica el motivo; sin
+// Rectify las correcciones quedan pendientes de aplicar en el módulo.
+type Source struct {
+	Name      string
+	Export    func(tenantID string, subject Subject) []Record
+	Erase     func(tenantID string, subject Subject) (int, error)
+	Rectify   func(tenantID string, subject Subject, corrections map[string]string) (int, error)
+	Retention string
+}
+
+// Service registro de autorizaciones y solicitudes de titulares (Ley 1581 de
+// 2012 y Decreto 1377 de 2013, compilado en el Decreto 1074 de 2015)
+type Service struct {
+	sources []Source
+
+	mu       sync.RWMutex
+	consents map[string][]*Consent // Tenant → autorizaciones, en orden de registro
+	requests map[string]*Request
+	now      func() time.Time
+}
+
+// NewService crea el registro de habeas data
+func NewService() *Service {
+	return &Service{
+		consents: make(map[string][]*Consent),
+		requests: make(map[string]*Request),
+		now:      time.Now,
+	}
+}
+
+// WithSources registra los módulos que guardan datos de los titulares, para
+// las exportaciones y las supresiones
+func (s *Service) WithSources(sources ...Source) *Service {
+	s.sources = append(s.sources, sources...)
+	return s
+}
+
+func newID(prefix string) string {
+	return prefix + strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:16])
+}
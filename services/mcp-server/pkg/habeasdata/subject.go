@@ -0,0 +1,113 @@
+package habeasdata
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"mcp-server/pkg/colombia"
+)
+
+// Subject titular de los datos personales: el cliente final del comercio. Se
+// identifica por documento, correo o celular; basta con uno.
+type Subject struct {
+	Name           string `json:"name,omitempty"`
+	DocumentType   string `json:"document_type,omitempty"` // Abreviatura: CC, CE, PA...
+	DocumentNumber string `json:"document_number,omitempty"`
+	Email          string `json:"email,omitempty"`
+	Phone          string `json:"phone,omitempty"` // E.164
+}
+
+// Normalize valida y normaliza los identificadores del titular
+func (s Subject) Normalize() (Subject, error) {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.DocumentNumber != "" {
+		docType := s.DocumentType
+		if docType == "" {
+			docType = colombia.DocCedulaCiudadania
+		}
+		document, err := colombia.ValidateDocument(docType, s.DocumentNumber)
+		if err != nil {
+			return Subject{}, fmt.Errorf("documento del titular inválido: %w", err)
+		}
+		s.DocumentType, s.DocumentNumber = document.Type, document.Number
+	} else {
+		s.DocumentType = ""
+	}
+	if s.Email != "" {
+		address, err := mail.ParseAddress(strings.TrimSpace(s.Email))
+		if err != nil {
+			return Subject{}, fmt.Errorf("correo del titular inválido: %s", s.Email)
+		}
+		s.Email = strings.ToLower(address.Address)
+	}
+	if s.Phone != "" {
+		phone, err := colombia.ParsePhone(s.Phone)
+		if err != nil {
+			return Subject{}, fmt.Errorf("celular del titular inválido: %w", err)
+		}
+		s.Phone = phone.E164
+	}
+	if s.DocumentNumber == "" && s.Email == "" && s.Phone == "" {
+		return Subject{}, fmt.Errorf("identifique al titular con documento, correo o celular")
+	}
+	return s, nil
+}
+
+// Key identificador estable del titular: documento, correo o celular, en ese
+// orden de preferencia. Requiere un titular normalizado.
+func (s Subject) Key() string {
+	switch {
+	case s.DocumentNumber != "":
+		return "doc:" + s.DocumentType + ":" + s.DocumentNumber
+	case s.Email != "":
+		return "email:" + s.Email
+	default:
+		return "tel:" + s.Phone
+	}
+}
+
+// Matches indica si los datos corresponden al titular: coincide el documento,
+// el correo o el celular. Los datos de other se normalizan antes de comparar.
+func (s Subject) Matches(other Subject) bool {
+	if other.DocumentNumber != "" && s.DocumentNumber != "" {
+		if document, err := colombia.ValidateDocument(firstNonEmpty(other.DocumentType, s.DocumentType), other.DocumentNumber); err == nil && document.Number == s.DocumentNumber {
+			return true
+		}
+	}
+	if other.Email != "" && s.Email != "" && strings.EqualFold(strings.TrimSpace(other.Email), s.Email) {
+		return true
+	}
+	if other.Phone != "" && s.Phone != "" {
+		if phone, err := colombia.ParsePhone(other.Phone); err == nil && phone.E164 == s.Phone {
+			return true
+		}
+	}
+	return false
+}
+
+// merge completa los identificadores del titular con los de other
+func (s Subject) merge(other Subject) Subject {
+	if s.Name == "" {
+		s.Name = other.Name
+	}
+	if s.DocumentNumber == "" {
+		s.DocumentType, s.DocumentNumber = other.DocumentType, other.DocumentNumber
+	}
+	if s.Email == "" {
+		s.Email = other.Email
+	}
+	if s.Phone == "" {
+		s.Phone = other.Phone
+	}
+	return s
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
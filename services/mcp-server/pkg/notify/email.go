@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// sendEmail envía el mensaje como texto plano en UTF-8 por SMTP con STARTTLS
+// (net/smtp lo negocia si el servidor lo anuncia)
+func sendEmail(cfg Config, message Message) error {
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return fmt.Errorf("correo del destinatario inválido: %w", err)
+	}
+	from := mail.Address{Name: cfg.FromName, Address: cfg.FromEmail}
+	if from.Address == "" {
+		from.Address = cfg.SMTPUser
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", from.String())
+	fmt.Fprintf(&body, "To: %s\r\n", to.String())
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body.WriteString(message.Body)
+	body.WriteString("\r\n")
+
+	port := cfg.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if cfg.SMTPUser != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(port))
+	if err := smtp.SendMail(addr, auth, from.Address, []string{to.Address}, body.Bytes()); err != nil {
+		return fmt.Errorf("error enviando correo: %w", err)
+	}
+	return nil
+}
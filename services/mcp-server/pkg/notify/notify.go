@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Canales de notificación
+const (
+	ChannelWhatsApp = "whatsapp"
+	ChannelEmail    = "email"
+)
+
+// Estados de una notificación
+const (
+	StatusSent      = "enviado"
+	StatusSimulated = "simulado" // Sin credenciales: queda solo en la bandeja de salida
+	StatusFailed    = "fallido"
+)
+
+// Config credenciales de envío del tenant. Un canal sin credenciales opera en
+// sandbox: el mensaje se registra pero no se envía.
+type Config struct {
+	TenantID string
+
+	// WhatsApp Cloud API (Meta)
+	WhatsAppPhoneNumberID string
+	WhatsAppToken         string
+
+	// Correo por SMTP
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUser     string
+	SMTPPassword string
+	FromEmail    string
+	FromName     string
+}
+
+// Message mensaje para un cliente final
+type Message struct {
+	Channel   string `json:"channel"`
+	To        string `json:"to"` // E.164 para WhatsApp, dirección para correo
+	Subject   string `json:"subject,omitempty"`
+	Body      string `json:"body"`
+	Reference string `json:"reference,omitempty"` // Objeto que originó el mensaje (envío, pedido)
+}
+
+// Delivery notificación registrada en la bandeja de salida del tenant
+type Delivery struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenant_id"`
+	Message    Message   `json:"message"`
+	Status     string    `json:"status"`
+	ProviderID string    `json:"provider_id,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Service envía notificaciones por WhatsApp y correo y guarda la bandeja de
+// salida de cada tenant
+type Service struct {
+	httpClient  *http.Client
+	whatsAppURL string
+
+	mu     sync.RWMutex
+	outbox map[string][]*Delivery
+	now    func() time.Time
+}
+
+// NewService crea el servicio de notificaciones
+func NewService() *Service {
+	return &Service{
+		httpClient:  &http.Client{Timeout: 15 * time.Second},
+		whatsAppURL: WhatsAppGraphURL,
+		outbox:      make(map[string][]*Delivery),
+		now:         time.Now,
+	}
+}
+
+// Send envía el mensaje por su canal. Un fallo del proveedor queda registrado
+// en la bandeja de salida y también se retorna.
+func (s *Service) Send(ctx context.Context, cfg Config, message Message) (*Delivery, error) {
+	message.To = strings.TrimSpace(message.To)
+	if message.To == "" {
+		return nil, fmt.Errorf("destinatario requerido para %s", message.Channel)
+	}
+
+	delivery := &Delivery{
+		ID:        "NT-" + strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:16]),
+		TenantID:  cfg.TenantID,
+		Message:   message,
+		CreatedAt: s.now(),
+	}
+
+	var providerID string
+	var err error
+	switch message.Channel {
+	case ChannelWhatsApp:
+		if cfg.WhatsAppToken == "" || cfg.WhatsAppPhoneNumberID == "" {
+			delivery.Status = StatusSimulated
+			break
+		}
+		providerID, err = s.sendWhatsApp(ctx, cfg, message)
+	case ChannelEmail:
+		if cfg.SMTPHost == "" {
+			delivery.Status = StatusSimulated
+			break
+		}
+		err = sendEmail(cfg, message)
+	default:
+		return nil, fmt.Errorf("canal de notificación no soportado: %s", message.Channel)
+	}
+
+	if delivery.Status == "" {
+		delivery.Status = StatusSent
+		delivery.ProviderID = providerID
+		if err != nil {
+			delivery.Status = StatusFailed
+			delivery.Error = err.Error()
+		}
+	}
+
+	s.mu.Lock()
+	s.outbox[cfg.TenantID] = append(s.outbox[cfg.TenantID], delivery)
+	s.mu.Unlock()
+
+	sent := *delivery
+	return &sent, err
+}
+
+// Outbox notificaciones del tenant, las más recientes primero, opcionalmente
+// de una referencia
+func (s *Service) Outbox(tenantID, reference string) []Delivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []Delivery
+	for _, delivery := range s.outbox[tenantID] {
+		if reference == "" || delivery.Message.Reference == reference {
+			result = append(result, *delivery)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+// EraseRecipient elimina de la bandeja de salida los mensajes enviados a los
+// destinatarios que cumplen match
+func (s *Service) EraseRecipient(tenantID string, match func(to string) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.outbox[tenantID][:0]
+	erased := 0
+	for _, delivery := range s.outbox[tenantID] {
+		if match(delivery.Message.To) {
+			erased++
+			continue
+		}
+		kept = append(kept, delivery)
+	}
+	s.outbox[tenantID] = kept
+	return erased
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WhatsAppGraphURL API de WhatsApp Cloud (Graph API de Meta)
+const WhatsAppGraphURL = "https://graph.facebook.com/v19.0"
+
+// sendWhatsApp envía un mensaje de texto. Fuera de la ventana de 24 horas
+// desde el último mensaje del cliente Meta exige plantillas aprobadas; en ese
+// caso la API responde error y la notificación queda fallida.
+func (s *Service) sendWhatsApp(ctx context.Context, cfg Config, message Message) (string, error) {
+	// La API recibe el número sin el "+"
+	to := strings.TrimPrefix(message.To, "+")
+	payload, err := json.Marshal(map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                to,
+		"type":              "text",
+		"text": map[string]interface{}{
+			"preview_url": true,
+			"body":        message.Body,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := s.whatsAppURL + "/" + url.PathEscape(cfg.WhatsAppPhoneNumberID) + "/messages"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.WhatsAppToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error conectando con WhatsApp: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+		Error struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &result)
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("WhatsApp respondió %d: %s (código %d)", resp.StatusCode, result.Error.Message, result.Error.Code)
+	}
+	if len(result.Messages) == 0 {
+		return "", fmt.Errorf("respuesta inválida de WhatsApp")
+	}
+	return result.Messages[0].ID, nil
+}
@@ -0,0 +1,377 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"mcp-server/pkg/colombia"
+)
+
+// ===== EFECTIVO: EFECTY Y BALOTO =====
+
+// CashNetwork red de recaudo en efectivo
+type CashNetwork struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// Instrucciones para el pagador: convenio, referencia y fecha límite
+	instructions string
+}
+
+// CashNetworks redes de recaudo soportadas
+var CashNetworks = map[string]CashNetwork{
+	ProviderEfecty: {Code: ProviderEfecty, Name: "Efecty", instructions: "Paga en cualquier punto Efecty indicando el convenio %s y la referencia %s antes del %s"},
+	ProviderBaloto: {Code: ProviderBaloto, Name: "Baloto", instructions: "Paga en cualquier punto Baloto con el código de convenio %s y la referencia %s antes del %s"},
+}
+
+// SandboxAgreement convenio usado por los tenants sin convenio de recaudo
+const SandboxAgreement = "000000"
+
+// CashVoucherDays días hábiles que tiene el pagador para pagar en efectivo
+const CashVoucherDays = 2
+
+// CashVoucher referencia de pago en efectivo
+type CashVoucher struct {
+	Network      string `json:"network"`
+	NetworkName  string `json:"network_name"`
+	Agreement    string `json:"agreement"`    // Convenio de recaudo del comercio
+	PaymentCode  string `json:"payment_code"` // Referencia que digita el cajero
+	Instructions string `json:"instructions"`
+	Receipt      string `json:"receipt,omitempty"` // Comprobante del pago en el punto
+}
+
+// CashRequest solicitud de una referencia de pago en efectivo
+type CashRequest struct {
+	OrderID     string
+	Network     string // efecty, baloto
+	Amount      int64
+	Description string
+	Customer    Customer
+	ExpiresAt   time.Time // Vacío: CashVoucherDays días hábiles
+}
+
+// CashConfirmation pago reportado por la red de recaudo
+type CashConfirmation struct {
+	PaymentCode string
+	Network     string
+	Amount      int64
+	Receipt     string
+}
+
+// CreateCashVoucher genera la referencia para pagar el pedido en efectivo. El
+// pago queda pendiente hasta que la red confirme el recaudo o venza la
+// referencia (lo resuelve la conciliación).
+func (s *Service) CreateCashVoucher(ctx context.Context, cfg TenantConfig, req CashRequest) (*Payment, error) {
+	network, ok := CashNetworks[strings.ToLower(req.Network)]
+	if !ok {
+		return nil, fmt.Errorf("red de recaudo '%s' no soportada (efecty, baloto)", req.Network)
+	}
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("el monto del pago debe ser mayor a cero")
+	}
+
+	screeningID, err := s.screenCustomer(cfg, req.OrderID, req.Customer)
+	if err != nil {
+		return nil, err
+	}
+	code, err := cashPaymentCode()
+	if err != nil {
+		return nil, err
+	}
+	agreement := cfg.CashAgreements[network.Code]
+	if agreement == "" {
+		agreement = SandboxAgreement
+	}
+
+	now := s.now().In(colombia.Location())
+	expiresAt := req.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = colombia.AddBusinessDays(now, CashVoucherDays)
+	}
+
+	payment := s.newPayment(cfg, req.OrderID, MethodCash, req.Amount, now)
+	payment.Provider = network.Code
+	payment.Sandbox = agreement == SandboxAgreement
+	payment.Description = req.Description
+	payment.Customer = req.Customer
+	payment.ScreeningID = screeningID
+	payment.ExpiresAt = &expiresAt
+	payment.Voucher = &CashVoucher{
+		Network:      network.Code,
+		NetworkName:  network.Name,
+		Agreement:    agreement,
+		PaymentCode:  code,
+		Instructions: fmt.Sprintf(network.instructions, agreement, code, expiresAt.Format("2006-01-02 15:04")),
+	}
+
+	s.store(payment)
+	s.mu.Lock()
+	s.references[cashKey(code)] = payment.ID
+	s.mu.Unlock()
+	return s.GetPayment(cfg.TenantID, payment.ID)
+}
+
+// ConfirmCashPayment aplica el recaudo reportado por la red (archivo de
+// recaudo o notificación). Verifica el dígito de control y el monto.
+func (s *Service) ConfirmCashPayment(tenantID string, confirmation CashConfirmation) (*Payment, error) {
+	code := strings.TrimSpace(confirmation.PaymentCode)
+	if !luhnValid(code) {
+		return nil, fmt.Errorf("la referencia de pago %s no es válida", code)
+	}
+
+	s.mu.RLock()
+	paymentID, ok := s.references[cashKey(code)]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: referencia %s", ErrPaymentNotFound, code)
+	}
+	payment, err := s.GetPayment(tenantID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if confirmation.Network != "" && !strings.EqualFold(confirmation.Network, payment.Provider) {
+		return nil, fmt.Errorf("la referencia %s es de %s, no de %s", code, payment.Voucher.NetworkName, confirmation.Network)
+	}
+	if confirmation.Amount != payment.Amount {
+		return nil, fmt.Errorf("el monto recaudado $%d no coincide con el del pago $%d", confirmation.Amount, payment.Amount)
+	}
+	if payment.Status == StatusApproved {
+		return payment, nil
+	}
+	if payment.Final() && payment.Status != StatusExpired {
+		return nil, fmt.Errorf("el pago %s está %s y no admite recaudo", payment.Reference, payment.Status)
+	}
+
+	s.mu.Lock()
+	if stored, ok := s.payments[paymentID]; ok {
+		stored.Voucher.Receipt = confirmation.Receipt
+	}
+	s.mu.Unlock()
+
+	message := "Pagado en " + payment.Voucher.NetworkName
+	if confirmation.Receipt != "" {
+		message += ", comprobante " + confirmation.Receipt
+	}
+	s.setStatus(paymentID, StatusApproved, message, SourceCash)
+	return s.GetPayment(tenantID, paymentID)
+}
+
+// cashPaymentCode referencia numérica de 12 dígitos; el último es dígito de
+// control (Luhn) para detectar errores de digitación en el punto de pago
+func cashPaymentCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1e11))
+	if err != nil {
+		return "", fmt.Errorf("no se pudo generar la referencia de pago: %w", err)
+	}
+	base := fmt.Sprintf("%011d", n.Int64())
+	return base + luhnDigit(base), nil
+}
+
+func luhnDigit(digits string) string {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return fmt.Sprint((10 - sum%10) % 10)
+}
+
+func luhnValid(code string) bool {
+	if len(code) < 2 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return luhnDigit(code[:len(code)-1]) == code[len(code)-1:]
+}
+
+func cashKey(code string) string {
+	return "efectivo:" + code
+}
+
+// ===== CONTRAENTREGA =====
+
+// Estados de envío que resuelven un pago contraentrega
+const (
+	ShipmentDelivered = "entregado"
+	ShipmentReturned  = "devuelto"
+)
+
+// shipmentStatusAliases estados equivalentes que reportan las transportadoras
+var shipmentStatusAliases = map[string]string{
+	"entregado":                ShipmentDelivered,
+	"entregada":                ShipmentDelivered,
+	"delivered":                ShipmentDelivered,
+	"entrega exitosa":          ShipmentDelivered,
+	"devuelto":                 ShipmentReturned,
+	"devuelta":                 ShipmentReturned,
+	"devolucion":               ShipmentReturned,
+	"devuelto al remitente":    ShipmentReturned,
+	"returned":                 ShipmentReturned,
+	"rechazado":                ShipmentReturned,
+	"rechazado destinatario":   ShipmentReturned,
+	"rechazado por el cliente": ShipmentReturned,
+}
+
+// ShipmentRef envío que recauda un pago contraentrega
+type ShipmentRef struct {
+	Carrier         string     `json:"carrier,omitempty"`
+	TrackingNumber  string     `json:"tracking_number,omitempty"`
+	Status          string     `json:"status,omitempty"` // Último estado reportado
+	CollectedAmount int64      `json:"collected_amount_cop,omitempty"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+}
+
+// CODRequest solicitud de pago contraentrega
+type CODRequest struct {
+	OrderID        string
+	Amount         int64
+	Description    string
+	Customer       Customer
+	Carrier        string // Puede asignarse después con AttachShipment
+	TrackingNumber string
+}
+
+// ShipmentEvent evento de la transportadora para un envío
+type ShipmentEvent struct {
+	Carrier         string
+	TrackingNumber  string
+	Status          string
+	Description     string
+	CollectedAmount int64 // Vacío: se asume el valor completo al entregar
+	At              time.Time
+}
+
+// CreateCashOnDelivery registra el pago contraentrega de un pedido. El pedido
+// queda listo para despacho y el pago se resuelve con los eventos del envío.
+func (s *Service) CreateCashOnDelivery(ctx context.Context, cfg TenantConfig, req CODRequest) (*Payment, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("el monto a recaudar debe ser mayor a cero")
+	}
+	screeningID, err := s.screenCustomer(cfg, req.OrderID, req.Customer)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().In(colombia.Location())
+	payment := s.newPayment(cfg, req.OrderID, MethodCashOnDelivery, req.Amount, now)
+	payment.Provider = carrierCode(req.Carrier)
+	payment.Sandbox = false
+	payment.Description = req.Description
+	payment.Customer = req.Customer
+	payment.ScreeningID = screeningID
+	payment.Shipment = &ShipmentRef{Carrier: req.Carrier, TrackingNumber: req.TrackingNumber}
+
+	s.store(payment)
+	if req.TrackingNumber != "" {
+		s.mu.Lock()
+		s.references[trackingKey(cfg.TenantID, req.TrackingNumber)] = payment.ID
+		s.mu.Unlock()
+	}
+	return s.GetPayment(cfg.TenantID, payment.ID)
+}
+
+// AttachShipment asocia la guía que recauda un pago contraentrega
+func (s *Service) AttachShipment(tenantID, paymentID, carrier, trackingNumber string) (*Payment, error) {
+	if trackingNumber == "" {
+		return nil, fmt.Errorf("el número de guía es requerido")
+	}
+
+	s.mu.Lock()
+	payment, ok := s.payments[paymentID]
+	if !ok || payment.TenantID != tenantID {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrPaymentNotFound, paymentID)
+	}
+	if payment.Method != MethodCashOnDelivery {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("el pago %s no es contraentrega", payment.Reference)
+	}
+	if payment.Shipment.TrackingNumber != "" {
+		delete(s.references, trackingKey(tenantID, payment.Shipment.TrackingNumber))
+	}
+	payment.Provider = carrierCode(carrier)
+	payment.Shipment.Carrier = carrier
+	payment.Shipment.TrackingNumber = trackingNumber
+	payment.UpdatedAt = s.now().In(colombia.Location())
+	s.references[trackingKey(tenantID, trackingNumber)] = paymentID
+	s.mu.Unlock()
+
+	return s.GetPayment(tenantID, paymentID)
+}
+
+// HandleShipmentEvent concilia un pago contraentrega con el evento de la
+// transportadora: entregado aprueba el pago y devuelto lo rechaza
+func (s *Service) HandleShipmentEvent(tenantID string, event ShipmentEvent) (*Payment, error) {
+	s.mu.Lock()
+	paymentID, ok := s.references[trackingKey(tenantID, event.TrackingNumber)]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: guía %s", ErrPaymentNotFound, event.TrackingNumber)
+	}
+	payment := s.payments[paymentID]
+	at := event.At
+	if at.IsZero() {
+		at = s.now()
+	}
+	at = at.In(colombia.Location())
+	status := normalizeShipmentStatus(event.Status)
+	payment.Shipment.Status = status
+	payment.Shipment.UpdatedAt = &at
+	if status == ShipmentDelivered {
+		collected := event.CollectedAmount
+		if collected == 0 {
+			collected = payment.Amount
+		}
+		payment.Shipment.CollectedAmount = collected
+	}
+	amount, collected := payment.Amount, payment.Shipment.CollectedAmount
+	s.mu.Unlock()
+
+	switch status {
+	case ShipmentDelivered:
+		message := "Recaudado por la transportadora al entregar"
+		if collected != amount {
+			message = fmt.Sprintf("Entregado con recaudo de $%d sobre $%d: revisar con la transportadora", collected, amount)
+		}
+		s.setStatus(paymentID, StatusApproved, message, SourceShipment)
+	case ShipmentReturned:
+		message := "Envío devuelto sin recaudo"
+		if event.Description != "" {
+			message += ": " + event.Description
+		}
+		s.setStatus(paymentID, StatusDeclined, message, SourceShipment)
+	}
+	return s.GetPayment(tenantID, paymentID)
+}
+
+func normalizeShipmentStatus(status string) string {
+	normalized := colombia.NormalizeText(status)
+	if alias, ok := shipmentStatusAliases[normalized]; ok {
+		return alias
+	}
+	return normalized
+}
+
+func carrierCode(carrier string) string {
+	if carrier == "" {
+		return "transportadora"
+	}
+	return strings.ReplaceAll(colombia.NormalizeText(carrier), " ", "_")
+}
+
+func trackingKey(tenantID, trackingNumber string) string {
+	return "guia:" + tenantID + ":" + strings.ToUpper(strings.TrimSpace(trackingNumber))
+}
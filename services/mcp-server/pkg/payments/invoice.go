@@ -0,0 +1,160 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"mcp-server/pkg/dian"
+)
+
+// ===== FACTURACIÓN DE PAGOS Y NOTAS CRÉDITO =====
+
+// creditNoteTimeout tiempo máximo para emitir una nota crédito ante la DIAN
+const creditNoteTimeout = 2 * time.Minute
+
+// WithInvoicing emite con el servicio DIAN la nota crédito de cada reembolso
+// o anulación aprobada de un pago facturado
+func (s *Service) WithInvoicing(dianService *dian.Service) *Service {
+	s.dian = dianService
+	return s
+}
+
+// LinkInvoice asocia el pago a la factura DIAN emitida por él. Los reembolsos
+// ya aprobados que no tengan nota crédito la reciben en ese momento.
+func (s *Service) LinkInvoice(ctx context.Context, cfg TenantConfig, paymentID, invoiceID string) (*Payment, error) {
+	if s.dian == nil {
+		return nil, fmt.Errorf("la facturación DIAN no está configurada en el servicio de pagos")
+	}
+	s.rememberConfig(cfg)
+
+	submission, err := s.dian.GetSubmission(cfg.TenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if submission.Kind != dian.KindInvoice {
+		return nil, fmt.Errorf("el documento %s no es una factura de venta", submission.Number)
+	}
+	if submission.Status == dian.SubmissionRejected {
+		return nil, fmt.Errorf("la factura %s fue rechazada por la DIAN", submission.Number)
+	}
+
+	s.mu.Lock()
+	payment, ok := s.payments[paymentID]
+	if !ok || payment.TenantID != cfg.TenantID {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrPaymentNotFound, paymentID)
+	}
+	if payment.InvoiceID != "" && payment.InvoiceID != invoiceID {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("el pago %s ya está asociado a otra factura", payment.Reference)
+	}
+	payment.InvoiceID = invoiceID
+	payment.UpdatedAt = s.now()
+	var pending []string
+	for _, refund := range payment.Refunds {
+		if refund.Status == RefundApproved && refund.CreditNoteID == "" {
+			pending = append(pending, refund.ID)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, refundID := range pending {
+		s.issueCreditNote(paymentID, refundID)
+	}
+	return s.GetPayment(cfg.TenantID, paymentID)
+}
+
+// issueCreditNote emite la nota crédito de un reembolso aprobado si el pago
+// está facturado. El resultado (o el error) queda en el reembolso.
+func (s *Service) issueCreditNote(paymentID, refundID string) {
+	if s.dian == nil {
+		return
+	}
+
+	s.mu.Lock()
+	payment, ok := s.payments[paymentID]
+	if !ok || payment.InvoiceID == "" || s.crediting[refundID] {
+		s.mu.Unlock()
+		return
+	}
+	refund := payment.refund(refundID)
+	if refund == nil || refund.Status != RefundApproved || refund.CreditNoteID != "" {
+		s.mu.Unlock()
+		return
+	}
+	s.crediting[refundID] = true
+	cfg := s.configs[payment.TenantID]
+	snapshot, credited := payment.clone(), *refund
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), creditNoteTimeout)
+	defer cancel()
+	submission, err := s.creditNote(ctx, cfg, snapshot, credited)
+
+	s.mu.Lock()
+	delete(s.crediting, refundID)
+	if refund := payment.refund(refundID); refund != nil {
+		if err != nil {
+			refund.CreditNoteError = err.Error()
+		} else {
+			refund.CreditNoteID = submission.ID
+			refund.CreditNoteNumber = submission.Number
+			refund.CreditNoteError = ""
+		}
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("⚠️ Nota crédito del reembolso %s (pago %s): %v", refundID, snapshot.Reference, err)
+	}
+}
+
+// creditNote arma y envía la nota crédito por el valor del reembolso, sin
+// superar el saldo de la factura. Un reverso que cubre toda la factura la anula.
+func (s *Service) creditNote(ctx context.Context, cfg TenantConfig, payment *Payment, refund Refund) (*dian.Submission, error) {
+	submission, err := s.dian.GetSubmission(payment.TenantID, payment.InvoiceID)
+	if err != nil {
+		return nil, err
+	}
+	invoice, ok := submission.Document.(*dian.Invoice)
+	if !ok {
+		return nil, fmt.Errorf("el documento %s no es una factura de venta", submission.Number)
+	}
+	balance, err := s.dian.CreditBalance(payment.TenantID, payment.InvoiceID)
+	if err != nil {
+		return nil, err
+	}
+	amount := int(refund.Amount)
+	if amount > balance || payment.Refunded >= payment.Amount {
+		// El último reverso acredita lo que queda de la factura
+		amount = balance
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("la factura %s ya está acreditada en su totalidad", submission.Number)
+	}
+
+	note := &dian.CreditNote{
+		ReasonCode: dian.CreditReasonPartialReturn,
+		Reason:     "Devolución parcial del pago " + payment.Reference,
+		Lines:      dian.CreditLines(invoice, amount),
+		Notes:      fmt.Sprintf("%s %s del pago %s", refund.Kind, refund.ID, payment.Reference),
+	}
+	if amount >= invoice.Total {
+		note.ReasonCode = dian.CreditReasonCancellation
+		note.Reason = "Anulación de la factura por reverso del pago " + payment.Reference
+	}
+	if refund.Reason != "" {
+		note.Reason += ": " + refund.Reason
+	}
+
+	result, err := s.dian.IssueCreditNote(ctx, cfg.DIAN, payment.InvoiceID, note)
+	if err != nil {
+		return nil, err
+	}
+	if result.Status == dian.SubmissionRejected {
+		return nil, fmt.Errorf("la DIAN rechazó la nota crédito %s", result.Number)
+	}
+	return result, nil
+}
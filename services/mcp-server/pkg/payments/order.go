@@ -0,0 +1,168 @@
+package payments
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"mcp-server/pkg/colombia"
+)
+
+// ===== ESTADO DEL PEDIDO =====
+
+// Estados de pago de un pedido
+const (
+	OrderAwaitingPayment = "pendiente_pago"
+	OrderCashOnDelivery  = "por_cobrar" // Contraentrega: se despacha y se cobra al entregar
+	OrderPaid            = "pagado"
+	OrderPaymentFailed   = "pago_rechazado"
+	OrderPaymentExpired  = "pago_vencido"
+	OrderPaymentVoided   = "pago_anulado"
+	OrderRefunded        = "pago_reembolsado"
+)
+
+// orderTransitions transiciones válidas del estado de pago de un pedido. Un
+// pedido rechazado o vencido admite un nuevo intento con otro medio; uno
+// pagado solo puede anularse o reembolsarse.
+var orderTransitions = map[string][]string{
+	OrderAwaitingPayment: {OrderCashOnDelivery, OrderPaid, OrderPaymentFailed, OrderPaymentExpired, OrderPaymentVoided},
+	OrderCashOnDelivery:  {OrderPaid, OrderPaymentFailed, OrderAwaitingPayment, OrderPaymentVoided},
+	OrderPaymentFailed:   {OrderAwaitingPayment, OrderCashOnDelivery, OrderPaid},
+	OrderPaymentExpired:  {OrderAwaitingPayment, OrderCashOnDelivery, OrderPaid},
+	OrderPaid:            {OrderPaymentVoided, OrderRefunded},
+}
+
+// Order estado de pago de un pedido, alimentado por todos sus pagos sin
+// importar el medio (Wompi, efectivo o contraentrega)
+type Order struct {
+	ID        string        `json:"id"`
+	TenantID  string        `json:"tenant_id"`
+	Status    string        `json:"status"`
+	PaymentID string        `json:"payment_id"` // Pago que definió el estado actual
+	Method    string        `json:"method"`
+	Amount    int64         `json:"amount_cop"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	History   []OrderChange `json:"history"`
+
+	Fulfillment *Fulfillment `json:"fulfillment,omitempty"`
+}
+
+// Fulfillment estado del despacho de un pedido según el rastreo de su guía
+type Fulfillment struct {
+	ShipmentID     string    `json:"shipment_id"`
+	Carrier        string    `json:"carrier"`
+	TrackingNumber string    `json:"tracking_number"`
+	Status         string    `json:"status"` // Estado normalizado del envío
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// OrderChange cambio de estado de un pedido
+type OrderChange struct {
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	PaymentID string    `json:"payment_id"`
+	Method    string    `json:"method"`
+	At        time.Time `json:"at"`
+}
+
+// ReadyToShip indica si el pedido se puede despachar: pagado o contraentrega
+func (o *Order) ReadyToShip() bool {
+	return o.Status == OrderPaid || o.Status == OrderCashOnDelivery
+}
+
+// CanTransition indica si el pedido puede pasar al estado to
+func (o *Order) CanTransition(to string) bool {
+	for _, allowed := range orderTransitions[o.Status] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// GetOrder obtiene el estado de pago de un pedido del tenant
+func (s *Service) GetOrder(tenantID, orderID string) (*Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	order, ok := s.orders[orderKey(tenantID, orderID)]
+	if !ok {
+		return nil, fmt.Errorf("pedido %s sin pagos registrados", orderID)
+	}
+	c := *order
+	c.History = append([]OrderChange(nil), order.History...)
+	if order.Fulfillment != nil {
+		fulfillment := *order.Fulfillment
+		c.Fulfillment = &fulfillment
+	}
+	return &c, nil
+}
+
+// UpdateFulfillment registra el estado del despacho del pedido. El estado de
+// pago no cambia aquí: la contraentrega se resuelve con HandleShipmentEvent.
+func (s *Service) UpdateFulfillment(tenantID, orderID string, fulfillment Fulfillment) (*Order, error) {
+	s.mu.Lock()
+	order, ok := s.orders[orderKey(tenantID, orderID)]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("pedido %s sin pagos registrados", orderID)
+	}
+	if fulfillment.UpdatedAt.IsZero() {
+		fulfillment.UpdatedAt = s.now()
+	}
+	fulfillment.UpdatedAt = fulfillment.UpdatedAt.In(colombia.Location())
+	order.Fulfillment = &fulfillment
+	s.mu.Unlock()
+
+	return s.GetOrder(tenantID, orderID)
+}
+
+// trackOrderLocked mueve el pedido del pago según la máquina de estados. Un
+// pago que no puede cambiar el estado (por ejemplo un segundo intento
+// rechazado de un pedido ya pagado) se registra y se ignora. Requiere s.mu.
+func (s *Service) trackOrderLocked(payment *Payment, at time.Time) {
+	if payment.OrderID == "" {
+		return
+	}
+	key := orderKey(payment.TenantID, payment.OrderID)
+	to := payment.OrderStatus()
+
+	order, ok := s.orders[key]
+	if !ok {
+		s.orders[key] = &Order{
+			ID:        payment.OrderID,
+			TenantID:  payment.TenantID,
+			Status:    to,
+			PaymentID: payment.ID,
+			Method:    payment.Method,
+			Amount:    payment.Amount,
+			UpdatedAt: at,
+			History:   []OrderChange{{To: to, PaymentID: payment.ID, Method: payment.Method, At: at}},
+		}
+		return
+	}
+	if order.Status == to && order.PaymentID == payment.ID {
+		return
+	}
+	if order.Status != to && !order.CanTransition(to) {
+		log.Printf("⚠️ Pedido %s: el pago %s (%s) no cambia el estado %s a %s", order.ID, payment.Reference, payment.Method, order.Status, to)
+		return
+	}
+
+	order.History = append(order.History, OrderChange{
+		From:      order.Status,
+		To:        to,
+		PaymentID: payment.ID,
+		Method:    payment.Method,
+		At:        at,
+	})
+	order.Status = to
+	order.PaymentID = payment.ID
+	order.Method = payment.Method
+	order.Amount = payment.Amount
+	order.UpdatedAt = at
+}
+
+func orderKey(tenantID, orderID string) string {
+	return tenantID + ":" + orderID
+}
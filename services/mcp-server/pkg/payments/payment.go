@@ -0,0 +1,137 @@
+package payments
+
+import (
+	"time"
+)
+
+// Medios de pago
+const (
+	MethodPSE                 = "pse"
+	MethodNequi               = "nequi"
+	MethodCard                = "card"
+	MethodBancolombiaTransfer = "bancolombia_transfer"
+	MethodLink                = "link" // Link de pago: el pagador elige el medio en el checkout
+	MethodCashOnDelivery      = "contraentrega"
+	MethodCash                = "efectivo" // Recaudo en Efecty o Baloto con referencia de pago
+)
+
+// Estados de un pago
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusDeclined = "declined"
+	StatusVoided   = "voided"
+	StatusError    = "error"
+	StatusExpired  = "expired"
+	StatusRefunded = "refunded" // Reembolsado en su totalidad
+)
+
+// Proveedores de pago
+const (
+	ProviderWompi  = "wompi"
+	ProviderEfecty = "efecty"
+	ProviderBaloto = "baloto"
+)
+
+// Payment pago de un pedido ante una pasarela
+type Payment struct {
+	ID            string         `json:"id"`
+	TenantID      string         `json:"tenant_id"`
+	OrderID       string         `json:"order_id,omitempty"`
+	Reference     string         `json:"reference"` // Referencia única enviada a la pasarela
+	Provider      string         `json:"provider"`
+	ProviderID    string         `json:"provider_id,omitempty"`      // Id de la transacción en la pasarela
+	ProviderLink  string         `json:"provider_link_id,omitempty"` // Id del link de pago
+	Method        string         `json:"method"`
+	Bank          string         `json:"bank,omitempty"` // Entidad financiera PSE
+	Status        string         `json:"status"`
+	StatusMessage string         `json:"status_message,omitempty"`
+	Amount        int64          `json:"amount_cop"`
+	Currency      string         `json:"currency"`
+	Description   string         `json:"description,omitempty"`
+	Customer      Customer       `json:"customer"`
+	PaymentURL    string         `json:"payment_url,omitempty"` // Redirección del pagador (PSE, Bancolombia, link)
+	RedirectURL   string         `json:"redirect_url,omitempty"`
+	Sandbox       bool           `json:"sandbox"`
+	ExpiresAt     *time.Time     `json:"expires_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	FinalizedAt   *time.Time     `json:"finalized_at,omitempty"`
+	Voucher       *CashVoucher   `json:"voucher,omitempty"`      // Pagos en efectivo
+	Shipment      *ShipmentRef   `json:"shipment,omitempty"`     // Contraentrega
+	InvoiceID     string         `json:"invoice_id,omitempty"`   // Factura DIAN emitida por el pago
+	ScreeningID   string         `json:"screening_id,omitempty"` // Consulta SARLAFT con coincidencias en revisión
+	Refunded      int64          `json:"refunded_cop,omitempty"` // Reembolsos y anulación aprobados
+	Refunds       []Refund       `json:"refunds,omitempty"`
+	History       []StatusChange `json:"history"`
+}
+
+// Customer pagador
+type Customer struct {
+	Email          string `json:"email"`
+	Name           string `json:"name,omitempty"`
+	Phone          string `json:"phone,omitempty"`
+	DocumentType   string `json:"document_type,omitempty"`
+	DocumentNumber string `json:"document_number,omitempty"`
+	PersonType     string `json:"person_type,omitempty"` // natural, juridica
+}
+
+// StatusChange cambio de estado de un pago y su origen
+type StatusChange struct {
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+	Source  string    `json:"source"` // api, webhook, consulta
+	At      time.Time `json:"at"`
+}
+
+// Orígenes de un cambio de estado
+const (
+	SourceAPI      = "api"
+	SourceWebhook  = "webhook"
+	SourcePolling  = "consulta"
+	SourceShipment = "envio"   // Evento de entrega de la transportadora
+	SourceCash     = "recaudo" // Confirmación de la red de recaudo
+)
+
+// Final indica si el pago ya no cambiará de estado (salvo anulación)
+func (p *Payment) Final() bool {
+	return p.Status != StatusPending
+}
+
+// OrderStatus estado del pedido según su pago. La contraentrega pendiente
+// deja el pedido listo para despacho.
+func (p *Payment) OrderStatus() string {
+	switch p.Status {
+	case StatusApproved:
+		return OrderPaid
+	case StatusPending:
+		if p.Method == MethodCashOnDelivery {
+			return OrderCashOnDelivery
+		}
+		return OrderAwaitingPayment
+	case StatusVoided:
+		return OrderPaymentVoided
+	case StatusRefunded:
+		return OrderRefunded
+	case StatusExpired:
+		return OrderPaymentExpired
+	default:
+		return OrderPaymentFailed
+	}
+}
+
+// clone copia el pago para entregarlo fuera del servicio
+func (p *Payment) clone() *Payment {
+	c := *p
+	c.History = append([]StatusChange(nil), p.History...)
+	c.Refunds = append([]Refund(nil), p.Refunds...)
+	if p.Voucher != nil {
+		voucher := *p.Voucher
+		c.Voucher = &voucher
+	}
+	if p.Shipment != nil {
+		shipment := *p.Shipment
+		c.Shipment = &shipment
+	}
+	return &c
+}
@@ -0,0 +1,126 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/payments/wompi"
+)
+
+// ===== BANCOS PSE =====
+
+// BanksTTL vigencia de la lista de bancos PSE en cache
+const BanksTTL = 24 * time.Hour
+
+// bankList lista de bancos PSE consultada en un momento
+type bankList struct {
+	Banks     []wompi.FinancialInstitution `json:"banks"`
+	FetchedAt time.Time                    `json:"fetched_at"`
+}
+
+// WithCache guarda la lista de bancos PSE en un cache compartido (Redis)
+// además de la memoria del proceso
+func (s *Service) WithCache(cache colombia.ResultCache) *Service {
+	s.cache = cache
+	return s
+}
+
+// FinancialInstitutions lista los bancos de PSE del ambiente del tenant. La
+// lista se guarda por BanksTTL; si Wompi no responde se usa la última conocida.
+func (s *Service) FinancialInstitutions(ctx context.Context, cfg TenantConfig) ([]wompi.FinancialInstitution, error) {
+	key := "wompi:pse:banks:" + wompiEnvironment(cfg)
+	now := s.now()
+
+	s.mu.RLock()
+	cached, ok := s.banks[key]
+	s.mu.RUnlock()
+	if ok && now.Sub(cached.FetchedAt) < BanksTTL {
+		return cached.Banks, nil
+	}
+
+	if s.cache != nil && !ok {
+		var shared bankList
+		if found, err := s.cache.GetCachedResult(key, &shared); err == nil && found && len(shared.Banks) > 0 {
+			s.mu.Lock()
+			s.banks[key] = shared
+			s.mu.Unlock()
+			if now.Sub(shared.FetchedAt) < BanksTTL {
+				return shared.Banks, nil
+			}
+			cached, ok = shared, true
+		}
+	}
+
+	banks, err := s.ClientFor(cfg).FinancialInstitutions(ctx)
+	if err != nil || len(banks) == 0 {
+		if ok {
+			log.Printf("⚠️ Bancos PSE desactualizados (%s): %v", key, err)
+			return cached.Banks, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("wompi no retornó bancos")
+		}
+		return nil, fmt.Errorf("error consultando los bancos PSE: %w", err)
+	}
+
+	list := bankList{Banks: banks, FetchedAt: now}
+	s.mu.Lock()
+	s.banks[key] = list
+	s.mu.Unlock()
+	if s.cache != nil {
+		if err := s.cache.CacheResult(key, list, BanksTTL); err != nil {
+			log.Printf("⚠️ No se pudo guardar la lista de bancos PSE en cache: %v", err)
+		}
+	}
+	return banks, nil
+}
+
+// ResolveBank ubica el banco PSE por código o por nombre (sin tildes ni
+// mayúsculas). Si la lista no está disponible se acepta el código tal cual.
+func (s *Service) ResolveBank(ctx context.Context, cfg TenantConfig, bank string) (wompi.FinancialInstitution, error) {
+	bank = strings.TrimSpace(bank)
+	if bank == "" {
+		return wompi.FinancialInstitution{}, fmt.Errorf("el banco es requerido para PSE")
+	}
+
+	banks, err := s.FinancialInstitutions(ctx, cfg)
+	if err != nil {
+		log.Printf("⚠️ Banco PSE %q sin validar: %v", bank, err)
+		return wompi.FinancialInstitution{Code: bank}, nil
+	}
+
+	wanted := colombia.NormalizeText(bank)
+	var partial []wompi.FinancialInstitution
+	for _, institution := range banks {
+		name := colombia.NormalizeText(institution.Name)
+		if institution.Code == bank || name == wanted {
+			return institution, nil
+		}
+		if strings.Contains(name, wanted) {
+			partial = append(partial, institution)
+		}
+	}
+	if len(partial) == 1 {
+		return partial[0], nil
+	}
+	if len(partial) > 1 {
+		return wompi.FinancialInstitution{}, fmt.Errorf("el banco '%s' es ambiguo: coincide con %d entidades PSE", bank, len(partial))
+	}
+	return wompi.FinancialInstitution{}, fmt.Errorf("el banco '%s' no está en la lista de entidades PSE", bank)
+}
+
+// wompiEnvironment ambiente de Wompi del tenant: stub, sandbox o producción
+func wompiEnvironment(cfg TenantConfig) string {
+	switch {
+	case cfg.Sandbox():
+		return "stub"
+	case cfg.Wompi.Sandbox():
+		return "sandbox"
+	default:
+		return "production"
+	}
+}
@@ -0,0 +1,133 @@
+package payments
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ===== CONCILIACIÓN DE PAGOS PENDIENTES =====
+
+// Parámetros de la conciliación
+const (
+	// ReconcileGrace margen tras el vencimiento antes de dar un pago por vencido
+	ReconcileGrace = 15 * time.Minute
+	// MaxPendingAge vigencia de los pagos sin vencimiento (tarjeta, Nequi)
+	MaxPendingAge = 24 * time.Hour
+)
+
+// SourceReconciliation origen de los cambios hechos por la conciliación
+const SourceReconciliation = "conciliacion"
+
+// ReconcileReport resultado de una corrida de conciliación
+type ReconcileReport struct {
+	Checked  int       `json:"checked"`
+	Approved int       `json:"approved"`
+	Declined int       `json:"declined"` // Rechazados, anulados o con error
+	Expired  int       `json:"expired"`
+	Errors   int       `json:"errors"` // Consultas fallidas; se reintentan en la siguiente corrida
+	RanAt    time.Time `json:"ran_at"`
+}
+
+// ReconcilePending consulta en la pasarela los pagos pendientes cuyo
+// vencimiento ya pasó y los resuelve: aprobados o rechazados según la
+// transacción, o vencidos si pasado el margen siguen sin respuesta. Las
+// referencias de efectivo sin recaudo vencen sin consulta.
+func (s *Service) ReconcilePending(ctx context.Context) ReconcileReport {
+	now := s.now()
+	report := ReconcileReport{RanAt: now}
+
+	for _, payment := range s.overduePayments(now) {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Checked++
+
+		if payment.ProviderID != "" {
+			s.mu.RLock()
+			cfg, ok := s.configs[payment.TenantID]
+			s.mu.RUnlock()
+			if !ok {
+				cfg = TenantConfig{TenantID: payment.TenantID}
+			}
+
+			transaction, err := s.ClientFor(cfg).GetTransaction(ctx, payment.ProviderID)
+			if err != nil {
+				log.Printf("⚠️ Conciliación: no se pudo consultar el pago %s: %v", payment.Reference, err)
+				report.Errors++
+				continue
+			}
+			s.applyTransaction(payment.ID, transaction, SourceReconciliation)
+		}
+
+		updated, err := s.GetPayment(payment.TenantID, payment.ID)
+		if err != nil {
+			continue
+		}
+		if updated.Status == StatusPending && now.After(paymentDeadline(updated).Add(ReconcileGrace)) {
+			message := "Sin respuesta de la pasarela después del vencimiento"
+			if updated.Method == MethodCash {
+				message = "Referencia de pago vencida sin recaudo"
+			}
+			s.setStatus(payment.ID, StatusExpired, message, SourceReconciliation)
+			updated.Status = StatusExpired
+		}
+
+		switch updated.Status {
+		case StatusApproved:
+			report.Approved++
+		case StatusExpired:
+			report.Expired++
+		case StatusDeclined, StatusVoided, StatusError:
+			report.Declined++
+		}
+	}
+	return report
+}
+
+// StartReconciler concilia los pagos pendientes cada interval hasta que se
+// cancele el contexto
+func (s *Service) StartReconciler(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report := s.ReconcilePending(ctx)
+				if report.Checked > 0 {
+					log.Printf("💳 Conciliación de pagos: %d revisados, %d aprobados, %d rechazados, %d vencidos, %d errores",
+						report.Checked, report.Approved, report.Declined, report.Expired, report.Errors)
+				}
+			}
+		}
+	}()
+}
+
+// overduePayments pagos pendientes cuyo vencimiento ya pasó
+func (s *Service) overduePayments(now time.Time) []*Payment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*Payment
+	for _, payment := range s.payments {
+		// La contraentrega no vence: la resuelven los eventos del envío
+		if payment.Method == MethodCashOnDelivery {
+			continue
+		}
+		if payment.Status == StatusPending && now.After(paymentDeadline(payment)) {
+			result = append(result, payment.clone())
+		}
+	}
+	return result
+}
+
+// paymentDeadline vencimiento del pago, o MaxPendingAge si no tiene
+func paymentDeadline(payment *Payment) time.Time {
+	if payment.ExpiresAt != nil {
+		return *payment.ExpiresAt
+	}
+	return payment.CreatedAt.Add(MaxPendingAge)
+}
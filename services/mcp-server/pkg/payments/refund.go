@@ -0,0 +1,436 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/payments/wompi"
+
+	"github.com/google/uuid"
+)
+
+// ===== REEMBOLSOS Y ANULACIONES =====
+
+// Tipos de reverso de un pago
+const (
+	RefundKindRefund = "reembolso" // Devolución total o parcial de un pago aprobado
+	RefundKindVoid   = "anulacion" // Anulación del pago completo
+)
+
+// Estados de un reembolso
+const (
+	RefundPending  = "pending"
+	RefundApproved = "approved"
+	RefundDeclined = "declined"
+	RefundError    = "error" // El proveedor no recibió la solicitud; se puede reintentar con la misma clave
+)
+
+// Refund reembolso o anulación de un pago. Si el pago ya estaba facturado, al
+// aprobarse se emite la nota crédito DIAN correspondiente.
+type Refund struct {
+	ID               string     `json:"id"` // También es la referencia enviada al proveedor
+	Kind             string     `json:"kind"`
+	ProviderID       string     `json:"provider_id,omitempty"`
+	Amount           int64      `json:"amount_cop"`
+	Reason           string     `json:"reason,omitempty"`
+	Status           string     `json:"status"`
+	StatusMessage    string     `json:"status_message,omitempty"`
+	IdempotencyKey   string     `json:"idempotency_key,omitempty"`
+	CreditNoteID     string     `json:"credit_note_id,omitempty"` // Envío DIAN de la nota crédito
+	CreditNoteNumber string     `json:"credit_note_number,omitempty"`
+	CreditNoteError  string     `json:"credit_note_error,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	FinalizedAt      *time.Time `json:"finalized_at,omitempty"`
+}
+
+// RefundRequest solicitud de reembolso de un pago
+type RefundRequest struct {
+	Amount         int64 // Vacío: todo el saldo reembolsable
+	Reason         string
+	IdempotencyKey string // Repetir la clave retorna el mismo reembolso en lugar de crear otro
+}
+
+// ErrIdempotencyConflict la clave de idempotencia ya se usó con otros datos
+var ErrIdempotencyConflict = errors.New("la clave de idempotencia ya se usó con otro pago o monto")
+
+// refundKey reembolso creado con una clave de idempotencia
+type refundKey struct {
+	paymentID string
+	refundID  string
+	amount    int64
+}
+
+// RefundProvider reversa pagos ante el proveedor que los cobró
+type RefundProvider interface {
+	// Refund solicita el reembolso; el resultado puede quedar pendiente hasta
+	// el evento del proveedor. Asigna ProviderID, Status y StatusMessage.
+	Refund(ctx context.Context, payment *Payment, refund *Refund) error
+	// Void anula el pago completo y retorna su nuevo estado
+	Void(ctx context.Context, payment *Payment) (status, message string, err error)
+}
+
+// wompiRefunds reembolsos y anulaciones con la API de Wompi
+type wompiRefunds struct {
+	client *wompi.Client
+}
+
+func (p wompiRefunds) Refund(ctx context.Context, payment *Payment, refund *Refund) error {
+	result, err := p.client.CreateRefund(ctx, payment.ProviderID, wompi.RefundRequest{
+		AmountInCents: refund.Amount * 100,
+		Reason:        refund.Reason,
+		Reference:     refund.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error solicitando el reembolso en wompi: %w", err)
+	}
+	refund.ProviderID = result.ID
+	refund.Status = refundStatusFromWompi(result.Status)
+	refund.StatusMessage = result.StatusMessage
+	return nil
+}
+
+func (p wompiRefunds) Void(ctx context.Context, payment *Payment) (string, string, error) {
+	if payment.Method != MethodCard || payment.Status != StatusApproved {
+		return "", "", fmt.Errorf("wompi solo anula pagos aprobados con tarjeta; para %s registre un reembolso", payment.Method)
+	}
+	transaction, err := p.client.VoidTransaction(ctx, payment.ProviderID)
+	if err != nil {
+		return "", "", fmt.Errorf("error anulando la transacción en wompi: %w", err)
+	}
+	return statusFromWompi(transaction.Status), transaction.StatusMessage, nil
+}
+
+// manualRefunds efectivo y contraentrega: el comercio devuelve el dinero por
+// su cuenta y el reembolso queda aprobado al registrarlo
+type manualRefunds struct{}
+
+func (manualRefunds) Refund(ctx context.Context, payment *Payment, refund *Refund) error {
+	refund.Status = RefundApproved
+	refund.StatusMessage = "Devolución registrada por el comercio"
+	return nil
+}
+
+func (manualRefunds) Void(ctx context.Context, payment *Payment) (string, string, error) {
+	if payment.Status != StatusPending {
+		return "", "", fmt.Errorf("un pago %s ya recaudado no se anula; registre un reembolso", payment.Method)
+	}
+	if payment.Method == MethodCashOnDelivery {
+		return StatusVoided, "Contraentrega cancelada antes de la entrega", nil
+	}
+	return StatusVoided, "Referencia de pago anulada por el comercio", nil
+}
+
+// refundProvider proveedor que reversa el pago según su medio
+func (s *Service) refundProvider(cfg TenantConfig, payment *Payment) RefundProvider {
+	if payment.Method == MethodCash || payment.Method == MethodCashOnDelivery {
+		return manualRefunds{}
+	}
+	return wompiRefunds{client: s.ClientFor(cfg)}
+}
+
+// RefundPayment reembolsa total o parcialmente un pago aprobado. Con clave de
+// idempotencia, repetir la solicitud retorna el mismo reembolso (o lo
+// reintenta ante el proveedor si la vez anterior falló).
+func (s *Service) RefundPayment(ctx context.Context, cfg TenantConfig, paymentID string, req RefundRequest) (*Payment, *Refund, error) {
+	s.rememberConfig(cfg)
+	key := strings.TrimSpace(req.IdempotencyKey)
+
+	s.mu.Lock()
+	payment, ok := s.payments[paymentID]
+	if !ok || payment.TenantID != cfg.TenantID {
+		s.mu.Unlock()
+		return nil, nil, fmt.Errorf("%w: %s", ErrPaymentNotFound, paymentID)
+	}
+
+	var refund *Refund
+	if key != "" {
+		if previous, used := s.refundKeys[cfg.TenantID+":"+key]; used {
+			if previous.paymentID != paymentID || (req.Amount != 0 && req.Amount != previous.amount) {
+				s.mu.Unlock()
+				return nil, nil, ErrIdempotencyConflict
+			}
+			refund = payment.refund(previous.refundID)
+			if refund.Status != RefundError {
+				result, snapshot := payment.clone(), *refund
+				s.mu.Unlock()
+				return result, &snapshot, nil
+			}
+			if payment.Status != StatusApproved || payment.reservedRefunds()+refund.Amount > payment.Amount {
+				s.mu.Unlock()
+				return nil, nil, fmt.Errorf("el reembolso %s ya no cabe en el saldo reembolsable del pago %s", refund.ID, payment.Reference)
+			}
+			refund.Status = RefundPending
+			refund.StatusMessage = ""
+		}
+	}
+
+	if refund == nil {
+		if payment.Status != StatusApproved {
+			s.mu.Unlock()
+			return nil, nil, fmt.Errorf("solo se reembolsan pagos aprobados; el pago %s está %s", payment.Reference, payment.Status)
+		}
+		available := payment.Amount - payment.reservedRefunds()
+		amount := req.Amount
+		if amount == 0 {
+			amount = available
+		}
+		if amount <= 0 || amount > available {
+			s.mu.Unlock()
+			return nil, nil, fmt.Errorf("el reembolso debe estar entre $1 y el saldo reembolsable ($%d)", available)
+		}
+
+		payment.Refunds = append(payment.Refunds, Refund{
+			ID:             newRefundID(),
+			Kind:           RefundKindRefund,
+			Amount:         amount,
+			Reason:         req.Reason,
+			Status:         RefundPending,
+			IdempotencyKey: key,
+			CreatedAt:      s.now().In(colombia.Location()),
+		})
+		refund = &payment.Refunds[len(payment.Refunds)-1]
+		s.references[refundReference(refund.ID)] = paymentID
+		if key != "" {
+			s.refundKeys[cfg.TenantID+":"+key] = refundKey{paymentID: paymentID, refundID: refund.ID, amount: amount}
+		}
+	}
+	working, snapshot := *refund, payment.clone()
+	s.mu.Unlock()
+
+	if err := s.refundProvider(cfg, snapshot).Refund(ctx, snapshot, &working); err != nil {
+		s.applyRefund(paymentID, working.ID, "", RefundError, err.Error())
+		return nil, nil, err
+	}
+	s.applyRefund(paymentID, working.ID, working.ProviderID, working.Status, working.StatusMessage)
+
+	result, err := s.GetPayment(cfg.TenantID, paymentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	applied := *result.refund(working.ID)
+	return result, &applied, nil
+}
+
+// VoidPayment anula un pago completo: una transacción con tarjeta aprobada
+// antes de compensarse, o una referencia de efectivo o contraentrega aún sin
+// recaudo. Anular un pago ya anulado no hace nada.
+func (s *Service) VoidPayment(ctx context.Context, cfg TenantConfig, paymentID, reason string) (*Payment, error) {
+	s.rememberConfig(cfg)
+
+	s.mu.Lock()
+	payment, ok := s.payments[paymentID]
+	if !ok || payment.TenantID != cfg.TenantID {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrPaymentNotFound, paymentID)
+	}
+	if payment.Status == StatusVoided {
+		s.mu.Unlock()
+		return s.GetPayment(cfg.TenantID, paymentID)
+	}
+	if payment.reservedRefunds() > 0 {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("el pago %s tiene reembolsos; no se puede anular", payment.Reference)
+	}
+	approved := payment.Status == StatusApproved
+	if approved {
+		// La anulación de un pago aprobado se registra como reverso por el total
+		payment.Refunds = append(payment.Refunds, Refund{
+			ID:        newRefundID(),
+			Kind:      RefundKindVoid,
+			Amount:    payment.Amount,
+			Reason:    reason,
+			Status:    RefundPending,
+			CreatedAt: s.now().In(colombia.Location()),
+		})
+	}
+	snapshot := payment.clone()
+	s.mu.Unlock()
+
+	status, message, err := s.refundProvider(cfg, snapshot).Void(ctx, snapshot)
+	if err == nil && status != StatusVoided {
+		err = fmt.Errorf("el proveedor no anuló el pago %s (%s)", snapshot.Reference, status)
+	}
+	if err != nil {
+		if approved {
+			s.discardRefund(paymentID, snapshot.Refunds[len(snapshot.Refunds)-1].ID)
+		}
+		return nil, err
+	}
+	if reason != "" {
+		message = message + ": " + reason
+	}
+	s.setStatus(paymentID, StatusVoided, message, SourceAPI)
+	if approved {
+		s.recordVoid(paymentID, reason)
+	}
+	return s.GetPayment(cfg.TenantID, paymentID)
+}
+
+// discardRefund descarta el reverso de una anulación que el proveedor no hizo
+func (s *Service) discardRefund(paymentID, refundID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payment, ok := s.payments[paymentID]
+	if !ok {
+		return
+	}
+	for i, refund := range payment.Refunds {
+		if refund.ID == refundID && refund.Status == RefundPending {
+			payment.Refunds = append(payment.Refunds[:i], payment.Refunds[i+1:]...)
+			return
+		}
+	}
+}
+
+// applyRefund actualiza el estado de un reembolso. Al aprobarse suma al valor
+// reembolsado, marca el pago como reembolsado si cubre el total y emite la
+// nota crédito si el pago estaba facturado.
+func (s *Service) applyRefund(paymentID, refundID, providerID, status, message string) {
+	s.mu.Lock()
+	payment, ok := s.payments[paymentID]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	refund := payment.refund(refundID)
+	if refund == nil || refund.Status == RefundApproved || refund.Status == RefundDeclined {
+		s.mu.Unlock()
+		return
+	}
+
+	now := s.now().In(colombia.Location())
+	if providerID != "" {
+		refund.ProviderID = providerID
+	}
+	refund.Status = status
+	refund.StatusMessage = message
+	payment.UpdatedAt = now
+	if status == RefundApproved || status == RefundDeclined {
+		refund.FinalizedAt = &now
+	}
+	approved := status == RefundApproved
+	if approved {
+		payment.Refunded += refund.Amount
+	}
+	fullyRefunded := approved && refund.Kind == RefundKindRefund && payment.Refunded >= payment.Amount
+	s.mu.Unlock()
+
+	if fullyRefunded {
+		s.setStatus(paymentID, StatusRefunded, "Pago reembolsado en su totalidad", SourceAPI)
+	}
+	if approved {
+		s.issueCreditNote(paymentID, refundID)
+	}
+}
+
+// recordVoid aprueba el reverso por el saldo de un pago aprobado que quedó
+// anulado, o lo crea si la anulación se hizo fuera del servicio (panel de
+// Wompi). Un pago anulado sin haberse aprobado no movió dinero.
+func (s *Service) recordVoid(paymentID, reason string) {
+	s.mu.Lock()
+	payment, ok := s.payments[paymentID]
+	if !ok || payment.Status != StatusVoided || !payment.wasApproved() {
+		s.mu.Unlock()
+		return
+	}
+	var void *Refund
+	for i := range payment.Refunds {
+		if payment.Refunds[i].Kind == RefundKindVoid {
+			void = &payment.Refunds[i]
+		}
+	}
+	if void == nil {
+		amount := payment.Amount - payment.Refunded
+		if amount <= 0 {
+			s.mu.Unlock()
+			return
+		}
+		payment.Refunds = append(payment.Refunds, Refund{
+			ID:        newRefundID(),
+			Kind:      RefundKindVoid,
+			Amount:    amount,
+			Reason:    reason,
+			Status:    RefundPending,
+			CreatedAt: s.now().In(colombia.Location()),
+		})
+		void = &payment.Refunds[len(payment.Refunds)-1]
+	}
+	refundID := void.ID
+	s.mu.Unlock()
+
+	s.applyRefund(paymentID, refundID, "", RefundApproved, "Pago anulado")
+}
+
+// refreshRefunds consulta en Wompi los reembolsos pendientes del pago
+func (s *Service) refreshRefunds(ctx context.Context, cfg TenantConfig, payment *Payment) {
+	if payment.Method == MethodCash || payment.Method == MethodCashOnDelivery {
+		return
+	}
+	for _, refund := range payment.Refunds {
+		if refund.Status != RefundPending || refund.ProviderID == "" {
+			continue
+		}
+		result, err := s.ClientFor(cfg).GetRefund(ctx, refund.ProviderID)
+		if err != nil {
+			continue
+		}
+		s.applyRefund(payment.ID, refund.ID, result.ID, refundStatusFromWompi(result.Status), result.StatusMessage)
+	}
+}
+
+// refund reembolso del pago por id, o nil
+func (p *Payment) refund(id string) *Refund {
+	for i := range p.Refunds {
+		if p.Refunds[i].ID == id {
+			return &p.Refunds[i]
+		}
+	}
+	return nil
+}
+
+// reservedRefunds valor de los reembolsos aprobados o en curso
+func (p *Payment) reservedRefunds() int64 {
+	var total int64
+	for _, refund := range p.Refunds {
+		if refund.Status == RefundPending || refund.Status == RefundApproved {
+			total += refund.Amount
+		}
+	}
+	return total
+}
+
+// wasApproved indica si el pago llegó a aprobarse
+func (p *Payment) wasApproved() bool {
+	for _, change := range p.History {
+		if change.Status == StatusApproved {
+			return true
+		}
+	}
+	return false
+}
+
+func newRefundID() string {
+	return "RF-" + strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:16])
+}
+
+// refundReference clave del reembolso en el índice de referencias
+func refundReference(refundID string) string {
+	return "reembolso:" + refundID
+}
+
+func refundStatusFromWompi(status string) string {
+	switch status {
+	case wompi.StatusApproved:
+		return RefundApproved
+	case wompi.StatusDeclined:
+		return RefundDeclined
+	case wompi.StatusError:
+		return RefundError
+	default:
+		return RefundPending
+	}
+}
@@ -0,0 +1,50 @@
+package payments
+
+import (
+	"fmt"
+
+	"mcp-server/pkg/sarlaft"
+)
+
+// ===== CONTROL SARLAFT DE LOS PAGADORES =====
+
+// WithScreening consulta a los pagadores en las listas restrictivas antes de
+// cobrar, para los tenants con el control SARLAFT activo
+func (s *Service) WithScreening(screening *sarlaft.Service) *Service {
+	s.screening = screening
+	return s
+}
+
+// screenCustomer consulta al pagador del pedido. Una coincidencia con una
+// lista restrictiva que no se haya descartado impide crear el pago; las de
+// listas de control (PEP) quedan en revisión y el pago continúa.
+func (s *Service) screenCustomer(cfg TenantConfig, orderID string, customer Customer) (string, error) {
+	if s.screening == nil || !cfg.Screening.Enabled {
+		return "", nil
+	}
+	if customer.Name == "" && customer.DocumentNumber == "" {
+		return "", nil
+	}
+	screening, err := s.screening.Screen(sarlaft.ScreenRequest{
+		TenantID:  cfg.TenantID,
+		Context:   sarlaft.ContextPayment,
+		Reference: orderID,
+		Subject: sarlaft.Subject{
+			Name:           customer.Name,
+			DocumentType:   customer.DocumentType,
+			DocumentNumber: customer.DocumentNumber,
+			PersonType:     customer.PersonType,
+		},
+		Threshold: cfg.Screening.Threshold,
+	})
+	if err != nil {
+		return "", err
+	}
+	if screening.Blocked() {
+		return "", fmt.Errorf("%w: %s", sarlaft.ErrScreeningHold, screening.Summary())
+	}
+	if screening.Result == sarlaft.ResultClear {
+		return "", nil
+	}
+	return screening.ID, nil
+}
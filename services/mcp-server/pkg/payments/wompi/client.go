@@ -0,0 +1,431 @@
+package wompi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// URLs de la API de Wompi
+const (
+	BaseURLSandbox    = "https://sandbox.wompi.co/v1"
+	BaseURLProduction = "https://production.wompi.co/v1"
+	CheckoutLinkURL   = "https://checkout.wompi.co/l/"
+)
+
+// Tipos de medio de pago de Wompi
+const (
+	PaymentPSE                 = "PSE"
+	PaymentNequi               = "NEQUI"
+	PaymentCard                = "CARD"
+	PaymentBancolombiaTransfer = "BANCOLOMBIA_TRANSFER"
+)
+
+// Estados de una transacción
+const (
+	StatusPending  = "PENDING"
+	StatusApproved = "APPROVED"
+	StatusDeclined = "DECLINED"
+	StatusVoided   = "VOIDED"
+	StatusError    = "ERROR"
+)
+
+// Config credenciales del comercio en Wompi. Las llaves de prueba
+// (pub_test_, prv_test_) apuntan al sandbox y las de producción a producción.
+type Config struct {
+	BaseURL         string // Vacío: se deduce del prefijo de la llave privada
+	PublicKey       string
+	PrivateKey      string
+	EventsSecret    string // Secreto de eventos para verificar los webhooks
+	IntegritySecret string // Secreto de integridad para firmar transacciones
+}
+
+// Sandbox indica si las llaves son de pruebas
+func (c Config) Sandbox() bool {
+	return !strings.HasPrefix(c.PrivateKey, "prv_prod_")
+}
+
+// Client cliente de la API REST de Wompi
+type Client struct {
+	config     Config
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient crea un cliente; httpClient es opcional (el stub local entrega el suyo)
+func NewClient(config Config, httpClient *http.Client) *Client {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = BaseURLSandbox
+		if !config.Sandbox() {
+			baseURL = BaseURLProduction
+		}
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{
+		config:     config,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// Config retorna las credenciales del cliente
+func (c *Client) Config() Config {
+	return c.config
+}
+
+// APIError error retornado por Wompi
+type APIError struct {
+	StatusCode int
+	Type       string              `json:"type"`
+	Reason     string              `json:"reason"`
+	Messages   map[string][]string `json:"messages"`
+}
+
+func (e *APIError) Error() string {
+	message := e.Reason
+	for field, msgs := range e.Messages {
+		message += fmt.Sprintf(" %s: %s", field, strings.Join(msgs, ", "))
+	}
+	if message == "" {
+		message = e.Type
+	}
+	return fmt.Sprintf("wompi respondió %d: %s", e.StatusCode, strings.TrimSpace(message))
+}
+
+// ===== COMERCIO Y TOKENS DE ACEPTACIÓN =====
+
+// Acceptance documento que el pagador debe aceptar antes de pagar
+type Acceptance struct {
+	AcceptanceToken string `json:"acceptance_token"`
+	Permalink       string `json:"permalink"`
+	Type            string `json:"type"`
+}
+
+// Merchant datos del comercio con los tokens de aceptación vigentes
+type Merchant struct {
+	ID                   int        `json:"id"`
+	Name                 string     `json:"name"`
+	Email                string     `json:"email"`
+	PresignedAcceptance  Acceptance `json:"presigned_acceptance"`
+	PresignedPersonalAut Acceptance `json:"presigned_personal_data_auth"`
+}
+
+// Merchant consulta el comercio y los tokens de aceptación de términos y de
+// tratamiento de datos personales, requeridos para crear transacciones
+func (c *Client) Merchant(ctx context.Context) (*Merchant, error) {
+	var merchant Merchant
+	if err := c.do(ctx, http.MethodGet, "/merchants/"+url.PathEscape(c.config.PublicKey), "", nil, &merchant); err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+// ===== TRANSACCIONES =====
+
+// PaymentMethod medio de pago de una transacción. Los campos usados dependen
+// del tipo: PSE (persona, documento, banco), NEQUI (celular), CARD (token y
+// cuotas) y BANCOLOMBIA_TRANSFER (tipo de usuario y descripción).
+type PaymentMethod struct {
+	Type                     string      `json:"type"`
+	Token                    string      `json:"token,omitempty"`
+	Installments             int         `json:"installments,omitempty"`
+	PhoneNumber              string      `json:"phone_number,omitempty"`
+	UserType                 interface{} `json:"user_type,omitempty"` // PSE: 0 natural, 1 jurídica; Bancolombia: "PERSON"
+	UserLegalIDType          string      `json:"user_legal_id_type,omitempty"`
+	UserLegalID              string      `json:"user_legal_id,omitempty"`
+	FinancialInstitutionCode string      `json:"financial_institution_code,omitempty"`
+	PaymentDescription       string      `json:"payment_description,omitempty"`
+}
+
+// CustomerData datos adicionales del pagador
+type CustomerData struct {
+	PhoneNumber string `json:"phone_number,omitempty"`
+	FullName    string `json:"full_name,omitempty"`
+	LegalID     string `json:"legal_id,omitempty"`
+	LegalIDType string `json:"legal_id_type,omitempty"`
+}
+
+// TransactionRequest solicitud de creación de una transacción
+type TransactionRequest struct {
+	AcceptanceToken    string        `json:"acceptance_token"`
+	AcceptPersonalAuth string        `json:"accept_personal_auth,omitempty"`
+	AmountInCents      int64         `json:"amount_in_cents"`
+	Currency           string        `json:"currency"`
+	Signature          string        `json:"signature"`
+	CustomerEmail      string        `json:"customer_email"`
+	Reference          string        `json:"reference"`
+	RedirectURL        string        `json:"redirect_url,omitempty"`
+	ExpirationTime     string        `json:"expiration_time,omitempty"` // ISO 8601, entra en la firma
+	PaymentMethod      PaymentMethod `json:"payment_method"`
+	CustomerData       *CustomerData `json:"customer_data,omitempty"`
+}
+
+// Transaction transacción de Wompi
+type Transaction struct {
+	ID                string                 `json:"id"`
+	CreatedAt         string                 `json:"created_at"`
+	FinalizedAt       string                 `json:"finalized_at,omitempty"`
+	AmountInCents     int64                  `json:"amount_in_cents"`
+	Reference         string                 `json:"reference"`
+	CustomerEmail     string                 `json:"customer_email"`
+	Currency          string                 `json:"currency"`
+	PaymentMethodType string                 `json:"payment_method_type"`
+	PaymentMethod     map[string]interface{} `json:"payment_method"`
+	RedirectURL       string                 `json:"redirect_url,omitempty"`
+	Status            string                 `json:"status"`
+	StatusMessage     string                 `json:"status_message,omitempty"`
+	PaymentLinkID     string                 `json:"payment_link_id,omitempty"`
+}
+
+// AsyncPaymentURL URL a la que se redirige al pagador en PSE y transferencia
+// Bancolombia; Wompi la entrega unos segundos después de crear la transacción
+func (t *Transaction) AsyncPaymentURL() string {
+	extra, _ := t.PaymentMethod["extra"].(map[string]interface{})
+	if extra == nil {
+		return ""
+	}
+	asyncURL, _ := extra["async_payment_url"].(string)
+	return asyncURL
+}
+
+// Final indica si la transacción ya no cambiará de estado
+func (t *Transaction) Final() bool {
+	return t.Status != StatusPending
+}
+
+// CreateTransaction firma y crea una transacción. Si no trae token de
+// aceptación lo consulta al comercio.
+func (c *Client) CreateTransaction(ctx context.Context, req TransactionRequest) (*Transaction, error) {
+	if req.AcceptanceToken == "" {
+		merchant, err := c.Merchant(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.AcceptanceToken = merchant.PresignedAcceptance.AcceptanceToken
+		req.AcceptPersonalAuth = merchant.PresignedPersonalAut.AcceptanceToken
+	}
+	if req.Currency == "" {
+		req.Currency = "COP"
+	}
+	if req.Signature == "" {
+		req.Signature = IntegritySignature(req.Reference, req.AmountInCents, req.Currency, req.ExpirationTime, c.config.IntegritySecret)
+	}
+
+	var transaction Transaction
+	if err := c.do(ctx, http.MethodPost, "/transactions", c.config.PrivateKey, req, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// GetTransaction consulta una transacción por su id
+func (c *Client) GetTransaction(ctx context.Context, id string) (*Transaction, error) {
+	var transaction Transaction
+	if err := c.do(ctx, http.MethodGet, "/transactions/"+url.PathEscape(id), c.config.PrivateKey, nil, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// TokenizeCard tokeniza una tarjeta con la llave pública. En producción lo hace
+// el navegador del pagador (Widget o Checkout); aquí se usa contra el sandbox.
+func (c *Client) TokenizeCard(ctx context.Context, card CardData) (string, error) {
+	var token struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/tokens/cards", c.config.PublicKey, card, &token); err != nil {
+		return "", err
+	}
+	return token.ID, nil
+}
+
+// CardData datos de una tarjeta a tokenizar
+type CardData struct {
+	Number     string `json:"number"`
+	CVC        string `json:"cvc"`
+	ExpMonth   string `json:"exp_month"`
+	ExpYear    string `json:"exp_year"`
+	CardHolder string `json:"card_holder"`
+}
+
+// ===== ANULACIONES Y REEMBOLSOS =====
+
+// RefundRequest solicitud de reembolso total o parcial de una transacción
+type RefundRequest struct {
+	AmountInCents int64  `json:"amount_in_cents"`
+	Reason        string `json:"reason,omitempty"`
+	Reference     string `json:"reference"` // Única por reembolso: repetirla retorna el mismo reembolso
+}
+
+// Refund reembolso de una transacción. Finaliza de forma asíncrona y su
+// estado llega en el evento refund.updated.
+type Refund struct {
+	ID            string `json:"id"`
+	TransactionID string `json:"transaction_id"`
+	AmountInCents int64  `json:"amount_in_cents"`
+	Reference     string `json:"reference"`
+	Reason        string `json:"reason,omitempty"`
+	Status        string `json:"status"` // PENDING, APPROVED, DECLINED, ERROR
+	StatusMessage string `json:"status_message,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	FinalizedAt   string `json:"finalized_at,omitempty"`
+}
+
+// VoidTransaction anula una transacción aprobada con tarjeta antes de que se
+// compense; la transacción queda VOIDED por su valor total
+func (c *Client) VoidTransaction(ctx context.Context, id string) (*Transaction, error) {
+	var transaction Transaction
+	if err := c.do(ctx, http.MethodPost, "/transactions/"+url.PathEscape(id)+"/void", c.config.PrivateKey, struct{}{}, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// CreateRefund solicita el reembolso total o parcial de una transacción aprobada
+func (c *Client) CreateRefund(ctx context.Context, transactionID string, req RefundRequest) (*Refund, error) {
+	var refund Refund
+	if err := c.do(ctx, http.MethodPost, "/transactions/"+url.PathEscape(transactionID)+"/refunds", c.config.PrivateKey, req, &refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// GetRefund consulta un reembolso por su id
+func (c *Client) GetRefund(ctx context.Context, id string) (*Refund, error) {
+	var refund Refund
+	if err := c.do(ctx, http.MethodGet, "/refunds/"+url.PathEscape(id), c.config.PrivateKey, nil, &refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// ===== PSE =====
+
+// FinancialInstitution entidad financiera disponible para pagos PSE
+type FinancialInstitution struct {
+	Code string `json:"financial_institution_code"`
+	Name string `json:"financial_institution_name"`
+}
+
+// FinancialInstitutions lista los bancos de PSE. Wompi incluye como primer
+// elemento el código "0" ("A continuación seleccione su banco"), que se omite.
+func (c *Client) FinancialInstitutions(ctx context.Context) ([]FinancialInstitution, error) {
+	var institutions []FinancialInstitution
+	if err := c.do(ctx, http.MethodGet, "/pse/financial_institutions", c.config.PublicKey, nil, &institutions); err != nil {
+		return nil, err
+	}
+
+	banks := institutions[:0]
+	for _, institution := range institutions {
+		if institution.Code != "0" {
+			banks = append(banks, institution)
+		}
+	}
+	return banks, nil
+}
+
+// ===== LINKS DE PAGO =====
+
+// PaymentLinkRequest solicitud de un link de pago de Wompi
+type PaymentLinkRequest struct {
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	SingleUse       bool   `json:"single_use"`
+	CollectShipping bool   `json:"collect_shipping"`
+	Currency        string `json:"currency"`
+	AmountInCents   int64  `json:"amount_in_cents,omitempty"` // Vacío: el pagador escribe el valor
+	ExpiresAt       string `json:"expires_at,omitempty"`
+	RedirectURL     string `json:"redirect_url,omitempty"`
+	SKU             string `json:"sku,omitempty"` // Se usa como referencia del pedido
+}
+
+// PaymentLink link de pago creado
+type PaymentLink struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Active        bool   `json:"active"`
+	SingleUse     bool   `json:"single_use"`
+	AmountInCents int64  `json:"amount_in_cents"`
+	ExpiresAt     string `json:"expires_at,omitempty"`
+	SKU           string `json:"sku,omitempty"`
+	URL           string `json:"url"`
+}
+
+// CreatePaymentLink crea un link de pago de Wompi Checkout
+func (c *Client) CreatePaymentLink(ctx context.Context, req PaymentLinkRequest) (*PaymentLink, error) {
+	if req.Currency == "" {
+		req.Currency = "COP"
+	}
+	var link PaymentLink
+	if err := c.do(ctx, http.MethodPost, "/payment_links", c.config.PrivateKey, req, &link); err != nil {
+		return nil, err
+	}
+	if link.URL == "" {
+		link.URL = CheckoutLinkURL + link.ID
+	}
+	return &link, nil
+}
+
+// do ejecuta una petición y decodifica el campo "data" de la respuesta
+func (c *Client) do(ctx context.Context, method, path, key string, body, dest interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error conectando con wompi: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var envelope struct {
+			Error APIError `json:"error"`
+		}
+		_ = json.Unmarshal(payload, &envelope)
+		envelope.Error.StatusCode = resp.StatusCode
+		return &envelope.Error
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return fmt.Errorf("respuesta inválida de wompi: %w", err)
+	}
+	if dest == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Data, dest)
+}
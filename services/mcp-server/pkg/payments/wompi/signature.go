@@ -0,0 +1,159 @@
+package wompi
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ===== FIRMAS =====
+
+// ErrInvalidChecksum el evento no fue firmado con el secreto del comercio
+var ErrInvalidChecksum = errors.New("checksum del evento de wompi inválido")
+
+// IntegritySignature firma de integridad de una transacción:
+// SHA256(referencia + monto en centavos + moneda [+ fecha de expiración] + secreto)
+func IntegritySignature(reference string, amountInCents int64, currency, expirationTime, secret string) string {
+	payload := reference + strconv.FormatInt(amountInCents, 10) + currency + expirationTime + secret
+	sum := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// Event evento enviado por Wompi a la URL de eventos del comercio
+type Event struct {
+	Event       string          `json:"event"` // transaction.updated, nequi_token.updated...
+	Data        json.RawMessage `json:"data"`
+	Environment string          `json:"environment"` // test, prod
+	Signature   EventSignature  `json:"signature"`
+	Timestamp   int64           `json:"timestamp"`
+	SentAt      string          `json:"sent_at"`
+}
+
+// EventSignature propiedades firmadas y checksum del evento
+type EventSignature struct {
+	Properties []string `json:"properties"`
+	Checksum   string   `json:"checksum"`
+}
+
+// Tipos de evento
+const (
+	EventTransactionUpdated = "transaction.updated"
+	EventRefundUpdated      = "refund.updated"
+)
+
+// Transaction extrae la transacción de un evento transaction.updated
+func (e *Event) Transaction() (*Transaction, error) {
+	var data struct {
+		Transaction Transaction `json:"transaction"`
+	}
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		return nil, fmt.Errorf("evento sin transacción: %w", err)
+	}
+	return &data.Transaction, nil
+}
+
+// Refund extrae el reembolso de un evento refund.updated
+func (e *Event) Refund() (*Refund, error) {
+	var data struct {
+		Refund Refund `json:"refund"`
+	}
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		return nil, fmt.Errorf("evento sin reembolso: %w", err)
+	}
+	return &data.Refund, nil
+}
+
+// EventChecksum calcula el checksum de un evento:
+// SHA256(valores de las propiedades firmadas + timestamp + secreto de eventos)
+func EventChecksum(data json.RawMessage, properties []string, timestamp int64, secret string) (string, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return "", fmt.Errorf("datos del evento inválidos: %w", err)
+	}
+
+	var b strings.Builder
+	for _, property := range properties {
+		value, ok := lookupPath(values, property)
+		if !ok {
+			return "", fmt.Errorf("propiedad firmada %q ausente en el evento", property)
+		}
+		b.WriteString(value)
+	}
+	b.WriteString(strconv.FormatInt(timestamp, 10))
+	b.WriteString(secret)
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ParseEvent decodifica un evento y verifica su checksum. headerChecksum es
+// el encabezado X-Event-Checksum; si viene debe coincidir con el del cuerpo.
+func ParseEvent(body []byte, headerChecksum, secret string) (*Event, error) {
+	event, err := ParseEventUnverified(body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("el comercio no tiene secreto de eventos configurado")
+	}
+
+	expected, err := EventChecksum(event.Data, event.Signature.Properties, event.Timestamp, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !equalChecksum(expected, event.Signature.Checksum) {
+		return nil, ErrInvalidChecksum
+	}
+	if headerChecksum != "" && !equalChecksum(expected, headerChecksum) {
+		return nil, ErrInvalidChecksum
+	}
+	return event, nil
+}
+
+// ParseEventUnverified decodifica un evento sin verificar su checksum; sirve
+// para ubicar al comercio dueño de la transacción antes de verificarlo
+func ParseEventUnverified(body []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("evento de wompi inválido: %w", err)
+	}
+	return &event, nil
+}
+
+func equalChecksum(expected, got string) bool {
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(got))) == 1
+}
+
+// lookupPath busca "transaction.amount_in_cents" en los datos del evento y
+// retorna el valor como texto, tal como lo concatena Wompi
+func lookupPath(values map[string]interface{}, path string) (string, bool) {
+	var current interface{} = values
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		current, ok = object[part]
+		if !ok {
+			return "", false
+		}
+	}
+
+	switch v := current.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "", true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
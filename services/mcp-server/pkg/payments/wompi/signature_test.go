@@ -0,0 +1,163 @@
+package wompi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestIntegritySignature(t *testing.T) {
+	tests := []struct {
+		name       string
+		reference  string
+		amount     int64
+		currency   string
+		expiration string
+		secret     string
+		payload    string
+	}{
+		{
+			name:      "sin expiración",
+			reference: "sk8-438k4-xmxm392-sn2m",
+			amount:    2490000,
+			currency:  "COP",
+			secret:    "test_integrity_VMVZ36lyoQot5DsN0fBXAmp4onT5T86G",
+			payload:   "sk8-438k4-xmxm392-sn2m2490000COPtest_integrity_VMVZ36lyoQot5DsN0fBXAmp4onT5T86G",
+		},
+		{
+			name:       "con expiración",
+			reference:  "sk8-438k4-xmxm392-sn2m",
+			amount:     2490000,
+			currency:   "COP",
+			expiration: "2023-06-09T20:28:50.000Z",
+			secret:     "test_integrity_VMVZ36lyoQot5DsN0fBXAmp4onT5T86G",
+			payload:    "sk8-438k4-xmxm392-sn2m2490000COP2023-06-09T20:28:50.000Ztest_integrity_VMVZ36lyoQot5DsN0fBXAmp4onT5T86G",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IntegritySignature(tt.reference, tt.amount, tt.currency, tt.expiration, tt.secret)
+			if want := sha256Hex(tt.payload); got != want {
+				t.Errorf("IntegritySignature() = %s, want %s", got, want)
+			}
+		})
+	}
+
+	base := IntegritySignature("pedido-1", 5000000, "COP", "", "secreto")
+	if other := IntegritySignature("pedido-1", 5000001, "COP", "", "secreto"); other == base {
+		t.Error("la firma no cambia con el monto")
+	}
+	if other := IntegritySignature("pedido-1", 5000000, "COP", "", "otro-secreto"); other == base {
+		t.Error("la firma no cambia con el secreto")
+	}
+}
+
+// eventData datos de ejemplo de la documentación de eventos de Wompi
+const eventData = `{"transaction":{"id":"1234-1610641025-49201","amount_in_cents":4490000,"reference":"MZQ3X2DE2SMX","customer_email":"juan.perez@gmail.com","currency":"COP","payment_method_type":"NEQUI","redirect_url":"https://mitienda.com.co/pagos/redireccion","status":"APPROVED","shipping_address":null,"payment_link_id":null,"payment_source_id":null}}`
+
+var eventProperties = []string{"transaction.id", "transaction.status", "transaction.amount_in_cents"}
+
+const (
+	eventTimestamp = 1530291411
+	eventsSecret   = "prod_events_OcHnIzeBl5socpwByQ4hA52Em3USQ93Z"
+)
+
+func TestEventChecksum(t *testing.T) {
+	got, err := EventChecksum(json.RawMessage(eventData), eventProperties, eventTimestamp, eventsSecret)
+	if err != nil {
+		t.Fatalf("EventChecksum() error = %v", err)
+	}
+	// Los montos se concatenan como enteros, no en notación científica
+	want := sha256Hex("1234-1610641025-49201" + "APPROVED" + "4490000" + "1530291411" + eventsSecret)
+	if got != want {
+		t.Errorf("EventChecksum() = %s, want %s", got, want)
+	}
+
+	if _, err := EventChecksum(json.RawMessage(eventData), []string{"transaction.no_existe"}, eventTimestamp, eventsSecret); err == nil {
+		t.Error("EventChecksum() con una propiedad ausente no retornó error")
+	}
+	if _, err := EventChecksum(json.RawMessage(`[1,2]`), eventProperties, eventTimestamp, eventsSecret); err == nil {
+		t.Error("EventChecksum() con datos inválidos no retornó error")
+	}
+}
+
+func signedEventBody(t *testing.T, data string, checksum string) []byte {
+	t.Helper()
+	body, err := json.Marshal(Event{
+		Event:       EventTransactionUpdated,
+		Data:        json.RawMessage(data),
+		Environment: "prod",
+		Signature:   EventSignature{Properties: eventProperties, Checksum: checksum},
+		Timestamp:   eventTimestamp,
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return body
+}
+
+func TestParseEvent(t *testing.T) {
+	checksum, err := EventChecksum(json.RawMessage(eventData), eventProperties, eventTimestamp, eventsSecret)
+	if err != nil {
+		t.Fatalf("EventChecksum() error = %v", err)
+	}
+	tampered := strings.Replace(eventData, `"status":"APPROVED"`, `"status":"DECLINED"`, 1)
+
+	tests := []struct {
+		name    string
+		body    []byte
+		header  string
+		secret  string
+		wantErr error // nil: el evento es válido
+		anyErr  bool
+	}{
+		{name: "válido", body: signedEventBody(t, eventData, checksum), header: checksum, secret: eventsSecret},
+		{name: "sin encabezado", body: signedEventBody(t, eventData, checksum), secret: eventsSecret},
+		{name: "checksum en mayúsculas", body: signedEventBody(t, eventData, strings.ToUpper(checksum)), header: strings.ToUpper(checksum), secret: eventsSecret},
+		{name: "datos alterados", body: signedEventBody(t, tampered, checksum), header: checksum, secret: eventsSecret, wantErr: ErrInvalidChecksum},
+		{name: "otro secreto", body: signedEventBody(t, eventData, checksum), secret: "prod_events_otro", wantErr: ErrInvalidChecksum},
+		{name: "encabezado distinto", body: signedEventBody(t, eventData, checksum), header: sha256Hex("otro"), secret: eventsSecret, wantErr: ErrInvalidChecksum},
+		{name: "checksum vacío", body: signedEventBody(t, eventData, ""), secret: eventsSecret, wantErr: ErrInvalidChecksum},
+		{name: "sin secreto configurado", body: signedEventBody(t, eventData, checksum), anyErr: true},
+		{name: "cuerpo inválido", body: []byte(`{"event":`), secret: eventsSecret, anyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ParseEvent(tt.body, tt.header, tt.secret)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseEvent() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.anyErr:
+				if err == nil {
+					t.Fatal("ParseEvent() no retornó error")
+				}
+				if errors.Is(err, ErrInvalidChecksum) {
+					t.Errorf("ParseEvent() error = %v, want un error distinto de ErrInvalidChecksum", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("ParseEvent() error = %v", err)
+				}
+				transaction, err := event.Transaction()
+				if err != nil {
+					t.Fatalf("Transaction() error = %v", err)
+				}
+				if transaction.Status != StatusApproved || transaction.AmountInCents != 4490000 {
+					t.Errorf("Transaction() = %+v", transaction)
+				}
+			}
+		})
+	}
+}
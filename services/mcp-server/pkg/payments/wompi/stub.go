@@ -0,0 +1,710 @@
+package wompi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ===== STUB LOCAL DE WOMPI =====
+
+// StubConfig credenciales que acepta el stub
+var StubConfig = Config{
+	PublicKey:       "pub_stub_local",
+	PrivateKey:      "prv_stub_local",
+	EventsSecret:    "stub_events_secret",
+	IntegritySecret: "stub_integrity_secret",
+}
+
+// Datos de prueba del sandbox de Wompi que el stub reproduce
+const (
+	StubCardApproved  = "4242424242424242"
+	StubCardDeclined  = "4111111111111111"
+	StubNequiApproved = "3991111111"
+	StubNequiDeclined = "3992222222"
+	StubBankApproves  = "1" // Código PSE del banco que aprueba
+	StubBankDeclines  = "2" // Código PSE del banco que rechaza
+	StubBankPending   = "3" // Código PSE del banco que nunca responde
+)
+
+// stubBanks entidades financieras PSE del stub
+var stubBanks = []FinancialInstitution{
+	{Code: StubBankApproves, Name: "Banco que aprueba"},
+	{Code: StubBankDeclines, Name: "Banco que rechaza"},
+	{Code: StubBankPending, Name: "Banco que no responde"},
+}
+
+// StubServer implementación en memoria de la API de Wompi para pruebas y
+// tenants en sandbox. Reproduce las reglas del sandbox (tarjetas, celulares
+// Nequi y bancos PSE de prueba), valida llaves, tokens de aceptación y firmas
+// de integridad, y envía eventos transaction.updated y refund.updated firmados
+// al finalizar cada transacción o reembolso. Se puede montar como servidor HTTP o usar en proceso con
+// HTTPClient.
+type StubServer struct {
+	// PublicURL URL base con la que se arman los enlaces de pago asíncrono
+	PublicURL string
+	// SettleAfter tiempo que tarda una transacción en finalizar
+	SettleAfter time.Duration
+	// EventsURL si no es vacío, los eventos también se envían por HTTP
+	EventsURL string
+
+	config       Config
+	mu           sync.Mutex
+	transactions map[string]*Transaction
+	references   map[string]string // referencia -> id de transacción
+	outcomes     map[string]stubOutcome
+	cards        map[string]string // token -> número de tarjeta
+	links        map[string]*PaymentLink
+	refunds      map[string]*Refund
+	refundRefs   map[string]string // referencia -> id de reembolso
+	listeners    []func(body []byte, checksum string)
+	now          func() time.Time
+}
+
+type stubOutcome struct {
+	status  string
+	message string
+}
+
+const stubAcceptanceToken = "stub_acceptance_token"
+
+// NewStubServer crea el stub con las credenciales StubConfig
+func NewStubServer(publicURL string) *StubServer {
+	return &StubServer{
+		PublicURL:    strings.TrimRight(publicURL, "/"),
+		SettleAfter:  3 * time.Second,
+		config:       StubConfig,
+		transactions: make(map[string]*Transaction),
+		references:   make(map[string]string),
+		outcomes:     make(map[string]stubOutcome),
+		cards:        make(map[string]string),
+		links:        make(map[string]*PaymentLink),
+		refunds:      make(map[string]*Refund),
+		refundRefs:   make(map[string]string),
+		now:          time.Now,
+	}
+}
+
+// OnEvent registra una función que recibe cada evento (cuerpo y checksum)
+func (s *StubServer) OnEvent(listener func(body []byte, checksum string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// HTTPClient cliente HTTP que atiende las peticiones en proceso, sin red
+func (s *StubServer) HTTPClient() *http.Client {
+	prefix := ""
+	if public, err := url.Parse(s.PublicURL); err == nil {
+		prefix = strings.TrimRight(public.Path, "/")
+	}
+	return &http.Client{Transport: stubTransport{handler: s, prefix: prefix}}
+}
+
+// Client cliente de Wompi conectado al stub
+func (s *StubServer) Client() *Client {
+	config := s.config
+	config.BaseURL = s.PublicURL + "/v1"
+	return NewClient(config, s.HTTPClient())
+}
+
+// stubTransport atiende en proceso las peticiones a PublicURL; prefix es la
+// ruta de PublicURL (por ejemplo /sandbox/wompi), que el stub no espera
+type stubTransport struct {
+	handler http.Handler
+	prefix  string
+}
+
+func (t stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	inner := req.Clone(req.Context())
+	inner.URL.Path = strings.TrimPrefix(req.URL.Path, t.prefix)
+	t.handler.ServeHTTP(recorder, inner)
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// ServeHTTP atiende la API (con o sin el prefijo /v1) y la página de pago simulada
+func (s *StubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && len(segments) == 2 && segments[0] == "merchants":
+		s.handleMerchant(w, segments[1])
+	case r.Method == http.MethodPost && path == "/tokens/cards":
+		s.handleTokenizeCard(w, r)
+	case r.Method == http.MethodPost && path == "/transactions":
+		s.handleCreateTransaction(w, r)
+	case r.Method == http.MethodGet && len(segments) == 2 && segments[0] == "transactions":
+		s.handleGetTransaction(w, r, segments[1])
+	case r.Method == http.MethodPost && len(segments) == 3 && segments[0] == "transactions" && segments[2] == "void":
+		s.handleVoidTransaction(w, r, segments[1])
+	case r.Method == http.MethodPost && len(segments) == 3 && segments[0] == "transactions" && segments[2] == "refunds":
+		s.handleCreateRefund(w, r, segments[1])
+	case r.Method == http.MethodGet && len(segments) == 2 && segments[0] == "refunds":
+		s.handleGetRefund(w, r, segments[1])
+	case r.Method == http.MethodGet && path == "/pse/financial_institutions":
+		s.handleFinancialInstitutions(w, r)
+	case r.Method == http.MethodPost && path == "/payment_links":
+		s.handleCreatePaymentLink(w, r)
+	case r.Method == http.MethodGet && len(segments) == 2 && segments[0] == "checkout":
+		s.handleCheckout(w, r, segments[1])
+	case r.Method == http.MethodGet && len(segments) == 3 && segments[0] == "checkout" && segments[1] == "link":
+		s.handlePayLink(w, r, segments[2])
+	default:
+		stubError(w, http.StatusNotFound, "NOT_FOUND_ERROR", "recurso no encontrado")
+	}
+}
+
+func (s *StubServer) handleMerchant(w http.ResponseWriter, publicKey string) {
+	if publicKey != s.config.PublicKey {
+		stubError(w, http.StatusNotFound, "NOT_FOUND_ERROR", "comercio no encontrado")
+		return
+	}
+	stubData(w, http.StatusOK, Merchant{
+		ID:    1,
+		Name:  "Comercio sandbox",
+		Email: "sandbox@tause.pro",
+		PresignedAcceptance: Acceptance{
+			AcceptanceToken: stubAcceptanceToken,
+			Permalink:       "https://wompi.co/wp-content/uploads/2019/09/TERMINOS-Y-CONDICIONES-DE-USO-USUARIOS-WOMPI.pdf",
+			Type:            "END_USER_POLICY",
+		},
+		PresignedPersonalAut: Acceptance{
+			AcceptanceToken: stubAcceptanceToken,
+			Permalink:       "https://wompi.com/assets/downloadble/autorizacion-administracion-datos-personales.pdf",
+			Type:            "PERSONAL_DATA_AUTH",
+		},
+	})
+}
+
+func (s *StubServer) handleFinancialInstitutions(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r, s.config.PublicKey) {
+		stubError(w, http.StatusUnauthorized, "INVALID_ACCESS_TOKEN", "llave pública inválida")
+		return
+	}
+	banks := append([]FinancialInstitution{{Code: "0", Name: "A continuación seleccione su banco"}}, stubBanks...)
+	stubData(w, http.StatusOK, banks)
+}
+
+func (s *StubServer) handleTokenizeCard(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r, s.config.PublicKey) {
+		stubError(w, http.StatusUnauthorized, "INVALID_ACCESS_TOKEN", "llave pública inválida")
+		return
+	}
+	var card CardData
+	if err := json.NewDecoder(r.Body).Decode(&card); err != nil || len(card.Number) < 13 {
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", "número de tarjeta inválido")
+		return
+	}
+
+	token := "tok_stub_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:16]
+	s.mu.Lock()
+	s.cards[token] = card.Number
+	s.mu.Unlock()
+	stubData(w, http.StatusCreated, map[string]string{"id": token, "status": "CREATED"})
+}
+
+func (s *StubServer) handleCreateTransaction(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r, s.config.PrivateKey, s.config.PublicKey) {
+		stubError(w, http.StatusUnauthorized, "INVALID_ACCESS_TOKEN", "llave inválida")
+		return
+	}
+	var req TransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", "cuerpo inválido")
+		return
+	}
+
+	switch {
+	case req.AcceptanceToken != stubAcceptanceToken:
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", "acceptance_token inválido")
+		return
+	case req.AmountInCents <= 0 || req.Reference == "" || req.CustomerEmail == "":
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", "amount_in_cents, reference y customer_email son requeridos")
+		return
+	case req.Signature != IntegritySignature(req.Reference, req.AmountInCents, req.Currency, req.ExpirationTime, s.config.IntegritySecret):
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", "la firma de integridad no coincide")
+		return
+	}
+
+	outcome, err := s.outcomeFor(req.PaymentMethod)
+	if err != nil {
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	if _, used := s.references[req.Reference]; used {
+		s.mu.Unlock()
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", "la referencia ya ha sido usada")
+		return
+	}
+	id := fmt.Sprintf("%d-%d-stub", s.now().Unix()%100000, len(s.transactions)+1)
+	transaction := &Transaction{
+		ID:                id,
+		CreatedAt:         s.now().UTC().Format(time.RFC3339Nano),
+		AmountInCents:     req.AmountInCents,
+		Reference:         req.Reference,
+		CustomerEmail:     req.CustomerEmail,
+		Currency:          req.Currency,
+		PaymentMethodType: req.PaymentMethod.Type,
+		PaymentMethod:     stubPaymentMethod(req.PaymentMethod),
+		RedirectURL:       req.RedirectURL,
+		Status:            StatusPending,
+	}
+	if req.PaymentMethod.Type == PaymentPSE || req.PaymentMethod.Type == PaymentBancolombiaTransfer {
+		transaction.PaymentMethod["extra"] = map[string]interface{}{
+			"async_payment_url": s.PublicURL + "/checkout/" + id,
+		}
+	}
+	s.transactions[id] = transaction
+	s.references[req.Reference] = id
+	s.outcomes[id] = outcome
+	response := *transaction
+	s.mu.Unlock()
+
+	time.AfterFunc(s.SettleAfter, func() { s.Settle(id) })
+	stubData(w, http.StatusCreated, response)
+}
+
+func (s *StubServer) handleGetTransaction(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.authorized(r, s.config.PrivateKey, s.config.PublicKey) {
+		stubError(w, http.StatusUnauthorized, "INVALID_ACCESS_TOKEN", "llave inválida")
+		return
+	}
+	s.mu.Lock()
+	transaction, exists := s.transactions[id]
+	var response Transaction
+	if exists {
+		response = *transaction
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		stubError(w, http.StatusNotFound, "NOT_FOUND_ERROR", "transacción no encontrada")
+		return
+	}
+	stubData(w, http.StatusOK, response)
+}
+
+// handleVoidTransaction anula una transacción aprobada con tarjeta que no
+// tenga reembolsos
+func (s *StubServer) handleVoidTransaction(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.authorized(r, s.config.PrivateKey) {
+		stubError(w, http.StatusUnauthorized, "INVALID_ACCESS_TOKEN", "llave privada inválida")
+		return
+	}
+	s.mu.Lock()
+	transaction, exists := s.transactions[id]
+	var reason string
+	switch {
+	case !exists:
+		reason = "transacción no encontrada"
+	case transaction.PaymentMethodType != PaymentCard:
+		reason = "solo se pueden anular transacciones con tarjeta"
+	case transaction.Status != StatusApproved:
+		reason = "solo se pueden anular transacciones aprobadas"
+	case s.refundedLocked(id) > 0:
+		reason = "la transacción tiene reembolsos; no se puede anular"
+	}
+	if reason != "" {
+		s.mu.Unlock()
+		status := http.StatusUnprocessableEntity
+		if !exists {
+			status = http.StatusNotFound
+		}
+		stubError(w, status, "INPUT_VALIDATION_ERROR", reason)
+		return
+	}
+	transaction.Status = StatusVoided
+	transaction.StatusMessage = "Transacción anulada por el comercio"
+	snapshot := *transaction
+	s.mu.Unlock()
+
+	s.emit(EventTransactionUpdated, "transaction", &snapshot, []string{"transaction.id", "transaction.status", "transaction.amount_in_cents"})
+	stubData(w, http.StatusOK, snapshot)
+}
+
+// handleCreateRefund crea un reembolso pendiente sobre el saldo de una
+// transacción aprobada. Una referencia repetida retorna el mismo reembolso.
+func (s *StubServer) handleCreateRefund(w http.ResponseWriter, r *http.Request, transactionID string) {
+	if !s.authorized(r, s.config.PrivateKey) {
+		stubError(w, http.StatusUnauthorized, "INVALID_ACCESS_TOKEN", "llave privada inválida")
+		return
+	}
+	var req RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Reference == "" || req.AmountInCents <= 0 {
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", "amount_in_cents y reference son requeridos")
+		return
+	}
+
+	s.mu.Lock()
+	if id, used := s.refundRefs[req.Reference]; used {
+		refund := *s.refunds[id]
+		s.mu.Unlock()
+		if refund.TransactionID != transactionID || refund.AmountInCents != req.AmountInCents {
+			stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", "la referencia ya fue usada en otro reembolso")
+			return
+		}
+		stubData(w, http.StatusOK, refund)
+		return
+	}
+	transaction, exists := s.transactions[transactionID]
+	if !exists {
+		s.mu.Unlock()
+		stubError(w, http.StatusNotFound, "NOT_FOUND_ERROR", "transacción no encontrada")
+		return
+	}
+	if transaction.Status != StatusApproved {
+		s.mu.Unlock()
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", "solo se pueden reembolsar transacciones aprobadas")
+		return
+	}
+	if available := transaction.AmountInCents - s.refundedLocked(transactionID); req.AmountInCents > available {
+		s.mu.Unlock()
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", fmt.Sprintf("el reembolso supera el saldo de la transacción (%d centavos)", available))
+		return
+	}
+
+	id := fmt.Sprintf("rf-%d-%d-stub", s.now().Unix()%100000, len(s.refunds)+1)
+	refund := &Refund{
+		ID:            id,
+		TransactionID: transactionID,
+		AmountInCents: req.AmountInCents,
+		Reference:     req.Reference,
+		Reason:        req.Reason,
+		Status:        StatusPending,
+		CreatedAt:     s.now().UTC().Format(time.RFC3339Nano),
+	}
+	s.refunds[id] = refund
+	s.refundRefs[req.Reference] = id
+	response := *refund
+	s.mu.Unlock()
+
+	time.AfterFunc(s.SettleAfter, func() { s.SettleRefund(id) })
+	stubData(w, http.StatusCreated, response)
+}
+
+func (s *StubServer) handleGetRefund(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.authorized(r, s.config.PrivateKey) {
+		stubError(w, http.StatusUnauthorized, "INVALID_ACCESS_TOKEN", "llave privada inválida")
+		return
+	}
+	s.mu.Lock()
+	refund, exists := s.refunds[id]
+	var response Refund
+	if exists {
+		response = *refund
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		stubError(w, http.StatusNotFound, "NOT_FOUND_ERROR", "reembolso no encontrado")
+		return
+	}
+	stubData(w, http.StatusOK, response)
+}
+
+// refundedLocked centavos reembolsados o por reembolsar de una transacción.
+// Requiere s.mu.
+func (s *StubServer) refundedLocked(transactionID string) int64 {
+	var total int64
+	for _, refund := range s.refunds {
+		if refund.TransactionID == transactionID && (refund.Status == StatusPending || refund.Status == StatusApproved) {
+			total += refund.AmountInCents
+		}
+	}
+	return total
+}
+
+func (s *StubServer) handleCreatePaymentLink(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r, s.config.PrivateKey) {
+		stubError(w, http.StatusUnauthorized, "INVALID_ACCESS_TOKEN", "llave privada inválida")
+		return
+	}
+	var req PaymentLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		stubError(w, http.StatusUnprocessableEntity, "INPUT_VALIDATION_ERROR", "name es requerido")
+		return
+	}
+
+	id := "stub" + strings.ReplaceAll(uuid.NewString(), "-", "")[:8]
+	link := &PaymentLink{
+		ID:            id,
+		Name:          req.Name,
+		Active:        true,
+		SingleUse:     req.SingleUse,
+		AmountInCents: req.AmountInCents,
+		ExpiresAt:     req.ExpiresAt,
+		SKU:           req.SKU,
+		URL:           s.PublicURL + "/checkout/link/" + id,
+	}
+	s.mu.Lock()
+	s.links[id] = link
+	s.mu.Unlock()
+	stubData(w, http.StatusCreated, link)
+}
+
+// handleCheckout simula la página del banco (PSE) o de Bancolombia: finaliza
+// la transacción y redirige al comercio
+func (s *StubServer) handleCheckout(w http.ResponseWriter, r *http.Request, id string) {
+	s.Settle(id)
+
+	s.mu.Lock()
+	transaction, exists := s.transactions[id]
+	var redirectURL, status string
+	if exists {
+		redirectURL, status = transaction.RedirectURL, transaction.Status
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	if redirectURL != "" {
+		separator := "?"
+		if strings.Contains(redirectURL, "?") {
+			separator = "&"
+		}
+		http.Redirect(w, r, redirectURL+separator+"id="+id, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<html><body><h1>Pago simulado</h1><p>Transacción %s: %s</p></body></html>", html.EscapeString(id), html.EscapeString(status))
+}
+
+// handlePayLink simula el pago aprobado de un link de pago con tarjeta. Si el
+// link no tiene monto se toma ?amount_in_cents.
+func (s *StubServer) handlePayLink(w http.ResponseWriter, r *http.Request, linkID string) {
+	s.mu.Lock()
+	link, exists := s.links[linkID]
+	if !exists || !link.Active {
+		s.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+	amount := link.AmountInCents
+	if amount == 0 {
+		fmt.Sscan(r.URL.Query().Get("amount_in_cents"), &amount)
+	}
+	if amount <= 0 {
+		s.mu.Unlock()
+		http.Error(w, "amount_in_cents es requerido para links sin monto", http.StatusBadRequest)
+		return
+	}
+	if link.SingleUse {
+		link.Active = false
+	}
+	id := fmt.Sprintf("%d-%d-stub", s.now().Unix()%100000, len(s.transactions)+1)
+	s.transactions[id] = &Transaction{
+		ID:                id,
+		CreatedAt:         s.now().UTC().Format(time.RFC3339Nano),
+		AmountInCents:     amount,
+		Reference:         fmt.Sprintf("%s_%d", linkID, len(s.transactions)+1),
+		CustomerEmail:     "pagador@sandbox.co",
+		Currency:          "COP",
+		PaymentMethodType: PaymentCard,
+		PaymentMethod:     map[string]interface{}{"type": PaymentCard},
+		Status:            StatusPending,
+		PaymentLinkID:     linkID,
+	}
+	s.references[s.transactions[id].Reference] = id
+	s.outcomes[id] = stubOutcome{StatusApproved, ""}
+	s.mu.Unlock()
+
+	s.handleCheckout(w, r, id)
+}
+
+// Settle finaliza una transacción pendiente según las reglas del sandbox y
+// emite el evento transaction.updated
+func (s *StubServer) Settle(id string) {
+	s.mu.Lock()
+	transaction, exists := s.transactions[id]
+	if !exists || transaction.Status != StatusPending {
+		s.mu.Unlock()
+		return
+	}
+	outcome := s.outcomes[id]
+	if outcome.status == StatusPending {
+		s.mu.Unlock()
+		return
+	}
+	transaction.Status = outcome.status
+	transaction.StatusMessage = outcome.message
+	transaction.FinalizedAt = s.now().UTC().Format(time.RFC3339Nano)
+	snapshot := *transaction
+	s.mu.Unlock()
+
+	s.emit(EventTransactionUpdated, "transaction", &snapshot, []string{"transaction.id", "transaction.status", "transaction.amount_in_cents"})
+}
+
+// SettleRefund aprueba un reembolso pendiente y emite el evento refund.updated
+func (s *StubServer) SettleRefund(id string) {
+	s.mu.Lock()
+	refund, exists := s.refunds[id]
+	if !exists || refund.Status != StatusPending {
+		s.mu.Unlock()
+		return
+	}
+	refund.Status = StatusApproved
+	refund.FinalizedAt = s.now().UTC().Format(time.RFC3339Nano)
+	snapshot := *refund
+	s.mu.Unlock()
+
+	s.emit(EventRefundUpdated, "refund", &snapshot, []string{"refund.id", "refund.status", "refund.amount_in_cents"})
+}
+
+// emit firma el evento y lo entrega a los suscriptores y a EventsURL
+func (s *StubServer) emit(event, key string, payload interface{}, properties []string) {
+	s.mu.Lock()
+	listeners := append([]func([]byte, string){}, s.listeners...)
+	s.mu.Unlock()
+
+	body, checksum, err := s.signedEvent(event, key, payload, properties)
+	if err != nil {
+		log.Printf("⚠️ stub wompi: no se pudo firmar el evento %s: %v", event, err)
+		return
+	}
+	for _, listener := range listeners {
+		listener(body, checksum)
+	}
+	if s.EventsURL != "" {
+		go s.postEvent(body, checksum)
+	}
+}
+
+// signedEvent arma un evento firmado con el secreto de eventos; payload va
+// en data bajo key ("transaction", "refund")
+func (s *StubServer) signedEvent(event, key string, payload interface{}, properties []string) ([]byte, string, error) {
+	data, err := json.Marshal(map[string]interface{}{key: payload})
+	if err != nil {
+		return nil, "", err
+	}
+	timestamp := s.now().Unix()
+	checksum, err := EventChecksum(data, properties, timestamp, s.config.EventsSecret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := json.Marshal(Event{
+		Event:       event,
+		Data:        data,
+		Environment: "test",
+		Signature:   EventSignature{Properties: properties, Checksum: checksum},
+		Timestamp:   timestamp,
+		SentAt:      s.now().UTC().Format(time.RFC3339Nano),
+	})
+	return body, checksum, err
+}
+
+func (s *StubServer) postEvent(body []byte, checksum string) {
+	req, err := http.NewRequest(http.MethodPost, s.EventsURL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("⚠️ stub wompi: URL de eventos inválida: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Checksum", checksum)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("⚠️ stub wompi: no se pudo enviar el evento: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// outcomeFor decide el resultado de la transacción con los datos de prueba del sandbox
+func (s *StubServer) outcomeFor(method PaymentMethod) (stubOutcome, error) {
+	switch method.Type {
+	case PaymentCard:
+		s.mu.Lock()
+		number, exists := s.cards[method.Token]
+		s.mu.Unlock()
+		if !exists {
+			return stubOutcome{}, fmt.Errorf("token de tarjeta inválido")
+		}
+		if number == StubCardApproved {
+			return stubOutcome{StatusApproved, ""}, nil
+		}
+		return stubOutcome{StatusDeclined, "Transacción rechazada por el emisor"}, nil
+	case PaymentNequi:
+		switch method.PhoneNumber {
+		case StubNequiApproved:
+			return stubOutcome{StatusApproved, ""}, nil
+		case StubNequiDeclined:
+			return stubOutcome{StatusDeclined, "El usuario rechazó el pago en Nequi"}, nil
+		default:
+			return stubOutcome{StatusError, "Número no registrado en Nequi"}, nil
+		}
+	case PaymentPSE:
+		if method.UserLegalID == "" || method.FinancialInstitutionCode == "" {
+			return stubOutcome{}, fmt.Errorf("user_legal_id y financial_institution_code son requeridos para PSE")
+		}
+		switch method.FinancialInstitutionCode {
+		case StubBankApproves:
+			return stubOutcome{StatusApproved, ""}, nil
+		case StubBankDeclines:
+			return stubOutcome{StatusDeclined, "Transacción rechazada por el banco"}, nil
+		case StubBankPending:
+			return stubOutcome{StatusPending, ""}, nil
+		default:
+			return stubOutcome{StatusError, "Entidad financiera no disponible en sandbox"}, nil
+		}
+	case PaymentBancolombiaTransfer:
+		return stubOutcome{StatusApproved, ""}, nil
+	default:
+		return stubOutcome{}, fmt.Errorf("tipo de medio de pago no soportado: %q", method.Type)
+	}
+}
+
+func (s *StubServer) authorized(r *http.Request, keys ...string) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	for _, key := range keys {
+		if token == key {
+			return true
+		}
+	}
+	return false
+}
+
+func stubPaymentMethod(method PaymentMethod) map[string]interface{} {
+	result := map[string]interface{}{"type": method.Type}
+	if method.PhoneNumber != "" {
+		result["phone_number"] = method.PhoneNumber
+	}
+	if method.FinancialInstitutionCode != "" {
+		result["financial_institution_code"] = method.FinancialInstitutionCode
+	}
+	if method.Installments > 0 {
+		result["installments"] = method.Installments
+	}
+	return result
+}
+
+func stubData(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func stubError(w http.ResponseWriter, status int, kind, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{"type": kind, "reason": reason},
+	})
+}
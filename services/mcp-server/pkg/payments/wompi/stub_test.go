@@ -0,0 +1,79 @@
+package wompi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func stubNequiRequest(reference string) TransactionRequest {
+	return TransactionRequest{
+		AcceptanceToken: stubAcceptanceToken,
+		AmountInCents:   5000000,
+		Currency:        "COP",
+		CustomerEmail:   "pagador@ejemplo.com.co",
+		Reference:       reference,
+		PaymentMethod:   PaymentMethod{Type: PaymentNequi, PhoneNumber: StubNequiApproved},
+	}
+}
+
+func TestStubRejectsInvalidIntegritySignature(t *testing.T) {
+	stub := NewStubServer("http://stub.local")
+	stub.SettleAfter = time.Hour
+
+	req := stubNequiRequest("pedido-firma-invalida")
+	req.Signature = IntegritySignature(req.Reference, req.AmountInCents+100, req.Currency, "", StubConfig.IntegritySecret)
+	_, err := stub.Client().CreateTransaction(context.Background(), req)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("CreateTransaction() error = %v, want APIError 422", err)
+	}
+
+	// Sin firma el cliente la calcula con el secreto de integridad
+	transaction, err := stub.Client().CreateTransaction(context.Background(), stubNequiRequest("pedido-firma-valida"))
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	if transaction.Status != StatusPending {
+		t.Errorf("Status = %s, want %s", transaction.Status, StatusPending)
+	}
+}
+
+func TestStubEventsCarryValidChecksum(t *testing.T) {
+	stub := NewStubServer("http://stub.local")
+	stub.SettleAfter = time.Hour
+
+	type delivery struct {
+		body     []byte
+		checksum string
+	}
+	events := make(chan delivery, 1)
+	stub.OnEvent(func(body []byte, checksum string) {
+		events <- delivery{body, checksum}
+	})
+
+	transaction, err := stub.Client().CreateTransaction(context.Background(), stubNequiRequest("pedido-evento"))
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	stub.Settle(transaction.ID)
+
+	event := <-events
+	parsed, err := ParseEvent(event.body, event.checksum, StubConfig.EventsSecret)
+	if err != nil {
+		t.Fatalf("ParseEvent() error = %v", err)
+	}
+	settled, err := parsed.Transaction()
+	if err != nil {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+	if settled.ID != transaction.ID || settled.Status != StatusApproved {
+		t.Errorf("Transaction() = %s %s, want %s %s", settled.ID, settled.Status, transaction.ID, StatusApproved)
+	}
+
+	if _, err := ParseEvent(event.body, event.checksum, "otro_secreto"); !errors.Is(err, ErrInvalidChecksum) {
+		t.Errorf("ParseEvent() con otro secreto error = %v, want ErrInvalidChecksum", err)
+	}
+}
@@ -0,0 +1,116 @@
+package sarlaft
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Listas restrictivas y de control
+const (
+	ListOFAC = "ofac_sdn" // OFAC Specially Designated Nationals (Tesoro de EE. UU.)
+	ListUN   = "onu"      // Lista consolidada del Consejo de Seguridad de la ONU
+	ListPEP  = "pep"      // Personas expuestas políticamente (Decreto 830 de 2021)
+)
+
+// listNames nombre de cada lista para los reportes
+var listNames = map[string]string{
+	ListOFAC: "OFAC - Lista SDN",
+	ListUN:   "ONU - Lista consolidada del Consejo de Seguridad",
+	ListPEP:  "Personas expuestas políticamente (PEP)",
+}
+
+// Restrictive indica si la lista es vinculante: una coincidencia impide operar
+// hasta que el oficial de cumplimiento la revise. Las listas de control (PEP)
+// solo exigen debida diligencia intensificada.
+func Restrictive(list string) bool {
+	return list == ListOFAC || list == ListUN
+}
+
+// Tipos de registro
+const (
+	EntryIndividual = "individual"
+	EntryEntity     = "entidad"
+	EntryVessel     = "embarcacion"
+	EntryAircraft   = "aeronave"
+)
+
+// Entry persona, entidad o bien listado
+type Entry struct {
+	ID           string     `json:"id"` // lista:identificador en la fuente
+	List         string     `json:"list"`
+	SourceID     string     `json:"source_id"`
+	Type         string     `json:"type"`
+	Name         string     `json:"name"`
+	Aliases      []Alias    `json:"aliases,omitempty"`
+	Documents    []Document `json:"documents,omitempty"`
+	Programs     []string   `json:"programs,omitempty"` // Programas de sanción o cargo PEP
+	Nationality  []string   `json:"nationality,omitempty"`
+	BirthDates   []string   `json:"birth_dates,omitempty"`
+	Remarks      string     `json:"remarks,omitempty"`
+	ListedOn     string     `json:"listed_on,omitempty"`
+	normalized   []nameKey  // Nombre y alias normalizados para la búsqueda
+	documentKeys []string
+}
+
+// Alias nombre alternativo; los débiles (weak, low quality) pesan menos
+type Alias struct {
+	Name string `json:"name"`
+	Weak bool   `json:"weak,omitempty"`
+}
+
+// Document documento de identidad del listado
+type Document struct {
+	Type    string `json:"type"`
+	Number  string `json:"number"`
+	Country string `json:"country,omitempty"`
+}
+
+// ListInfo lista cargada
+type ListInfo struct {
+	List        string    `json:"list"`
+	Name        string    `json:"name"`
+	Files       []string  `json:"files"`
+	Entries     int       `json:"entries"`
+	PublishedAt string    `json:"published_at,omitempty"` // Fecha de publicación declarada en el archivo
+	LoadedAt    time.Time `json:"loaded_at"`
+}
+
+// listFiles archivos de cada lista en el directorio, con los nombres con que
+// se publican: sdn.csv y alt.csv (o sdn.xml) de OFAC, consolidated.xml de la
+// ONU y pep*.csv para las PEP
+type listFiles struct {
+	ofacCSV, ofacAlt, ofacXML, un string
+	pep                           []string
+}
+
+func findListFiles(dir string) (listFiles, error) {
+	var files listFiles
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return files, fmt.Errorf("no se pudo leer el directorio de listas: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := strings.ToLower(entry.Name())
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case name == "sdn.csv":
+			files.ofacCSV = path
+		case name == "alt.csv":
+			files.ofacAlt = path
+		case name == "sdn.xml":
+			files.ofacXML = path
+		case name == "consolidated.xml", strings.HasPrefix(name, "un") && strings.HasSuffix(name, ".xml"),
+			strings.HasPrefix(name, "onu") && strings.HasSuffix(name, ".xml"):
+			files.un = path
+		case strings.HasPrefix(name, "pep") && strings.HasSuffix(name, ".csv"):
+			files.pep = append(files.pep, path)
+		}
+	}
+	return files, nil
+}
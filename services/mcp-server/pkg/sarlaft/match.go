@@ -0,0 +1,276 @@
+package sarlaft
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Umbral por defecto de similitud de nombres (0 a 1) para generar una alerta
+const DefaultThreshold = 0.88
+
+// Similitud mínima entre dos palabras para contarlas como coincidencia
+const tokenThreshold = 0.8
+
+// Peso de un alias débil frente al nombre principal
+const weakAliasFactor = 0.95
+
+// transliterations letras fuera del alfabeto básico: latinas con diacríticos
+// o ligaduras y cirílicas (romanización BGN/PCGN), ya en minúscula
+var transliterations = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "c", 'ĉ': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ģ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ķ': "k", 'ł': "l", 'ľ': "l", 'ļ': "l", 'ñ': "n", 'ń': "n", 'ň': "n", 'ņ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ș': "s", 'ť': "t", 'ţ': "t", 'ț': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'þ': "th",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "e", 'є': "ye",
+	'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh",
+	'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Palabras que no distinguen un nombre: partículas y formas societarias
+var nameStopwords = map[string]bool{
+	"de": true, "del": true, "la": true, "las": true, "los": true, "y": true, "e": true,
+	"da": true, "do": true, "dos": true, "van": true, "von": true, "der": true, "el": true,
+	"sa": true, "sas": true, "ltda": true, "eu": true, "cia": true, "sca": true, "scs": true,
+	"inc": true, "llc": true, "ltd": true, "corp": true, "co": true, "limited": true,
+}
+
+// Transliterate pasa un nombre a minúsculas en el alfabeto latino básico
+func Transliterate(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII {
+			b.WriteRune(r)
+			continue
+		}
+		if latin, ok := transliterations[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		b.WriteRune(' ')
+	}
+	return b.String()
+}
+
+// nameKey nombre normalizado de un registro
+type nameKey struct {
+	name   string
+	tokens []string
+	weak   bool
+}
+
+// nameTokens palabras significativas del nombre transliterado, sin
+// puntuación ("S.A.S." es "sas"), partículas ni formas societarias
+func nameTokens(name string) []string {
+	name = Transliterate(strings.NewReplacer(".", "", "'", "", "’", "").Replace(name))
+	var tokens []string
+	for _, token := range strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		if !nameStopwords[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// NameScore similitud entre dos nombres sin importar el orden de las palabras
+// ("ESCOBAR GAVIRIA, Pablo Emilio" y "Pablo Escobar"). Cada palabra del
+// nombre más corto se empareja con la más parecida del otro (Jaro-Winkler);
+// las palabras sobrantes del nombre largo restan poco, porque en Colombia es
+// común usar solo uno de los nombres y uno de los apellidos.
+func NameScore(a, b string) float64 {
+	return tokenScore(nameTokens(a), nameTokens(b))
+}
+
+func tokenScore(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	short, long := a, b
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+
+	used := make([]bool, len(long))
+	var sum float64
+	matched := 0
+	for _, token := range short {
+		best, bestIndex := 0.0, -1
+		for i, candidate := range long {
+			if used[i] {
+				continue
+			}
+			if score := jaroWinkler(token, candidate); score > best {
+				best, bestIndex = score, i
+			}
+		}
+		if best >= tokenThreshold {
+			used[bestIndex] = true
+			sum += best
+			matched++
+		}
+	}
+	score := sum / float64(len(short)) * (0.85 + 0.15*float64(matched)/float64(len(long)))
+	// Una sola palabra ("Pablo") no identifica a una persona de varios nombres
+	if len(short) == 1 && len(long) > 1 {
+		score *= float64(matched) / float64(len(long))
+	}
+
+	// Palabras unidas o separadas distinto ("MARIAFERNANDA", "MARIA FERNANDA")
+	if joined := jaroWinkler(joinSorted(a), joinSorted(b)); len(short) > 1 && joined > score {
+		score = joined
+	}
+	return score
+}
+
+func joinSorted(tokens []string) string {
+	sorted := append([]string(nil), tokens...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, "")
+}
+
+// jaroWinkler similitud de Jaro-Winkler entre dos palabras ASCII
+func jaroWinkler(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	window := maxInt(len(a), len(b))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	aMatches := make([]bool, len(a))
+	bMatches := make([]bool, len(b))
+	matches := 0
+	for i := 0; i < len(a); i++ {
+		from, to := maxInt(0, i-window), minInt(len(b), i+window+1)
+		for j := from; j < to; j++ {
+			if !bMatches[j] && a[i] == b[j] {
+				aMatches[i], bMatches[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, k := 0, 0
+	for i := 0; i < len(a); i++ {
+		if !aMatches[i] {
+			continue
+		}
+		for !bMatches[k] {
+			k++
+		}
+		if a[i] != b[k] {
+			transpositions++
+		}
+		k++
+	}
+	m := float64(matches)
+	jaro := (m/float64(len(a)) + m/float64(len(b)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < minInt(4, minInt(len(a), len(b))) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// NormalizeDocument deja solo letras y números en mayúscula ("16.247.821",
+// "800213685-4")
+func NormalizeDocument(number string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(number) {
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// documentMatches compara números de documento normalizados; un NIT coincide
+// con o sin dígito de verificación
+func documentMatches(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if a == b {
+		return true
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return len(a) >= 8 && len(b) == len(a)+1 && numeric(b) && strings.HasPrefix(b, a)
+}
+
+func numeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// prepare calcula los nombres y documentos normalizados del registro
+func (e *Entry) prepare() {
+	e.normalized = e.normalized[:0]
+	e.normalized = append(e.normalized, nameKey{name: e.Name, tokens: nameTokens(e.Name)})
+	for _, alias := range e.Aliases {
+		e.normalized = append(e.normalized, nameKey{name: alias.Name, tokens: nameTokens(alias.Name), weak: alias.Weak})
+	}
+	e.documentKeys = e.documentKeys[:0]
+	for _, document := range e.Documents {
+		if key := NormalizeDocument(document.Number); key != "" {
+			e.documentKeys = append(e.documentKeys, key)
+		}
+	}
+}
+
+// match similitud del sujeto con el registro: el nombre (o alias) más
+// parecido y si algún documento coincide
+func (e *Entry) match(tokens []string, document string) (score float64, matchedName string, documentMatch bool) {
+	for _, key := range e.normalized {
+		s := tokenScore(tokens, key.tokens)
+		if key.weak {
+			s *= weakAliasFactor
+		}
+		if s > score {
+			score, matchedName = s, key.name
+		}
+	}
+	for _, key := range e.documentKeys {
+		if documentMatches(document, key) {
+			documentMatch = true
+			break
+		}
+	}
+	return score, matchedName, documentMatch
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
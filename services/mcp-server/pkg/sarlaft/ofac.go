@@ -0,0 +1,263 @@
+package sarlaft
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ofacNull valor vacío del formato CSV heredado de OFAC
+const ofacNull = "-0-"
+
+// Documentos reconocidos en las observaciones (Remarks) de OFAC
+var ofacDocumentPrefixes = []string{
+	"Cedula No.", "NIT #", "Passport", "National ID No.", "Identification Number",
+	"RUC #", "D.N.I.", "C.U.I.T.", "C.U.R.P.", "R.F.C.", "Tax ID No.", "Registration ID",
+}
+
+// ParseOFACCSV lee la lista SDN en el formato CSV publicado por OFAC: sdn.csv
+// (ent_num, SDN_Name, SDN_Type, Program, Title, Call_Sign, Vess_type, Tonnage,
+// GRT, Vess_flag, Vess_owner, Remarks) y opcionalmente alt.csv con los alias
+// (ent_num, alt_num, alt_type, alt_name, alt_remarks). Los documentos, fechas
+// de nacimiento y nacionalidades se extraen de Remarks.
+func ParseOFACCSV(sdn io.Reader, alt io.Reader) ([]Entry, error) {
+	rows, err := readOFACCSV(sdn)
+	if err != nil {
+		return nil, fmt.Errorf("sdn.csv: %w", err)
+	}
+
+	var entries []Entry
+	index := make(map[string]int)
+	for _, row := range rows {
+		if len(row) < 12 {
+			continue
+		}
+		entry := Entry{
+			List:     ListOFAC,
+			SourceID: ofacValue(row[0]),
+			Type:     ofacEntryType(ofacValue(row[2])),
+			Name:     ofacValue(row[1]),
+			Programs: ofacPrograms(ofacValue(row[3])),
+			Remarks:  ofacValue(row[11]),
+		}
+		if entry.SourceID == "" || entry.Name == "" {
+			continue
+		}
+		entry.ID = ListOFAC + ":" + entry.SourceID
+		parseOFACRemarks(&entry)
+		index[entry.SourceID] = len(entries)
+		entries = append(entries, entry)
+	}
+
+	if alt != nil {
+		rows, err := readOFACCSV(alt)
+		if err != nil {
+			return nil, fmt.Errorf("alt.csv: %w", err)
+		}
+		for _, row := range rows {
+			if len(row) < 4 {
+				continue
+			}
+			i, ok := index[ofacValue(row[0])]
+			name := ofacValue(row[3])
+			if !ok || name == "" {
+				continue
+			}
+			weak := len(row) > 4 && strings.Contains(strings.ToLower(row[4]), "weak")
+			entries[i].Aliases = append(entries[i].Aliases, Alias{Name: name, Weak: weak})
+		}
+	}
+	return entries, nil
+}
+
+func readOFACCSV(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// El archivo termina con un registro de control (carácter 0x1A)
+		if len(row) == 1 && strings.TrimSpace(strings.Trim(row[0], "\x1a")) == "" {
+			continue
+		}
+		rows = append(rows, row)
+	}
+}
+
+func ofacValue(value string) string {
+	value = strings.TrimSpace(value)
+	if value == ofacNull {
+		return ""
+	}
+	return value
+}
+
+func ofacEntryType(sdnType string) string {
+	switch strings.ToLower(sdnType) {
+	case "individual":
+		return EntryIndividual
+	case "vessel":
+		return EntryVessel
+	case "aircraft":
+		return EntryAircraft
+	default:
+		return EntryEntity
+	}
+}
+
+// ofacPrograms separa "SDNTK] [SDGT" en sus programas
+func ofacPrograms(value string) []string {
+	var programs []string
+	for _, program := range strings.Split(value, "]") {
+		if program = strings.Trim(program, " ["); program != "" {
+			programs = append(programs, program)
+		}
+	}
+	return programs
+}
+
+// parseOFACRemarks extrae de las observaciones los documentos ("Cedula No.
+// 16247821 (Colombia)"), fechas de nacimiento ("DOB 18 Mar 1961") y
+// nacionalidades ("nationality Colombia", "citizen Colombia")
+func parseOFACRemarks(entry *Entry) {
+	for _, part := range strings.Split(strings.TrimSuffix(entry.Remarks, "."), ";") {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(part, "DOB "):
+			entry.BirthDates = append(entry.BirthDates, strings.TrimPrefix(part, "DOB "))
+		case strings.HasPrefix(part, "nationality "):
+			entry.Nationality = appendUnique(entry.Nationality, strings.TrimPrefix(part, "nationality "))
+		case strings.HasPrefix(part, "citizen "):
+			entry.Nationality = appendUnique(entry.Nationality, strings.TrimPrefix(part, "citizen "))
+		default:
+			if document, ok := ofacDocument(part); ok {
+				entry.Documents = append(entry.Documents, document)
+			}
+		}
+	}
+}
+
+func ofacDocument(part string) (Document, bool) {
+	for _, prefix := range ofacDocumentPrefixes {
+		if !strings.HasPrefix(part, prefix) {
+			continue
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(part, prefix))
+		document := Document{Type: strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(prefix, "#"), "No."))}
+		if open := strings.Index(rest, "("); open >= 0 {
+			document.Country = strings.TrimSpace(strings.Trim(rest[open:], "()"))
+			rest = rest[:open]
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return Document{}, false
+		}
+		document.Number = fields[0]
+		return document, true
+	}
+	return Document{}, false
+}
+
+// ofacXML formato XML de la lista SDN (sdn.xml)
+type ofacXML struct {
+	Publish struct {
+		Date string `xml:"Publish_Date"`
+	} `xml:"publshInformation"`
+	Entries []struct {
+		UID       string   `xml:"uid"`
+		FirstName string   `xml:"firstName"`
+		LastName  string   `xml:"lastName"`
+		Type      string   `xml:"sdnType"`
+		Remarks   string   `xml:"remarks"`
+		Programs  []string `xml:"programList>program"`
+		IDs       []struct {
+			Type    string `xml:"idType"`
+			Number  string `xml:"idNumber"`
+			Country string `xml:"idCountry"`
+		} `xml:"idList>id"`
+		AKAs []struct {
+			Category  string `xml:"category"`
+			FirstName string `xml:"firstName"`
+			LastName  string `xml:"lastName"`
+		} `xml:"akaList>aka"`
+		BirthDates    []string `xml:"dateOfBirthList>dateOfBirthItem>dateOfBirth"`
+		Nationalities []string `xml:"nationalityList>nationality>country"`
+		Citizenships  []string `xml:"citizenshipList>citizenship>country"`
+	} `xml:"sdnEntry"`
+}
+
+// ParseOFACXML lee la lista SDN en el formato XML publicado por OFAC (sdn.xml)
+func ParseOFACXML(r io.Reader) ([]Entry, string, error) {
+	var list ofacXML
+	if err := xml.NewDecoder(r).Decode(&list); err != nil {
+		return nil, "", fmt.Errorf("sdn.xml: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(list.Entries))
+	for _, item := range list.Entries {
+		entry := Entry{
+			ID:         ListOFAC + ":" + item.UID,
+			List:       ListOFAC,
+			SourceID:   item.UID,
+			Type:       ofacEntryType(item.Type),
+			Name:       ofacXMLName(item.LastName, item.FirstName),
+			Programs:   item.Programs,
+			BirthDates: item.BirthDates,
+			Remarks:    strings.TrimSpace(item.Remarks),
+		}
+		for _, id := range item.IDs {
+			if id.Number == "" {
+				continue
+			}
+			entry.Documents = append(entry.Documents, Document{
+				Type:    strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(id.Type, "#"), "No.")),
+				Number:  id.Number,
+				Country: id.Country,
+			})
+		}
+		for _, aka := range item.AKAs {
+			entry.Aliases = append(entry.Aliases, Alias{
+				Name: ofacXMLName(aka.LastName, aka.FirstName),
+				Weak: strings.EqualFold(aka.Category, "weak"),
+			})
+		}
+		for _, country := range append(item.Nationalities, item.Citizenships...) {
+			entry.Nationality = appendUnique(entry.Nationality, country)
+		}
+		if entry.SourceID != "" && entry.Name != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, list.Publish.Date, nil
+}
+
+// ofacXMLName arma el nombre como en el CSV: "APELLIDO, Nombre"
+func ofacXMLName(lastName, firstName string) string {
+	lastName, firstName = strings.TrimSpace(lastName), strings.TrimSpace(firstName)
+	if firstName == "" {
+		return lastName
+	}
+	return lastName + ", " + firstName
+}
+
+func appendUnique(list []string, value string) []string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return list
+	}
+	for _, existing := range list {
+		if strings.EqualFold(existing, value) {
+			return list
+		}
+	}
+	return append(list, value)
+}
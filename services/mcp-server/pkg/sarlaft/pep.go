@@ -0,0 +1,103 @@
+package sarlaft
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"mcp-server/pkg/colombia"
+)
+
+// Columnas reconocidas del archivo de PEP, por su encabezado normalizado
+var pepColumns = map[string]string{
+	"nombre": "name", "nombres y apellidos": "name", "nombre completo": "name", "name": "name",
+	"tipo documento": "document_type", "tipo de documento": "document_type", "document type": "document_type",
+	"numero documento": "document_number", "numero de documento": "document_number", "documento": "document_number",
+	"identificacion": "document_number", "document number": "document_number",
+	"cargo": "position", "position": "position",
+	"entidad": "entity", "entity": "entity",
+	"fecha vinculacion": "from", "fecha de vinculacion": "from", "desde": "from",
+	"fecha desvinculacion": "to", "fecha de desvinculacion": "to", "hasta": "to",
+}
+
+// ParsePEPCSV lee un listado de personas expuestas políticamente en CSV con
+// encabezado (separado por coma o punto y coma, como lo exporta Excel). Las
+// columnas se reconocen por nombre: nombre, tipo_documento, numero_documento,
+// cargo, entidad, fecha_vinculacion y fecha_desvinculacion.
+func ParsePEPCSV(r io.Reader, source string) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if firstLine, _, _ := strings.Cut(text, "\n"); strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	columns := make(map[string]int)
+	for i, header := range rows[0] {
+		key := colombia.NormalizeText(strings.ReplaceAll(header, "_", " "))
+		if column, ok := pepColumns[key]; ok {
+			columns[column] = i
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("%s: falta la columna nombre", source)
+	}
+
+	value := func(row []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	var entries []Entry
+	for n, row := range rows[1:] {
+		name := value(row, "name")
+		if name == "" {
+			continue
+		}
+		sourceID := fmt.Sprintf("%s-%d", source, n+2)
+		entry := Entry{
+			List:     ListPEP,
+			SourceID: sourceID,
+			Type:     EntryIndividual,
+			Name:     name,
+		}
+		if number := value(row, "document_number"); number != "" {
+			entry.SourceID = number
+			entry.Documents = []Document{{Type: value(row, "document_type"), Number: number, Country: "Colombia"}}
+		}
+		entry.ID = ListPEP + ":" + entry.SourceID
+		position := strings.Trim(value(row, "position")+" - "+value(row, "entity"), " -")
+		if position != "" {
+			entry.Programs = []string{position}
+		}
+		if from, to := value(row, "from"), value(row, "to"); from != "" || to != "" {
+			entry.Remarks = strings.TrimSpace(fmt.Sprintf("Vinculación: %s a %s", from, firstNonEmpty(to, "la fecha")))
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
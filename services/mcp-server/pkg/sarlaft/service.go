@@ -0,0 +1,571 @@
+package sarlaft
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server/pkg/colombia"
+
+	"github.com/google/uuid"
+)
+
+// Momentos en que se consulta a un tercero
+const (
+	ContextPayment    = "pago"
+	ContextOnboarding = "vinculacion"
+	ContextManual     = "consulta"
+)
+
+// Resultado de una consulta
+const (
+	ResultClear   = "sin_coincidencias"
+	ResultReview  = "en_revision" // Coincidencias en listas de control (PEP)
+	ResultBlocked = "bloqueado"   // Coincidencias en listas restrictivas
+)
+
+// Estados de una alerta
+const (
+	HitPending   = "pendiente"
+	HitConfirmed = "confirmada" // Coincidencia real: no se opera y se reporta a la UIAF
+	HitDiscarded = "descartada" // Falso positivo: no vuelve a alertar para el mismo tercero
+)
+
+// Tipos de coincidencia
+const (
+	MatchName     = "nombre"
+	MatchDocument = "documento"
+	MatchBoth     = "nombre_y_documento"
+)
+
+// Máximo de alertas por consulta
+const maxHits = 20
+
+var (
+	// ErrScreeningHold el tercero coincide con una lista restrictiva y la
+	// operación queda retenida hasta la revisión del oficial de cumplimiento
+	ErrScreeningHold = errors.New("operación retenida por coincidencia en listas restrictivas (SARLAFT)")
+	// ErrHitNotFound la alerta no existe o no pertenece al tenant
+	ErrHitNotFound = errors.New("alerta no encontrada")
+	// ErrScreeningNotFound la consulta no existe o no pertenece al tenant
+	ErrScreeningNotFound = errors.New("consulta no encontrada")
+)
+
+// Policy control SARLAFT del tenant
+type Policy struct {
+	Enabled   bool
+	Threshold float64 // Vacío: DefaultThreshold
+}
+
+// Subject tercero consultado
+type Subject struct {
+	Name           string `json:"name"`
+	DocumentType   string `json:"document_type,omitempty"`
+	DocumentNumber string `json:"document_number,omitempty"`
+	PersonType     string `json:"person_type,omitempty"` // natural, juridica; vacío = ambas
+}
+
+// key identifica al tercero para recordar las alertas descartadas
+func (s Subject) key() string {
+	if number := NormalizeDocument(s.DocumentNumber); number != "" {
+		return "doc:" + number
+	}
+	return "nombre:" + strings.Join(nameTokens(s.Name), " ")
+}
+
+// ScreenRequest solicitud de consulta en listas
+type ScreenRequest struct {
+	TenantID  string
+	Context   string
+	Reference string // Pedido, subdominio o referencia de la operación
+	Subject   Subject
+	Threshold float64 // Vacío: DefaultThreshold
+}
+
+// Review decisión del oficial de cumplimiento sobre una alerta
+type Review struct {
+	Status   string    `json:"status"`
+	Reviewer string    `json:"reviewer"`
+	Notes    string    `json:"notes"`
+	At       time.Time `json:"at"`
+}
+
+// Hit alerta por coincidencia de un tercero con un registro de una lista
+type Hit struct {
+	ID          string     `json:"id"`
+	TenantID    string     `json:"tenant_id"`
+	ScreeningID string     `json:"screening_id"` // Consulta que la generó
+	Context     string     `json:"context"`
+	Reference   string     `json:"reference,omitempty"`
+	Subject     Subject    `json:"subject"`
+	List        string     `json:"list"`
+	ListName    string     `json:"list_name"`
+	Restrictive bool       `json:"restrictive"` // Impide operar mientras no se descarte
+	Entry       Entry      `json:"entry"`
+	MatchedName string     `json:"matched_name,omitempty"` // Nombre o alias que coincidió
+	MatchType   string     `json:"match_type"`
+	Score       float64    `json:"score"`
+	Status      string     `json:"status"`
+	Occurrences int        `json:"occurrences"` // Consultas que la repitieron
+	Reviews     []Review   `json:"reviews,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// Blocking indica si la alerta retiene las operaciones del tercero
+func (h *Hit) Blocking() bool {
+	return h.Restrictive && h.Status != HitDiscarded
+}
+
+// Screening consulta de un tercero en las listas cargadas
+type Screening struct {
+	ID         string     `json:"id"`
+	TenantID   string     `json:"tenant_id"`
+	Context    string     `json:"context"`
+	Reference  string     `json:"reference,omitempty"`
+	Subject    Subject    `json:"subject"`
+	Threshold  float64    `json:"threshold"`
+	Lists      []ListInfo `json:"lists"`
+	Result     string     `json:"result"`
+	Hits       []*Hit     `json:"hits"`
+	Warnings   []string   `json:"warnings,omitempty"`
+	ScreenedAt time.Time  `json:"screened_at"`
+}
+
+// Blocked indica si alguna alerta impide operar
+func (s *Screening) Blocked() bool {
+	return s.Result == ResultBlocked
+}
+
+// Summary describe las alertas que retienen la operación
+func (s *Screening) Summary() string {
+	var parts []string
+	for _, hit := range s.Hits {
+		if hit.Blocking() {
+			parts = append(parts, fmt.Sprintf("%s (%s, alerta %s %s)", hit.ListName, hit.Entry.Name, hit.ID, hit.Status))
+		}
+	}
+	if len(parts) == 0 {
+		return "sin coincidencias en listas restrictivas"
+	}
+	return "coincidencia con " + strings.Join(parts, "; ")
+}
+
+// Service carga las listas restrictivas y de control, consulta a los terceros
+// y guarda las alertas para la revisión del oficial de cumplimiento
+type Service struct {
+	dir string
+
+	mu          sync.RWMutex
+	entries     map[string][]Entry // Por lista
+	lists       map[string]ListInfo
+	hits        map[string]*Hit
+	subjectHits map[string]string // tenant|tercero|registro -> alerta
+	screenings  map[string]*Screening
+	now         func() time.Time
+}
+
+// NewService crea el servicio sin listas cargadas
+func NewService() *Service {
+	return &Service{
+		entries:     make(map[string][]Entry),
+		lists:       make(map[string]ListInfo),
+		hits:        make(map[string]*Hit),
+		subjectHits: make(map[string]string),
+		screenings:  make(map[string]*Screening),
+		now:         time.Now,
+	}
+}
+
+// WithListsDir configura el directorio con los archivos de las listas tal como
+// se publican (ver Reload)
+func (s *Service) WithListsDir(dir string) *Service {
+	s.dir = dir
+	return s
+}
+
+// Reload carga las listas del directorio configurado: sdn.csv y alt.csv (o
+// sdn.xml) de OFAC, consolidated.xml de la ONU y pep*.csv. Una lista que no
+// se pueda leer conserva su versión anterior.
+func (s *Service) Reload() ([]ListInfo, error) {
+	if s.dir == "" {
+		return nil, fmt.Errorf("no hay directorio de listas configurado")
+	}
+	files, err := findListFiles(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	fail := func(err error) { problems = append(problems, err.Error()) }
+	switch {
+	case files.ofacCSV != "":
+		if err := s.loadOFACCSV(files.ofacCSV, files.ofacAlt); err != nil {
+			fail(err)
+		}
+	case files.ofacXML != "":
+		if err := s.loadFile(files.ofacXML, func(f *os.File) ([]Entry, string, error) { return ParseOFACXML(f) }, ListOFAC); err != nil {
+			fail(err)
+		}
+	}
+	if files.un != "" {
+		if err := s.loadFile(files.un, func(f *os.File) ([]Entry, string, error) { return ParseUNXML(f) }, ListUN); err != nil {
+			fail(err)
+		}
+	}
+	if len(files.pep) > 0 {
+		var entries []Entry
+		for _, path := range files.pep {
+			f, err := os.Open(path)
+			if err != nil {
+				fail(err)
+				continue
+			}
+			parsed, err := ParsePEPCSV(f, strings.TrimSuffix(filepath.Base(path), ".csv"))
+			f.Close()
+			if err != nil {
+				fail(err)
+				continue
+			}
+			entries = append(entries, parsed...)
+		}
+		if len(entries) > 0 {
+			s.Load(ListInfo{List: ListPEP, Files: fileNames(files.pep)}, entries)
+		}
+	}
+
+	lists := s.Lists()
+	if len(problems) > 0 {
+		return lists, fmt.Errorf("error cargando listas: %s", strings.Join(problems, "; "))
+	}
+	if len(lists) == 0 {
+		return lists, fmt.Errorf("no se encontraron listas en %s", s.dir)
+	}
+	return lists, nil
+}
+
+func (s *Service) loadOFACCSV(sdnPath, altPath string) error {
+	sdn, err := os.Open(sdnPath)
+	if err != nil {
+		return err
+	}
+	defer sdn.Close()
+	info := ListInfo{List: ListOFAC, Files: []string{filepath.Base(sdnPath)}}
+	var entries []Entry
+	if altPath != "" {
+		alt, err := os.Open(altPath)
+		if err != nil {
+			return err
+		}
+		defer alt.Close()
+		info.Files = append(info.Files, filepath.Base(altPath))
+		entries, err = ParseOFACCSV(sdn, alt)
+		if err != nil {
+			return err
+		}
+	} else if entries, err = ParseOFACCSV(sdn, nil); err != nil {
+		return err
+	}
+	if stat, err := os.Stat(sdnPath); err == nil {
+		info.PublishedAt = stat.ModTime().Format("2006-01-02")
+	}
+	s.Load(info, entries)
+	return nil
+}
+
+func (s *Service) loadFile(path string, parse func(*os.File) ([]Entry, string, error), list string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entries, published, err := parse(f)
+	if err != nil {
+		return err
+	}
+	s.Load(ListInfo{List: list, Files: []string{filepath.Base(path)}, PublishedAt: published}, entries)
+	return nil
+}
+
+// Load reemplaza los registros de una lista
+func (s *Service) Load(info ListInfo, entries []Entry) ListInfo {
+	for i := range entries {
+		entries[i].prepare()
+	}
+	info.Name = listNames[info.List]
+	if info.Name == "" {
+		info.Name = info.List
+	}
+	info.Entries = len(entries)
+	info.LoadedAt = s.now().In(colombia.Location())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[info.List] = entries
+	s.lists[info.List] = info
+	log.Printf("🛡️ SARLAFT: %s cargada con %d registros", info.Name, info.Entries)
+	return info
+}
+
+// Lists listas cargadas
+func (s *Service) Lists() []ListInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lists := make([]ListInfo, 0, len(s.lists))
+	for _, info := range s.lists {
+		lists = append(lists, info)
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].List < lists[j].List })
+	return lists
+}
+
+// Screen consulta al tercero en las listas cargadas por nombre (similitud
+// difusa con transliteración) y por número de documento. Las coincidencias
+// nuevas quedan como alertas pendientes; las que el oficial de cumplimiento
+// descartó para el mismo tercero no vuelven a alertar.
+func (s *Service) Screen(req ScreenRequest) (*Screening, error) {
+	subject := req.Subject
+	subject.Name = strings.TrimSpace(subject.Name)
+	subject.DocumentNumber = strings.TrimSpace(subject.DocumentNumber)
+	tokens := nameTokens(subject.Name)
+	document := NormalizeDocument(subject.DocumentNumber)
+	if len(tokens) == 0 && document == "" {
+		return nil, fmt.Errorf("se requiere el nombre o el documento del tercero")
+	}
+	threshold := req.Threshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultThreshold
+	}
+	context := req.Context
+	if context == "" {
+		context = ContextManual
+	}
+
+	now := s.now().In(colombia.Location())
+	screening := &Screening{
+		ID:         newID("SCR"),
+		TenantID:   req.TenantID,
+		Context:    context,
+		Reference:  req.Reference,
+		Subject:    subject,
+		Threshold:  threshold,
+		Result:     ResultClear,
+		Hits:       []*Hit{},
+		ScreenedAt: now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, info := range s.lists {
+		screening.Lists = append(screening.Lists, info)
+	}
+	sort.Slice(screening.Lists, func(i, j int) bool { return screening.Lists[i].List < screening.Lists[j].List })
+	if len(screening.Lists) == 0 {
+		screening.Warnings = append(screening.Warnings, "no hay listas cargadas: la consulta no verificó ninguna lista")
+	}
+
+	var candidates []*Hit
+	for list, entries := range s.entries {
+		for i := range entries {
+			entry := &entries[i]
+			if !entryApplies(entry.Type, subject.PersonType) {
+				continue
+			}
+			score, matchedName, documentMatch := entry.match(tokens, document)
+			if !documentMatch && score < threshold {
+				continue
+			}
+			hit := &Hit{
+				List:        list,
+				ListName:    listNames[list],
+				Restrictive: Restrictive(list),
+				Entry:       *entry,
+				MatchedName: matchedName,
+				MatchType:   MatchName,
+				Score:       roundScore(score),
+			}
+			switch {
+			case documentMatch && score >= threshold:
+				hit.MatchType, hit.Score = MatchBoth, 1
+			case documentMatch:
+				hit.MatchType, hit.Score, hit.MatchedName = MatchDocument, 1, ""
+			}
+			candidates = append(candidates, hit)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	if len(candidates) > maxHits {
+		candidates = candidates[:maxHits]
+	}
+
+	subjectKey := subject.key()
+	for _, candidate := range candidates {
+		key := req.TenantID + "|" + subjectKey + "|" + candidate.Entry.ID
+		if id, ok := s.subjectHits[key]; ok {
+			hit := s.hits[id]
+			if hit.Status == HitDiscarded {
+				continue
+			}
+			hit.Occurrences++
+			hit.LastSeenAt = now
+			screening.Hits = append(screening.Hits, hit.clone())
+			continue
+		}
+
+		hit := candidate
+		hit.ID = newID("ALR")
+		hit.TenantID = req.TenantID
+		hit.ScreeningID = screening.ID
+		hit.Context = context
+		hit.Reference = req.Reference
+		hit.Subject = subject
+		hit.Status = HitPending
+		hit.Occurrences = 1
+		hit.CreatedAt = now
+		hit.LastSeenAt = now
+		s.hits[hit.ID] = hit
+		s.subjectHits[key] = hit.ID
+		screening.Hits = append(screening.Hits, hit.clone())
+	}
+
+	for _, hit := range screening.Hits {
+		if hit.Blocking() {
+			screening.Result = ResultBlocked
+			break
+		}
+		screening.Result = ResultReview
+	}
+	if len(screening.Hits) > 0 {
+		s.screenings[screening.ID] = screening
+		log.Printf("🛡️ SARLAFT: %s de %s (%s) con %d coincidencias: %s", context, subject.Name, req.TenantID, len(screening.Hits), screening.Result)
+	}
+	return screening.clone(), nil
+}
+
+// entryApplies filtra los registros por tipo de persona: a una persona
+// natural solo la comparan individuos y a una jurídica solo entidades
+func entryApplies(entryType, personType string) bool {
+	switch strings.ToLower(personType) {
+	case "natural":
+		return entryType == EntryIndividual
+	case "juridica":
+		return entryType == EntryEntity
+	default:
+		return entryType == EntryIndividual || entryType == EntryEntity
+	}
+}
+
+// Hits alertas del tenant, las pendientes primero. status y context filtran
+// si no están vacíos.
+func (s *Service) Hits(tenantID, status, context string) []*Hit {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var hits []*Hit
+	for _, hit := range s.hits {
+		if hit.TenantID != tenantID || (status != "" && hit.Status != status) || (context != "" && hit.Context != context) {
+			continue
+		}
+		hits = append(hits, hit.clone())
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if (hits[i].Status == HitPending) != (hits[j].Status == HitPending) {
+			return hits[i].Status == HitPending
+		}
+		return hits[i].LastSeenAt.After(hits[j].LastSeenAt)
+	})
+	return hits
+}
+
+// GetHit retorna una alerta del tenant
+func (s *Service) GetHit(tenantID, id string) (*Hit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hit, ok := s.hits[id]
+	if !ok || hit.TenantID != tenantID {
+		return nil, ErrHitNotFound
+	}
+	return hit.clone(), nil
+}
+
+// GetScreening retorna una consulta con coincidencias del tenant
+func (s *Service) GetScreening(tenantID, id string) (*Screening, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	screening, ok := s.screenings[id]
+	if !ok || screening.TenantID != tenantID {
+		return nil, ErrScreeningNotFound
+	}
+	// Las alertas con su estado actual
+	c := screening.clone()
+	for i, hit := range c.Hits {
+		if current, ok := s.hits[hit.ID]; ok {
+			c.Hits[i] = current.clone()
+		}
+	}
+	return c, nil
+}
+
+// ReviewHit registra la decisión del oficial de cumplimiento: confirmada
+// (coincidencia real) o descartada (falso positivo). La justificación es
+// obligatoria y las decisiones anteriores quedan en el historial.
+func (s *Service) ReviewHit(tenantID, id string, review Review) (*Hit, error) {
+	review.Status = strings.ToLower(strings.TrimSpace(review.Status))
+	if review.Status != HitConfirmed && review.Status != HitDiscarded {
+		return nil, fmt.Errorf("decisión inválida: use %s o %s", HitConfirmed, HitDiscarded)
+	}
+	if strings.TrimSpace(review.Notes) == "" {
+		return nil, fmt.Errorf("la justificación de la decisión es requerida")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hit, ok := s.hits[id]
+	if !ok || hit.TenantID != tenantID {
+		return nil, ErrHitNotFound
+	}
+	review.At = s.now().In(colombia.Location())
+	hit.Status = review.Status
+	hit.Reviews = append(hit.Reviews, review)
+	hit.ReviewedAt = &review.At
+	return hit.clone(), nil
+}
+
+func (h *Hit) clone() *Hit {
+	c := *h
+	c.Reviews = append([]Review(nil), h.Reviews...)
+	return &c
+}
+
+func (s *Screening) clone() *Screening {
+	c := *s
+	c.Lists = append([]ListInfo(nil), s.Lists...)
+	c.Hits = make([]*Hit, 0, len(s.Hits))
+	for _, hit := range s.Hits {
+		c.Hits = append(c.Hits, hit.clone())
+	}
+	c.Warnings = append([]string(nil), s.Warnings...)
+	return &c
+}
+
+func roundScore(score float64) float64 {
+	return float64(int(score*1000+0.5)) / 1000
+}
+
+func fileNames(paths []string) []string {
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, filepath.Base(path))
+	}
+	return names
+}
+
+func newID(prefix string) string {
+	return prefix + "-" + strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:16])
+}
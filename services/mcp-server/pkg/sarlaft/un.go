@@ -0,0 +1,126 @@
+package sarlaft
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// unAlias alias de la lista de la ONU; QUALITY "Low" es un alias débil
+type unAlias struct {
+	Quality string `xml:"QUALITY"`
+	Name    string `xml:"ALIAS_NAME"`
+}
+
+// unRecord individuo o entidad de la lista consolidada
+type unRecord struct {
+	DataID      string    `xml:"DATAID"`
+	FirstName   string    `xml:"FIRST_NAME"`
+	SecondName  string    `xml:"SECOND_NAME"`
+	ThirdName   string    `xml:"THIRD_NAME"`
+	FourthName  string    `xml:"FOURTH_NAME"`
+	ListType    string    `xml:"UN_LIST_TYPE"`
+	Reference   string    `xml:"REFERENCE_NUMBER"`
+	ListedOn    string    `xml:"LISTED_ON"`
+	Comments    string    `xml:"COMMENTS1"`
+	Nationality []string  `xml:"NATIONALITY>VALUE"`
+	Aliases     []unAlias `xml:"INDIVIDUAL_ALIAS"`
+	EntityAlias []unAlias `xml:"ENTITY_ALIAS"`
+	BirthDates  []struct {
+		Date string `xml:"DATE"`
+		Year string `xml:"YEAR"`
+	} `xml:"INDIVIDUAL_DATE_OF_BIRTH"`
+	Documents []struct {
+		Type    string `xml:"TYPE_OF_DOCUMENT"`
+		Number  string `xml:"NUMBER"`
+		Country string `xml:"ISSUING_COUNTRY"`
+	} `xml:"INDIVIDUAL_DOCUMENT"`
+}
+
+// unXML formato XML de la lista consolidada (consolidated.xml)
+type unXML struct {
+	Generated   string     `xml:"dateGenerated,attr"`
+	Individuals []unRecord `xml:"INDIVIDUALS>INDIVIDUAL"`
+	Entities    []unRecord `xml:"ENTITIES>ENTITY"`
+}
+
+// ParseUNXML lee la lista consolidada del Consejo de Seguridad de la ONU en su
+// formato XML publicado (consolidated.xml)
+func ParseUNXML(r io.Reader) ([]Entry, string, error) {
+	var list unXML
+	if err := xml.NewDecoder(r).Decode(&list); err != nil {
+		return nil, "", fmt.Errorf("lista de la ONU: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(list.Individuals)+len(list.Entities))
+	for _, record := range list.Individuals {
+		entries = append(entries, unEntry(record, EntryIndividual))
+	}
+	for _, record := range list.Entities {
+		entries = append(entries, unEntry(record, EntryEntity))
+	}
+
+	valid := entries[:0]
+	for _, entry := range entries {
+		if entry.SourceID != "" && entry.Name != "" {
+			valid = append(valid, entry)
+		}
+	}
+	published := list.Generated
+	if i := strings.Index(published, "T"); i > 0 {
+		published = published[:i]
+	}
+	return valid, published, nil
+}
+
+func unEntry(record unRecord, entryType string) Entry {
+	sourceID := strings.TrimSpace(record.Reference)
+	if sourceID == "" {
+		sourceID = strings.TrimSpace(record.DataID)
+	}
+	var names []string
+	for _, name := range []string{record.FirstName, record.SecondName, record.ThirdName, record.FourthName} {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	entry := Entry{
+		ID:       ListUN + ":" + sourceID,
+		List:     ListUN,
+		SourceID: sourceID,
+		Type:     entryType,
+		Name:     strings.Join(names, " "),
+		Remarks:  strings.TrimSpace(record.Comments),
+		ListedOn: strings.TrimSpace(record.ListedOn),
+	}
+	if listType := strings.TrimSpace(record.ListType); listType != "" {
+		entry.Programs = []string{listType}
+	}
+	for _, country := range record.Nationality {
+		entry.Nationality = appendUnique(entry.Nationality, country)
+	}
+	for _, alias := range append(record.Aliases, record.EntityAlias...) {
+		if name := strings.TrimSpace(alias.Name); name != "" {
+			entry.Aliases = append(entry.Aliases, Alias{Name: name, Weak: strings.EqualFold(alias.Quality, "low")})
+		}
+	}
+	for _, birth := range record.BirthDates {
+		if date := strings.TrimSpace(birth.Date); date != "" {
+			entry.BirthDates = append(entry.BirthDates, date)
+		} else if year := strings.TrimSpace(birth.Year); year != "" {
+			entry.BirthDates = append(entry.BirthDates, year)
+		}
+	}
+	for _, document := range record.Documents {
+		if number := strings.TrimSpace(document.Number); number != "" {
+			entry.Documents = append(entry.Documents, Document{
+				Type:    strings.TrimSpace(document.Type),
+				Number:  number,
+				Country: strings.TrimSpace(document.Country),
+			})
+		}
+	}
+	return entry
+}
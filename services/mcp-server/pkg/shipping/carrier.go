@@ -0,0 +1,282 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"mcp-server/pkg/colombia"
+)
+
+// Códigos de las transportadoras soportadas
+const (
+	CarrierServientrega    = "servientrega"
+	CarrierCoordinadora    = "coordinadora"
+	CarrierInterrapidisimo = "interrapidisimo"
+	CarrierTCC             = "tcc"
+)
+
+// carrierNames nombre comercial y página de rastreo de cada transportadora,
+// en orden de preferencia
+var carrierNames = []struct {
+	code        string
+	name        string
+	trackingURL string
+}{
+	{CarrierServientrega, "Servientrega", "https://www.servientrega.com/wps/portal/rastreo-envio?guia="},
+	{CarrierCoordinadora, "Coordinadora", "https://coordinadora.com/rastreo/rastreo-de-guia/detalle-de-rastreo-de-guia/?guia="},
+	{CarrierInterrapidisimo, "Interrapidísimo", "https://www.interrapidisimo.com/sigue-tu-envio/?guia="},
+	{CarrierTCC, "TCC", "https://tcc.com.co/rastreo/?guia="},
+}
+
+// Carriers códigos de todas las transportadoras soportadas
+func Carriers() []string {
+	codes := make([]string, 0, len(carrierNames))
+	for _, carrier := range carrierNames {
+		codes = append(codes, carrier.code)
+	}
+	return codes
+}
+
+// CarrierName nombre comercial de la transportadora
+func CarrierName(code string) string {
+	for _, carrier := range carrierNames {
+		if carrier.code == code {
+			return carrier.name
+		}
+	}
+	return code
+}
+
+// TrackingURL página de rastreo de la guía en el sitio de la transportadora
+func TrackingURL(code, trackingNumber string) string {
+	trackingNumber = strings.TrimSpace(trackingNumber)
+	if trackingNumber == "" {
+		return ""
+	}
+	for _, carrier := range carrierNames {
+		if carrier.code == code {
+			return carrier.trackingURL + url.QueryEscape(trackingNumber)
+		}
+	}
+	return ""
+}
+
+// NormalizeCarrier resuelve el código de una transportadora a partir de su
+// nombre como lo escribe el usuario ("Interrapidísimo", "inter rapidisimo", "TCC")
+func NormalizeCarrier(name string) (string, bool) {
+	key := strings.ReplaceAll(colombia.NormalizeText(name), " ", "")
+	switch key {
+	case "inter", "interrapidisimo":
+		return CarrierInterrapidisimo, true
+	case "coordinadora", "coordinadoramercantil":
+		return CarrierCoordinadora, true
+	}
+	for _, carrier := range carrierNames {
+		if key == carrier.code {
+			return carrier.code, true
+		}
+	}
+	return "", false
+}
+
+// Estados normalizados de un envío
+const (
+	StatusCreated        = "creado"
+	StatusPickedUp       = "recogido"
+	StatusInTransit      = "en_transito"
+	StatusOutForDelivery = "en_reparto"
+	StatusDelivered      = "entregado"
+	StatusReturned       = "devuelto"
+	StatusException      = "novedad"
+	StatusCancelled      = "anulado"
+)
+
+// statusLabels descripción de cada estado para el cliente final
+var statusLabels = map[string]string{
+	StatusCreated:        "Guía generada",
+	StatusPickedUp:       "Recogido por la transportadora",
+	StatusInTransit:      "En tránsito",
+	StatusOutForDelivery: "En reparto",
+	StatusDelivered:      "Entregado",
+	StatusReturned:       "Devuelto al remitente",
+	StatusException:      "Con novedad",
+	StatusCancelled:      "Anulado",
+}
+
+// StatusLabel descripción del estado para el cliente final
+func StatusLabel(status string) string {
+	if label, ok := statusLabels[status]; ok {
+		return label
+	}
+	return status
+}
+
+// Orígenes de los eventos de rastreo
+const (
+	SourceWebhook = "webhook"  // Notificado por la transportadora
+	SourcePolling = "consulta" // Consultado a la API de la transportadora
+	SourceManual  = "manual"   // Registrado por el comercio
+)
+
+// Errores de las transportadoras
+var (
+	ErrShipmentNotFound = errors.New("envío no encontrado")
+	ErrNoCoverage       = errors.New("la transportadora no tiene cobertura para la ruta")
+	ErrNotCancellable   = errors.New("la guía ya no puede anularse")
+	ErrCarrierDisabled  = errors.New("la transportadora no está habilitada para el comercio")
+	ErrInvalidSignature = errors.New("firma del webhook inválida")
+	// ErrCarrierUnsupported aún no hay integración con las APIs reales de las
+	// transportadoras: sin especificaciones ni credenciales de prueba solo
+	// opera el simulador
+	ErrCarrierUnsupported = errors.New("la integración directa con la transportadora aún no está disponible; opera solo en el simulador")
+)
+
+// Credentials credenciales de integración que entrega la transportadora al
+// firmar el contrato de crédito. Con llave la transportadora queda marcada
+// como no soportada hasta que exista su adaptador.
+type Credentials struct {
+	APIKey string
+}
+
+// DefaultVolumetricDivisor centímetros cúbicos por kilo con los que se
+// calcula el peso volumétrico si la tarifa no indica otro
+const DefaultVolumetricDivisor = 5000
+
+// Package paquete a enviar. Las medidas son de cada pieza; sin medidas se
+// cobra el peso real.
+type Package struct {
+	WeightGrams   int    `json:"weight_grams"`
+	LengthCm      int    `json:"length_cm,omitempty"`
+	WidthCm       int    `json:"width_cm,omitempty"`
+	HeightCm      int    `json:"height_cm,omitempty"`
+	DeclaredValue int64  `json:"declared_value_cop"`
+	Pieces        int    `json:"pieces,omitempty"`
+	Content       string `json:"content,omitempty"`
+}
+
+// VolumetricGrams peso volumétrico de todas las piezas: largo × ancho × alto
+// entre el divisor (cm³ por kilo), en gramos
+func (p Package) VolumetricGrams(divisor int) int {
+	if divisor <= 0 {
+		divisor = DefaultVolumetricDivisor
+	}
+	volume := int64(p.LengthCm) * int64(p.WidthCm) * int64(p.HeightCm) * int64(maxInt(p.Pieces, 1))
+	return int((volume*1000 + int64(divisor) - 1) / int64(divisor))
+}
+
+// ChargeableGrams peso a cobrar: el mayor entre el real y el volumétrico
+func (p Package) ChargeableGrams(divisor int) int {
+	return maxInt(p.WeightGrams, p.VolumetricGrams(divisor))
+}
+
+// Party remitente o destinatario de un envío
+type Party struct {
+	Name     string                `json:"name"`
+	Document string                `json:"document,omitempty"`
+	Phone    string                `json:"phone"` // E.164
+	Email    string                `json:"email,omitempty"`
+	Address  string                `json:"address"` // Dirección normalizada
+	City     colombia.Municipality `json:"city"`
+}
+
+// QuoteRequest datos para cotizar un envío entre dos municipios DIVIPOLA
+type QuoteRequest struct {
+	Origin         colombia.Municipality
+	Destination    colombia.Municipality
+	Package        Package
+	CashOnDelivery int64 // Valor a recaudar al entregar; cero si no hay recaudo
+}
+
+// Quote cotización de una transportadora
+type Quote struct {
+	Carrier           string    `json:"carrier"`
+	CarrierName       string    `json:"carrier_name"`
+	Service           string    `json:"service"`
+	Freight           int64     `json:"freight_cop"`
+	Insurance         int64     `json:"insurance_cop"`
+	CODFee            int64     `json:"cod_fee_cop,omitempty"`
+	Total             int64     `json:"total_cop"`
+	DeliveryDays      int       `json:"delivery_days"` // Días hábiles
+	EstimatedDelivery time.Time `json:"estimated_delivery"`
+	Sandbox           bool      `json:"sandbox"`
+
+	// Cotizaciones con la tabla de tarifas negociada del comercio
+	FuelSurcharge   int64  `json:"fuel_surcharge_cop,omitempty"`
+	ChargeableGrams int    `json:"chargeable_grams,omitempty"`
+	RateTableID     string `json:"rate_table_id,omitempty"`
+	RateZone        string `json:"rate_zone,omitempty"`
+}
+
+// GuideRequest solicitud de generación de guía
+type GuideRequest struct {
+	QuoteRequest
+	Sender    Party
+	Recipient Party
+	Reference string // Pedido del comercio
+	Service   string // Vacío: servicio por defecto de la transportadora
+}
+
+// Guide guía generada por la transportadora
+type Guide struct {
+	Carrier        string    `json:"carrier"`
+	TrackingNumber string    `json:"tracking_number"`
+	Service        string    `json:"service"`
+	TrackingURL    string    `json:"tracking_url"`
+	Quote          Quote     `json:"quote"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TrackingEvent evento del rastreo de una guía
+type TrackingEvent struct {
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	Location    string    `json:"location,omitempty"`
+	At          time.Time `json:"at"`
+	Source      string    `json:"source,omitempty"`
+	// CollectedAmount valor recaudado al entregar un envío contraentrega
+	CollectedAmount int64 `json:"collected_amount_cop,omitempty"`
+}
+
+// Tracking estado de una guía con su historial
+type Tracking struct {
+	Carrier        string          `json:"carrier"`
+	TrackingNumber string          `json:"tracking_number"`
+	Status         string          `json:"status"`
+	Events         []TrackingEvent `json:"events"`
+}
+
+// Carrier adaptador de una transportadora
+type Carrier interface {
+	// Code código de la transportadora
+	Code() string
+	// Quote cotiza el envío; ErrNoCoverage si la ruta no está cubierta
+	Quote(ctx context.Context, req QuoteRequest) (*Quote, error)
+	// CreateGuide genera la guía del envío
+	CreateGuide(ctx context.Context, req GuideRequest) (*Guide, error)
+	// Label rótulo de la guía en PDF
+	Label(ctx context.Context, trackingNumber string) ([]byte, error)
+	// Track consulta el rastreo de la guía
+	Track(ctx context.Context, trackingNumber string) (*Tracking, error)
+	// Cancel anula la guía antes de su recolección
+	Cancel(ctx context.Context, trackingNumber string) error
+}
+
+// Final indica si el envío ya no cambiará de estado
+func Final(status string) bool {
+	switch status {
+	case StatusDelivered, StatusReturned, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// chargeableKilos kilos a cobrar: fracción de kilo se cobra como kilo completo
+func chargeableKilos(grams int) int {
+	if grams <= 1000 {
+		return 1
+	}
+	return (grams + 999) / 1000
+}
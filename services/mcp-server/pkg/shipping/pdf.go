@@ -0,0 +1,81 @@
+package shipping
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// labelLine línea del rótulo; Bold resalta los datos que lee el operario
+type labelLine struct {
+	Text string
+	Bold bool
+	Size int
+}
+
+// renderLabelPDF genera el rótulo de una guía como PDF de una página de
+// 10x15 cm (formato de impresora térmica), con fuentes estándar Helvetica
+func renderLabelPDF(lines []labelLine) []byte {
+	const width, height = 283, 425 // Puntos: 10x15 cm
+
+	var content bytes.Buffer
+	y := height - 36
+	for _, line := range lines {
+		size := line.Size
+		if size == 0 {
+			size = 10
+		}
+		font := "F1"
+		if line.Bold {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "BT /%s %d Tf 18 %d Td (%s) Tj ET\n", font, size, y, pdfString(line.Text))
+		y -= size + 8
+	}
+	// Marco del rótulo
+	fmt.Fprintf(&content, "1 w 10 10 %d %d re S\n", width-20, height-20)
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Contents 4 0 R /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>", width, height),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return pdf.Bytes()
+}
+
+// pdfString escapa el texto y lo codifica en Latin-1 (WinAnsi), que cubre
+// las tildes y la eñe; los demás caracteres se reemplazan por "?"
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x100:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,372 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server/pkg/colombia"
+
+	"github.com/google/uuid"
+)
+
+// ===== TABLAS DE TARIFAS NEGOCIADAS =====
+
+// ErrRateTableNotFound tabla de tarifas inexistente
+var ErrRateTableNotFound = errors.New("tabla de tarifas no encontrada")
+
+// AnyLocation patrón de zona que cubre cualquier municipio
+const AnyLocation = "*"
+
+// RateTable tarifas que el comercio negoció con una transportadora. Las
+// cotizaciones de esa transportadora salen de la tabla cuando alguna zona
+// cubre la ruta y el peso; si no, se cotiza con la transportadora.
+type RateTable struct {
+	ID                string     `json:"id"`
+	TenantID          string     `json:"tenant_id"`
+	Carrier           string     `json:"carrier"`
+	Name              string     `json:"name"`
+	Service           string     `json:"service,omitempty"`
+	VolumetricDivisor int        `json:"volumetric_divisor,omitempty"` // cm³ por kilo; vacío = DefaultVolumetricDivisor
+	FuelSurcharge     float64    `json:"fuel_surcharge_pct,omitempty"` // Porcentaje sobre el flete
+	InsuranceRate     float64    `json:"insurance_pct,omitempty"`      // Porcentaje sobre el valor declarado
+	MinInsurance      int64      `json:"min_insurance_cop,omitempty"`
+	MinFreight        int64      `json:"min_freight_cop,omitempty"`
+	CODRate           float64    `json:"cod_pct,omitempty"` // Comisión de recaudo contraentrega
+	MinCODFee         int64      `json:"min_cod_fee_cop,omitempty"`
+	Zones             []RateZone `json:"zones"`
+	Disabled          bool       `json:"disabled,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// RateZone trayectos con la misma tarifa. Origen y destino se indican con
+// códigos DIVIPOLA de municipio (5 dígitos), de departamento (2 dígitos) o
+// "*"; si varias zonas cubren la ruta se usa la más específica que tenga
+// tarifa para el peso.
+type RateZone struct {
+	Code         string          `json:"code"`
+	Name         string          `json:"name,omitempty"`
+	Origins      []string        `json:"origins"`
+	Destinations []string        `json:"destinations"`
+	DeliveryDays int             `json:"delivery_days"`            // Días hábiles
+	Brackets     []WeightBracket `json:"brackets"`                 // Rangos de peso ascendentes
+	ExtraKilo    int64           `json:"extra_kilo_cop,omitempty"` // Kilo adicional sobre el último rango; vacío = sin tarifa
+}
+
+// WeightBracket flete de los envíos hasta un peso cobrable
+type WeightBracket struct {
+	UpToGrams int   `json:"up_to_grams"`
+	Price     int64 `json:"price_cop"`
+}
+
+// normalize valida la tabla y completa los valores por defecto
+func (t *RateTable) normalize() error {
+	code, ok := NormalizeCarrier(t.Carrier)
+	if !ok {
+		return fmt.Errorf("transportadora no soportada: %s", t.Carrier)
+	}
+	t.Carrier = code
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		t.Name = "Tarifa " + CarrierName(code)
+	}
+	if t.VolumetricDivisor < 0 {
+		return fmt.Errorf("el divisor volumétrico no puede ser negativo")
+	}
+	for label, pct := range map[string]float64{"recargo de combustible": t.FuelSurcharge, "seguro": t.InsuranceRate, "comisión de recaudo": t.CODRate} {
+		if pct < 0 || pct > 100 {
+			return fmt.Errorf("porcentaje de %s inválido: %.2f", label, pct)
+		}
+	}
+	if t.MinInsurance < 0 || t.MinFreight < 0 || t.MinCODFee < 0 {
+		return fmt.Errorf("los mínimos no pueden ser negativos")
+	}
+	if len(t.Zones) == 0 {
+		return fmt.Errorf("la tabla debe tener al menos una zona")
+	}
+
+	codes := make(map[string]bool)
+	for i := range t.Zones {
+		zone := &t.Zones[i]
+		zone.Code = strings.TrimSpace(zone.Code)
+		if zone.Code == "" {
+			zone.Code = fmt.Sprintf("Z%d", i+1)
+		}
+		if codes[zone.Code] {
+			return fmt.Errorf("zona %s repetida", zone.Code)
+		}
+		codes[zone.Code] = true
+
+		var err error
+		if zone.Origins, err = normalizeLocations(zone.Origins); err != nil {
+			return fmt.Errorf("zona %s, origen: %w", zone.Code, err)
+		}
+		if zone.Destinations, err = normalizeLocations(zone.Destinations); err != nil {
+			return fmt.Errorf("zona %s, destino: %w", zone.Code, err)
+		}
+		if zone.DeliveryDays < 1 {
+			return fmt.Errorf("zona %s: los días de entrega deben ser al menos 1", zone.Code)
+		}
+		if len(zone.Brackets) == 0 {
+			return fmt.Errorf("zona %s: debe tener al menos un rango de peso", zone.Code)
+		}
+		sort.SliceStable(zone.Brackets, func(a, b int) bool {
+			return zone.Brackets[a].UpToGrams < zone.Brackets[b].UpToGrams
+		})
+		for j, bracket := range zone.Brackets {
+			if bracket.UpToGrams <= 0 || bracket.Price <= 0 {
+				return fmt.Errorf("zona %s: peso y valor de los rangos deben ser mayores a cero", zone.Code)
+			}
+			if j > 0 && bracket.UpToGrams == zone.Brackets[j-1].UpToGrams {
+				return fmt.Errorf("zona %s: rango de %d g repetido", zone.Code, bracket.UpToGrams)
+			}
+		}
+		if zone.ExtraKilo < 0 {
+			return fmt.Errorf("zona %s: el kilo adicional no puede ser negativo", zone.Code)
+		}
+	}
+	return nil
+}
+
+// normalizeLocations valida los códigos DIVIPOLA de una zona
+func normalizeLocations(patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("indique códigos DIVIPOLA o %q", AnyLocation)
+	}
+	result := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		switch len(pattern) {
+		case 1:
+			if pattern != AnyLocation {
+				return nil, fmt.Errorf("código DIVIPOLA inválido: %s", pattern)
+			}
+		case 2:
+			if dept, ok := colombia.FindDepartment(pattern); !ok || dept.Code != pattern {
+				return nil, fmt.Errorf("departamento DIVIPOLA inexistente: %s", pattern)
+			}
+		case 5:
+			if _, ok := colombia.MunicipalityByCode(pattern); !ok {
+				return nil, fmt.Errorf("municipio DIVIPOLA inexistente: %s", pattern)
+			}
+		default:
+			return nil, fmt.Errorf("código DIVIPOLA inválido: %s (municipio de 5 dígitos o departamento de 2)", pattern)
+		}
+		result = append(result, pattern)
+	}
+	return result, nil
+}
+
+// locationScore qué tan específico es el patrón que cubre el municipio: 2 el
+// municipio, 1 el departamento, 0 cualquiera; -1 si ninguno lo cubre
+func locationScore(patterns []string, m colombia.Municipality) int {
+	best := -1
+	for _, pattern := range patterns {
+		score := -1
+		switch {
+		case pattern == m.Code:
+			score = 2
+		case pattern == m.DepartmentCode:
+			score = 1
+		case pattern == AnyLocation:
+			score = 0
+		}
+		if score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// zonesFor zonas que cubren la ruta, de la más específica a la más general
+func (t *RateTable) zonesFor(origin, destination colombia.Municipality) []*RateZone {
+	var zones []*RateZone
+	scores := make(map[*RateZone]int)
+	for i := range t.Zones {
+		zone := &t.Zones[i]
+		from, to := locationScore(zone.Origins, origin), locationScore(zone.Destinations, destination)
+		if from < 0 || to < 0 {
+			continue
+		}
+		zones = append(zones, zone)
+		scores[zone] = from + to
+	}
+	sort.SliceStable(zones, func(i, j int) bool {
+		return scores[zones[i]] > scores[zones[j]]
+	})
+	return zones
+}
+
+// freight flete de la zona para el peso cobrable; false si supera el último
+// rango y la zona no tiene tarifa de kilo adicional
+func (z *RateZone) freight(grams int) (int64, bool) {
+	for _, bracket := range z.Brackets {
+		if grams <= bracket.UpToGrams {
+			return bracket.Price, true
+		}
+	}
+	if z.ExtraKilo <= 0 {
+		return 0, false
+	}
+	last := z.Brackets[len(z.Brackets)-1]
+	extraKilos := (grams - last.UpToGrams + 999) / 1000
+	return last.Price + int64(extraKilos)*z.ExtraKilo, true
+}
+
+// Quote cotiza con la zona más específica que cubre la ruta y el peso; false
+// si ninguna los cubre
+func (t *RateTable) Quote(req QuoteRequest, now time.Time) (*Quote, bool) {
+	grams := req.Package.ChargeableGrams(t.VolumetricDivisor)
+	var zone *RateZone
+	var freight int64
+	for _, candidate := range t.zonesFor(req.Origin, req.Destination) {
+		if price, ok := candidate.freight(grams); ok {
+			zone, freight = candidate, price
+			break
+		}
+	}
+	if zone == nil {
+		return nil, false
+	}
+	if freight < t.MinFreight {
+		freight = t.MinFreight
+	}
+
+	fuel := roundPeso(float64(freight) * t.FuelSurcharge / 100)
+	insurance := roundPeso(float64(req.Package.DeclaredValue) * t.InsuranceRate / 100)
+	if insurance < t.MinInsurance {
+		insurance = t.MinInsurance
+	}
+	var codFee int64
+	if req.CashOnDelivery > 0 {
+		codFee = roundPeso(float64(req.CashOnDelivery) * t.CODRate / 100)
+		if codFee < t.MinCODFee {
+			codFee = t.MinCODFee
+		}
+	}
+
+	service := t.Service
+	if service == "" {
+		service = t.Name
+	}
+	return &Quote{
+		Carrier:           t.Carrier,
+		CarrierName:       CarrierName(t.Carrier),
+		Service:           service,
+		Freight:           freight,
+		FuelSurcharge:     fuel,
+		Insurance:         insurance,
+		CODFee:            codFee,
+		Total:             freight + fuel + insurance + codFee,
+		DeliveryDays:      zone.DeliveryDays,
+		EstimatedDelivery: colombia.AddBusinessDays(now.In(colombia.Location()), zone.DeliveryDays),
+		ChargeableGrams:   grams,
+		RateTableID:       t.ID,
+		RateZone:          zone.Code,
+	}, true
+}
+
+func (t *RateTable) clone() *RateTable {
+	table := *t
+	table.Zones = make([]RateZone, len(t.Zones))
+	for i, zone := range t.Zones {
+		zone.Origins = append([]string(nil), zone.Origins...)
+		zone.Destinations = append([]string(nil), zone.Destinations...)
+		zone.Brackets = append([]WeightBracket(nil), zone.Brackets...)
+		table.Zones[i] = zone
+	}
+	return &table
+}
+
+// SaveRateTable crea la tabla de tarifas del tenant o, si trae el ID de una
+// existente, la reemplaza
+func (s *Service) SaveRateTable(tenantID string, table RateTable) (*RateTable, error) {
+	if err := table.normalize(); err != nil {
+		return nil, err
+	}
+	now := s.now().In(colombia.Location())
+	table.TenantID = tenantID
+	table.UpdatedAt = now
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tables := s.rateTables[tenantID]
+	if table.ID != "" {
+		for i, existing := range tables {
+			if existing.ID == table.ID {
+				table.CreatedAt = existing.CreatedAt
+				tables[i] = table.clone()
+				return table.clone(), nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", ErrRateTableNotFound, table.ID)
+	}
+
+	table.ID = "TAR-" + strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:16])
+	table.CreatedAt = now
+	s.rateTables[tenantID] = append(tables, table.clone())
+	return table.clone(), nil
+}
+
+// RateTables tablas de tarifas del tenant, opcionalmente de una transportadora
+func (s *Service) RateTables(tenantID, carrier string) []*RateTable {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*RateTable
+	for _, table := range s.rateTables[tenantID] {
+		if carrier == "" || table.Carrier == carrier {
+			result = append(result, table.clone())
+		}
+	}
+	return result
+}
+
+// GetRateTable tabla de tarifas del tenant
+func (s *Service) GetRateTable(tenantID, tableID string) (*RateTable, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, table := range s.rateTables[tenantID] {
+		if table.ID == tableID {
+			return table.clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrRateTableNotFound, tableID)
+}
+
+// DeleteRateTable elimina la tabla; la transportadora vuelve a cotizar con su API
+func (s *Service) DeleteRateTable(tenantID, tableID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tables := s.rateTables[tenantID]
+	for i, table := range tables {
+		if table.ID == tableID {
+			s.rateTables[tenantID] = append(tables[:i:i], tables[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrRateTableNotFound, tableID)
+}
+
+// tableQuote cotización con la primera tabla activa de la transportadora que
+// cubre la ruta y el peso; nil para cotizar con la transportadora
+func (s *Service) tableQuote(tenantID, carrier string, req QuoteRequest) *Quote {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	for _, table := range s.rateTables[tenantID] {
+		if table.Disabled || table.Carrier != carrier {
+			continue
+		}
+		if quote, ok := table.Quote(req, now); ok {
+			return quote
+		}
+	}
+	return nil
+}
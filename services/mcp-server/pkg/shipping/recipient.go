@@ -0,0 +1,67 @@
+package shipping
+
+import (
+	"sort"
+
+	"mcp-server/pkg/colombia"
+)
+
+// ===== DATOS PERSONALES DE LOS DESTINATARIOS =====
+
+// ErasedRecipient nombre con que queda el destinatario suprimido
+const ErasedRecipient = "Titular suprimido"
+
+// RecipientShipments envíos del tenant cuyo destinatario cumple match
+func (s *Service) RecipientShipments(tenantID string, match func(Party) bool) []*Shipment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*Shipment
+	for _, shipment := range s.shipments {
+		if shipment.TenantID == tenantID && match(shipment.Recipient) {
+			result = append(result, shipment.clone())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// EraseRecipient suprime los datos del destinatario en los envíos terminados;
+// los envíos en curso los conservan hasta la entrega. Retorna los envíos
+// suprimidos y los que siguen en curso.
+func (s *Service) EraseRecipient(tenantID string, match func(Party) bool) (erased, active int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().In(colombia.Location())
+	for _, shipment := range s.shipments {
+		if shipment.TenantID != tenantID || !match(shipment.Recipient) {
+			continue
+		}
+		if !Final(shipment.Status) {
+			active++
+			continue
+		}
+		shipment.Recipient = Party{Name: ErasedRecipient, City: shipment.Recipient.City}
+		shipment.UpdatedAt = now
+		erased++
+	}
+	return erased, active
+}
+
+// RectifyRecipient corrige los datos del destinatario en sus envíos
+func (s *Service) RectifyRecipient(tenantID string, match func(Party) bool, correct func(*Party)) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, shipment := range s.shipments {
+		if shipment.TenantID == tenantID && match(shipment.Recipient) {
+			correct(&shipment.Recipient)
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,523 @@
+package shipping
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/notify"
+
+	"github.com/google/uuid"
+)
+
+// quoteTimeout tiempo máximo de espera por la cotización de cada transportadora
+const quoteTimeout = 15 * time.Second
+
+// Criterios de selección de transportadora
+const (
+	SelectByPrice = "precio"
+	SelectBySpeed = "tiempo"
+)
+
+// ParseStrategy interpreta el criterio de selección; vacío es por precio
+func ParseStrategy(value string) (string, error) {
+	switch colombia.NormalizeText(value) {
+	case "", "precio", "price", "barato", "economico", "cheapest":
+		return SelectByPrice, nil
+	case "tiempo", "speed", "rapido", "fastest", "velocidad":
+		return SelectBySpeed, nil
+	}
+	return "", fmt.Errorf("criterio de selección inválido: %s (use precio o tiempo)", value)
+}
+
+// TenantConfig transportadoras habilitadas para un tenant. Las que no tienen
+// llave de API cotizan y generan guías contra el simulador local; sin ninguna
+// configurada se habilitan todas en el simulador. Las que tienen llave
+// retornan ErrCarrierUnsupported mientras no exista su adaptador.
+type TenantConfig struct {
+	TenantID string
+	Carriers map[string]Credentials
+
+	// WebhookSecret clave con la que las transportadoras firman sus
+	// notificaciones; sin ella solo se aceptan webhooks de guías de sandbox
+	WebhookSecret string
+	// Notify credenciales para avisar al destinatario los hitos del envío
+	Notify notify.Config
+}
+
+// enabled códigos de las transportadoras habilitadas, en orden de preferencia
+func (c TenantConfig) enabled() []string {
+	if len(c.Carriers) == 0 {
+		return Carriers()
+	}
+	var codes []string
+	for _, code := range Carriers() {
+		if _, ok := c.Carriers[code]; ok {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// sandbox indica si la transportadora opera contra el simulador
+func (c TenantConfig) sandbox(code string) bool {
+	return c.Carriers[code].APIKey == ""
+}
+
+// CarrierStatus transportadora y su modo de operación para un tenant
+type CarrierStatus struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Sandbox bool   `json:"sandbox"`
+	// Supported falso si el tenant configuró llave: aún no hay adaptador real
+	Supported bool `json:"supported"`
+}
+
+// RateShopping resultado de cotizar con todas las transportadoras habilitadas
+type RateShopping struct {
+	Origin      colombia.Municipality `json:"origin"`
+	Destination colombia.Municipality `json:"destination"`
+	Package     Package               `json:"package"`
+	Strategy    string                `json:"strategy"`
+	Selected    *Quote                `json:"selected"`
+	Quotes      []Quote               `json:"quotes"`                // Ordenadas según el criterio
+	Unavailable map[string]string     `json:"unavailable,omitempty"` // Transportadora → motivo
+	QuotedAt    time.Time             `json:"quoted_at"`
+}
+
+// ShipmentRequest solicitud de envío. Sin transportadora se elige la mejor
+// cotización según el criterio.
+type ShipmentRequest struct {
+	GuideRequest
+	Carrier        string
+	Strategy       string
+	FreightPayment string // origen (paga el remitente) o destino (paga el destinatario)
+}
+
+// Formas de pago del flete
+const (
+	FreightPaidBySender    = "origen"
+	FreightPaidByRecipient = "destino"
+)
+
+// Shipment envío con su guía
+type Shipment struct {
+	ID             string                `json:"id"`
+	TenantID       string                `json:"tenant_id"`
+	Reference      string                `json:"reference,omitempty"` // Pedido del comercio
+	Carrier        string                `json:"carrier"`
+	CarrierName    string                `json:"carrier_name"`
+	TrackingNumber string                `json:"tracking_number"`
+	TrackingURL    string                `json:"tracking_url"` // Página pública de rastreo del comercio
+	CarrierURL     string                `json:"carrier_tracking_url"`
+	Service        string                `json:"service"`
+	Status         string                `json:"status"`
+	Origin         colombia.Municipality `json:"origin"`
+	Destination    colombia.Municipality `json:"destination"`
+	Sender         Party                 `json:"sender"`
+	Recipient      Party                 `json:"recipient"`
+	Package        Package               `json:"package"`
+	CashOnDelivery int64                 `json:"cash_on_delivery_cop,omitempty"`
+	FreightPayment string                `json:"freight_payment"`
+	Quote          Quote                 `json:"quote"`
+	Events         []TrackingEvent       `json:"events,omitempty"`
+	Notified       []string              `json:"notified,omitempty"` // Hitos ya avisados al destinatario
+	Sandbox        bool                  `json:"sandbox"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+	LastCheckedAt  *time.Time            `json:"last_checked_at,omitempty"`
+}
+
+func (s *Shipment) clone() *Shipment {
+	shipment := *s
+	shipment.Events = append([]TrackingEvent(nil), s.Events...)
+	shipment.Notified = append([]string(nil), s.Notified...)
+	return &shipment
+}
+
+// Listener recibe cada cambio de estado de un envío con el evento que lo produjo
+type Listener func(ctx context.Context, shipment *Shipment, event TrackingEvent)
+
+// Service cotiza, genera y rastrea envíos con las transportadoras de cada tenant
+type Service struct {
+	simulator *Simulator
+	notifier  *notify.Service
+	listeners []Listener
+	publicURL string
+
+	mu         sync.RWMutex
+	configs    map[string]TenantConfig // Última configuración de cada tenant, para el rastreo periódico
+	shipments  map[string]*Shipment
+	byTracking map[string]string // Transportadora:guía → envío
+	rateTables map[string][]*RateTable
+	now        func() time.Time
+}
+
+// NewService crea el servicio de envíos con el simulador para sandbox
+func NewService(simulator *Simulator) *Service {
+	if simulator == nil {
+		simulator = NewSimulator()
+	}
+	return &Service{
+		simulator:  simulator,
+		configs:    make(map[string]TenantConfig),
+		shipments:  make(map[string]*Shipment),
+		byTracking: make(map[string]string),
+		rateTables: make(map[string][]*RateTable),
+		now:        time.Now,
+	}
+}
+
+// WithNotifications avisa al destinatario los hitos del envío
+func (s *Service) WithNotifications(notifier *notify.Service) *Service {
+	s.notifier = notifier
+	return s
+}
+
+// WithPublicURL publica el rastreo de los envíos en {publicURL}/rastreo/{id}
+// en lugar de enlazar la página de la transportadora
+func (s *Service) WithPublicURL(publicURL string) *Service {
+	s.publicURL = strings.TrimRight(publicURL, "/")
+	return s
+}
+
+// OnStatusChange registra un listener de cambios de estado. Se registran al
+// iniciar, antes de recibir eventos.
+func (s *Service) OnStatusChange(listener Listener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// rememberConfig guarda la configuración del tenant para el rastreo periódico
+// y los webhooks, que llegan sin tenant
+func (s *Service) rememberConfig(cfg TenantConfig) {
+	s.mu.Lock()
+	s.configs[cfg.TenantID] = cfg
+	s.mu.Unlock()
+}
+
+func (s *Service) configFor(tenantID string) TenantConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if cfg, ok := s.configs[tenantID]; ok {
+		return cfg
+	}
+	return TenantConfig{TenantID: tenantID}
+}
+
+// Carriers transportadoras soportadas y su estado para el tenant
+func (s *Service) Carriers(cfg TenantConfig) []CarrierStatus {
+	enabled := make(map[string]bool)
+	for _, code := range cfg.enabled() {
+		enabled[code] = true
+	}
+	var statuses []CarrierStatus
+	for _, code := range Carriers() {
+		statuses = append(statuses, CarrierStatus{
+			Code:      code,
+			Name:      CarrierName(code),
+			Enabled:   enabled[code],
+			Sandbox:   cfg.sandbox(code),
+			Supported: cfg.sandbox(code),
+		})
+	}
+	return statuses
+}
+
+// CarrierFor adaptador de la transportadora para el tenant. Solo existe el
+// simulador: las transportadoras con llave del tenant retornan
+// ErrCarrierUnsupported y quedan sin cotizar.
+func (s *Service) CarrierFor(cfg TenantConfig, code string) (Carrier, error) {
+	enabled := false
+	for _, candidate := range cfg.enabled() {
+		enabled = enabled || candidate == code
+	}
+	if !enabled {
+		return nil, fmt.Errorf("%w: %s", ErrCarrierDisabled, CarrierName(code))
+	}
+	if !cfg.sandbox(code) {
+		return nil, fmt.Errorf("%w (%s)", ErrCarrierUnsupported, CarrierName(code))
+	}
+	return s.simulator.Carrier(code)
+}
+
+// Quote cotiza el envío con todas las transportadoras habilitadas en paralelo
+// y elige la mejor según el criterio: menor valor total o menos días hábiles.
+// Las transportadoras con tabla de tarifas del tenant que cubre la ruta
+// cotizan con la tabla, sin consultar su API.
+func (s *Service) Quote(ctx context.Context, cfg TenantConfig, req QuoteRequest, strategy string) (*RateShopping, error) {
+	strategy, err := ParseStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+	if req.Package.WeightGrams <= 0 {
+		return nil, fmt.Errorf("el peso debe ser mayor a cero")
+	}
+
+	codes := cfg.enabled()
+	quotes := make([]*Quote, len(codes))
+	failures := make([]error, len(codes))
+	var wg sync.WaitGroup
+	for i, code := range codes {
+		wg.Add(1)
+		go func(i int, code string) {
+			defer wg.Done()
+			if quote := s.tableQuote(cfg.TenantID, code, req); quote != nil {
+				quotes[i] = quote
+				return
+			}
+			carrier, err := s.CarrierFor(cfg, code)
+			if err != nil {
+				failures[i] = err
+				return
+			}
+			quoteCtx, cancel := context.WithTimeout(ctx, quoteTimeout)
+			defer cancel()
+			quotes[i], failures[i] = carrier.Quote(quoteCtx, req)
+		}(i, code)
+	}
+	wg.Wait()
+
+	result := &RateShopping{
+		Origin:      req.Origin,
+		Destination: req.Destination,
+		Package:     req.Package,
+		Strategy:    strategy,
+		QuotedAt:    s.now().In(colombia.Location()),
+	}
+	for i, code := range codes {
+		if failures[i] != nil {
+			if result.Unavailable == nil {
+				result.Unavailable = make(map[string]string)
+			}
+			result.Unavailable[code] = failures[i].Error()
+			continue
+		}
+		quote := *quotes[i]
+		quote.Sandbox = cfg.sandbox(code)
+		result.Quotes = append(result.Quotes, quote)
+	}
+	if len(result.Quotes) == 0 {
+		return nil, fmt.Errorf("%w: ninguna transportadora cotizó %s → %s", ErrNoCoverage, req.Origin.Name, req.Destination.Name)
+	}
+
+	sortQuotes(result.Quotes, strategy)
+	result.Selected = &result.Quotes[0]
+	return result, nil
+}
+
+// sortQuotes ordena las cotizaciones por el criterio y desempata por el otro
+func sortQuotes(quotes []Quote, strategy string) {
+	sort.SliceStable(quotes, func(i, j int) bool {
+		a, b := quotes[i], quotes[j]
+		if strategy == SelectBySpeed {
+			if a.DeliveryDays != b.DeliveryDays {
+				return a.DeliveryDays < b.DeliveryDays
+			}
+			return a.Total < b.Total
+		}
+		if a.Total != b.Total {
+			return a.Total < b.Total
+		}
+		return a.DeliveryDays < b.DeliveryDays
+	})
+}
+
+// CreateShipment genera la guía con la transportadora indicada o, si no se
+// indica, con la mejor cotización; si esa falla se intenta con la siguiente
+func (s *Service) CreateShipment(ctx context.Context, cfg TenantConfig, req ShipmentRequest) (*Shipment, error) {
+	if req.Package.WeightGrams <= 0 {
+		return nil, fmt.Errorf("el peso debe ser mayor a cero")
+	}
+	if req.Recipient.Name == "" || req.Recipient.Address == "" {
+		return nil, fmt.Errorf("nombre y dirección del destinatario son requeridos")
+	}
+	switch req.FreightPayment {
+	case "":
+		req.FreightPayment = FreightPaidBySender
+	case FreightPaidBySender, FreightPaidByRecipient:
+	default:
+		return nil, fmt.Errorf("forma de pago del flete inválida: %s (use origen o destino)", req.FreightPayment)
+	}
+	req.Sender.City = req.Origin
+	req.Recipient.City = req.Destination
+	s.rememberConfig(cfg)
+
+	candidates := []string{req.Carrier}
+	if req.Carrier == "" {
+		shopping, err := s.Quote(ctx, cfg, req.QuoteRequest, req.Strategy)
+		if err != nil {
+			return nil, err
+		}
+		candidates = candidates[:0]
+		for _, quote := range shopping.Quotes {
+			candidates = append(candidates, quote.Carrier)
+		}
+	}
+
+	var lastErr error
+	for _, code := range candidates {
+		carrier, err := s.CarrierFor(cfg, code)
+		if err != nil {
+			return nil, err
+		}
+		guide, err := carrier.CreateGuide(ctx, req.GuideRequest)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", CarrierName(code), err)
+			continue
+		}
+		// El valor del envío es el de la tarifa negociada, no el de lista
+		if quote := s.tableQuote(cfg.TenantID, code, req.QuoteRequest); quote != nil {
+			quote.Service = guide.Service
+			guide.Quote = *quote
+		}
+		return s.store(cfg, req, guide), nil
+	}
+	return nil, lastErr
+}
+
+func (s *Service) store(cfg TenantConfig, req ShipmentRequest, guide *Guide) *Shipment {
+	now := s.now().In(colombia.Location())
+	if guide.CreatedAt.IsZero() {
+		guide.CreatedAt = now
+	}
+	quote := guide.Quote
+	quote.Sandbox = cfg.sandbox(guide.Carrier)
+	shipment := &Shipment{
+		ID:             "ENV-" + strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:16]),
+		TenantID:       cfg.TenantID,
+		Reference:      req.Reference,
+		Carrier:        guide.Carrier,
+		CarrierName:    CarrierName(guide.Carrier),
+		TrackingNumber: guide.TrackingNumber,
+		CarrierURL:     guide.TrackingURL,
+		Service:        guide.Service,
+		Status:         StatusCreated,
+		Origin:         req.Origin,
+		Destination:    req.Destination,
+		Sender:         req.Sender,
+		Recipient:      req.Recipient,
+		Package:        req.Package,
+		CashOnDelivery: req.CashOnDelivery,
+		FreightPayment: req.FreightPayment,
+		Quote:          quote,
+		Sandbox:        quote.Sandbox,
+		CreatedAt:      guide.CreatedAt,
+		UpdatedAt:      now,
+	}
+	if shipment.CarrierURL == "" {
+		shipment.CarrierURL = TrackingURL(guide.Carrier, guide.TrackingNumber)
+	}
+	shipment.TrackingURL = shipment.CarrierURL
+	if s.publicURL != "" {
+		shipment.TrackingURL = s.publicURL + "/rastreo/" + shipment.ID
+	}
+
+	s.mu.Lock()
+	s.shipments[shipment.ID] = shipment
+	s.byTracking[guideKey(shipment.Carrier, shipment.TrackingNumber)] = shipment.ID
+	s.mu.Unlock()
+	return shipment.clone()
+}
+
+// GetShipment retorna un envío del tenant
+func (s *Service) GetShipment(tenantID, shipmentID string) (*Shipment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shipment, ok := s.shipments[shipmentID]
+	if !ok || shipment.TenantID != tenantID {
+		return nil, fmt.Errorf("%w: %s", ErrShipmentNotFound, shipmentID)
+	}
+	return shipment.clone(), nil
+}
+
+// ListShipments lista los envíos de un tenant, opcionalmente de un pedido
+func (s *Service) ListShipments(tenantID, reference string) []*Shipment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*Shipment
+	for _, shipment := range s.shipments {
+		if shipment.TenantID == tenantID && (reference == "" || shipment.Reference == reference) {
+			result = append(result, shipment.clone())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Label rótulo PDF de la guía del envío
+func (s *Service) Label(ctx context.Context, cfg TenantConfig, shipmentID string) ([]byte, error) {
+	shipment, carrier, err := s.shipmentCarrier(cfg, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if shipment.Status == StatusCancelled {
+		return nil, fmt.Errorf("la guía %s está anulada", shipment.TrackingNumber)
+	}
+	return carrier.Label(ctx, shipment.TrackingNumber)
+}
+
+// Track consulta el rastreo con la transportadora y actualiza el envío
+func (s *Service) Track(ctx context.Context, cfg TenantConfig, shipmentID string) (*Shipment, error) {
+	shipment, carrier, err := s.shipmentCarrier(cfg, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if Final(shipment.Status) {
+		return shipment, nil
+	}
+	s.rememberConfig(cfg)
+
+	tracking, err := carrier.Track(ctx, shipment.TrackingNumber)
+	if err != nil {
+		return nil, err
+	}
+	return s.ApplyTracking(ctx, shipmentID, tracking.Events, SourcePolling)
+}
+
+// Cancel anula la guía con la transportadora; solo antes de la recolección
+func (s *Service) Cancel(ctx context.Context, cfg TenantConfig, shipmentID string) (*Shipment, error) {
+	shipment, carrier, err := s.shipmentCarrier(cfg, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if shipment.Status == StatusCancelled {
+		return shipment, nil
+	}
+	if shipment.Status != StatusCreated {
+		return nil, fmt.Errorf("%w: el envío %s está %s", ErrNotCancellable, shipment.TrackingNumber, shipment.Status)
+	}
+
+	if err := carrier.Cancel(ctx, shipment.TrackingNumber); err != nil {
+		return nil, err
+	}
+
+	return s.ApplyTracking(ctx, shipmentID, []TrackingEvent{{
+		Status:      StatusCancelled,
+		Description: "Guía anulada por el remitente",
+		Location:    shipment.Origin.Name,
+		At:          s.now(),
+	}}, SourceManual)
+}
+
+func (s *Service) shipmentCarrier(cfg TenantConfig, shipmentID string) (*Shipment, Carrier, error) {
+	shipment, err := s.GetShipment(cfg.TenantID, shipmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Las guías de sandbox viven en el simulador aunque el tenant ya tenga llaves
+	if shipment.Sandbox {
+		carrier, err := s.simulator.Carrier(shipment.Carrier)
+		return shipment, carrier, err
+	}
+	carrier, err := s.CarrierFor(cfg, shipment.Carrier)
+	return shipment, carrier, err
+}
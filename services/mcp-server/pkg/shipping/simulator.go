@@ -0,0 +1,357 @@
+package shipping
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server/pkg/colombia"
+)
+
+// carrierProfile tarifa y tiempos simulados de una transportadora
+type carrierProfile struct {
+	code          string
+	service       string
+	guidePrefix   string
+	priceFactor   float64 // Sobre la tarifa base de la ruta
+	extraDays     int     // Días hábiles sobre el tiempo base (negativo: más rápida)
+	insuranceRate float64 // Porcentaje del valor declarado
+	minInsurance  int64
+	codRate       float64 // Comisión de recaudo contraentrega
+	minCODFee     int64
+	remoteRoutes  bool // Atiende trayectos especiales (Amazonía, Orinoquía, San Andrés)
+}
+
+var simulatedProfiles = map[string]carrierProfile{
+	CarrierServientrega: {
+		code: CarrierServientrega, service: "Mercancía Premier", guidePrefix: "2",
+		priceFactor: 1.0, insuranceRate: 0.01, minInsurance: 500,
+		codRate: 0.03, minCODFee: 3000, remoteRoutes: true,
+	},
+	CarrierCoordinadora: {
+		code: CarrierCoordinadora, service: "Paquetería", guidePrefix: "9",
+		priceFactor: 0.95, insuranceRate: 0.01, minInsurance: 400,
+		codRate: 0.025, minCODFee: 3500,
+	},
+	CarrierInterrapidisimo: {
+		code: CarrierInterrapidisimo, service: "Mensajería", guidePrefix: "24",
+		priceFactor: 0.85, extraDays: 1, insuranceRate: 0.012, minInsurance: 300,
+		codRate: 0.035, minCODFee: 2500, remoteRoutes: true,
+	},
+	CarrierTCC: {
+		code: CarrierTCC, service: "Paquetería Express", guidePrefix: "7",
+		priceFactor: 1.12, extraDays: -1, insuranceRate: 0.008, minInsurance: 600,
+		codRate: 0.03, minCODFee: 4000,
+	},
+}
+
+// routeBaseCosts tarifa base del primer kilo entre ciudades principales (simulada)
+var routeBaseCosts = map[string]map[string]int64{
+	"11001": { // Bogotá
+		"05001": 12000, // Medellín
+		"76001": 15000, // Cali
+		"08001": 18000, // Barranquilla
+		"13001": 20000, // Cartagena
+	},
+	"05001": { // Medellín
+		"11001": 12000, // Bogotá
+		"76001": 10000, // Cali
+		"08001": 16000, // Barranquilla
+	},
+}
+
+// remoteDepartments departamentos con trayecto especial: aéreo o fluvial
+var remoteDepartments = map[string]bool{
+	"88": true, // San Andrés
+	"91": true, // Amazonas
+	"94": true, // Guainía
+	"95": true, // Guaviare
+	"97": true, // Vaupés
+	"99": true, // Vichada
+}
+
+// Simulator transportadora local para tenants sin credenciales: cotiza con
+// tarifas simuladas por tipo de trayecto, genera guías y rótulos, y avanza el
+// rastreo de cada guía un estado por StepInterval
+type Simulator struct {
+	StepInterval time.Duration
+
+	mu       sync.Mutex
+	guides   map[string]*simulatedGuide
+	sequence int64
+	now      func() time.Time
+}
+
+type simulatedGuide struct {
+	profile   carrierProfile
+	request   GuideRequest
+	guide     Guide
+	cancelled *time.Time
+}
+
+// NewSimulator crea el simulador compartido por todas las transportadoras
+func NewSimulator() *Simulator {
+	return &Simulator{
+		StepInterval: 2 * time.Minute,
+		guides:       make(map[string]*simulatedGuide),
+		now:          time.Now,
+	}
+}
+
+// Carrier adaptador simulado de la transportadora indicada
+func (s *Simulator) Carrier(code string) (Carrier, error) {
+	profile, ok := simulatedProfiles[code]
+	if !ok {
+		return nil, fmt.Errorf("transportadora no soportada: %s", code)
+	}
+	return &simulatedCarrier{simulator: s, profile: profile}, nil
+}
+
+type simulatedCarrier struct {
+	simulator *Simulator
+	profile   carrierProfile
+}
+
+func (c *simulatedCarrier) Code() string {
+	return c.profile.code
+}
+
+func (c *simulatedCarrier) Quote(ctx context.Context, req QuoteRequest) (*Quote, error) {
+	return c.simulator.quote(c.profile, req)
+}
+
+func (c *simulatedCarrier) CreateGuide(ctx context.Context, req GuideRequest) (*Guide, error) {
+	return c.simulator.createGuide(c.profile, req)
+}
+
+func (c *simulatedCarrier) Label(ctx context.Context, trackingNumber string) ([]byte, error) {
+	return c.simulator.label(c.profile.code, trackingNumber)
+}
+
+func (c *simulatedCarrier) Track(ctx context.Context, trackingNumber string) (*Tracking, error) {
+	return c.simulator.track(c.profile.code, trackingNumber)
+}
+
+func (c *simulatedCarrier) Cancel(ctx context.Context, trackingNumber string) error {
+	return c.simulator.cancel(c.profile.code, trackingNumber)
+}
+
+// routeTariff tarifa del primer kilo, valor del kilo adicional y días hábiles
+// según el tipo de trayecto: urbano, regional, nacional o especial
+func routeTariff(origin, destination colombia.Municipality) (base, perKilo int64, days int, remote bool) {
+	switch {
+	case remoteDepartments[origin.DepartmentCode] || remoteDepartments[destination.DepartmentCode]:
+		base, perKilo, days, remote = 32000, 6000, 6, true
+	case origin.Code == destination.Code:
+		base, perKilo, days = 8000, 1500, 1
+	case origin.DepartmentCode == destination.DepartmentCode:
+		base, perKilo, days = 10000, 2000, 2
+	case origin.Capital && destination.Capital:
+		base, perKilo, days = 14000, 2800, 2
+	default:
+		base, perKilo, days = 16000, 3000, 3
+	}
+	if cost, ok := routeBaseCosts[origin.Code][destination.Code]; ok {
+		base = cost
+	}
+	return base, perKilo, days, remote
+}
+
+func (s *Simulator) quote(profile carrierProfile, req QuoteRequest) (*Quote, error) {
+	if req.Origin.Code == "" || req.Destination.Code == "" {
+		return nil, fmt.Errorf("origen y destino DIVIPOLA son requeridos")
+	}
+	if req.Package.WeightGrams <= 0 {
+		return nil, fmt.Errorf("el peso debe ser mayor a cero")
+	}
+
+	base, perKilo, days, remote := routeTariff(req.Origin, req.Destination)
+	if remote && !profile.remoteRoutes {
+		return nil, fmt.Errorf("%w: %s → %s", ErrNoCoverage, req.Origin.Name, req.Destination.Name)
+	}
+
+	kilos := chargeableKilos(req.Package.ChargeableGrams(DefaultVolumetricDivisor))
+	freight := roundPeso(float64(base+int64(kilos-1)*perKilo) * profile.priceFactor)
+	insurance := roundPeso(float64(req.Package.DeclaredValue) * profile.insuranceRate)
+	if insurance < profile.minInsurance {
+		insurance = profile.minInsurance
+	}
+	var codFee int64
+	if req.CashOnDelivery > 0 {
+		codFee = roundPeso(float64(req.CashOnDelivery) * profile.codRate)
+		if codFee < profile.minCODFee {
+			codFee = profile.minCODFee
+		}
+	}
+
+	days += profile.extraDays
+	if days < 1 {
+		days = 1
+	}
+	now := s.now().In(colombia.Location())
+	return &Quote{
+		Carrier:           profile.code,
+		CarrierName:       CarrierName(profile.code),
+		Service:           profile.service,
+		Freight:           freight,
+		Insurance:         insurance,
+		CODFee:            codFee,
+		Total:             freight + insurance + codFee,
+		DeliveryDays:      days,
+		EstimatedDelivery: colombia.AddBusinessDays(now, days),
+		Sandbox:           true,
+	}, nil
+}
+
+func (s *Simulator) createGuide(profile carrierProfile, req GuideRequest) (*Guide, error) {
+	quote, err := s.quote(profile, req.QuoteRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sequence++
+	number := fmt.Sprintf("%s%09d", profile.guidePrefix, 100000000+s.sequence)
+	guide := Guide{
+		Carrier:        profile.code,
+		TrackingNumber: number,
+		Service:        quote.Service,
+		TrackingURL:    TrackingURL(profile.code, number),
+		Quote:          *quote,
+		CreatedAt:      s.now().In(colombia.Location()),
+	}
+	s.guides[guideKey(profile.code, number)] = &simulatedGuide{profile: profile, request: req, guide: guide}
+	return &guide, nil
+}
+
+func (s *Simulator) lookup(carrier, trackingNumber string) (*simulatedGuide, error) {
+	guide, ok := s.guides[guideKey(carrier, trackingNumber)]
+	if !ok {
+		return nil, fmt.Errorf("%w: guía %s", ErrShipmentNotFound, trackingNumber)
+	}
+	return guide, nil
+}
+
+func (s *Simulator) label(carrier, trackingNumber string) ([]byte, error) {
+	s.mu.Lock()
+	sim, err := s.lookup(carrier, trackingNumber)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	req, guide := sim.request, sim.guide
+	lines := []labelLine{
+		{Text: CarrierName(carrier) + " - " + guide.Service, Bold: true, Size: 14},
+		{Text: "Guía " + guide.TrackingNumber, Bold: true, Size: 18},
+		{Text: "PRUEBAS - SIN VALIDEZ", Size: 8},
+		{Text: "Remitente: " + req.Sender.Name, Bold: true},
+		{Text: req.Sender.Address + ", " + req.Origin.Name + " (" + req.Origin.Department + ")"},
+		{Text: "Tel. " + req.Sender.Phone},
+		{Text: "Destinatario: " + req.Recipient.Name, Bold: true},
+		{Text: req.Recipient.Address},
+		{Text: req.Destination.Name + " (" + req.Destination.Department + ") DANE " + req.Destination.Code, Bold: true},
+		{Text: "Tel. " + req.Recipient.Phone},
+		{Text: fmt.Sprintf("Peso: %d g   Piezas: %d", req.Package.WeightGrams, maxInt(req.Package.Pieces, 1))},
+		{Text: fmt.Sprintf("Valor declarado: $%d", req.Package.DeclaredValue)},
+	}
+	if req.CashOnDelivery > 0 {
+		lines = append(lines, labelLine{Text: fmt.Sprintf("RECAUDO CONTRAENTREGA: $%d", req.CashOnDelivery), Bold: true, Size: 12})
+	}
+	if req.Reference != "" {
+		lines = append(lines, labelLine{Text: "Pedido: " + req.Reference})
+	}
+	lines = append(lines, labelLine{Text: "Generada: " + guide.CreatedAt.Format("2006-01-02 15:04"), Size: 8})
+	return renderLabelPDF(lines), nil
+}
+
+// simulatedSteps estados que recorre una guía simulada, uno por StepInterval
+var simulatedSteps = []struct {
+	status      string
+	description string
+	atOrigin    bool
+}{
+	{StatusCreated, "Guía generada, pendiente de recolección", true},
+	{StatusPickedUp, "Envío recogido por la transportadora", true},
+	{StatusInTransit, "En tránsito hacia la ciudad de destino", true},
+	{StatusOutForDelivery, "En reparto al destinatario", false},
+	{StatusDelivered, "Entregado al destinatario", false},
+}
+
+func (s *Simulator) track(carrier, trackingNumber string) (*Tracking, error) {
+	s.mu.Lock()
+	sim, err := s.lookup(carrier, trackingNumber)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	tracking := &Tracking{Carrier: carrier, TrackingNumber: trackingNumber}
+	created := sim.guide.CreatedAt
+	now := s.now().In(colombia.Location())
+	for i, step := range simulatedSteps {
+		at := created.Add(time.Duration(i) * s.StepInterval)
+		if i > 0 && (at.After(now) || (sim.cancelled != nil && at.After(*sim.cancelled))) {
+			break
+		}
+		location := sim.request.Destination.Name
+		if step.atOrigin {
+			location = sim.request.Origin.Name
+		}
+		tracking.Events = append(tracking.Events, TrackingEvent{
+			Status:      step.status,
+			Description: step.description,
+			Location:    location,
+			At:          at,
+		})
+	}
+	if sim.cancelled != nil {
+		tracking.Events = append(tracking.Events, TrackingEvent{
+			Status:      StatusCancelled,
+			Description: "Guía anulada por el remitente",
+			Location:    sim.request.Origin.Name,
+			At:          *sim.cancelled,
+		})
+	}
+	tracking.Status = tracking.Events[len(tracking.Events)-1].Status
+	return tracking, nil
+}
+
+func (s *Simulator) cancel(carrier, trackingNumber string) error {
+	tracking, err := s.track(carrier, trackingNumber)
+	if err != nil {
+		return err
+	}
+	if tracking.Status == StatusCancelled {
+		return nil
+	}
+	if tracking.Status != StatusCreated {
+		return fmt.Errorf("%w: la guía %s está %s", ErrNotCancellable, trackingNumber, tracking.Status)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().In(colombia.Location())
+	s.guides[guideKey(carrier, trackingNumber)].cancelled = &now
+	return nil
+}
+
+func guideKey(carrier, trackingNumber string) string {
+	return carrier + ":" + strings.TrimSpace(trackingNumber)
+}
+
+// roundPeso redondea a la centena, como facturan las transportadoras
+func roundPeso(value float64) int64 {
+	return int64(math.Round(value/100) * 100)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
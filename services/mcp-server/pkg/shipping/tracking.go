@@ -0,0 +1,359 @@
+package shipping
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server/pkg/colombia"
+	"mcp-server/pkg/notify"
+)
+
+// ===== RASTREO Y AVISOS AL DESTINATARIO =====
+
+// milestones estados que se avisan al destinatario
+var milestones = map[string]bool{
+	StatusPickedUp:       true,
+	StatusOutForDelivery: true,
+	StatusDelivered:      true,
+	StatusException:      true,
+	StatusReturned:       true,
+}
+
+// ApplyTracking incorpora eventos de rastreo al envío, vengan del webhook o
+// de la consulta a la transportadora. Los eventos repetidos se descartan, el
+// historial se ordena por fecha y el estado pasa al del evento más reciente;
+// un evento final (entregado, devuelto, anulado) prevalece aunque otra fuente
+// reporte después un evento con fecha posterior, y un envío en estado final
+// ya no cambia. Si el estado cambia se avisa
+// al destinatario (una vez por hito) y se notifica a los listeners.
+func (s *Service) ApplyTracking(ctx context.Context, shipmentID string, events []TrackingEvent, source string) (*Shipment, error) {
+	now := s.now().In(colombia.Location())
+
+	s.mu.Lock()
+	stored, ok := s.shipments[shipmentID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrShipmentNotFound, shipmentID)
+	}
+
+	seen := make(map[string]bool)
+	for _, event := range stored.Events {
+		seen[eventKey(event)] = true
+	}
+	added := 0
+	for _, event := range events {
+		if event.Status == "" {
+			continue
+		}
+		if event.At.IsZero() {
+			event.At = now
+		}
+		event.At = event.At.In(colombia.Location())
+		if event.Source == "" {
+			event.Source = source
+		}
+		if key := eventKey(event); !seen[key] {
+			seen[key] = true
+			stored.Events = append(stored.Events, event)
+			added++
+		}
+	}
+	sort.SliceStable(stored.Events, func(i, j int) bool {
+		return stored.Events[i].At.Before(stored.Events[j].At)
+	})
+	if source == SourcePolling {
+		stored.LastCheckedAt = &now
+	}
+
+	previous := stored.Status
+	var latest TrackingEvent
+	if added > 0 {
+		stored.UpdatedAt = now
+		latest = currentEvent(stored.Events)
+		if !Final(previous) {
+			stored.Status = latest.Status
+		}
+	}
+	changed := stored.Status != previous
+	announce := changed && milestones[stored.Status] && !notified(stored, stored.Status)
+	if announce {
+		stored.Notified = append(stored.Notified, stored.Status)
+	}
+	shipment := stored.clone()
+	s.mu.Unlock()
+
+	if changed {
+		if announce {
+			s.notifyRecipient(ctx, shipment, latest)
+		}
+		for _, listener := range s.listeners {
+			listener(ctx, shipment, latest)
+		}
+	}
+	return shipment, nil
+}
+
+// currentEvent evento que define el estado: el último final o, si no hay, el
+// más reciente. Los eventos deben estar ordenados por fecha.
+func currentEvent(events []TrackingEvent) TrackingEvent {
+	for i := len(events) - 1; i >= 0; i-- {
+		if Final(events[i].Status) {
+			return events[i]
+		}
+	}
+	return events[len(events)-1]
+}
+
+// eventKey identifica un evento para descartar repetidos entre webhook y consulta
+func eventKey(event TrackingEvent) string {
+	return event.Status + "|" + event.At.UTC().Format(time.RFC3339) + "|" + strings.ToLower(strings.TrimSpace(event.Description))
+}
+
+func notified(shipment *Shipment, status string) bool {
+	for _, milestone := range shipment.Notified {
+		if milestone == status {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyRecipient avisa el hito al destinatario por WhatsApp y por correo,
+// según los datos de contacto del envío. Los fallos quedan en la bandeja de
+// salida y no detienen el rastreo.
+func (s *Service) notifyRecipient(ctx context.Context, shipment *Shipment, event TrackingEvent) {
+	if s.notifier == nil {
+		return
+	}
+	cfg := s.configFor(shipment.TenantID).Notify
+	cfg.TenantID = shipment.TenantID
+	if cfg.FromName == "" {
+		cfg.FromName = shipment.Sender.Name
+	}
+
+	body := RecipientMessage(shipment, event)
+	var messages []notify.Message
+	if shipment.Recipient.Phone != "" {
+		messages = append(messages, notify.Message{
+			Channel:   notify.ChannelWhatsApp,
+			To:        shipment.Recipient.Phone,
+			Body:      body,
+			Reference: shipment.ID,
+		})
+	}
+	if shipment.Recipient.Email != "" {
+		messages = append(messages, notify.Message{
+			Channel:   notify.ChannelEmail,
+			To:        shipment.Recipient.Email,
+			Subject:   recipientSubject(shipment),
+			Body:      body,
+			Reference: shipment.ID,
+		})
+	}
+	for _, message := range messages {
+		if _, err := s.notifier.Send(ctx, cfg, message); err != nil {
+			log.Printf("⚠️ Envío %s: no se pudo avisar por %s el estado %s: %v", shipment.ID, message.Channel, shipment.Status, err)
+		}
+	}
+}
+
+// RecipientMessage texto del aviso al destinatario para el estado del envío
+func RecipientMessage(shipment *Shipment, event TrackingEvent) string {
+	greeting := "Hola"
+	if fields := strings.Fields(shipment.Recipient.Name); len(fields) > 0 {
+		greeting += " " + fields[0]
+	}
+	order := "tu pedido"
+	if shipment.Reference != "" {
+		order += " " + shipment.Reference
+	}
+	if shipment.Sender.Name != "" {
+		order += " de " + shipment.Sender.Name
+	}
+
+	var text string
+	switch shipment.Status {
+	case StatusPickedUp:
+		text = fmt.Sprintf("%s, %s ya va en camino con %s (guía %s).", greeting, order, shipment.CarrierName, shipment.TrackingNumber)
+	case StatusOutForDelivery:
+		text = fmt.Sprintf("%s, %s está en reparto y llega hoy a %s.", greeting, order, shipment.Recipient.Address)
+		if shipment.CashOnDelivery > 0 {
+			text += fmt.Sprintf(" Ten listos $%s para pagar al recibir.", formatPesos(shipment.CashOnDelivery))
+		}
+	case StatusDelivered:
+		text = fmt.Sprintf("%s, %s fue entregado. ¡Gracias por tu compra!", greeting, order)
+	case StatusException:
+		detail := event.Description
+		if detail == "" {
+			detail = "la transportadora reportó un inconveniente con la entrega"
+		}
+		text = fmt.Sprintf("%s, %s tiene una novedad con %s: %s. Escríbenos para resolverla.", greeting, order, shipment.CarrierName, detail)
+	case StatusReturned:
+		text = fmt.Sprintf("%s, %s fue devuelto al remitente. Escríbenos para coordinar un nuevo envío.", greeting, order)
+	default:
+		text = fmt.Sprintf("%s, %s: %s.", greeting, order, strings.ToLower(StatusLabel(shipment.Status)))
+	}
+	if shipment.TrackingURL != "" && shipment.Status != StatusDelivered {
+		text += "\nSigue tu envío: " + shipment.TrackingURL
+	}
+	return text
+}
+
+func recipientSubject(shipment *Shipment) string {
+	subject := "Tu pedido"
+	if shipment.Reference != "" {
+		subject += " " + shipment.Reference
+	}
+	return subject + ": " + strings.ToLower(StatusLabel(shipment.Status))
+}
+
+// formatPesos valor en pesos con separador de miles
+func formatPesos(value int64) string {
+	digits := fmt.Sprintf("%d", value)
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
+
+// ===== RASTREO PERIÓDICO =====
+
+// TrackingReport resultado de una corrida del rastreo periódico
+type TrackingReport struct {
+	Checked int       `json:"checked"`
+	Updated int       `json:"updated"` // Envíos que cambiaron de estado
+	Errors  int       `json:"errors"`  // Consultas fallidas; se reintentan en la siguiente corrida
+	RanAt   time.Time `json:"ran_at"`
+}
+
+// PollShipments consulta con la transportadora el rastreo de los envíos que
+// aún no están en un estado final, para las transportadoras que no notifican
+// por webhook o cuando una notificación se pierde
+func (s *Service) PollShipments(ctx context.Context) TrackingReport {
+	report := TrackingReport{RanAt: s.now()}
+	for _, shipment := range s.activeShipments() {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Checked++
+		updated, err := s.Track(ctx, s.configFor(shipment.TenantID), shipment.ID)
+		if err != nil {
+			log.Printf("⚠️ Rastreo: no se pudo consultar la guía %s de %s: %v", shipment.TrackingNumber, shipment.CarrierName, err)
+			report.Errors++
+			continue
+		}
+		if updated.Status != shipment.Status {
+			report.Updated++
+		}
+	}
+	return report
+}
+
+// StartTracker consulta el rastreo de los envíos activos cada interval hasta
+// que se cancele el contexto
+func (s *Service) StartTracker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report := s.PollShipments(ctx)
+				if report.Checked > 0 {
+					log.Printf("📦 Rastreo de envíos: %d consultados, %d actualizados, %d errores",
+						report.Checked, report.Updated, report.Errors)
+				}
+			}
+		}
+	}()
+}
+
+func (s *Service) activeShipments() []*Shipment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*Shipment
+	for _, shipment := range s.shipments {
+		if !Final(shipment.Status) {
+			result = append(result, shipment.clone())
+		}
+	}
+	return result
+}
+
+// ===== CONSULTA DEL RASTREO =====
+
+// FindByTracking busca el envío del tenant por número de guía
+func (s *Service) FindByTracking(tenantID, trackingNumber string) (*Shipment, error) {
+	trackingNumber = strings.TrimSpace(trackingNumber)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, shipment := range s.shipments {
+		if shipment.TenantID == tenantID && strings.EqualFold(shipment.TrackingNumber, trackingNumber) {
+			return shipment.clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: guía %s", ErrShipmentNotFound, trackingNumber)
+}
+
+// PublicTracking rastreo de un envío para el cliente final, sin datos de
+// contacto ni direcciones
+type PublicTracking struct {
+	ID             string          `json:"id"`
+	Merchant       string          `json:"merchant"`
+	Reference      string          `json:"reference,omitempty"`
+	Carrier        string          `json:"carrier"`
+	CarrierName    string          `json:"carrier_name"`
+	TrackingNumber string          `json:"tracking_number"`
+	CarrierURL     string          `json:"carrier_tracking_url"`
+	Status         string          `json:"status"`
+	StatusLabel    string          `json:"status_label"`
+	Destination    string          `json:"destination"`
+	CashOnDelivery int64           `json:"cash_on_delivery_cop,omitempty"`
+	Estimated      time.Time       `json:"estimated_delivery"`
+	Events         []TrackingEvent `json:"events"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// GetPublicTracking rastreo público de un envío por su identificador
+func (s *Service) GetPublicTracking(shipmentID string) (*PublicTracking, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shipment, ok := s.shipments[shipmentID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrShipmentNotFound, shipmentID)
+	}
+	tracking := &PublicTracking{
+		ID:             shipment.ID,
+		Merchant:       shipment.Sender.Name,
+		Reference:      shipment.Reference,
+		Carrier:        shipment.Carrier,
+		CarrierName:    shipment.CarrierName,
+		TrackingNumber: shipment.TrackingNumber,
+		CarrierURL:     shipment.CarrierURL,
+		Status:         shipment.Status,
+		StatusLabel:    StatusLabel(shipment.Status),
+		Destination:    shipment.Destination.Name + ", " + shipment.Destination.Department,
+		CashOnDelivery: shipment.CashOnDelivery,
+		Estimated:      shipment.Quote.EstimatedDelivery,
+		UpdatedAt:      shipment.UpdatedAt,
+	}
+	for _, event := range shipment.Events {
+		event.Source = ""
+		event.CollectedAmount = 0
+		tracking.Events = append(tracking.Events, event)
+	}
+	return tracking, nil
+}
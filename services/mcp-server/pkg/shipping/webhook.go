@@ -0,0 +1,191 @@
+package shipping
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcp-server/pkg/colombia"
+)
+
+// WebhookSignatureHeader encabezado con la firma HMAC-SHA256 (hex) del cuerpo
+const WebhookSignatureHeader = "X-Signature"
+
+// WebhookUpdate evento de una guía recibido por webhook
+type WebhookUpdate struct {
+	TrackingNumber string
+	Event          TrackingEvent
+}
+
+// WebhookResult resultado de procesar una notificación de transportadora
+type WebhookResult struct {
+	Carrier   string   `json:"carrier"`
+	Received  int      `json:"received"`
+	Applied   int      `json:"applied"` // Eventos de guías registradas; los repetidos se descartan al aplicarlos
+	Ignored   int      `json:"ignored"` // Guías que no son de ningún envío registrado
+	Shipments []string `json:"shipments,omitempty"`
+}
+
+// ParseWebhook interpreta una notificación de rastreo: un evento o una lista
+// de eventos en el formato de la plataforma (numero_guia, estado,
+// descripcion, ciudad, fecha y valor_recaudado). Las transportadoras no
+// tienen adaptador propio, así que todas usan este formato.
+func ParseWebhook(carrier string, body []byte) ([]WebhookUpdate, error) {
+	var notifications []struct {
+		NumeroGuia     string `json:"numero_guia"`
+		Estado         string `json:"estado"`
+		Descripcion    string `json:"descripcion"`
+		Ciudad         string `json:"ciudad"`
+		Fecha          string `json:"fecha"`
+		ValorRecaudado int64  `json:"valor_recaudado"`
+	}
+	if err := decodeWebhook(body, &notifications); err != nil {
+		return nil, fmt.Errorf("notificación de %s inválida: %w", CarrierName(carrier), err)
+	}
+	var updates []WebhookUpdate
+	for _, n := range notifications {
+		updates = append(updates, WebhookUpdate{
+			TrackingNumber: n.NumeroGuia,
+			Event: TrackingEvent{
+				Status:          normalizeStatus(n.Estado),
+				Description:     n.Descripcion,
+				Location:        n.Ciudad,
+				At:              parseCarrierTime(n.Fecha),
+				CollectedAmount: n.ValorRecaudado,
+			},
+		})
+	}
+	return updates, nil
+}
+
+// decodeWebhook decodifica un objeto o una lista de objetos en v, que debe
+// ser un puntero a slice
+func decodeWebhook(body []byte, v interface{}) error {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] != '[' {
+		body = append(append([]byte("["), body...), ']')
+	}
+	return json.Unmarshal(body, v)
+}
+
+// VerifyWebhookSignature valida la firma HMAC-SHA256 del cuerpo con la clave
+// compartida con la transportadora
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	signature = strings.TrimPrefix(strings.TrimSpace(signature), "sha256=")
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// HandleWebhook aplica la notificación de la transportadora a los envíos
+// registrados. La firma se valida con la clave del tenant de cada guía antes
+// de aplicar cualquier evento; los tenants sin clave solo reciben eventos de
+// guías de sandbox.
+func (s *Service) HandleWebhook(ctx context.Context, carrier string, body []byte, signature string) (*WebhookResult, error) {
+	code, ok := NormalizeCarrier(carrier)
+	if !ok {
+		return nil, fmt.Errorf("transportadora no soportada: %s", carrier)
+	}
+	updates, err := ParseWebhook(code, body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WebhookResult{Carrier: code, Received: len(updates)}
+	var order []string
+	events := make(map[string][]TrackingEvent)
+	for _, update := range updates {
+		s.mu.RLock()
+		shipmentID, found := s.byTracking[guideKey(code, update.TrackingNumber)]
+		var shipment *Shipment
+		if found {
+			shipment = s.shipments[shipmentID]
+		}
+		s.mu.RUnlock()
+		if !found {
+			result.Ignored++
+			continue
+		}
+
+		cfg := s.configFor(shipment.TenantID)
+		if cfg.WebhookSecret == "" && !shipment.Sandbox {
+			return nil, fmt.Errorf("%w: el comercio no tiene clave de webhook configurada", ErrInvalidSignature)
+		}
+		if cfg.WebhookSecret != "" && !VerifyWebhookSignature(cfg.WebhookSecret, body, signature) {
+			return nil, ErrInvalidSignature
+		}
+		if _, seen := events[shipmentID]; !seen {
+			order = append(order, shipmentID)
+		}
+		events[shipmentID] = append(events[shipmentID], update.Event)
+	}
+
+	for _, shipmentID := range order {
+		shipment, err := s.ApplyTracking(ctx, shipmentID, events[shipmentID], SourceWebhook)
+		if err != nil {
+			return nil, err
+		}
+		result.Applied += len(events[shipmentID])
+		result.Shipments = append(result.Shipments, shipment.ID)
+	}
+	return result, nil
+}
+
+// statusAliases estados que reportan las transportadoras y su equivalente normalizado
+var statusAliases = map[string]string{
+	"creado":                  StatusCreated,
+	"generada":                StatusCreated,
+	"guia generada":           StatusCreated,
+	"admitida":                StatusCreated,
+	"recogido":                StatusPickedUp,
+	"recibido":                StatusPickedUp,
+	"recibido en origen":      StatusPickedUp,
+	"en transito":             StatusInTransit,
+	"en terminal destino":     StatusInTransit,
+	"en centro de acopio":     StatusInTransit,
+	"en reparto":              StatusOutForDelivery,
+	"en distribucion":         StatusOutForDelivery,
+	"entregado":               StatusDelivered,
+	"entregada":               StatusDelivered,
+	"entrega exitosa":         StatusDelivered,
+	"devuelto":                StatusReturned,
+	"devuelta":                StatusReturned,
+	"devuelto al remitente":   StatusReturned,
+	"novedad":                 StatusException,
+	"direccion errada":        StatusException,
+	"destinatario no ubicado": StatusException,
+	"anulado":                 StatusCancelled,
+	"anulada":                 StatusCancelled,
+}
+
+// normalizeStatus convierte el estado de la transportadora al estado del envío;
+// los estados desconocidos se tratan como tránsito
+func normalizeStatus(raw string) string {
+	if status, ok := statusAliases[colombia.NormalizeText(raw)]; ok {
+		return status
+	}
+	return StatusInTransit
+}
+
+// parseCarrierTime interpreta las fechas de las transportadoras como hora de Colombia
+func parseCarrierTime(value string) time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, colombia.Location()); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,561 @@
+package webcrawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ===== CRAWLER DEL SITIO WEB DE LA EMPRESA =====
+
+// Tipos de página visitada
+const (
+	PageHome    = "inicio"
+	PageContact = "contacto"
+	PageAbout   = "nosotros"
+	PageShop    = "tienda"
+	PageBlog    = "blog"
+)
+
+// UserAgent identificación del crawler; robots.txt puede dirigirle reglas
+// con el token "TausePro"
+const UserAgent = "Mozilla/5.0 (compatible; TausePro-Analyzer/1.0; +https://tause.pro)"
+
+// Errores del crawler
+var (
+	ErrBlockedByRobots = errors.New("robots.txt no permite analizar el sitio")
+	ErrNotHTML         = errors.New("la página no es HTML")
+	ErrPrivateAddress  = errors.New("la dirección del sitio no es pública")
+)
+
+const (
+	defaultMaxPages     = 5
+	defaultMaxDepth     = 1
+	defaultPageTimeout  = 10 * time.Second
+	defaultCrawlTimeout = 20 * time.Second
+	defaultDelay        = 500 * time.Millisecond
+	maxCrawlDelay       = 5 * time.Second
+	maxPageBytes        = 3 << 20
+	maxRedirects        = 10
+)
+
+// Page página visitada
+type Page struct {
+	URL              string   `json:"url"`
+	Kind             string   `json:"kind"`
+	Depth            int      `json:"depth"`
+	StatusCode       int      `json:"status_code"`
+	ResponseTimeMS   int64    `json:"response_time_ms"`
+	Bytes            int64    `json:"bytes"` // Peso del HTML (sin imágenes, scripts ni estilos)
+	Title            string   `json:"title"`
+	Description      string   `json:"description"`
+	Keywords         []string `json:"keywords,omitempty"`
+	H1               []string `json:"h1,omitempty"`
+	H2               []string `json:"h2,omitempty"`
+	Canonical        string   `json:"canonical,omitempty"`
+	Viewport         string   `json:"viewport,omitempty"`
+	Lang             string   `json:"lang,omitempty"`
+	Images           int      `json:"images"`
+	ImagesWithoutAlt int      `json:"images_without_alt"`
+	Scripts          int      `json:"scripts"`
+	Stylesheets      int      `json:"stylesheets"`
+}
+
+// Site resultado del análisis del sitio: la página de inicio y las páginas
+// clave (contacto, nosotros, tienda, blog) que se alcanzaron
+type Site struct {
+	URL              string            `json:"url"`       // URL solicitada
+	FinalURL         string            `json:"final_url"` // Después de las redirecciones
+	HTTPS            bool              `json:"https"`
+	Redirects        []string          `json:"redirects,omitempty"`
+	StatusCode       int               `json:"status_code"`
+	ResponseTimeMS   int64             `json:"response_time_ms"` // De la página de inicio
+	PageWeight       int64             `json:"page_weight"`      // Bytes del HTML de la página de inicio
+	Pages            []*Page           `json:"pages"`
+	Technologies     []string          `json:"technologies"`
+	Phones           []string          `json:"phones,omitempty"` // E.164
+	Emails           []string          `json:"emails,omitempty"`
+	WhatsApp         []string          `json:"whatsapp,omitempty"` // E.164
+	Social           map[string]string `json:"social,omitempty"`   // Red → URL del perfil
+	ContactPage      bool              `json:"contact_page"`
+	Ecommerce        bool              `json:"ecommerce"`
+	EcommerceSignals []string          `json:"ecommerce_signals,omitempty"`
+	Blog             bool              `json:"blog"`
+	BlogSignals      []string          `json:"blog_signals,omitempty"`
+	SkippedByRobots  []string          `json:"skipped_by_robots,omitempty"`
+	CrawledAt        time.Time         `json:"crawled_at"`
+}
+
+// Home página de inicio
+func (s *Site) Home() *Page {
+	if len(s.Pages) == 0 {
+		return &Page{}
+	}
+	return s.Pages[0]
+}
+
+// Crawler recorre el sitio de una empresa de forma respetuosa: consulta
+// robots.txt, visita pocas páginas una tras otra con pausa entre ellas y
+// limita el tiempo y el tamaño de cada descarga
+type Crawler struct {
+	httpClient   *http.Client
+	userAgent    string
+	maxPages     int
+	maxDepth     int
+	pageTimeout  time.Duration
+	crawlTimeout time.Duration
+	delay        time.Duration
+	allowPrivate bool
+	now          func() time.Time
+}
+
+// NewCrawler crea el crawler con los límites por defecto: 5 páginas, un nivel
+// de enlaces desde la página de inicio y 20 segundos por sitio
+func NewCrawler() *Crawler {
+	c := &Crawler{
+		userAgent:    UserAgent,
+		maxPages:     defaultMaxPages,
+		maxDepth:     defaultMaxDepth,
+		pageTimeout:  defaultPageTimeout,
+		crawlTimeout: defaultCrawlTimeout,
+		delay:        defaultDelay,
+		now:          time.Now,
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: c.checkAddress}
+	c.httpClient = &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: defaultPageTimeout,
+			MaxIdleConnsPerHost:   2,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("demasiadas redirecciones")
+			}
+			return nil
+		},
+	}
+	return c
+}
+
+// WithLimits cambia el máximo de páginas y la profundidad de enlaces
+func (c *Crawler) WithLimits(maxPages, maxDepth int) *Crawler {
+	if maxPages > 0 {
+		c.maxPages = maxPages
+	}
+	if maxDepth >= 0 {
+		c.maxDepth = maxDepth
+	}
+	return c
+}
+
+// WithTimeouts cambia el tiempo límite por página y por sitio
+func (c *Crawler) WithTimeouts(page, crawl time.Duration) *Crawler {
+	c.pageTimeout = page
+	c.crawlTimeout = crawl
+	return c
+}
+
+// WithDelay cambia la pausa mínima entre páginas (robots.txt puede pedir más)
+func (c *Crawler) WithDelay(delay time.Duration) *Crawler {
+	c.delay = delay
+	return c
+}
+
+// WithPrivateNetworks permite sitios en redes privadas o locales (solo pruebas)
+func (c *Crawler) WithPrivateNetworks() *Crawler {
+	c.allowPrivate = true
+	return c
+}
+
+// checkAddress impide que el análisis de una URL ingresada por el usuario
+// llegue a servicios internos
+func (c *Crawler) checkAddress(network, address string, _ syscall.RawConn) error {
+	if c.allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// Crawl analiza el sitio. Sin esquema se intenta HTTPS y luego HTTP. Falla
+// solo si la página de inicio no se puede descargar o robots.txt la excluye;
+// las páginas clave que fallan se omiten.
+func (c *Crawler) Crawl(ctx context.Context, rawURL string) (*Site, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.crawlTimeout)
+	defer cancel()
+
+	candidates, err := startURLs(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	site := &Site{URL: rawURL, Social: make(map[string]string), CrawledAt: c.now()}
+	var home *fetchResult
+	var robots *robotsRules
+	for _, candidate := range candidates {
+		// robots.txt antes que cualquier página; si el servidor no responde
+		// tampoco responderá la página de inicio
+		robots, err = c.robots(ctx, candidate)
+		if err != nil {
+			continue
+		}
+		if !robots.allowed(candidate.EscapedPath()) {
+			return nil, ErrBlockedByRobots
+		}
+		home, err = c.fetch(ctx, candidate.String())
+		if err == nil {
+			if home.finalURL.Host != candidate.Host {
+				// Redirigió a otro dominio, que tiene su propio robots.txt. La
+				// página ya respondió: si robots.txt no responde no hay restricciones
+				if robots, err = c.robots(ctx, home.finalURL); err != nil {
+					robots, err = &robotsRules{}, nil
+				}
+				if !robots.allowed(home.finalURL.EscapedPath()) {
+					return nil, ErrBlockedByRobots
+				}
+			}
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	delay := c.delay
+	if robots.delay > delay {
+		delay = robots.delay
+		if delay > maxCrawlDelay {
+			delay = maxCrawlDelay
+		}
+	}
+
+	site.FinalURL = home.finalURL.String()
+	site.HTTPS = home.finalURL.Scheme == "https"
+	site.Redirects = home.redirects
+	site.StatusCode = home.statusCode
+	site.ResponseTimeMS = home.elapsed.Milliseconds()
+	site.PageWeight = home.bytes
+
+	collector := newCollector(site, home.finalURL)
+	collector.add(home, PageHome, 0)
+
+	// Páginas clave: se visitan en orden de prioridad, una a la vez
+	type pending struct {
+		link  *url.URL
+		kind  string
+		depth int
+	}
+	var queue []pending
+	visited := map[string]bool{pageKey(home.finalURL): true}
+	enqueue := func(links []*url.URL, depth int) {
+		if depth > c.maxDepth {
+			return
+		}
+		for _, page := range keyPages {
+			if collector.kinds[page.kind] {
+				continue
+			}
+			for _, link := range links {
+				if !sameSite(link, home.finalURL) || visited[pageKey(link)] || pageKind(link) != page.kind {
+					continue
+				}
+				visited[pageKey(link)] = true
+				collector.kinds[page.kind] = true
+				queue = append(queue, pending{link: link, kind: page.kind, depth: depth})
+				break
+			}
+		}
+	}
+	enqueue(home.doc.links, 1)
+
+	for len(queue) > 0 && len(site.Pages) < c.maxPages {
+		next := queue[0]
+		queue = queue[1:]
+		if !robots.allowed(next.link.EscapedPath()) {
+			site.SkippedByRobots = append(site.SkippedByRobots, next.link.String())
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return collector.finish(), nil
+		case <-time.After(delay):
+		}
+
+		result, err := c.fetch(ctx, next.link.String())
+		if err != nil {
+			continue
+		}
+		collector.add(result, next.kind, next.depth)
+		enqueue(result.doc.links, next.depth+1)
+	}
+	return collector.finish(), nil
+}
+
+// startURLs URLs a intentar para la página de inicio
+func startURLs(rawURL string) ([]*url.URL, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, fmt.Errorf("URL vacía")
+	}
+	if strings.Contains(rawURL, "://") {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return nil, fmt.Errorf("URL inválida: %s", rawURL)
+		}
+		return []*url.URL{parsed}, nil
+	}
+	parsed, err := url.Parse("https://" + rawURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("URL inválida: %s", rawURL)
+	}
+	insecure := *parsed
+	insecure.Scheme = "http"
+	return []*url.URL{parsed, &insecure}, nil
+}
+
+// fetchResult descarga de una página
+type fetchResult struct {
+	finalURL   *url.URL
+	redirects  []string
+	statusCode int
+	header     http.Header
+	elapsed    time.Duration
+	bytes      int64
+	html       string
+	doc        *document
+}
+
+// fetch descarga y lee una página HTML
+func (c *Crawler) fetch(ctx context.Context, pageURL string) (*fetchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.pageTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("Accept-Language", "es-CO,es;q=0.9")
+
+	started := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return nil, err
+	}
+	result := &fetchResult{
+		finalURL:   resp.Request.URL,
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+		elapsed:    time.Since(started),
+		bytes:      int64(len(body)),
+	}
+	for previous := resp.Request.Response; previous != nil; previous = previous.Request.Response {
+		result.redirects = append([]string{previous.Request.URL.String()}, result.redirects...)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s respondió %d", pageURL, resp.StatusCode)
+	}
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if contentType != "" && !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("%w: %s", ErrNotHTML, contentType)
+	}
+
+	result.html = string(body)
+	result.doc = parseDocument(result.finalURL, result.html)
+	return result, nil
+}
+
+// robots descarga robots.txt. Si no existe no hay restricciones; el error es
+// solo para servidores que no responden.
+func (c *Crawler) robots(ctx context.Context, site *url.URL) (*robotsRules, error) {
+	robotsURL := url.URL{Scheme: site.Scheme, Host: site.Host, Path: "/robots.txt"}
+	ctx, cancel := context.WithTimeout(ctx, c.pageTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &robotsRules{}, nil
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 512<<10))
+	if err != nil {
+		return &robotsRules{}, nil
+	}
+	return parseRobots(string(content), c.userAgent), nil
+}
+
+// sameSite compara los hosts sin el prefijo www
+func sameSite(link, home *url.URL) bool {
+	if link.Scheme != "http" && link.Scheme != "https" {
+		return false
+	}
+	return strings.TrimPrefix(strings.ToLower(link.Hostname()), "www.") == strings.TrimPrefix(strings.ToLower(home.Hostname()), "www.")
+}
+
+// pageKey identifica una página sin esquema, www ni barra final
+func pageKey(link *url.URL) string {
+	host := strings.TrimPrefix(strings.ToLower(link.Hostname()), "www.")
+	return host + strings.TrimSuffix(link.EscapedPath(), "/") + "?" + link.RawQuery
+}
+
+// ===== CONSOLIDACIÓN =====
+
+// collector reúne las señales de las páginas visitadas
+type collector struct {
+	site         *Site
+	home         *url.URL
+	kinds        map[string]bool // Tipos de página ya encolados o visitados
+	technologies map[string]bool
+}
+
+func newCollector(site *Site, home *url.URL) *collector {
+	return &collector{
+		site:         site,
+		home:         home,
+		kinds:        map[string]bool{PageHome: true},
+		technologies: make(map[string]bool),
+	}
+}
+
+// add incorpora una página descargada
+func (c *collector) add(result *fetchResult, kind string, depth int) {
+	doc := result.doc
+	page := &Page{
+		URL:              result.finalURL.String(),
+		Kind:             kind,
+		Depth:            depth,
+		StatusCode:       result.statusCode,
+		ResponseTimeMS:   result.elapsed.Milliseconds(),
+		Bytes:            result.bytes,
+		Title:            doc.title,
+		Description:      doc.description,
+		H1:               doc.h1,
+		H2:               doc.h2,
+		Canonical:        doc.canonical,
+		Viewport:         doc.viewport,
+		Lang:             doc.lang,
+		Images:           doc.images,
+		ImagesWithoutAlt: doc.imagesNoAlt,
+		Scripts:          len(doc.scripts) + len(doc.inline),
+		Stylesheets:      doc.stylesheets,
+	}
+	for _, keyword := range strings.Split(doc.keywords, ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			page.Keywords = append(page.Keywords, keyword)
+		}
+	}
+	c.site.Pages = append(c.site.Pages, page)
+	if kind == PageContact {
+		c.site.ContactPage = true
+	}
+
+	lowerHTML := strings.ToLower(result.html)
+	for _, technology := range detectTechnologies(doc, lowerHTML, result.header) {
+		c.technologies[technology] = true
+		if ecommercePlatforms[technology] {
+			c.site.EcommerceSignals = addUnique(c.site.EcommerceSignals, "plataforma "+technology)
+		}
+	}
+
+	for _, link := range doc.links {
+		switch strings.ToLower(link.Scheme) {
+		case "tel":
+			if phone, ok := normalizePhone(link.Opaque + link.Path); ok {
+				c.site.Phones = addUnique(c.site.Phones, phone)
+			}
+			continue
+		case "mailto":
+			if email, ok := normalizeEmail(link.Opaque + link.Path); ok {
+				c.site.Emails = addUnique(c.site.Emails, email)
+			}
+			continue
+		}
+
+		if number, ok := whatsappNumber(link); ok {
+			if phone, valid := normalizePhone(number); valid {
+				c.site.WhatsApp = addUnique(c.site.WhatsApp, phone)
+			}
+			continue
+		}
+		if network := socialNetwork(link); network != "" {
+			if _, exists := c.site.Social[network]; !exists {
+				c.site.Social[network] = link.String()
+			}
+			continue
+		}
+		if !sameSite(link, c.home) {
+			continue
+		}
+		path := strings.ToLower(link.Path)
+		for _, prefix := range ecommercePaths {
+			if strings.HasPrefix(path, prefix) {
+				c.site.EcommerceSignals = addUnique(c.site.EcommerceSignals, "enlace "+prefix)
+			}
+		}
+		for _, prefix := range blogPaths {
+			if strings.HasPrefix(path, prefix) {
+				c.site.BlogSignals = addUnique(c.site.BlogSignals, "enlace "+prefix)
+			}
+		}
+	}
+
+	lowerText := strings.ToLower(doc.text)
+	for _, text := range ecommerceTexts {
+		if strings.Contains(lowerText, text) {
+			c.site.EcommerceSignals = addUnique(c.site.EcommerceSignals, "texto \""+text+"\"")
+		}
+	}
+	if doc.feed {
+		c.site.BlogSignals = addUnique(c.site.BlogSignals, "feed RSS")
+	}
+	if doc.articles >= 3 {
+		c.site.BlogSignals = addUnique(c.site.BlogSignals, "artículos publicados")
+	}
+	if kind == PageBlog {
+		c.site.BlogSignals = addUnique(c.site.BlogSignals, "página de blog")
+	}
+
+	c.site.Phones = addUnique(c.site.Phones, textPhones(doc.text)...)
+	c.site.Emails = addUnique(c.site.Emails, textEmails(doc.text)...)
+}
+
+// finish consolida las señales del sitio
+func (c *collector) finish() *Site {
+	c.site.Technologies = sortedKeys(c.technologies)
+	c.site.Ecommerce = len(c.site.EcommerceSignals) > 0
+	c.site.Blog = len(c.site.BlogSignals) > 0
+	return c.site
+}
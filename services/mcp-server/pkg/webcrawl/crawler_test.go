@@ -0,0 +1,73 @@
+package webcrawl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCrawlRedirectToHostWithUnresponsiveRobots(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>Inicio | Café Ejemplo</title></head><body><h1>Café</h1></body></html>`)
+	}))
+	defer target.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nAllow: /\n")
+			return
+		}
+		http.Redirect(w, r, target.URL+"/", http.StatusMovedPermanently)
+	}))
+	defer origin.Close()
+
+	crawler := NewCrawler().WithPrivateNetworks().WithDelay(0).WithTimeouts(300*time.Millisecond, 5*time.Second)
+	site, err := crawler.Crawl(context.Background(), origin.URL)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	if site.FinalURL != target.URL+"/" {
+		t.Errorf("FinalURL = %q, want %q", site.FinalURL, target.URL+"/")
+	}
+	if len(site.Redirects) != 1 {
+		t.Errorf("Redirects = %v, want one redirect", site.Redirects)
+	}
+	if got := site.Home().Title; got != "Inicio | Café Ejemplo" {
+		t.Errorf("Home().Title = %q", got)
+	}
+}
+
+func TestCrawlRedirectToHostThatDisallows(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /\n")
+			return
+		}
+		fmt.Fprint(w, `<html><title>Privado</title></html>`)
+	}))
+	defer target.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, target.URL+"/", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	crawler := NewCrawler().WithPrivateNetworks().WithDelay(0)
+	if _, err := crawler.Crawl(context.Background(), origin.URL); err != ErrBlockedByRobots {
+		t.Fatalf("Crawl() error = %v, want ErrBlockedByRobots", err)
+	}
+}
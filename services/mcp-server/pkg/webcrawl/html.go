@@ -0,0 +1,318 @@
+package webcrawl
+
+import (
+	"html"
+	"net/url"
+	"strings"
+)
+
+// ===== LECTURA DE HTML =====
+
+// Tipos de token
+const (
+	tokenText = iota
+	tokenStart
+	tokenEnd
+	tokenRaw // Contenido de <script> o <style>
+)
+
+// token etiqueta o texto del documento. El lector es tolerante: no arma el
+// árbol, solo recorre las etiquetas en orden, que es lo que necesita el
+// análisis (títulos, metas, enlaces y texto visible).
+type token struct {
+	kind  int
+	name  string // Nombre de la etiqueta en minúsculas
+	attrs map[string]string
+	text  string
+}
+
+// rawTextElements elementos cuyo contenido no es HTML
+var rawTextElements = map[string]bool{"script": true, "style": true}
+
+// tokenize recorre el documento y retorna sus tokens
+func tokenize(doc string) []token {
+	var tokens []token
+	lower := asciiLower(doc)
+	i := 0
+	for i < len(doc) {
+		lt := strings.IndexByte(doc[i:], '<')
+		if lt < 0 {
+			tokens = appendText(tokens, doc[i:])
+			break
+		}
+		if lt > 0 {
+			tokens = appendText(tokens, doc[i:i+lt])
+		}
+		i += lt
+
+		rest := doc[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return tokens
+			}
+			i += 4 + end + 3
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+		case strings.HasPrefix(rest, "</"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return tokens
+			}
+			if fields := strings.Fields(rest[2:end]); len(fields) > 0 {
+				tokens = append(tokens, token{kind: tokenEnd, name: strings.ToLower(fields[0])})
+			}
+			i += end + 1
+		case len(rest) > 1 && isLetter(rest[1]):
+			tok, size := parseStartTag(rest)
+			tokens = append(tokens, tok)
+			i += size
+			if rawTextElements[tok.name] {
+				closing := strings.Index(lower[i:], "</"+tok.name)
+				if closing < 0 {
+					tokens = append(tokens, token{kind: tokenRaw, name: tok.name, text: doc[i:]})
+					return tokens
+				}
+				tokens = append(tokens, token{kind: tokenRaw, name: tok.name, text: doc[i : i+closing]})
+				i += closing
+			}
+		default:
+			tokens = appendText(tokens, "<")
+			i++
+		}
+	}
+	return tokens
+}
+
+func appendText(tokens []token, text string) []token {
+	if strings.TrimSpace(text) == "" {
+		return tokens
+	}
+	return append(tokens, token{kind: tokenText, text: html.UnescapeString(text)})
+}
+
+// parseStartTag lee una etiqueta de apertura y retorna el token y su largo
+func parseStartTag(s string) (token, int) {
+	tok := token{kind: tokenStart, attrs: make(map[string]string)}
+	i := 1
+	start := i
+	for i < len(s) && !isSpace(s[i]) && s[i] != '>' && s[i] != '/' {
+		i++
+	}
+	tok.name = strings.ToLower(s[start:i])
+
+	for i < len(s) {
+		for i < len(s) && (isSpace(s[i]) || s[i] == '/') {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			return tok, i + 1
+		}
+
+		start = i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				end := strings.IndexByte(s[i+1:], quote)
+				if end < 0 {
+					return tok, len(s)
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start = i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[start:i]
+			}
+		}
+		if name != "" {
+			if _, exists := tok.attrs[name]; !exists {
+				tok.attrs[name] = html.UnescapeString(value)
+			}
+		}
+	}
+	return tok, len(s)
+}
+
+// asciiLower pasa a minúsculas solo las letras ASCII para conservar las
+// posiciones de los bytes del documento
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// ===== EXTRACCIÓN DE LA PÁGINA =====
+
+// document datos de una página HTML antes de resolver las señales del sitio
+type document struct {
+	title       string
+	description string
+	keywords    string
+	generator   string
+	canonical   string
+	viewport    string
+	lang        string
+	h1          []string
+	h2          []string
+	links       []*url.URL // Enlaces resueltos contra la URL de la página
+	scripts     []string   // src de los scripts
+	inline      []string   // Contenido de los scripts en línea
+	stylesheets int
+	images      int
+	imagesNoAlt int
+	feed        bool   // Enlace a RSS o Atom
+	articles    int    // Elementos <article>
+	text        string // Texto visible
+}
+
+// maxVisibleText límite del texto visible que se guarda por página
+const maxVisibleText = 200000
+
+// parseDocument extrae de la página lo que usa el análisis
+func parseDocument(base *url.URL, doc string) *document {
+	d := &document{}
+	var text strings.Builder
+	var capture *strings.Builder // Texto del título o encabezado abierto
+	captureTag := ""
+
+	for _, tok := range tokenize(doc) {
+		switch tok.kind {
+		case tokenText:
+			if capture != nil {
+				capture.WriteString(tok.text)
+			}
+			if text.Len() < maxVisibleText {
+				text.WriteString(tok.text)
+				text.WriteByte(' ')
+			}
+		case tokenRaw:
+			if tok.name == "script" && strings.TrimSpace(tok.text) != "" {
+				d.inline = append(d.inline, tok.text)
+			}
+		case tokenEnd:
+			if capture != nil && tok.name == captureTag {
+				value := collapseSpaces(capture.String())
+				switch captureTag {
+				case "title":
+					if d.title == "" {
+						d.title = value
+					}
+				case "h1":
+					if value != "" {
+						d.h1 = append(d.h1, value)
+					}
+				case "h2":
+					if value != "" {
+						d.h2 = append(d.h2, value)
+					}
+				}
+				capture = nil
+			}
+		case tokenStart:
+			switch tok.name {
+			case "html":
+				d.lang = tok.attrs["lang"]
+			case "title", "h1", "h2":
+				capture = &strings.Builder{}
+				captureTag = tok.name
+			case "meta":
+				content := strings.TrimSpace(tok.attrs["content"])
+				switch strings.ToLower(tok.attrs["name"]) {
+				case "description":
+					d.description = content
+				case "keywords":
+					d.keywords = content
+				case "generator":
+					d.generator = content
+				case "viewport":
+					d.viewport = content
+				}
+				if d.description == "" && strings.ToLower(tok.attrs["property"]) == "og:description" {
+					d.description = content
+				}
+			case "link":
+				rel := strings.ToLower(tok.attrs["rel"])
+				switch {
+				case rel == "canonical":
+					if canonical := resolve(base, tok.attrs["href"]); canonical != nil {
+						d.canonical = canonical.String()
+					}
+				case strings.Contains(rel, "stylesheet"):
+					d.stylesheets++
+				case rel == "alternate" && strings.Contains(tok.attrs["type"], "xml"):
+					d.feed = true
+				}
+			case "a":
+				if link := resolve(base, tok.attrs["href"]); link != nil {
+					d.links = append(d.links, link)
+				}
+			case "script":
+				if src := tok.attrs["src"]; src != "" {
+					d.scripts = append(d.scripts, src)
+				}
+			case "img":
+				d.images++
+				if strings.TrimSpace(tok.attrs["alt"]) == "" {
+					d.imagesNoAlt++
+				}
+			case "article":
+				d.articles++
+			}
+		}
+	}
+	d.text = collapseSpaces(text.String())
+	return d
+}
+
+// resolve resuelve un enlace relativo; nil si no es un enlace navegable
+func resolve(base *url.URL, href string) *url.URL {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return nil
+	}
+	link, err := base.Parse(href)
+	if err != nil {
+		return nil
+	}
+	link.Fragment = ""
+	return link
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
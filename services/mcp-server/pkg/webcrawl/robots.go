@@ -0,0 +1,136 @@
+package webcrawl
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ===== ROBOTS.TXT =====
+
+// robotsRules reglas de robots.txt que aplican al crawler
+type robotsRules struct {
+	allow    []string
+	disallow []string
+	delay    time.Duration
+}
+
+// parseRobots lee las reglas del grupo del agente (por el token de su
+// User-Agent) o, si no tiene grupo propio, las del grupo "*"
+func parseRobots(content, agent string) *robotsRules {
+	agent = strings.ToLower(agent)
+	var own, wildcard *robotsRules
+	var current []*robotsRules // Grupos del bloque de User-agent actual
+	inRules := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if hash := strings.IndexByte(line, '#'); hash >= 0 {
+			line = line[:hash]
+		}
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			continue
+		}
+		field := strings.ToLower(strings.TrimSpace(line[:colon]))
+		value := strings.TrimSpace(line[colon+1:])
+
+		if field == "user-agent" {
+			if inRules {
+				current = nil
+				inRules = false
+			}
+			name := strings.ToLower(value)
+			switch {
+			case name == "*":
+				if wildcard == nil {
+					wildcard = &robotsRules{}
+				}
+				current = append(current, wildcard)
+			case name != "" && strings.Contains(agent, name):
+				if own == nil {
+					own = &robotsRules{}
+				}
+				current = append(current, own)
+			default:
+				current = append(current, &robotsRules{}) // Grupo de otro agente
+			}
+			continue
+		}
+
+		inRules = true
+		for _, rules := range current {
+			switch field {
+			case "allow":
+				if value != "" {
+					rules.allow = append(rules.allow, value)
+				}
+			case "disallow":
+				if value != "" {
+					rules.disallow = append(rules.disallow, value)
+				}
+			case "crawl-delay":
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					rules.delay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	if own != nil {
+		return own
+	}
+	if wildcard != nil {
+		return wildcard
+	}
+	return &robotsRules{}
+}
+
+// allowed aplica la regla más específica (la de mayor longitud); con igual
+// longitud gana Allow
+func (r *robotsRules) allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	best, allow := -1, true
+	for _, pattern := range r.allow {
+		if n := matchRobots(pattern, path); n > best || (n == best && n >= 0) {
+			best, allow = n, true
+		}
+	}
+	for _, pattern := range r.disallow {
+		if n := matchRobots(pattern, path); n > best {
+			best, allow = n, false
+		}
+	}
+	return allow
+}
+
+// matchRobots retorna la longitud del patrón si coincide con la ruta o -1.
+// Soporta los comodines * y $ del estándar (RFC 9309).
+func matchRobots(pattern, path string) int {
+	anchored := strings.HasSuffix(pattern, "$")
+	body := strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(body, "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return -1
+	}
+	pos := len(parts[0])
+	for _, part := range parts[1:] {
+		index := strings.Index(path[pos:], part)
+		if index < 0 {
+			return -1
+		}
+		pos += index + len(part)
+	}
+	if anchored && pos != len(path) {
+		// Con comodín final la última parte debe quedar al final de la ruta
+		if len(parts) == 1 || !strings.HasSuffix(path, parts[len(parts)-1]) {
+			return -1
+		}
+	}
+	return len(pattern)
+}
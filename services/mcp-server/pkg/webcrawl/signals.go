@@ -0,0 +1,255 @@
+package webcrawl
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"mcp-server/pkg/colombia"
+)
+
+// ===== SEÑALES DEL SITIO =====
+
+// socialHosts dominio de cada red social
+var socialHosts = map[string]string{
+	"facebook.com":  "facebook",
+	"fb.com":        "facebook",
+	"instagram.com": "instagram",
+	"linkedin.com":  "linkedin",
+	"twitter.com":   "twitter",
+	"x.com":         "twitter",
+	"tiktok.com":    "tiktok",
+	"youtube.com":   "youtube",
+	"youtu.be":      "youtube",
+	"pinterest.com": "pinterest",
+}
+
+// shareLinkPaths rutas de botones de compartir, que no son perfiles
+var shareLinkPaths = []string{"/sharer", "/share", "/intent/", "/sharearticle", "/dialog/"}
+
+var (
+	emailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)*\.[a-zA-Z]{2,}`)
+	// Móviles (3XX) y fijos (60X) con separadores opcionales y +57 opcional
+	phonePattern = regexp.MustCompile(`(?:^|[^\d+])((?:\+?57[\s.-]?)?(?:3\d{2}|\(?60\d\)?)[\s.-]?\d{3}[\s.-]?\d{4})(?:$|\D)`)
+)
+
+// imageExtensions terminaciones de archivos que parecen correos (logo@2x.png)
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg"}
+
+// technologySignatures texto que delata cada tecnología en el HTML, los
+// scripts o los headers
+var technologySignatures = []struct {
+	name     string
+	patterns []string
+}{
+	{"WordPress", []string{"wp-content/", "wp-includes/", "wordpress"}},
+	{"WooCommerce", []string{"woocommerce"}},
+	{"Elementor", []string{"elementor"}},
+	{"Shopify", []string{"cdn.shopify.com", "shopify"}},
+	{"Wix", []string{"wixstatic.com", "wix.com"}},
+	{"Squarespace", []string{"squarespace"}},
+	{"VTEX", []string{"vtex"}},
+	{"PrestaShop", []string{"prestashop"}},
+	{"Magento", []string{"magento", "mage/cookies"}},
+	{"Tiendanube", []string{"tiendanube", "nuvemshop"}},
+	{"Jumpseller", []string{"jumpseller"}},
+	{"Joomla", []string{"joomla"}},
+	{"Drupal", []string{"drupal"}},
+	{"Webflow", []string{"webflow"}},
+	{"Next.js", []string{"__next_data__", "/_next/"}},
+	{"Nuxt", []string{"__nuxt", "/_nuxt/"}},
+	{"React", []string{"react-dom", "data-reactroot"}},
+	{"Vue.js", []string{"vue.min.js", "vue.runtime", "data-v-app"}},
+	{"jQuery", []string{"jquery"}},
+	{"Bootstrap", []string{"bootstrap.min"}},
+	{"Google Tag Manager", []string{"googletagmanager.com/gtm.js"}},
+	{"Google Analytics", []string{"google-analytics.com", "googletagmanager.com/gtag/js"}},
+	{"Meta Pixel", []string{"connect.facebook.net", "fbq("}},
+	{"Wompi", []string{"wompi"}},
+	{"ePayco", []string{"epayco"}},
+	{"PayU", []string{"payu"}},
+	{"Mercado Pago", []string{"mercadopago"}},
+	{"Cloudflare", []string{"cloudflare"}},
+	{"PHP", []string{"x-powered-by: php"}},
+	{"Nginx", []string{"server: nginx"}},
+	{"Apache", []string{"server: apache"}},
+	{"LiteSpeed", []string{"server: litespeed"}},
+}
+
+// ecommercePlatforms tecnologías que implican tienda en línea
+var ecommercePlatforms = map[string]bool{
+	"WooCommerce": true, "Shopify": true, "VTEX": true, "PrestaShop": true, "Magento": true,
+	"Tiendanube": true, "Jumpseller": true, "Wompi": true, "ePayco": true, "PayU": true, "Mercado Pago": true,
+}
+
+var ecommercePaths = []string{"/carrito", "/cart", "/checkout", "/tienda", "/shop"}
+var ecommerceTexts = []string{"agregar al carrito", "añadir al carrito", "add to cart", "comprar ahora", "finalizar compra", "ver carrito"}
+var blogPaths = []string{"/blog", "/noticias", "/articulos", "/news", "/novedades"}
+
+// keyPages páginas del sitio que vale la pena visitar, en orden de prioridad
+var keyPages = []struct {
+	kind     string
+	keywords []string
+}{
+	{PageContact, []string{"contacto", "contactenos", "contactanos", "contact"}},
+	{PageAbout, []string{"nosotros", "quienes-somos", "quienessomos", "about", "empresa"}},
+	{PageShop, []string{"tienda", "shop", "productos", "catalogo", "products"}},
+	{PageBlog, []string{"blog", "noticias", "articulos", "news"}},
+}
+
+// pageKind clasifica un enlace del mismo sitio como página clave
+func pageKind(link *url.URL) string {
+	path := strings.ToLower(strings.Trim(link.Path, "/"))
+	if path == "" {
+		return ""
+	}
+	for _, page := range keyPages {
+		for _, keyword := range page.keywords {
+			for _, segment := range strings.Split(path, "/") {
+				if segment == keyword || strings.HasPrefix(segment, keyword+"-") || strings.HasPrefix(segment, keyword+".") {
+					return page.kind
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// socialNetwork red social del enlace si es un perfil
+func socialNetwork(link *url.URL) string {
+	host := strings.TrimPrefix(strings.ToLower(link.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	network, exists := socialHosts[host]
+	if !exists {
+		return ""
+	}
+	path := strings.ToLower(link.Path)
+	if strings.Trim(path, "/") == "" {
+		return ""
+	}
+	for _, share := range shareLinkPaths {
+		if strings.HasPrefix(path, share) {
+			return ""
+		}
+	}
+	return network
+}
+
+// whatsappNumber número de un enlace de WhatsApp (wa.me o api.whatsapp.com)
+func whatsappNumber(link *url.URL) (string, bool) {
+	host := strings.TrimPrefix(strings.ToLower(link.Hostname()), "www.")
+	switch host {
+	case "wa.me":
+		return strings.Trim(link.Path, "/"), true
+	case "api.whatsapp.com", "web.whatsapp.com", "whatsapp.com":
+		return link.Query().Get("phone"), true
+	}
+	return "", false
+}
+
+// normalizePhone valida el número con el plan de numeración colombiano; los
+// números de relleno se descartan
+func normalizePhone(raw string) (string, bool) {
+	phone, err := colombia.ParsePhone(raw)
+	if err != nil || phone.LikelyInvalid {
+		return "", false
+	}
+	return phone.E164, true
+}
+
+// textPhones números de teléfono escritos en el texto de la página
+func textPhones(text string) []string {
+	var phones []string
+	for _, match := range phonePattern.FindAllStringSubmatch(text, -1) {
+		if phone, ok := normalizePhone(match[1]); ok {
+			phones = append(phones, phone)
+		}
+	}
+	return phones
+}
+
+// textEmails correos escritos en el texto de la página
+func textEmails(text string) []string {
+	var emails []string
+	for _, match := range emailPattern.FindAllString(text, -1) {
+		if email, ok := normalizeEmail(match); ok {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+func normalizeEmail(raw string) (string, bool) {
+	email := strings.ToLower(strings.TrimSpace(raw))
+	if at := strings.IndexByte(email, '?'); at >= 0 {
+		email = email[:at]
+	}
+	if !emailPattern.MatchString(email) {
+		return "", false
+	}
+	for _, extension := range imageExtensions {
+		if strings.HasSuffix(email, extension) {
+			return "", false
+		}
+	}
+	return email, true
+}
+
+// detectTechnologies tecnologías reconocibles en el HTML y los headers
+func detectTechnologies(doc *document, lowerHTML string, header http.Header) []string {
+	var evidence strings.Builder
+	evidence.WriteString(lowerHTML)
+	evidence.WriteString(strings.ToLower(doc.generator))
+	for _, src := range doc.scripts {
+		evidence.WriteString(" " + strings.ToLower(src))
+	}
+	for _, key := range []string{"Server", "X-Powered-By"} {
+		if value := header.Get(key); value != "" {
+			evidence.WriteString(" " + strings.ToLower(key) + ": " + strings.ToLower(value))
+		}
+	}
+	if header.Get("Cf-Ray") != "" {
+		evidence.WriteString(" cloudflare")
+	}
+	text := evidence.String()
+
+	var found []string
+	for _, signature := range technologySignatures {
+		for _, pattern := range signature.patterns {
+			if strings.Contains(text, pattern) {
+				found = append(found, signature.name)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// addUnique agrega los valores que no estén en la lista
+func addUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		exists := false
+		for _, current := range list {
+			if current == value {
+				exists = true
+				break
+			}
+		}
+		if !exists && value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// sortedKeys claves del mapa en orden alfabético
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}